@@ -0,0 +1,37 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type ArtifactsCommand struct {
+	Meta
+}
+
+func (c *ArtifactsCommand) Synopsis() string {
+	return "Interact with the artifacts of a local artifact store"
+}
+
+func (c *ArtifactsCommand) Help() string {
+	helpText := `
+Usage: packer artifacts <subcommand> [options] [args]
+  This command groups subcommands for interacting with the versions of the
+  builds stored in a local artifact store.
+
+Related but not under the "artifacts" command :
+
+- The "artifact" and "artifact_id" functions read the version of a build
+  promoted to a channel from a template.
+
+Subcommands:
+  promote     Promote a version of a build to a channel.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ArtifactsCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
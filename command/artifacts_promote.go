@@ -0,0 +1,100 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/posener/complete"
+)
+
+type ArtifactsPromoteCommand struct {
+	Meta
+}
+
+func (c *ArtifactsPromoteCommand) Synopsis() string {
+	return "Promote a version of a build to a channel"
+}
+
+func (c *ArtifactsPromoteCommand) Help() string {
+	helpText := `
+Usage: packer artifacts promote [options] <lineage> <version> <channel>
+
+  Promote a version of a build stored in a local artifact store to a channel,
+  replacing the version previously promoted to it. The lineage is the name of
+  the build, like amazon-ebs.base, and the version is one of the versions
+  listed in its versions directory, or "latest" for the last one.
+
+  Templates read the version promoted to a channel with the "artifact" and
+  "artifact_id" functions.
+
+  Ex: packer artifacts promote -path=artifacts amazon-ebs.base latest stable
+
+Options:
+  -path=PATH                    The directory of the local artifact store.
+                                Defaults to the PACKER_ARTIFACT_STORE_PATH
+                                env var.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ArtifactsPromoteCommand) Run(args []string) int {
+	ctx, cleanup := handleTermInterrupt(c.Ui)
+	defer cleanup()
+
+	cfg, ret := c.ParseArgs(args)
+	if ret != 0 {
+		return ret
+	}
+
+	return c.RunContext(ctx, cfg)
+}
+
+func (c *ArtifactsPromoteCommand) ParseArgs(args []string) (*ArtifactsPromoteArgs, int) {
+	var cfg ArtifactsPromoteArgs
+	flags := c.Meta.FlagSet("artifacts promote", 0)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	cfg.AddFlagSets(flags)
+	if err := flags.Parse(args); err != nil {
+		return &cfg, 1
+	}
+
+	args = flags.Args()
+	if len(args) != 3 {
+		flags.Usage()
+		return &cfg, 1
+	}
+	cfg.Lineage, cfg.Version, cfg.Channel = args[0], args[1], args[2]
+	if cfg.Path == "" {
+		cfg.Path = os.Getenv("PACKER_ARTIFACT_STORE_PATH")
+	}
+	if cfg.Path == "" {
+		c.Ui.Error("The directory of the artifact store must be set with -path or the PACKER_ARTIFACT_STORE_PATH env var")
+		return &cfg, 1
+	}
+	return &cfg, 0
+}
+
+func (c *ArtifactsPromoteCommand) RunContext(_ context.Context, cla *ArtifactsPromoteArgs) int {
+	store := &packer.LocalArtifactStore{Dir: cla.Path}
+	promoted, err := store.Promote(cla.Lineage, cla.Version, cla.Channel)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to promote %s %s: %s", cla.Lineage, cla.Version, err))
+		return 1
+	}
+	c.Ui.Say(fmt.Sprintf("Promoted %s %s to the %s channel", cla.Lineage, promoted.Version, cla.Channel))
+	return 0
+}
+
+func (*ArtifactsPromoteCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*ArtifactsPromoteCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-path": complete.PredictDirs("*"),
+	}
+}
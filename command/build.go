@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,7 +18,9 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template"
 	"github.com/hashicorp/packer/hcl2template"
+	"github.com/hashicorp/packer/helper/i18n"
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/diagnostics"
 	"github.com/hashicorp/packer/version"
 	"golang.org/x/sync/semaphore"
 
@@ -68,6 +72,11 @@ func (m *Meta) GetConfigFromHCL(cla *MetaArgs) (*hcl2template.PackerConfig, int)
 		CorePackerVersionString: version.FormattedVersion(),
 		Parser:                  hclparse.NewParser(),
 		PluginConfig:            m.CoreConfig.Components.PluginConfig,
+		InstallSummaries:        installSummaries(),
+		ValidationOptions: hcl2template.ValidationOptions{
+			Strict:       cla.Strict,
+			WarnOnUnused: cla.WarnOnUnusedVars,
+		},
 	}
 	cfg, diags := parser.Parse(cla.Path, cla.VarFiles, cla.Vars)
 	return cfg, writeDiags(m.Ui, parser.Files(), diags)
@@ -76,9 +85,9 @@ func (m *Meta) GetConfigFromHCL(cla *MetaArgs) (*hcl2template.PackerConfig, int)
 func writeDiags(ui packersdk.Ui, files map[string]*hcl.File, diags hcl.Diagnostics) int {
 	// write HCL errors/diagnostics if any.
 	b := bytes.NewBuffer(nil)
-	err := hcl.NewDiagnosticTextWriter(b, files, 80, false).WriteDiagnostics(diags)
+	err := hcl.NewDiagnosticTextWriter(b, files, 80, false).WriteDiagnostics(translateDiags(diags))
 	if err != nil {
-		ui.Error("could not write diagnostic: " + err.Error())
+		ui.Error(i18n.Sprintf("could not write diagnostic: %s", err))
 		return 1
 	}
 	if b.Len() != 0 {
@@ -91,6 +100,17 @@ func writeDiags(ui packersdk.Ui, files map[string]*hcl.File, diags hcl.Diagnosti
 	return 0
 }
 
+// translateDiags returns diags with their summary in the language of the user.
+func translateDiags(diags hcl.Diagnostics) hcl.Diagnostics {
+	translated := make(hcl.Diagnostics, len(diags))
+	for i, diag := range diags {
+		d := *diag
+		d.Summary = i18n.Text(d.Summary)
+		translated[i] = &d
+	}
+	return translated
+}
+
 func (m *Meta) GetConfig(cla *MetaArgs) (packer.Handler, int) {
 	cfgType, err := cla.GetConfigType()
 	if err != nil {
@@ -156,19 +176,28 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 	}
 
 	builds, diags := packerStarter.GetBuilds(packer.GetBuildsOptions{
-		Only:    cla.Only,
-		Except:  cla.Except,
-		Debug:   cla.Debug,
-		Force:   cla.Force,
-		OnError: cla.OnError,
+		Only:                  cla.Only,
+		Except:                cla.Except,
+		SkipProvisioners:      cla.SkipProvisioners,
+		ExcludePostProcessors: cla.ExcludePostProcessors,
+		Debug:                 cla.Debug,
+		Force:                 cla.Force,
+		OnError:               cla.OnError,
 	})
 
 	// here, something could have gone wrong but we still want to run valid
 	// builds.
 	ret = writeDiags(c.Ui, nil, diags)
 
+	// Policies are evaluated before any builder runs.
+	if cla.PolicyDir != "" {
+		if ret := checkPolicies(buildCtx, c.Ui, packerStarter, builds, cla.PolicyDir); ret != 0 {
+			return ret
+		}
+	}
+
 	if cla.Debug {
-		c.Ui.Say("Debug mode enabled. Builds will not be parallelized.")
+		c.Ui.Say(i18n.Sprintf("Debug mode enabled. Builds will not be parallelized."))
 	}
 
 	// Compile all the UIs for the builds
@@ -189,7 +218,7 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 					Color: colors[i%len(colors)],
 					Ui:    ui,
 				}
-				ui.Say(fmt.Sprintf("%s: output will be in this color.", builds[i].Name()))
+				ui.Say(i18n.Sprintf("%s: output will be in this color.", builds[i].Name()))
 				if i+1 == len(builds) {
 					// Add a newline between the color output and the actual output
 					c.Ui.Say("")
@@ -206,6 +235,17 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 		buildUis[builds[i]] = ui
 	}
 
+	// Record the steps of the builds for the summary report
+	reports := make(map[string]*packer.BuildReport)
+	if cla.ReportPath != "" {
+		for _, b := range builds {
+			if cb, ok := b.(*packer.CoreBuild); ok {
+				cb.Report = &packer.BuildReport{}
+				reports[b.Name()] = cb.Report
+			}
+		}
+	}
+
 	log.Printf("Build debug mode: %v", cla.Debug)
 	log.Printf("Force build: %v", cla.Force)
 	log.Printf("On error: %v", cla.OnError)
@@ -224,7 +264,23 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 		sync.RWMutex
 		m map[string]error
 	}{m: make(map[string]error)}
+	var results = struct {
+		sync.Mutex
+		l []buildResult
+	}{}
 	limitParallel := semaphore.NewWeighted(cla.ParallelBuilds)
+	// Builds of local hypervisor builders are deferred while they would
+	// oversubscribe the host
+	budget := packer.NewResourceBudget(cla.MaxCPUs, cla.MaxMemory)
+	// Builds of a same concurrency group are run one at a time
+	concurrencyGroups := map[string]*semaphore.Weighted{}
+	for _, b := range builds {
+		if cb, ok := b.(*packer.CoreBuild); ok && cb.ConcurrencyGroup != "" {
+			if _, found := concurrencyGroups[cb.ConcurrencyGroup]; !found {
+				concurrencyGroups[cb.ConcurrencyGroup] = semaphore.NewWeighted(1)
+			}
+		}
+	}
 	for i := range builds {
 		if err := buildCtx.Err(); err != nil {
 			log.Println("Interrupted, not going to start any more builds.")
@@ -253,6 +309,39 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 
 			defer limitParallel.Release(1)
 
+			if cb, ok := b.(*packer.CoreBuild); ok && cb.ConcurrencyGroup != "" {
+				group := concurrencyGroups[cb.ConcurrencyGroup]
+				if !group.TryAcquire(1) {
+					ui.Say(fmt.Sprintf("Waiting for the other builds of concurrency group %q", cb.ConcurrencyGroup))
+					if err := group.Acquire(buildCtx, 1); err != nil {
+						ui.Error(fmt.Sprintf("Build '%s' failed to acquire concurrency group %q: %s", name, cb.ConcurrencyGroup, err))
+						errors.Lock()
+						errors.m[name] = err
+						errors.Unlock()
+						return
+					}
+				}
+				defer group.Release(1)
+				// the build starts when it got its turn
+				buildStart = time.Now()
+			}
+
+			if cb, ok := b.(*packer.CoreBuild); ok && cb.Resources != nil {
+				resources := *cb.Resources
+				if !budget.TryAcquire(resources) {
+					ui.Say(fmt.Sprintf("Waiting for %s to be available", resources))
+					if err := budget.Acquire(buildCtx, resources); err != nil {
+						ui.Error(fmt.Sprintf("Build '%s' failed to acquire %s: %s", name, resources, err))
+						errors.Lock()
+						errors.m[name] = err
+						errors.Unlock()
+						return
+					}
+				}
+				defer budget.Release(resources)
+				buildStart = time.Now()
+			}
+
 			log.Printf("Starting build run: %s", name)
 			runArtifacts, err := b.Run(buildCtx, ui)
 
@@ -261,13 +350,23 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 			buildDuration := buildEnd.Sub(buildStart)
 			fmtBuildDuration := durafmt.Parse(buildDuration).LimitFirstN(2)
 
+			results.Lock()
+			results.l = append(results.l, buildResult{
+				Name:     name,
+				Start:    buildStart,
+				Duration: buildDuration,
+				Err:      err,
+				Report:   reports[name],
+			})
+			results.Unlock()
+
 			if err != nil {
-				ui.Error(fmt.Sprintf("Build '%s' errored after %s: %s", name, fmtBuildDuration, err))
+				ui.Error(i18n.Sprintf("Build '%s' errored after %s: %s", name, fmtBuildDuration, err))
 				errors.Lock()
 				errors.m[name] = err
 				errors.Unlock()
 			} else {
-				ui.Say(fmt.Sprintf("Build '%s' finished after %s.", name, fmtBuildDuration))
+				ui.Say(i18n.Sprintf("Build '%s' finished after %s.", name, fmtBuildDuration))
 				if nil != runArtifacts {
 					artifacts.Lock()
 					artifacts.m[name] = runArtifacts
@@ -297,17 +396,26 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 	buildCommandEnd := time.Now()
 	buildCommandDuration := buildCommandEnd.Sub(buildCommandStart)
 	fmtBuildCommandDuration := durafmt.Parse(buildCommandDuration).LimitFirstN(2)
-	c.Ui.Say(fmt.Sprintf("\n==> Wait completed after %s", fmtBuildCommandDuration))
+	c.Ui.Say(i18n.Sprintf("\n==> Wait completed after %s", fmtBuildCommandDuration))
+
+	if cla.ReportPath != "" {
+		sort.Slice(results.l, func(i, j int) bool { return results.l[i].Name < results.l[j].Name })
+		if err := writeReport(cla.ReportPath, cla.ReportFormat, results.l); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to write report %s: %s", cla.ReportPath, err))
+		} else {
+			c.Ui.Say(fmt.Sprintf("Report written to %s", cla.ReportPath))
+		}
+	}
 
 	if err := buildCtx.Err(); err != nil {
-		c.Ui.Say("Cleanly cancelled builds after being interrupted.")
+		c.Ui.Say(i18n.Sprintf("Cleanly cancelled builds after being interrupted."))
 		return 1
 	}
 
 	if len(errors.m) > 0 {
 		c.Ui.Machine("error-count", strconv.FormatInt(int64(len(errors.m)), 10))
 
-		c.Ui.Error("\n==> Some builds didn't complete successfully and had errors:")
+		c.Ui.Error(i18n.Sprintf("\n==> Some builds didn't complete successfully and had errors:"))
 		for name, err := range errors.m {
 			// Create a UI for the machine readable stuff to be targeted
 			ui := &packer.TargetedUI{
@@ -319,10 +427,18 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 
 			c.Ui.Error(fmt.Sprintf("--> %s: %s", name, err))
 		}
+
+		for name, err := range errors.m {
+			if !diagnostics.IsInternalError(err) {
+				continue
+			}
+			c.writeDiagnosticsBundle(cla, fmt.Sprintf("build %q failed: %s", name, err))
+			break
+		}
 	}
 
 	if len(artifacts.m) > 0 {
-		c.Ui.Say("\n==> Builds finished. The artifacts of successful builds are:")
+		c.Ui.Say(i18n.Sprintf("\n==> Builds finished. The artifacts of successful builds are:"))
 		for name, buildArtifacts := range artifacts.m {
 			// Create a UI for the machine readable stuff to be targeted
 			ui := &packer.TargetedUI{
@@ -366,7 +482,7 @@ func (c *BuildCommand) RunContext(buildCtx context.Context, cla *BuildArgs) int
 			}
 		}
 	} else {
-		c.Ui.Say("\n==> Builds finished but no artifacts were created.")
+		c.Ui.Say(i18n.Sprintf("\n==> Builds finished but no artifacts were created."))
 	}
 
 	if len(errors.m) > 0 {
@@ -389,14 +505,23 @@ Options:
   -color=false                  Disable color output. (Default: color)
   -debug                        Debug mode enabled for builds.
   -except=foo,bar,baz           Run all builds and post-processors other than these.
+  -exclude-post-processors=foo  Do not run these post-processors, by name or type.
   -only=foo,bar,baz             Build only the specified builds.
   -force                        Force a build to continue if artifacts exist, deletes existing artifacts.
   -machine-readable             Produce machine-readable output.
+  -max-cpus=0                   vCPUs the qemu, virtualbox and hyperv builds running at the same time can use. 0 means no limit (Default: 0)
+  -max-memory=0                 Memory, in MB, the qemu, virtualbox and hyperv builds running at the same time can use. 0 means no limit (Default: 0)
   -on-error=[cleanup|abort|ask|run-cleanup-provisioner] If the build fails do: clean up (default), abort, ask, or run-cleanup-provisioner.
   -parallel-builds=1            Number of builds to run in parallel. 1 disables parallelization. 0 means no limit (Default: 0)
+  -policy-dir=path              Evaluate the rego policies of this directory against the resolved template before running builds.
+  -report=path                  Write a summary of the builds, their provisioners and post-processors to this file.
+  -report-format=[junit|sarif]  Format of the -report file, JUnit XML or SARIF. (Default: junit)
+  -skip-provisioner=foo,bar     Do not run these provisioners, by name or type.
+  -strict                       Make warnings errors, like the use of undefined or, with -warn-on-unused-vars, unused variables. HCL2 only.
   -timestamp-ui                 Enable prefixing of each ui output with an RFC3339 timestamp.
   -var 'key=value'              Variable for templates, can be used multiple times.
   -var-file=path                JSON or HCL2 file containing user variables.
+  -warn-on-unused-vars          Warn about variables set with -var or -var-file that the template doesn't use, and about required plugins whose components are never used. HCL2 only.
 `
 
 	return strings.TrimSpace(helpText)
@@ -412,16 +537,46 @@ func (*BuildCommand) AutocompleteArgs() complete.Predictor {
 
 func (*BuildCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-color":            complete.PredictNothing,
-		"-debug":            complete.PredictNothing,
-		"-except":           complete.PredictNothing,
-		"-only":             complete.PredictNothing,
-		"-force":            complete.PredictNothing,
-		"-machine-readable": complete.PredictNothing,
-		"-on-error":         complete.PredictNothing,
-		"-parallel":         complete.PredictNothing,
-		"-timestamp-ui":     complete.PredictNothing,
-		"-var":              complete.PredictNothing,
-		"-var-file":         complete.PredictNothing,
+		"-color":                   complete.PredictNothing,
+		"-debug":                   complete.PredictNothing,
+		"-except":                  complete.PredictNothing,
+		"-exclude-post-processors": complete.PredictNothing,
+		"-only":                    complete.PredictNothing,
+		"-force":                   complete.PredictNothing,
+		"-machine-readable":        complete.PredictNothing,
+		"-max-cpus":                complete.PredictNothing,
+		"-max-memory":              complete.PredictNothing,
+		"-on-error":                complete.PredictNothing,
+		"-parallel":                complete.PredictNothing,
+		"-policy-dir":              complete.PredictNothing,
+		"-report":                  complete.PredictFiles("*"),
+		"-report-format":           complete.PredictSet("junit", "sarif"),
+		"-skip-provisioner":        complete.PredictNothing,
+		"-strict":                  complete.PredictNothing,
+		"-timestamp-ui":            complete.PredictNothing,
+		"-var":                     complete.PredictNothing,
+		"-var-file":                complete.PredictNothing,
+		"-warn-on-unused-vars":     complete.PredictNothing,
+	}
+}
+
+// writeDiagnosticsBundle writes a diagnostics bundle in the current directory
+// and tells the user where it is.
+func (c *BuildCommand) writeDiagnosticsBundle(cla *BuildArgs, reason string) {
+	bundle := &diagnostics.Bundle{
+		Reason:        reason,
+		Goroutines:    diagnostics.Goroutines(),
+		Args:          os.Args[1:],
+		Env:           os.Environ(),
+		TemplatePaths: []string{cla.Path},
+		PluginFolders: c.CoreConfig.Components.PluginConfig.KnownPluginFolders,
+		LogPath:       os.Getenv(diagnostics.LogPathEnvVar),
+	}
+	path, err := bundle.Write(".")
+	if err != nil {
+		log.Printf("[WARN] Failed to write diagnostics bundle: %s", err)
+		return
 	}
+	c.Ui.Error(fmt.Sprintf("\n==> A build failed with an internal error, which is probably a bug.\n"+
+		"A diagnostics bundle has been placed at %q, please attach it to your report.", path))
 }
@@ -68,6 +68,14 @@ type MetaArgs struct {
 	VarFiles     []string
 	// set to "hcl2" to force hcl2 mode
 	ConfigType configType
+	// Strict makes warnings about HCL2 configs, like deprecated fields,
+	// errors. It is set by the -strict flag of `packer validate` and
+	// `packer build`.
+	Strict bool
+	// WarnOnUnusedVars reports the variables set with -var or -var-file that
+	// HCL2 configs don't use, and their unused required plugins. It is set
+	// by -warn-on-unused-vars.
+	WarnOnUnusedVars bool
 }
 
 func (ba *BuildArgs) AddFlagSets(flags *flag.FlagSet) {
@@ -78,6 +86,17 @@ func (ba *BuildArgs) AddFlagSets(flags *flag.FlagSet) {
 	flags.BoolVar(&ba.MachineReadable, "machine-readable", false, "")
 
 	flags.Int64Var(&ba.ParallelBuilds, "parallel-builds", 0, "")
+	flags.Int64Var(&ba.MaxCPUs, "max-cpus", 0, "")
+	flags.Int64Var(&ba.MaxMemory, "max-memory", 0, "")
+	flags.StringVar(&ba.PolicyDir, "policy-dir", "", "")
+	flags.StringVar(&ba.ReportPath, "report", "", "")
+	flags.BoolVar(&ba.Strict, "strict", false, "")
+	flags.BoolVar(&ba.WarnOnUnusedVars, "warn-on-unused-vars", false, "")
+	flags.Var((*sliceflag.StringFlag)(&ba.SkipProvisioners), "skip-provisioner", "")
+	flags.Var((*sliceflag.StringFlag)(&ba.ExcludePostProcessors), "exclude-post-processors", "")
+
+	flagReportFormat := enumflag.New(&ba.ReportFormat, "junit", "sarif")
+	flags.Var(flagReportFormat, "report-format", "")
 
 	flagOnError := enumflag.New(&ba.OnError, "cleanup", "abort", "ask", "run-cleanup-provisioner")
 	flags.Var(flagOnError, "on-error", "")
@@ -90,11 +109,20 @@ type BuildArgs struct {
 	MetaArgs
 	Color, Debug, Force, TimestampUi, MachineReadable bool
 	ParallelBuilds                                    int64
+	MaxCPUs, MaxMemory                                int64
 	OnError                                           string
+	PolicyDir                                         string
+	ReportPath, ReportFormat                          string
+	SkipProvisioners, ExcludePostProcessors           []string
 }
 
 func (ia *InitArgs) AddFlagSets(flags *flag.FlagSet) {
 	flags.BoolVar(&ia.Upgrade, "upgrade", false, "upgrade any present plugin to the highest allowed version.")
+	flags.BoolVar(&ia.DryRun, "plan", false, "print what would be installed and written, without doing it.")
+	flags.BoolVar(&ia.DryRun, "dry-run", false, "same as -plan.")
+	flags.IntVar(&ia.ParallelInstalls, "parallel-installs", 0, "number of plugins to install at the same time.")
+	flags.IntVar(&ia.KeepVersions, "keep-versions", 0, "once a plugin is installed, remove its older versions but this number of versions.")
+	flags.BoolVar(&ia.Offline, "offline", false, "fail when a required plugin is not installed, instead of downloading it.")
 
 	ia.MetaArgs.AddFlagSets(flags)
 }
@@ -102,7 +130,54 @@ func (ia *InitArgs) AddFlagSets(flags *flag.FlagSet) {
 // InitArgs represents a parsed cli line for a `packer build`
 type InitArgs struct {
 	MetaArgs
-	Upgrade bool
+	Upgrade          bool
+	DryRun           bool
+	ParallelInstalls int
+	KeepVersions     int
+	Offline          bool
+}
+
+func (aa *ArtifactsPromoteArgs) AddFlagSets(flags *flag.FlagSet) {
+	flags.StringVar(&aa.Path, "path", "", "directory of the local artifact store.")
+}
+
+// ArtifactsPromoteArgs represents a parsed cli line for a `packer artifacts promote`
+type ArtifactsPromoteArgs struct {
+	Path                      string
+	Lineage, Version, Channel string
+}
+
+func (da *DocsArgs) AddFlagSets(flags *flag.FlagSet) {
+	flags.StringVar(&da.Kind, "kind", "", "only show components of this kind.")
+	flags.BoolVar(&da.Markdown, "markdown", false, "output markdown.")
+}
+
+// DocsArgs represents a parsed cli line for a `packer docs`
+type DocsArgs struct {
+	Component string
+	Kind      string
+	Markdown  bool
+}
+
+func (pa *PluginsDoctorArgs) AddFlagSets(flags *flag.FlagSet) {
+	flags.BoolVar(&pa.Fix, "fix", false, "apply the suggested fixes.")
+}
+
+// PluginsDoctorArgs represents a parsed cli line for a `packer plugins doctor`
+type PluginsDoctorArgs struct {
+	Fix bool
+}
+
+// PluginsInstallArgs represents a parsed cli line for a `packer plugins install`
+type PluginsInstallArgs struct {
+	Plugin  string
+	Version string
+}
+
+// PluginsRemoveArgs represents a parsed cli line for a `packer plugins remove`
+type PluginsRemoveArgs struct {
+	Plugin            string
+	VersionConstraint string
 }
 
 // ConsoleArgs represents a parsed cli line for a `packer console`
@@ -112,6 +187,8 @@ type ConsoleArgs struct {
 
 func (fa *FixArgs) AddFlagSets(flags *flag.FlagSet) {
 	flags.BoolVar(&fa.Validate, "validate", true, "")
+	flags.BoolVar(&fa.Write, "write", false, "")
+	flags.BoolVar(&fa.Diff, "diff", false, "")
 
 	fa.MetaArgs.AddFlagSets(flags)
 }
@@ -120,10 +197,17 @@ func (fa *FixArgs) AddFlagSets(flags *flag.FlagSet) {
 type FixArgs struct {
 	MetaArgs
 	Validate bool
+	// Write rewrites HCL2 files in place instead of printing them.
+	Write bool
+	// Diff shows the changes to HCL2 files as a diff.
+	Diff bool
 }
 
 func (va *ValidateArgs) AddFlagSets(flags *flag.FlagSet) {
 	flags.BoolVar(&va.SyntaxOnly, "syntax-only", false, "check syntax only")
+	flags.StringVar(&va.PolicyDir, "policy-dir", "", "evaluate the rego policies of this directory against the resolved template")
+	flags.BoolVar(&va.Strict, "strict", false, "make warnings, like deprecated fields, errors")
+	flags.BoolVar(&va.WarnOnUnusedVars, "warn-on-unused-vars", false, "warn about unused variables and plugins")
 
 	va.MetaArgs.AddFlagSets(flags)
 }
@@ -132,6 +216,7 @@ func (va *ValidateArgs) AddFlagSets(flags *flag.FlagSet) {
 type ValidateArgs struct {
 	MetaArgs
 	SyntaxOnly bool
+	PolicyDir  string
 }
 
 func (va *InspectArgs) AddFlagSets(flags *flag.FlagSet) {
@@ -143,6 +228,18 @@ type InspectArgs struct {
 	MetaArgs
 }
 
+func (da *DiffArgs) AddFlagSets(flags *flag.FlagSet) {
+	flags.StringVar(&da.Against, "against", "", "compare with the configuration at a git ref, like git:main.")
+	da.MetaArgs.AddFlagSets(flags)
+}
+
+// DiffArgs represents a parsed cli line for a `packer diff`
+type DiffArgs struct {
+	MetaArgs
+	OldPath, NewPath string
+	Against          string
+}
+
 func (va *HCL2UpgradeArgs) AddFlagSets(flags *flag.FlagSet) {
 	flags.StringVar(&va.OutputFile, "output-file", "", "File where to put the hcl2 generated config. Defaults to JSON_TEMPLATE.pkr.hcl")
 	flags.BoolVar(&va.WithAnnotations, "with-annotations", false, "Adds helper annotations with information about the generated HCL2 blocks.")
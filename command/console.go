@@ -5,9 +5,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
+	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/chzyer/readline"
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
 	"github.com/hashicorp/packer/helper/wrappedreadline"
 	"github.com/hashicorp/packer/helper/wrappedstreams"
 	"github.com/hashicorp/packer/packer"
@@ -120,14 +124,50 @@ func (c *ConsoleCommand) modePiped(cfg packer.Evaluator) int {
 	return ret
 }
 
+// consoleCompleter completes the reference or function name under the
+// cursor.
+type consoleCompleter struct {
+	candidates []string
+}
+
+func isReferenceRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+func (cc *consoleCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && isReferenceRune(line[start-1]) {
+		start--
+	}
+	prefix := string(line[start:pos])
+
+	res := [][]rune{}
+	for _, candidate := range cc.candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			res = append(res, []rune(candidate[len(prefix):]))
+		}
+	}
+	return res, pos - start
+}
+
 func (c *ConsoleCommand) modeInteractive(cfg packer.Evaluator) int {
-	// Setup the UI so we can output directly to stdout
-	l, err := readline.NewEx(wrappedreadline.Override(&readline.Config{
+	rlConfig := &readline.Config{
 		Prompt:            "> ",
 		InterruptPrompt:   "^C",
 		EOFPrompt:         "exit",
 		HistorySearchFold: true,
-	}))
+	}
+	if completer, ok := cfg.(packer.ExpressionCompleter); ok {
+		rlConfig.AutoComplete = &consoleCompleter{candidates: completer.ExpressionCompletions()}
+	}
+	if configDir, err := pathing.ConfigDir(); err != nil {
+		log.Printf("[WARN] console: not persisting history: %s", err)
+	} else {
+		rlConfig.HistoryFile = filepath.Join(configDir, "console_history")
+	}
+
+	// Setup the UI so we can output directly to stdout
+	l, err := readline.NewEx(wrappedreadline.Override(rlConfig))
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error initializing console: %s",
@@ -52,3 +52,39 @@ func Test_console(t *testing.T) {
 		})
 	}
 }
+
+func Test_consoleCompleter(t *testing.T) {
+	cc := &consoleCompleter{candidates: []string{
+		"data.amazon-ami.ubuntu",
+		"local.name",
+		"upper(",
+		"var.fruit",
+		"var.fruit_count",
+	}}
+
+	tc := []struct {
+		line       string
+		pos        int
+		expected   []string
+		prefixSize int
+	}{
+		{"var.fr", 6, []string{"uit", "uit_count"}, 6},
+		{"upper(var.f", 11, []string{"ruit", "ruit_count"}, 5},
+		{"up", 2, []string{"per("}, 2},
+		{"data.amazon-a", 13, []string{"mi.ubuntu"}, 13},
+		{"var.fruit + lo", 9, []string{"", "_count"}, 9},
+		{"nope", 4, []string{}, 4},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.line, func(t *testing.T) {
+			got, prefixSize := cc.Do([]rune(tc.line), tc.pos)
+			completions := []string{}
+			for _, c := range got {
+				completions = append(completions, string(c))
+			}
+			assert.Equal(t, tc.expected, completions)
+			assert.Equal(t, tc.prefixSize, prefixSize)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"github.com/hashicorp/hcl/v2"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer/packer"
 	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
 )
@@ -34,3 +35,17 @@ func (c *CoreWrapper) PluginRequirements() (plugingetter.Requirements, hcl.Diagn
 		},
 	}
 }
+
+func (c *CoreWrapper) PolicyInput(builds []packersdk.Build) (*packer.PolicyInput, hcl.Diagnostics) {
+	input, err := c.Core.PolicyInput(builds)
+	if err != nil {
+		return nil, hcl.Diagnostics{
+			&hcl.Diagnostic{
+				Summary:  "Failed to get policy input",
+				Detail:   err.Error(),
+				Severity: hcl.DiagError,
+			},
+		}
+	}
+	return input, nil
+}
@@ -0,0 +1,202 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/hcl2template"
+	"github.com/hashicorp/packer/packer"
+	"github.com/posener/complete"
+)
+
+type DiffCommand struct {
+	Meta
+}
+
+func (c *DiffCommand) Run(args []string) int {
+	ctx := context.Background()
+
+	cfg, ret := c.ParseArgs(args)
+	if ret != 0 {
+		return ret
+	}
+
+	return c.RunContext(ctx, cfg)
+}
+
+func (c *DiffCommand) ParseArgs(args []string) (*DiffArgs, int) {
+	var cfg DiffArgs
+	flags := c.Meta.FlagSet("diff", FlagSetVars)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	cfg.AddFlagSets(flags)
+	if err := flags.Parse(args); err != nil {
+		return &cfg, 1
+	}
+
+	args = flags.Args()
+	switch {
+	case cfg.Against != "" && len(args) == 1:
+		cfg.NewPath = args[0]
+	case cfg.Against == "" && len(args) == 2:
+		cfg.OldPath, cfg.NewPath = args[0], args[1]
+	default:
+		flags.Usage()
+		return &cfg, 1
+	}
+	return &cfg, 0
+}
+
+func (c *DiffCommand) RunContext(ctx context.Context, cla *DiffArgs) int {
+	oldPath := cla.OldPath
+	if cla.Against != "" {
+		ref := strings.TrimPrefix(cla.Against, "git:")
+		if ref == cla.Against || ref == "" {
+			c.Ui.Error(fmt.Sprintf("Invalid -against %q, expected git:<ref>", cla.Against))
+			return 1
+		}
+		dir, err := ioutil.TempDir("", "packer-diff")
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		defer os.RemoveAll(dir)
+		oldPath, err = checkoutConfig(ctx, ref, cla.NewPath, dir)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to get %s at %s: %s", cla.NewPath, ref, err))
+			return 1
+		}
+	}
+
+	oldCfg, ret := c.resolvedConfig(oldPath, cla)
+	if ret != 0 {
+		return ret
+	}
+	newCfg, ret := c.resolvedConfig(cla.NewPath, cla)
+	if ret != 0 {
+		return ret
+	}
+
+	changes := hcl2template.DiffConfigs(oldCfg.Entries(), newCfg.Entries())
+	if len(changes) == 0 {
+		c.Ui.Say("No changes.")
+		return 0
+	}
+	for _, change := range changes {
+		c.Ui.Say(change.String())
+	}
+	return 0
+}
+
+// resolvedConfig parses and initializes the HCL2 configuration at path.
+func (c *DiffCommand) resolvedConfig(path string, cla *DiffArgs) (*hcl2template.PackerConfig, int) {
+	args := cla.MetaArgs
+	args.Path = path
+	cfgType, err := args.GetConfigType()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("%q: %s", path, err))
+		return nil, 1
+	}
+	if cfgType != ConfigTypeHCL2 {
+		c.Ui.Error(fmt.Sprintf("%q: packer diff only supports HCL2 configurations", path))
+		return nil, 1
+	}
+	cfg, ret := c.GetConfigFromHCL(&args)
+	if ret != 0 {
+		return nil, ret
+	}
+	// like inspect, initialization diags are ignored so that unknown values
+	// can still be described; datasources are not executed.
+	_ = cfg.Initialize(packer.InitializeOptions{SkipDatasourcesExecution: true})
+	return cfg, 0
+}
+
+// checkoutConfig writes the configuration at path, as of the git ref, in dir
+// and returns its path there. Only the top level files of a directory are
+// written, as they are the only ones Packer reads.
+func checkoutConfig(ctx context.Context, ref, path, dir string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	gitDir, names := filepath.Dir(path), []string{filepath.Base(path)}
+	if fi.IsDir() {
+		gitDir = path
+		out, err := git(ctx, gitDir, "ls-tree", ref, "--", ".")
+		if err != nil {
+			return "", err
+		}
+		// lines look like "<mode> <type> <object>\t<name>"
+		names = nil
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			parts := strings.SplitN(line, "\t", 2)
+			if len(parts) == 2 && strings.Contains(parts[0], " blob ") {
+				names = append(names, parts[1])
+			}
+		}
+	}
+	for _, name := range names {
+		content, err := git(ctx, gitDir, "show", ref+":./"+name)
+		if err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			return "", err
+		}
+	}
+	if fi.IsDir() {
+		return dir, nil
+	}
+	return filepath.Join(dir, names[0]), nil
+}
+
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+func (*DiffCommand) Help() string {
+	helpText := `
+Usage: packer diff [options] OLD NEW
+       packer diff [options] -against=git:REF PATH
+
+  Compares two HCL2 configurations, files or directories, once resolved, and
+  prints the changed variables and locals, source attributes, and build
+  sources, provisioners and post-processors. With -against, the configuration
+  at PATH is compared with its version at a git ref.
+
+  Lines start with + for additions, - for removals and ~ for changes.
+
+Options:
+  -against=git:REF              Compare PATH with its version at the git REF.
+  -var 'key=value'              Variable for templates, can be used multiple times.
+  -var-file=path                JSON or HCL2 file containing user variables.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*DiffCommand) Synopsis() string {
+	return "show the resolved changes between two configurations"
+}
+
+func (*DiffCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*DiffCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-against":  complete.PredictNothing,
+		"-var":      complete.PredictNothing,
+		"-var-file": complete.PredictNothing,
+	}
+}
@@ -0,0 +1,292 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer/packer"
+	"github.com/posener/complete"
+)
+
+type DocsCommand struct {
+	Meta
+}
+
+func (c *DocsCommand) Synopsis() string {
+	return "Show the configuration options of a component"
+}
+
+func (c *DocsCommand) Help() string {
+	helpText := `
+Usage: packer docs [options] [COMPONENT]
+
+  Show the configuration options of the builders, provisioners,
+  post-processors and data sources named COMPONENT, as they are read from
+  their HCL2 specification. This works without network access, for the
+  components built into Packer and for the installed plugins.
+
+  Without COMPONENT, the available components are listed.
+
+Options:
+  -kind=KIND                    Only show components of this kind: builder,
+                                provisioner, post-processor or data-source.
+  -markdown                     Output markdown instead of plain text.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *DocsCommand) Run(args []string) int {
+	ctx, cleanup := handleTermInterrupt(c.Ui)
+	defer cleanup()
+
+	cfg, ret := c.ParseArgs(args)
+	if ret != 0 {
+		return ret
+	}
+
+	return c.RunContext(ctx, cfg)
+}
+
+func (c *DocsCommand) ParseArgs(args []string) (*DocsArgs, int) {
+	var cfg DocsArgs
+	flags := c.Meta.FlagSet("docs", 0)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	cfg.AddFlagSets(flags)
+	if err := flags.Parse(args); err != nil {
+		return &cfg, 1
+	}
+
+	args = flags.Args()
+	if len(args) > 1 {
+		flags.Usage()
+		return &cfg, 1
+	}
+	if len(args) == 1 {
+		cfg.Component = args[0]
+	}
+	if cfg.Kind != "" {
+		if _, found := componentKinds[cfg.Kind]; !found {
+			c.Ui.Error(fmt.Sprintf("Invalid -kind %q, expected one of %s", cfg.Kind, strings.Join(componentKindNames(), ", ")))
+			return &cfg, 1
+		}
+	}
+	return &cfg, 0
+}
+
+// componentKind gives access to the components of a kind.
+type componentKind struct {
+	store func(*packer.PluginConfig) packer.BasicStore
+	// specs starts a component and returns its configuration spec and, for
+	// data sources, its output spec.
+	specs func(pc *packer.PluginConfig, name string) (config, output hcldec.ObjectSpec, err error)
+}
+
+var componentKinds = map[string]componentKind{
+	"builder": {
+		store: func(pc *packer.PluginConfig) packer.BasicStore { return pc.Builders },
+		specs: func(pc *packer.PluginConfig, name string) (hcldec.ObjectSpec, hcldec.ObjectSpec, error) {
+			b, err := pc.Builders.Start(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			return b.ConfigSpec(), nil, nil
+		},
+	},
+	"provisioner": {
+		store: func(pc *packer.PluginConfig) packer.BasicStore { return pc.Provisioners },
+		specs: func(pc *packer.PluginConfig, name string) (hcldec.ObjectSpec, hcldec.ObjectSpec, error) {
+			p, err := pc.Provisioners.Start(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			return p.ConfigSpec(), nil, nil
+		},
+	},
+	"post-processor": {
+		store: func(pc *packer.PluginConfig) packer.BasicStore { return pc.PostProcessors },
+		specs: func(pc *packer.PluginConfig, name string) (hcldec.ObjectSpec, hcldec.ObjectSpec, error) {
+			pp, err := pc.PostProcessors.Start(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			return pp.ConfigSpec(), nil, nil
+		},
+	},
+	"data-source": {
+		store: func(pc *packer.PluginConfig) packer.BasicStore { return pc.DataSources },
+		specs: func(pc *packer.PluginConfig, name string) (hcldec.ObjectSpec, hcldec.ObjectSpec, error) {
+			ds, err := pc.DataSources.Start(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			return ds.ConfigSpec(), ds.OutputSpec(), nil
+		},
+	},
+}
+
+func componentKindNames() []string {
+	names := make([]string, 0, len(componentKinds))
+	for name := range componentKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *DocsCommand) RunContext(_ context.Context, cla *DocsArgs) int {
+	pc := c.CoreConfig.Components.PluginConfig
+	kinds := componentKindNames()
+	if cla.Kind != "" {
+		kinds = []string{cla.Kind}
+	}
+
+	if cla.Component == "" {
+		for _, kind := range kinds {
+			names := componentKinds[kind].store(pc).List()
+			sort.Strings(names)
+			c.Ui.Say(fmt.Sprintf("%ss: %s", strings.Title(kind), strings.Join(names, ", ")))
+		}
+		return 0
+	}
+
+	found := false
+	for _, kind := range kinds {
+		if !componentKinds[kind].store(pc).Has(cla.Component) {
+			continue
+		}
+		found = true
+		config, output, err := componentKinds[kind].specs(pc, cla.Component)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to start %s %q: %s", kind, cla.Component, err))
+			return 1
+		}
+		w := &docsWriter{markdown: cla.Markdown}
+		w.heading(fmt.Sprintf("%s %q", kind, cla.Component))
+		w.section("Configuration", config)
+		if output != nil {
+			w.section("Outputs", output)
+		}
+		c.Ui.Say(strings.TrimRight(w.String(), "\n"))
+	}
+	if !found {
+		c.Ui.Error(fmt.Sprintf("No component named %q was found; run `packer docs` to list the available components.", cla.Component))
+		return 1
+	}
+	return 0
+}
+
+func (*DocsCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*DocsCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-kind":     complete.PredictSet(componentKindNames()...),
+		"-markdown": complete.PredictNothing,
+	}
+}
+
+// docsWriter renders HCL2 specs as a list of options, in plain text or in
+// markdown.
+type docsWriter struct {
+	bytes.Buffer
+	markdown bool
+}
+
+func (w *docsWriter) heading(title string) {
+	if w.markdown {
+		fmt.Fprintf(w, "# %s\n\n", title)
+		return
+	}
+	fmt.Fprintf(w, "%s\n\n", title)
+}
+
+func (w *docsWriter) section(title string, spec hcldec.ObjectSpec) {
+	if w.markdown {
+		fmt.Fprintf(w, "## %s\n\n", title)
+	} else {
+		fmt.Fprintf(w, "%s:\n\n", title)
+	}
+	w.object(spec, 0)
+	w.WriteString("\n")
+}
+
+// object writes the options of spec, sorted by name. The packer_* options
+// set by Packer itself are skipped.
+func (w *docsWriter) object(spec hcldec.ObjectSpec, depth int) {
+	names := make([]string, 0, len(spec))
+	for name := range spec {
+		if strings.HasPrefix(name, "packer_") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		w.option(depth, "(none)", "")
+	}
+	for _, name := range names {
+		w.spec(name, spec[name], depth)
+	}
+}
+
+func (w *docsWriter) option(depth int, name, description string) {
+	indent := strings.Repeat("  ", depth+1)
+	if w.markdown {
+		indent = strings.Repeat("  ", depth) + "- "
+		if name != "(none)" {
+			name = "`" + name + "`"
+		}
+	}
+	if description != "" {
+		description = " " + description
+	}
+	fmt.Fprintf(w, "%s%s%s\n", indent, name, description)
+}
+
+// spec writes the option name described by spec, and the options of its
+// nested blocks.
+func (w *docsWriter) spec(name string, spec hcldec.Spec, depth int) {
+	required := func(required bool) string {
+		if required {
+			return " - required"
+		}
+		return ""
+	}
+	switch s := spec.(type) {
+	case *hcldec.AttrSpec:
+		w.option(depth, name, fmt.Sprintf("(%s)%s", s.Type.FriendlyName(), required(s.Required)))
+	case *hcldec.DefaultSpec:
+		w.spec(name, s.Primary, depth)
+	case *hcldec.BlockSpec:
+		w.option(depth, name, "(block)"+required(s.Required))
+		w.nested(s.Nested, depth+1)
+	case *hcldec.BlockListSpec:
+		w.option(depth, name, "(repeatable block)"+required(s.MinItems > 0))
+		w.nested(s.Nested, depth+1)
+	case *hcldec.BlockSetSpec:
+		w.option(depth, name, "(repeatable block)"+required(s.MinItems > 0))
+		w.nested(s.Nested, depth+1)
+	case *hcldec.BlockMapSpec:
+		w.option(depth, name, fmt.Sprintf("(block labeled by %s)", strings.Join(s.LabelNames, ", ")))
+		w.nested(s.Nested, depth+1)
+	case *hcldec.BlockAttrsSpec:
+		w.option(depth, name, fmt.Sprintf("(block of %s attributes)%s", s.ElementType.FriendlyName(), required(s.Required)))
+	default:
+		w.option(depth, name, "")
+	}
+}
+
+func (w *docsWriter) nested(spec hcldec.Spec, depth int) {
+	switch s := spec.(type) {
+	case hcldec.ObjectSpec:
+		w.object(s, depth)
+	case *hcldec.ObjectSpec:
+		w.object(*s, depth)
+	}
+}
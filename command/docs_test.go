@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocsCommand_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantRet  int
+		contains []string
+		excludes []string
+	}{
+		{"list", nil, 0, []string{"Builders: file, null", "Provisioners: file, shell, shell-local"}, nil},
+		{"every kind", []string{"file"}, 0,
+			[]string{"builder \"file\"", "  content (string)", "provisioner \"file\"", "  sources (list of string)"},
+			[]string{"packer_build_name"}},
+		{"one kind", []string{"-kind=builder", "file"}, 0,
+			[]string{"builder \"file\""},
+			[]string{"provisioner \"file\""}},
+		{"markdown", []string{"-markdown", "-kind=provisioner", "file"}, 0,
+			[]string{"# provisioner \"file\"", "## Configuration", "- `destination` (string)"}, nil},
+		{"unknown component", []string{"happycloud"}, 1, nil, nil},
+		{"unknown kind", []string{"-kind=hook", "file"}, 1, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &DocsCommand{Meta: testMetaFile(t)}
+			if ret := c.Run(tt.args); ret != tt.wantRet {
+				fatalCommand(t, c.Meta)
+			}
+			out, _ := outputCommand(t, c.Meta)
+			for _, s := range tt.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("expected output to contain %q, got:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(out, s) {
+					t.Errorf("expected output not to contain %q, got:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
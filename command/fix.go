@@ -5,12 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/packer-plugin-sdk/template"
 	"github.com/hashicorp/packer/fix"
+	"github.com/hashicorp/packer/hcl2template"
 
 	"github.com/posener/complete"
 )
@@ -50,6 +53,10 @@ func (c *FixCommand) ParseArgs(args []string) (*FixArgs, int) {
 }
 
 func (c *FixCommand) RunContext(ctx context.Context, cla *FixArgs) int {
+	if cfgType, _ := cla.GetConfigType(); cfgType == ConfigTypeHCL2 {
+		return c.fixHCL2(cla)
+	}
+
 	// Read the file for decoding
 	tplF, err := os.Open(cla.Path)
 	if err != nil {
@@ -129,6 +136,65 @@ func (c *FixCommand) RunContext(ctx context.Context, cla *FixArgs) int {
 	return 0
 }
 
+// fixHCL2 applies the HCL2 migrations to the files of cla.Path. Migrated
+// files are printed, or shown as a diff with cla.Diff, or rewritten when
+// cla.Write is set; files in the JSON syntax are left untouched.
+func (c *FixCommand) fixHCL2(cla *FixArgs) int {
+	hclFiles, jsonFiles, diags := hcl2template.GetHCL2Files(cla.Path, ".pkr.hcl", ".pkr.json")
+	if ret := writeDiags(c.Ui, nil, diags); ret != 0 {
+		return ret
+	}
+	for _, filename := range jsonFiles {
+		c.Ui.Say(fmt.Sprintf("Skipping %s: files in the JSON syntax can't be fixed", filename))
+	}
+
+	files := map[string]*hcl.File{}
+	ret := 0
+	for _, filename := range hclFiles {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading %s: %s", filename, err))
+			ret = 1
+			continue
+		}
+		files[filename] = &hcl.File{Bytes: src}
+		fixed, diags := hcl2template.MigrateFile(filename, src)
+		if writeDiags(c.Ui, files, diags) != 0 {
+			ret = 1
+			continue
+		}
+		if bytes.Equal(fixed, src) {
+			continue
+		}
+		if cla.Diff {
+			diff, err := hcl2template.BytesDiff(src, fixed, filename)
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf("Error computing the diff of %s: %s", filename, err))
+				ret = 1
+				continue
+			}
+			c.Ui.Say(string(diff))
+		}
+		if !cla.Write {
+			if cla.Diff {
+				continue
+			}
+			if len(hclFiles) > 1 {
+				c.Ui.Say("# " + filename)
+			}
+			c.Ui.Say(string(fixed))
+			continue
+		}
+		if err := ioutil.WriteFile(filename, fixed, 0644); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error writing %s: %s", filename, err))
+			ret = 1
+			continue
+		}
+		c.Ui.Say(fmt.Sprintf("Fixed %s", filename))
+	}
+	return ret
+}
+
 func (*FixCommand) Help() string {
 	helpText := `
 Usage: packer fix [options] TEMPLATE
@@ -136,6 +202,10 @@ Usage: packer fix [options] TEMPLATE
   Reads the JSON template and attempts to fix known backwards
   incompatibilities. The fixed template will be outputted to standard out.
 
+  When TEMPLATE is an HCL2 file or directory, the HCL2 migrations below
+  are applied to its files instead. The fixed files are outputted to
+  standard out, previewed as a diff with -diff, or rewritten with -write.
+
   If the template cannot be fixed due to an error, the command will exit
   with a non-zero exit status. Error messages will appear on standard error.
 
@@ -148,10 +218,22 @@ Fixes that are run (in order):
 			"  %-27s%s\n", name, fix.Fixers[name].Synopsis())
 	}
 
+	helpText += `
+HCL2 migrations that are run (in order):
+
+`
+
+	for _, name := range hcl2template.MigrationOrder {
+		helpText += fmt.Sprintf(
+			"  %-27s%s\n", name, hcl2template.Migrations[name].Synopsis())
+	}
+
 	helpText += `
 Options:
 
+  -diff=false         If true, shows the changes to the HCL2 files as a diff.
   -validate=true      If true (default), validates the fixed template.
+  -write=false        If true, rewrites the fixed HCL2 files in place.
 `
 
 	return strings.TrimSpace(helpText)
@@ -167,6 +249,8 @@ func (c *FixCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *FixCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
+		"-diff":     complete.PredictNothing,
 		"-validate": complete.PredictNothing,
+		"-write":    complete.PredictNothing,
 	}
 }
@@ -2,16 +2,26 @@ package command
 
 import (
 	"context"
-	"crypto/sha256"
+	"crypto/ed25519"
 	"fmt"
 	"log"
-	"runtime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
-	pluginsdk "github.com/hashicorp/packer-plugin-sdk/plugin"
+	goversion "github.com/hashicorp/go-version"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
+	"github.com/hashicorp/packer/hcl2template/addrs"
 	"github.com/hashicorp/packer/packer"
 	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+	"github.com/hashicorp/packer/packer/plugin-getter/genericrepo"
 	"github.com/hashicorp/packer/packer/plugin-getter/github"
+	"github.com/hashicorp/packer/packer/plugin-getter/mirror"
+	"github.com/hashicorp/packer/packer/plugin-getter/oci"
 	"github.com/hashicorp/packer/version"
 	"github.com/posener/complete"
 )
@@ -63,36 +73,48 @@ func (c *InitCommand) RunContext(buildCtx context.Context, cla *InitArgs) int {
 		return ret
 	}
 
-	opts := plugingetter.ListInstallationsOptions{
-		FromFolders: c.Meta.CoreConfig.Components.PluginConfig.KnownPluginFolders,
-		BinaryInstallationOptions: plugingetter.BinaryInstallationOptions{
-			OS:              runtime.GOOS,
-			ARCH:            runtime.GOARCH,
-			APIVersionMajor: pluginsdk.APIVersionMajor,
-			APIVersionMinor: pluginsdk.APIVersionMinor,
-			Checksummers: []plugingetter.Checksummer{
-				{Type: "sha256", Hash: sha256.New()},
-			},
-		},
+	opts := c.Meta.listInstallationsOptions()
+	opts.Offline = cla.Offline
+
+	log.Printf("[TRACE] init: %#v", opts)
+
+	var httpCache *plugingetter.HTTPCache
+	mirrorsHealth := &mirror.HealthFile{}
+	if cla.DryRun {
+		log.Printf("[TRACE] init: dry-run, not caching http responses")
+	} else {
+		httpCache, mirrorsHealth = pluginHTTPCache()
 	}
+	defer func() {
+		if err := mirrorsHealth.Save(); err != nil {
+			log.Printf("[TRACE] init: could not save the health of mirrors: %s", err)
+		}
+	}()
 
-	if runtime.GOOS == "windows" && opts.Ext == "" {
-		opts.BinaryInstallationOptions.Ext = ".exe"
+	getters, err := pluginGetters(httpCache, mirrorsHealth)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
 	}
 
-	log.Printf("[TRACE] init: %#v", opts)
+	retryPolicy, err := getterRetryPolicy()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
 
-	getters := []plugingetter.Getter{
-		&github.Getter{
-			// In the past some terraform plugins downloads were blocked from a
-			// specific aws region by s3. Changing the user agent unblocked the
-			// downloads so having one user agent per version will help mitigate
-			// that a little more. Especially in the case someone forks this
-			// code to make it more aggressive or something.
-			// TODO: allow to set this from the config file or an environment
-			// variable.
-			UserAgent: "packer-getter-github-" + version.String(),
-		},
+	releasesKeys, err := releasesPublicKeys(getters)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// plugins whose required_plugins entry sets a getter are installed from
+	// their own mirror, or with their own signing keys.
+	sources, err := requirementSources(reqs, getters, releasesKeys, httpCache)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
 	}
 
 	ui := &packer.ColoredUi{
@@ -100,10 +122,77 @@ func (c *InitCommand) RunContext(buildCtx context.Context, cla *InitArgs) int {
 		Ui:    c.Ui,
 	}
 
+	lockPath := plugingetter.LockFilePath(cla.Path)
+	lockFile, err := plugingetter.ReadLockFile(lockPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to read lock file %s: %s", lockPath, err))
+		return 1
+	}
+	lockChanged := false
+
+	// plugins that moved are installed from their new source address, the
+	// redirects recorded in the lock file are followed unless upgrading.
+	aliases, err := plugingetter.SourceAliasesFromEnv()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	redirectOpts := plugingetter.RedirectOptions{Aliases: aliases}
+	if !cla.Upgrade {
+		redirectOpts.LockFile = lockFile
+	}
+	if !cla.Offline {
+		redirectOpts.Getters = getters
+	}
+
+	// installOptions returns the options to install pr with. Checksummers hold
+	// a hash state, so each installation gets its own.
+	installOptions := func(pr *plugingetter.Requirement) plugingetter.InstallOptions {
+		installOpts := plugingetter.InstallOptions{
+			InFolders:                 opts.FromFolders,
+			BinaryInstallationOptions: c.Meta.listInstallationsOptions().BinaryInstallationOptions,
+			Getters:                   getters,
+			ReleasesPublicKeys:        releasesKeys,
+			DryRun:                    cla.DryRun,
+			CacheDir:                  os.Getenv("PACKER_PLUGIN_CACHE_DIR"),
+			RetryPolicy:               retryPolicy,
+			ProgressTracker:           c.Ui,
+			KeepVersions:              cla.KeepVersions,
+			ExtractFiles:              plugingetter.DefaultExtractFiles,
+			Offline:                   cla.Offline,
+		}
+		if source, found := sources[pr]; found {
+			installOpts.Getters, installOpts.ReleasesPublicKeys = source.getters, source.releasesKeys
+		}
+		// plugins required with `version = "latest"` are always checked for
+		// a newer release, as if -upgrade was set for them; the lock file is
+		// then updated with the version installed.
+		if !cla.Upgrade && !pr.Latest {
+			installOpts.Locked = lockFile.Plugin(pr.Identifier)
+		}
+		return installOpts
+	}
+
+	var toInstall plugingetter.Requirements
+	// with -plan, the plugins kept are listed along with the ones to install
+	var kept []*plugingetter.PlannedAction
 	for _, pluginRequirement := range reqs {
+		reqRedirectOpts := redirectOpts
+		if source, found := sources[pluginRequirement]; found && !cla.Offline {
+			reqRedirectOpts.Getters = source.getters
+		}
+		if err := pluginRequirement.ResolveRedirects(buildCtx, reqRedirectOpts); err != nil {
+			c.Ui.Error(err.Error())
+			ret = 1
+			continue
+		}
+		if from := pluginRequirement.RedirectedFrom; from != nil {
+			ui.Say(fmt.Sprintf("Plugin %s moved to %s, consider updating its source in required_plugins", from, pluginRequirement.Identifier))
+		}
+
 		// Get installed plugins that match requirement
 
-		installs, err := pluginRequirement.ListInstallations(opts)
+		installs, err := pluginRequirement.ListInstallations(buildCtx, opts)
 		if err != nil {
 			c.Ui.Error(err.Error())
 			return 1
@@ -111,15 +200,53 @@ func (c *InitCommand) RunContext(buildCtx context.Context, cla *InitArgs) int {
 
 		log.Printf("[TRACE] for plugin %s found %d matching installation(s)", pluginRequirement.Identifier, len(installs))
 
-		if len(installs) > 0 && cla.Upgrade == false {
+		upgrade := cla.Upgrade || pluginRequirement.Latest
+		installOpts := installOptions(pluginRequirement)
+
+		if locked := installOpts.Locked; locked != nil {
+			if err := locked.Validate(pluginRequirement); err != nil {
+				c.Ui.Error(err.Error())
+				ret = 1
+				continue
+			}
+			if install := locked.Select(installs); install != nil {
+				kept = append(kept, &plugingetter.PlannedAction{
+					Requirement:  pluginRequirement,
+					Action:       plugingetter.PlanKeep,
+					Installation: install,
+					Reason:       "locked version already installed",
+				})
+				continue
+			}
+		} else if len(installs) > 0 && !upgrade {
+			// lock the version in use
+			install := installs[len(installs)-1]
+			if lockPlugin(buildCtx, lockFile, pluginRequirement, install.Version, installOpts) {
+				lockChanged = true
+			}
+			kept = append(kept, &plugingetter.PlannedAction{
+				Requirement:  pluginRequirement,
+				Action:       plugingetter.PlanKeep,
+				Installation: install,
+				Reason:       "already installed",
+			})
 			continue
 		}
 
-		newInstall, err := pluginRequirement.InstallLatest(plugingetter.InstallOptions{
-			InFolders:                 opts.FromFolders,
-			BinaryInstallationOptions: opts.BinaryInstallationOptions,
-			Getters:                   getters,
-		})
+		toInstall = append(toInstall, pluginRequirement)
+	}
+
+	if cla.DryRun {
+		if c.plan(buildCtx, ui, toInstall, installOptions, kept, lockChanged, lockPath) != 0 {
+			ret = 1
+		}
+		return ret
+	}
+
+	// Plugins are downloaded concurrently, and reported as they are
+	// installed.
+	results, _ := toInstall.InstallAll(buildCtx, cla.ParallelInstalls, installOptions, func(res plugingetter.InstallResult) {
+		pluginRequirement, newInstall, err := res.Requirement, res.Installation, res.Err
 		if err != nil {
 			if pluginRequirement.Implicit {
 				msg := fmt.Sprintf(`
@@ -164,16 +291,178 @@ packer {
 					newInstall.Version,
 				)
 				ui.Error(warn)
-				continue
+				return
 			}
 			msg := fmt.Sprintf("Installed plugin %s %s in %q", pluginRequirement.Identifier, newInstall.Version, newInstall.BinaryPath)
 			ui.Say(msg)
+		}
+	})
 
+	// the lock file is updated in the order of the requirements
+	for _, res := range results {
+		if res.Installation == nil || res.Requirement.Implicit {
+			continue
+		}
+		if lockPlugin(buildCtx, lockFile, res.Requirement, res.Installation.Version, installOptions(res.Requirement)) {
+			lockChanged = true
 		}
 	}
+
+	if lockChanged {
+		if err := lockFile.WriteFile(lockPath); err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to write lock file %s: %s", lockPath, err))
+			return 1
+		}
+		log.Printf("[TRACE] init: wrote lock file %s", lockPath)
+	}
+
+	if ret == 0 {
+		writeInstallSummary(buildCtx, reqs, opts, lockFile)
+	}
 	return ret
 }
 
+// writeInstallSummary records the binaries resolved for reqs, so that other
+// commands don't have to list installations again. Nothing is written when
+// a requirement has no usable binary.
+func writeInstallSummary(ctx context.Context, reqs plugingetter.Requirements, opts plugingetter.ListInstallationsOptions, lockFile *plugingetter.LockFile) {
+	summary := &plugingetter.InstallSummary{
+		Fingerprint: plugingetter.Fingerprint(reqs, opts, lockFile),
+		Plugins:     map[string]plugingetter.SummarizedInstallation{},
+	}
+	for _, pr := range reqs {
+		installs, err := pr.ListInstallations(ctx, opts)
+		if err != nil || len(installs) == 0 {
+			log.Printf("[TRACE] init: not writing install summary, no installation of %s", pr.Identifier)
+			return
+		}
+		install := installs[len(installs)-1]
+		if locked := lockFile.Plugin(pr.Identifier); locked != nil {
+			if install = locked.Select(installs); install == nil {
+				log.Printf("[TRACE] init: not writing install summary, %s %s is not installed", pr.Identifier, locked.Version)
+				return
+			}
+		}
+		summarized, err := plugingetter.Summarize(pr, install)
+		if err != nil {
+			log.Printf("[TRACE] init: not writing install summary: %s", err)
+			return
+		}
+		summary.Plugins[pr.Accessor] = summarized
+	}
+	if err := installSummaries().Put(summary); err != nil {
+		log.Printf("[WARN] init: could not write install summary: %s", err)
+	}
+}
+
+// lockPlugin records version v of the plugin required by pr in lockFile,
+// along with the checksums of its release. It returns whether the lock file
+// changed.
+func lockPlugin(ctx context.Context, lockFile *plugingetter.LockFile, pr *plugingetter.Requirement, v string, opts plugingetter.InstallOptions) bool {
+	parsed, err := goversion.NewVersion(v)
+	if err != nil {
+		log.Printf("[WARN] init: not locking %s: %s", pr.Identifier, err)
+		return false
+	}
+	locked := &plugingetter.LockedPlugin{
+		Identifier:     pr.Identifier,
+		Version:        parsed,
+		Constraints:    pr.VersionConstraints.String(),
+		RedirectedFrom: pr.RedirectedFrom,
+	}
+	// the plugin is only locked at its new source address once it moved.
+	unlocked := pr.RedirectedFrom != nil && lockFile.Unlock(pr.RedirectedFrom)
+	if existing := lockFile.Plugin(pr.Identifier); existing != nil && existing.Version.String() == parsed.String() {
+		if existing.Constraints == locked.Constraints && sameSource(existing.RedirectedFrom, locked.RedirectedFrom) {
+			return unlocked
+		}
+		locked.Hashes = existing.Hashes
+	} else {
+		locked.Hashes, err = pr.ReleaseHashes(ctx, parsed, opts)
+		if err != nil {
+			log.Printf("[WARN] init: locking %s without checksums: %s", pr.Identifier, err)
+		}
+	}
+	lockFile.Lock(locked)
+	return true
+}
+
+// sameSource tells whether a and b are the same source address, or both nil.
+func sameSource(a, b *addrs.Plugin) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+// plan prints what init would do: the plugins kept, the plugins of toInstall
+// that would be installed, and whether the lock file would change. Nothing is
+// downloaded or written. The installations are planned one after the other,
+// so that they are always listed in the same order.
+func (c *InitCommand) plan(ctx context.Context, ui packersdk.Ui, toInstall plugingetter.Requirements,
+	installOptions func(*plugingetter.Requirement) plugingetter.InstallOptions,
+	kept []*plugingetter.PlannedAction, lockChanged bool, lockPath string) int {
+
+	ret := 0
+	actions := kept
+	for _, pr := range toInstall {
+		action, err := pr.Plan(ctx, installOptions(pr))
+		if err != nil {
+			if pr.Implicit {
+				ui.Say(fmt.Sprintf("Would not install the implicitly required plugin %s: %s", pr.Identifier, err))
+				continue
+			}
+			c.Ui.Error(fmt.Sprintf("%s: %s", pr.Identifier, err))
+			ret = 1
+			continue
+		}
+		// installed plugins are locked, but the implicitly required ones.
+		if action.Action == plugingetter.PlanInstall && !pr.Implicit {
+			lockChanged = true
+		}
+		actions = append(actions, action)
+	}
+
+	installs := 0
+	for _, action := range actions {
+		ui.Say(formatPlannedAction(action))
+		if action.Action == plugingetter.PlanInstall {
+			installs++
+		}
+	}
+	if lockChanged {
+		ui.Say(fmt.Sprintf("Would update the lock file %s", lockPath))
+	}
+	ui.Say(fmt.Sprintf("Plan: %d plugin(s) to install, %d unchanged.", installs, len(actions)-installs))
+	return ret
+}
+
+// formatPlannedAction describes what installing a plugin would do.
+func formatPlannedAction(action *plugingetter.PlannedAction) string {
+	if action.Action != plugingetter.PlanInstall {
+		return "Would " + action.String()
+	}
+	install := action.Installation
+	source := install.Planned.SourceURL
+	if source == "" {
+		source = "unknown"
+	}
+	size := "unknown"
+	if install.Planned.Size >= 0 {
+		size = fmt.Sprintf("%d bytes", install.Planned.Size)
+	}
+	checksum := install.Planned.Checksum
+	if checksum == "" {
+		checksum = "unknown"
+	}
+	msg := fmt.Sprintf("Would install plugin %s %s\n  source:      %s\n  size:        %s\n  checksum:    %s\n  destination: %s",
+		action.Requirement.Identifier, install.Version, source, size, checksum, install.BinaryPath)
+	for _, path := range install.Planned.Writes {
+		msg += fmt.Sprintf("\n  writes:      %s", path)
+	}
+	return msg
+}
+
 func (*InitCommand) Help() string {
 	helpText := `
 Usage: packer init [options] [config.pkr.hcl|folder/]
@@ -185,7 +474,8 @@ Usage: packer init [options] [config.pkr.hcl|folder/]
   or existing template.
 
   This command is always safe to run multiple times. Though subsequent runs may
-  give errors, this command will never delete anything.
+  give errors, this command will never delete anything, unless -keep-versions
+  is set.
 
 Options:
   -upgrade                     On top of installing missing plugins, update
@@ -193,6 +483,23 @@ Options:
                                version, if there is a new higher one. Note that
                                this still takes into consideration the version
                                constraint of the config.
+  -plan                        Resolve the plugins to install and print what
+                               would change: the plugins kept, the version,
+                               checksum and source of the plugins that would
+                               be downloaded, the files that would be
+                               written, and whether the lock file would be
+                               updated. Nothing is downloaded or written.
+  -dry-run                     Same as -plan.
+  -parallel-installs=4         Number of plugins to download and install at
+                               the same time. Defaults to 4.
+  -keep-versions=N             Once a version of a plugin is installed,
+                               remove its older versions from the plugin
+                               directory, but the N highest ones, including
+                               the installed one.
+  -offline                     Never download plugins, fail right away when a
+                               required plugin, or its locked version, is not
+                               installed. For environments without network
+                               access.
 `
 
 	return strings.TrimSpace(helpText)
@@ -208,6 +515,391 @@ func (*InitCommand) AutocompleteArgs() complete.Predictor {
 
 func (*InitCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-upgrade": complete.PredictNothing,
+		"-upgrade":           complete.PredictNothing,
+		"-plan":              complete.PredictNothing,
+		"-dry-run":           complete.PredictNothing,
+		"-parallel-installs": complete.PredictNothing,
+		"-keep-versions":     complete.PredictNothing,
+		"-offline":           complete.PredictNothing,
+	}
+}
+
+// pluginHTTPCache returns the cache of the http responses of getters and the
+// health of the network mirrors, both kept in the Packer config directory.
+// No response is cached when the config directory can't be found.
+func pluginHTTPCache() (*plugingetter.HTTPCache, *mirror.HealthFile) {
+	mirrorsHealth := &mirror.HealthFile{}
+	configDir, err := pathing.ConfigDir()
+	if err != nil {
+		log.Printf("[TRACE] not caching http responses: %s", err)
+		return nil, mirrorsHealth
+	}
+	mirrorsHealth.Path = filepath.Join(configDir, "plugin_mirrors.json")
+	return &plugingetter.HTTPCache{Dir: filepath.Join(configDir, "http_cache")}, mirrorsHealth
+}
+
+// pluginGetters returns the getters plugins are installed from, as set by the
+// PACKER_PLUGIN_* env vars: GitHub, the https hosts, the Artifactory and
+// Nexus repositories and the OCI registries, or only the network mirrors
+// when set. The filesystem mirror, when set, comes first.
+func pluginGetters(httpCache *plugingetter.HTTPCache, mirrorsHealth *mirror.HealthFile) ([]plugingetter.Getter, error) {
+	timeouts, err := getterTimeouts()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := getterCredentials()
+
+	enterprise, err := githubEnterpriseHosts(os.Getenv("PACKER_GITHUB_ENTERPRISE_HOSTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	getters := []plugingetter.Getter{
+		&plugingetter.CircuitBreaker{Getter: &github.Getter{
+			// In the past some terraform plugins downloads were blocked from a
+			// specific aws region by s3. Changing the user agent unblocked the
+			// downloads so having one user agent per version will help mitigate
+			// that a little more. Especially in the case someone forks this
+			// code to make it more aggressive or something.
+			// TODO: allow to set this from the config file or an environment
+			// variable.
+			UserAgent:   "packer-getter-github-" + version.String(),
+			Cache:       httpCache,
+			Timeouts:    timeouts,
+			Credentials: credentials,
+			Enterprise:  enterprise,
+		}},
+	}
+
+	hosts, err := pluginHosts(os.Getenv("PACKER_PLUGIN_HTTPS_HOSTS"), httpCache, timeouts, credentials)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		getters = append(getters, &plugingetter.CircuitBreaker{Getter: host})
+	}
+	for _, r := range []struct {
+		env  string
+		kind genericrepo.Kind
+	}{
+		{"PACKER_PLUGIN_ARTIFACTORY_HOSTS", genericrepo.Artifactory},
+		{"PACKER_PLUGIN_NEXUS_HOSTS", genericrepo.Nexus},
+	} {
+		repos, err := repositoryHosts(r.env, r.kind, timeouts, credentials)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			getters = append(getters, &plugingetter.CircuitBreaker{Getter: repo})
+		}
+	}
+	for _, registry := range strings.Split(os.Getenv("PACKER_PLUGIN_OCI_REGISTRIES"), ",") {
+		if registry = strings.TrimSpace(registry); registry == "" {
+			continue
+		}
+		if strings.ContainsAny(registry, "/:") {
+			return nil, fmt.Errorf("Invalid PACKER_PLUGIN_OCI_REGISTRIES %q: expected a hostname, like registry.example.com", registry)
+		}
+		getters = append(getters, &plugingetter.CircuitBreaker{Getter: &oci.Getter{
+			Hostname:    registry,
+			UserAgent:   "packer-getter-oci-" + version.String(),
+			Cache:       httpCache,
+			Timeouts:    timeouts,
+			Credentials: credentials,
+		}})
+	}
+
+	if mirrors := os.Getenv("PACKER_PLUGIN_NETWORK_MIRROR"); mirrors != "" {
+		pool, err := networkMirrors(mirrors, httpCache, timeouts, credentials)
+		if err != nil {
+			return nil, err
+		}
+		pool.Health = mirrorsHealth
+		// plugins are only fetched from the mirrors, as they are usually set
+		// when the default release source can't be reached.
+		getters = []plugingetter.Getter{&plugingetter.CircuitBreaker{Getter: pool}}
+	}
+	if mirrorDir := os.Getenv("PACKER_PLUGIN_FILESYSTEM_MIRROR"); mirrorDir != "" {
+		// the filesystem mirror comes first, so that plugins found there are
+		// installed without any network access.
+		getters = append([]plugingetter.Getter{&mirror.FilesystemGetter{Dir: mirrorDir}}, getters...)
+	}
+	return getters, nil
+}
+
+// releasesPublicKeys reads the keys signing the releases of plugins from the
+// PACKER_PLUGIN_RELEASES_PUBLIC_KEYS env var. An error is returned when keys
+// are set but none of getters can serve a releases signature.
+func releasesPublicKeys(getters []plugingetter.Getter) ([]ed25519.PublicKey, error) {
+	keys, err := plugingetter.ParsePublicKeys(os.Getenv("PACKER_PLUGIN_RELEASES_PUBLIC_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid PACKER_PLUGIN_RELEASES_PUBLIC_KEYS: %s", err)
+	}
+	if len(keys) > 0 && !plugingetter.ServeReleasesSignature(getters) {
+		return nil, fmt.Errorf("PACKER_PLUGIN_RELEASES_PUBLIC_KEYS is set, but none of the plugin sources can serve a releases signature. " +
+			"Signed releases can only be installed from network mirrors, filesystem mirrors and PACKER_PLUGIN_HTTPS_HOSTS; GitHub and OCI registries don't serve signatures.")
+	}
+	return keys, nil
+}
+
+// requirementSource is where a plugin whose required_plugins entry sets a
+// getter is installed from.
+type requirementSource struct {
+	getters      []plugingetter.Getter
+	releasesKeys []ed25519.PublicKey
+}
+
+// requirementSources returns the sources of the plugins of reqs whose
+// required_plugins entry sets a getter. Its mirror replaces getters, the
+// filesystem mirror still coming first when set; and its releases public keys
+// replace releasesKeys.
+func requirementSources(reqs plugingetter.Requirements, getters []plugingetter.Getter, releasesKeys []ed25519.PublicKey, httpCache *plugingetter.HTTPCache) (map[*plugingetter.Requirement]requirementSource, error) {
+	timeouts, err := getterTimeouts()
+	if err != nil {
+		return nil, err
+	}
+	sources := map[*plugingetter.Requirement]requirementSource{}
+	for _, pr := range reqs {
+		config := pr.Getter
+		if config == nil {
+			continue
+		}
+		source := requirementSource{getters: getters, releasesKeys: releasesKeys}
+		if config.MirrorURL != "" {
+			u, err := url.Parse(config.MirrorURL)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid mirror of the %s plugin: %s", pr.Identifier, err)
+			}
+			var credentials plugingetter.CredentialsSource = getterCredentials()
+			if config.TokenEnv != "" {
+				credentials = plugingetter.CredentialsChain{
+					&plugingetter.TokenEnvCredentials{Host: u.Hostname(), Env: config.TokenEnv},
+					credentials,
+				}
+			}
+			source.getters = []plugingetter.Getter{&plugingetter.CircuitBreaker{Getter: &mirror.Getter{
+				BaseURL:     u.String(),
+				UserAgent:   "packer-getter-mirror-" + version.String(),
+				Cache:       httpCache,
+				Timeouts:    timeouts,
+				Credentials: credentials,
+			}}}
+			if mirrorDir := os.Getenv("PACKER_PLUGIN_FILESYSTEM_MIRROR"); mirrorDir != "" {
+				source.getters = append([]plugingetter.Getter{&mirror.FilesystemGetter{Dir: mirrorDir}}, source.getters...)
+			}
+		}
+		if len(config.ReleasesPublicKeys) > 0 {
+			if !plugingetter.ServeReleasesSignature(source.getters) {
+				return nil, fmt.Errorf("The getter of the %s plugin sets releases_public_keys, but none of its sources can serve a releases signature. "+
+					"Set the mirror of its getter, or use network mirrors, filesystem mirrors or PACKER_PLUGIN_HTTPS_HOSTS.", pr.Identifier)
+			}
+			source.releasesKeys = config.ReleasesPublicKeys
+		}
+		log.Printf("[TRACE] init: installing %s from %v", pr.Identifier, source.getters)
+		sources[pr] = source
+	}
+	return sources, nil
+}
+
+// getterTimeouts reads the timeouts of getters from the
+// PACKER_PLUGIN_CONNECT_TIMEOUT and PACKER_PLUGIN_READ_TIMEOUT env vars.
+func getterTimeouts() (plugingetter.Timeouts, error) {
+	timeouts := plugingetter.Timeouts{}
+	for env, d := range map[string]*time.Duration{
+		"PACKER_PLUGIN_CONNECT_TIMEOUT": &timeouts.Connect,
+		"PACKER_PLUGIN_READ_TIMEOUT":    &timeouts.Read,
+	} {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return timeouts, fmt.Errorf("Invalid %s duration %q: %s", env, v, err)
+		}
+		*d = parsed
+	}
+	return timeouts, nil
+}
+
+// getterCredentials returns the credentials of plugin hosts, from the most
+// specific source to the least: a PACKER_PLUGIN_TOKEN_<host> env var, the
+// PACKER_PLUGIN_CREDENTIALS_HELPER executable, then the netrc file.
+func getterCredentials() plugingetter.CredentialsSource {
+	chain := plugingetter.CredentialsChain{plugingetter.EnvCredentials{}}
+	if helper := os.Getenv("PACKER_PLUGIN_CREDENTIALS_HELPER"); helper != "" {
+		chain = append(chain, &plugingetter.HelperCredentials{Command: helper})
+	}
+	return append(chain, &plugingetter.NetrcCredentials{Path: plugingetter.DefaultNetrcPath()})
+}
+
+// defaultGetterMaxAttempts is the number of times a getter call failing with
+// a transient error is made, when PACKER_PLUGIN_MAX_ATTEMPTS is not set.
+const defaultGetterMaxAttempts = 3
+
+// getterRetryPolicy reads the retry policy of getters from the
+// PACKER_PLUGIN_MAX_ATTEMPTS, PACKER_PLUGIN_RETRY_BACKOFF and
+// PACKER_PLUGIN_MAX_RETRY_AFTER env vars.
+func getterRetryPolicy() (*plugingetter.RetryPolicy, error) {
+	policy := &plugingetter.RetryPolicy{MaxAttempts: defaultGetterMaxAttempts}
+	if v := os.Getenv("PACKER_PLUGIN_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil || attempts < 1 {
+			return nil, fmt.Errorf("Invalid PACKER_PLUGIN_MAX_ATTEMPTS %q, expected a positive number", v)
+		}
+		policy.MaxAttempts = attempts
+	}
+	if v := os.Getenv("PACKER_PLUGIN_RETRY_BACKOFF"); v != "" {
+		backoff, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid PACKER_PLUGIN_RETRY_BACKOFF duration %q: %s", v, err)
+		}
+		policy.Backoff = backoff
+	}
+	if v := os.Getenv("PACKER_PLUGIN_MAX_RETRY_AFTER"); v != "" {
+		maxRetryAfter, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid PACKER_PLUGIN_MAX_RETRY_AFTER duration %q: %s", v, err)
+		}
+		policy.MaxRetryAfter = maxRetryAfter
+	}
+	return policy, nil
+}
+
+// githubEnterpriseHosts parses the comma separated list of GitHub Enterprise
+// instances of PACKER_GITHUB_ENTERPRISE_HOSTS. Each entry is a hostname,
+// optionally followed by the base URL of its API, like
+// ghe.example.com=https://api.ghe.example.com/.
+func githubEnterpriseHosts(value string) (map[string]string, error) {
+	hosts := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		hostname, apiURL := entry, ""
+		if i := strings.Index(entry, "="); i >= 0 {
+			hostname, apiURL = entry[:i], entry[i+1:]
+			u, err := url.Parse(apiURL)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return nil, fmt.Errorf("Invalid PACKER_GITHUB_ENTERPRISE_HOSTS %q: expected %q to be an https URL", entry, apiURL)
+			}
+		}
+		if hostname == "" || strings.ContainsAny(hostname, "/:") {
+			return nil, fmt.Errorf("Invalid PACKER_GITHUB_ENTERPRISE_HOSTS %q: expected a hostname, like ghe.example.com", entry)
+		}
+		hosts[hostname] = apiURL
+	}
+	return hosts, nil
+}
+
+// pluginHosts parses the comma separated list of plugin hosts of
+// PACKER_PLUGIN_HTTPS_HOSTS, which serve their plugins from a plain HTTPS
+// server. Each entry is a hostname, optionally followed by the URL plugins are
+// laid out at, like plugins.example.com=https://artifactory.example.com/packer/.
+// The URL defaults to https://<hostname>/.
+func pluginHosts(value string, cache *plugingetter.HTTPCache, timeouts plugingetter.Timeouts, credentials plugingetter.CredentialsSource) ([]*mirror.Getter, error) {
+	var hosts []*mirror.Getter
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		hostname, baseURL := entry, "https://"+entry+"/"
+		if i := strings.Index(entry, "="); i >= 0 {
+			hostname, baseURL = entry[:i], entry[i+1:]
+		}
+		if hostname == "" || strings.ContainsAny(hostname, "/:") {
+			return nil, fmt.Errorf("Invalid PACKER_PLUGIN_HTTPS_HOSTS %q: expected a hostname, like plugins.example.com", entry)
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("Invalid PACKER_PLUGIN_HTTPS_HOSTS %q: expected %q to be an http or https URL", entry, baseURL)
+		}
+		hosts = append(hosts, &mirror.Getter{
+			BaseURL:     u.String(),
+			Hostname:    hostname,
+			UserAgent:   "packer-getter-https-" + version.String(),
+			Cache:       cache,
+			Timeouts:    timeouts,
+			Credentials: credentials,
+		})
+	}
+	return hosts, nil
+}
+
+// repositoryHosts returns the getters of the Artifactory or Nexus
+// repositories set in the env env var: a comma separated list of
+// <hostname>=<repository URL>, like
+// plugins.example.com=https://artifactory.example.com/artifactory/packer-plugins.
+func repositoryHosts(env string, kind genericrepo.Kind, timeouts plugingetter.Timeouts, credentials plugingetter.CredentialsSource) ([]*genericrepo.Getter, error) {
+	var repos []*genericrepo.Getter
+	for _, entry := range strings.Split(os.Getenv(env), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.Index(entry, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid %s %q: expected <hostname>=<repository URL>", env, entry)
+		}
+		hostname, repositoryURL := entry[:i], entry[i+1:]
+		if hostname == "" || strings.ContainsAny(hostname, "/:") {
+			return nil, fmt.Errorf("Invalid %s %q: expected a hostname, like plugins.example.com", env, entry)
+		}
+		u, err := url.Parse(repositoryURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return nil, fmt.Errorf("Invalid %s %q: expected %q to be the http or https URL of a repository", env, entry, repositoryURL)
+		}
+		repos = append(repos, &genericrepo.Getter{
+			Hostname:      hostname,
+			Kind:          kind,
+			RepositoryURL: u.String(),
+			UserAgent:     "packer-getter-" + string(kind) + "-" + version.String(),
+			Timeouts:      timeouts,
+			Credentials:   credentials,
+		})
+	}
+	return repos, nil
+}
+
+// networkMirrors returns a pool of the plugin network mirrors set in the
+// PACKER_PLUGIN_NETWORK_MIRROR env var: a comma separated list of http(s)
+// URLs, each optionally followed by ";priority=<n>".
+func networkMirrors(value string, cache *plugingetter.HTTPCache, timeouts plugingetter.Timeouts, credentials plugingetter.CredentialsSource) (*mirror.Pool, error) {
+	pool := &mirror.Pool{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		m := &mirror.Mirror{}
+		mirrorURL := entry
+		if i := strings.Index(entry, ";"); i >= 0 {
+			mirrorURL = entry[:i]
+			param := strings.TrimSpace(entry[i+1:])
+			priority, err := strconv.Atoi(strings.TrimPrefix(param, "priority="))
+			if err != nil || !strings.HasPrefix(param, "priority=") {
+				return nil, fmt.Errorf("Invalid PACKER_PLUGIN_NETWORK_MIRROR %q: expected %q to be like priority=<n>", entry, param)
+			}
+			m.Priority = priority
+		}
+		u, err := url.Parse(mirrorURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("Invalid PACKER_PLUGIN_NETWORK_MIRROR %q: expected an http or https URL", mirrorURL)
+		}
+		m.Getter = &mirror.Getter{
+			BaseURL:     u.String(),
+			UserAgent:   "packer-getter-mirror-" + version.String(),
+			Cache:       cache,
+			Timeouts:    timeouts,
+			Credentials: credentials,
+		}
+		pool.Mirrors = append(pool.Mirrors, m)
+	}
+	if len(pool.Mirrors) == 0 {
+		return nil, fmt.Errorf("Invalid PACKER_PLUGIN_NETWORK_MIRROR %q: no mirror set", value)
 	}
+	return pool, nil
 }
@@ -17,11 +17,18 @@ import (
 	nullbuilder "github.com/hashicorp/packer/builder/null"
 	oneandonebuilder "github.com/hashicorp/packer/builder/oneandone"
 	profitbricksbuilder "github.com/hashicorp/packer/builder/profitbricks"
+	packerartifactdatasource "github.com/hashicorp/packer/datasource/packer-artifact"
+	timedatasource "github.com/hashicorp/packer/datasource/time"
+	tlsprivatekeydatasource "github.com/hashicorp/packer/datasource/tls-private-key"
+	tlsselfsignedcertdatasource "github.com/hashicorp/packer/datasource/tls-self-signed-cert"
 	artificepostprocessor "github.com/hashicorp/packer/post-processor/artifice"
 	checksumpostprocessor "github.com/hashicorp/packer/post-processor/checksum"
 	compresspostprocessor "github.com/hashicorp/packer/post-processor/compress"
+	diskconvertpostprocessor "github.com/hashicorp/packer/post-processor/disk-convert"
 	manifestpostprocessor "github.com/hashicorp/packer/post-processor/manifest"
+	ovfpostprocessor "github.com/hashicorp/packer/post-processor/ovf"
 	shelllocalpostprocessor "github.com/hashicorp/packer/post-processor/shell-local"
+	signaturepostprocessor "github.com/hashicorp/packer/post-processor/signature"
 	breakpointprovisioner "github.com/hashicorp/packer/provisioner/breakpoint"
 	fileprovisioner "github.com/hashicorp/packer/provisioner/file"
 	inspecprovisioner "github.com/hashicorp/packer/provisioner/inspec"
@@ -30,6 +37,7 @@ import (
 	shellprovisioner "github.com/hashicorp/packer/provisioner/shell"
 	shelllocalprovisioner "github.com/hashicorp/packer/provisioner/shell-local"
 	sleepprovisioner "github.com/hashicorp/packer/provisioner/sleep"
+	waitforprovisioner "github.com/hashicorp/packer/provisioner/wait-for"
 	windowsrestartprovisioner "github.com/hashicorp/packer/provisioner/windows-restart"
 	windowsshellprovisioner "github.com/hashicorp/packer/provisioner/windows-shell"
 )
@@ -54,19 +62,28 @@ var Provisioners = map[string]packersdk.Provisioner{
 	"shell":           new(shellprovisioner.Provisioner),
 	"shell-local":     new(shelllocalprovisioner.Provisioner),
 	"sleep":           new(sleepprovisioner.Provisioner),
+	"wait-for":        new(waitforprovisioner.Provisioner),
 	"windows-restart": new(windowsrestartprovisioner.Provisioner),
 	"windows-shell":   new(windowsshellprovisioner.Provisioner),
 }
 
 var PostProcessors = map[string]packersdk.PostProcessor{
-	"artifice":    new(artificepostprocessor.PostProcessor),
-	"checksum":    new(checksumpostprocessor.PostProcessor),
-	"compress":    new(compresspostprocessor.PostProcessor),
-	"manifest":    new(manifestpostprocessor.PostProcessor),
-	"shell-local": new(shelllocalpostprocessor.PostProcessor),
+	"artifice":     new(artificepostprocessor.PostProcessor),
+	"checksum":     new(checksumpostprocessor.PostProcessor),
+	"compress":     new(compresspostprocessor.PostProcessor),
+	"disk-convert": new(diskconvertpostprocessor.PostProcessor),
+	"manifest":     new(manifestpostprocessor.PostProcessor),
+	"ovf":          new(ovfpostprocessor.PostProcessor),
+	"shell-local":  new(shelllocalpostprocessor.PostProcessor),
+	"signature":    new(signaturepostprocessor.PostProcessor),
 }
 
-var Datasources = map[string]packersdk.Datasource{}
+var Datasources = map[string]packersdk.Datasource{
+	"packer-artifact":      new(packerartifactdatasource.Datasource),
+	"time":                 new(timedatasource.Datasource),
+	"tls-private-key":      new(tlsprivatekeydatasource.Datasource),
+	"tls-self-signed-cert": new(tlsselfsignedcertdatasource.Datasource),
+}
 
 var pluginRegexp = regexp.MustCompile("packer-(builder|post-processor|provisioner|datasource)-(.+)")
 
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
+	pluginsdk "github.com/hashicorp/packer-plugin-sdk/plugin"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+	"github.com/mitchellh/cli"
+)
+
+type PluginsCommand struct {
+	Meta
+}
+
+func (c *PluginsCommand) Synopsis() string {
+	return "Interact with Packer plugins and catalog"
+}
+
+func (c *PluginsCommand) Help() string {
+	helpText := `
+Usage: packer plugins <subcommand> [options] [args]
+  This command groups subcommands for interacting with Packer plugins.
+
+Related but not under the "plugins" command :
+
+- "packer init <path>" will install all plugins required by a config.
+
+Subcommands:
+  doctor      Check the installed plugins for problems.
+  install     Install a plugin.
+  remove      Remove installed plugins.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PluginsCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// listInstallationsOptions returns the options used to list and install
+// plugins for the running Packer.
+func (m *Meta) listInstallationsOptions() plugingetter.ListInstallationsOptions {
+	opts := plugingetter.ListInstallationsOptions{
+		FromFolders: m.CoreConfig.Components.PluginConfig.KnownPluginFolders,
+		BinaryInstallationOptions: plugingetter.BinaryInstallationOptions{
+			OS:              runtime.GOOS,
+			ARCH:            runtime.GOARCH,
+			APIVersionMajor: pluginsdk.APIVersionMajor,
+			APIVersionMinor: pluginsdk.APIVersionMinor,
+			Checksummers:    plugingetter.DefaultChecksummers(),
+		},
+	}
+
+	if runtime.GOOS == "windows" && opts.Ext == "" {
+		opts.BinaryInstallationOptions.Ext = ".exe"
+	}
+	return opts
+}
+
+// installSummaries returns the cache of the install summaries written by
+// packer init, nil when the Packer config directory can't be found.
+func installSummaries() *plugingetter.InstallSummaryCache {
+	configDir, err := pathing.ConfigDir()
+	if err != nil {
+		log.Printf("[TRACE] not using install summaries: %s", err)
+		return nil
+	}
+	return &plugingetter.InstallSummaryCache{Dir: filepath.Join(configDir, plugingetter.InstallSummaryDirName)}
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+	"github.com/posener/complete"
+)
+
+type PluginsDoctorCommand struct {
+	Meta
+}
+
+func (c *PluginsDoctorCommand) Synopsis() string {
+	return "Check the installed plugins for problems"
+}
+
+func (c *PluginsDoctorCommand) Help() string {
+	helpText := `
+Usage: packer plugins doctor [options]
+
+  Check the plugin directories for orphaned checksum files, binaries with
+  names Packer can't parse, binaries without or with a wrong checksum,
+  binaries using an incompatible protocol version, versions installed in more
+  than one directory and binaries that are not executable. A fix is suggested
+  for each problem found.
+
+Options:
+  -fix                          Apply the fixes, when possible.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PluginsDoctorCommand) Run(args []string) int {
+	ctx, cleanup := handleTermInterrupt(c.Ui)
+	defer cleanup()
+
+	cfg, ret := c.ParseArgs(args)
+	if ret != 0 {
+		return ret
+	}
+
+	return c.RunContext(ctx, cfg)
+}
+
+func (c *PluginsDoctorCommand) ParseArgs(args []string) (*PluginsDoctorArgs, int) {
+	var cfg PluginsDoctorArgs
+	flags := c.Meta.FlagSet("plugins doctor", 0)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	cfg.AddFlagSets(flags)
+	if err := flags.Parse(args); err != nil {
+		return &cfg, 1
+	}
+
+	if len(flags.Args()) != 0 {
+		flags.Usage()
+		return &cfg, 1
+	}
+	return &cfg, 0
+}
+
+func (c *PluginsDoctorCommand) RunContext(_ context.Context, cla *PluginsDoctorArgs) int {
+	opts := c.Meta.listInstallationsOptions()
+	problems, err := plugingetter.Diagnose(opts)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if len(problems) == 0 {
+		c.Ui.Say(fmt.Sprintf("No problem found in %s.", strings.Join(opts.FromFolders, ", ")))
+		return 0
+	}
+
+	ret := 0
+	for _, problem := range problems {
+		msg := fmt.Sprintf("%s %s.", problem.Path, problem.Description)
+		if !cla.Fix || !problem.Fixable() {
+			c.Ui.Error(msg)
+			c.Ui.Say(fmt.Sprintf("  Fix: %s.", problem.Fix))
+			ret = 1
+			continue
+		}
+		if err := problem.Apply(); err != nil {
+			c.Ui.Error(msg)
+			c.Ui.Error(fmt.Sprintf("  Could not %s: %s", problem.Fix, err))
+			ret = 1
+			continue
+		}
+		c.Ui.Say(msg)
+		c.Ui.Say(fmt.Sprintf("  Fixed: %s.", problem.Fix))
+	}
+	return ret
+}
+
+func (*PluginsDoctorCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*PluginsDoctorCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-fix": complete.PredictNothing,
+	}
+}
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+	"github.com/hashicorp/packer/packer"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+	"github.com/posener/complete"
+)
+
+type PluginsInstallCommand struct {
+	Meta
+}
+
+func (c *PluginsInstallCommand) Synopsis() string {
+	return "Install a plugin"
+}
+
+func (c *PluginsInstallCommand) Help() string {
+	helpText := `
+Usage: packer plugins install <plugin> [<version>]
+
+  Install a plugin, without a config file requiring it. The latest release of
+  the plugin is installed, unless a version is given. The version is then
+  installed whatever the installed versions, so that a plugin can be
+  downgraded.
+
+  Ex: packer plugins install github.com/hashicorp/happycloud v1.2.3
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PluginsInstallCommand) Run(args []string) int {
+	ctx, cleanup := handleTermInterrupt(c.Ui)
+	defer cleanup()
+
+	cfg, ret := c.ParseArgs(args)
+	if ret != 0 {
+		return ret
+	}
+
+	return c.RunContext(ctx, cfg)
+}
+
+func (c *PluginsInstallCommand) ParseArgs(args []string) (*PluginsInstallArgs, int) {
+	var cfg PluginsInstallArgs
+	flags := c.Meta.FlagSet("plugins install", 0)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return &cfg, 1
+	}
+
+	args = flags.Args()
+	if len(args) < 1 || len(args) > 2 {
+		flags.Usage()
+		return &cfg, 1
+	}
+	cfg.Plugin = args[0]
+	if len(args) == 2 {
+		cfg.Version = args[1]
+	}
+	return &cfg, 0
+}
+
+func (c *PluginsInstallCommand) RunContext(ctx context.Context, cla *PluginsInstallArgs) int {
+	identifier, diags := addrs.ParsePluginSourceString(cla.Plugin)
+	if diags.HasErrors() {
+		c.Ui.Error(diags.Error())
+		return 1
+	}
+	pr := &plugingetter.Requirement{Identifier: identifier}
+
+	httpCache, mirrorsHealth := pluginHTTPCache()
+	defer func() {
+		if err := mirrorsHealth.Save(); err != nil {
+			log.Printf("[TRACE] plugins install: could not save the health of mirrors: %s", err)
+		}
+	}()
+
+	getters, err := pluginGetters(httpCache, mirrorsHealth)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	retryPolicy, err := getterRetryPolicy()
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	releasesKeys, err := releasesPublicKeys(getters)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	opts := c.Meta.listInstallationsOptions()
+	installOpts := plugingetter.InstallOptions{
+		InFolders:                 opts.FromFolders,
+		BinaryInstallationOptions: opts.BinaryInstallationOptions,
+		Getters:                   getters,
+		ReleasesPublicKeys:        releasesKeys,
+		CacheDir:                  os.Getenv("PACKER_PLUGIN_CACHE_DIR"),
+		RetryPolicy:               retryPolicy,
+		ProgressTracker:           c.Ui,
+		ExtractFiles:              plugingetter.DefaultExtractFiles,
+	}
+
+	var install *plugingetter.Installation
+	if cla.Version == "" {
+		install, err = pr.InstallLatest(ctx, installOpts)
+	} else {
+		v, parseErr := version.NewVersion(cla.Version)
+		if parseErr != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid version %q: %s", cla.Version, parseErr))
+			return 1
+		}
+		install, err = pr.InstallVersion(ctx, v, installOpts)
+	}
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	ui := &packer.ColoredUi{
+		Color: packer.UiColorCyan,
+		Ui:    c.Ui,
+	}
+	if install == nil && cla.Version != "" {
+		ui.Say(fmt.Sprintf("Version %s of the %s plugin is already installed", cla.Version, identifier))
+		return 0
+	}
+	if install == nil {
+		ui.Say(fmt.Sprintf("The %s plugin is already installed", identifier))
+		return 0
+	}
+	ui.Say(fmt.Sprintf("Installed plugin %s %s in %q", identifier, install.Version, install.BinaryPath))
+	return 0
+}
+
+func (*PluginsInstallCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*PluginsInstallCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{}
+}
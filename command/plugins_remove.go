@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+	"github.com/posener/complete"
+)
+
+type PluginsRemoveCommand struct {
+	Meta
+}
+
+func (c *PluginsRemoveCommand) Synopsis() string {
+	return "Remove installed plugins"
+}
+
+func (c *PluginsRemoveCommand) Help() string {
+	helpText := `
+Usage: packer plugins remove <plugin> [<version constraint>]
+
+  Remove the installed binaries of a plugin, and their checksum files, from
+  every plugin directory. Only the versions matching the version constraint
+  are removed when one is given.
+
+  Ex: packer plugins remove github.com/hashicorp/happycloud "< v1.2.0"
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PluginsRemoveCommand) Run(args []string) int {
+	ctx, cleanup := handleTermInterrupt(c.Ui)
+	defer cleanup()
+
+	cfg, ret := c.ParseArgs(args)
+	if ret != 0 {
+		return ret
+	}
+
+	return c.RunContext(ctx, cfg)
+}
+
+func (c *PluginsRemoveCommand) ParseArgs(args []string) (*PluginsRemoveArgs, int) {
+	var cfg PluginsRemoveArgs
+	flags := c.Meta.FlagSet("plugins remove", 0)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return &cfg, 1
+	}
+
+	args = flags.Args()
+	if len(args) < 1 || len(args) > 2 {
+		flags.Usage()
+		return &cfg, 1
+	}
+	cfg.Plugin = args[0]
+	if len(args) == 2 {
+		cfg.VersionConstraint = args[1]
+	}
+	return &cfg, 0
+}
+
+func (c *PluginsRemoveCommand) RunContext(_ context.Context, cla *PluginsRemoveArgs) int {
+	identifier, diags := addrs.ParsePluginSourceString(cla.Plugin)
+	if diags.HasErrors() {
+		c.Ui.Error(diags.Error())
+		return 1
+	}
+	pr := plugingetter.Requirement{Identifier: identifier}
+	if cla.VersionConstraint != "" {
+		constraints, err := version.NewConstraint(cla.VersionConstraint)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid version constraint %q: %s", cla.VersionConstraint, err))
+			return 1
+		}
+		pr.VersionConstraints = constraints
+	}
+
+	removed, err := pr.Uninstall(c.Meta.listInstallationsOptions())
+	for _, install := range removed {
+		c.Ui.Say(fmt.Sprintf("Removed plugin %s %s from %q", identifier, install.Version, install.BinaryPath))
+	}
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if len(removed) == 0 {
+		c.Ui.Error(fmt.Sprintf("No installed version of the %s plugin matches %q", identifier, cla.VersionConstraint))
+		return 1
+	}
+	return 0
+}
+
+func (*PluginsRemoveCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*PluginsRemoveCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{}
+}
@@ -0,0 +1,38 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer/packer"
+)
+
+// checkPolicies evaluates the rego policies of dir against the resolved
+// builds. Denials are reported as errors and make checkPolicies return 1,
+// warnings are only displayed.
+func checkPolicies(ctx context.Context, ui packersdk.Ui, handler packer.Handler, builds []packersdk.Build, dir string) int {
+	input, diags := handler.PolicyInput(builds)
+	if ret := writeDiags(ui, nil, diags); ret != 0 {
+		return ret
+	}
+
+	checker := &packer.PolicyChecker{Dir: dir}
+	res, err := checker.Check(ctx, input)
+	if err != nil {
+		ui.Error(err.Error())
+		return 1
+	}
+
+	for _, msg := range res.Warn {
+		ui.Say(fmt.Sprintf("Policy warning: %s", msg))
+	}
+	for _, msg := range res.Deny {
+		ui.Error(fmt.Sprintf("Policy violation: %s", msg))
+	}
+	if len(res.Deny) > 0 {
+		ui.Error(fmt.Sprintf("%d policy violation(s) found in %q.", len(res.Deny), dir))
+		return 1
+	}
+	return 0
+}
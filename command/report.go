@@ -0,0 +1,214 @@
+package command
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/version"
+)
+
+// buildResult is the outcome of a build, as written in the summary report
+// of `packer build -report`.
+type buildResult struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+	Report   *packer.BuildReport
+}
+
+// writeReport writes the summary report of results in the given format,
+// junit or sarif.
+func writeReport(path, format string, results []buildResult) error {
+	var (
+		out []byte
+		err error
+	)
+	switch format {
+	case "sarif":
+		out, err = json.MarshalIndent(sarifReport(results), "", "  ")
+	default:
+		out, err = xml.MarshalIndent(junitReport(results), "", "  ")
+		out = append([]byte(xml.Header), out...)
+	}
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path, append(out, '\n'), 0644)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junitReport returns a test suite per build, whose test cases are the
+// steps of the build. Builds whose steps weren't recorded, and builds that
+// failed outside of their steps, like when they were cancelled, get a "build"
+// test case.
+func junitReport(results []buildResult) *junitTestSuites {
+	report := &junitTestSuites{Name: "packer"}
+	var total time.Duration
+	for _, res := range results {
+		suite := junitTestSuite{
+			Name:      res.Name,
+			Time:      seconds(res.Duration),
+			Timestamp: res.Start.UTC().Format("2006-01-02T15:04:05"),
+		}
+		stepFailed := false
+		for _, step := range res.Report.Steps() {
+			tc := junitTestCase{
+				ClassName: res.Name,
+				Name:      fmt.Sprintf("%s %s", step.Type, step.Name),
+				Time:      seconds(step.Duration),
+			}
+			if step.Error != "" {
+				tc.Failure = &junitFailure{Message: step.Error, Text: step.Error}
+				suite.Failures++
+				stepFailed = true
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		if len(suite.Cases) == 0 || (res.Err != nil && !stepFailed) {
+			tc := junitTestCase{
+				ClassName: res.Name,
+				Name:      "build",
+				Time:      seconds(res.Duration),
+			}
+			if res.Err != nil {
+				tc.Failure = &junitFailure{Message: res.Err.Error(), Text: res.Err.Error()}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Tests = len(suite.Cases)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		total += res.Duration
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = seconds(total)
+	return report
+}
+
+// The SARIF report follows the version 2.1.0 of the format, with a result
+// per failed build.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool `json:"executionSuccessful"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+func sarifReport(results []buildResult) *sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "packer",
+			Version:        version.FormattedVersion(),
+			InformationURI: "https://www.packer.io",
+		}},
+		Results: []sarifResult{},
+	}
+	successful := true
+	for _, res := range results {
+		if res.Err == nil {
+			continue
+		}
+		successful = false
+		result := sarifResult{
+			RuleID:  "build-failed",
+			Level:   "error",
+			Message: sarifMessage{Text: fmt.Sprintf("Build '%s' errored: %s", res.Name, res.Err)},
+			Properties: map[string]interface{}{
+				"build":            res.Name,
+				"duration_seconds": res.Duration.Seconds(),
+			},
+		}
+		// the builder fails with the error of its provisioners, so the step
+		// that caused the failure is the last failed one to start
+		for _, step := range res.Report.Steps() {
+			if step.Error != "" {
+				result.Properties["step"] = fmt.Sprintf("%s %s", step.Type, step.Name)
+			}
+		}
+		run.Results = append(run.Results, result)
+	}
+	run.Invocations = []sarifInvocation{{ExecutionSuccessful: successful}}
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
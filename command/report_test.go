@@ -0,0 +1,78 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBuildResults() []buildResult {
+	return []buildResult{
+		{Name: "null.ok", Start: time.Now(), Duration: 2 * time.Second},
+		{Name: "null.failed", Start: time.Now(), Duration: time.Second, Err: errors.New("cancelled")},
+	}
+}
+
+func TestJUnitReport(t *testing.T) {
+	report := junitReport(testBuildResults())
+	if report.Tests != 2 || report.Failures != 1 || report.Time != "3.000" {
+		t.Fatalf("unexpected totals: %#v", report)
+	}
+	failed := report.Suites[1]
+	if failed.Name != "null.failed" || len(failed.Cases) != 1 {
+		t.Fatalf("unexpected suite: %#v", failed)
+	}
+	if tc := failed.Cases[0]; tc.Name != "build" || tc.Failure == nil || tc.Failure.Message != "cancelled" {
+		t.Fatalf("unexpected test case: %#v", tc)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	junitPath := filepath.Join(dir, "reports", "packer.xml")
+	if err := writeReport(junitPath, "junit", testBuildResults()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(junitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuites name="packer" tests="2" failures="1" time="3.000">`,
+		`<testcase classname="null.failed" name="build" time="1.000">`,
+		`<failure message="cancelled">cancelled</failure>`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("%q not found in report:\n%s", want, b)
+		}
+	}
+
+	sarifPath := filepath.Join(dir, "packer.sarif")
+	if err := writeReport(sarifPath, "sarif", testBuildResults()); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadFile(sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(b, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("unexpected sarif report:\n%s", b)
+	}
+	if log.Runs[0].Invocations[0].ExecutionSuccessful {
+		t.Errorf("the run should not be successful")
+	}
+}
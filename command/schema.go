@@ -0,0 +1,32 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type SchemaCommand struct {
+	Meta
+}
+
+func (c *SchemaCommand) Synopsis() string {
+	return "Show the schemas of the output of Packer"
+}
+
+func (c *SchemaCommand) Help() string {
+	helpText := `
+Usage: packer schema <subcommand> [options] [args]
+  This command groups subcommands showing the schemas of the output of
+  Packer, which parsers can use to check their compatibility with a release.
+
+Subcommands:
+  events      Show the JSON schema of the machine-readable events.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SchemaCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
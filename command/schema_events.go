@@ -0,0 +1,52 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/posener/complete"
+)
+
+type SchemaEventsCommand struct {
+	Meta
+}
+
+func (c *SchemaEventsCommand) Synopsis() string {
+	return "Show the JSON schema of the machine-readable events"
+}
+
+func (c *SchemaEventsCommand) Help() string {
+	helpText := `
+Usage: packer schema events
+
+  Show the JSON schema of the events written with -machine-readable, each
+  timestamp,target,type,data... line being read as an object with those four
+  properties. The version property of the schema is only bumped when events
+  change in a way existing parsers can't read.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SchemaEventsCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("schema events", 0)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if len(flags.Args()) != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	c.Ui.Say(strings.TrimSpace(string(packer.MachineReadableSchema)))
+	return 0
+}
+
+func (*SchemaEventsCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*SchemaEventsCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{}
+}
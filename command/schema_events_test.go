@@ -0,0 +1,31 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestSchemaEventsCommand_Run(t *testing.T) {
+	c := &SchemaEventsCommand{Meta: testMetaFile(t)}
+	if ret := c.Run(nil); ret != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	out, _ := outputCommand(t, c.Meta)
+	var schema struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(out), &schema); err != nil {
+		t.Fatalf("output is not a JSON schema: %s\n%s", err, out)
+	}
+	if schema.Version != packer.MachineReadableSchemaVersion {
+		t.Errorf("schema version is %d, expected %d", schema.Version, packer.MachineReadableSchemaVersion)
+	}
+
+	c = &SchemaEventsCommand{Meta: testMetaFile(t)}
+	if ret := c.Run([]string{"build"}); ret != 1 {
+		t.Errorf("expected an argument to fail, got %d", ret)
+	}
+}
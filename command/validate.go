@@ -56,15 +56,17 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 		return 0
 	}
 
+	// Policies are evaluated against the resolved template, so data sources
+	// need to be executed in that case.
 	diags := packerStarter.Initialize(packer.InitializeOptions{
-		SkipDatasourcesExecution: true,
+		SkipDatasourcesExecution: cla.PolicyDir == "",
 	})
 	ret = writeDiags(c.Ui, nil, diags)
 	if ret != 0 {
 		return ret
 	}
 
-	_, diags = packerStarter.GetBuilds(packer.GetBuildsOptions{
+	builds, diags := packerStarter.GetBuilds(packer.GetBuildsOptions{
 		Only:   cla.Only,
 		Except: cla.Except,
 	})
@@ -74,7 +76,12 @@ func (c *ValidateCommand) RunContext(ctx context.Context, cla *ValidateArgs) int
 	})
 	diags = append(diags, fixerDiags...)
 
-	return writeDiags(c.Ui, nil, diags)
+	ret = writeDiags(c.Ui, nil, diags)
+	if ret != 0 || cla.PolicyDir == "" {
+		return ret
+	}
+
+	return checkPolicies(ctx, c.Ui, packerStarter, builds, cla.PolicyDir)
 }
 
 func (*ValidateCommand) Help() string {
@@ -94,8 +101,16 @@ Options:
   -except=foo,bar,baz    Validate all builds other than these.
   -machine-readable      Produce machine-readable output.
   -only=foo,bar,baz      Validate only these builds.
+  -policy-dir=path       Evaluate the rego policies of this directory against
+                         the resolved template. Requires the opa executable.
+  -strict                Make warnings errors, like the use of deprecated
+                         fields or of undefined variables, or with
+                         -warn-on-unused-vars of unused ones. HCL2 only.
   -var 'key=value'       Variable for templates, can be used multiple times.
   -var-file=path         JSON or HCL2 file containing user variables.
+  -warn-on-unused-vars   Warn about variables set with -var or -var-file that
+                         the template doesn't use, and about required plugins
+                         whose components are never used. HCL2 only.
 `
 
 	return strings.TrimSpace(helpText)
@@ -111,11 +126,14 @@ func (*ValidateCommand) AutocompleteArgs() complete.Predictor {
 
 func (*ValidateCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-syntax-only":      complete.PredictNothing,
-		"-except":           complete.PredictNothing,
-		"-only":             complete.PredictNothing,
-		"-var":              complete.PredictNothing,
-		"-machine-readable": complete.PredictNothing,
-		"-var-file":         complete.PredictNothing,
+		"-syntax-only":         complete.PredictNothing,
+		"-except":              complete.PredictNothing,
+		"-only":                complete.PredictNothing,
+		"-policy-dir":          complete.PredictNothing,
+		"-strict":              complete.PredictNothing,
+		"-var":                 complete.PredictNothing,
+		"-machine-readable":    complete.PredictNothing,
+		"-var-file":            complete.PredictNothing,
+		"-warn-on-unused-vars": complete.PredictNothing,
 	}
 }
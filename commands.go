@@ -17,6 +17,18 @@ const OutputPrefix = "o:"
 
 func init() {
 	Commands = map[string]cli.CommandFactory{
+		"artifacts": func() (cli.Command, error) {
+			return &command.ArtifactsCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"artifacts promote": func() (cli.Command, error) {
+			return &command.ArtifactsPromoteCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"build": func() (cli.Command, error) {
 			return &command.BuildCommand{Meta: *CommandMeta}, nil
 		},
@@ -26,6 +38,18 @@ func init() {
 			}, nil
 		},
 
+		"diff": func() (cli.Command, error) {
+			return &command.DiffCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"docs": func() (cli.Command, error) {
+			return &command.DocsCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"fix": func() (cli.Command, error) {
 			return &command.FixCommand{
 				Meta: *CommandMeta,
@@ -62,6 +86,42 @@ func init() {
 			}, nil
 		},
 
+		"plugins": func() (cli.Command, error) {
+			return &command.PluginsCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"plugins doctor": func() (cli.Command, error) {
+			return &command.PluginsDoctorCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"plugins install": func() (cli.Command, error) {
+			return &command.PluginsInstallCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"plugins remove": func() (cli.Command, error) {
+			return &command.PluginsRemoveCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"schema": func() (cli.Command, error) {
+			return &command.SchemaCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"schema events": func() (cli.Command, error) {
+			return &command.SchemaEventsCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"validate": func() (cli.Command, error) {
 			return &command.ValidateCommand{
 				Meta: *CommandMeta,
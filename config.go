@@ -185,11 +185,40 @@ func (c *config) discoverInternalComponents() error {
 		if !c.Plugins.DataSources.Has(dataSource) {
 			bin := fmt.Sprintf("%s%splugin%spacker-datasource-%s",
 				packerPath, PACKERSPACE, PACKERSPACE, dataSource)
+			// what outputs are sensitive doesn't cross the plugin RPC
+			// boundary, so it is read from the in-process data source.
+			var sensitiveOutputs []string
+			if sensitive, ok := command.Datasources[dataSource].(packer.SensitiveDatasource); ok {
+				sensitiveOutputs = sensitive.SensitiveOutputs()
+			}
 			c.Plugins.DataSources.Set(dataSource, func() (packersdk.Datasource, error) {
-				return c.Plugins.Client(bin).Datasource()
+				datasource, err := c.Plugins.Client(bin).Datasource()
+				if err != nil || len(sensitiveOutputs) == 0 {
+					return datasource, err
+				}
+				return &internalDatasource{Datasource: datasource, sensitiveOutputs: sensitiveOutputs}, nil
 			})
 		}
 	}
 
 	return nil
 }
+
+// internalDatasource is an internal data source started as a plugin, that
+// still reports the sensitive outputs of its in-process counterpart.
+type internalDatasource struct {
+	packersdk.Datasource
+	sensitiveOutputs []string
+}
+
+func (d *internalDatasource) SensitiveOutputs() []string {
+	return d.sensitiveOutputs
+}
+
+func (d *internalDatasource) Warnings() []string {
+	warner, ok := d.Datasource.(packer.DatasourceWarner)
+	if !ok {
+		return nil
+	}
+	return warner.Warnings()
+}
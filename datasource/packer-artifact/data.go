@@ -0,0 +1,145 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+//go:generate packer-sdc struct-markdown
+
+// Package packerartifact implements a data source looking up the artifacts of
+// a build in an artifact store, so that a build can use the image of another
+// build as its source.
+package packerartifact
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The directory of a local artifact store, as set by the `path` of a
+	// local `artifact_store` block. Exactly one of `store_path` and `url`
+	// must be set.
+	StorePath string `mapstructure:"store_path"`
+	// The URL of an artifact store served over http, like the `url` of an
+	// http `artifact_store` block, or a web server serving the directory of
+	// a local artifact store.
+	URL string `mapstructure:"url"`
+	// Headers set on the requests to `url`, for example to authenticate.
+	Headers map[string]string `mapstructure:"headers"`
+	// The name of the build whose artifacts are looked up, like
+	// `amazon-ebs.base`.
+	Lineage string `mapstructure:"lineage" required:"true"`
+	// The channel the version of the build was promoted to with `packer
+	// artifacts promote`, like `stable`. When empty, the last version of the
+	// build is used; over http, only the last version uploaded can be found.
+	Channel string `mapstructure:"channel"`
+	// The labels the version of the build must have, as set by the `labels`
+	// of the `artifact_store` block of its template.
+	Labels map[string]string `mapstructure:"labels"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	// The id of the last artifact of the version: the artifact of its last
+	// post-processor, or of its builder when it has none. For an amazon-ebs
+	// build, this is like `us-east-1:ami-0123456789abcdef0`.
+	ID string `mapstructure:"id"`
+	// The ids of all the artifacts of the version.
+	ArtifactIDs []string `mapstructure:"artifact_ids"`
+	// The version found, like `20211016T012924Z`.
+	Version string `mapstructure:"version"`
+	// The name of the build.
+	BuildName string `mapstructure:"build_name"`
+	// When the artifacts of the version were stored, in RFC 3339 format.
+	Time string `mapstructure:"time"`
+	// The labels of the version.
+	Labels map[string]string `mapstructure:"labels"`
+}
+
+var _ packersdk.Datasource = new(Datasource)
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	if (d.config.StorePath == "") == (d.config.URL == "") {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("exactly one of store_path or url must be set"))
+	}
+	if d.config.URL != "" {
+		if u, err := url.Parse(d.config.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("url must be an http or https URL, got %q", d.config.URL))
+		}
+	}
+	if d.config.Lineage == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("lineage must be set"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) registry() packer.ArtifactRegistry {
+	if d.config.URL != "" {
+		return &packer.HTTPArtifactRegistry{URL: d.config.URL, Headers: d.config.Headers}
+	}
+	return &packer.LocalArtifactStore{Dir: d.config.StorePath}
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	null := cty.NullVal(cty.EmptyObject)
+	registry := d.registry()
+
+	var build *packer.StoredBuild
+	var err error
+	if d.config.Channel != "" {
+		build, err = registry.Channel(d.config.Lineage, d.config.Channel)
+		if err == nil && !build.HasLabels(d.config.Labels) {
+			err = fmt.Errorf("version %s of %s promoted to the %s channel doesn't have labels %v",
+				build.Version, d.config.Lineage, d.config.Channel, d.config.Labels)
+		}
+	} else {
+		build, err = registry.Latest(d.config.Lineage, d.config.Labels)
+	}
+	if err != nil {
+		return null, err
+	}
+	if len(build.Artifacts) == 0 {
+		return null, fmt.Errorf("version %s of %s has no artifact", build.Version, d.config.Lineage)
+	}
+
+	output := DatasourceOutput{
+		ID:          build.Artifacts[len(build.Artifacts)-1].Id,
+		ArtifactIDs: make([]string, 0, len(build.Artifacts)),
+		Version:     build.Version,
+		BuildName:   build.BuildName,
+		Time:        build.Time.Format(time.RFC3339),
+		Labels:      build.Labels,
+	}
+	for _, a := range build.Artifacts {
+		output.ArtifactIDs = append(output.ArtifactIDs, a.Id)
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
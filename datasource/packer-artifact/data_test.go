@@ -0,0 +1,90 @@
+package packerartifact
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"local", map[string]interface{}{"store_path": "artifacts", "lineage": "amazon-ebs.base"}, false},
+		{"http", map[string]interface{}{"url": "https://artifacts.example.com/packer", "lineage": "amazon-ebs.base"}, false},
+		{"no store", map[string]interface{}{"lineage": "amazon-ebs.base"}, true},
+		{"both stores", map[string]interface{}{"store_path": "artifacts", "url": "https://artifacts.example.com", "lineage": "amazon-ebs.base"}, true},
+		{"invalid url", map[string]interface{}{"url": "artifacts.example.com", "lineage": "amazon-ebs.base"}, true},
+		{"no lineage", map[string]interface{}{"store_path": "artifacts"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Datasource{}
+			if err := d.Configure(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-artifact-datasource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storePath := filepath.Join(dir, "artifacts")
+	for _, ami := range []string{"ami-0123456789abcdef0", "ami-0fedcba9876543210"} {
+		store := &packer.LocalArtifactStore{Dir: storePath, Labels: map[string]string{"os": "ubuntu"}}
+		artifacts := []packersdk.Artifact{&packersdk.MockArtifact{BuilderIdValue: "amazon-ebs", IdValue: "us-east-1:" + ami}}
+		if err := store.Store(context.Background(), "amazon-ebs.base", artifacts); err != nil {
+			t.Fatal(err)
+		}
+		if ami == "ami-0123456789abcdef0" {
+			if _, err := store.Promote("amazon-ebs.base", packer.LatestArtifactVersion, "stable"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantID  string
+		wantErr bool
+	}{
+		{"latest", map[string]interface{}{}, "us-east-1:ami-0fedcba9876543210", false},
+		{"channel", map[string]interface{}{"channel": "stable"}, "us-east-1:ami-0123456789abcdef0", false},
+		{"labels", map[string]interface{}{"labels": map[string]string{"os": "ubuntu"}}, "us-east-1:ami-0fedcba9876543210", false},
+		{"unknown channel", map[string]interface{}{"channel": "testing"}, "", true},
+		{"unknown labels", map[string]interface{}{"channel": "stable", "labels": map[string]string{"os": "debian"}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["store_path"] = storePath
+			tt.config["lineage"] = "amazon-ebs.base"
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if id := got.GetAttr("id").AsString(); id != tt.wantID {
+				t.Errorf("Execute() id = %q, want %q", id, tt.wantID)
+			}
+		})
+	}
+}
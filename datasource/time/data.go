@@ -0,0 +1,149 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+//go:generate packer-sdc struct-markdown
+
+// Package time implements a data source returning the time a Packer run
+// started, so that all the builds of a run can share a single timestamp.
+package time
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// RunStartTimeEnv is the env var in which Packer sets the time its run
+// started, in RFC 3339 format with nanoseconds, before starting any plugin.
+const RunStartTimeEnv = "PACKER_RUN_START_TIME"
+
+// defaultFormat is the format of `formatted` when `format` is not set. It
+// has no colons, so that it can be used in image names.
+const defaultFormat = "YYYYMMDDhhmmss"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The format of the `formatted` output, in the syntax of the
+	// [`formatdate`](/docs/templates/hcl_templates/functions/datetime/formatdate)
+	// function. Defaults to `YYYYMMDDhhmmss`, like `20210415154028`.
+	Format string `mapstructure:"format"`
+	// The time zone of the outputs, as a name of the IANA Time Zone
+	// database like `Europe/Paris`, or `Local` for the time zone of the
+	// machine running Packer. Defaults to `UTC`.
+	TimeZone string `mapstructure:"time_zone"`
+
+	location *time.Location
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	// The start time of the run in RFC 3339 format, like
+	// `2021-04-15T15:40:28Z`.
+	RFC3339 string `mapstructure:"rfc3339"`
+	// The start time of the run in the format of `format`.
+	Formatted string `mapstructure:"formatted"`
+	// The start time of the run as a number of seconds since the Unix epoch.
+	Unix int64 `mapstructure:"unix"`
+	// The year of the start time of the run, like `2021`.
+	Year string `mapstructure:"year"`
+	// The month of the start time of the run, from `01` to `12`.
+	Month string `mapstructure:"month"`
+	// The day of the start time of the run, from `01` to `31`.
+	Day string `mapstructure:"day"`
+	// The hour of the start time of the run, from `00` to `23`.
+	Hour string `mapstructure:"hour"`
+	// The minute of the start time of the run, from `00` to `59`.
+	Minute string `mapstructure:"minute"`
+	// The second of the start time of the run, from `00` to `59`.
+	Second string `mapstructure:"second"`
+}
+
+var _ packersdk.Datasource = new(Datasource)
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	if d.config.Format == "" {
+		d.config.Format = defaultFormat
+	}
+	if _, err := formatDate(d.config.Format, time.Now().UTC()); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid format %q: %s", d.config.Format, err))
+	}
+	if d.config.TimeZone == "" {
+		d.config.TimeZone = "UTC"
+	}
+	d.config.location, err = time.LoadLocation(d.config.TimeZone)
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid time_zone %q: %s", d.config.TimeZone, err))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	null := cty.NullVal(cty.EmptyObject)
+	start := RunStartTime().In(d.config.location)
+
+	formatted, err := formatDate(d.config.Format, start)
+	if err != nil {
+		return null, err
+	}
+	output := DatasourceOutput{
+		RFC3339:   start.Format(time.RFC3339),
+		Formatted: formatted,
+		Unix:      start.Unix(),
+		Year:      start.Format("2006"),
+		Month:     start.Format("01"),
+		Day:       start.Format("02"),
+		Hour:      start.Format("15"),
+		Minute:    start.Format("04"),
+		Second:    start.Format("05"),
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// RunStartTime returns the time the Packer run started, as set in the
+// RunStartTimeEnv env var, truncated to the second. When the env var is not
+// set, like when a plugin is run on its own, the current time is returned.
+func RunStartTime() time.Time {
+	if v := os.Getenv(RunStartTimeEnv); v != "" {
+		if start, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return start.Truncate(time.Second)
+		}
+	}
+	return time.Now().Truncate(time.Second)
+}
+
+// formatDate formats t like the formatdate function of HCL templates.
+func formatDate(format string, t time.Time) (string, error) {
+	v, err := stdlib.FormatDate(cty.StringVal(format), cty.StringVal(t.Format(time.RFC3339)))
+	if err != nil {
+		return "", err
+	}
+	return v.AsString(), nil
+}
@@ -0,0 +1,69 @@
+package time
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"defaults", map[string]interface{}{}, false},
+		{"format", map[string]interface{}{"format": "YYYY-MM-DD"}, false},
+		{"time zone", map[string]interface{}{"time_zone": "Europe/Paris"}, false},
+		{"invalid format", map[string]interface{}{"format": "YYYY-MM-DD'"}, true},
+		{"unknown time zone", map[string]interface{}{"time_zone": "Mars/Olympus_Mons"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Datasource{}
+			if err := d.Configure(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	defer os.Unsetenv(RunStartTimeEnv)
+	os.Setenv(RunStartTimeEnv, "2021-04-15T15:40:28.123456789Z")
+
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		wantRFC3339   string
+		wantFormatted string
+		wantHour      string
+	}{
+		{"defaults", map[string]interface{}{}, "2021-04-15T15:40:28Z", "20210415154028", "15"},
+		{"format", map[string]interface{}{"format": "YYYY-MM-DD"}, "2021-04-15T15:40:28Z", "2021-04-15", "15"},
+		{"time zone", map[string]interface{}{"time_zone": "Asia/Tokyo"}, "2021-04-16T00:40:28+09:00", "20210416004028", "00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if rfc3339 := got.GetAttr("rfc3339").AsString(); rfc3339 != tt.wantRFC3339 {
+				t.Errorf("Execute() rfc3339 = %q, want %q", rfc3339, tt.wantRFC3339)
+			}
+			if formatted := got.GetAttr("formatted").AsString(); formatted != tt.wantFormatted {
+				t.Errorf("Execute() formatted = %q, want %q", formatted, tt.wantFormatted)
+			}
+			if hour := got.GetAttr("hour").AsString(); hour != tt.wantHour {
+				t.Errorf("Execute() hour = %q, want %q", hour, tt.wantHour)
+			}
+			if unix, _ := got.GetAttr("unix").AsBigFloat().Int64(); unix != 1618501228 {
+				t.Errorf("Execute() unix = %d, want 1618501228", unix)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+//go:generate packer-sdc struct-markdown
+
+// Package tlsprivatekey implements a data source generating a private key,
+// so that a template can mint the keys it needs, like the ones of bootstrap
+// certificates or of SSH key pairs, instead of relying on wrapper scripts.
+package tlsprivatekey
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	KeyConfig           `mapstructure:",squash"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	// The algorithm of the key.
+	Algorithm string `mapstructure:"algorithm"`
+	// The private key in PEM format: PKCS#1 for an RSA key, SEC 1 for an
+	// ECDSA key and PKCS#8 for an ED25519 key.
+	PrivateKeyPEM string `mapstructure:"private_key_pem"`
+	// The public key in PEM format.
+	PublicKeyPEM string `mapstructure:"public_key_pem"`
+	// The public key in the format of an OpenSSH authorized_keys file.
+	// Empty for a P224 ECDSA key, that OpenSSH doesn't support.
+	PublicKeyOpenSSH string `mapstructure:"public_key_openssh"`
+}
+
+var _ packersdk.Datasource = new(Datasource)
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, d.config.KeyConfig.Prepare()...)
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+// SensitiveOutputs returns the outputs of the data source hidden from the
+// logs.
+func (d *Datasource) SensitiveOutputs() []string {
+	return []string{"private_key_pem"}
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	null := cty.NullVal(cty.EmptyObject)
+	key, err := d.config.Generate()
+	if err != nil {
+		return null, err
+	}
+
+	output := DatasourceOutput{Algorithm: d.config.Algorithm}
+	if output.PrivateKeyPEM, err = PrivateKeyPEM(key); err != nil {
+		return null, err
+	}
+	if output.PublicKeyPEM, err = PublicKeyPEM(key); err != nil {
+		return null, err
+	}
+	if output.PublicKeyOpenSSH, err = PublicKeyOpenSSH(key); err != nil {
+		return null, err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
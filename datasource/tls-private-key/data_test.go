@@ -0,0 +1,86 @@
+package tlsprivatekey
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"strings"
+	"testing"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"defaults", map[string]interface{}{}, false},
+		{"rsa", map[string]interface{}{"algorithm": "RSA", "rsa_bits": 4096}, false},
+		{"ecdsa", map[string]interface{}{"algorithm": "ecdsa", "ecdsa_curve": "p384"}, false},
+		{"ed25519", map[string]interface{}{"algorithm": "ED25519"}, false},
+		{"small rsa key", map[string]interface{}{"algorithm": "RSA", "rsa_bits": 1024}, true},
+		{"unknown curve", map[string]interface{}{"algorithm": "ECDSA", "ecdsa_curve": "P192"}, true},
+		{"unknown algorithm", map[string]interface{}{"algorithm": "DSA"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Datasource{}
+			if err := d.Configure(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		wantKeyType string
+		wantSSH     string
+	}{
+		{"ecdsa", map[string]interface{}{"algorithm": "ECDSA"}, "*ecdsa.PrivateKey", "ecdsa-sha2-nistp256 "},
+		{"ecdsa p224", map[string]interface{}{"algorithm": "ECDSA", "ecdsa_curve": "P224"}, "*ecdsa.PrivateKey", ""},
+		{"ed25519", map[string]interface{}{"algorithm": "ED25519"}, "ed25519.PrivateKey", "ssh-ed25519 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			key, err := ParsePrivateKeyPEM(got.GetAttr("private_key_pem").AsString())
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyPEM() error = %v", err)
+			}
+			var keyType string
+			switch key.(type) {
+			case *rsa.PrivateKey:
+				keyType = "*rsa.PrivateKey"
+			case *ecdsa.PrivateKey:
+				keyType = "*ecdsa.PrivateKey"
+			case ed25519.PrivateKey:
+				keyType = "ed25519.PrivateKey"
+			}
+			if keyType != tt.wantKeyType {
+				t.Errorf("Execute() key type = %s, want %s", keyType, tt.wantKeyType)
+			}
+			publicKeyPEM, err := PublicKeyPEM(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := got.GetAttr("public_key_pem").AsString(); got != publicKeyPEM {
+				t.Errorf("Execute() public_key_pem = %q, want %q", got, publicKeyPEM)
+			}
+			ssh := got.GetAttr("public_key_openssh").AsString()
+			if tt.wantSSH == "" && ssh != "" || !strings.HasPrefix(ssh, tt.wantSSH) {
+				t.Errorf("Execute() public_key_openssh = %q, want prefix %q", ssh, tt.wantSSH)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package tlsprivatekey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Algorithms of the generated keys.
+const (
+	RSA     = "RSA"
+	ECDSA   = "ECDSA"
+	ED25519 = "ED25519"
+)
+
+var curves = map[string]elliptic.Curve{
+	"P224": elliptic.P224(),
+	"P256": elliptic.P256(),
+	"P384": elliptic.P384(),
+	"P521": elliptic.P521(),
+}
+
+// KeyConfig describes a private key to generate. Data sources generating
+// keys squash it in their configuration.
+type KeyConfig struct {
+	// The algorithm of the key: `RSA`, `ECDSA` or `ED25519`. Defaults to
+	// `RSA`.
+	Algorithm string `mapstructure:"algorithm"`
+	// The size of an RSA key, in bits. Defaults to 2048.
+	RSABits int `mapstructure:"rsa_bits"`
+	// The curve of an ECDSA key: `P224`, `P256`, `P384` or `P521`. Defaults
+	// to `P256`.
+	ECDSACurve string `mapstructure:"ecdsa_curve"`
+}
+
+// Prepare sets the defaults of c, and validates it.
+func (c *KeyConfig) Prepare() []error {
+	c.Algorithm = strings.ToUpper(c.Algorithm)
+	if c.Algorithm == "" {
+		c.Algorithm = RSA
+	}
+	if c.RSABits == 0 {
+		c.RSABits = 2048
+	}
+	c.ECDSACurve = strings.ToUpper(c.ECDSACurve)
+	if c.ECDSACurve == "" {
+		c.ECDSACurve = "P256"
+	}
+
+	var errs []error
+	switch c.Algorithm {
+	case RSA:
+		if c.RSABits < 2048 {
+			errs = append(errs, fmt.Errorf("rsa_bits must be at least 2048, got %d", c.RSABits))
+		}
+	case ECDSA:
+		if _, found := curves[c.ECDSACurve]; !found {
+			errs = append(errs, fmt.Errorf("unknown ecdsa_curve %q, expected one of P224, P256, P384 or P521", c.ECDSACurve))
+		}
+	case ED25519:
+	default:
+		errs = append(errs, fmt.Errorf("unknown algorithm %q, expected one of RSA, ECDSA or ED25519", c.Algorithm))
+	}
+	return errs
+}
+
+// Generate generates a private key as described by c, once prepared.
+func (c *KeyConfig) Generate() (crypto.Signer, error) {
+	switch c.Algorithm {
+	case RSA:
+		return rsa.GenerateKey(rand.Reader, c.RSABits)
+	case ECDSA:
+		return ecdsa.GenerateKey(curves[c.ECDSACurve], rand.Reader)
+	case ED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", c.Algorithm)
+	}
+}
+
+// PrivateKeyPEM encodes key in PEM format: PKCS#1 for RSA keys, SEC 1 for
+// ECDSA keys and PKCS#8 for ED25519 keys, the formats tools expect.
+func PrivateKeyPEM(key crypto.Signer) (string, error) {
+	var block *pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", err
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return "", err
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	return string(pem.EncodeToMemory(block)), nil
+}
+
+// ParsePrivateKeyPEM decodes a private key encoded by PrivateKeyPEM, or by
+// openssl.
+func ParsePrivateKeyPEM(s string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported %q PEM block", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported %T private key", key)
+	}
+	return signer, nil
+}
+
+// PublicKeyPEM encodes the public key of key in PEM format, as a PKIX
+// public key.
+func PublicKeyPEM(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// PublicKeyOpenSSH encodes the public key of key in the format of the
+// authorized_keys file of OpenSSH. OpenSSH has no support for P224 keys,
+// an empty string is returned for them.
+func PublicKeyOpenSSH(key crypto.Signer) (string, error) {
+	if k, ok := key.(*ecdsa.PrivateKey); ok && k.Curve == elliptic.P224() {
+		return "", nil
+	}
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return "", err
+	}
+	return string(ssh.MarshalAuthorizedKey(pub)), nil
+}
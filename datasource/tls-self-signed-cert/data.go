@@ -0,0 +1,243 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type DatasourceOutput,Config
+//go:generate packer-sdc struct-markdown
+
+// Package tlsselfsignedcert implements a data source generating a self
+// signed certificate, so that builds needing bootstrap certificates, like the
+// ones of WinRM HTTPS listeners, can mint them in their template.
+package tlsselfsignedcert
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	tlsprivatekey "github.com/hashicorp/packer/datasource/tls-private-key"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// keyUsages are the values of allowed_uses setting a key usage.
+var keyUsages = map[string]x509.KeyUsage{
+	"digital_signature": x509.KeyUsageDigitalSignature,
+	"key_encipherment":  x509.KeyUsageKeyEncipherment,
+	"cert_signing":      x509.KeyUsageCertSign,
+	"crl_signing":       x509.KeyUsageCRLSign,
+}
+
+// extKeyUsages are the values of allowed_uses setting an extended key usage.
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"server_auth":  x509.ExtKeyUsageServerAuth,
+	"client_auth":  x509.ExtKeyUsageClientAuth,
+	"code_signing": x509.ExtKeyUsageCodeSigning,
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The private key of the certificate, in PEM format, like the
+	// `private_key_pem` of a `tls-private-key` data source. When empty, a
+	// key is generated as described by `algorithm`, `rsa_bits` and
+	// `ecdsa_curve`.
+	PrivateKeyPEM           string `mapstructure:"private_key_pem"`
+	tlsprivatekey.KeyConfig `mapstructure:",squash"`
+
+	// The common name of the subject of the certificate, like the hostname
+	// of the machine being built.
+	CommonName string `mapstructure:"common_name" required:"true"`
+	// The organization of the subject of the certificate.
+	Organization string `mapstructure:"organization"`
+	// The DNS names the certificate is valid for.
+	DNSNames []string `mapstructure:"dns_names"`
+	// The IP addresses the certificate is valid for.
+	IPAddresses []string `mapstructure:"ip_addresses"`
+	// How long the certificate is valid for, like `720h`. Defaults to one
+	// year.
+	ValidityPeriod time.Duration `mapstructure:"validity_period"`
+	// What the certificate can be used for, among `digital_signature`,
+	// `key_encipherment`, `cert_signing`, `crl_signing`, `server_auth`,
+	// `client_auth` and `code_signing`. Defaults to `digital_signature`,
+	// `key_encipherment` and `server_auth`, as needed by a TLS server.
+	AllowedUses []string `mapstructure:"allowed_uses"`
+	// Makes the certificate a certificate authority, that can sign other
+	// certificates.
+	IsCACertificate bool `mapstructure:"is_ca_certificate"`
+
+	privateKey crypto.Signer
+	ips        []net.IP
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	// The certificate in PEM format.
+	CertPEM string `mapstructure:"cert_pem"`
+	// The private key of the certificate in PEM format, the one of
+	// `private_key_pem` when it is set.
+	PrivateKeyPEM string `mapstructure:"private_key_pem"`
+	// The public key of the certificate in PEM format.
+	PublicKeyPEM string `mapstructure:"public_key_pem"`
+	// The hexadecimal SHA-1 fingerprint of the certificate, as used by
+	// Windows to reference certificates, like in the thumbprint of a WinRM
+	// listener.
+	Thumbprint string `mapstructure:"thumbprint"`
+	// When the certificate starts being valid, in RFC 3339 format.
+	ValidityStartTime string `mapstructure:"validity_start_time"`
+	// When the certificate stops being valid, in RFC 3339 format.
+	ValidityEndTime string `mapstructure:"validity_end_time"`
+}
+
+var _ packersdk.Datasource = new(Datasource)
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	if d.config.PrivateKeyPEM != "" {
+		d.config.privateKey, err = tlsprivatekey.ParsePrivateKeyPEM(d.config.PrivateKeyPEM)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid private_key_pem: %s", err))
+		}
+	} else {
+		errs = packersdk.MultiErrorAppend(errs, d.config.KeyConfig.Prepare()...)
+	}
+	if d.config.CommonName == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("common_name must be set"))
+	}
+	d.config.ips = nil
+	for _, s := range d.config.IPAddresses {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid ip address %q", s))
+			continue
+		}
+		d.config.ips = append(d.config.ips, ip)
+	}
+	if d.config.ValidityPeriod == 0 {
+		d.config.ValidityPeriod = 365 * 24 * time.Hour
+	}
+	if d.config.ValidityPeriod < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("validity_period must be positive, got %s", d.config.ValidityPeriod))
+	}
+	if len(d.config.AllowedUses) == 0 {
+		d.config.AllowedUses = []string{"digital_signature", "key_encipherment", "server_auth"}
+	}
+	for _, use := range d.config.AllowedUses {
+		_, isKeyUsage := keyUsages[use]
+		_, isExtKeyUsage := extKeyUsages[use]
+		if !isKeyUsage && !isExtKeyUsage {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("unknown allowed use %q", use))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+// SensitiveOutputs returns the outputs of the data source hidden from the
+// logs.
+func (d *Datasource) SensitiveOutputs() []string {
+	return []string{"private_key_pem"}
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	null := cty.NullVal(cty.EmptyObject)
+
+	key := d.config.privateKey
+	if key == nil {
+		var err error
+		if key, err = d.config.Generate(); err != nil {
+			return null, err
+		}
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return null, err
+	}
+	// the certificate is valid from a minute ago, so that machines whose
+	// clock is slightly late accept it.
+	notBefore := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: d.config.CommonName,
+		},
+		DNSNames:              d.config.DNSNames,
+		IPAddresses:           d.config.ips,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(d.config.ValidityPeriod),
+		BasicConstraintsValid: true,
+		IsCA:                  d.config.IsCACertificate,
+	}
+	if d.config.Organization != "" {
+		template.Subject.Organization = []string{d.config.Organization}
+	}
+	for _, use := range d.config.AllowedUses {
+		if usage, found := keyUsages[use]; found {
+			template.KeyUsage |= usage
+		}
+		if usage, found := extKeyUsages[use]; found {
+			template.ExtKeyUsage = append(template.ExtKeyUsage, usage)
+		}
+	}
+	if d.config.IsCACertificate {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return null, fmt.Errorf("failed to create certificate: %s", err)
+	}
+
+	output := DatasourceOutput{
+		CertPEM:           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		PrivateKeyPEM:     d.config.PrivateKeyPEM,
+		Thumbprint:        thumbprint(der),
+		ValidityStartTime: template.NotBefore.Format(time.RFC3339),
+		ValidityEndTime:   template.NotAfter.Format(time.RFC3339),
+	}
+	if output.PrivateKeyPEM == "" {
+		if output.PrivateKeyPEM, err = tlsprivatekey.PrivateKeyPEM(key); err != nil {
+			return null, err
+		}
+	}
+	if output.PublicKeyPEM, err = tlsprivatekey.PublicKeyPEM(key); err != nil {
+		return null, err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// thumbprint returns the SHA-1 fingerprint of the certificate der, in upper
+// case hexadecimal, the way Windows shows it.
+func thumbprint(der []byte) string {
+	sum := sha1.Sum(der)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
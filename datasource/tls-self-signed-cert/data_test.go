@@ -0,0 +1,124 @@
+package tlsselfsignedcert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	tlsprivatekey "github.com/hashicorp/packer/datasource/tls-private-key"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"defaults", map[string]interface{}{"common_name": "example.com"}, false},
+		{"full", map[string]interface{}{
+			"common_name":     "example.com",
+			"dns_names":       []string{"example.com", "www.example.com"},
+			"ip_addresses":    []string{"10.0.0.1", "::1"},
+			"validity_period": "720h",
+			"allowed_uses":    []string{"digital_signature", "client_auth"},
+		}, false},
+		{"no common name", map[string]interface{}{}, true},
+		{"invalid ip address", map[string]interface{}{"common_name": "example.com", "ip_addresses": []string{"10.0.0"}}, true},
+		{"negative validity period", map[string]interface{}{"common_name": "example.com", "validity_period": "-1h"}, true},
+		{"unknown allowed use", map[string]interface{}{"common_name": "example.com", "allowed_uses": []string{"email_protection"}}, true},
+		{"invalid private key", map[string]interface{}{"common_name": "example.com", "private_key_pem": "not a key"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Datasource{}
+			if err := d.Configure(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatasource_Execute(t *testing.T) {
+	keyConfig := tlsprivatekey.KeyConfig{Algorithm: tlsprivatekey.ECDSA}
+	keyConfig.Prepare()
+	key, err := keyConfig.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err := tlsprivatekey.PrivateKeyPEM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{"generated key", map[string]interface{}{"algorithm": "ECDSA"}},
+		{"given key", map[string]interface{}{"private_key_pem": keyPEM}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["common_name"] = "winrm.example.com"
+			tt.config["dns_names"] = []string{"winrm.example.com"}
+			tt.config["ip_addresses"] = []string{"10.0.0.1"}
+			tt.config["validity_period"] = "24h"
+			d := &Datasource{}
+			if err := d.Configure(tt.config); err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			block, _ := pem.Decode([]byte(got.GetAttr("cert_pem").AsString()))
+			if block == nil {
+				t.Fatal("Execute() cert_pem has no PEM block")
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cert.CheckSignatureFrom(cert); err != nil {
+				t.Errorf("certificate is not self signed: %s", err)
+			}
+			if err := cert.VerifyHostname("winrm.example.com"); err != nil {
+				t.Errorf("VerifyHostname() error = %v", err)
+			}
+			if len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.1")) {
+				t.Errorf("certificate ip addresses = %v, want [10.0.0.1]", cert.IPAddresses)
+			}
+			if validity := cert.NotAfter.Sub(cert.NotBefore); validity != 24*time.Hour {
+				t.Errorf("certificate validity = %s, want 24h", validity)
+			}
+			if got, want := got.GetAttr("thumbprint").AsString(), thumbprint(block.Bytes); got != want {
+				t.Errorf("Execute() thumbprint = %q, want %q", got, want)
+			}
+
+			certKey, err := tlsprivatekey.ParsePrivateKeyPEM(got.GetAttr("private_key_pem").AsString())
+			if err != nil {
+				t.Fatal(err)
+			}
+			publicKeyPEM, err := tlsprivatekey.PublicKeyPEM(certKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := got.GetAttr("public_key_pem").AsString(); got != publicKeyPEM {
+				t.Errorf("Execute() public_key_pem = %q, want %q", got, publicKeyPEM)
+			}
+			der, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if certPublicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})); certPublicKeyPEM != publicKeyPEM {
+				t.Errorf("certificate public key doesn't match private_key_pem")
+			}
+			if _, given := tt.config["private_key_pem"]; given && got.GetAttr("private_key_pem").AsString() != keyPEM {
+				t.Errorf("Execute() private_key_pem isn't the given private key")
+			}
+		})
+	}
+}
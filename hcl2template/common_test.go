@@ -32,8 +32,9 @@ func getBasicParser(opts ...getParserOption) *Parser {
 				"null":           func() (packersdk.Builder, error) { return &null.Builder{}, nil },
 			},
 			Provisioners: packer.MapOfProvisioner{
-				"shell": func() (packersdk.Provisioner, error) { return &MockProvisioner{}, nil },
-				"file":  func() (packersdk.Provisioner, error) { return &MockProvisioner{}, nil },
+				"shell":    func() (packersdk.Provisioner, error) { return &MockProvisioner{}, nil },
+				"file":     func() (packersdk.Provisioner, error) { return &MockProvisioner{}, nil },
+				"wait-for": func() (packersdk.Provisioner, error) { return &MockProvisioner{}, nil },
 			},
 			PostProcessors: packer.MapOfPostProcessor{
 				"amazon-import": func() (packersdk.PostProcessor, error) { return &MockPostProcessor{}, nil },
@@ -358,6 +359,9 @@ var cmpOpts = []cmp.Option{
 	cmpopts.IgnoreFields(PackerConfig{},
 		"Cwd", // Cwd will change for every os type
 	),
+	cmpopts.IgnoreFields(packer.CoreBuild{},
+		"WorkDir", // WorkDir is a new temporary path for every build
+	),
 	cmpopts.IgnoreFields(VariableAssignment{},
 		"Expr", // its an interface
 	),
@@ -0,0 +1,155 @@
+package hcl2template
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// DeprecatedField is a field of the HCL2 spec of a component that was renamed
+// or that is going away.
+type DeprecatedField struct {
+	// Name of the deprecated field.
+	Name string
+	// Replacement is the field to use instead, if any. `packer fix` renames
+	// the deprecated field to it.
+	Replacement string
+	// RemovedIn is the version of the component the field will be removed
+	// in, when known.
+	RemovedIn string
+	// Hint tells how to migrate away from the field when it has no
+	// replacement.
+	Hint string
+}
+
+// deprecatedFields are the deprecated fields of components, by kind of block
+// then by component type. The "*" type matches every component of a kind.
+var deprecatedFields = map[string]map[string][]DeprecatedField{}
+
+// DeprecateFields declares fields of the HCL2 spec of the components of type
+// typ as deprecated. kind is the block the components are used in: "source",
+// "provisioner", "post-processor" or "data"; typ "*" matches every component
+// of kind. Components are served by plugins through their ConfigSpec only, so
+// their deprecations are declared here, next to the spec they apply to.
+func DeprecateFields(kind, typ string, fields ...DeprecatedField) {
+	if deprecatedFields[kind] == nil {
+		deprecatedFields[kind] = map[string][]DeprecatedField{}
+	}
+	deprecatedFields[kind][typ] = append(deprecatedFields[kind][typ], fields...)
+}
+
+func init() {
+	DeprecateFields(sourceLabel, "qemu",
+		DeprecatedField{Name: "ssh_host_port_min", Replacement: "host_port_min"},
+		DeprecatedField{Name: "ssh_host_port_max", Replacement: "host_port_max"},
+	)
+	DeprecateFields(buildPostProcessorLabel, "docker-tag",
+		DeprecatedField{Name: "tag", Replacement: "tags"},
+	)
+}
+
+// deprecatedFieldsOf returns the deprecated fields of the components of type
+// typ used in kind blocks, by name.
+func deprecatedFieldsOf(kind, typ string) map[string]DeprecatedField {
+	fields := map[string]DeprecatedField{}
+	for _, t := range []string{"*", typ} {
+		for _, f := range deprecatedFields[kind][t] {
+			fields[f.Name] = f
+		}
+	}
+	return fields
+}
+
+// deprecatedAttribute is a deprecated field set in a config file.
+type deprecatedAttribute struct {
+	kind, typ string
+	body      *hclsyntax.Body
+	attr      *hclsyntax.Attribute
+	field     DeprecatedField
+}
+
+// deprecatedAttributes returns the deprecated fields set in the component
+// blocks of body, in the order they appear. Only native syntax files are
+// walked.
+func deprecatedAttributes(body hcl.Body) []deprecatedAttribute {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+	var found []deprecatedAttribute
+	check := func(kind string, block *hclsyntax.Block) {
+		if len(block.Labels) == 0 {
+			return
+		}
+		typ := block.Labels[0]
+		fields := deprecatedFieldsOf(kind, typ)
+		for name, attr := range block.Body.Attributes {
+			if field, deprecated := fields[name]; deprecated {
+				found = append(found, deprecatedAttribute{kind, typ, block.Body, attr, field})
+			}
+		}
+	}
+	for _, block := range syntaxBody.Blocks {
+		switch block.Type {
+		case sourceLabel, dataSourceLabel:
+			check(block.Type, block)
+		case buildLabel:
+			for _, inner := range block.Body.Blocks {
+				switch inner.Type {
+				case buildProvisionerLabel, buildErrorCleanupProvisionerLabel:
+					check(buildProvisionerLabel, inner)
+				case buildPostProcessorLabel:
+					check(buildPostProcessorLabel, inner)
+				case buildPostProcessorsLabel:
+					for _, pp := range inner.Body.Blocks {
+						if pp.Type == buildPostProcessorLabel {
+							check(buildPostProcessorLabel, pp)
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].attr.SrcRange.Start.Byte < found[j].attr.SrcRange.Start.Byte
+	})
+	return found
+}
+
+func (d deprecatedAttribute) diagnostic(severity hcl.DiagnosticSeverity) *hcl.Diagnostic {
+	detail := fmt.Sprintf("The %q field of the %q %s is deprecated", d.field.Name, d.typ, d.kind)
+	if d.field.RemovedIn != "" {
+		detail += " and will be removed in version " + d.field.RemovedIn
+	}
+	detail += "."
+	if d.field.Replacement != "" {
+		detail += fmt.Sprintf(" Use %q instead; `packer fix` can rename it for you.", d.field.Replacement)
+	}
+	if d.field.Hint != "" {
+		detail += " " + d.field.Hint
+	}
+	return &hcl.Diagnostic{
+		Severity: severity,
+		Summary:  "Deprecated field",
+		Detail:   detail,
+		Subject:  d.attr.NameRange.Ptr(),
+	}
+}
+
+// checkDeprecatedFields returns a warning for every deprecated field set in
+// the config files, or an error in strict mode.
+func (cfg *PackerConfig) checkDeprecatedFields() hcl.Diagnostics {
+	severity := hcl.DiagWarning
+	if cfg.ValidationOptions.Strict {
+		severity = hcl.DiagError
+	}
+	var diags hcl.Diagnostics
+	for _, file := range cfg.files {
+		for _, d := range deprecatedAttributes(file.Body) {
+			diags = append(diags, d.diagnostic(severity))
+		}
+	}
+	return diags
+}
@@ -0,0 +1,44 @@
+package hcl2template
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestPackerConfig_checkDeprecatedFields(t *testing.T) {
+	src := `source "qemu" "ubuntu" {
+  ssh_host_port_min = 2222
+}
+
+build {
+  sources = ["source.qemu.ubuntu"]
+
+  post-processor "docker-tag" {
+    tag = ["latest"]
+  }
+}
+`
+	file, diags := hclsyntax.ParseConfig([]byte(src), "test.pkr.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	for _, strict := range []bool{false, true} {
+		cfg := &PackerConfig{
+			files:             []*hcl.File{file},
+			ValidationOptions: ValidationOptions{Strict: strict},
+		}
+		diags := cfg.checkDeprecatedFields()
+		if len(diags) != 2 {
+			t.Fatalf("expected 2 diagnostics, got %s", diags)
+		}
+		if diags.HasErrors() != strict {
+			t.Errorf("strict: %t, unexpected diagnostics %s", strict, diags)
+		}
+		if line := diags[0].Subject.Start.Line; line != 2 {
+			t.Errorf("expected the first diagnostic on line 2, got line %d", line)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package hcl2template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ConfigEntry is a value of a resolved configuration, as compared by
+// DiffConfigs.
+type ConfigEntry struct {
+	Value string
+	// Sensitive is set for sensitive variables, and for attributes
+	// referencing them. Their values are compared but never printed.
+	Sensitive bool
+}
+
+// Entries returns a flat description of the resolved configuration, keyed by
+// address: the values of variables, like "var.region", the attributes of
+// sources, like "source.amazon-ebs.ubuntu.ami_name", and the sources,
+// provisioners and post-processors of builds with their attributes, like
+// "build.ubuntu.provisioner[0].inline".
+//
+// Attributes are evaluated when possible; attributes that can only be known
+// once a build is started, like the ones referencing `build.ID`, are described
+// by their expression.
+func (cfg *PackerConfig) Entries() map[string]ConfigEntry {
+	entries := map[string]ConfigEntry{}
+	sensitive := cfg.sensitiveVariables()
+
+	for prefix, vars := range map[string]Variables{
+		inputVariablesAccessor: cfg.InputVariables,
+		localsAccessor:         cfg.LocalVariables,
+	} {
+		for name, v := range vars {
+			entries[prefix+"."+name] = ConfigEntry{
+				Value:     PrintableCtyValue(v.Value()),
+				Sensitive: v.Sensitive,
+			}
+		}
+	}
+
+	ectx := cfg.EvalContext(BuildContext, nil)
+	var files map[string]*hcl.File
+	if cfg.parser != nil {
+		files = cfg.parser.Files()
+	}
+	body := func(prefix string, b hcl.Body) {
+		if b == nil {
+			return
+		}
+		addBodyEntries(entries, prefix, b, ectx, files, sensitive)
+	}
+
+	for ref, source := range cfg.Sources {
+		key := "source." + ref.String()
+		entries[key] = ConfigEntry{Value: ref.String()}
+		if source.block != nil {
+			body(key, source.block.Body)
+		}
+	}
+
+	for i, build := range cfg.Builds {
+		key := fmt.Sprintf("build[%d]", i)
+		if build.Name != "" {
+			key = "build." + build.Name
+		}
+		var sources []string
+		for _, source := range build.Sources {
+			sources = append(sources, source.String())
+			body(key+".source."+source.String(), source.Body)
+		}
+		entries[key+".sources"] = ConfigEntry{Value: strings.Join(sources, ", ")}
+		for j, prov := range build.ProvisionerBlocks {
+			provKey := fmt.Sprintf("%s.provisioner[%d]", key, j)
+			entries[provKey] = ConfigEntry{Value: componentName(prov.PType, prov.PName)}
+			body(provKey, prov.Rest)
+		}
+		if prov := build.ErrorCleanupProvisionerBlock; prov != nil {
+			provKey := key + ".error-cleanup-provisioner"
+			entries[provKey] = ConfigEntry{Value: componentName(prov.PType, prov.PName)}
+			body(provKey, prov.Rest)
+		}
+		for j, ppList := range build.PostProcessorsLists {
+			for k, pp := range ppList {
+				ppKey := fmt.Sprintf("%s.post-processors[%d][%d]", key, j, k)
+				entries[ppKey] = ConfigEntry{Value: componentName(pp.PType, pp.PName)}
+				body(ppKey, pp.Rest)
+			}
+		}
+	}
+	return entries
+}
+
+func componentName(typ, name string) string {
+	if name == "" {
+		return typ
+	}
+	return typ + "." + name
+}
+
+// addBodyEntries adds the attributes of b, and of its nested blocks, to
+// entries.
+func addBodyEntries(entries map[string]ConfigEntry, prefix string, b hcl.Body, ectx *hcl.EvalContext, files map[string]*hcl.File, sensitive map[string]bool) {
+	syntaxBody, ok := b.(*hclsyntax.Body)
+	if !ok {
+		// json bodies can't be walked without a schema, only the attributes
+		// of flat bodies are described.
+		attrs, _ := b.JustAttributes()
+		for name, attr := range attrs {
+			entries[prefix+"."+name] = attributeEntry(attr.Expr, ectx, files, sensitive)
+		}
+		return
+	}
+	for name, attr := range syntaxBody.Attributes {
+		entries[prefix+"."+name] = attributeEntry(attr.Expr, ectx, files, sensitive)
+	}
+	counts := map[string]int{}
+	for _, block := range syntaxBody.Blocks {
+		base := prefix + "." + strings.Join(append([]string{block.Type}, block.Labels...), ".")
+		key := fmt.Sprintf("%s[%d]", base, counts[base])
+		counts[base]++
+		addBodyEntries(entries, key, block.Body, ectx, files, sensitive)
+	}
+}
+
+// sensitiveVariables returns the sensitive input and local variables, keyed
+// by how they are referenced, like "var.password".
+func (cfg *PackerConfig) sensitiveVariables() map[string]bool {
+	sensitive := map[string]bool{}
+	for prefix, vars := range map[string]Variables{
+		inputVariablesAccessor: cfg.InputVariables,
+		localsAccessor:         cfg.LocalVariables,
+	} {
+		for name, v := range vars {
+			if v.Sensitive {
+				sensitive[prefix+"."+name] = true
+			}
+		}
+	}
+	return sensitive
+}
+
+// referencesSensitive tells whether expr references one of the sensitive
+// variables, as returned by sensitiveVariables.
+func referencesSensitive(expr hcl.Expression, sensitive map[string]bool) bool {
+	for _, traversal := range expr.Variables() {
+		if len(traversal) < 2 {
+			continue
+		}
+		if attr, ok := traversal[1].(hcl.TraverseAttr); ok && sensitive[traversal.RootName()+"."+attr.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+func attributeEntry(expr hcl.Expression, ectx *hcl.EvalContext, files map[string]*hcl.File, sensitive map[string]bool) ConfigEntry {
+	entry := ConfigEntry{Sensitive: referencesSensitive(expr, sensitive)}
+	val, diags := expr.Value(ectx)
+	if !diags.HasErrors() && val.IsWhollyKnown() {
+		entry.Value = PrintableCtyValue(val)
+		return entry
+	}
+	rng := expr.Range()
+	if f, found := files[rng.Filename]; found {
+		entry.Value = string(rng.SliceBytes(f.Bytes))
+	} else {
+		entry.Value = "<unknown>"
+	}
+	return entry
+}
+
+// ConfigChange is a difference between two configurations, Old is nil for
+// added entries, New for removed ones.
+type ConfigChange struct {
+	Key      string
+	Old, New *ConfigEntry
+}
+
+func (c ConfigChange) String() string {
+	printable := func(e *ConfigEntry) string {
+		if e.Sensitive {
+			return "(sensitive value)"
+		}
+		return e.Value
+	}
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("+ %s = %s", c.Key, printable(c.New))
+	case c.New == nil:
+		return fmt.Sprintf("- %s = %s", c.Key, printable(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s => %s", c.Key, printable(c.Old), printable(c.New))
+	}
+}
+
+// DiffConfigs returns the changes between the entries of two configurations,
+// sorted by key.
+func DiffConfigs(old, new map[string]ConfigEntry) []ConfigChange {
+	var changes []ConfigChange
+	for key, o := range old {
+		o := o
+		n, found := new[key]
+		switch {
+		case !found:
+			changes = append(changes, ConfigChange{Key: key, Old: &o})
+		case n.Value != o.Value:
+			changes = append(changes, ConfigChange{Key: key, Old: &o, New: &n})
+		}
+	}
+	for key, n := range new {
+		n := n
+		if _, found := old[key]; !found {
+			changes = append(changes, ConfigChange{Key: key, New: &n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
@@ -0,0 +1,50 @@
+package hcl2template
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	entries := func(dir string) map[string]ConfigEntry {
+		cfg, diags := getBasicParser().Parse(filepath.Join("testdata", "diff", dir), nil, nil)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		if diags := cfg.Initialize(packer.InitializeOptions{}); diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		return cfg.Entries()
+	}
+
+	changes := DiffConfigs(entries("old"), entries("new"))
+	var keys []string
+	got := map[string]string{}
+	for _, change := range changes {
+		keys = append(keys, change.Key)
+		got[change.Key] = change.String()
+	}
+	wantKeys := []string{
+		"build.ubuntu.provisioner[0].inline",
+		"build.ubuntu.provisioner[1]",
+		"build.ubuntu.provisioner[1].destination",
+		"build.ubuntu.provisioner[1].source",
+		"var.region",
+		"var.token",
+	}
+	if diff := cmp.Diff(wantKeys, keys); diff != "" {
+		t.Fatalf("DiffConfigs(): unexpected changes %s", diff)
+	}
+	for key, want := range map[string]string{
+		"build.ubuntu.provisioner[1]": `+ build.ubuntu.provisioner[1] = file`,
+		"var.region":                  `~ var.region: "us-east-1" => "eu-west-1"`,
+		"var.token":                   `~ var.token: (sensitive value) => (sensitive value)`,
+	} {
+		if got[key] != want {
+			t.Errorf("change of %s: got %q, want %q", key, got[key], want)
+		}
+	}
+}
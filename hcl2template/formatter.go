@@ -158,7 +158,7 @@ func (f *HCL2Formatter) processFile(filename string) ([]byte, error) {
 	}
 
 	if f.ShowDiff {
-		diff, err := bytesDiff(inSrc, outSrc, filename)
+		diff, err := BytesDiff(inSrc, outSrc, filename)
 		if err != nil {
 			return outSrc, fmt.Errorf("failed to generate diff for %s: %s", filename, err)
 		}
@@ -168,9 +168,10 @@ func (f *HCL2Formatter) processFile(filename string) ([]byte, error) {
 	return outSrc, nil
 }
 
-// bytesDiff returns the unified diff of b1 and b2
+// BytesDiff returns the unified diff of b1 and b2, the old and new content of
+// the file path.
 // Shamelessly copied from Terraform's fmt command.
-func bytesDiff(b1, b2 []byte, path string) (data []byte, err error) {
+func BytesDiff(b1, b2 []byte, path string) (data []byte, err error) {
 	f1, err := ioutil.TempFile("", "")
 	if err != nil {
 		return
@@ -0,0 +1,120 @@
+package function
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/mitchellh/go-homedir"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// StoredArtifactType is the type of an artifact of a stored build.
+var StoredArtifactType = cty.Object(map[string]cty.Type{
+	"builder_id":  cty.String,
+	"id":          cty.String,
+	"description": cty.String,
+	"files":       cty.List(cty.String),
+})
+
+// StoredBuildType is the type of a stored build, as returned by artifact.
+var StoredBuildType = cty.Object(map[string]cty.Type{
+	"build_name": cty.String,
+	"version":    cty.String,
+	"time":       cty.String,
+	"artifacts":  cty.List(StoredArtifactType),
+})
+
+var artifactParams = []function.Parameter{
+	{
+		Name: "store_path",
+		Type: cty.String,
+	},
+	{
+		Name: "lineage",
+		Type: cty.String,
+	},
+	{
+		Name: "channel",
+		Type: cty.String,
+	},
+}
+
+// MakeArtifactFunc constructs a function that returns the version of a build
+// promoted to a channel of a local artifact store, with
+// `packer artifacts promote`. A relative store path is relative to baseDir.
+func MakeArtifactFunc(baseDir string) function.Function {
+	return function.New(&function.Spec{
+		Params: artifactParams,
+		Type:   function.StaticReturnType(StoredBuildType),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			build, err := promotedBuild(baseDir, args)
+			if err != nil {
+				return cty.UnknownVal(retType), err
+			}
+			artifacts := make([]cty.Value, 0, len(build.Artifacts))
+			for _, a := range build.Artifacts {
+				files := cty.ListValEmpty(cty.String)
+				if len(a.Files) > 0 {
+					vals := make([]cty.Value, 0, len(a.Files))
+					for _, f := range a.Files {
+						vals = append(vals, cty.StringVal(f))
+					}
+					files = cty.ListVal(vals)
+				}
+				artifacts = append(artifacts, cty.ObjectVal(map[string]cty.Value{
+					"builder_id":  cty.StringVal(a.BuilderId),
+					"id":          cty.StringVal(a.Id),
+					"description": cty.StringVal(a.Description),
+					"files":       files,
+				}))
+			}
+			list := cty.ListValEmpty(StoredArtifactType)
+			if len(artifacts) > 0 {
+				list = cty.ListVal(artifacts)
+			}
+			return cty.ObjectVal(map[string]cty.Value{
+				"build_name": cty.StringVal(build.BuildName),
+				"version":    cty.StringVal(build.Version),
+				"time":       cty.StringVal(build.Time.Format(time.RFC3339)),
+				"artifacts":  list,
+			}), nil
+		},
+	})
+}
+
+// MakeArtifactIDFunc constructs a function that returns the id of the last
+// artifact of the version of a build promoted to a channel of a local artifact
+// store, that is the artifact of its last post-processor, or of its builder
+// when it has none. Like an AMI, it can be used as the source image of another
+// build.
+func MakeArtifactIDFunc(baseDir string) function.Function {
+	return function.New(&function.Spec{
+		Params: artifactParams,
+		Type:   function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			build, err := promotedBuild(baseDir, args)
+			if err != nil {
+				return cty.UnknownVal(retType), err
+			}
+			if len(build.Artifacts) == 0 {
+				return cty.UnknownVal(retType), fmt.Errorf("version %s of %s has no artifact", build.Version, build.BuildName)
+			}
+			return cty.StringVal(build.Artifacts[len(build.Artifacts)-1].Id), nil
+		},
+	})
+}
+
+func promotedBuild(baseDir string, args []cty.Value) (*packer.StoredBuild, error) {
+	path, err := homedir.Expand(args[0].AsString())
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand ~: %s", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	store := &packer.LocalArtifactStore{Dir: path}
+	return store.Channel(args[1].AsString(), args[2].AsString())
+}
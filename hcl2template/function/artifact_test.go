@@ -0,0 +1,55 @@
+package function
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer/packer"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestArtifactID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-artifact-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &packer.LocalArtifactStore{Dir: filepath.Join(dir, "artifacts")}
+	artifacts := []packersdk.Artifact{
+		&packersdk.MockArtifact{BuilderIdValue: "amazon-ebs", IdValue: "us-east-1:ami-0123456789abcdef0"},
+		&packersdk.MockArtifact{BuilderIdValue: "manifest", IdValue: "us-east-1:ami-0fedcba9876543210"},
+	}
+	if err := store.Store(context.Background(), "amazon-ebs.base", artifacts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Promote("amazon-ebs.base", packer.LatestArtifactVersion, "stable"); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []cty.Value{cty.StringVal("artifacts"), cty.StringVal("amazon-ebs.base"), cty.StringVal("stable")}
+	got, err := MakeArtifactIDFunc(dir).Call(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := cty.StringVal("us-east-1:ami-0fedcba9876543210"); !got.RawEquals(want) {
+		t.Errorf("wrong result %#v, want %#v", got, want)
+	}
+
+	build, err := MakeArtifactFunc(dir).Call(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := build.GetAttr("artifacts").LengthInt(); n != 2 {
+		t.Errorf("expected 2 artifacts, got %d", n)
+	}
+
+	args[2] = cty.StringVal("unknown")
+	if _, err := MakeArtifactIDFunc(dir).Call(args); err == nil {
+		t.Error("expected an error for a channel without version")
+	}
+}
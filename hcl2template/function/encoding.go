@@ -0,0 +1,115 @@
+package function
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// Base64GzipFunc constructs a function that compresses a given string with
+// gzip and then encodes the result in Base64.
+var Base64GzipFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name: "str",
+			Type: cty.String,
+		},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		s := args[0].AsString()
+
+		var b bytes.Buffer
+		gz := gzip.NewWriter(&b)
+		if _, err := gz.Write([]byte(s)); err != nil {
+			return cty.UnknownVal(cty.String), fmt.Errorf("failed to write gzip raw data: %s", err)
+		}
+		if err := gz.Flush(); err != nil {
+			return cty.UnknownVal(cty.String), fmt.Errorf("failed to flush gzip writer: %s", err)
+		}
+		if err := gz.Close(); err != nil {
+			return cty.UnknownVal(cty.String), fmt.Errorf("failed to close gzip writer: %s", err)
+		}
+		return cty.StringVal(base64.StdEncoding.EncodeToString(b.Bytes())), nil
+	},
+})
+
+// TextEncodeBase64Func constructs a function that encodes a given string
+// using the given character encoding, like UTF-16LE, and then encodes the
+// result in Base64.
+var TextEncodeBase64Func = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name: "string",
+			Type: cty.String,
+		},
+		{
+			Name: "encoding",
+			Type: cty.String,
+		},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		encName := args[1].AsString()
+		enc, err := ianaindex.IANA.Encoding(encName)
+		if err != nil || enc == nil {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(1, "%q is not a supported IANA encoding name or alias", encName)
+		}
+
+		encoded, err := enc.NewEncoder().String(args[0].AsString())
+		if err != nil {
+			return cty.UnknownVal(cty.String), function.NewArgErrorf(0, "the given string contains characters that cannot be represented in %s", encName)
+		}
+		return cty.StringVal(base64.StdEncoding.EncodeToString([]byte(encoded))), nil
+	},
+})
+
+// JSONEncodePrettyFunc constructs a function that is like jsonencode, except
+// that the result is indented with two spaces, to be readable.
+var JSONEncodePrettyFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "val",
+			Type:             cty.DynamicPseudoType,
+			AllowDynamicType: true,
+			AllowNull:        true,
+		},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		compact, err := stdlib.JSONEncode(args[0])
+		if err != nil || !compact.IsKnown() {
+			return compact, err
+		}
+
+		var out bytes.Buffer
+		if err := json.Indent(&out, []byte(compact.AsString()), "", "  "); err != nil {
+			return cty.UnknownVal(cty.String), err
+		}
+		return cty.StringVal(out.String()), nil
+	},
+})
+
+// Base64Gzip compresses a string with gzip and then encodes the result in
+// Base64.
+func Base64Gzip(str cty.Value) (cty.Value, error) {
+	return Base64GzipFunc.Call([]cty.Value{str})
+}
+
+// TextEncodeBase64 encodes a string in the given character encoding and then
+// encodes the result in Base64.
+func TextEncodeBase64(str, enc cty.Value) (cty.Value, error) {
+	return TextEncodeBase64Func.Call([]cty.Value{str, enc})
+}
+
+// JSONEncodePretty returns an indented JSON encoding of a value.
+func JSONEncodePretty(val cty.Value) (cty.Value, error) {
+	return JSONEncodePrettyFunc.Call([]cty.Value{val})
+}
@@ -0,0 +1,118 @@
+package function
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestBase64Gzip(t *testing.T) {
+	got, err := Base64Gzip(cty.StringVal("test"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the compressed bytes depend on the gzip implementation, decode them
+	// back instead of comparing them.
+	compressed, err := base64.StdEncoding.DecodeString(got.AsString())
+	if err != nil {
+		t.Fatalf("result is not base64: %s", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("result is not gzipped: %s", err)
+	}
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "test" {
+		t.Errorf("wrong result %q", raw)
+	}
+}
+
+func TestTextEncodeBase64(t *testing.T) {
+	tests := []struct {
+		String   cty.Value
+		Encoding cty.Value
+		Want     cty.Value
+		Err      bool
+	}{
+		{
+			cty.StringVal("abc123!?$*&()'-=@~"),
+			cty.StringVal("UTF-16LE"),
+			cty.StringVal("YQBiAGMAMQAyADMAIQA/ACQAKgAmACgAKQAnAC0APQBAAH4A"),
+			false,
+		},
+		{
+			cty.StringVal("abc123!?$*&()'-=@~"),
+			cty.StringVal("UTF-8"),
+			cty.StringVal("YWJjMTIzIT8kKiYoKSctPUB+"),
+			false,
+		},
+		{
+			cty.StringVal("abc"),
+			cty.StringVal("NOT-AN-ENCODING"),
+			cty.NilVal,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("textencodebase64(%#v, %#v)", test.String, test.Encoding), func(t *testing.T) {
+			got, err := TextEncodeBase64(test.String, test.Encoding)
+
+			if test.Err {
+				if err == nil {
+					t.Fatal("succeeded; want error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !got.RawEquals(test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestJSONEncodePretty(t *testing.T) {
+	tests := []struct {
+		Val  cty.Value
+		Want cty.Value
+	}{
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"packages": cty.ListVal([]cty.Value{cty.StringVal("git")}),
+			}),
+			cty.StringVal("{\n  \"packages\": [\n    \"git\"\n  ]\n}"),
+		},
+		{
+			cty.StringVal("hello"),
+			cty.StringVal(`"hello"`),
+		},
+		{
+			cty.UnknownVal(cty.String),
+			cty.UnknownVal(cty.String),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("jsonencode_pretty(%#v)", test.Val), func(t *testing.T) {
+			got, err := JSONEncodePretty(test.Val)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !got.RawEquals(test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
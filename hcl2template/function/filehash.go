@@ -0,0 +1,79 @@
+package function
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// MakeFileMd5Func constructs a function that is like md5 except that it
+// reads the contents of a file rather than hashing a given literal string.
+func MakeFileMd5Func(baseDir string) function.Function {
+	return makeFileHashFunction(baseDir, md5.New)
+}
+
+// MakeFileSha1Func constructs a function that is like sha1 except that it
+// reads the contents of a file rather than hashing a given literal string.
+func MakeFileSha1Func(baseDir string) function.Function {
+	return makeFileHashFunction(baseDir, sha1.New)
+}
+
+// MakeFileSha256Func constructs a function that is like sha256 except that it
+// reads the contents of a file rather than hashing a given literal string.
+func MakeFileSha256Func(baseDir string) function.Function {
+	return makeFileHashFunction(baseDir, sha256.New)
+}
+
+// MakeFileSha512Func constructs a function that is like sha512 except that it
+// reads the contents of a file rather than hashing a given literal string.
+func MakeFileSha512Func(baseDir string) function.Function {
+	return makeFileHashFunction(baseDir, sha512.New)
+}
+
+// makeFileHashFunction hashes the raw bytes of a file, so that binary files
+// can be hashed too. Relative paths are relative to baseDir.
+func makeFileHashFunction(baseDir string, hf func() hash.Hash) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{
+				Name: "path",
+				Type: cty.String,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path, err := homedir.Expand(args[0].AsString())
+			if err != nil {
+				return cty.UnknownVal(cty.String), fmt.Errorf("failed to expand ~: %s", err)
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(baseDir, path)
+			}
+			// Ensure that the path is canonical for the host OS
+			path = filepath.Clean(path)
+
+			f, err := os.Open(path)
+			if err != nil {
+				return cty.UnknownVal(cty.String), fmt.Errorf("failed to open %s: %s", path, err)
+			}
+			defer f.Close()
+
+			h := hf()
+			if _, err := io.Copy(h, f); err != nil {
+				return cty.UnknownVal(cty.String), fmt.Errorf("failed to read %s: %s", path, err)
+			}
+			return cty.StringVal(hex.EncodeToString(h.Sum(nil))), nil
+		},
+	})
+}
@@ -0,0 +1,68 @@
+package function
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+func TestFileHashFunctions(t *testing.T) {
+	tests := []struct {
+		Func function.Function
+		Path cty.Value
+		Want cty.Value
+		Err  bool
+	}{
+		{
+			MakeFileMd5Func("testdata/filehash"),
+			cty.StringVal("hello.txt"),
+			cty.StringVal("5eb63bbbe01eeed093cb22bb8f5acdc3"),
+			false,
+		},
+		{
+			MakeFileSha1Func("testdata/filehash"),
+			cty.StringVal("hello.txt"),
+			cty.StringVal("2aae6c35c94fcfb415dbe95f408b9ce91ee846ed"),
+			false,
+		},
+		{
+			MakeFileSha256Func("testdata"),
+			cty.StringVal("filehash/hello.txt"),
+			cty.StringVal("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"),
+			false,
+		},
+		{
+			MakeFileSha512Func("testdata/filehash"),
+			cty.StringVal("hello.txt"),
+			cty.StringVal("309ecc489c12d6eb4cc40f50c902f2b4d0ed77ee511a7c7a9bcd3ca86d4cd86f989dd35bc5ff499670da34255b45b0cfd830e81f605dcf7dc5542e93ae9cd76f"),
+			false,
+		},
+		{
+			MakeFileSha256Func("testdata/filehash"),
+			cty.StringVal("missing.txt"),
+			cty.NilVal,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%#v", test.Path), func(t *testing.T) {
+			got, err := test.Func.Call([]cty.Value{test.Path})
+
+			if test.Err {
+				if err == nil {
+					t.Fatal("succeeded; want error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !got.RawEquals(test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package function
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// ImageRefType is the type of an image reference, as returned by
+// parse_image_ref. Attributes that don't apply to the cloud of the image are
+// empty strings.
+var ImageRefType = cty.Object(map[string]cty.Type{
+	"cloud":           cty.String,
+	"id":              cty.String,
+	"name":            cty.String,
+	"family":          cty.String,
+	"region":          cty.String,
+	"project":         cty.String,
+	"subscription_id": cty.String,
+	"resource_group":  cty.String,
+	"gallery":         cty.String,
+	"version":         cty.String,
+})
+
+var (
+	amiIDRe  = regexp.MustCompile(`^ami-[0-9a-f]{8,17}$`)
+	amiARNRe = regexp.MustCompile(`^arn:aws[a-z-]*:ec2:([a-z0-9-]*):[0-9]*:image/(ami-[0-9a-f]{8,17})$`)
+
+	azureImageRe   = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/Microsoft\.Compute/images/([^/]+)$`)
+	azureGalleryRe = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/Microsoft\.Compute/galleries/([^/]+)/images/([^/]+)(?:/versions/([^/]+))?$`)
+
+	gceImageRe = regexp.MustCompile(`^(?:https://(?:www|compute)\.googleapis\.com/compute/(?:v1|beta|alpha)/)?projects/([^/]+)/global/images/(family/)?([^/]+)$`)
+)
+
+// ParseImageRefFunc constructs a function that parses an AMI ID or ARN, an
+// Azure managed or shared gallery image ID, or a GCE image self-link into an
+// image reference object.
+var ParseImageRefFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name: "ref",
+			Type: cty.String,
+		},
+	},
+	Type: function.StaticReturnType(ImageRefType),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		ref := strings.TrimSpace(args[0].AsString())
+		attrs := map[string]string{}
+
+		switch {
+		case amiIDRe.MatchString(ref):
+			attrs["cloud"], attrs["id"] = "aws", ref
+		case amiARNRe.MatchString(ref):
+			m := amiARNRe.FindStringSubmatch(ref)
+			attrs["cloud"], attrs["region"], attrs["id"] = "aws", m[1], m[2]
+		case azureImageRe.MatchString(ref):
+			m := azureImageRe.FindStringSubmatch(ref)
+			attrs["cloud"], attrs["id"] = "azure", ref
+			attrs["subscription_id"], attrs["resource_group"], attrs["name"] = m[1], m[2], m[3]
+		case azureGalleryRe.MatchString(ref):
+			m := azureGalleryRe.FindStringSubmatch(ref)
+			attrs["cloud"], attrs["id"] = "azure", ref
+			attrs["subscription_id"], attrs["resource_group"] = m[1], m[2]
+			attrs["gallery"], attrs["name"], attrs["version"] = m[3], m[4], m[5]
+		case gceImageRe.MatchString(ref):
+			m := gceImageRe.FindStringSubmatch(ref)
+			attrs["cloud"], attrs["project"] = "gce", m[1]
+			if m[2] != "" {
+				attrs["family"] = m[3]
+			} else {
+				attrs["name"] = m[3]
+			}
+			attrs["id"] = formatGCEImage(attrs)
+		default:
+			return cty.NilVal, fmt.Errorf("%q is not a known AMI, Azure image or GCE image reference", ref)
+		}
+
+		return imageRefVal(attrs), nil
+	},
+})
+
+// FormatImageRefFunc constructs a function that formats an image reference
+// object, like the ones returned by parse_image_ref, into the identifier
+// expected by the builders of its cloud: an AMI ID, an Azure image ID or a
+// GCE image self-link. Only the attributes required by the cloud need to be
+// set.
+var FormatImageRefFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name: "image_ref",
+			Type: cty.DynamicPseudoType,
+		},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		ref := args[0]
+		if !ref.Type().IsObjectType() && !ref.Type().IsMapType() {
+			return cty.NilVal, fmt.Errorf("image_ref must be an object, got %s", ref.Type().FriendlyName())
+		}
+
+		if !ref.IsWhollyKnown() {
+			return cty.UnknownVal(cty.String), nil
+		}
+
+		attrs := map[string]string{}
+		for it := ref.ElementIterator(); it.Next(); {
+			k, v := it.Element()
+			if v.IsNull() || !v.Type().Equals(cty.String) {
+				continue
+			}
+			attrs[k.AsString()] = v.AsString()
+		}
+
+		missing := func(names ...string) error {
+			for _, name := range names {
+				if attrs[name] == "" {
+					return fmt.Errorf("%s image_ref requires the %q attribute", attrs["cloud"], name)
+				}
+			}
+			return nil
+		}
+
+		switch attrs["cloud"] {
+		case "aws":
+			if err := missing("id"); err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(attrs["id"]), nil
+		case "azure":
+			if err := missing("subscription_id", "resource_group", "name"); err != nil {
+				return cty.NilVal, err
+			}
+			id := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute",
+				attrs["subscription_id"], attrs["resource_group"])
+			if attrs["gallery"] == "" {
+				return cty.StringVal(id + "/images/" + attrs["name"]), nil
+			}
+			id += "/galleries/" + attrs["gallery"] + "/images/" + attrs["name"]
+			if attrs["version"] != "" {
+				id += "/versions/" + attrs["version"]
+			}
+			return cty.StringVal(id), nil
+		case "gce":
+			if err := missing("project"); err != nil {
+				return cty.NilVal, err
+			}
+			if attrs["name"] == "" && attrs["family"] == "" {
+				return cty.NilVal, fmt.Errorf("gce image_ref requires either the \"name\" or the \"family\" attribute")
+			}
+			return cty.StringVal(formatGCEImage(attrs)), nil
+		default:
+			return cty.NilVal, fmt.Errorf("unknown image_ref cloud %q, expected one of aws, azure or gce", attrs["cloud"])
+		}
+	},
+})
+
+func formatGCEImage(attrs map[string]string) string {
+	link := "https://www.googleapis.com/compute/v1/projects/" + attrs["project"] + "/global/images/"
+	if attrs["name"] != "" {
+		return link + attrs["name"]
+	}
+	return link + "family/" + attrs["family"]
+}
+
+func imageRefVal(attrs map[string]string) cty.Value {
+	vals := map[string]cty.Value{}
+	for name := range ImageRefType.AttributeTypes() {
+		vals[name] = cty.StringVal(attrs[name])
+	}
+	return cty.ObjectVal(vals)
+}
+
+// ParseImageRef parses an image reference string into an image reference
+// object.
+func ParseImageRef(ref cty.Value) (cty.Value, error) {
+	return ParseImageRefFunc.Call([]cty.Value{ref})
+}
+
+// FormatImageRef formats an image reference object into a string.
+func FormatImageRef(ref cty.Value) (cty.Value, error) {
+	return FormatImageRefFunc.Call([]cty.Value{ref})
+}
@@ -0,0 +1,180 @@
+package function
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		Ref  string
+		Want map[string]string
+		Err  bool
+	}{
+		{
+			"ami-0123456789abcdef0",
+			map[string]string{"cloud": "aws", "id": "ami-0123456789abcdef0"},
+			false,
+		},
+		{
+			"arn:aws:ec2:eu-west-1::image/ami-0123456789abcdef0",
+			map[string]string{"cloud": "aws", "id": "ami-0123456789abcdef0", "region": "eu-west-1"},
+			false,
+		},
+		{
+			"/subscriptions/0000/resourceGroups/images/providers/Microsoft.Compute/images/ubuntu",
+			map[string]string{
+				"cloud":           "azure",
+				"id":              "/subscriptions/0000/resourceGroups/images/providers/Microsoft.Compute/images/ubuntu",
+				"subscription_id": "0000",
+				"resource_group":  "images",
+				"name":            "ubuntu",
+			},
+			false,
+		},
+		{
+			"/subscriptions/0000/resourceGroups/images/providers/Microsoft.Compute/galleries/shared/images/ubuntu/versions/1.0.0",
+			map[string]string{
+				"cloud":           "azure",
+				"id":              "/subscriptions/0000/resourceGroups/images/providers/Microsoft.Compute/galleries/shared/images/ubuntu/versions/1.0.0",
+				"subscription_id": "0000",
+				"resource_group":  "images",
+				"gallery":         "shared",
+				"name":            "ubuntu",
+				"version":         "1.0.0",
+			},
+			false,
+		},
+		{
+			"projects/debian-cloud/global/images/family/debian-10",
+			map[string]string{
+				"cloud":   "gce",
+				"id":      "https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/family/debian-10",
+				"project": "debian-cloud",
+				"family":  "debian-10",
+			},
+			false,
+		},
+		{
+			"https://www.googleapis.com/compute/v1/projects/my-project/global/images/base-1",
+			map[string]string{
+				"cloud":   "gce",
+				"id":      "https://www.googleapis.com/compute/v1/projects/my-project/global/images/base-1",
+				"project": "my-project",
+				"name":    "base-1",
+			},
+			false,
+		},
+		{
+			"ubuntu-20.04",
+			nil,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Ref, func(t *testing.T) {
+			got, err := ParseImageRef(cty.StringVal(test.Ref))
+
+			if test.Err {
+				if err == nil {
+					t.Fatal("succeeded; want error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			want := imageRefVal(test.Want)
+			if !got.RawEquals(want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, want)
+			}
+
+			formatted, err := FormatImageRef(got)
+			if err != nil {
+				t.Fatalf("unexpected error formatting %#v: %s", got, err)
+			}
+			if formatted.AsString() != test.Want["id"] {
+				t.Errorf("wrong formatted reference %q, want %q", formatted.AsString(), test.Want["id"])
+			}
+		})
+	}
+}
+
+func TestFormatImageRef(t *testing.T) {
+	tests := []struct {
+		Ref  cty.Value
+		Want cty.Value
+		Err  bool
+	}{
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"cloud":   cty.StringVal("gce"),
+				"project": cty.StringVal("debian-cloud"),
+				"family":  cty.StringVal("debian-10"),
+			}),
+			cty.StringVal("https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/family/debian-10"),
+			false,
+		},
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"cloud":           cty.StringVal("azure"),
+				"subscription_id": cty.StringVal("0000"),
+				"resource_group":  cty.StringVal("images"),
+				"gallery":         cty.StringVal("shared"),
+				"name":            cty.StringVal("ubuntu"),
+			}),
+			cty.StringVal("/subscriptions/0000/resourceGroups/images/providers/Microsoft.Compute/galleries/shared/images/ubuntu"),
+			false,
+		},
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"cloud": cty.StringVal("aws"),
+				"id":    cty.UnknownVal(cty.String),
+			}),
+			cty.UnknownVal(cty.String),
+			false,
+		},
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"cloud": cty.StringVal("azure"),
+				"name":  cty.StringVal("ubuntu"),
+			}),
+			cty.NilVal,
+			true,
+		},
+		{
+			cty.ObjectVal(map[string]cty.Value{
+				"cloud": cty.StringVal("openstack"),
+			}),
+			cty.NilVal,
+			true,
+		},
+		{
+			cty.StringVal("ami-0123456789abcdef0"),
+			cty.NilVal,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("format_image_ref(%#v)", test.Ref), func(t *testing.T) {
+			got, err := FormatImageRef(test.Ref)
+
+			if test.Err {
+				if err == nil {
+					t.Fatal("succeeded; want error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !got.RawEquals(test.Want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.Want)
+			}
+		})
+	}
+}
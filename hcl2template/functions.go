@@ -30,10 +30,13 @@ func Functions(basedir string) map[string]function.Function {
 	funcs := map[string]function.Function{
 		"abs":                stdlib.AbsoluteFunc,
 		"abspath":            filesystem.AbsPathFunc,
+		"artifact":           pkrfunction.MakeArtifactFunc(basedir),
+		"artifact_id":        pkrfunction.MakeArtifactIDFunc(basedir),
 		"aws_secretsmanager": pkrfunction.AWSSecret,
 		"basename":           filesystem.BasenameFunc,
 		"base64decode":       encoding.Base64DecodeFunc,
 		"base64encode":       encoding.Base64EncodeFunc,
+		"base64gzip":         pkrfunction.Base64GzipFunc,
 		"bcrypt":             crypto.BcryptFunc,
 		"can":                tryfunc.CanFunc,
 		"ceil":               stdlib.CeilFunc,
@@ -56,17 +59,23 @@ func Functions(basedir string) map[string]function.Function {
 		"element":            stdlib.ElementFunc,
 		"file":               filesystem.MakeFileFunc(basedir, false),
 		"fileexists":         filesystem.MakeFileExistsFunc(basedir),
+		"filemd5":            pkrfunction.MakeFileMd5Func(basedir),
 		"fileset":            filesystem.MakeFileSetFunc(basedir),
+		"filesha1":           pkrfunction.MakeFileSha1Func(basedir),
+		"filesha256":         pkrfunction.MakeFileSha256Func(basedir),
+		"filesha512":         pkrfunction.MakeFileSha512Func(basedir),
 		"flatten":            stdlib.FlattenFunc,
 		"floor":              stdlib.FloorFunc,
 		"format":             stdlib.FormatFunc,
 		"formatdate":         stdlib.FormatDateFunc,
 		"formatlist":         stdlib.FormatListFunc,
+		"format_image_ref":   pkrfunction.FormatImageRefFunc,
 		"indent":             stdlib.IndentFunc,
 		"index":              pkrfunction.IndexFunc, // stdlib.IndexFunc is not compatible
 		"join":               stdlib.JoinFunc,
 		"jsondecode":         stdlib.JSONDecodeFunc,
 		"jsonencode":         stdlib.JSONEncodeFunc,
+		"jsonencode_pretty":  pkrfunction.JSONEncodePrettyFunc,
 		"keys":               stdlib.KeysFunc,
 		"legacy_isotime":     pkrfunction.LegacyIsotimeFunc,
 		"length":             pkrfunction.LengthFunc,
@@ -78,6 +87,7 @@ func Functions(basedir string) map[string]function.Function {
 		"merge":              stdlib.MergeFunc,
 		"min":                stdlib.MinFunc,
 		"parseint":           stdlib.ParseIntFunc,
+		"parse_image_ref":    pkrfunction.ParseImageRefFunc,
 		"pathexpand":         filesystem.PathExpandFunc,
 		"pow":                stdlib.PowFunc,
 		"range":              stdlib.RangeFunc,
@@ -99,6 +109,7 @@ func Functions(basedir string) map[string]function.Function {
 		"split":              stdlib.SplitFunc,
 		"strrev":             stdlib.ReverseFunc,
 		"substr":             stdlib.SubstrFunc,
+		"textencodebase64":   pkrfunction.TextEncodeBase64Func,
 		"timestamp":          pkrfunction.TimestampFunc,
 		"timeadd":            stdlib.TimeAddFunc,
 		"title":              stdlib.TitleFunc,
@@ -0,0 +1,287 @@
+package hcl2template
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Edit replaces the bytes of Range of a config file with Text.
+type Edit struct {
+	Range hcl.Range
+	Text  string
+}
+
+// A Migration rewrites HCL2 config files written for an older version of
+// Packer, or of its plugins. It is the HCL2 counterpart of fix.Fixer.
+type Migration interface {
+	// Synopsis returns a short description of the migration.
+	Synopsis() string
+
+	// Edits returns the edits migrating body, the body of a config file of
+	// content src. Edits must not overlap.
+	Edits(body *hclsyntax.Body, src []byte) ([]Edit, hcl.Diagnostics)
+}
+
+// Migrations are the HCL2 migrations, by name. They are applied by MigrateFile
+// in MigrationOrder.
+var Migrations = map[string]Migration{
+	"deprecated-fields": new(deprecatedFieldsMigration),
+	"component-renames": new(componentRenamesMigration),
+	"plugin-sources":    new(pluginSourcesMigration),
+}
+
+// MigrationOrder is the order in which the migrations are applied.
+var MigrationOrder = []string{
+	"component-renames",
+	"deprecated-fields",
+	"plugin-sources",
+}
+
+// MigrateFile applies the migrations to src, the content of the HCL2 config
+// file filename, and returns the migrated content. The file is parsed again
+// after each migration, so that a migration sees the changes of the previous
+// ones. A formatted file stays formatted.
+func MigrateFile(filename string, src []byte) ([]byte, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	migrated := src
+	for _, name := range MigrationOrder {
+		migration, ok := Migrations[name]
+		if !ok {
+			panic("migration not found: " + name)
+		}
+		file, moreDiags := hclsyntax.ParseConfig(migrated, filename, hcl.InitialPos)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return nil, diags
+		}
+		edits, moreDiags := migration.Edits(file.Body.(*hclsyntax.Body), migrated)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			return nil, diags
+		}
+		migrated = applyEdits(migrated, edits)
+	}
+	if bytes.Equal(hclwrite.Format(src), src) {
+		migrated = hclwrite.Format(migrated)
+	}
+	return migrated, diags
+}
+
+// applyEdits returns src with edits applied.
+func applyEdits(src []byte, edits []Edit) []byte {
+	if len(edits) == 0 {
+		return src
+	}
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Range.Start.Byte < edits[j].Range.Start.Byte
+	})
+	out := make([]byte, 0, len(src))
+	last := 0
+	for _, edit := range edits {
+		out = append(out, src[last:edit.Range.Start.Byte]...)
+		out = append(out, edit.Text...)
+		last = edit.Range.End.Byte
+	}
+	return append(out, src[last:]...)
+}
+
+// lineRange returns rng widened to its indentation and trailing newline, to
+// remove a whole line.
+func lineRange(src []byte, rng hcl.Range) hcl.Range {
+	for rng.Start.Byte > 0 && (src[rng.Start.Byte-1] == ' ' || src[rng.Start.Byte-1] == '\t') {
+		rng.Start.Byte--
+	}
+	if rng.End.Byte < len(src) && src[rng.End.Byte] == '\n' {
+		rng.End.Byte++
+	}
+	return rng
+}
+
+// literalString returns the value of expr when it is a string without
+// interpolations.
+func literalString(expr hclsyntax.Expression) (string, bool) {
+	tpl, ok := expr.(*hclsyntax.TemplateExpr)
+	if !ok || !tpl.IsStringLiteral() {
+		return "", false
+	}
+	v, diags := tpl.Value(nil)
+	if diags.HasErrors() || v.IsNull() {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+type deprecatedFieldsMigration struct{}
+
+func (*deprecatedFieldsMigration) Synopsis() string {
+	return "Renames the deprecated fields of components to their replacement"
+}
+
+// Edits renames the deprecated fields of body to their replacement. A
+// deprecated field is removed when its replacement is set too. Fields without
+// replacement are left untouched, and reported as warnings.
+func (*deprecatedFieldsMigration) Edits(body *hclsyntax.Body, src []byte) ([]Edit, hcl.Diagnostics) {
+	var edits []Edit
+	var diags hcl.Diagnostics
+	for _, d := range deprecatedAttributes(body) {
+		switch _, set := d.body.Attributes[d.field.Replacement]; {
+		case d.field.Replacement == "":
+			diags = append(diags, d.diagnostic(hcl.DiagWarning))
+		case set:
+			edits = append(edits, Edit{Range: lineRange(src, d.attr.SrcRange)})
+		default:
+			edits = append(edits, Edit{Range: d.attr.NameRange, Text: d.field.Replacement})
+		}
+	}
+	return edits, diags
+}
+
+// componentRenames are the new types of renamed components, by kind of block
+// then by old type.
+var componentRenames = map[string]map[string]string{}
+
+// RenameComponent declares that the components of type from used in kind
+// blocks are now of type to. kind is "source", "provisioner",
+// "post-processor" or "data".
+func RenameComponent(kind, from, to string) {
+	if componentRenames[kind] == nil {
+		componentRenames[kind] = map[string]string{}
+	}
+	componentRenames[kind][from] = to
+}
+
+func init() {
+	RenameComponent(sourceLabel, "proxmox", "proxmox-iso")
+}
+
+type componentRenamesMigration struct{}
+
+func (*componentRenamesMigration) Synopsis() string {
+	return "Updates the type of renamed components, and the sources referencing them"
+}
+
+// Edits updates the type label of the blocks of renamed components, and the
+// "source.<type>.<name>" references to renamed sources of build blocks.
+func (*componentRenamesMigration) Edits(body *hclsyntax.Body, _ []byte) ([]Edit, hcl.Diagnostics) {
+	var edits []Edit
+	rename := func(kind string, block *hclsyntax.Block) {
+		if len(block.Labels) == 0 {
+			return
+		}
+		if to, renamed := componentRenames[kind][block.Labels[0]]; renamed {
+			edits = append(edits, Edit{Range: block.LabelRanges[0], Text: strconv.Quote(to)})
+		}
+	}
+	renameRef := func(ref string, rng hcl.Range) {
+		parts := strings.Split(ref, ".")
+		if len(parts) != 3 || parts[0] != sourceLabel {
+			return
+		}
+		if to, renamed := componentRenames[sourceLabel][parts[1]]; renamed {
+			parts[1] = to
+			edits = append(edits, Edit{Range: rng, Text: strconv.Quote(strings.Join(parts, "."))})
+		}
+	}
+
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case sourceLabel, dataSourceLabel:
+			rename(block.Type, block)
+		case buildLabel:
+			if attr, ok := block.Body.Attributes["sources"]; ok {
+				if tuple, ok := attr.Expr.(*hclsyntax.TupleConsExpr); ok {
+					for _, expr := range tuple.Exprs {
+						if ref, ok := literalString(expr); ok {
+							renameRef(ref, expr.Range())
+						}
+					}
+				}
+			}
+			for _, inner := range block.Body.Blocks {
+				switch inner.Type {
+				case buildSourceLabel:
+					if len(inner.Labels) > 0 {
+						renameRef(inner.Labels[0], inner.LabelRanges[0])
+					}
+				case buildProvisionerLabel, buildErrorCleanupProvisionerLabel:
+					rename(buildProvisionerLabel, inner)
+				case buildPostProcessorLabel:
+					rename(buildPostProcessorLabel, inner)
+				case buildPostProcessorsLabel:
+					for _, pp := range inner.Body.Blocks {
+						if pp.Type == buildPostProcessorLabel {
+							rename(buildPostProcessorLabel, pp)
+						}
+					}
+				}
+			}
+		}
+	}
+	return edits, nil
+}
+
+// pluginSourceMoves are the new sources of plugins that moved, by old source.
+var pluginSourceMoves = map[string]string{}
+
+// MovePluginSource declares that the plugin of source from, like
+// "github.com/hashicorp/proxmox", is now released from source to.
+func MovePluginSource(from, to string) {
+	pluginSourceMoves[strings.ToLower(from)] = to
+}
+
+type pluginSourcesMigration struct{}
+
+func (*pluginSourcesMigration) Synopsis() string {
+	return "Updates the source of required plugins that moved"
+}
+
+// Edits updates the source of the required plugins of body that moved.
+func (*pluginSourcesMigration) Edits(body *hclsyntax.Body, _ []byte) ([]Edit, hcl.Diagnostics) {
+	var edits []Edit
+	var diags hcl.Diagnostics
+	for _, block := range body.Blocks {
+		if block.Type != packerLabel {
+			continue
+		}
+		for _, inner := range block.Body.Blocks {
+			if inner.Type != "required_plugins" {
+				continue
+			}
+			for name, attr := range inner.Body.Attributes {
+				obj, ok := attr.Expr.(*hclsyntax.ObjectConsExpr)
+				if !ok {
+					continue
+				}
+				for _, item := range obj.Items {
+					if key := hcl.ExprAsKeyword(item.KeyExpr); key != "source" {
+						continue
+					}
+					source, ok := literalString(item.ValueExpr)
+					if !ok {
+						continue
+					}
+					to, moved := pluginSourceMoves[strings.ToLower(source)]
+					if !moved {
+						continue
+					}
+					edits = append(edits, Edit{Range: item.ValueExpr.Range(), Text: strconv.Quote(to)})
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagWarning,
+						Summary:  "Plugin source moved",
+						Detail: fmt.Sprintf("The %q plugin moved from %q to %q; run `packer init` "+
+							"to install it from its new source.", name, source, to),
+						Subject: item.ValueExpr.Range().Ptr(),
+					})
+				}
+			}
+		}
+	}
+	return edits, diags
+}
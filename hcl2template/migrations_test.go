@@ -0,0 +1,150 @@
+package hcl2template
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMigrateFile(t *testing.T) {
+	MovePluginSource("github.com/old-org/example", "github.com/new-org/example")
+	defer delete(pluginSourceMoves, "github.com/old-org/example")
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"renamed",
+			`source "qemu" "ubuntu" {
+  ssh_host_port_min = 2222
+  ssh_host_port_max = 4444
+}
+`,
+			`source "qemu" "ubuntu" {
+  host_port_min = 2222
+  host_port_max = 4444
+}
+`,
+		},
+		{
+			"replacement already set",
+			`build {
+  post-processor "docker-tag" {
+    repository = "hashicorp/packer"
+    tag        = ["latest"]
+    tags       = ["1.7"]
+  }
+}
+`,
+			`build {
+  post-processor "docker-tag" {
+    repository = "hashicorp/packer"
+    tags       = ["1.7"]
+  }
+}
+`,
+		},
+		{
+			"unformatted file stays unformatted",
+			`build {
+  post-processors {
+    post-processor "docker-tag" {
+      tag=["latest"]
+    }
+  }
+}
+`,
+			`build {
+  post-processors {
+    post-processor "docker-tag" {
+      tags=["latest"]
+    }
+  }
+}
+`,
+		},
+		{
+			"other component",
+			`source "null" "ubuntu" {
+  ssh_host_port_min = 2222
+}
+`,
+			`source "null" "ubuntu" {
+  ssh_host_port_min = 2222
+}
+`,
+		},
+		{
+			"renamed component",
+			`source "proxmox" "ubuntu" {
+  node = "pve"
+}
+
+build {
+  sources = ["source.proxmox.ubuntu", "source.null.ubuntu"]
+
+  source "source.proxmox.ubuntu" {
+    name = "other"
+  }
+}
+`,
+			`source "proxmox-iso" "ubuntu" {
+  node = "pve"
+}
+
+build {
+  sources = ["source.proxmox-iso.ubuntu", "source.null.ubuntu"]
+
+  source "source.proxmox-iso.ubuntu" {
+    name = "other"
+  }
+}
+`,
+		},
+		{
+			"moved plugin source",
+			`packer {
+  required_plugins {
+    example = {
+      version = ">= 1.0.0"
+      source  = "github.com/old-org/example"
+    }
+  }
+}
+`,
+			`packer {
+  required_plugins {
+    example = {
+      version = ">= 1.0.0"
+      source  = "github.com/new-org/example"
+    }
+  }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := MigrateFile("test.pkr.hcl", []byte(tt.src))
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("unexpected migrated file: %s", diff)
+			}
+		})
+	}
+}
+
+func TestMigrationOrder(t *testing.T) {
+	if len(MigrationOrder) != len(Migrations) {
+		t.Fatalf("MigrationOrder has %d migrations, Migrations %d", len(MigrationOrder), len(Migrations))
+	}
+	for _, name := range MigrationOrder {
+		if _, ok := Migrations[name]; !ok {
+			t.Errorf("migration %q is not registered", name)
+		}
+	}
+}
@@ -11,19 +11,21 @@ import (
 	"github.com/hashicorp/hcl/v2/hclparse"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer/packer"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
 	"github.com/zclconf/go-cty/cty"
 )
 
 const (
-	packerLabel       = "packer"
-	sourceLabel       = "source"
-	variablesLabel    = "variables"
-	variableLabel     = "variable"
-	localsLabel       = "locals"
-	localLabel        = "local"
-	dataSourceLabel   = "data"
-	buildLabel        = "build"
-	communicatorLabel = "communicator"
+	packerLabel        = "packer"
+	sourceLabel        = "source"
+	variablesLabel     = "variables"
+	variableLabel      = "variable"
+	localsLabel        = "locals"
+	localLabel         = "local"
+	dataSourceLabel    = "data"
+	buildLabel         = "build"
+	communicatorLabel  = "communicator"
+	artifactStoreLabel = "artifact_store"
 )
 
 var configSchema = &hcl.BodySchema{
@@ -37,6 +39,7 @@ var configSchema = &hcl.BodySchema{
 		{Type: dataSourceLabel, LabelNames: []string{"type", "name"}},
 		{Type: buildLabel},
 		{Type: communicatorLabel, LabelNames: []string{"type", "name"}},
+		{Type: artifactStoreLabel, LabelNames: []string{"type"}},
 	},
 }
 
@@ -45,6 +48,7 @@ var configSchema = &hcl.BodySchema{
 var packerBlockSchema = &hcl.BodySchema{
 	Attributes: []hcl.AttributeSchema{
 		{Name: "required_version"},
+		{Name: "experiments"},
 	},
 	Blocks: []hcl.BlockHeaderSchema{
 		{Type: "required_plugins"},
@@ -63,6 +67,15 @@ type Parser struct {
 	*hclparse.Parser
 
 	PluginConfig *packer.PluginConfig
+
+	// InstallSummaries, when set, holds the summaries written by packer init,
+	// used to find plugin binaries without listing every installation.
+	InstallSummaries *plugingetter.InstallSummaryCache
+
+	// ValidationOptions are the options of the configs parsed, in strict mode
+	// undefined variables, deprecated fields and, when reported, unused
+	// variables and plugins are errors.
+	ValidationOptions ValidationOptions
 }
 
 const (
@@ -137,6 +150,7 @@ func (p *Parser) Parse(filename string, varFiles []string, argVars map[string]st
 		Basedir:                 basedir,
 		Cwd:                     wd,
 		CorePackerVersionString: p.CorePackerVersionString,
+		ValidationOptions:       p.ValidationOptions,
 		parser:                  p,
 		files:                   files,
 	}
@@ -156,6 +170,10 @@ func (p *Parser) Parse(filename string, varFiles []string, argVars map[string]st
 		return cfg, diags
 	}
 
+	for _, file := range files {
+		diags = append(diags, cfg.decodeExperiments(file)...)
+	}
+
 	// Decode required_plugins blocks and create implicit required_plugins
 	// blocks. Implicit required_plugins blocks happen when a builder or another
 	// plugin cannot be found, for example if one uses :
@@ -290,16 +308,21 @@ func filterVarsFromLogs(inputOrLocal Variables) {
 		if !variable.Sensitive {
 			continue
 		}
-		value := variable.Value()
-		_ = cty.Walk(value, func(_ cty.Path, nested cty.Value) (bool, error) {
-			if nested.IsWhollyKnown() && !nested.IsNull() && nested.Type().Equals(cty.String) {
-				packersdk.LogSecretFilter.Set(nested.AsString())
-			}
-			return true, nil
-		})
+		filterValueFromLogs(variable.Value())
 	}
 }
 
+// filterValueFromLogs hides every known string nested in value from the logs
+// and the UI.
+func filterValueFromLogs(value cty.Value) {
+	_ = cty.Walk(value, func(_ cty.Path, nested cty.Value) (bool, error) {
+		if nested.IsWhollyKnown() && !nested.IsNull() && nested.Type().Equals(cty.String) {
+			packersdk.LogSecretFilter.Set(nested.AsString())
+		}
+		return true, nil
+	})
+}
+
 func (cfg *PackerConfig) Initialize(opts packer.InitializeOptions) hcl.Diagnostics {
 	var diags hcl.Diagnostics
 
@@ -327,6 +350,10 @@ func (cfg *PackerConfig) Initialize(opts packer.InitializeOptions) hcl.Diagnosti
 
 	diags = append(diags, cfg.initializeBlocks()...)
 
+	if cfg.ValidationOptions.WarnOnUnused {
+		diags = append(diags, cfg.checkUnused()...)
+	}
+
 	return diags
 }
 
@@ -376,6 +403,25 @@ func (p *Parser) parseConfig(f *hcl.File, cfg *PackerConfig) hcl.Diagnostics {
 			}
 			cfg.Builds = append(cfg.Builds, build)
 
+		case artifactStoreLabel:
+			store, moreDiags := p.decodeArtifactStore(block, cfg)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				continue
+			}
+			if cfg.ArtifactStore != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate " + artifactStoreLabel + " block",
+					Detail: fmt.Sprintf("An "+artifactStoreLabel+" block is already "+
+						"declared at %s. Artifacts can only be stored in one place.",
+						cfg.ArtifactStore.HCL2Ref.DefRange.Ptr()),
+					Subject: block.DefRange.Ptr(),
+				})
+				continue
+			}
+			cfg.ArtifactStore = store
+
 		}
 	}
 
@@ -1,9 +1,10 @@
 package hcl2template
 
 import (
-	"crypto/sha256"
+	"context"
 	"fmt"
 	"log"
+	"path/filepath"
 	"runtime"
 
 	"github.com/hashicorp/hcl/v2"
@@ -41,7 +42,11 @@ func (cfg *PackerConfig) PluginRequirements() (plugingetter.Requirements, hcl.Di
 				Accessor:           name,
 				Identifier:         block.Type,
 				VersionConstraints: block.Requirement.Required,
+				Latest:             block.Requirement.Latest,
 				Implicit:           block.PluginDependencyReason == PluginDependencyImplicit,
+				IncludePrereleases: block.IncludePrereleases,
+				Checksums:          block.Checksums,
+				Getter:             block.Getter,
 			})
 			uniq[name] = block
 		}
@@ -59,9 +64,7 @@ func (cfg *PackerConfig) detectPluginBinaries() hcl.Diagnostics {
 			ARCH:            runtime.GOARCH,
 			APIVersionMajor: pluginsdk.APIVersionMajor,
 			APIVersionMinor: pluginsdk.APIVersionMinor,
-			Checksummers: []plugingetter.Checksummer{
-				{Type: "sha256", Hash: sha256.New()},
-			},
+			Checksummers:    plugingetter.DefaultChecksummers(),
 		},
 	}
 
@@ -74,8 +77,65 @@ func (cfg *PackerConfig) detectPluginBinaries() hcl.Diagnostics {
 		return diags
 	}
 
+	// the versions recorded by packer init in the lock file are used, when
+	// there is one.
+	lockPath := filepath.Join(cfg.Basedir, plugingetter.LockFileName)
+	lockFile, err := plugingetter.ReadLockFile(lockPath)
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Failed to read lock file %s", lockPath),
+			Detail:   err.Error(),
+		})
+	}
+
+	// plugins that moved are used from the source address packer init
+	// installed them from.
+	aliases, err := plugingetter.SourceAliasesFromEnv()
+	if err != nil {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid plugin source aliases",
+			Detail:   err.Error(),
+		})
+	}
+	for _, pluginRequirement := range pluginReqs {
+		err := pluginRequirement.ResolveRedirects(context.Background(), plugingetter.RedirectOptions{
+			Aliases:  aliases,
+			LockFile: lockFile,
+		})
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Failed to follow the redirects of %s", pluginRequirement.Identifier),
+				Detail:   err.Error(),
+			})
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+
+	// the binaries resolved by packer init are used as long as nothing
+	// changed since.
+	fingerprint := plugingetter.Fingerprint(pluginReqs, opts, lockFile)
+	if paths, ok := cfg.parser.InstallSummaries.Get(fingerprint).Resolved(pluginReqs); ok {
+		log.Printf("[TRACE] using the plugins resolved by packer init: %v", paths)
+		for _, pluginRequirement := range pluginReqs {
+			err := cfg.parser.PluginConfig.DiscoverMultiPlugin(pluginRequirement.Accessor, paths[pluginRequirement.Accessor])
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("Error discovering plugin %s", pluginRequirement.Identifier),
+					Detail:   err.Error(),
+				})
+			}
+		}
+		return diags
+	}
+
 	for _, pluginRequirement := range pluginReqs {
-		sortedInstalls, err := pluginRequirement.ListInstallations(opts)
+		sortedInstalls, err := pluginRequirement.ListInstallations(context.Background(), opts)
 		if err != nil {
 			diags = append(diags, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
@@ -94,6 +154,25 @@ func (cfg *PackerConfig) detectPluginBinaries() hcl.Diagnostics {
 		}
 		log.Printf("[TRACE] Found the following %q installations: %v", pluginRequirement.Identifier, sortedInstalls)
 		install := sortedInstalls[len(sortedInstalls)-1]
+		if locked := lockFile.Plugin(pluginRequirement.Identifier); locked != nil {
+			if err := locked.Validate(pluginRequirement); err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("Locked version of %s does not match its requirement", pluginRequirement.Identifier),
+					Detail:   err.Error(),
+				})
+				continue
+			}
+			install = locked.Select(sortedInstalls)
+			if install == nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("%s is locked at version %s, which is not installed", pluginRequirement.Identifier, locked.Version),
+					Detail:   "Did you run packer init for this project ?",
+				})
+				continue
+			}
+		}
 		err = cfg.parser.PluginConfig.DiscoverMultiPlugin(pluginRequirement.Accessor, install.BinaryPath)
 		if err != nil {
 			diags = append(diags, &hcl.Diagnostic{
@@ -0,0 +1,11 @@
+
+build {
+    sources = [
+        "source.virtualbox-iso.ubuntu-1204",
+    ]
+
+    concurrency_group = "pxe-subnet"
+}
+
+source "virtualbox-iso" "ubuntu-1204" {
+}
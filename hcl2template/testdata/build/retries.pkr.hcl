@@ -0,0 +1,13 @@
+
+build {
+    sources = [
+        "source.virtualbox-iso.ubuntu-1204",
+    ]
+
+    retries       = 3
+    retry_backoff = "1m"
+    retry_on      = ["capacity"]
+}
+
+source "virtualbox-iso" "ubuntu-1204" {
+}
@@ -0,0 +1,12 @@
+
+build {
+    sources = [
+        "source.virtualbox-iso.ubuntu-1204",
+    ]
+
+    retries  = 3
+    retry_on = ["bad-luck"]
+}
+
+source "virtualbox-iso" "ubuntu-1204" {
+}
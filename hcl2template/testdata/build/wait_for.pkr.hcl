@@ -0,0 +1,18 @@
+
+// a wait_for block runs in order with the other provisioners.
+build {
+    sources = [
+        "source.virtualbox-iso.ubuntu-1204",
+    ]
+
+    provisioner "shell" {
+    }
+    wait_for {
+        name = "domain-join"
+    }
+    provisioner "file" {
+    }
+}
+
+source "virtualbox-iso" "ubuntu-1204" {
+}
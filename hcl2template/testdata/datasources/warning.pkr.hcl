@@ -0,0 +1,3 @@
+data "warning" "test" {
+  string = "string"
+}
@@ -0,0 +1,26 @@
+variable "region" {
+  default = "eu-west-1"
+}
+
+variable "token" {
+  default   = "other secret"
+  sensitive = true
+}
+
+source "null" "ubuntu" {
+  communicator = "none"
+}
+
+build {
+  name    = "ubuntu"
+  sources = ["source.null.ubuntu"]
+
+  provisioner "shell" {
+    inline = ["echo ${var.region}"]
+  }
+
+  provisioner "file" {
+    source      = "app.tar.gz"
+    destination = "/tmp/app.tar.gz"
+  }
+}
@@ -0,0 +1,21 @@
+variable "region" {
+  default = "us-east-1"
+}
+
+variable "token" {
+  default   = "secret"
+  sensitive = true
+}
+
+source "null" "ubuntu" {
+  communicator = "none"
+}
+
+build {
+  name    = "ubuntu"
+  sources = ["source.null.ubuntu"]
+
+  provisioner "shell" {
+    inline = ["echo ${var.region}"]
+  }
+}
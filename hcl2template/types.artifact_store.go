@@ -0,0 +1,97 @@
+package hcl2template
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/packer/packer"
+)
+
+// ArtifactStoreBlock references an HCL 'artifact_store' block, it sets where
+// the artifacts of every build are pushed, for example :
+//
+//	artifact_store "http" {
+//		url     = "https://artifacts.example.com/packer"
+//		headers = { Authorization = "Bearer ${var.token}" }
+//	}
+type ArtifactStoreBlock struct {
+	// Type is local or http.
+	Type string
+
+	// Path is the directory of a local store.
+	Path string
+	// URL and Headers configure an http store.
+	URL     string
+	Headers map[string]string
+
+	// Labels are recorded with the artifacts of every build.
+	Labels map[string]string
+
+	HCL2Ref HCL2Ref
+}
+
+func (p *Parser) decodeArtifactStore(block *hcl.Block, cfg *PackerConfig) (*ArtifactStoreBlock, hcl.Diagnostics) {
+	store := &ArtifactStoreBlock{
+		Type:    block.Labels[0],
+		HCL2Ref: newHCL2Ref(block, nil),
+	}
+
+	var b struct {
+		Path    string            `hcl:"path,optional"`
+		URL     string            `hcl:"url,optional"`
+		Headers map[string]string `hcl:"headers,optional"`
+		Labels  map[string]string `hcl:"labels,optional"`
+	}
+	diags := gohcl.DecodeBody(block.Body, cfg.EvalContext(DatasourceContext, nil), &b)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	store.Path = b.Path
+	store.URL = b.URL
+	store.Headers = b.Headers
+	store.Labels = b.Labels
+
+	switch store.Type {
+	case "local":
+		if store.Path == "" {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing path",
+				Detail:   "A local " + artifactStoreLabel + " must set the directory to store artifacts in.",
+				Subject:  attributeRange(block, "path"),
+			})
+		}
+	case "http":
+		if u, err := url.Parse(store.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid url",
+				Detail:   fmt.Sprintf("An http %s must set an http or https url, got %q.", artifactStoreLabel, store.URL),
+				Subject:  attributeRange(block, "url"),
+			})
+		}
+	default:
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Unknown %s type %q", artifactStoreLabel, store.Type),
+			Detail:   "Artifacts can be stored in a local directory or uploaded to an http server, with the local and http types.",
+			Subject:  block.LabelRanges[0].Ptr(),
+		})
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return store, diags
+}
+
+// Store returns the packer.ArtifactStore configured by the block.
+func (b *ArtifactStoreBlock) Store() packer.ArtifactStore {
+	switch b.Type {
+	case "http":
+		return &packer.HTTPArtifactStore{URL: b.URL, Headers: b.Headers, Labels: b.Labels}
+	default:
+		return &packer.LocalArtifactStore{Dir: b.Path, Labels: b.Labels}
+	}
+}
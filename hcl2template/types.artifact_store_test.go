@@ -0,0 +1,46 @@
+package hcl2template
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestParser_decodeArtifactStore_diagnosticRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantLine int
+	}{
+		{"empty path", `
+artifact_store "local" {
+  labels = {}
+  path   = ""
+}`, 4},
+		{"missing path", `
+artifact_store "local" {
+}`, 2},
+		{"invalid url", `
+artifact_store "http" {
+  headers = {}
+  url     = "ftp://example.com"
+}`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := getBasicParser()
+			file, diags := parser.ParseHCL([]byte(tt.template), "main.pkr.hcl")
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			block := file.Body.(*hclsyntax.Body).Blocks[0].AsHCLBlock()
+			_, diags = parser.decodeArtifactStore(block, &PackerConfig{parser: parser})
+			if len(diags) != 1 {
+				t.Fatalf("expected one diagnostic, got %v", diags)
+			}
+			if line := diags[0].Subject.Start.Line; line != tt.wantLine {
+				t.Fatalf("expected the diagnostic to point at line %d, got %d", tt.wantLine, line)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package hcl2template
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// decodeArtifactsDir evaluates the artifacts_dir of a build block for one of
+// its sources, as an absolute path so that provisioners and post-processors
+// find it whatever their working directory. It is empty when not set.
+func (b *BuildBlock) decodeArtifactsDir(ectx *hcl.EvalContext) (string, hcl.Diagnostics) {
+	if b.ArtifactsDir == nil {
+		return "", nil
+	}
+	val, diags := b.ArtifactsDir.Value(ectx)
+	if diags.HasErrors() || val.IsNull() {
+		return "", diags
+	}
+	val, err := convert.Convert(val, cty.String)
+	if err != nil || !val.IsKnown() || val.IsNull() || val.AsString() == "" {
+		detail := "artifacts_dir must be a path."
+		if err != nil {
+			detail = fmt.Sprintf("artifacts_dir must be a path: %s.", err)
+		}
+		return "", append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid artifacts_dir",
+			Detail:   detail,
+			Subject:  b.ArtifactsDir.Range().Ptr(),
+		})
+	}
+	dir, err := filepath.Abs(val.AsString())
+	if err != nil {
+		return "", append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid artifacts_dir",
+			Detail:   err.Error(),
+			Subject:  b.ArtifactsDir.Range().Ptr(),
+		})
+	}
+	return dir, diags
+}
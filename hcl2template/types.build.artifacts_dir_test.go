@@ -0,0 +1,48 @@
+package hcl2template
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestBuildBlock_decodeArtifactsDir(t *testing.T) {
+	abs, err := filepath.Abs(filepath.Join("output", "ubuntu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		expr     string
+		want     string
+		wantDiag bool
+	}{
+		{"relative", `"output/${source.name}"`, abs, false},
+		{"null", `null`, "", false},
+		{"empty", `""`, "", true},
+		{"not a string", `["output"]`, "", true},
+	}
+	ectx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"source": cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("ubuntu")}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tt.expr), "test.pkr.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			got, diags := (&BuildBlock{ArtifactsDir: expr}).decodeArtifactsDir(ectx)
+			if diags.HasErrors() != tt.wantDiag {
+				t.Fatalf("unexpected diagnostics: %s", diags)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
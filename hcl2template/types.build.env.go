@@ -0,0 +1,152 @@
+package hcl2template
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Provisioners take their environment variables from one of these fields.
+const (
+	provisionerEnvAttr     = "env"
+	provisionerEnvVarsAttr = "environment_vars"
+)
+
+// decodeEnv evaluates the env map of a build block for one of its sources.
+// The values computed from sensitive variables, as told by sensitive, are
+// hidden from the logs as a whole, since they are merged in the configuration
+// of every provisioner. Passwords of the URLs of its values, like the ones of
+// proxy settings, are hidden too, as they can be logged on their own.
+func (b *BuildBlock) decodeEnv(ectx *hcl.EvalContext, sensitive map[string]bool) (map[string]string, hcl.Diagnostics) {
+	if b.Env == nil {
+		return nil, nil
+	}
+	val, diags := b.Env.Value(ectx)
+	if diags.HasErrors() || val.IsNull() {
+		return nil, diags
+	}
+	val, err := convert.Convert(val, cty.Map(cty.String))
+	if err != nil || !val.IsWhollyKnown() {
+		detail := "env must be a map of strings."
+		if err != nil {
+			detail = fmt.Sprintf("env must be a map of strings: %s.", err)
+		}
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid env",
+			Detail:   detail,
+			Subject:  b.Env.Range().Ptr(),
+		})
+	}
+
+	sensitiveKeys := envSensitiveKeys(b.Env, ectx, sensitive)
+	env := map[string]string{}
+	for k, v := range val.AsValueMap() {
+		if v.IsNull() {
+			continue
+		}
+		env[k] = v.AsString()
+		if env[k] != "" && (sensitiveKeys == nil || sensitiveKeys[k]) {
+			packersdk.LogSecretFilter.Set(env[k])
+		}
+		if u, err := url.Parse(env[k]); err == nil && u.User != nil {
+			if password, set := u.User.Password(); set && password != "" {
+				packersdk.LogSecretFilter.Set(password)
+			}
+		}
+	}
+	return env, diags
+}
+
+// envSensitiveKeys returns the keys of the env map expr whose value
+// references a sensitive variable. It returns nil when every value is to be
+// considered sensitive: when expr is not a map literal, like `var.env`, and
+// references a sensitive variable.
+func envSensitiveKeys(expr hcl.Expression, ectx *hcl.EvalContext, sensitive map[string]bool) map[string]bool {
+	keys := map[string]bool{}
+	cons, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		if referencesSensitive(expr, sensitive) {
+			return nil
+		}
+		return keys
+	}
+	for _, item := range cons.Items {
+		if !referencesSensitive(item.ValueExpr, sensitive) {
+			continue
+		}
+		key, diags := item.KeyExpr.Value(ectx)
+		if diags.HasErrors() || !key.IsWhollyKnown() || key.IsNull() {
+			// the key can't be told, all values are hidden.
+			return nil
+		}
+		key, err := convert.Convert(key, cty.String)
+		if err != nil {
+			return nil
+		}
+		keys[key.AsString()] = true
+	}
+	return keys
+}
+
+// mergeBuildEnv adds env, the environment variables of a build block, to the
+// environment variables of cfg, the decoded configuration of a provisioner.
+// The variables of the provisioner take precedence. Provisioners without an
+// env map nor an environment_vars list are left untouched.
+func mergeBuildEnv(cfg cty.Value, env map[string]string) cty.Value {
+	if len(env) == 0 || cfg.IsNull() || !cfg.IsKnown() || !cfg.Type().IsObjectType() {
+		return cfg
+	}
+	attrs := cfg.AsValueMap()
+
+	switch {
+	case cfg.Type().HasAttribute(provisionerEnvAttr) &&
+		cfg.Type().AttributeType(provisionerEnvAttr).Equals(cty.Map(cty.String)):
+		current := attrs[provisionerEnvAttr]
+		if !current.IsKnown() {
+			return cfg
+		}
+		merged := map[string]cty.Value{}
+		for k, v := range env {
+			merged[k] = cty.StringVal(v)
+		}
+		if !current.IsNull() {
+			for k, v := range current.AsValueMap() {
+				merged[k] = v
+			}
+		}
+		attrs[provisionerEnvAttr] = cty.MapVal(merged)
+
+	case cfg.Type().HasAttribute(provisionerEnvVarsAttr) &&
+		cfg.Type().AttributeType(provisionerEnvVarsAttr).Equals(cty.List(cty.String)):
+		current := attrs[provisionerEnvVarsAttr]
+		if !current.IsKnown() {
+			return cfg
+		}
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		// variables are exported in order, so the ones of the provisioner
+		// come last to win.
+		vars := make([]cty.Value, 0, len(env))
+		for _, k := range keys {
+			vars = append(vars, cty.StringVal(k+"="+env[k]))
+		}
+		if !current.IsNull() {
+			vars = append(vars, current.AsValueSlice()...)
+		}
+		attrs[provisionerEnvVarsAttr] = cty.ListVal(vars)
+
+	default:
+		return cfg
+	}
+	return cty.ObjectVal(attrs)
+}
@@ -2,10 +2,13 @@ package hcl2template
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/packer/packer"
 )
 
 const (
@@ -17,6 +20,8 @@ const (
 
 	buildErrorCleanupProvisionerLabel = "error-cleanup-provisioner"
 
+	buildWaitForLabel = "wait_for"
+
 	buildPostProcessorLabel = "post-processor"
 
 	buildPostProcessorsLabel = "post-processors"
@@ -28,6 +33,7 @@ var buildSchema = &hcl.BodySchema{
 		{Type: sourceLabel, LabelNames: []string{"reference"}},
 		{Type: buildProvisionerLabel, LabelNames: []string{"type"}},
 		{Type: buildErrorCleanupProvisionerLabel, LabelNames: []string{"type"}},
+		{Type: buildWaitForLabel, LabelNames: []string{}},
 		{Type: buildPostProcessorLabel, LabelNames: []string{"type"}},
 		{Type: buildPostProcessorsLabel, LabelNames: []string{}},
 	},
@@ -72,6 +78,28 @@ type BuildBlock struct {
 	// steps.
 	PostProcessorsLists [][]*PostProcessorBlock
 
+	// Retries is the number of times a build is run again after failing with
+	// an error of one of the RetryOn classes.
+	Retries int
+	// RetryBackoff is the time waited before the first retry, it doubles
+	// after each attempt.
+	RetryBackoff time.Duration
+	// RetryOn is the list of packer.RetryableErrorClasses to retry on, all
+	// of them when empty.
+	RetryOn []string
+
+	// ConcurrencyGroup is the name of a group of builds that never run at
+	// the same time, even when builds are run in parallel.
+	ConcurrencyGroup string
+
+	// Env is the map of environment variables added to the environment of
+	// every provisioner of the build, evaluated for each source.
+	Env hcl.Expression
+
+	// ArtifactsDir is the directory where provisioners and post-processors
+	// drop the files to keep, evaluated for each source.
+	ArtifactsDir hcl.Expression
+
 	HCL2Ref HCL2Ref
 }
 
@@ -84,10 +112,16 @@ func (p *Parser) decodeBuildConfig(block *hcl.Block, cfg *PackerConfig) (*BuildB
 	body := block.Body
 
 	var b struct {
-		Name        string   `hcl:"name,optional"`
-		Description string   `hcl:"description,optional"`
-		FromSources []string `hcl:"sources,optional"`
-		Config      hcl.Body `hcl:",remain"`
+		Name         string         `hcl:"name,optional"`
+		Description  string         `hcl:"description,optional"`
+		FromSources  []string       `hcl:"sources,optional"`
+		Retries      int            `hcl:"retries,optional"`
+		RetryBackoff string         `hcl:"retry_backoff,optional"`
+		RetryOn      []string       `hcl:"retry_on,optional"`
+		Concurrency  string         `hcl:"concurrency_group,optional"`
+		Env          hcl.Expression `hcl:"env,optional"`
+		ArtifactsDir hcl.Expression `hcl:"artifacts_dir,optional"`
+		Config       hcl.Body       `hcl:",remain"`
 	}
 	diags := gohcl.DecodeBody(body, nil, &b)
 	if diags.HasErrors() {
@@ -96,6 +130,46 @@ func (p *Parser) decodeBuildConfig(block *hcl.Block, cfg *PackerConfig) (*BuildB
 
 	build.Name = b.Name
 	build.Description = b.Description
+	build.Retries = b.Retries
+	build.RetryOn = b.RetryOn
+	build.ConcurrencyGroup = b.Concurrency
+	build.Env = b.Env
+	build.ArtifactsDir = b.ArtifactsDir
+
+	if b.Retries < 0 {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid retries",
+			Detail:   "retries must be a positive number.",
+			Subject:  attributeRange(block, "retries"),
+		})
+	}
+	if b.RetryBackoff != "" {
+		backoff, err := time.ParseDuration(b.RetryBackoff)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to parse retry_backoff duration",
+				Detail:   err.Error(),
+				Subject:  attributeRange(block, "retry_backoff"),
+			})
+		}
+		build.RetryBackoff = backoff
+	}
+	for _, class := range b.RetryOn {
+		if _, found := packer.RetryableErrorClasses[class]; !found {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Unknown retry_on class %q", class),
+				Detail: fmt.Sprintf("Builds can be retried on the following classes of errors: %s.",
+					strings.Join(packer.RetryableErrorClassNames(), ", ")),
+				Subject: attributeRange(block, "retry_on"),
+			})
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
 
 	for _, buildFrom := range b.FromSources {
 		ref := sourceRefFromString(buildFrom)
@@ -141,6 +215,13 @@ func (p *Parser) decodeBuildConfig(block *hcl.Block, cfg *PackerConfig) (*BuildB
 				continue
 			}
 			build.ProvisionerBlocks = append(build.ProvisionerBlocks, p)
+		case buildWaitForLabel:
+			p, moreDiags := p.decodeProvisioner(waitForProvisionerBlock(block), cfg)
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				continue
+			}
+			build.ProvisionerBlocks = append(build.ProvisionerBlocks, p)
 		case buildErrorCleanupProvisionerLabel:
 			if build.ErrorCleanupProvisionerBlock != nil {
 				diags = append(diags, &hcl.Diagnostic{
@@ -74,6 +74,24 @@ func (p *ProvisionerBlock) String() string {
 	return fmt.Sprintf(buildProvisionerLabel+"-block %q %q", p.PType, p.PName)
 }
 
+// waitForProvisionerType is the type of the provisioner wait_for blocks are
+// decoded as.
+const waitForProvisionerType = "wait-for"
+
+// waitForProvisionerBlock turns a wait_for block into the equivalent
+// `provisioner "wait-for"` block, so that it runs in order with the other
+// provisioners of the build.
+func waitForProvisionerBlock(block *hcl.Block) *hcl.Block {
+	return &hcl.Block{
+		Type:        buildProvisionerLabel,
+		Labels:      []string{waitForProvisionerType},
+		Body:        block.Body,
+		DefRange:    block.DefRange,
+		TypeRange:   block.TypeRange,
+		LabelRanges: []hcl.Range{block.TypeRange},
+	}
+}
+
 func (p *Parser) decodeProvisioner(block *hcl.Block, cfg *PackerConfig) (*ProvisionerBlock, hcl.Diagnostics) {
 	var b struct {
 		Name        string    `hcl:"name,optional"`
@@ -141,7 +159,7 @@ func (p *Parser) decodeProvisioner(block *hcl.Block, cfg *PackerConfig) (*Provis
 	return provisioner, diags
 }
 
-func (cfg *PackerConfig) startProvisioner(source SourceUseBlock, pb *ProvisionerBlock, ectx *hcl.EvalContext) (packersdk.Provisioner, hcl.Diagnostics) {
+func (cfg *PackerConfig) startProvisioner(source SourceUseBlock, pb *ProvisionerBlock, ectx *hcl.EvalContext, env map[string]string) (packersdk.Provisioner, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 
 	provisioner, err := cfg.parser.PluginConfig.Provisioners.Start(pb.PType)
@@ -165,6 +183,7 @@ func (cfg *PackerConfig) startProvisioner(source SourceUseBlock, pb *Provisioner
 		provisionerBlock: pb,
 		evalContext:      ectx,
 		builderVariables: builderVars,
+		env:              env,
 	}
 
 	if pb.Override != nil {
@@ -3,6 +3,7 @@ package hcl2template
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	. "github.com/hashicorp/packer/hcl2template/internal"
@@ -314,6 +315,168 @@ func TestParse_build(t *testing.T) {
 			},
 			false,
 		},
+		{"build with retries",
+			defaultParser,
+			parseTestArgs{"testdata/build/retries.pkr.hcl", nil, nil},
+			&PackerConfig{
+				CorePackerVersionString: lockedVersion,
+				Basedir:                 filepath.Join("testdata", "build"),
+				Sources: map[SourceRef]SourceBlock{
+					refVBIsoUbuntu1204: {Type: "virtualbox-iso", Name: "ubuntu-1204"},
+				},
+				Builds: Builds{
+					&BuildBlock{
+						Sources: []SourceUseBlock{
+							{
+								SourceRef: refVBIsoUbuntu1204,
+							},
+						},
+						Retries:      3,
+						RetryBackoff: time.Minute,
+						RetryOn:      []string{"capacity"},
+					},
+				},
+			},
+			false, false,
+			[]packersdk.Build{
+				&packer.CoreBuild{
+					Type:           "virtualbox-iso.ubuntu-1204",
+					Prepared:       true,
+					Builder:        emptyMockBuilder,
+					Provisioners:   []packer.CoreBuildProvisioner{},
+					PostProcessors: [][]packer.CoreBuildPostProcessor{},
+					Retries:        3,
+					RetryBackoff:   time.Minute,
+					RetryOn:        []string{"capacity"},
+				},
+			},
+			false,
+		},
+		{"build with concurrency group",
+			defaultParser,
+			parseTestArgs{"testdata/build/concurrency_group.pkr.hcl", nil, nil},
+			&PackerConfig{
+				CorePackerVersionString: lockedVersion,
+				Basedir:                 filepath.Join("testdata", "build"),
+				Sources: map[SourceRef]SourceBlock{
+					refVBIsoUbuntu1204: {Type: "virtualbox-iso", Name: "ubuntu-1204"},
+				},
+				Builds: Builds{
+					&BuildBlock{
+						Sources: []SourceUseBlock{
+							{
+								SourceRef: refVBIsoUbuntu1204,
+							},
+						},
+						ConcurrencyGroup: "pxe-subnet",
+					},
+				},
+			},
+			false, false,
+			[]packersdk.Build{
+				&packer.CoreBuild{
+					Type:             "virtualbox-iso.ubuntu-1204",
+					Prepared:         true,
+					Builder:          emptyMockBuilder,
+					Provisioners:     []packer.CoreBuildProvisioner{},
+					PostProcessors:   [][]packer.CoreBuildPostProcessor{},
+					ConcurrencyGroup: "pxe-subnet",
+				},
+			},
+			false,
+		},
+		{"build with unknown retry class",
+			defaultParser,
+			parseTestArgs{"testdata/build/retries_unknown_class.pkr.hcl", nil, nil},
+			&PackerConfig{
+				CorePackerVersionString: lockedVersion,
+				Basedir:                 filepath.Join("testdata", "build"),
+				Sources: map[SourceRef]SourceBlock{
+					refVBIsoUbuntu1204: {Type: "virtualbox-iso", Name: "ubuntu-1204"},
+				},
+			},
+			true, true,
+			[]packersdk.Build{},
+			false,
+		},
+		{"wait_for between provisioners",
+			defaultParser,
+			parseTestArgs{"testdata/build/wait_for.pkr.hcl", nil, nil},
+			&PackerConfig{
+				CorePackerVersionString: lockedVersion,
+				Basedir:                 filepath.Join("testdata", "build"),
+				Sources: map[SourceRef]SourceBlock{
+					refVBIsoUbuntu1204: {Type: "virtualbox-iso", Name: "ubuntu-1204"},
+				},
+				Builds: Builds{
+					&BuildBlock{
+						Sources: []SourceUseBlock{
+							{
+								SourceRef: refVBIsoUbuntu1204,
+							},
+						},
+						ProvisionerBlocks: []*ProvisionerBlock{
+							{
+								PType: "shell",
+							},
+							{
+								PType: "wait-for",
+								PName: "domain-join",
+							},
+							{
+								PType: "file",
+							},
+						},
+					},
+				},
+			},
+			false, false,
+			[]packersdk.Build{
+				&packer.CoreBuild{
+					Type:     "virtualbox-iso.ubuntu-1204",
+					Prepared: true,
+					Builder:  emptyMockBuilder,
+					Provisioners: []packer.CoreBuildProvisioner{
+						{
+							PType: "shell",
+							Provisioner: &HCL2Provisioner{
+								Provisioner: &MockProvisioner{
+									Config: MockConfig{
+										NestedMockConfig: NestedMockConfig{Tags: []MockTag{}},
+										NestedSlice:      []NestedMockConfig{},
+									},
+								},
+							},
+						},
+						{
+							PType: "wait-for",
+							PName: "domain-join",
+							Provisioner: &HCL2Provisioner{
+								Provisioner: &MockProvisioner{
+									Config: MockConfig{
+										NestedMockConfig: NestedMockConfig{Tags: []MockTag{}},
+										NestedSlice:      []NestedMockConfig{},
+									},
+								},
+							},
+						},
+						{
+							PType: "file",
+							Provisioner: &HCL2Provisioner{
+								Provisioner: &MockProvisioner{
+									Config: MockConfig{
+										NestedMockConfig: NestedMockConfig{Tags: []MockTag{}},
+										NestedSlice:      []NestedMockConfig{},
+									},
+								},
+							},
+						},
+					},
+					PostProcessors: [][]packer.CoreBuildPostProcessor{},
+				},
+			},
+			false,
+		},
 		{"provisioner with only and except",
 			defaultParser,
 			parseTestArgs{"testdata/build/provisioner_onlyexcept.pkr.hcl", nil, nil},
@@ -424,3 +587,16 @@ func TestParse_build(t *testing.T) {
 	}
 	testParse(t, tests)
 }
+
+func TestParse_build_retryDiagnosticRange(t *testing.T) {
+	_, diags := getBasicParser().Parse("testdata/build/retries_unknown_class.pkr.hcl", nil, nil)
+	if !diags.HasErrors() {
+		t.Fatal("expected an unknown retry class error")
+	}
+	for _, diag := range diags {
+		if diag.Subject != nil && diag.Subject.Start.Line == 8 {
+			return
+		}
+	}
+	t.Fatalf("expected a diagnostic pointing at the retry_on attribute, got %v", diags)
+}
@@ -124,6 +124,30 @@ func (cfg *PackerConfig) startDatasource(dataSourceStore packer.DatasourceStore,
 	return datasource, diags
 }
 
+// datasourceWarnings returns the warnings reported by datasource, when it
+// reports any, as diagnostics pointing at its block.
+func datasourceWarnings(block *hcl.Block, datasource packersdk.Datasource) hcl.Diagnostics {
+	warner, ok := datasource.(packer.DatasourceWarner)
+	if !ok {
+		return nil
+	}
+	return warningErrorsToDiags(block, warner.Warnings(), nil)
+}
+
+// filterSensitiveOutputsFromLogs hides the sensitive outputs of datasource,
+// when it has any, from the logs and the UI.
+func filterSensitiveOutputsFromLogs(datasource packersdk.Datasource, value cty.Value) {
+	sensitive, ok := datasource.(packer.SensitiveDatasource)
+	if !ok || value.IsNull() || !value.IsKnown() || !value.Type().IsObjectType() {
+		return
+	}
+	for _, name := range sensitive.SensitiveOutputs() {
+		if value.Type().HasAttribute(name) {
+			filterValueFromLogs(value.GetAttr(name))
+		}
+	}
+}
+
 func (p *Parser) decodeDataBlock(block *hcl.Block) (*DatasourceBlock, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	r := &DatasourceBlock{
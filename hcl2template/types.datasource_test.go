@@ -4,7 +4,11 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	. "github.com/hashicorp/packer/hcl2template/internal"
+	"github.com/hashicorp/packer/packer"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestParse_datasource(t *testing.T) {
@@ -123,3 +127,37 @@ func TestParse_datasource(t *testing.T) {
 	}
 	testParse(t, tests)
 }
+
+// warningDatasource is a data source reporting a warning.
+type warningDatasource struct {
+	MockDatasource
+}
+
+func (d *warningDatasource) Warnings() []string {
+	return []string{"the string filter is deprecated"}
+}
+
+func TestParse_datasourceWarnings(t *testing.T) {
+	parser := getBasicParser(func(p *Parser) {
+		p.PluginConfig.DataSources = packer.MapOfDatasource{
+			"warning": func() (packersdk.Datasource, error) { return &warningDatasource{}, nil },
+		}
+	})
+	cfg, diags := parser.Parse("testdata/datasources/warning.pkr.hcl", nil, nil)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	for _, opts := range []packer.InitializeOptions{{}, {SkipDatasourcesExecution: true}} {
+		for ref, ds := range cfg.Datasources {
+			ds.value = cty.Value{}
+			cfg.Datasources[ref] = ds
+		}
+		diags := cfg.evaluateDatasources(opts.SkipDatasourcesExecution)
+		if len(diags) != 1 || diags[0].Severity != hcl.DiagWarning {
+			t.Fatalf("expected a warning, got %s", diags)
+		}
+		if diags[0].Summary != "the string filter is deprecated" || diags[0].Subject.Start.Line != 1 {
+			t.Fatalf("expected the warning to point at the data block, got %s", diags)
+		}
+	}
+}
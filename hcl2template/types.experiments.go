@@ -0,0 +1,110 @@
+package hcl2template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+)
+
+// Experiments are the experimental features a template can opt in to, with
+// the `experiments` setting of the packer block, for example :
+//
+//	packer {
+//		experiments = ["some_feature"]
+//	}
+//
+// Experiments are keyed by name, with a short description of the feature.
+// Only features that do something are registered here, along with the code
+// checking ExperimentEnabled. Experimental features can change, or go away,
+// in any release. Once a feature is stable, its experiment is removed from
+// this list and enabling it becomes a no-op, see ConcludedExperiments.
+var Experiments = map[string]string{}
+
+// ConcludedExperiments are the former experiments that are now enabled for
+// every template, they are accepted and ignored.
+var ConcludedExperiments = map[string]bool{}
+
+// ExperimentNames returns the sorted names of the Experiments.
+func ExperimentNames() []string {
+	names := make([]string, 0, len(Experiments))
+	for name := range Experiments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExperimentEnabled tells whether the template opted in to the experiment.
+func (cfg *PackerConfig) ExperimentEnabled(name string) bool {
+	for _, enabled := range cfg.Experiments {
+		if enabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeExperiments decodes the `experiments` setting of the packer blocks of
+// f. Experiments are read before anything else, so that they can change how
+// the rest of the configuration is decoded.
+func (cfg *PackerConfig) decodeExperiments(f *hcl.File) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	rootContent, _, _ := f.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: packerLabel}},
+	})
+	for _, block := range rootContent.Blocks {
+		content, _, _ := block.Body.PartialContent(packerBlockSchema)
+		attr, exists := content.Attributes["experiments"]
+		if !exists {
+			continue
+		}
+
+		var names []string
+		moreDiags := gohcl.DecodeExpression(attr.Expr, nil, &names)
+		diags = append(diags, moreDiags...)
+		if moreDiags.HasErrors() {
+			continue
+		}
+		for _, name := range names {
+			if ConcludedExperiments[name] {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  fmt.Sprintf("Experiment %q is concluded", name),
+					Detail:   "This feature is now enabled for every template, it can be removed from the experiments list.",
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+				continue
+			}
+			description, known := Experiments[name]
+			if !known {
+				detail := "This version of Packer has no experiments."
+				if names := ExperimentNames(); len(names) > 0 {
+					detail = fmt.Sprintf("The experiments of this version of Packer are: %s.", strings.Join(names, ", "))
+				}
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  fmt.Sprintf("Unknown experiment %q", name),
+					Detail:   detail,
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+				continue
+			}
+			if cfg.ExperimentEnabled(name) {
+				continue
+			}
+			cfg.Experiments = append(cfg.Experiments, name)
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  fmt.Sprintf("Experimental feature %q is enabled", name),
+				Detail: fmt.Sprintf("The %s experiment is %s. Experimental features can change "+
+					"in any release of Packer; do not rely on them in production.", name, description),
+				Subject: attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+	return diags
+}
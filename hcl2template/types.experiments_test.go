@@ -0,0 +1,81 @@
+package hcl2template
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/hcl/v2"
+)
+
+func TestPackerConfig_decodeExperiments(t *testing.T) {
+	// no experiment is shipped yet, test ones are registered for the time of
+	// the test.
+	Experiments["module_system"] = "a test experiment"
+	Experiments["layer_cache"] = "another test experiment"
+	ConcludedExperiments["stable_feature"] = true
+	defer func() {
+		delete(Experiments, "module_system")
+		delete(Experiments, "layer_cache")
+		delete(ConcludedExperiments, "stable_feature")
+	}()
+
+	tests := []struct {
+		name            string
+		template        string
+		wantExperiments []string
+		wantErrors      bool
+		wantWarnings    int
+	}{
+		{"no experiments", `
+		packer {
+			required_version = ">= v1"
+		}`, nil, false, 0},
+		{"experiments", `
+		packer {
+			experiments = ["module_system", "layer_cache", "module_system"]
+		}`, []string{"module_system", "layer_cache"}, false, 2},
+		{"concluded experiment", `
+		packer {
+			experiments = ["stable_feature"]
+		}`, nil, false, 1},
+		{"unknown experiment", `
+		packer {
+			experiments = ["time_travel"]
+		}`, nil, true, 0},
+		{"not a list of strings", `
+		packer {
+			experiments = "module_system"
+		}`, nil, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := getBasicParser()
+			file, diags := parser.ParseHCL([]byte(tt.template), "main.pkr.hcl")
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			cfg := &PackerConfig{parser: parser}
+			diags = cfg.decodeExperiments(file)
+			if diags.HasErrors() != tt.wantErrors {
+				t.Fatalf("decodeExperiments() diags = %v, want errors: %t", diags, tt.wantErrors)
+			}
+			warnings := 0
+			for _, diag := range diags {
+				if diag.Severity == hcl.DiagWarning {
+					warnings++
+				}
+			}
+			if warnings != tt.wantWarnings {
+				t.Fatalf("expected %d warnings, got %v", tt.wantWarnings, diags)
+			}
+			if diff := cmp.Diff(tt.wantExperiments, cfg.Experiments); diff != "" {
+				t.Fatalf("unexpected experiments: %s", diff)
+			}
+			for _, name := range tt.wantExperiments {
+				if !cfg.ExperimentEnabled(name) {
+					t.Fatalf("expected %s to be enabled", name)
+				}
+			}
+		})
+	}
+}
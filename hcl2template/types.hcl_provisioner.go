@@ -20,6 +20,9 @@ type HCL2Provisioner struct {
 	evalContext      *hcl.EvalContext
 	builderVariables map[string]string
 	override         map[string]interface{}
+	// env are the environment variables of the build block, added to the
+	// ones of the provisioner.
+	env map[string]string
 }
 
 func (p *HCL2Provisioner) ConfigSpec() hcldec.ObjectSpec {
@@ -54,6 +57,8 @@ func (p *HCL2Provisioner) HCL2Prepare(buildVars map[string]interface{}) error {
 		return diags
 	}
 
+	flatProvisionerCfg = mergeBuildEnv(flatProvisionerCfg, p.env)
+
 	// In case of cty.Unknown values, this will write a equivalent placeholder of the same type
 	// Unknown types are not recognized by the json marshal during the RPC call and we have to do this here
 	// to avoid json parsing failures when running the validate command.
@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	pkrfunction "github.com/hashicorp/packer/hcl2template/function"
+	hcl2shim "github.com/hashicorp/packer/hcl2template/shim"
 	"github.com/hashicorp/packer/packer"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
@@ -24,6 +25,10 @@ type PackerConfig struct {
 		RequiredPlugins    []*RequiredPlugins
 	}
 
+	// Experiments are the experimental features enabled with the
+	// `experiments` setting of the packer block.
+	Experiments []string
+
 	// Directory where the config files are defined
 	Basedir string
 
@@ -52,6 +57,10 @@ type PackerConfig struct {
 	// Builds is the list of Build blocks defined in the config files.
 	Builds Builds
 
+	// ArtifactStore is where the artifacts of all builds are pushed, nil
+	// when no artifact_store block is defined.
+	ArtifactStore *ArtifactStoreBlock
+
 	parser *Parser
 	files  []*hcl.File
 
@@ -61,10 +70,20 @@ type PackerConfig struct {
 	force   bool
 	debug   bool
 	onError string
+
+	skipProvisioners, excludePostProcessors []string
+
+	// builderConfigs holds the decoded builder configurations of the builds
+	// returned by GetBuilds, keyed by build name.
+	builderConfigs map[string]resolvedBuilderConfig
 }
 
 type ValidationOptions struct {
 	Strict bool
+	// WarnOnUnused reports the variables set with -var or in a var file that
+	// the config files don't use, and the required plugins whose components
+	// are never used.
+	WarnOnUnused bool
 }
 
 const (
@@ -295,10 +314,12 @@ func (cfg *PackerConfig) evaluateDatasources(skipExecution bool) hcl.Diagnostics
 			placeholderValue := cty.UnknownVal(hcldec.ImpliedType(datasource.OutputSpec()))
 			ds.value = placeholderValue
 			cfg.Datasources[ref] = ds
+			diags = append(diags, datasourceWarnings(ds.block, datasource)...)
 			continue
 		}
 
 		realValue, err := datasource.Execute()
+		diags = append(diags, datasourceWarnings(ds.block, datasource)...)
 		if err != nil {
 			diags = append(diags, &hcl.Diagnostic{
 				Summary:  err.Error(),
@@ -307,6 +328,7 @@ func (cfg *PackerConfig) evaluateDatasources(skipExecution bool) hcl.Diagnostics
 			})
 			continue
 		}
+		filterSensitiveOutputsFromLogs(datasource, realValue)
 		ds.value = realValue
 		cfg.Datasources[ref] = ds
 	}
@@ -315,16 +337,23 @@ func (cfg *PackerConfig) evaluateDatasources(skipExecution bool) hcl.Diagnostics
 }
 
 // getCoreBuildProvisioners takes a list of provisioner block, starts according
-// provisioners and sends parsed HCL2 over to it.
-func (cfg *PackerConfig) getCoreBuildProvisioners(source SourceUseBlock, blocks []*ProvisionerBlock, ectx *hcl.EvalContext) ([]packer.CoreBuildProvisioner, hcl.Diagnostics) {
+// provisioners and sends parsed HCL2 over to it. env are the environment
+// variables of the build block, added to the ones of every provisioner.
+func (cfg *PackerConfig) getCoreBuildProvisioners(pcb *packer.CoreBuild, source SourceUseBlock, blocks []*ProvisionerBlock, ectx *hcl.EvalContext, env map[string]string) ([]packer.CoreBuildProvisioner, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	res := []packer.CoreBuildProvisioner{}
+	skipOpts := packer.GetBuildsOptions{SkipProvisioners: cfg.skipProvisioners}
 	for _, pb := range blocks {
 		if pb.OnlyExcept.Skip(source.String()) {
 			continue
 		}
+		// -skip-provisioner
+		if skipOpts.SkipsProvisioner(pb.PType, pb.PName) {
+			pcb.Skip("provisioner", pb.PType, pb.PName)
+			continue
+		}
 
-		coreBuildProv, moreDiags := cfg.getCoreBuildProvisioner(source, pb, ectx)
+		coreBuildProv, moreDiags := cfg.getCoreBuildProvisioner(source, pb, ectx, env)
 		diags = append(diags, moreDiags...)
 		if moreDiags.HasErrors() {
 			continue
@@ -334,9 +363,9 @@ func (cfg *PackerConfig) getCoreBuildProvisioners(source SourceUseBlock, blocks
 	return res, diags
 }
 
-func (cfg *PackerConfig) getCoreBuildProvisioner(source SourceUseBlock, pb *ProvisionerBlock, ectx *hcl.EvalContext) (packer.CoreBuildProvisioner, hcl.Diagnostics) {
+func (cfg *PackerConfig) getCoreBuildProvisioner(source SourceUseBlock, pb *ProvisionerBlock, ectx *hcl.EvalContext, env map[string]string) (packer.CoreBuildProvisioner, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
-	provisioner, moreDiags := cfg.startProvisioner(source, pb, ectx)
+	provisioner, moreDiags := cfg.startProvisioner(source, pb, ectx, env)
 	diags = append(diags, moreDiags...)
 	if moreDiags.HasErrors() {
 		return packer.CoreBuildProvisioner{}, diags
@@ -370,9 +399,10 @@ func (cfg *PackerConfig) getCoreBuildProvisioner(source SourceUseBlock, pb *Prov
 
 // getCoreBuildProvisioners takes a list of post processor block, starts
 // according provisioners and sends parsed HCL2 over to it.
-func (cfg *PackerConfig) getCoreBuildPostProcessors(source SourceUseBlock, blocksList [][]*PostProcessorBlock, ectx *hcl.EvalContext) ([][]packer.CoreBuildPostProcessor, hcl.Diagnostics) {
+func (cfg *PackerConfig) getCoreBuildPostProcessors(pcb *packer.CoreBuild, source SourceUseBlock, blocksList [][]*PostProcessorBlock, ectx *hcl.EvalContext) ([][]packer.CoreBuildPostProcessor, hcl.Diagnostics) {
 	var diags hcl.Diagnostics
 	res := [][]packer.CoreBuildPostProcessor{}
+	skipOpts := packer.GetBuildsOptions{ExcludePostProcessors: cfg.excludePostProcessors}
 	for _, blocks := range blocksList {
 		pps := []packer.CoreBuildPostProcessor{}
 		for _, ppb := range blocks {
@@ -395,6 +425,11 @@ func (cfg *PackerConfig) getCoreBuildPostProcessors(source SourceUseBlock, block
 			if exclude {
 				break
 			}
+			// -exclude-post-processors
+			if skipOpts.ExcludesPostProcessor(ppb.PType, ppb.PName) {
+				pcb.Skip("post-processor", ppb.PType, ppb.PName)
+				continue
+			}
 
 			postProcessor, moreDiags := cfg.startPostProcessor(source, ppb, ectx)
 			diags = append(diags, moreDiags...)
@@ -426,6 +461,9 @@ func (cfg *PackerConfig) GetBuilds(opts packer.GetBuildsOptions) ([]packersdk.Bu
 	cfg.debug = opts.Debug
 	cfg.force = opts.Force
 	cfg.onError = opts.OnError
+	cfg.skipProvisioners = opts.SkipProvisioners
+	cfg.excludePostProcessors = opts.ExcludePostProcessors
+	cfg.builderConfigs = map[string]resolvedBuilderConfig{}
 
 	for _, build := range cfg.Builds {
 		for _, srcUsage := range build.Sources {
@@ -441,8 +479,16 @@ func (cfg *PackerConfig) GetBuilds(opts packer.GetBuildsOptions) ([]packersdk.Bu
 			}
 
 			pcb := &packer.CoreBuild{
-				BuildName: build.Name,
-				Type:      srcUsage.String(),
+				BuildName:        build.Name,
+				Type:             srcUsage.String(),
+				Retries:          build.Retries,
+				RetryBackoff:     build.RetryBackoff,
+				RetryOn:          build.RetryOn,
+				ConcurrencyGroup: build.ConcurrencyGroup,
+				WorkDir:          packer.NewWorkDir(),
+			}
+			if cfg.ArtifactStore != nil {
+				pcb.ArtifactStore = cfg.ArtifactStore.Store()
 			}
 
 			// Apply the -only and -except command-line options to exclude matching builds.
@@ -485,11 +531,14 @@ func (cfg *PackerConfig) GetBuilds(opts packer.GetBuildsOptions) ([]packersdk.Bu
 				}
 			}
 
-			builder, moreDiags, generatedVars := cfg.startBuilder(srcUsage, cfg.EvalContext(BuildContext, nil))
+			builder, builderConfig, moreDiags, generatedVars := cfg.startBuilder(srcUsage, cfg.EvalContext(BuildContext, nil), pcb.WorkDir)
 			diags = append(diags, moreDiags...)
 			if moreDiags.HasErrors() {
 				continue
 			}
+			if config, ok := hcl2shim.ConfigValueFromHCL2(builderConfig).(map[string]interface{}); ok {
+				pcb.Resources = packer.NewBuildResources(srcUsage.Type, config)
+			}
 
 			// If the builder has provided a list of to-be-generated variables that
 			// should be made accessible to provisioners, pass that list into
@@ -507,12 +556,23 @@ func (cfg *PackerConfig) GetBuilds(opts packer.GetBuildsOptions) ([]packersdk.Bu
 				buildAccessor:   cty.ObjectVal(unknownBuildValues),
 			}
 
-			provisioners, moreDiags := cfg.getCoreBuildProvisioners(srcUsage, build.ProvisionerBlocks, cfg.EvalContext(BuildContext, variables))
+			env, moreDiags := build.decodeEnv(cfg.EvalContext(BuildContext, variables), cfg.sensitiveVariables())
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				continue
+			}
+			pcb.ArtifactsDir, moreDiags = build.decodeArtifactsDir(cfg.EvalContext(BuildContext, variables))
+			diags = append(diags, moreDiags...)
+			if moreDiags.HasErrors() {
+				continue
+			}
+
+			provisioners, moreDiags := cfg.getCoreBuildProvisioners(pcb, srcUsage, build.ProvisionerBlocks, cfg.EvalContext(BuildContext, variables), env)
 			diags = append(diags, moreDiags...)
 			if moreDiags.HasErrors() {
 				continue
 			}
-			pps, moreDiags := cfg.getCoreBuildPostProcessors(srcUsage, build.PostProcessorsLists, cfg.EvalContext(BuildContext, variables))
+			pps, moreDiags := cfg.getCoreBuildPostProcessors(pcb, srcUsage, build.PostProcessorsLists, cfg.EvalContext(BuildContext, variables))
 			diags = append(diags, moreDiags...)
 			if moreDiags.HasErrors() {
 				continue
@@ -520,7 +580,7 @@ func (cfg *PackerConfig) GetBuilds(opts packer.GetBuildsOptions) ([]packersdk.Bu
 
 			if build.ErrorCleanupProvisionerBlock != nil {
 				if !build.ErrorCleanupProvisionerBlock.OnlyExcept.Skip(srcUsage.String()) {
-					errorCleanupProv, moreDiags := cfg.getCoreBuildProvisioner(srcUsage, build.ErrorCleanupProvisionerBlock, cfg.EvalContext(BuildContext, variables))
+					errorCleanupProv, moreDiags := cfg.getCoreBuildProvisioner(srcUsage, build.ErrorCleanupProvisionerBlock, cfg.EvalContext(BuildContext, variables), env)
 					diags = append(diags, moreDiags...)
 					if moreDiags.HasErrors() {
 						continue
@@ -547,6 +607,10 @@ func (cfg *PackerConfig) GetBuilds(opts packer.GetBuildsOptions) ([]packersdk.Bu
 				continue
 			}
 
+			cfg.builderConfigs[pcb.Name()] = resolvedBuilderConfig{
+				builderType: srcUsage.Type,
+				config:      builderConfig,
+			}
 			res = append(res, pcb)
 		}
 	}
@@ -587,6 +651,30 @@ func (p *PackerConfig) EvaluateExpression(line string) (out string, exit bool, d
 	}
 }
 
+// ExpressionCompletions returns the sorted list of console commands,
+// references and functions that can be used in the `packer console`.
+func (p *PackerConfig) ExpressionCompletions() []string {
+	res := []string{"exit", "help", "variables",
+		pathVariablesAccessor + ".cwd",
+		pathVariablesAccessor + ".root",
+		packerAccessor + ".version",
+	}
+	for _, key := range p.InputVariables.Keys() {
+		res = append(res, inputVariablesAccessor+"."+key)
+	}
+	for _, key := range p.LocalVariables.Keys() {
+		res = append(res, localsAccessor+"."+key)
+	}
+	for ref := range p.Datasources {
+		res = append(res, dataAccessor+"."+ref.Type+"."+ref.Name)
+	}
+	for name := range Functions(p.Basedir) {
+		res = append(res, name+"(")
+	}
+	sort.Strings(res)
+	return res
+}
+
 func (p *PackerConfig) printVariables() string {
 	out := &strings.Builder{}
 	out.WriteString("> input-variables:\n\n")
@@ -674,9 +762,17 @@ func (p *PackerConfig) handleEval(line string) (out string, exit bool, diags hcl
 	return PrintableCtyValue(val), false, diags
 }
 
-func (p *PackerConfig) FixConfig(_ packer.FixConfigOptions) (diags hcl.Diagnostics) {
-	// No Fixers exist for HCL2 configs so there is nothing to do here for now.
-	return
+// FixConfig reports the deprecated fields set in the config files; in strict
+// mode they are errors. HCL2 files are fixed by `packer fix`, using
+// MigrateFile.
+func (p *PackerConfig) FixConfig(opts packer.FixConfigOptions) (diags hcl.Diagnostics) {
+	if opts.Mode != packer.Diff {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("FixConfig only supports template diff; FixConfigMode %d not supported", opts.Mode),
+		})
+	}
+	return p.checkDeprecatedFields()
 }
 
 func (p *PackerConfig) InspectConfig(opts packer.InspectConfigOptions) int {
@@ -687,3 +783,38 @@ func (p *PackerConfig) InspectConfig(opts packer.InspectConfigOptions) int {
 	ui.Say(p.printBuilds())
 	return 0
 }
+
+// resolvedBuilderConfig is the decoded configuration of the builder of a
+// build.
+type resolvedBuilderConfig struct {
+	builderType string
+	config      cty.Value
+}
+
+// PolicyInput returns the resolved configuration of builds, builds must have
+// been returned by GetBuilds.
+func (cfg *PackerConfig) PolicyInput(builds []packersdk.Build) (*packer.PolicyInput, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	input := &packer.PolicyInput{Builds: []packer.PolicyBuild{}}
+	for _, b := range builds {
+		cb, ok := b.(*packer.CoreBuild)
+		if !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Unexpected build type %T", b),
+			})
+			continue
+		}
+		resolved, found := cfg.builderConfigs[cb.Name()]
+		if !found {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Unknown build %s", cb.Name()),
+				Detail:   "The build was not started from this configuration.",
+			})
+			continue
+		}
+		input.Builds = append(input.Builds, packer.NewPolicyBuild(cb, resolved.builderType, hcl2shim.ConfigValueFromHCL2(resolved.config)))
+	}
+	return input, diags
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/packer/hcl2template/addrs"
 	"github.com/hashicorp/packer/packer"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -173,7 +174,16 @@ type RequiredPlugin struct {
 	Source      string
 	Type        *addrs.Plugin
 	Requirement VersionConstraint
-	DeclRange   hcl.Range
+	// IncludePrereleases is set by include_prereleases, to allow
+	// pre-releases of the plugin to be installed and used.
+	IncludePrereleases bool
+	// Checksums are set by checksums, to pin the checksums of the zip
+	// files of the releases of the plugin, by platform.
+	Checksums []plugingetter.PinnedChecksum
+	// Getter is set by getter, to install the plugin from its own mirror or
+	// with its own signing keys.
+	Getter    *plugingetter.GetterConfig
+	DeclRange hcl.Range
 	PluginDependencyReason
 }
 
@@ -262,6 +272,12 @@ func decodeRequiredPluginsBlock(block *hcl.Block) (*RequiredPlugins, hcl.Diagnos
 				continue
 			}
 			constraintStr := constraint.AsString()
+			if constraintStr == LatestVersionKeyword {
+				// "latest" explicitly opts in to the newest available
+				// release, it matches any version.
+				vc.Latest = true
+				constraintStr = ">= 0.0.0"
+			}
 			constraints, err := version.NewConstraint(constraintStr)
 			if err != nil {
 				// NewConstraint doesn't return user-friendly errors, so we'll just
@@ -315,15 +331,47 @@ func decodeRequiredPluginsBlock(block *hcl.Block) (*RequiredPlugins, hcl.Diagnos
 				rp.Type = p
 			}
 
+			if expr.Type().HasAttribute("include_prereleases") {
+				include := expr.GetAttr("include_prereleases")
+				if !include.Type().Equals(cty.Bool) || include.IsNull() {
+					diags = append(diags, &hcl.Diagnostic{
+						Severity: hcl.DiagError,
+						Summary:  "Invalid include_prereleases",
+						Detail:   "include_prereleases must be a bool. For example: include_prereleases = true",
+						Subject:  attr.Expr.Range().Ptr(),
+					})
+					continue
+				}
+				rp.IncludePrereleases = include.True()
+			}
+
+			if expr.Type().HasAttribute("checksums") {
+				checksums, checksumsDiags := decodePinnedChecksums(expr.GetAttr("checksums"), attr.Expr.Range())
+				diags = append(diags, checksumsDiags...)
+				if checksumsDiags.HasErrors() {
+					continue
+				}
+				rp.Checksums = checksums
+			}
+
+			if expr.Type().HasAttribute("getter") {
+				getter, getterDiags := decodeGetterConfig(expr.GetAttr("getter"), attr.Expr.Range())
+				diags = append(diags, getterDiags...)
+				if getterDiags.HasErrors() {
+					continue
+				}
+				rp.Getter = getter
+			}
+
 			attrTypes := expr.Type().AttributeTypes()
 			for name := range attrTypes {
-				if name == "version" || name == "source" {
+				if name == "version" || name == "source" || name == "include_prereleases" || name == "checksums" || name == "getter" {
 					continue
 				}
 				diags = append(diags, &hcl.Diagnostic{
 					Severity: hcl.DiagError,
 					Summary:  "Invalid required_plugins object",
-					Detail:   `required_plugins objects can only contain "version" and "source" attributes.`,
+					Detail:   `required_plugins objects can only contain "version", "source", "include_prereleases", "checksums" and "getter" attributes.`,
 					Subject:  attr.Expr.Range().Ptr(),
 				})
 				break
@@ -375,3 +423,105 @@ func checkPluginNameNormalized(name string, declrange hcl.Range) hcl.Diagnostics
 	}
 	return diags
 }
+
+// decodePinnedChecksums decodes the checksums attribute of a required plugin,
+// a map of the checksums of the zip files of its releases, by platform:
+//
+//	checksums = {
+//	  linux_amd64 = "sha256:3a7b..."
+//	}
+func decodePinnedChecksums(checksums cty.Value, rng hcl.Range) ([]plugingetter.PinnedChecksum, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	if checksums.IsNull() || !(checksums.Type().IsObjectType() || checksums.Type().IsMapType()) {
+		return nil, append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid checksums",
+			Detail:   `checksums must be a map of checksums by platform. For example: checksums = { linux_amd64 = "sha256:3a7b..." }`,
+			Subject:  rng.Ptr(),
+		})
+	}
+	var pins []plugingetter.PinnedChecksum
+	for it := checksums.ElementIterator(); it.Next(); {
+		platform, checksum := it.Element()
+		if !checksum.Type().Equals(cty.String) || checksum.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid checksum",
+				Detail:   fmt.Sprintf("The checksum of %s must be a string, like \"sha256:3a7b...\".", platform.AsString()),
+				Subject:  rng.Ptr(),
+			})
+			continue
+		}
+		pin, err := plugingetter.ParsePinnedChecksum(platform.AsString(), checksum.AsString())
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid checksum",
+				Detail:   fmt.Sprintf("The checksum of %s is invalid: %s", platform.AsString(), err),
+				Subject:  rng.Ptr(),
+			})
+			continue
+		}
+		pins = append(pins, pin)
+	}
+	return pins, diags
+}
+
+// decodeGetterConfig decodes the getter attribute of a required plugin, the
+// settings of the getter it is installed with:
+//
+//	getter = {
+//	  mirror               = "https://plugins.example.com/packer/"
+//	  token_env            = "HAPPYCLOUD_MIRROR_TOKEN"
+//	  releases_public_keys = ["3q2+7wFz0i9m..."]
+//	}
+func decodeGetterConfig(getter cty.Value, rng hcl.Range) (*plugingetter.GetterConfig, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	invalid := func(detail string) hcl.Diagnostics {
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid getter",
+			Detail:   detail,
+			Subject:  rng.Ptr(),
+		})
+	}
+	if getter.IsNull() || !getter.Type().IsObjectType() {
+		return nil, invalid(`getter must be an object. For example: getter = { mirror = "https://plugins.example.com/packer/" }`)
+	}
+	config := &plugingetter.GetterConfig{}
+	for name := range getter.Type().AttributeTypes() {
+		value := getter.GetAttr(name)
+		switch name {
+		case "mirror", "token_env":
+			if !value.Type().Equals(cty.String) || value.IsNull() {
+				return nil, invalid(fmt.Sprintf("The %s of the getter must be a string.", name))
+			}
+			if name == "mirror" {
+				config.MirrorURL = value.AsString()
+			} else {
+				config.TokenEnv = value.AsString()
+			}
+		case "releases_public_keys":
+			if value.IsNull() || !(value.Type().IsListType() || value.Type().IsTupleType()) {
+				return nil, invalid(`The releases_public_keys of the getter must be a list of base64 encoded ed25519 public keys.`)
+			}
+			for it := value.ElementIterator(); it.Next(); {
+				_, encoded := it.Element()
+				if !encoded.Type().Equals(cty.String) || encoded.IsNull() {
+					return nil, invalid(`The releases_public_keys of the getter must be a list of base64 encoded ed25519 public keys.`)
+				}
+				keys, err := plugingetter.ParsePublicKeys(encoded.AsString())
+				if err != nil {
+					return nil, invalid(fmt.Sprintf("The releases_public_keys of the getter are invalid: %s", err))
+				}
+				config.ReleasesPublicKeys = append(config.ReleasesPublicKeys, keys...)
+			}
+		default:
+			return nil, invalid(`getter objects can only contain "mirror", "token_env" and "releases_public_keys" attributes.`)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, invalid(fmt.Sprintf("The getter is invalid: %s.", err))
+	}
+	return config, diags
+}
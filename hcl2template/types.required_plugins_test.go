@@ -1,11 +1,15 @@
 package hcl2template
 
 import (
+	"crypto/ed25519"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/packer/hcl2template/addrs"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestPackerConfig_required_plugin_parse(t *testing.T) {
@@ -49,6 +53,116 @@ func TestPackerConfig_required_plugin_parse(t *testing.T) {
 				},
 			},
 		}},
+		{"required_plugin_latest", PackerConfig{parser: getBasicParser()}, `
+		packer {
+			required_plugins {
+				amazon = {
+					source  = "github.com/hashicorp/amazon"
+					version = "latest"
+				}
+			}
+		} `, `
+		source "amazon-ebs" "example" {
+		}
+		`, false, PackerConfig{
+			Packer: struct {
+				VersionConstraints []VersionConstraint
+				RequiredPlugins    []*RequiredPlugins
+			}{
+				RequiredPlugins: []*RequiredPlugins{
+					{RequiredPlugins: map[string]*RequiredPlugin{
+						"amazon": {
+							Name:   "amazon",
+							Source: "github.com/hashicorp/amazon",
+							Type:   &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "amazon"},
+							Requirement: VersionConstraint{
+								Required: mustVersionConstraints(version.NewConstraint(">= 0.0.0")),
+								Latest:   true,
+							},
+							PluginDependencyReason: PluginDependencyExplicit,
+						},
+					}},
+				},
+			},
+		}},
+		{"required_plugin_checksums", PackerConfig{parser: getBasicParser()}, `
+		packer {
+			required_plugins {
+				amazon = {
+					source    = "github.com/hashicorp/amazon"
+					version   = "v1.2.3"
+					checksums = {
+						linux_amd64 = "sha256:3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b"
+					}
+				}
+			}
+		} `, `
+		source "amazon-ebs" "example" {
+		}
+		`, false, PackerConfig{
+			Packer: struct {
+				VersionConstraints []VersionConstraint
+				RequiredPlugins    []*RequiredPlugins
+			}{
+				RequiredPlugins: []*RequiredPlugins{
+					{RequiredPlugins: map[string]*RequiredPlugin{
+						"amazon": {
+							Name:   "amazon",
+							Source: "github.com/hashicorp/amazon",
+							Type:   &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "amazon"},
+							Requirement: VersionConstraint{
+								Required: mustVersionConstraints(version.NewConstraint("v1.2.3")),
+							},
+							Checksums: []plugingetter.PinnedChecksum{
+								{OS: "linux", ARCH: "amd64", Type: "sha256", Checksum: "3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b3a7b"},
+							},
+							PluginDependencyReason: PluginDependencyExplicit,
+						},
+					}},
+				},
+			},
+		}},
+		{"required_plugin_getter", PackerConfig{parser: getBasicParser()}, `
+		packer {
+			required_plugins {
+				amazon = {
+					source  = "github.com/hashicorp/amazon"
+					version = "v1.2.3"
+					getter = {
+						mirror               = "https://plugins.example.com/packer/"
+						token_env            = "AMAZON_MIRROR_TOKEN"
+						releases_public_keys = ["AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="]
+					}
+				}
+			}
+		} `, `
+		source "amazon-ebs" "example" {
+		}
+		`, false, PackerConfig{
+			Packer: struct {
+				VersionConstraints []VersionConstraint
+				RequiredPlugins    []*RequiredPlugins
+			}{
+				RequiredPlugins: []*RequiredPlugins{
+					{RequiredPlugins: map[string]*RequiredPlugin{
+						"amazon": {
+							Name:   "amazon",
+							Source: "github.com/hashicorp/amazon",
+							Type:   &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "amazon"},
+							Requirement: VersionConstraint{
+								Required: mustVersionConstraints(version.NewConstraint("v1.2.3")),
+							},
+							Getter: &plugingetter.GetterConfig{
+								MirrorURL:          "https://plugins.example.com/packer/",
+								TokenEnv:           "AMAZON_MIRROR_TOKEN",
+								ReleasesPublicKeys: []ed25519.PublicKey{make(ed25519.PublicKey, ed25519.PublicKeySize)},
+							},
+							PluginDependencyReason: PluginDependencyExplicit,
+						},
+					}},
+				},
+			},
+		}},
 		{"required_plugin_forked_no_redirect", PackerConfig{parser: getBasicParser()}, `
 		packer {
 			required_plugins {
@@ -361,3 +475,23 @@ func TestPackerConfig_required_plugin_parse(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeGetterConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		getter cty.Value
+	}{
+		{"not an object", cty.StringVal("https://plugins.example.com/packer/")},
+		{"unknown attribute", cty.ObjectVal(map[string]cty.Value{"url": cty.StringVal("https://plugins.example.com/packer/")})},
+		{"not an http mirror", cty.ObjectVal(map[string]cty.Value{"mirror": cty.StringVal("ftp://plugins.example.com/packer/")})},
+		{"token without mirror", cty.ObjectVal(map[string]cty.Value{"token_env": cty.StringVal("AMAZON_MIRROR_TOKEN")})},
+		{"invalid key", cty.ObjectVal(map[string]cty.Value{"releases_public_keys": cty.TupleVal([]cty.Value{cty.StringVal("AAAA")})})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, diags := decodeGetterConfig(tt.getter, hcl.Range{}); !diags.HasErrors() {
+				t.Fatal("expected the getter to be invalid")
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/hashicorp/hcl/v2/gohcl"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	hcl2shim "github.com/hashicorp/packer/hcl2template/shim"
+	"github.com/hashicorp/packer/packer"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -96,7 +97,10 @@ func (p *Parser) decodeSource(block *hcl.Block) (SourceBlock, hcl.Diagnostics) {
 	return source, diags
 }
 
-func (cfg *PackerConfig) startBuilder(source SourceUseBlock, ectx *hcl.EvalContext) (packersdk.Builder, hcl.Diagnostics, []string) {
+// startBuilder starts and prepares the builder of a source, which gets
+// workDir as its scratch directory. The decoded configuration of the builder
+// is returned too.
+func (cfg *PackerConfig) startBuilder(source SourceUseBlock, ectx *hcl.EvalContext, workDir string) (packersdk.Builder, cty.Value, hcl.Diagnostics, []string) {
 	var diags hcl.Diagnostics
 
 	builder, err := cfg.parser.PluginConfig.Builders.Start(source.Type)
@@ -106,14 +110,14 @@ func (cfg *PackerConfig) startBuilder(source SourceUseBlock, ectx *hcl.EvalConte
 			Summary:  "Failed to load " + sourceLabel + " type",
 			Detail:   err.Error(),
 		})
-		return builder, diags, nil
+		return builder, cty.NilVal, diags, nil
 	}
 
 	body := source.Body
 	decoded, moreDiags := decodeHCL2Spec(body, ectx, builder)
 	diags = append(diags, moreDiags...)
 	if moreDiags.HasErrors() {
-		return builder, diags, nil
+		return builder, cty.NilVal, diags, nil
 	}
 
 	// In case of cty.Unknown values, this will write a equivalent placeholder of the same type
@@ -131,11 +135,12 @@ func (cfg *PackerConfig) startBuilder(source SourceUseBlock, ectx *hcl.EvalConte
 	builderVars["packer_debug"] = strconv.FormatBool(cfg.debug)
 	builderVars["packer_force"] = strconv.FormatBool(cfg.force)
 	builderVars["packer_on_error"] = cfg.onError
+	builderVars[packer.WorkDirConfigKey] = workDir
 
 	generatedVars, warning, err := builder.Prepare(builderVars, decoded)
 	moreDiags = warningErrorsToDiags(cfg.Sources[source.SourceRef].block, warning, err)
 	diags = append(diags, moreDiags...)
-	return builder, diags, generatedVars
+	return builder, decoded, diags, generatedVars
 }
 
 // These variables will populate the PackerConfig inside of the builders.
@@ -0,0 +1,188 @@
+package hcl2template
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// checkUnused returns a warning for every variable set with -var or in a var
+// file that the config files don't use, and for every required plugin whose
+// components are never used; or an error in strict mode.
+func (cfg *PackerConfig) checkUnused() hcl.Diagnostics {
+	severity := hcl.DiagWarning
+	if cfg.ValidationOptions.Strict {
+		severity = hcl.DiagError
+	}
+	diags := cfg.checkUnusedVariables(severity)
+	return append(diags, cfg.checkUnusedPlugins(severity)...)
+}
+
+// checkUnusedVariables reports the variables set with -var or in a var file
+// that no expression of the config files references.
+func (cfg *PackerConfig) checkUnusedVariables(severity hcl.DiagnosticSeverity) hcl.Diagnostics {
+	used, known := variableReferences(cfg.files)
+	if !known {
+		log.Printf("[TRACE] not checking for unused variables, the variables used can't be told")
+		return nil
+	}
+	var diags hcl.Diagnostics
+	names := cfg.InputVariables.Keys()
+	sort.Strings(names)
+	for _, name := range names {
+		if used[name] {
+			continue
+		}
+		variable := cfg.InputVariables[name]
+		subject := variable.Range.Ptr()
+		from := ""
+		for _, value := range variable.Values {
+			switch value.From {
+			case "cmd":
+				from, subject = "with -var", variable.Range.Ptr()
+			case "varfile":
+				from, subject = "in a var file", value.Expr.Range().Ptr()
+			}
+		}
+		if from == "" {
+			continue
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: severity,
+			Summary:  "Unused variable",
+			Detail: fmt.Sprintf("The %q variable is set %s, but no expression of "+
+				"the config files uses it.", name, from),
+			Subject: subject,
+		})
+	}
+	return diags
+}
+
+// variableReferences returns the names of the input variables referenced in
+// files, outside of variable blocks, where they can only be referenced by
+// their own validation rules. It returns false when the variables used can't
+// be told: when a file is not a native syntax one, or when the whole var
+// object is referenced, like in `var[local.name]`.
+func variableReferences(files []*hcl.File) (map[string]bool, bool) {
+	used := map[string]bool{}
+	known := true
+	visit := func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || expr.Traversal.RootName() != inputVariablesAccessor {
+			return nil
+		}
+		if len(expr.Traversal) < 2 {
+			known = false
+			return nil
+		}
+		switch step := expr.Traversal[1].(type) {
+		case hcl.TraverseAttr:
+			used[step.Name] = true
+		case hcl.TraverseIndex:
+			if step.Key.IsKnown() && !step.Key.IsNull() && step.Key.Type() == cty.String {
+				used[step.Key.AsString()] = true
+			} else {
+				known = false
+			}
+		}
+		return nil
+	}
+	for _, file := range files {
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			return nil, false
+		}
+		for _, attr := range body.Attributes {
+			hclsyntax.VisitAll(attr, visit)
+		}
+		for _, block := range body.Blocks {
+			switch block.Type {
+			case variableLabel, variablesLabel:
+				continue
+			}
+			hclsyntax.VisitAll(block, visit)
+		}
+	}
+	return used, known
+}
+
+// checkUnusedPlugins reports the plugins of required_plugins blocks that
+// have none of their components used in the config files. The components of
+// a plugin are named after it, like the amazon-ebs builder of the amazon
+// plugin.
+func (cfg *PackerConfig) checkUnusedPlugins(severity hcl.DiagnosticSeverity) hcl.Diagnostics {
+	types := componentTypes(cfg.files)
+	var diags hcl.Diagnostics
+	for _, reqs := range cfg.Packer.RequiredPlugins {
+		names := make([]string, 0, len(reqs.RequiredPlugins))
+		for name := range reqs.RequiredPlugins {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			req := reqs.RequiredPlugins[name]
+			if req.PluginDependencyReason == PluginDependencyImplicit || usesPlugin(types, name) {
+				continue
+			}
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: severity,
+				Summary:  "Unused plugin",
+				Detail: fmt.Sprintf("The %q plugin is required, but none of its "+
+					"components is used in the config files. Its "+
+					"required_plugins entry can be removed.", name),
+				Subject: req.DeclRange.Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// usesPlugin tells whether one of the component types belongs to the plugin
+// whose accessor is name.
+func usesPlugin(types map[string]bool, name string) bool {
+	for typ := range types {
+		if typ == name || strings.HasPrefix(typ, name+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// componentTypes returns the types of the sources, data sources,
+// provisioners and post-processors used in files.
+func componentTypes(files []*hcl.File) map[string]bool {
+	types := map[string]bool{}
+	add := func(block *hcl.Block) {
+		if len(block.Labels) > 0 {
+			types[block.Labels[0]] = true
+		}
+	}
+	for _, file := range files {
+		content, _, _ := file.Body.PartialContent(configSchema)
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case sourceLabel, dataSourceLabel:
+				add(block)
+			case buildLabel:
+				content, _, _ := block.Body.PartialContent(buildSchema)
+				for _, block := range content.Blocks {
+					switch block.Type {
+					case buildProvisionerLabel, buildErrorCleanupProvisionerLabel, buildPostProcessorLabel:
+						add(block)
+					case buildPostProcessorsLabel:
+						content, _, _ := block.Body.PartialContent(postProcessorsSchema)
+						for _, block := range content.Blocks {
+							add(block)
+						}
+					}
+				}
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,111 @@
+package hcl2template
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestPackerConfig_checkUnused(t *testing.T) {
+	src := `variable "region" {
+  type = string
+}
+
+variable "unused" {
+  type = string
+  validation {
+    condition     = var.unused != ""
+    error_message = "The unused variable must be set."
+  }
+}
+
+variable "defaulted" {
+  default = "value"
+}
+
+locals {
+  zone = "${var.region}-a"
+}
+
+source "amazon-ebs" "ubuntu" {
+  region = local.zone
+}
+
+build {
+  sources = ["source.amazon-ebs.ubuntu"]
+}
+`
+	file, diags := hclsyntax.ParseConfig([]byte(src), "test.pkr.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	set := func(from string) []VariableAssignment {
+		return []VariableAssignment{{From: from, Value: cty.StringVal("value"), Expr: hcl.StaticExpr(cty.StringVal("value"), hcl.Range{})}}
+	}
+
+	for _, strict := range []bool{false, true} {
+		cfg := &PackerConfig{
+			files:             []*hcl.File{file},
+			ValidationOptions: ValidationOptions{Strict: strict, WarnOnUnused: true},
+			InputVariables: Variables{
+				"region":    &Variable{Name: "region", Values: set("cmd")},
+				"unused":    &Variable{Name: "unused", Values: set("varfile")},
+				"defaulted": &Variable{Name: "defaulted", Values: set("default")},
+			},
+		}
+		cfg.Packer.RequiredPlugins = []*RequiredPlugins{{
+			RequiredPlugins: map[string]*RequiredPlugin{
+				"amazon": {Name: "amazon"},
+				"docker": {Name: "docker"},
+				"qemu":   {Name: "qemu", PluginDependencyReason: PluginDependencyImplicit},
+			},
+		}}
+		diags := cfg.checkUnused()
+		if len(diags) != 2 {
+			t.Fatalf("expected 2 diagnostics, got %s", diags)
+		}
+		if diags.HasErrors() != strict {
+			t.Errorf("strict: %t, unexpected diagnostics %s", strict, diags)
+		}
+		if diags[0].Summary != "Unused variable" || diags[1].Summary != "Unused plugin" {
+			t.Errorf("expected the unused variable and plugin to be reported, got %s", diags)
+		}
+	}
+}
+
+func TestVariableReferences(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		wantUsed  []string
+		wantKnown bool
+	}{
+		{"attribute", `locals { a = var.a }`, []string{"a"}, true},
+		{"index", `locals { b = var["b"] }`, []string{"b"}, true},
+		{"nested", `build {
+  provisioner "shell" {
+    inline = [for s in var.c : upper(s)]
+  }
+}`, []string{"c"}, true},
+		{"whole object", `locals { d = var[local.name] }`, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclsyntax.ParseConfig([]byte(tt.src), "test.pkr.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			used, known := variableReferences([]*hcl.File{file})
+			if known != tt.wantKnown {
+				t.Fatalf("expected known to be %t", tt.wantKnown)
+			}
+			for _, name := range tt.wantUsed {
+				if !used[name] {
+					t.Errorf("expected %q to be used, got %v", name, used)
+				}
+			}
+		})
+	}
+}
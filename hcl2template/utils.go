@@ -34,6 +34,19 @@ func warningErrorsToDiags(block *hcl.Block, warnings []string, err error) hcl.Di
 	return diags
 }
 
+// attributeRange returns the range of the value of the name attribute of
+// block, or the range of the block definition when the attribute isn't set, so
+// that diagnostics about an attribute point at it.
+func attributeRange(block *hcl.Block, name string) *hcl.Range {
+	content, _, _ := block.Body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: name}},
+	})
+	if attr, found := content.Attributes[name]; found {
+		return attr.Expr.Range().Ptr()
+	}
+	return block.DefRange.Ptr()
+}
+
 func isDir(name string) (bool, error) {
 	s, err := os.Stat(name)
 	if err != nil {
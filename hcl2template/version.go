@@ -15,8 +15,15 @@ import (
 type VersionConstraint struct {
 	Required  version.Constraints
 	DeclRange hcl.Range
+
+	// Latest is set when the constraint was the LatestVersionKeyword.
+	Latest bool
 }
 
+// LatestVersionKeyword can be used as the version of a required plugin to
+// explicitly require its most recent release.
+const LatestVersionKeyword = "latest"
+
 func decodeVersionConstraint(attr *hcl.Attribute) (VersionConstraint, hcl.Diagnostics) {
 	ret := VersionConstraint{
 		DeclRange: attr.Range,
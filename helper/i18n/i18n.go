@@ -0,0 +1,98 @@
+// Package i18n translates the messages Packer shows to its users.
+//
+// Messages are looked up in a catalog by their English format, like gettext
+// does, so that an untranslated message is shown as is. Only what is shown to
+// users is translated: log lines stay in English, so that they can be searched
+// and shared regardless of the language of whoever ran Packer.
+//
+// The language is read from the PACKER_LANG env var, then from the usual
+// LC_ALL, LC_MESSAGES and LANG ones. When PACKER_LANG_DUAL is set, translated
+// messages are followed by their English version.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultLang is the language messages are written in.
+const DefaultLang = "en"
+
+// A Catalog maps languages to the translations of the messages, keyed by
+// their English format.
+type Catalog map[string]map[string]string
+
+// Lang returns the language messages are shown in, like `fr` for the
+// fr_FR.UTF-8 locale.
+func Lang() string {
+	for _, env := range []string{"PACKER_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			return parseLocale(v)
+		}
+	}
+	return DefaultLang
+}
+
+// parseLocale returns the language of a POSIX locale, like
+// language_TERRITORY.codeset@modifier.
+func parseLocale(locale string) string {
+	if i := strings.IndexAny(locale, "_.@-"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ToLower(locale)
+	if locale == "c" || locale == "posix" || locale == "" {
+		return DefaultLang
+	}
+	return locale
+}
+
+// dual tells whether translated messages are followed by their English
+// version.
+func dual() bool {
+	return os.Getenv("PACKER_LANG_DUAL") != ""
+}
+
+// translate returns the translation of format in lang, and whether there is
+// one.
+func (c Catalog) translate(lang, format string) (string, bool) {
+	translated, found := c[lang][format]
+	return translated, found && translated != ""
+}
+
+// Sprintf formats the translation of format in the language of the user, or
+// format itself when it has no translation.
+func (c Catalog) Sprintf(format string, args ...interface{}) string {
+	translated, found := c.translate(Lang(), format)
+	if !found {
+		return fmt.Sprintf(format, args...)
+	}
+	msg := fmt.Sprintf(translated, args...)
+	if dual() {
+		msg += "\n[en] " + strings.TrimLeft(fmt.Sprintf(format, args...), "\n")
+	}
+	return msg
+}
+
+// Text returns the translation of msg, which is not a format, like the
+// summary of a diagnostic.
+func (c Catalog) Text(msg string) string {
+	translated, found := c.translate(Lang(), msg)
+	if !found {
+		return msg
+	}
+	if dual() {
+		return translated + " [en: " + msg + "]"
+	}
+	return translated
+}
+
+// Sprintf formats the translation of format from the Messages catalog.
+func Sprintf(format string, args ...interface{}) string {
+	return Messages.Sprintf(format, args...)
+}
+
+// Text returns the translation of msg from the Messages catalog.
+func Text(msg string) string {
+	return Messages.Text(msg)
+}
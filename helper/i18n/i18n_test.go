@@ -0,0 +1,98 @@
+package i18n
+
+import (
+	"os"
+	"testing"
+)
+
+func setenv(t *testing.T, key, value string) {
+	old, set := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if set {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestLang(t *testing.T) {
+	tests := []struct {
+		packerLang, lang string
+		want             string
+	}{
+		{"", "", DefaultLang},
+		{"", "fr_FR.UTF-8", "fr"},
+		{"", "C", DefaultLang},
+		{"", "POSIX", DefaultLang},
+		{"de", "fr_FR.UTF-8", "de"},
+		{"pt-BR", "", "pt"},
+		{"", "sr_RS@latin", "sr"},
+	}
+	for _, tt := range tests {
+		setenv(t, "LC_ALL", "")
+		setenv(t, "LC_MESSAGES", "")
+		setenv(t, "PACKER_LANG", tt.packerLang)
+		setenv(t, "LANG", tt.lang)
+		if got := Lang(); got != tt.want {
+			t.Errorf("Lang() with PACKER_LANG=%q LANG=%q: got %q, want %q", tt.packerLang, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	catalog := Catalog{
+		"fr": {
+			"Build '%s' finished.": "Le build '%s' est terminé.",
+			"Invalid expression":   "Expression invalide",
+		},
+	}
+	setenv(t, "PACKER_LANG_DUAL", "")
+
+	setenv(t, "PACKER_LANG", "en")
+	if got, want := catalog.Sprintf("Build '%s' finished.", "foo"), "Build 'foo' finished."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	setenv(t, "PACKER_LANG", "fr")
+	if got, want := catalog.Sprintf("Build '%s' finished.", "foo"), "Le build 'foo' est terminé."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := catalog.Sprintf("Build '%s' errored.", "foo"), "Build 'foo' errored."; got != want {
+		t.Errorf("untranslated message: got %q, want %q", got, want)
+	}
+	if got, want := catalog.Text("Invalid expression"), "Expression invalide"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	setenv(t, "PACKER_LANG_DUAL", "1")
+	if got, want := catalog.Sprintf("Build '%s' finished.", "foo"), "Le build 'foo' est terminé.\n[en] Build 'foo' finished."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := catalog.Text("Invalid expression"), "Expression invalide [en: Invalid expression]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMessages_verbs(t *testing.T) {
+	for lang, messages := range Messages {
+		for format, translated := range messages {
+			if got, want := verbs(translated), verbs(format); got != want {
+				t.Errorf("%s translation of %q has verbs %q, want %q", lang, format, got, want)
+			}
+		}
+	}
+}
+
+// verbs returns the formatting verbs of format.
+func verbs(format string) string {
+	var v []byte
+	for i := 0; i < len(format)-1; i++ {
+		if format[i] == '%' {
+			v = append(v, format[i+1])
+			i++
+		}
+	}
+	return string(v)
+}
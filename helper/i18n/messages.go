@@ -0,0 +1,72 @@
+package i18n
+
+// Messages is the catalog of the messages of Packer core. Translations keep
+// the verbs of the English format, in the same order.
+var Messages = Catalog{
+	"fr": {
+		// packer build
+		"Debug mode enabled. Builds will not be parallelized.":           "Mode debug activé. Les builds ne seront pas parallélisés.",
+		"%s: output will be in this color.":                              "%s : la sortie sera de cette couleur.",
+		"Build '%s' errored after %s: %s":                                "Le build '%s' a échoué après %s : %s",
+		"Build '%s' finished after %s.":                                  "Le build '%s' s'est terminé après %s.",
+		"\n==> Wait completed after %s":                                  "\n==> Attente terminée après %s",
+		"Cleanly cancelled builds after being interrupted.":              "Builds annulés proprement après interruption.",
+		"\n==> Some builds didn't complete successfully and had errors:": "\n==> Certains builds ne se sont pas terminés correctement et ont eu des erreurs :",
+		"\n==> Builds finished. The artifacts of successful builds are:": "\n==> Builds terminés. Les artefacts des builds réussis sont :",
+		"\n==> Builds finished but no artifacts were created.":           "\n==> Builds terminés mais aucun artefact n'a été créé.",
+		"could not write diagnostic: %s":                                 "impossible d'écrire le diagnostic : %s",
+		// diagnostics
+		"Unsupported argument":                  "Argument non supporté",
+		"Unsupported block type":                "Type de bloc non supporté",
+		"Missing required argument":             "Argument requis manquant",
+		"Invalid expression":                    "Expression invalide",
+		"Unknown variable":                      "Variable inconnue",
+		"Undefined -var variable":               "Variable -var non définie",
+		"Argument or block definition required": "Définition d'argument ou de bloc requise",
+		"Duplicate argument":                    "Argument en double",
+	},
+	"de": {
+		// packer build
+		"Debug mode enabled. Builds will not be parallelized.":           "Debug-Modus aktiviert. Builds werden nicht parallelisiert.",
+		"%s: output will be in this color.":                              "%s: Ausgabe erscheint in dieser Farbe.",
+		"Build '%s' errored after %s: %s":                                "Build '%s' ist nach %s fehlgeschlagen: %s",
+		"Build '%s' finished after %s.":                                  "Build '%s' wurde nach %s abgeschlossen.",
+		"\n==> Wait completed after %s":                                  "\n==> Warten nach %s abgeschlossen",
+		"Cleanly cancelled builds after being interrupted.":              "Builds wurden nach der Unterbrechung sauber abgebrochen.",
+		"\n==> Some builds didn't complete successfully and had errors:": "\n==> Einige Builds wurden nicht erfolgreich abgeschlossen und hatten Fehler:",
+		"\n==> Builds finished. The artifacts of successful builds are:": "\n==> Builds abgeschlossen. Die Artefakte der erfolgreichen Builds sind:",
+		"\n==> Builds finished but no artifacts were created.":           "\n==> Builds abgeschlossen, aber es wurden keine Artefakte erstellt.",
+		"could not write diagnostic: %s":                                 "Diagnose konnte nicht geschrieben werden: %s",
+		// diagnostics
+		"Unsupported argument":                  "Nicht unterstütztes Argument",
+		"Unsupported block type":                "Nicht unterstützter Blocktyp",
+		"Missing required argument":             "Erforderliches Argument fehlt",
+		"Invalid expression":                    "Ungültiger Ausdruck",
+		"Unknown variable":                      "Unbekannte Variable",
+		"Undefined -var variable":               "Nicht definierte -var-Variable",
+		"Argument or block definition required": "Argument- oder Blockdefinition erforderlich",
+		"Duplicate argument":                    "Doppeltes Argument",
+	},
+	"es": {
+		// packer build
+		"Debug mode enabled. Builds will not be parallelized.":           "Modo de depuración activado. Los builds no se ejecutarán en paralelo.",
+		"%s: output will be in this color.":                              "%s: la salida se mostrará en este color.",
+		"Build '%s' errored after %s: %s":                                "El build '%s' falló después de %s: %s",
+		"Build '%s' finished after %s.":                                  "El build '%s' terminó después de %s.",
+		"\n==> Wait completed after %s":                                  "\n==> Espera completada después de %s",
+		"Cleanly cancelled builds after being interrupted.":              "Builds cancelados limpiamente tras la interrupción.",
+		"\n==> Some builds didn't complete successfully and had errors:": "\n==> Algunos builds no terminaron correctamente y tuvieron errores:",
+		"\n==> Builds finished. The artifacts of successful builds are:": "\n==> Builds terminados. Los artefactos de los builds exitosos son:",
+		"\n==> Builds finished but no artifacts were created.":           "\n==> Builds terminados, pero no se creó ningún artefacto.",
+		"could not write diagnostic: %s":                                 "no se pudo escribir el diagnóstico: %s",
+		// diagnostics
+		"Unsupported argument":                  "Argumento no admitido",
+		"Unsupported block type":                "Tipo de bloque no admitido",
+		"Missing required argument":             "Falta un argumento obligatorio",
+		"Invalid expression":                    "Expresión no válida",
+		"Unknown variable":                      "Variable desconocida",
+		"Undefined -var variable":               "Variable -var no definida",
+		"Argument or block definition required": "Se requiere una definición de argumento o de bloque",
+		"Duplicate argument":                    "Argumento duplicado",
+	},
+}
@@ -0,0 +1,62 @@
+// Package longpath helps using Windows paths longer than MAX_PATH, and paths
+// on UNC network shares, for plugin folders and uploaded files.
+//
+// Go extends long absolute local paths itself, but neither relative paths nor
+// UNC paths, like \\server\share\packer\plugins. Extended paths start with
+// \\?\, whose ? is a glob metacharacter, so Glob must be used to list them.
+package longpath
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the length from which a path has to be extended, MAX_PATH minus
+// the 12 characters of an 8.3 file name, as directories need that room.
+const maxPath = 248
+
+const (
+	extendedPrefix    = `\\?\`
+	extendedUNCPrefix = `\\?\UNC\`
+)
+
+// extend returns the extended form of the absolute Windows path abs.
+func extend(abs string) string {
+	switch {
+	case strings.HasPrefix(abs, extendedPrefix):
+		return abs
+	case strings.HasPrefix(abs, `\\`):
+		return extendedUNCPrefix + abs[2:]
+	default:
+		return extendedPrefix + abs
+	}
+}
+
+// Glob returns the paths in dir matching pattern, a slash separated
+// filepath.Match pattern like "*/*/packer-plugin-*". Unlike filepath.Glob,
+// dir is never interpreted as a pattern, so that it can be an extended path.
+// Returned paths start with dir as given.
+func Glob(dir, pattern string) ([]string, error) {
+	matches := []string{dir}
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, err := filepath.Match(elem, ""); err != nil {
+			return nil, err
+		}
+		var next []string
+		for _, parent := range matches {
+			entries, err := ioutil.ReadDir(Fix(parent))
+			if err != nil {
+				// like filepath.Glob, unreadable directories are ignored
+				continue
+			}
+			for _, entry := range entries {
+				if ok, _ := filepath.Match(elem, entry.Name()); ok {
+					next = append(next, filepath.Join(parent, entry.Name()))
+				}
+			}
+		}
+		matches = next
+	}
+	return matches, nil
+}
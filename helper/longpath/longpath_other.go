@@ -0,0 +1,8 @@
+// +build !windows
+
+package longpath
+
+// Fix returns path, only Windows paths need to be extended.
+func Fix(path string) string {
+	return path
+}
@@ -0,0 +1,65 @@
+package longpath
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExtend(t *testing.T) {
+	for path, want := range map[string]string{
+		`C:\packer\plugins`:             `\\?\C:\packer\plugins`,
+		`\\server\share\packer\plugins`: `\\?\UNC\server\share\packer\plugins`,
+		`\\?\C:\packer\plugins`:         `\\?\C:\packer\plugins`,
+	} {
+		if got := extend(path); got != want {
+			t.Errorf("extend(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-longpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the ? and [ of dir must not be interpreted
+	dir = filepath.Join(dir, "[plugins?]")
+	for _, p := range []string{
+		"github.com/hashicorp/amazon/packer-plugin-amazon_v1.0.0_x5.0_linux_amd64",
+		"github.com/hashicorp/amazon/packer-plugin-amazon_v1.0.0_x5.0_linux_amd64_SHA256SUM",
+		"github.com/hashicorp/docker/packer-plugin-docker_v1.0.0_x5.0_linux_amd64",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matches, err := Glob(dir, "github.com/hashicorp/amazon/packer-plugin-amazon_*_linux_amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64")}
+	if !reflect.DeepEqual(matches, want) {
+		t.Fatalf("Glob() = %v, want %v", matches, want)
+	}
+
+	matches, err = Glob(dir, "*/*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected the amazon and docker folders, got %v", matches)
+	}
+
+	if _, err := Glob(dir, "[-"); err == nil {
+		t.Fatal("expected a bad pattern error")
+	}
+}
@@ -0,0 +1,19 @@
+// +build windows
+
+package longpath
+
+import "path/filepath"
+
+// Fix returns the extended form of path when it is too long to be used with
+// the Windows APIs, or when it is on a UNC share and long. Other paths are
+// returned as is.
+func Fix(path string) string {
+	if path == "" {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil || len(abs) < maxPath {
+		return path
+	}
+	return extend(abs)
+}
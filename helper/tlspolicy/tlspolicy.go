@@ -0,0 +1,134 @@
+// Package tlspolicy applies a TLS policy, set with env vars, to the http
+// clients of Packer. Plugins inherit the environment of Packer, so the
+// policy applies to the internal plugins too.
+//
+// The policy is set with:
+//
+//   - PACKER_TLS_MIN_VERSION, the minimum TLS version, like 1.2.
+//   - PACKER_TLS_CIPHER_SUITES, a comma separated list of the names of the
+//     cipher suites allowed with TLS 1.2 and below, like
+//     TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. TLS 1.3 suites are not
+//     configurable.
+//   - PACKER_TLS_CA_FILE, a PEM file of root certificates trusted in
+//     addition to the ones of the system.
+package tlspolicy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Env vars setting the policy.
+const (
+	MinVersionEnv   = "PACKER_TLS_MIN_VERSION"
+	CipherSuitesEnv = "PACKER_TLS_CIPHER_SUITES"
+	CAFileEnv       = "PACKER_TLS_CA_FILE"
+)
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Policy restricts the TLS connections made by http clients. Its zero value
+// restricts nothing.
+type Policy struct {
+	// MinVersion is the minimum TLS version, like tls.VersionTLS12.
+	MinVersion uint16
+	// CipherSuites are the cipher suites allowed with TLS 1.2 and below.
+	CipherSuites []uint16
+	// RootCAs are the trusted root certificates, nil for the ones of the
+	// system.
+	RootCAs *x509.CertPool
+}
+
+// FromEnv reads the policy from its env vars.
+func FromEnv() (*Policy, error) {
+	p := &Policy{}
+	if v := os.Getenv(MinVersionEnv); v != "" {
+		version, found := versions[strings.TrimSpace(v)]
+		if !found {
+			return nil, fmt.Errorf("invalid %s %q, expected one of 1.0, 1.1, 1.2 or 1.3", MinVersionEnv, v)
+		}
+		p.MinVersion = version
+	}
+	if v := os.Getenv(CipherSuitesEnv); v != "" {
+		suites := map[string]uint16{}
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			id, found := suites[name]
+			if !found {
+				return nil, fmt.Errorf("invalid %s: unknown cipher suite %q", CipherSuitesEnv, name)
+			}
+			p.CipherSuites = append(p.CipherSuites, id)
+		}
+	}
+	if path := os.Getenv(CAFileEnv); path != "" {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", CAFileEnv, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("%s can't be used, the root certificates of the system can't be loaded: %s", CAFileEnv, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid %s: no PEM encoded certificate found in %s", CAFileEnv, path)
+		}
+		p.RootCAs = pool
+	}
+	return p, nil
+}
+
+// Apply restricts cfg according to p.
+func (p *Policy) Apply(cfg *tls.Config) {
+	if p.MinVersion != 0 {
+		cfg.MinVersion = p.MinVersion
+	}
+	if len(p.CipherSuites) > 0 {
+		cfg.CipherSuites = p.CipherSuites
+	}
+	if p.RootCAs != nil {
+		cfg.RootCAs = p.RootCAs
+	}
+}
+
+var (
+	loadOnce sync.Once
+	policy   *Policy
+	loadErr  error
+)
+
+// Load returns the policy read from the env vars of the process, which are
+// read once.
+func Load() (*Policy, error) {
+	loadOnce.Do(func() {
+		policy, loadErr = FromEnv()
+	})
+	return policy, loadErr
+}
+
+// ConfigureTransport restricts the TLS connections of t according to the
+// policy of the process.
+func ConfigureTransport(t *http.Transport) error {
+	p, err := Load()
+	if err != nil {
+		return err
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	p.Apply(t.TLSClientConfig)
+	return nil
+}
@@ -0,0 +1,115 @@
+package tlspolicy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name             string
+		env              map[string]string
+		wantMinVersion   uint16
+		wantCipherSuites []uint16
+		wantErr          bool
+	}{
+		{"empty", map[string]string{}, 0, nil, false},
+		{"min version", map[string]string{MinVersionEnv: "1.2"}, tls.VersionTLS12, nil, false},
+		{"cipher suites", map[string]string{CipherSuitesEnv: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}, 0,
+			[]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}, false},
+		{"unknown min version", map[string]string{MinVersionEnv: "1.4"}, 0, nil, true},
+		{"unknown cipher suite", map[string]string{CipherSuitesEnv: "TLS_NULL"}, 0, nil, true},
+		{"missing ca file", map[string]string{CAFileEnv: filepath.Join("testdata", "missing.pem")}, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{MinVersionEnv, CipherSuitesEnv, CAFileEnv} {
+				defer os.Setenv(name, os.Getenv(name))
+				os.Setenv(name, tt.env[name])
+			}
+			p, err := FromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.MinVersion != tt.wantMinVersion {
+				t.Errorf("FromEnv() MinVersion = %x, want %x", p.MinVersion, tt.wantMinVersion)
+			}
+			if len(p.CipherSuites) != len(tt.wantCipherSuites) {
+				t.Fatalf("FromEnv() CipherSuites = %v, want %v", p.CipherSuites, tt.wantCipherSuites)
+			}
+			for i := range p.CipherSuites {
+				if p.CipherSuites[i] != tt.wantCipherSuites[i] {
+					t.Errorf("FromEnv() CipherSuites = %v, want %v", p.CipherSuites, tt.wantCipherSuites)
+				}
+			}
+		})
+	}
+}
+
+func TestFromEnv_caFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the root certificates of the system can't be loaded on windows")
+	}
+	dir, err := ioutil.TempDir("", "packer-tlspolicy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Example Corp Root CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Setenv(CAFileEnv, os.Getenv(CAFileEnv))
+	os.Setenv(CAFileEnv, caFile)
+	p, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: p.RootCAs}); err != nil {
+		t.Errorf("the certificate of %s is not trusted: %s", CAFileEnv, err)
+	}
+
+	cfg := &tls.Config{}
+	p.Apply(cfg)
+	if cfg.RootCAs != p.RootCAs {
+		t.Errorf("Apply() did not set the root certificates")
+	}
+}
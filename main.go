@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"log"
 	"math/rand"
+	"net/http"
 	"os"
 	"runtime"
 	"sync"
@@ -21,7 +22,9 @@ import (
 	pluginsdk "github.com/hashicorp/packer-plugin-sdk/plugin"
 	"github.com/hashicorp/packer-plugin-sdk/tmp"
 	"github.com/hashicorp/packer/command"
+	"github.com/hashicorp/packer/helper/tlspolicy"
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/diagnostics"
 	"github.com/hashicorp/packer/version"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/panicwrap"
@@ -44,6 +47,14 @@ func realMain() int {
 	wrapConfig.CookieKey = "PACKER_WRAP_COOKIE"
 	wrapConfig.CookieValue = "49C22B1A-3A93-4C98-97FA-E07D18C787B5"
 
+	// Restrict the TLS connections of every http client sharing the default
+	// transport, in Packer and in the plugins it starts, to the TLS policy
+	// set in the environment.
+	if err := tlspolicy.ConfigureTransport(http.DefaultTransport.(*http.Transport)); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid TLS policy: %s\n", err)
+		return 1
+	}
+
 	if inPlugin() || panicwrap.Wrapped(&wrapConfig) {
 		// Call the real main
 		return wrappedMain()
@@ -55,6 +66,10 @@ func realMain() int {
 	UUID, _ := uuid.GenerateUUID()
 	os.Setenv("PACKER_RUN_UUID", UUID)
 
+	// Pass the start time of this run to the environment too, so that the
+	// time data source returns the same time to all the plugins it starts.
+	os.Setenv("PACKER_RUN_START_TIME", time.Now().UTC().Format(time.RFC3339Nano))
+
 	// Determine where logs should go in general (requested by the user)
 	logWriter, err := logOutput()
 	if err != nil {
@@ -80,6 +95,10 @@ func realMain() int {
 	defer os.Remove(logTempFile.Name())
 	defer logTempFile.Close()
 
+	// Let the wrapped process know where logs are, so that it can add them
+	// to a diagnostics bundle when a build fails with an internal error.
+	os.Setenv(diagnostics.LogPathEnvVar, logTempFile.Name())
+
 	// Setup the prefixed readers that send data properly to
 	// stdout/stderr.
 	doneCh := make(chan struct{})
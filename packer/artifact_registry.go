@@ -0,0 +1,314 @@
+package packer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArtifactVersionFormat is the time layout of the versions of stored builds.
+const ArtifactVersionFormat = "20060102T150405Z"
+
+// LatestArtifactVersion can be promoted instead of a version, to promote the
+// last version of a lineage.
+const LatestArtifactVersion = "latest"
+
+// A lineage is the history of the builds of a same name in a
+// LocalArtifactStore: every time a build stores its artifacts, the
+// artifacts.json document of the build is kept as a new version of the
+// lineage, in <dir>/<lineage>/versions/<version>.json. Only the files of the
+// last version are kept; older versions keep the ids of their artifacts, like
+// image ids, which is what later builds use.
+//
+// Versions are promoted to channels, like stable, recorded in
+// <dir>/<lineage>/channels.json, so that a pipeline of builds can use the last
+// version of a lineage that passed some checks as its source.
+
+// ArtifactRegistry looks up the versions of the lineages of an artifact
+// store.
+type ArtifactRegistry interface {
+	// Channel returns the version of lineage promoted to channel.
+	Channel(lineage, channel string) (*StoredBuild, error)
+	// Latest returns the last version of lineage having labels.
+	Latest(lineage string, labels map[string]string) (*StoredBuild, error)
+}
+
+var (
+	_ ArtifactRegistry = &LocalArtifactStore{}
+	_ ArtifactRegistry = &HTTPArtifactRegistry{}
+)
+
+// HasLabels tells whether b has all of labels, with the same values.
+func (b *StoredBuild) HasLabels(labels map[string]string) bool {
+	for k, v := range labels {
+		if got, found := b.Labels[k]; !found || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *LocalArtifactStore) lineageDir(lineage string) string {
+	return filepath.Join(s.Dir, storeDirName(lineage))
+}
+
+// addVersion records doc as a new version of lineage. The version of doc is
+// suffixed when another build stored a version in the same second.
+func (s *LocalArtifactStore) addVersion(lineage string, doc *StoredBuild) error {
+	dir := filepath.Join(s.lineageDir(lineage), "versions")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	base := doc.Version
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, doc.Version+".json")); os.IsNotExist(err) {
+			break
+		}
+		doc.Version = fmt.Sprintf("%s-%d", base, i)
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, doc.Version+".json"), append(b, '\n'), 0644)
+}
+
+// Versions returns the versions of lineage, from the oldest to the newest.
+func (s *LocalArtifactStore) Versions(lineage string) ([]*StoredBuild, error) {
+	files, err := filepath.Glob(filepath.Join(s.lineageDir(lineage), "versions", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]*StoredBuild, 0, len(files))
+	for _, file := range files {
+		doc, err := readStoredBuild(file)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, doc)
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if !versions[i].Time.Equal(versions[j].Time) {
+			return versions[i].Time.Before(versions[j].Time)
+		}
+		return versions[i].Version < versions[j].Version
+	})
+	return versions, nil
+}
+
+// Latest returns the last version of lineage having labels.
+func (s *LocalArtifactStore) Latest(lineage string, labels map[string]string) (*StoredBuild, error) {
+	versions, err := s.Versions(lineage)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].HasLabels(labels) {
+			return versions[i], nil
+		}
+	}
+	if len(labels) > 0 {
+		return nil, fmt.Errorf("no version of %s with labels %v was stored in %s", lineage, labels, s.Dir)
+	}
+	return nil, fmt.Errorf("no version of %s was stored in %s", lineage, s.Dir)
+}
+
+// Version returns version of lineage; LatestArtifactVersion returns its last
+// version.
+func (s *LocalArtifactStore) Version(lineage, version string) (*StoredBuild, error) {
+	if version == LatestArtifactVersion {
+		versions, err := s.Versions(lineage)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no version of %s was stored in %s", lineage, s.Dir)
+		}
+		return versions[len(versions)-1], nil
+	}
+	if err := checkRegistryName("version", version); err != nil {
+		return nil, err
+	}
+	doc, err := readStoredBuild(filepath.Join(s.lineageDir(lineage), "versions", version+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("version %s of %s was not found in %s", version, lineage, s.Dir)
+	}
+	return doc, err
+}
+
+// ArtifactChannel is the version of a lineage promoted to a channel.
+type ArtifactChannel struct {
+	Version  string    `json:"version"`
+	Promoted time.Time `json:"promoted"`
+}
+
+// Channels returns the channels of lineage, by name.
+func (s *LocalArtifactStore) Channels(lineage string) (map[string]ArtifactChannel, error) {
+	channels := map[string]ArtifactChannel{}
+	b, err := ioutil.ReadFile(filepath.Join(s.lineageDir(lineage), "channels.json"))
+	if os.IsNotExist(err) {
+		return channels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &channels); err != nil {
+		return nil, fmt.Errorf("invalid channels of %s: %w", lineage, err)
+	}
+	return channels, nil
+}
+
+// Promote assigns version of lineage to channel, replacing the version
+// previously promoted to it. It returns the promoted version.
+func (s *LocalArtifactStore) Promote(lineage, version, channel string) (*StoredBuild, error) {
+	if err := checkRegistryName("channel", channel); err != nil {
+		return nil, err
+	}
+	doc, err := s.Version(lineage, version)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := s.Channels(lineage)
+	if err != nil {
+		return nil, err
+	}
+	channels[channel] = ArtifactChannel{Version: doc.Version, Promoted: time.Now().UTC()}
+	b, err := json.MarshalIndent(channels, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// channels.json is read by concurrent builds, so it is replaced at once.
+	path := filepath.Join(s.lineageDir(lineage), "channels.json")
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "channels.json.*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Channel returns the version of lineage promoted to channel.
+func (s *LocalArtifactStore) Channel(lineage, channel string) (*StoredBuild, error) {
+	channels, err := s.Channels(lineage)
+	if err != nil {
+		return nil, err
+	}
+	c, found := channels[channel]
+	if !found {
+		return nil, fmt.Errorf("no version of %s was promoted to the %s channel", lineage, channel)
+	}
+	return s.Version(lineage, c.Version)
+}
+
+func readStoredBuild(path string) (*StoredBuild, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &StoredBuild{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, fmt.Errorf("invalid stored build %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// checkRegistryName makes sure a version or a channel can't be used to read or
+// write outside of the store.
+func checkRegistryName(what, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) {
+		return fmt.Errorf("invalid %s %q", what, name)
+	}
+	return nil
+}
+
+// HTTPArtifactRegistry reads the versions of the lineages of an artifact
+// store served over http, at <URL>/<lineage>/: the last version uploaded by an
+// HTTPArtifactStore in artifacts.json, the channels in channels.json and the
+// versions in versions/<version>.json. A local artifact store served by a web
+// server has the same layout.
+type HTTPArtifactRegistry struct {
+	URL string
+	// Headers are set on every request, for example to authenticate.
+	Headers map[string]string
+
+	// Client defaults to defaultArtifactStoreClient.
+	Client *http.Client
+}
+
+func (r *HTTPArtifactRegistry) get(ctx context.Context, lineage, name string, v interface{}) error {
+	u := strings.TrimSuffix(r.URL, "/") + "/" + storeDirName(lineage) + "/" + name
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	client := r.Client
+	if client == nil {
+		client = defaultArtifactStoreClient
+	}
+	log.Printf("[DEBUG] artifact registry: getting %q", u)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid document %s: %w", u, err)
+	}
+	return nil
+}
+
+func (r *HTTPArtifactRegistry) Channel(lineage, channel string) (*StoredBuild, error) {
+	channels := map[string]ArtifactChannel{}
+	if err := r.get(context.TODO(), lineage, "channels.json", &channels); err != nil {
+		return nil, err
+	}
+	c, found := channels[channel]
+	if !found {
+		return nil, fmt.Errorf("no version of %s was promoted to the %s channel", lineage, channel)
+	}
+	if err := checkRegistryName("version", c.Version); err != nil {
+		return nil, err
+	}
+	doc := &StoredBuild{}
+	if err := r.get(context.TODO(), lineage, "versions/"+c.Version+".json", doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Latest returns the last version uploaded of lineage, older versions can't
+// be listed over http: an error is returned when it doesn't have labels.
+func (r *HTTPArtifactRegistry) Latest(lineage string, labels map[string]string) (*StoredBuild, error) {
+	doc := &StoredBuild{}
+	if err := r.get(context.TODO(), lineage, "artifacts.json", doc); err != nil {
+		return nil, err
+	}
+	if !doc.HasLabels(labels) {
+		return nil, fmt.Errorf("the last version %s of %s doesn't have labels %v", doc.Version, lineage, labels)
+	}
+	return doc, nil
+}
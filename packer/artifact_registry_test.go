@@ -0,0 +1,136 @@
+package packer
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalArtifactStore_Promote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-artifact-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &LocalArtifactStore{Dir: filepath.Join(dir, "store")}
+	if _, err := store.Promote("qemu.ubuntu", LatestArtifactVersion, "stable"); err == nil {
+		t.Fatal("expected an error promoting a lineage without versions")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := store.Store(context.Background(), "qemu.ubuntu", testStoredArtifacts(t, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versions, err := store.Versions("qemu.ubuntu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Version == versions[1].Version {
+		t.Fatalf("expected two distinct versions, got %#v", versions)
+	}
+	first, last := versions[0].Version, versions[1].Version
+
+	if _, err := store.Promote("qemu.ubuntu", first, "stable"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Promote("qemu.ubuntu", LatestArtifactVersion, "testing"); err != nil {
+		t.Fatal(err)
+	}
+	for channel, want := range map[string]string{"stable": first, "testing": last} {
+		got, err := store.Channel("qemu.ubuntu", channel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != want || got.Artifacts[0].Id != "1" {
+			t.Errorf("unexpected version in the %s channel: %#v", channel, got)
+		}
+	}
+
+	if _, err := store.Channel("qemu.ubuntu", "prod"); err == nil {
+		t.Error("expected an error for a channel without version")
+	}
+	if _, err := store.Promote("qemu.ubuntu", "20060102T150405Z", "stable"); err == nil {
+		t.Error("expected an error promoting an unknown version")
+	}
+	if _, err := store.Promote("qemu.ubuntu", first, "../stable"); err == nil {
+		t.Error("expected an error promoting to an invalid channel")
+	}
+}
+
+func TestLocalArtifactStore_Latest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-artifact-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, distro := range []string{"ubuntu", "debian"} {
+		store := &LocalArtifactStore{Dir: filepath.Join(dir, "store"), Labels: map[string]string{"os": distro}}
+		if err := store.Store(context.Background(), "qemu.base", testStoredArtifacts(t, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := &LocalArtifactStore{Dir: filepath.Join(dir, "store")}
+	for labels, want := range map[string]string{"": "debian", "ubuntu": "ubuntu", "debian": "debian"} {
+		var filter map[string]string
+		if labels != "" {
+			filter = map[string]string{"os": labels}
+		}
+		got, err := store.Latest("qemu.base", filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Labels["os"] != want {
+			t.Errorf("Latest(%v) returned the %s version", filter, got.Labels["os"])
+		}
+	}
+	if _, err := store.Latest("qemu.base", map[string]string{"os": "arch"}); err == nil {
+		t.Error("expected an error when no version has the labels")
+	}
+}
+
+func TestHTTPArtifactRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-artifact-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &LocalArtifactStore{Dir: filepath.Join(dir, "store"), Labels: map[string]string{"os": "ubuntu"}}
+	if err := store.Store(context.Background(), "qemu.base", testStoredArtifacts(t, dir)); err != nil {
+		t.Fatal(err)
+	}
+	promoted, err := store.Promote("qemu.base", LatestArtifactVersion, "stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a local store served by a web server
+	srv := httptest.NewServer(http.FileServer(http.Dir(store.Dir)))
+	defer srv.Close()
+	registry := &HTTPArtifactRegistry{URL: srv.URL}
+
+	got, err := registry.Channel("qemu.base", "stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != promoted.Version {
+		t.Errorf("expected version %s in the stable channel, got %s", promoted.Version, got.Version)
+	}
+	if _, err := registry.Latest("qemu.base", map[string]string{"os": "ubuntu"}); err != nil {
+		t.Error(err)
+	}
+	if _, err := registry.Latest("qemu.base", map[string]string{"os": "debian"}); err == nil {
+		t.Error("expected an error when the last version doesn't have the labels")
+	}
+	if _, err := registry.Channel("qemu.base", "testing"); err == nil {
+		t.Error("expected an error for a channel without version")
+	}
+}
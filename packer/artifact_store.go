@@ -0,0 +1,231 @@
+package packer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+// ArtifactStore is where the artifacts of builds are pushed once their
+// post-processors ran. It is configured once per template, so that where
+// artifacts go doesn't depend on the post-processors of each build.
+type ArtifactStore interface {
+	// Store pushes the files of the artifacts of a build, along with an
+	// artifacts.json document describing them.
+	Store(ctx context.Context, buildName string, artifacts []packersdk.Artifact) error
+}
+
+// StoredArtifact describes an artifact in the artifacts.json document of a
+// build.
+type StoredArtifact struct {
+	BuilderId   string `json:"builder_id"`
+	Id          string `json:"id"`
+	Description string `json:"description"`
+	// Files are the names of the files of the artifact, relative to the
+	// directory of the build in the store.
+	Files []string `json:"files"`
+}
+
+// StoredBuild is the artifacts.json document of a build.
+type StoredBuild struct {
+	BuildName string `json:"build_name"`
+	// Version identifies the build in the versions of its lineage, see
+	// LocalArtifactStore.Versions.
+	Version string    `json:"version,omitempty"`
+	Time    time.Time `json:"time"`
+	// Labels are set by the artifact store, they are used to look up builds.
+	Labels    map[string]string `json:"labels,omitempty"`
+	Artifacts []StoredArtifact  `json:"artifacts"`
+}
+
+// artifactFile is a file of an artifact, and its name in the store.
+type artifactFile struct {
+	path, name string
+}
+
+// storedBuild returns the document describing the artifacts of a build and
+// the files to store. Files are stored flat in the directory of the build,
+// files whose names collide are prefixed with the index of their artifact.
+func storedBuild(buildName string, labels map[string]string, artifacts []packersdk.Artifact) (*StoredBuild, []artifactFile) {
+	now := time.Now().UTC()
+	doc := &StoredBuild{BuildName: buildName, Version: now.Format(ArtifactVersionFormat), Time: now, Labels: labels}
+	var files []artifactFile
+	names := map[string]bool{}
+	for i, artifact := range artifacts {
+		if artifact == nil {
+			continue
+		}
+		stored := StoredArtifact{
+			BuilderId:   artifact.BuilderId(),
+			Id:          artifact.Id(),
+			Description: artifact.String(),
+			Files:       []string{},
+		}
+		for _, path := range artifact.Files() {
+			name := filepath.Base(path)
+			if names[name] {
+				name = fmt.Sprintf("%d-%s", i, name)
+			}
+			names[name] = true
+			stored.Files = append(stored.Files, name)
+			files = append(files, artifactFile{path: path, name: name})
+		}
+		doc.Artifacts = append(doc.Artifacts, stored)
+	}
+	return doc, files
+}
+
+// storeDirName is the name of the directory of a build in a store.
+func storeDirName(buildName string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(buildName)
+}
+
+// LocalArtifactStore copies artifacts in a local directory, under a
+// directory per build. It also keeps track of the versions of each build, and
+// of the channels they are promoted to, see Promote.
+type LocalArtifactStore struct {
+	Dir string
+	// Labels are recorded in the artifacts.json document of every build.
+	Labels map[string]string
+}
+
+var _ ArtifactStore = &LocalArtifactStore{}
+
+func (s *LocalArtifactStore) Store(ctx context.Context, buildName string, artifacts []packersdk.Artifact) error {
+	doc, files := storedBuild(buildName, s.Labels, artifacts)
+	dir := filepath.Join(s.Dir, storeDirName(buildName))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		log.Printf("[DEBUG] artifact store: copying %q to %q", f.path, dir)
+		if err := copyFile(f.path, filepath.Join(dir, f.name)); err != nil {
+			return err
+		}
+	}
+	if err := s.addVersion(buildName, doc); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "artifacts.json"), append(b, '\n'), 0644)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// HTTPArtifactStore uploads artifacts with PUT requests, to
+// <URL>/<build name>/<file name>. This works with most artifact repositories
+// and with pre-authorized object storage endpoints. Like in a local store, the
+// artifacts.json document is also uploaded as a version of the build, to
+// <URL>/<build name>/versions/<version>.json.
+type HTTPArtifactStore struct {
+	URL string
+	// Headers are set on every request, for example to authenticate.
+	Headers map[string]string
+	// Labels are recorded in the artifacts.json document of every build.
+	Labels map[string]string
+
+	// Client defaults to defaultArtifactStoreClient.
+	Client *http.Client
+}
+
+var _ ArtifactStore = &HTTPArtifactStore{}
+
+// defaultArtifactStoreClient is used by http stores and registries without a
+// client. Unlike http.DefaultClient, it gives up on a server that can't be
+// reached or doesn't answer, instead of hanging the build; uploads themselves
+// are not bounded, artifacts can be large.
+var defaultArtifactStoreClient = &http.Client{
+	Transport: plugingetter.NewHTTPTransport(plugingetter.Timeouts{}),
+}
+
+func (s *HTTPArtifactStore) Store(ctx context.Context, buildName string, artifacts []packersdk.Artifact) error {
+	doc, files := storedBuild(buildName, s.Labels, artifacts)
+	for _, f := range files {
+		if err := s.putFile(ctx, buildName, f); err != nil {
+			return err
+		}
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.put(ctx, s.url(buildName, "versions/"+doc.Version+".json"), bytes.NewReader(b), int64(len(b))); err != nil {
+		return err
+	}
+	return s.put(ctx, s.url(buildName, "artifacts.json"), bytes.NewReader(b), int64(len(b)))
+}
+
+func (s *HTTPArtifactStore) url(buildName, name string) string {
+	return strings.TrimSuffix(s.URL, "/") + "/" + storeDirName(buildName) + "/" + name
+}
+
+func (s *HTTPArtifactStore) putFile(ctx context.Context, buildName string, f artifactFile) error {
+	in, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, s.url(buildName, f.name), in, fi.Size())
+}
+
+func (s *HTTPArtifactStore) put(ctx context.Context, u string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, "PUT", u, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultArtifactStoreClient
+	}
+	log.Printf("[DEBUG] artifact store: uploading %q", u)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("PUT %s: unexpected status %s", u, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package packer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testStoredArtifacts(t *testing.T, dir string) []packersdk.Artifact {
+	var files []string
+	for _, name := range []string{"a/disk.qcow2", "b/disk.qcow2"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	return []packersdk.Artifact{
+		&packersdk.MockArtifact{BuilderIdValue: "qemu", IdValue: "1", FilesValue: files[:1]},
+		nil,
+		&packersdk.MockArtifact{BuilderIdValue: "compress", IdValue: "2", FilesValue: files[1:]},
+	}
+}
+
+func TestLocalArtifactStore_Store(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-artifact-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &LocalArtifactStore{Dir: filepath.Join(dir, "store")}
+	if err := store.Store(context.Background(), "qemu.ubuntu", testStoredArtifacts(t, dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	buildDir := filepath.Join(dir, "store", "qemu.ubuntu")
+	for name, want := range map[string]string{"disk.qcow2": "a/disk.qcow2", "2-disk.qcow2": "b/disk.qcow2"} {
+		if b, err := ioutil.ReadFile(filepath.Join(buildDir, name)); err != nil || string(b) != want {
+			t.Errorf("unexpected content of %s: %q, %v", name, b, err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(buildDir, "artifacts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := StoredBuild{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.BuildName != "qemu.ubuntu" || len(doc.Artifacts) != 2 {
+		t.Fatalf("unexpected artifacts.json: %s", b)
+	}
+	if got := doc.Artifacts[1]; got.BuilderId != "compress" || !reflect.DeepEqual(got.Files, []string{"2-disk.qcow2"}) {
+		t.Fatalf("unexpected stored artifact: %#v", got)
+	}
+}
+
+func TestHTTPArtifactStore_Store(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-artifact-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var l sync.Mutex
+	uploads := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		l.Lock()
+		uploads[r.URL.Path] = string(b)
+		l.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	store := &HTTPArtifactStore{
+		URL:     srv.URL + "/packer/",
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}
+	if err := store.Store(context.Background(), "qemu.ubuntu", testStoredArtifacts(t, dir)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/packer/qemu.ubuntu/disk.qcow2", "/packer/qemu.ubuntu/2-disk.qcow2", "/packer/qemu.ubuntu/artifacts.json"} {
+		if _, ok := uploads[path]; !ok {
+			t.Errorf("%s was not uploaded, got %v", path, uploads)
+		}
+	}
+	doc := StoredBuild{}
+	if err := json.Unmarshal([]byte(uploads["/packer/qemu.ubuntu/artifacts.json"]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := uploads["/packer/qemu.ubuntu/versions/"+doc.Version+".json"]; !ok {
+		t.Errorf("version %s was not uploaded, got %v", doc.Version, uploads)
+	}
+
+	store.Headers = nil
+	if err := store.Store(context.Background(), "qemu.ubuntu", testStoredArtifacts(t, dir)); err == nil {
+		t.Fatal("expected an error when the server refuses the upload")
+	}
+}
@@ -0,0 +1,83 @@
+package packer
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const (
+	// ArtifactsDirState is the state of the artifacts of a build holding the
+	// path of its artifacts directory.
+	ArtifactsDirState = "artifacts_dir"
+
+	// ArtifactsDirFilesState is the state of the artifacts of a build listing
+	// the files of its artifacts directory, relative to it.
+	ArtifactsDirFilesState = "artifacts_dir_files"
+
+	// ArtifactsDirBuildVariable is the build variable the artifacts directory
+	// of a build is passed to its provisioners and post-processors with.
+	ArtifactsDirBuildVariable = "PackerArtifactsDir"
+)
+
+// ArtifactsDirFiles lists the files of the artifacts directory dir, relative
+// to it and with forward slashes, in lexical order.
+func ArtifactsDirFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// artifactsDirArtifact passes the artifacts directory of a build to the
+// post-processors, as its "artifacts_dir" state and as the PackerArtifactsDir
+// variable of its generated data, and lists the files kept there as its
+// "artifacts_dir_files" state.
+type artifactsDirArtifact struct {
+	packersdk.Artifact
+	dir string
+}
+
+func (a *artifactsDirArtifact) State(name string) interface{} {
+	switch name {
+	case ArtifactsDirState:
+		return a.dir
+	case ArtifactsDirFilesState:
+		files, err := ArtifactsDirFiles(a.dir)
+		if err != nil {
+			log.Printf("Failed to list the files of the artifacts directory %s: %s", a.dir, err)
+		}
+		return files
+	case "generated_data":
+		// generated data is a map[interface{}]interface{} once it went
+		// through RPC, which is what HCL2 post-processors expect.
+		data := map[interface{}]interface{}{}
+		switch generated := a.Artifact.State(name).(type) {
+		case map[interface{}]interface{}:
+			for k, v := range generated {
+				data[k] = v
+			}
+		case map[string]interface{}:
+			for k, v := range generated {
+				data[k] = v
+			}
+		}
+		data[ArtifactsDirBuildVariable] = a.dir
+		return data
+	}
+	return a.Artifact.State(name)
+}
@@ -4,14 +4,39 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"github.com/hashicorp/packer/version"
 )
 
+// WorkDirConfigKey is the builder variable the scratch directory of a build is
+// passed to its builder with.
+const WorkDirConfigKey = "packer_work_dir"
+
+// NewWorkDir returns the path of a new scratch directory for a build, in
+// PACKER_TMP_DIR like the rest of Packer's temporary files. The directory is
+// only created when the build runs, so that preparing a build, like packer
+// validate does, leaves nothing behind.
+func NewWorkDir() string {
+	dir := os.Getenv("PACKER_TMP_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		id = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return filepath.Join(dir, "packer-build-"+id)
+}
+
 // A CoreBuild struct represents a single build job, the result of which should
 // be a single machine image artifact. This artifact may be comprised of
 // multiple files, of course, but it should be for only a single provider (such
@@ -29,6 +54,56 @@ type CoreBuild struct {
 	TemplatePath       string
 	Variables          map[string]string
 
+	// Retries is the number of times the build is run again when it fails
+	// with an error of one of the RetryOn classes, waiting RetryBackoff
+	// before the first retry and twice as long before each next one. Every
+	// attempt runs the builder with the configuration it was prepared with,
+	// so resource names set in the template are the same for every attempt.
+	Retries      int
+	RetryBackoff time.Duration
+	// RetryOn is the list of RetryableErrorClasses the build is retried on,
+	// all of them when empty.
+	RetryOn []string
+
+	// ConcurrencyGroup is the name of the group of builds this build is
+	// part of. Builds of the same group are never run at the same time.
+	ConcurrencyGroup string
+
+	// Resources, when set, are the host resources requested by the build
+	// of a local hypervisor builder. `packer build` defers the build while
+	// they would oversubscribe its resource budget.
+	Resources *BuildResources
+
+	// ArtifactStore, when set, is where the artifacts of the build are
+	// pushed once its post-processors ran.
+	ArtifactStore ArtifactStore
+
+	// Report, when set, records the steps of the build for the summary
+	// report of `packer build -report`.
+	Report *BuildReport
+
+	// WorkDir is the scratch directory of the build, so that builds running
+	// in parallel don't write into each other's files. It is passed to the
+	// builder as the packer_work_dir builder variable and to the provisioners
+	// as the PackerWorkDir build variable. It is set by Prepare when empty,
+	// and created anew for each attempt of the build.
+	WorkDir string
+
+	// ArtifactsDir, when set, is the directory where the provisioners and
+	// post-processors of the build drop the files to keep, like logs and
+	// reports. Unlike WorkDir, it is kept once the build is done, even when
+	// the machine built is destroyed. It is passed to the provisioners and
+	// post-processors as the PackerArtifactsDir build variable, and the
+	// files found there are listed in the artifacts_dir_files state of the
+	// artifacts of the build.
+	ArtifactsDir string
+
+	// Skipped describes the provisioners and post-processors skipped with
+	// `packer build -skip-provisioner` or `-exclude-post-processors`, like
+	// "provisioner shell". They are announced when the build starts and
+	// recorded by the manifest post-processor.
+	Skipped []string
+
 	// Indicates whether the build is already initialized before calling Prepare(..)
 	Prepared bool
 
@@ -58,6 +133,33 @@ type CoreBuildProvisioner struct {
 	config      []interface{}
 }
 
+// Skip records that the component of kind, "provisioner" or
+// "post-processor", of type pType named pName, was skipped.
+func (b *CoreBuild) Skip(kind, pType, pName string) {
+	b.Skipped = append(b.Skipped, skippedComponent(kind, pType, pName))
+}
+
+func skippedComponent(kind, pType, pName string) string {
+	if pName == "" || pName == pType {
+		return kind + " " + pType
+	}
+	return fmt.Sprintf("%s %s (type %s)", kind, pName, pType)
+}
+
+// skippedArtifact passes the skipped components of a build to the
+// post-processors, as its "skipped_components" state.
+type skippedArtifact struct {
+	packersdk.Artifact
+	skipped []string
+}
+
+func (a *skippedArtifact) State(name string) interface{} {
+	if name == "skipped_components" {
+		return a.skipped
+	}
+	return a.Artifact.State(name)
+}
+
 // Returns the name of the build.
 func (b *CoreBuild) Name() string {
 	if b.BuildName != "" {
@@ -70,6 +172,10 @@ func (b *CoreBuild) Name() string {
 // and any hooks. This _must_ be called prior to Run. The parameter is the
 // overrides for the variables within the template (if any).
 func (b *CoreBuild) Prepare() (warn []string, err error) {
+	if b.WorkDir == "" {
+		b.WorkDir = NewWorkDir()
+	}
+
 	// For HCL2 templates, the builder and hooks are initialized when the
 	// template is parsed. Calling Prepare(...) is not necessary
 	if b.Prepared {
@@ -97,6 +203,7 @@ func (b *CoreBuild) Prepare() (warn []string, err error) {
 		common.OnErrorConfigKey:       b.onError,
 		common.TemplatePathKey:        b.TemplatePath,
 		common.UserVariablesConfigKey: b.Variables,
+		WorkDirConfigKey:              b.WorkDir,
 	}
 
 	// Prepare the builder
@@ -161,6 +268,84 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 		panic("Prepare must be called first")
 	}
 
+	ui := &TargetedUI{
+		Target: b.Name(),
+		Ui:     originalUi,
+	}
+	if b.WorkDir == "" {
+		b.WorkDir = NewWorkDir()
+	}
+	// the files of failed attempts are kept too, they usually tell why the
+	// attempt failed.
+	if b.ArtifactsDir != "" {
+		if err := os.MkdirAll(b.ArtifactsDir, 0755); err != nil {
+			return nil, fmt.Errorf("Failed to create the artifacts directory of the build: %s", err)
+		}
+		log.Printf("Artifacts directory of build %q: %s", b.Name(), b.ArtifactsDir)
+	}
+
+	var attemptErrs []error
+	for attempt := 1; ; attempt++ {
+		artifacts, err := b.runAttempt(ctx, originalUi, attempt)
+		if err == nil {
+			return artifacts, nil
+		}
+		attemptErrs = append(attemptErrs, err)
+
+		if !b.willRetry(ctx, attempt, artifacts, err) {
+			if len(attemptErrs) == 1 {
+				return artifacts, err
+			}
+			return artifacts, &BuildAttemptsError{Errors: attemptErrs}
+		}
+
+		class, _ := retryableErrorClass(err, b.RetryOn)
+		backoff := retryBackoff(b.RetryBackoff, attempt)
+		ui.Error(fmt.Sprintf("Build failed with a %s error, retrying in %s (attempt %d/%d): %s",
+			class, backoff, attempt+1, b.Retries+1, err))
+		select {
+		case <-ctx.Done():
+			return nil, &BuildAttemptsError{Errors: append(attemptErrs, ctx.Err())}
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// willRetry tells whether the build is run again after the attempt failed
+// with err and produced artifacts.
+func (b *CoreBuild) willRetry(ctx context.Context, attempt int, artifacts []packersdk.Artifact, err error) bool {
+	// a build that produced artifacts is not retried, post-processors
+	// may already have published them.
+	_, retryable := retryableErrorClass(err, b.RetryOn)
+	return attempt <= b.Retries && retryable && len(artifacts) == 0 && ctx.Err() == nil
+}
+
+// runAttempt runs the build once, in a new b.WorkDir. The directory is
+// removed when the build succeeds or will be retried, and kept for inspection
+// when the build fails for good.
+func (b *CoreBuild) runAttempt(ctx context.Context, originalUi packersdk.Ui, attempt int) ([]packersdk.Artifact, error) {
+	// a failed attempt may have left files behind, each attempt starts from
+	// an empty directory.
+	if err := os.RemoveAll(b.WorkDir); err != nil {
+		return nil, fmt.Errorf("Failed to clean the working directory of the build: %s", err)
+	}
+	if err := os.MkdirAll(b.WorkDir, 0700); err != nil {
+		return nil, fmt.Errorf("Failed to create the working directory of the build: %s", err)
+	}
+	log.Printf("Working directory of build %q: %s", b.Name(), b.WorkDir)
+
+	artifacts, err := b.run(ctx, originalUi, b.WorkDir)
+	if err != nil && !b.willRetry(ctx, attempt, artifacts, err) {
+		log.Printf("Keeping working directory of failed build %q: %s", b.Name(), b.WorkDir)
+		return artifacts, err
+	}
+	if rmErr := os.RemoveAll(b.WorkDir); rmErr != nil {
+		log.Printf("Failed to remove working directory %s: %s", b.WorkDir, rmErr)
+	}
+	return artifacts, err
+}
+
+func (b *CoreBuild) run(ctx context.Context, originalUi packersdk.Ui, workDir string) ([]packersdk.Artifact, error) {
 	// Copy the hooks
 	hooks := make(map[string][]packersdk.Hook)
 	for hookName, hookList := range b.hooks {
@@ -197,6 +382,9 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 
 		hooks[packersdk.HookProvision] = append(hooks[packersdk.HookProvision], &ProvisionHook{
 			Provisioners: hookedProvisioners,
+			WorkDir:      workDir,
+			ArtifactsDir: b.ArtifactsDir,
+			Report:       b.Report,
 		})
 	}
 
@@ -208,6 +396,9 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 		}
 		hooks[packersdk.HookCleanupProvision] = []packersdk.Hook{&ProvisionHook{
 			Provisioners: []*HookedProvisioner{hookedCleanupProvisioner},
+			WorkDir:      workDir,
+			ArtifactsDir: b.ArtifactsDir,
+			Report:       b.Report,
 		}}
 	}
 
@@ -220,9 +411,15 @@ func (b *CoreBuild) Run(ctx context.Context, originalUi packersdk.Ui) ([]packers
 		Ui:     originalUi,
 	}
 
+	for _, skipped := range b.Skipped {
+		builderUi.Say(fmt.Sprintf("Skipping %s, as requested", skipped))
+	}
+
 	log.Printf("Running builder: %s", b.BuilderType)
 	ts := CheckpointReporter.AddSpan(b.BuilderType, "builder", b.BuilderConfig)
+	endStep := b.Report.startStep("builder", b.BuilderType)
 	builderArtifact, err := b.Builder.Run(ctx, builderUi, hook)
+	endStep(err)
 	ts.End(err)
 	if err != nil {
 		return nil, err
@@ -260,7 +457,16 @@ PostProcessorRunSeqLoop:
 				builderUi.Say(fmt.Sprintf("Running post-processor: %s (type %s)", corePP.PName, corePP.PType))
 			}
 			ts := CheckpointReporter.AddSpan(corePP.PType, "post-processor", corePP.config)
-			artifact, defaultKeep, forceOverride, err := corePP.PostProcessor.PostProcess(ctx, ppUi, priorArtifact)
+			endStep := b.Report.startStep("post-processor", corePP.PType)
+			input := priorArtifact
+			if len(b.Skipped) > 0 {
+				input = &skippedArtifact{Artifact: input, skipped: b.Skipped}
+			}
+			if b.ArtifactsDir != "" {
+				input = &artifactsDirArtifact{Artifact: input, dir: b.ArtifactsDir}
+			}
+			artifact, defaultKeep, forceOverride, err := corePP.PostProcessor.PostProcess(ctx, ppUi, input)
+			endStep(err)
 			ts.End(err)
 			if err != nil {
 				errors = append(errors, fmt.Errorf("Post-processor failed: %s", err))
@@ -335,6 +541,24 @@ PostProcessorRunSeqLoop:
 		}
 	}
 
+	if b.ArtifactsDir != "" {
+		for i, artifact := range artifacts {
+			artifacts[i] = &artifactsDirArtifact{Artifact: artifact, dir: b.ArtifactsDir}
+		}
+		files, err := ArtifactsDirFiles(b.ArtifactsDir)
+		if err != nil {
+			log.Printf("Failed to list the files of the artifacts directory %s: %s", b.ArtifactsDir, err)
+		}
+		builderUi.Say(fmt.Sprintf("Kept %d file(s) in the artifacts directory %s", len(files), b.ArtifactsDir))
+	}
+
+	if b.ArtifactStore != nil && len(artifacts) > 0 {
+		builderUi.Say("Storing artifacts")
+		if err := b.ArtifactStore.Store(ctx, b.Name(), artifacts); err != nil {
+			errors = append(errors, fmt.Errorf("Failed to store artifacts: %s", err))
+		}
+	}
+
 	if len(errors) > 0 {
 		err = &packersdk.MultiError{Errors: errors}
 	}
@@ -0,0 +1,59 @@
+package packer
+
+import (
+	"sync"
+	"time"
+)
+
+// BuildReport records the steps of a build, with their duration and error,
+// so that a summary of the run can be written for CI systems. A nil
+// BuildReport records nothing.
+type BuildReport struct {
+	l     sync.Mutex
+	steps []ReportStep
+}
+
+// ReportStep is a step of a build: its builder, a provisioner or a
+// post-processor. The builder step lasts until the builder returns, so it
+// includes the provisioners.
+type ReportStep struct {
+	// Type is one of "builder", "provisioner" or "post-processor".
+	Type string
+	// Name is the type of the component, like shell or amazon-ebs.
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	// Error is the error returned by the step, empty when it succeeded.
+	Error string
+}
+
+// Steps returns the steps recorded so far, in the order they started.
+func (r *BuildReport) Steps() []ReportStep {
+	if r == nil {
+		return nil
+	}
+	r.l.Lock()
+	defer r.l.Unlock()
+	return append([]ReportStep{}, r.steps...)
+}
+
+// startStep records the start of a step. The returned function must be
+// called with the error of the step when it ends.
+func (r *BuildReport) startStep(stepType, name string) func(error) {
+	if r == nil {
+		return func(error) {}
+	}
+	r.l.Lock()
+	i := len(r.steps)
+	r.steps = append(r.steps, ReportStep{Type: stepType, Name: name, Start: time.Now()})
+	r.l.Unlock()
+
+	return func(err error) {
+		r.l.Lock()
+		defer r.l.Unlock()
+		r.steps[i].Duration = time.Since(r.steps[i].Start)
+		if err != nil {
+			r.steps[i].Error = err.Error()
+		}
+	}
+}
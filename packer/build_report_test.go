@@ -0,0 +1,57 @@
+package packer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestBuild_Run_report(t *testing.T) {
+	build := testBuild()
+	build.Report = &BuildReport{}
+	build.Prepare()
+
+	ctx := context.Background()
+	if _, err := build.Run(ctx, testUi()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	// provisioners are run by the builder through the provision hook
+	builder := build.Builder.(*packersdk.MockBuilder)
+	if err := builder.RunHook.Run(ctx, packersdk.HookProvision, nil, new(packersdk.MockCommunicator), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var got []string
+	for _, step := range build.Report.Steps() {
+		got = append(got, step.Type+" "+step.Name)
+		if step.Error != "" {
+			t.Errorf("unexpected error for %s %s: %s", step.Type, step.Name, step.Error)
+		}
+	}
+	want := []string{"builder foo", "post-processor testPP", "provisioner mock-provisioner"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected steps %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected steps %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBuildReport_startStep(t *testing.T) {
+	r := &BuildReport{}
+	r.startStep("provisioner", "shell")(errors.New("exit status 1"))
+	steps := r.Steps()
+	if len(steps) != 1 || steps[0].Error != "exit status 1" || steps[0].Start.IsZero() {
+		t.Fatalf("unexpected steps: %#v", steps)
+	}
+
+	var nilReport *BuildReport
+	nilReport.startStep("builder", "null")(nil)
+	if steps := nilReport.Steps(); steps != nil {
+		t.Fatalf("a nil report should not record steps: %#v", steps)
+	}
+}
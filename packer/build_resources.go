@@ -0,0 +1,159 @@
+package packer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// BuildResources are the host resources a build of a local hypervisor
+// builder, like qemu, requests for its virtual machine.
+type BuildResources struct {
+	CPUs     int64
+	MemoryMB int64
+}
+
+func (r BuildResources) String() string {
+	return fmt.Sprintf("%d vCPUs and %d MB of memory", r.CPUs, r.MemoryMB)
+}
+
+// localBuilderResources are the resources requested by the builders running
+// virtual machines on the host when their cpus and memory options are not
+// set, by builder type.
+var localBuilderResources = map[string]BuildResources{
+	"qemu":           {CPUs: 1, MemoryMB: 512},
+	"virtualbox-iso": {CPUs: 1, MemoryMB: 512},
+	"virtualbox-ovf": {CPUs: 1, MemoryMB: 512},
+	"virtualbox-vm":  {CPUs: 1, MemoryMB: 512},
+	"hyperv-iso":     {CPUs: 1, MemoryMB: 1024},
+	"hyperv-vmcx":    {CPUs: 1, MemoryMB: 1024},
+}
+
+// NewBuildResources returns the resources requested by a build of builder
+// builderType configured with config, read from its cpus and memory options.
+// nil is returned for builders that don't run virtual machines on the host.
+func NewBuildResources(builderType string, config map[string]interface{}) *BuildResources {
+	defaults, found := localBuilderResources[builderType]
+	if !found {
+		return nil
+	}
+	r := defaults
+	if cpus, ok := resourceQuantity(config["cpus"]); ok {
+		r.CPUs = cpus
+	}
+	if memory, ok := resourceQuantity(config["memory"]); ok {
+		r.MemoryMB = memory
+	}
+	return &r
+}
+
+// resourceQuantity returns v as a positive number, v being a decoded json or
+// HCL2 value.
+func resourceQuantity(v interface{}) (int64, bool) {
+	var n int64
+	switch v := v.(type) {
+	case int:
+		n = int64(v)
+	case int64:
+		n = v
+	case float64:
+		n = int64(v)
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		n = i
+	default:
+		return 0, false
+	}
+	return n, n > 0
+}
+
+// ResourceBudget limits the resources used by the builds running at the same
+// time, so that parallel builds of local hypervisor builders don't
+// oversubscribe the host.
+type ResourceBudget struct {
+	MaxCPUs, MaxMemoryMB int64
+
+	cpus, memory *semaphore.Weighted
+}
+
+// NewResourceBudget returns a budget of maxCPUs vCPUs and maxMemoryMB MB of
+// memory. A maximum of 0 means no limit.
+func NewResourceBudget(maxCPUs, maxMemoryMB int64) *ResourceBudget {
+	b := &ResourceBudget{MaxCPUs: maxCPUs, MaxMemoryMB: maxMemoryMB}
+	if maxCPUs > 0 {
+		b.cpus = semaphore.NewWeighted(maxCPUs)
+	}
+	if maxMemoryMB > 0 {
+		b.memory = semaphore.NewWeighted(maxMemoryMB)
+	}
+	return b
+}
+
+// clamp returns the part of r accounted against the budget: a build
+// requesting more than the budget is run alone rather than never.
+func (b *ResourceBudget) clamp(r BuildResources) BuildResources {
+	if b.cpus == nil || r.CPUs < 0 {
+		r.CPUs = 0
+	} else if r.CPUs > b.MaxCPUs {
+		r.CPUs = b.MaxCPUs
+	}
+	if b.memory == nil || r.MemoryMB < 0 {
+		r.MemoryMB = 0
+	} else if r.MemoryMB > b.MaxMemoryMB {
+		r.MemoryMB = b.MaxMemoryMB
+	}
+	return r
+}
+
+// TryAcquire reserves r without blocking, and tells whether it succeeded.
+func (b *ResourceBudget) TryAcquire(r BuildResources) bool {
+	r = b.clamp(r)
+	if r.CPUs > 0 && !b.cpus.TryAcquire(r.CPUs) {
+		return false
+	}
+	if r.MemoryMB > 0 && !b.memory.TryAcquire(r.MemoryMB) {
+		if r.CPUs > 0 {
+			b.cpus.Release(r.CPUs)
+		}
+		return false
+	}
+	return true
+}
+
+// Acquire reserves r, blocking until the resources are available or ctx is
+// done.
+func (b *ResourceBudget) Acquire(ctx context.Context, r BuildResources) error {
+	r = b.clamp(r)
+	// vCPUs are always acquired before memory, so that two builds waiting
+	// for each other's resources can't deadlock.
+	if r.CPUs > 0 {
+		if err := b.cpus.Acquire(ctx, r.CPUs); err != nil {
+			return err
+		}
+	}
+	if r.MemoryMB > 0 {
+		if err := b.memory.Acquire(ctx, r.MemoryMB); err != nil {
+			if r.CPUs > 0 {
+				b.cpus.Release(r.CPUs)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Release gives back r, reserved with Acquire or TryAcquire.
+func (b *ResourceBudget) Release(r BuildResources) {
+	r = b.clamp(r)
+	if r.CPUs > 0 {
+		b.cpus.Release(r.CPUs)
+	}
+	if r.MemoryMB > 0 {
+		b.memory.Release(r.MemoryMB)
+	}
+}
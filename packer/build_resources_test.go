@@ -0,0 +1,81 @@
+package packer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewBuildResources(t *testing.T) {
+	tests := []struct {
+		name        string
+		builderType string
+		config      map[string]interface{}
+		want        *BuildResources
+	}{
+		{"not a local builder", "amazon-ebs", map[string]interface{}{"cpus": 4}, nil},
+		{"defaults", "qemu", map[string]interface{}{}, &BuildResources{CPUs: 1, MemoryMB: 512}},
+		{"hcl2", "qemu", map[string]interface{}{"cpus": 4, "memory": 4096}, &BuildResources{CPUs: 4, MemoryMB: 4096}},
+		{"json", "virtualbox-iso", map[string]interface{}{"cpus": float64(2), "memory": "2048"}, &BuildResources{CPUs: 2, MemoryMB: 2048}},
+		{"invalid", "hyperv-iso", map[string]interface{}{"cpus": "{{user `cpus`}}", "memory": 0}, &BuildResources{CPUs: 1, MemoryMB: 1024}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewBuildResources(tt.builderType, tt.config)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("NewBuildResources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceBudget(t *testing.T) {
+	budget := NewResourceBudget(4, 4096)
+	big := BuildResources{CPUs: 3, MemoryMB: 1024}
+	if !budget.TryAcquire(big) {
+		t.Fatal("the budget should fit the first build")
+	}
+	if budget.TryAcquire(BuildResources{CPUs: 2, MemoryMB: 512}) {
+		t.Fatal("the budget should not fit 5 vCPUs")
+	}
+	small := BuildResources{CPUs: 1, MemoryMB: 3072}
+	if !budget.TryAcquire(small) {
+		t.Fatal("the budget should fit the second build")
+	}
+
+	acquired := make(chan error)
+	huge := BuildResources{CPUs: 16, MemoryMB: 65536}
+	go func() { acquired <- budget.Acquire(context.Background(), huge) }()
+	select {
+	case <-acquired:
+		t.Fatal("a build should wait for the resources of the running builds")
+	case <-time.After(50 * time.Millisecond):
+	}
+	budget.Release(big)
+	budget.Release(small)
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a build bigger than the budget should run alone")
+	}
+	budget.Release(huge)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if !budget.TryAcquire(BuildResources{CPUs: 4, MemoryMB: 4096}) {
+		t.Fatal("the whole budget should be available")
+	}
+	if err := budget.Acquire(ctx, BuildResources{CPUs: 1}); err == nil {
+		t.Fatal("expected an error from a cancelled context")
+	}
+
+	unlimited := NewResourceBudget(0, 0)
+	for i := 0; i < 10; i++ {
+		if !unlimited.TryAcquire(huge) {
+			t.Fatal("a budget of 0 should not limit builds")
+		}
+	}
+}
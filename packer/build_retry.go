@@ -0,0 +1,84 @@
+package packer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRetryBackoff is the time waited before the first retry of a build
+// when its retry_backoff is not set. It doubles after each attempt.
+const DefaultRetryBackoff = 30 * time.Second
+
+// maxRetryBackoff caps the time waited between two attempts of a build.
+const maxRetryBackoff = 10 * time.Minute
+
+// RetryableErrorClasses maps the failure classes a build can be retried on to
+// the error messages they match. Errors coming from plugins are only strings
+// once they went through RPC, so they are matched against the messages cloud
+// providers return.
+var RetryableErrorClasses = map[string]*regexp.Regexp{
+	"capacity": regexp.MustCompile(`(?i)InsufficientInstanceCapacity|InsufficientCapacity|` +
+		`capacity-not-available|SpotMaxPriceTooLow|MaxSpotInstanceCountExceeded|` +
+		`ZONE_RESOURCE_POOL_EXHAUSTED|SkuNotAvailable|AllocationFailed`),
+	"throttling": regexp.MustCompile(`(?i)Throttl|RequestLimitExceeded|rate ?limit|` +
+		`rate exceeded|TooManyRequests|Too Many Requests`),
+}
+
+// RetryableErrorClassNames returns the sorted names of the
+// RetryableErrorClasses.
+func RetryableErrorClassNames() []string {
+	names := make([]string, 0, len(RetryableErrorClasses))
+	for name := range RetryableErrorClasses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// retryableErrorClass returns the class of err when it is one of classes, or
+// one of all the RetryableErrorClasses when classes is empty.
+func retryableErrorClass(err error, classes []string) (string, bool) {
+	if len(classes) == 0 {
+		classes = RetryableErrorClassNames()
+	}
+	for _, class := range classes {
+		re, found := RetryableErrorClasses[class]
+		if found && re.MatchString(err.Error()) {
+			return class, true
+		}
+	}
+	return "", false
+}
+
+// retryBackoff returns how long to wait before the retry following attempt,
+// attempts being numbered from 1.
+func retryBackoff(initial time.Duration, attempt int) time.Duration {
+	if initial <= 0 {
+		initial = DefaultRetryBackoff
+	}
+	backoff := initial
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+// BuildAttemptsError is returned by a build that failed after being retried.
+// It reports the error of every attempt.
+type BuildAttemptsError struct {
+	Errors []error
+}
+
+func (e *BuildAttemptsError) Error() string {
+	lines := []string{fmt.Sprintf("build failed after %d attempts:", len(e.Errors))}
+	for i, err := range e.Errors {
+		lines = append(lines, fmt.Sprintf("* attempt %d: %s", i+1, err))
+	}
+	return strings.Join(lines, "\n")
+}
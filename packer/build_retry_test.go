@@ -0,0 +1,165 @@
+package packer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// flakyBuilder fails with the errors in Errs before succeeding.
+type flakyBuilder struct {
+	packersdk.MockBuilder
+	Errs  []error
+	Calls int
+}
+
+func (b *flakyBuilder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	b.Calls++
+	if b.Calls <= len(b.Errs) {
+		return nil, b.Errs[b.Calls-1]
+	}
+	return &packersdk.MockArtifact{IdValue: "b"}, nil
+}
+
+func TestBuild_Run_retries(t *testing.T) {
+	throttled := errors.New("RequestLimitExceeded: Request limit exceeded.")
+	capacity := errors.New("InsufficientInstanceCapacity: no capacity in us-east-1a")
+	broken := errors.New("unknown field: foo")
+
+	tests := []struct {
+		name      string
+		errs      []error
+		retries   int
+		retryOn   []string
+		wantCalls int
+		wantErr   bool
+	}{
+		{"no retries", []error{throttled}, 0, nil, 1, true},
+		{"retried until success", []error{throttled, capacity}, 2, nil, 3, false},
+		{"out of retries", []error{throttled, throttled, throttled}, 2, nil, 3, true},
+		{"not retryable", []error{broken}, 2, nil, 1, true},
+		{"class not selected", []error{capacity}, 2, []string{"throttling"}, 1, true},
+		{"class selected", []error{capacity}, 2, []string{"capacity"}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := &flakyBuilder{Errs: tt.errs}
+			build := &CoreBuild{
+				Type:         "test",
+				Builder:      builder,
+				BuilderType:  "foo",
+				Variables:    make(map[string]string),
+				Retries:      tt.retries,
+				RetryBackoff: time.Millisecond,
+				RetryOn:      tt.retryOn,
+				onError:      "cleanup",
+			}
+			build.Prepare()
+
+			_, err := build.Run(context.Background(), testUi())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if builder.Calls != tt.wantCalls {
+				t.Fatalf("expected %d attempts, got %d", tt.wantCalls, builder.Calls)
+			}
+			if attemptsErr, ok := err.(*BuildAttemptsError); ok && len(attemptsErr.Errors) != tt.wantCalls {
+				t.Fatalf("expected the error of each attempt, got %s", err)
+			}
+		})
+	}
+}
+
+// workDirBuilder writes into its packer_work_dir, and fails with the errors in
+// Errs before succeeding.
+type workDirBuilder struct {
+	flakyBuilder
+	WorkDir string
+	// Leftovers is set when an attempt found the files of a previous one.
+	Leftovers bool
+}
+
+func (b *workDirBuilder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	b.WorkDir = raws[len(raws)-1].(map[string]interface{})[WorkDirConfigKey].(string)
+	return nil, nil, nil
+}
+
+func (b *workDirBuilder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	file := filepath.Join(b.WorkDir, "attempt")
+	if _, err := os.Stat(file); err == nil {
+		b.Leftovers = true
+	}
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		return nil, err
+	}
+	return b.flakyBuilder.Run(ctx, ui, hook)
+}
+
+func TestBuild_Run_workDir(t *testing.T) {
+	throttled := errors.New("RequestLimitExceeded: Request limit exceeded.")
+
+	tests := []struct {
+		name        string
+		errs        []error
+		wantRemoved bool
+	}{
+		{"success", nil, true},
+		{"retried until success", []error{throttled, throttled}, true},
+		{"out of retries", []error{throttled, throttled, throttled}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := &workDirBuilder{flakyBuilder: flakyBuilder{Errs: tt.errs}}
+			build := &CoreBuild{
+				Type:         "test",
+				Builder:      builder,
+				BuilderType:  "foo",
+				Variables:    make(map[string]string),
+				Retries:      2,
+				RetryBackoff: time.Millisecond,
+				onError:      "cleanup",
+			}
+			if _, err := build.Prepare(); err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(build.WorkDir)
+
+			if builder.WorkDir != build.WorkDir || build.WorkDir == "" {
+				t.Fatalf("builder got %q as its working directory, expected %q", builder.WorkDir, build.WorkDir)
+			}
+
+			build.Run(context.Background(), testUi())
+			if builder.Leftovers {
+				t.Errorf("an attempt found the files of a previous attempt")
+			}
+			_, err := os.Stat(build.WorkDir)
+			if removed := os.IsNotExist(err); removed != tt.wantRemoved {
+				t.Errorf("working directory removed: %t, expected %t", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		initial time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{0, 1, DefaultRetryBackoff},
+		{time.Second, 1, time.Second},
+		{time.Second, 3, 4 * time.Second},
+		{time.Minute, 10, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.initial, tt.attempt); got != tt.want {
+			t.Errorf("retryBackoff(%s, %d) = %s, want %s", tt.initial, tt.attempt, got, tt.want)
+		}
+	}
+}
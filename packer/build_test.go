@@ -2,6 +2,9 @@ package packer
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -36,10 +39,13 @@ func testBuild() *CoreBuild {
 			},
 		},
 		Variables: make(map[string]string),
+		WorkDir:   testWorkDir,
 		onError:   "cleanup",
 	}
 }
 
+var testWorkDir = filepath.Join(os.TempDir(), "packer-build-test")
+
 func testDefaultPackerConfig() map[string]interface{} {
 	return map[string]interface{}{
 		common.BuildNameConfigKey:     "test",
@@ -50,6 +56,7 @@ func testDefaultPackerConfig() map[string]interface{} {
 		common.OnErrorConfigKey:       "cleanup",
 		common.TemplatePathKey:        "",
 		common.UserVariablesConfigKey: make(map[string]string),
+		WorkDirConfigKey:              testWorkDir,
 	}
 }
 func TestBuild_Name(t *testing.T) {
@@ -273,6 +280,79 @@ func TestBuild_Run(t *testing.T) {
 	}
 }
 
+func TestBuild_Run_Skipped(t *testing.T) {
+	ui := testUi()
+
+	build := testBuild()
+	build.Skip("provisioner", "shell", "")
+	build.Skip("post-processor", "amazon-import", "upload")
+	build.Prepare()
+	if _, err := build.Run(context.Background(), ui); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"provisioner shell", "post-processor upload (type amazon-import)"}
+	pp := build.PostProcessors[0][0].PostProcessor.(*MockPostProcessor)
+	skipped, _ := pp.PostProcessArtifact.State("skipped_components").([]string)
+	if !reflect.DeepEqual(skipped, expected) {
+		t.Fatalf("post-processor got skipped components %#v, expected %#v", skipped, expected)
+	}
+}
+
+func TestBuild_Run_ArtifactsDir(t *testing.T) {
+	ui := testUi()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "logs", "cloud-init.log"), []byte("done"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	build := testBuild()
+	build.ArtifactsDir = dir
+	build.Prepare()
+	artifacts, err := build.Run(context.Background(), ui)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	pp := build.PostProcessors[0][0].PostProcessor.(*MockPostProcessor)
+	if got := pp.PostProcessArtifact.State(ArtifactsDirState); got != dir {
+		t.Fatalf("post-processor got artifacts directory %#v, expected %q", got, dir)
+	}
+	data, _ := pp.PostProcessArtifact.State("generated_data").(map[interface{}]interface{})
+	if data[ArtifactsDirBuildVariable] != dir {
+		t.Fatalf("post-processor got generated data %#v", data)
+	}
+	expected := []string{"logs/cloud-init.log"}
+	for _, artifact := range artifacts {
+		files, _ := artifact.State(ArtifactsDirFilesState).([]string)
+		if !reflect.DeepEqual(files, expected) {
+			t.Fatalf("artifact %s lists files %#v, expected %#v", artifact.Id(), files, expected)
+		}
+	}
+}
+
+func TestGetBuildsOptions_Skips(t *testing.T) {
+	opts := GetBuildsOptions{
+		SkipProvisioners:      []string{"shell"},
+		ExcludePostProcessors: []string{"upload"},
+	}
+	if !opts.SkipsProvisioner("shell", "") || !opts.SkipsProvisioner("file", "shell") {
+		t.Fatal("provisioners should be skipped by type or name")
+	}
+	if opts.SkipsProvisioner("file", "") {
+		t.Fatal("file provisioner should not be skipped")
+	}
+	if !opts.ExcludesPostProcessor("amazon-import", "upload") {
+		t.Fatal("post-processor should be excluded by name")
+	}
+	if opts.ExcludesPostProcessor("shell", "") {
+		t.Fatal("post-processors should not be excluded by provisioner names")
+	}
+}
+
 func TestBuild_Run_Artifacts(t *testing.T) {
 	ui := testUi()
 
@@ -19,7 +19,10 @@ func (b *cmdBuilder) ConfigSpec() hcldec.ObjectSpec {
 		b.checkExit(r, nil)
 	}()
 
-	return b.builder.ConfigSpec()
+	done := b.client.traceCall("builder", "ConfigSpec")
+	spec := b.builder.ConfigSpec()
+	done(nil)
+	return spec
 }
 
 func (b *cmdBuilder) Prepare(config ...interface{}) ([]string, []string, error) {
@@ -28,7 +31,10 @@ func (b *cmdBuilder) Prepare(config ...interface{}) ([]string, []string, error)
 		b.checkExit(r, nil)
 	}()
 
-	return b.builder.Prepare(config...)
+	done := b.client.traceCall("builder", "Prepare")
+	generatedVars, warnings, err := b.builder.Prepare(config...)
+	done(err)
+	return generatedVars, warnings, err
 }
 
 func (b *cmdBuilder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
@@ -37,7 +43,10 @@ func (b *cmdBuilder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Ho
 		b.checkExit(r, nil)
 	}()
 
-	return b.builder.Run(ctx, ui, hook)
+	done := b.client.traceCall("builder", "Run")
+	artifact, err := b.builder.Run(ctx, ui, hook)
+	done(err)
+	return artifact, err
 }
 
 func (c *cmdBuilder) checkExit(p interface{}, cb func()) {
@@ -19,7 +19,10 @@ func (d *cmdDatasource) ConfigSpec() hcldec.ObjectSpec {
 		d.checkExit(r, nil)
 	}()
 
-	return d.d.ConfigSpec()
+	done := d.client.traceCall("datasource", "ConfigSpec")
+	spec := d.d.ConfigSpec()
+	done(nil)
+	return spec
 }
 
 func (d *cmdDatasource) Configure(configs ...interface{}) error {
@@ -28,7 +31,10 @@ func (d *cmdDatasource) Configure(configs ...interface{}) error {
 		d.checkExit(r, nil)
 	}()
 
-	return d.d.Configure(configs...)
+	done := d.client.traceCall("datasource", "Configure")
+	err := d.d.Configure(configs...)
+	done(err)
+	return err
 }
 
 func (d *cmdDatasource) OutputSpec() hcldec.ObjectSpec {
@@ -37,7 +43,10 @@ func (d *cmdDatasource) OutputSpec() hcldec.ObjectSpec {
 		d.checkExit(r, nil)
 	}()
 
-	return d.d.OutputSpec()
+	done := d.client.traceCall("datasource", "OutputSpec")
+	spec := d.d.OutputSpec()
+	done(nil)
+	return spec
 }
 
 func (d *cmdDatasource) Execute() (cty.Value, error) {
@@ -46,7 +55,23 @@ func (d *cmdDatasource) Execute() (cty.Value, error) {
 		d.checkExit(r, nil)
 	}()
 
-	return d.d.Execute()
+	done := d.client.traceCall("datasource", "Execute")
+	output, err := d.d.Execute()
+	done(err)
+	return output, err
+}
+
+// Warnings returns the warnings of the data source, when it reports any.
+func (d *cmdDatasource) Warnings() []string {
+	warner, ok := d.d.(DatasourceWarner)
+	if !ok {
+		return nil
+	}
+	defer func() {
+		r := recover()
+		d.checkExit(r, nil)
+	}()
+	return warner.Warnings()
 }
 
 func (d *cmdDatasource) checkExit(p interface{}, cb func()) {
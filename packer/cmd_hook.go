@@ -18,7 +18,10 @@ func (c *cmdHook) Run(ctx context.Context, name string, ui packersdk.Ui, comm pa
 		c.checkExit(r, nil)
 	}()
 
-	return c.hook.Run(ctx, name, ui, comm, data)
+	done := c.client.traceCall("hook", "Run")
+	err := c.hook.Run(ctx, name, ui, comm, data)
+	done(err)
+	return err
 }
 
 func (c *cmdHook) checkExit(p interface{}, cb func()) {
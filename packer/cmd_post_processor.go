@@ -19,7 +19,10 @@ func (b *cmdPostProcessor) ConfigSpec() hcldec.ObjectSpec {
 		b.checkExit(r, nil)
 	}()
 
-	return b.p.ConfigSpec()
+	done := b.client.traceCall("post-processor", "ConfigSpec")
+	spec := b.p.ConfigSpec()
+	done(nil)
+	return spec
 }
 
 func (c *cmdPostProcessor) Configure(config ...interface{}) error {
@@ -28,7 +31,10 @@ func (c *cmdPostProcessor) Configure(config ...interface{}) error {
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.Configure(config...)
+	done := c.client.traceCall("post-processor", "Configure")
+	err := c.p.Configure(config...)
+	done(err)
+	return err
 }
 
 func (c *cmdPostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, a packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
@@ -37,7 +43,10 @@ func (c *cmdPostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, a p
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.PostProcess(ctx, ui, a)
+	done := c.client.traceCall("post-processor", "PostProcess")
+	artifact, keep, forceOverride, err := c.p.PostProcess(ctx, ui, a)
+	done(err)
+	return artifact, keep, forceOverride, err
 }
 
 func (c *cmdPostProcessor) checkExit(p interface{}, cb func()) {
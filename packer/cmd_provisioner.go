@@ -19,7 +19,10 @@ func (p *cmdProvisioner) ConfigSpec() hcldec.ObjectSpec {
 		p.checkExit(r, nil)
 	}()
 
-	return p.p.ConfigSpec()
+	done := p.client.traceCall("provisioner", "ConfigSpec")
+	spec := p.p.ConfigSpec()
+	done(nil)
+	return spec
 }
 
 func (c *cmdProvisioner) Prepare(configs ...interface{}) error {
@@ -28,7 +31,10 @@ func (c *cmdProvisioner) Prepare(configs ...interface{}) error {
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.Prepare(configs...)
+	done := c.client.traceCall("provisioner", "Prepare")
+	err := c.p.Prepare(configs...)
+	done(err)
+	return err
 }
 
 func (c *cmdProvisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, generatedData map[string]interface{}) error {
@@ -37,7 +43,10 @@ func (c *cmdProvisioner) Provision(ctx context.Context, ui packersdk.Ui, comm pa
 		c.checkExit(r, nil)
 	}()
 
-	return c.p.Provision(ctx, ui, comm, generatedData)
+	done := c.client.traceCall("provisioner", "Provision")
+	err := c.p.Provision(ctx, ui, comm, generatedData)
+	done(err)
+	return err
 }
 
 func (c *cmdProvisioner) checkExit(p interface{}, cb func()) {
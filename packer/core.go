@@ -33,6 +33,10 @@ type Core struct {
 
 	except []string
 	only   []string
+
+	// buildsOptions are the options of the last GetBuilds call, used to skip
+	// components.
+	buildsOptions GetBuildsOptions
 }
 
 // CoreConfig is the structure for initializing a new Core. Once a CoreConfig
@@ -106,6 +110,23 @@ type DatasourceSet interface {
 	Set(name string, starter func() (packersdk.Datasource, error))
 }
 
+// DatasourceWarner is implemented by data sources reporting non-fatal
+// problems, like a deprecated filter or an ambiguous result. They are shown
+// as warnings pointing at the data block.
+type DatasourceWarner interface {
+	// Warnings returns the warnings found since the data source was
+	// started, by Configure and Execute.
+	Warnings() []string
+}
+
+// SensitiveDatasource is implemented by data sources whose outputs hold
+// secrets, like generated private keys. The string values of these outputs
+// are hidden from the logs and the UI, the way sensitive variables are.
+type SensitiveDatasource interface {
+	// SensitiveOutputs returns the names of the sensitive outputs.
+	SensitiveOutputs() []string
+}
+
 // ComponentFinder is a struct that contains the various function
 // pointers necessary to look up components of Packer such as builders,
 // commands, etc.
@@ -243,6 +264,7 @@ func (c *Core) generateCoreBuildProvisioner(rawP *template.Provisioner, rawName
 // This is used for json templates to launch the build plugins.
 // They will be prepared via b.Prepare() later.
 func (c *Core) GetBuilds(opts GetBuildsOptions) ([]packersdk.Build, hcl.Diagnostics) {
+	c.buildsOptions = opts
 	buildNames := c.BuildNames(opts.Only, opts.Except)
 	builds := []packersdk.Build{}
 	diags := hcl.Diagnostics{}
@@ -316,6 +338,8 @@ func (c *Core) Build(n string) (packersdk.Build, error) {
 	// rawName is the uninterpolated name that we use for various lookups
 	rawName := configBuilder.Name
 
+	var skipped []string
+
 	// Setup the provisioners for this build
 	provisioners := make([]CoreBuildProvisioner, 0, len(c.Template.Provisioners))
 	for _, rawP := range c.Template.Provisioners {
@@ -323,6 +347,11 @@ func (c *Core) Build(n string) (packersdk.Build, error) {
 		if rawP.OnlyExcept.Skip(rawName) {
 			continue
 		}
+		// -skip-provisioner, provisioners of JSON templates have no name
+		if c.buildsOptions.SkipsProvisioner(rawP.Type, "") {
+			skipped = append(skipped, skippedComponent("provisioner", rawP.Type, ""))
+			continue
+		}
 		cbp, err := c.generateCoreBuildProvisioner(rawP, rawName)
 		if err != nil {
 			return nil, err
@@ -360,6 +389,11 @@ func (c *Core) Build(n string) (packersdk.Build, error) {
 			if foundExcept {
 				break
 			}
+			// -exclude-post-processors
+			if c.buildsOptions.ExcludesPostProcessor(rawP.Type, rawP.Name) {
+				skipped = append(skipped, skippedComponent("post-processor", rawP.Type, rawP.Name))
+				continue
+			}
 
 			// Get the post-processor
 			postProcessor, err := c.components.PluginConfig.PostProcessors.Start(rawP.Type)
@@ -404,6 +438,8 @@ func (c *Core) Build(n string) (packersdk.Build, error) {
 		CleanupProvisioner: cleanupProvisioner,
 		TemplatePath:       c.Template.Path,
 		Variables:          c.variables,
+		Skipped:            skipped,
+		Resources:          NewBuildResources(configBuilder.Type, configBuilder.Config),
 	}, nil
 }
 
@@ -712,6 +748,11 @@ func isDoneInterpolating(v string) (bool, error) {
 	return true, nil
 }
 
+// buildTimeRenderFilter is a regex excluding any build function variable or
+// template variable from being interpolated before the build runs.
+// E.g.: {{ .HTTPIP }}  won't interpolate
+const buildTimeRenderFilter = "{{(\\s|)\\.(.*?)(\\s|)}}"
+
 func (c *Core) renderVarsRecursively() (*interpolate.Context, error) {
 	ctx := c.Context()
 	ctx.EnableEnv = true
@@ -772,11 +813,6 @@ func (c *Core) renderVarsRecursively() (*interpolate.Context, error) {
 		sortedMap = append(sortedMap, keyValue{k, repeatMap[k]})
 	}
 
-	// Regex to exclude any build function variable or template variable
-	// from interpolating earlier
-	// E.g.: {{ .HTTPIP }}  won't interpolate now
-	renderFilter := "{{(\\s|)\\.(.*?)(\\s|)}}"
-
 	for i := 0; i < 100; i++ {
 		shouldRetry = false
 		changed = false
@@ -784,7 +820,7 @@ func (c *Core) renderVarsRecursively() (*interpolate.Context, error) {
 		// First, loop over the variables in the template
 		for _, kv := range sortedMap {
 			// Interpolate the default
-			renderedV, err := interpolate.RenderRegex(kv.Value, ctx, renderFilter)
+			renderedV, err := interpolate.RenderRegex(kv.Value, ctx, buildTimeRenderFilter)
 			switch err.(type) {
 			case nil:
 				// We only get here if interpolation has succeeded, so something is
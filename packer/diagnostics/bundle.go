@@ -0,0 +1,282 @@
+// Package diagnostics writes the bundles of information Packer leaves behind
+// when it crashes, so that bug reports can be acted upon.
+package diagnostics
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/version"
+)
+
+// LogPathEnvVar is set by the Packer parent process to the path of the file
+// it writes logs to, so that the wrapped process can add them to a bundle.
+const LogPathEnvVar = "PACKER_DIAGNOSTICS_LOG_PATH"
+
+// LogLines is the number of log lines kept in a bundle.
+const LogLines = 500
+
+// envAllowList is the list of prefixes of environment variables whose value
+// is kept in a bundle. The values of other variables are redacted.
+var envAllowList = []string{"PACKER_", "CHECKPOINT_", "TMPDIR", "TMP", "TEMP", "HOME", "PATH", "SHELL", "TERM"}
+
+// sensitiveEnvRe matches the names of variables whose value is redacted even
+// when they are in the envAllowList.
+var sensitiveEnvRe = regexp.MustCompile(`(?i)token|secret|passw|key|credential|auth`)
+
+// templateExtensions are the extensions of the files fingerprinted when a
+// template path is a directory.
+var templateExtensions = []string{".pkr.hcl", ".pkr.json", ".pkrvars.hcl", ".pkrvars.json"}
+
+// Bundle is the information gathered when Packer crashes.
+type Bundle struct {
+	// Reason is the panic or the error that caused the crash.
+	Reason string
+	// Goroutines is a dump of the goroutines of the crashed process.
+	Goroutines string
+	// Args are the command line arguments Packer was called with. The
+	// values of variables and secrets are redacted before being written.
+	Args []string
+	// Env is the environment Packer ran in, as returned by os.Environ.
+	// Values are sanitized before being written.
+	Env []string
+	// TemplatePaths are the templates, or directories of templates, being
+	// used. Only their checksums are written.
+	TemplatePaths []string
+	// PluginFolders are searched for installed plugins, whose names contain
+	// their version.
+	PluginFolders []string
+	// LogPath is the path of the Packer log, of which the last LogLines are
+	// written.
+	LogPath string
+}
+
+// Write writes the bundle as a zip archive in dir and returns its path.
+func (b *Bundle) Write(dir string) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("packer-diagnostics-%s.zip", time.Now().UTC().Format("20060102T150405Z")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"reason.txt", b.reason()},
+		{"args.txt", strings.Join(SanitizeArgs(b.Args), "\n") + "\n"},
+		{"env.txt", strings.Join(SanitizeEnv(b.Env), "\n") + "\n"},
+		{"templates.txt", b.templates()},
+		{"plugins.txt", b.plugins()},
+		{"packer.log", b.log()},
+		{"goroutines.txt", b.Goroutines},
+	}
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(w, file.content); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
+
+func (b *Bundle) reason() string {
+	return fmt.Sprintf("Packer %s\n%s %s/%s\n%s\n\n%s\n",
+		version.FormattedVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH,
+		time.Now().UTC().Format(time.RFC3339), b.Reason)
+}
+
+// SanitizeEnv returns env with the values of variables that are not in the
+// allow list, or that look like secrets, redacted.
+func SanitizeEnv(env []string) []string {
+	res := make([]string, 0, len(env))
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		name := parts[0]
+		keep := false
+		for _, prefix := range envAllowList {
+			if strings.HasPrefix(name, prefix) {
+				keep = true
+				break
+			}
+		}
+		if !keep || sensitiveEnvRe.MatchString(name) {
+			res = append(res, name+"=<redacted>")
+			continue
+		}
+		res = append(res, kv)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// SanitizeArgs returns args with the values of -var flags redacted, like
+// SanitizeEnv does, as well as the values of the flags and name=value
+// arguments whose name looks like a secret.
+func SanitizeArgs(args []string) []string {
+	res := make([]string, 0, len(args))
+	// redactNext is set when the value of the previous flag is the next
+	// argument; isVar when that flag is -var, whose value is name=value.
+	redactNext, isVar := false, false
+	for _, arg := range args {
+		if redactNext {
+			redactNext = false
+			if isVar {
+				res = append(res, redactAssignment(arg))
+			} else {
+				res = append(res, "<redacted>")
+			}
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		isFlag := name != arg
+		value, hasValue := "", false
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value, hasValue = name[:i], name[i+1:], true
+		}
+		switch {
+		case isFlag && name == "var" && hasValue:
+			res = append(res, strings.TrimSuffix(arg, value)+redactAssignment(value))
+		case isFlag && name == "var":
+			res = append(res, arg)
+			redactNext, isVar = true, true
+		case isFlag && sensitiveEnvRe.MatchString(name) && hasValue:
+			res = append(res, strings.TrimSuffix(arg, value)+"<redacted>")
+		case isFlag && sensitiveEnvRe.MatchString(name):
+			res = append(res, arg)
+			redactNext, isVar = true, false
+		case !isFlag && hasValue && sensitiveEnvRe.MatchString(name):
+			res = append(res, name+"=<redacted>")
+		default:
+			res = append(res, arg)
+		}
+	}
+	return res
+}
+
+// redactAssignment redacts the value of a name=value variable assignment.
+func redactAssignment(kv string) string {
+	if i := strings.Index(kv, "="); i >= 0 {
+		return kv[:i] + "=<redacted>"
+	}
+	return kv
+}
+
+func (b *Bundle) templates() string {
+	var lines []string
+	for _, path := range b.TemplatePaths {
+		files := []string{path}
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			files = nil
+			entries, _ := ioutil.ReadDir(path)
+			for _, entry := range entries {
+				for _, ext := range templateExtensions {
+					if !entry.IsDir() && strings.HasSuffix(entry.Name(), ext) {
+						files = append(files, filepath.Join(path, entry.Name()))
+						break
+					}
+				}
+			}
+		}
+		for _, file := range files {
+			sum, err := fileSHA256(file)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("%s: %s", file, err))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s  %s", sum, file))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (b *Bundle) plugins() string {
+	var lines []string
+	for _, folder := range b.PluginFolders {
+		_ = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if !info.IsDir() && strings.HasPrefix(info.Name(), "packer-") &&
+				!strings.HasSuffix(info.Name(), "SUM") {
+				lines = append(lines, path)
+			}
+			return nil
+		})
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// log returns the last LogLines lines of the log.
+func (b *Bundle) log() string {
+	if b.LogPath == "" {
+		return ""
+	}
+	f, err := os.Open(b.LogPath)
+	if err != nil {
+		return fmt.Sprintf("could not read log: %s\n", err)
+	}
+	defer f.Close()
+
+	lines := make([]string, 0, LogLines)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(lines) == LogLines {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// internalErrorRe matches the errors returned when a plugin crashed or the
+// RPC connection to it was lost. Errors like "unexpected EOF" are left out,
+// as ordinary network failures return them too.
+var internalErrorRe = regexp.MustCompile(`connection is shut down|plugin exited|panic: `)
+
+// IsInternalError tells whether err is caused by a crash rather than by the
+// configuration or the environment of the build.
+func IsInternalError(err error) bool {
+	return err != nil && internalErrorRe.MatchString(err.Error())
+}
+
+// Goroutines returns a dump of all the goroutines of the current process.
+func Goroutines() string {
+	buf := make([]byte, 1024*1024)
+	n := runtime.Stack(buf, true)
+	return string(buf[:n])
+}
@@ -0,0 +1,171 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSanitizeEnv(t *testing.T) {
+	got := SanitizeEnv([]string{
+		"PACKER_LOG=1",
+		"PACKER_GITHUB_API_TOKEN=ghp_123",
+		"AWS_SECRET_ACCESS_KEY=abc",
+		"HOME=/home/packer",
+		"EMPTY",
+	})
+	want := []string{
+		"AWS_SECRET_ACCESS_KEY=<redacted>",
+		"EMPTY=<redacted>",
+		"HOME=/home/packer",
+		"PACKER_GITHUB_API_TOKEN=<redacted>",
+		"PACKER_LOG=1",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected env: %s", diff)
+	}
+}
+
+func TestSanitizeArgs(t *testing.T) {
+	got := SanitizeArgs([]string{
+		"build",
+		"-var", "password=hunter2",
+		"-var=region=us-east-1",
+		"--var", "token=abc",
+		"-var-file=secrets.pkrvars.hcl",
+		"-vault-token", "s.abc",
+		"-api-key=xyz",
+		"aws_secret=def",
+		"-force",
+		"template.pkr.hcl",
+	})
+	want := []string{
+		"build",
+		"-var", "password=<redacted>",
+		"-var=region=<redacted>",
+		"--var", "token=<redacted>",
+		"-var-file=secrets.pkrvars.hcl",
+		"-vault-token", "<redacted>",
+		"-api-key=<redacted>",
+		"aws_secret=<redacted>",
+		"-force",
+		"template.pkr.hcl",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected args: %s", diff)
+	}
+}
+
+func TestIsInternalError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("unexpected EOF"), false},
+		{errors.New("connection is shut down"), true},
+		{errors.New("plugin exited before we could connect"), true},
+		{errors.New("Error launching source instance: InvalidAMIID.NotFound"), false},
+	}
+	for _, tt := range tests {
+		if got := IsInternalError(tt.err); got != tt.want {
+			t.Errorf("IsInternalError(%v) = %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBundle_Write(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-diagnostics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	templateDir := filepath.Join(dir, "template")
+	pluginDir := filepath.Join(dir, "plugins", "github.com", "hashicorp", "amazon")
+	for _, d := range []string{templateDir, pluginDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(templateDir, "build.pkr.hcl"):                                        "hello world",
+		filepath.Join(templateDir, "README.md"):                                            "not a template",
+		filepath.Join(pluginDir, "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64"):           "",
+		filepath.Join(pluginDir, "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64_SHA256SUM"): "",
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logPath := filepath.Join(dir, "packer.log")
+	var log strings.Builder
+	for i := 1; i <= LogLines+10; i++ {
+		fmt.Fprintf(&log, "line %d\n", i)
+	}
+	if err := ioutil.WriteFile(logPath, []byte(log.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := &Bundle{
+		Reason:        "panic: runtime error",
+		Goroutines:    "goroutine 1 [running]:",
+		Args:          []string{"build", "-var", "password=hunter2", templateDir},
+		Env:           []string{"PACKER_LOG=1"},
+		TemplatePaths: []string{templateDir},
+		PluginFolders: []string{filepath.Join(dir, "plugins")},
+		LogPath:       logPath,
+	}
+	path, err := bundle.Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	got := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(b)
+	}
+
+	if !strings.Contains(got["reason.txt"], "panic: runtime error") {
+		t.Errorf("reason not found in %q", got["reason.txt"])
+	}
+	wantTemplates := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9  " +
+		filepath.Join(templateDir, "build.pkr.hcl") + "\n"
+	if got["templates.txt"] != wantTemplates {
+		t.Errorf("unexpected templates.txt: %q", got["templates.txt"])
+	}
+	wantPlugins := filepath.Join(pluginDir, "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64") + "\n"
+	if got["plugins.txt"] != wantPlugins {
+		t.Errorf("unexpected plugins.txt: %q", got["plugins.txt"])
+	}
+	logLines := strings.Split(strings.TrimSpace(got["packer.log"]), "\n")
+	if len(logLines) != LogLines || logLines[0] != "line 11" {
+		t.Errorf("expected the last %d log lines, got %d starting with %q", LogLines, len(logLines), logLines[0])
+	}
+	if strings.Contains(got["args.txt"], "hunter2") {
+		t.Errorf("the value of a variable was not redacted: %q", got["args.txt"])
+	}
+	if got["goroutines.txt"] != "goroutine 1 [running]:" {
+		t.Errorf("unexpected goroutines.txt: %q", got["goroutines.txt"])
+	}
+}
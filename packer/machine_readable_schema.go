@@ -0,0 +1,18 @@
+package packer
+
+import (
+	_ "embed"
+)
+
+// MachineReadableSchemaVersion is the version of the JSON schema of the
+// events written by MachineReadableUi. It is bumped whenever an event changes
+// in a way existing parsers can't read, not when a new event type is added.
+const MachineReadableSchemaVersion = 1
+
+// MachineReadableSchema is the JSON schema of an event written by
+// MachineReadableUi, the timestamp,target,type,data... line being read as an
+// object with those four properties. Its version property is
+// MachineReadableSchemaVersion.
+//
+//go:embed machine_readable_schema.json
+var MachineReadableSchema []byte
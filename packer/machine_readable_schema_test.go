@@ -0,0 +1,64 @@
+package packer
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMachineReadableSchema(t *testing.T) {
+	var schema struct {
+		Version int `json:"version"`
+		OneOf   []struct {
+			Ref string `json:"$ref"`
+		} `json:"oneOf"`
+		Definitions map[string]struct {
+			Properties struct {
+				Type struct {
+					Const string `json:"const"`
+					Not   struct {
+						Enum []string `json:"enum"`
+					} `json:"not"`
+				} `json:"type"`
+			} `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(MachineReadableSchema, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %s", err)
+	}
+
+	if schema.Version != MachineReadableSchemaVersion {
+		t.Errorf("schema version is %d, expected %d", schema.Version, MachineReadableSchemaVersion)
+	}
+
+	// Every definition of an event type is one of the accepted events, and
+	// is excluded from the events of unknown types.
+	types := []string{"#/definitions/other"}
+	var names, refs []string
+	for name, def := range schema.Definitions {
+		if def.Properties.Type.Const == "" {
+			continue
+		}
+		if def.Properties.Type.Const != name {
+			t.Errorf("definition %q is for type %q", name, def.Properties.Type.Const)
+		}
+		names = append(names, name)
+		types = append(types, "#/definitions/"+name)
+	}
+	for _, ref := range schema.OneOf {
+		refs = append(refs, ref.Ref)
+	}
+	sort.Strings(types)
+	sort.Strings(refs)
+	if diff := cmp.Diff(types, refs); diff != "" {
+		t.Errorf("event types and accepted events differ: %s", diff)
+	}
+	known := schema.Definitions["other"].Properties.Type.Not.Enum
+	sort.Strings(names)
+	sort.Strings(known)
+	if diff := cmp.Diff(names, known); diff != "" {
+		t.Errorf("event types and types excluded from other differ: %s", diff)
+	}
+}
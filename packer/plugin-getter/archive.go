@@ -0,0 +1,223 @@
+package plugingetter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// ArchiveExtensions are the extensions of the plugin archives that can be
+// installed. Zip files are what plugins publish on GitHub; tarballs are
+// common for plugins that are only built for Linux.
+var ArchiveExtensions = []string{".zip", ".tar.gz", ".tgz", ".tar.xz"}
+
+// ArchiveExt returns the extension of filename if it is the one of a plugin
+// archive, or an empty string.
+func ArchiveExt(filename string) string {
+	for _, ext := range ArchiveExtensions {
+		if strings.HasSuffix(filename, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// openArchivedFile returns the content of the regular file called name in the
+// archive f, whose extension is ext, along with its size. f is read from its
+// start, whatever its offset.
+func openArchivedFile(f *os.File, ext, name string) (io.ReadCloser, int64, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to stat: %v", err)
+	}
+	if ext == ".zip" {
+		return openZippedFile(f, stat.Size(), name)
+	}
+	tr, closeTarball, err := openTarball(f, stat.Size(), ext)
+	if err != nil {
+		return nil, 0, err
+	}
+	rc, size, err := openTarredFile(tr, name)
+	if err != nil {
+		_ = closeTarball()
+		return nil, 0, err
+	}
+	return &readCloser{Reader: rc, close: closeTarball}, size, nil
+}
+
+func openZippedFile(f io.ReaderAt, size int64, name string) (io.ReadCloser, int64, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, 0, fmt.Errorf("zip : %v", err)
+	}
+	for _, zf := range zr.File {
+		if zf.Name != name {
+			continue
+		}
+		// the zip reader verifies the crc32 of the entry once it is fully
+		// read.
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, 0, err
+		}
+		return rc, int64(zf.UncompressedSize64), nil
+	}
+	return nil, 0, fmt.Errorf("could not find a %s file in zipfile", name)
+}
+
+// openTarball returns a reader of the tarball f of size bytes, decompressed
+// like its extension ext says, and the func closing the decompressor.
+func openTarball(f io.ReaderAt, size int64, ext string) (*tar.Reader, func() error, error) {
+	r := io.NewSectionReader(f, 0, size)
+	switch ext {
+	case ".tar.gz", ".tgz":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gzip: %v", err)
+		}
+		return tar.NewReader(gr), gr.Close, nil
+	case ".tar.xz":
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("xz: %v", err)
+		}
+		return tar.NewReader(xr), func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported archive extension %q, expected one of %s", ext, strings.Join(ArchiveExtensions, ", "))
+	}
+}
+
+// openTarredFile reads tr up to the regular file called name. Tarballs are
+// often created from a folder, so entries like ./name match too.
+func openTarredFile(tr *tar.Reader, name string) (io.Reader, int64, error) {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, 0, fmt.Errorf("could not find a %s file in tarball", name)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("tar: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if path.Clean(header.Name) != name {
+			continue
+		}
+		return tr, header.Size, nil
+	}
+}
+
+// walkArchive calls fn with the slash separated name and the content of
+// every regular file of the archive f, whose extension is ext, until fn
+// returns an error.
+func walkArchive(f *os.File, ext string, fn func(name string, r io.Reader) error) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat: %v", err)
+	}
+	if ext == ".zip" {
+		zr, err := zip.NewReader(f, stat.Size())
+		if err != nil {
+			return fmt.Errorf("zip : %v", err)
+		}
+		for _, zf := range zr.File {
+			if !zf.Mode().IsRegular() {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(path.Clean(zf.Name), rc)
+			_ = rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tr, closeTarball, err := openTarball(f, stat.Size(), ext)
+	if err != nil {
+		return err
+	}
+	defer closeTarball()
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("tar: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if err := fn(path.Clean(header.Name), tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractArchivedFiles extracts the regular files of the archive f, whose
+// extension is ext, matching one of patterns, but skip, into dir. The folders
+// of the files are kept. It returns the slash separated names of the
+// extracted files.
+func extractArchivedFiles(f *os.File, ext string, patterns []string, skip, dir string) ([]string, error) {
+	extracted := []string{}
+	err := walkArchive(f, ext, func(name string, r io.Reader) error {
+		if name == skip || !matchesAny(patterns, name) {
+			return nil
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("refusing to extract %q outside of %q", name, dir)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			_ = out.Close()
+			return fmt.Errorf("Extract file %s: %v", name, err)
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		extracted = append(extracted, name)
+		return nil
+	})
+	sort.Strings(extracted)
+	return extracted, err
+}
+
+// matchesAny tells whether name matches one of the path.Match patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readCloser is an io.Reader closed with close.
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc *readCloser) Close() error { return rc.close() }
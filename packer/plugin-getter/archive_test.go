@@ -0,0 +1,235 @@
+package plugingetter
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+	packerversion "github.com/hashicorp/packer/version"
+	"github.com/ulikunitz/xz"
+)
+
+// tarball returns a tarball of files, compressed like ext says.
+func tarball(t *testing.T, ext string, files map[string]string) []byte {
+	buff := bytes.NewBuffer(nil)
+	var w io.WriteCloser
+	var err error
+	switch ext {
+	case ".tar.gz", ".tgz":
+		w = gzip.NewWriter(buff)
+	case ".tar.xz":
+		w, err = xz.NewWriter(buff)
+	default:
+		t.Fatalf("no tarball with a %q extension", ext)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(w)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buff.Bytes()
+}
+
+func TestArchiveExt(t *testing.T) {
+	for filename, want := range map[string]string{
+		"packer-plugin-amazon_v1.2.3_x5.0_linux_amd64.zip":    ".zip",
+		"packer-plugin-amazon_v1.2.3_x5.0_linux_amd64.tar.gz": ".tar.gz",
+		"packer-plugin-amazon_v1.2.3_x5.0_linux_amd64.tgz":    ".tgz",
+		"packer-plugin-amazon_v1.2.3_x5.0_linux_amd64.tar.xz": ".tar.xz",
+		"packer-plugin-amazon_v1.2.3_x5.0_linux_amd64.tar":    "",
+		"packer-plugin-amazon_v1.2.3_x5.0_linux_amd64":        "",
+	} {
+		if got := ArchiveExt(filename); got != want {
+			t.Errorf("ArchiveExt(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
+
+func TestOpenArchivedFile(t *testing.T) {
+	const name = "packer-plugin-amazon_v1.2.3_x5.0_linux_amd64"
+	tests := []struct {
+		ext     string
+		content []byte
+	}{
+		{".tar.gz", tarball(t, ".tar.gz", map[string]string{"README.md": "readme", name: "binary"})},
+		{".tgz", tarball(t, ".tgz", map[string]string{"./" + name: "binary"})},
+		{".tar.xz", tarball(t, ".tar.xz", map[string]string{name: "binary"})},
+	}
+	zipContent, err := ioutil.ReadAll(zipFile(map[string]string{name: "binary"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests = append(tests, struct {
+		ext     string
+		content []byte
+	}{".zip", zipContent})
+
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "archive-*"+tt.ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			defer f.Close()
+			// the file is written first, like a download, and read from its
+			// start anyway.
+			if _, err := f.Write(tt.content); err != nil {
+				t.Fatal(err)
+			}
+
+			rc, size, err := openArchivedFile(f, tt.ext, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ioutil.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := rc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "binary" || size != int64(len(got)) {
+				t.Fatalf("extracted %q of size %d, expected %q", got, size, "binary")
+			}
+
+			if _, _, err := openArchivedFile(f, tt.ext, name+".exe"); err == nil {
+				t.Fatal("expected a missing file not to be found")
+			}
+		})
+	}
+}
+
+func TestRequirement_InstallLatest_tarball(t *testing.T) {
+	const filename = "packer-plugin-amazon_v1.2.3_x5.0_linux_amd64.tar.gz"
+	content := tarball(t, ".tar.gz", map[string]string{
+		"packer-plugin-amazon_v1.2.3_x5.0_linux_amd64": "v1.2.3_x5.0_linux_amd64",
+		"LICENSE":              "license",
+		"schemas/builder.json": "{}",
+		"README.md":            "readme",
+	})
+	sum := sha256.Sum256(content)
+
+	tmpDir, err := ioutil.TempDir("", "packer-install-tarball")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Setenv("PACKER_TMP_DIR", tmpDir)
+	defer os.Unsetenv("PACKER_TMP_DIR")
+
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	pr := &Requirement{Identifier: identifier}
+	install, err := pr.InstallLatest(context.Background(), InstallOptions{
+		Getters: []Getter{&mockPluginGetter{
+			Releases: []Release{{Version: "v1.2.3"}},
+			ChecksumFileEntries: map[string][]ChecksumFileEntry{
+				"1.2.3": {{Filename: filename, Checksum: hex.EncodeToString(sum[:])}},
+			},
+			Zips: map[string]io.ReadCloser{
+				"github.com/hashicorp/packer-plugin-amazon/" + filename: ioutil.NopCloser(bytes.NewReader(content)),
+			},
+		}},
+		InFolders:    []string{filepath.Join(tmpDir, "plugins")},
+		ExtractFiles: DefaultExtractFiles,
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			APIVersionMajor: "5", APIVersionMinor: "0",
+			OS: "linux", ARCH: "amd64",
+			Checksummers: []Checksummer{{Type: "sha256", Hash: sha256.New()}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(install.BinaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "v1.2.3_x5.0_linux_amd64" {
+		t.Fatalf("installed %q", got)
+	}
+
+	receipt, err := ReadInstallReceipt(install.BinaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binarySum := sha256.Sum256([]byte("v1.2.3_x5.0_linux_amd64"))
+	want := &InstallReceipt{
+		Source:           "github.com/hashicorp/amazon",
+		Version:          "v1.2.3",
+		Getter:           "*plugingetter.mockPluginGetter",
+		Archive:          filename,
+		ArchiveChecksums: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+		BinaryChecksums:  map[string]string{"sha256": hex.EncodeToString(binarySum[:])},
+		Files:            []string{"LICENSE", "schemas/builder.json"},
+		PackerVersion:    packerversion.FormattedVersion(),
+	}
+	if diff := cmp.Diff(want, receipt, cmpopts.IgnoreFields(InstallReceipt{}, "InstalledAt")); diff != "" {
+		t.Fatalf("unexpected receipt: %s", diff)
+	}
+	if receipt.InstalledAt.IsZero() {
+		t.Fatal("expected the receipt to record the install time")
+	}
+	if diff := cmp.Diff(receipt, install.Receipt); diff != "" {
+		t.Fatalf("expected the installation to have the receipt: %s", diff)
+	}
+	license, err := ioutil.ReadFile(filepath.Join(FilesDir(install.BinaryPath), "LICENSE"))
+	if err != nil || string(license) != "license" {
+		t.Fatalf("expected the license to be extracted, got %q, %v", license, err)
+	}
+
+	if err := removeInstallation(context.Background(), install, []Checksummer{{Type: "sha256"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(FilesDir(install.BinaryPath)); !os.IsNotExist(err) {
+		t.Fatalf("expected the extracted files to be removed with the binary, got %v", err)
+	}
+}
+
+func TestExtractArchivedFiles_outsideOfFolder(t *testing.T) {
+	f, err := ioutil.TempFile("", "archive-*.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.Write(tarball(t, ".tar.gz", map[string]string{"../LICENSE": "license"})); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := extractArchivedFiles(f, ".tar.gz", []string{"../*"}, "", dir); err == nil {
+		t.Fatal("expected a file outside of the folder to be refused")
+	}
+}
@@ -1,13 +1,22 @@
 package plugingetter
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"hash"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
 )
 
 // A ChecksumError is returned when a checksum differs
@@ -47,6 +56,75 @@ type Checksummer struct {
 	hash.Hash
 }
 
+// ChecksumTypes are the types of the checksum files Packer can verify plugin
+// releases with, in order of preference: when a release publishes more than
+// one checksum file, the first one found is used.
+var ChecksumTypes = []string{"sha256", "sha512", "blake2b"}
+
+// NewChecksummer returns a Checksummer of type typ, one of ChecksumTypes.
+// blake2b is BLAKE2b-512, as computed by b2sum.
+func NewChecksummer(typ string) (Checksummer, error) {
+	switch typ {
+	case "sha256":
+		return Checksummer{Type: typ, Hash: sha256.New()}, nil
+	case "sha512":
+		return Checksummer{Type: typ, Hash: sha512.New()}, nil
+	case "blake2b":
+		h, err := blake2b.New512(nil)
+		if err != nil {
+			return Checksummer{}, err
+		}
+		return Checksummer{Type: typ, Hash: h}, nil
+	default:
+		return Checksummer{}, fmt.Errorf("unknown checksum type %q", typ)
+	}
+}
+
+// DefaultChecksummers returns a Checksummer of each of ChecksumTypes. Each
+// call returns new hashes, as they are not safe for concurrent use.
+func DefaultChecksummers() []Checksummer {
+	checksummers := make([]Checksummer, 0, len(ChecksumTypes))
+	for _, typ := range ChecksumTypes {
+		c, err := NewChecksummer(typ)
+		if err != nil {
+			panic(err)
+		}
+		checksummers = append(checksummers, c)
+	}
+	return checksummers
+}
+
+// copyChecksummers returns new checksummers of the types of checksummers,
+// that can be used concurrently with them.
+func copyChecksummers(checksummers []Checksummer) ([]Checksummer, error) {
+	res := make([]Checksummer, 0, len(checksummers))
+	for _, c := range checksummers {
+		c, err := NewChecksummer(c.Type)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+// IsChecksumType tells whether what is one of ChecksumTypes, that is whether
+// it names the checksum document of a Getter.
+func IsChecksumType(what string) bool {
+	for _, typ := range ChecksumTypes {
+		if what == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// ChecksumFileSuffix returns the suffix of the checksum file of type typ
+// published along plugin releases, like _SHA256SUMS.
+func ChecksumFileSuffix(typ string) string {
+	return "_" + strings.ToUpper(typ) + "SUMS"
+}
+
 func (c *Checksummer) FileExt() string {
 	return "_" + strings.ToUpper(c.Type) + "SUM"
 }
@@ -90,6 +168,63 @@ func (c *Checksummer) ChecksumFile(expected []byte, filePath string) error {
 	return err
 }
 
+// checksumCacheKey identifies a file hashed by a checksummer. A file with
+// the same path, size and modification time is assumed to be unchanged.
+type checksumCacheKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+	typ     string
+}
+
+// checksumCache holds the checksums of the binaries verified by
+// ListInstallations, so that listing the installations of a plugin again,
+// like when a template is validated then built, doesn't hash all of its
+// binaries again.
+var checksumCache = struct {
+	sync.Mutex
+	sums map[checksumCacheKey][]byte
+}{sums: map[checksumCacheKey][]byte{}}
+
+// checksumFileCached is like ChecksumFile, but the checksum of the file at
+// filePath is only computed again when its size or modification time
+// changed.
+func (c *Checksummer) checksumFileCached(expected []byte, filePath string) error {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("Checksum: failed to stat file for checksum: %s", err)
+	}
+	key := checksumCacheKey{path: filePath, size: info.Size(), modTime: info.ModTime(), typ: c.Type}
+
+	checksumCache.Lock()
+	actual, found := checksumCache.sums[key]
+	checksumCache.Unlock()
+	if !found {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("Checksum: failed to open file for checksum: %s", err)
+		}
+		actual, err = c.Sum(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		checksumCache.Lock()
+		checksumCache.sums[key] = actual
+		checksumCache.Unlock()
+	}
+
+	if !bytes.Equal(actual, expected) {
+		return &ChecksumError{
+			Hash:     c.Hash,
+			Actual:   actual,
+			Expected: expected,
+			File:     filePath,
+		}
+	}
+	return nil
+}
+
 func (c *Checksummer) Sum(f io.Reader) ([]byte, error) {
 	c.Hash.Reset()
 	if _, err := io.Copy(c.Hash, f); err != nil {
@@ -114,3 +249,45 @@ func (c *Checksummer) Checksum(expected []byte, f io.Reader) error {
 
 	return nil
 }
+
+// TransformChecksumStream returns a function converting a checksum file, like
+// SHA256SUMS, as published along plugin releases, into the json list of
+// checksums expected from the checksum documents of a Getter, like "sha256".
+func TransformChecksumStream() func(in io.ReadCloser) (io.ReadCloser, error) {
+	return func(in io.ReadCloser) (io.ReadCloser, error) {
+		defer in.Close()
+		rd := bufio.NewReader(in)
+		buffer := bytes.NewBufferString("[")
+		enc := json.NewEncoder(buffer)
+		for i := 0; ; i++ {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				if err != io.EOF {
+					return nil, fmt.Errorf(
+						"Error reading checksum file: %s", err)
+				}
+				break
+			}
+			parts := strings.Fields(line)
+			switch len(parts) {
+			case 2: // nominal case
+				checksumString, checksumFilename := parts[0], parts[1]
+
+				if i > 0 {
+					_, _ = buffer.WriteString(",")
+				}
+				if err := enc.Encode(struct {
+					Checksum string `json:"checksum"`
+					Filename string `json:"filename"`
+				}{
+					Checksum: checksumString,
+					Filename: checksumFilename,
+				}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		_, _ = buffer.WriteString("]")
+		return ioutil.NopCloser(buffer), nil
+	}
+}
@@ -0,0 +1,56 @@
+package plugingetter
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChecksummer_checksumFileCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-plugin-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64")
+	if err := ioutil.WriteFile(binary, []byte("v1.2.3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(binary, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	expected := sha256.Sum256([]byte("v1.2.3"))
+
+	checksummer, err := NewChecksummer("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checksummer.checksumFileCached(expected[:], binary); err != nil {
+		t.Fatalf("checksumFileCached() error = %v", err)
+	}
+
+	// a file of the same size and modification time is not hashed again.
+	if err := ioutil.WriteFile(binary, []byte("v3.2.1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(binary, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := checksummer.checksumFileCached(expected[:], binary); err != nil {
+		t.Fatalf("checksumFileCached() of an unchanged file error = %v", err)
+	}
+
+	// a modified file is.
+	if err := os.Chtimes(binary, time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	err = checksummer.checksumFileCached(expected[:], binary)
+	if _, ok := err.(*ChecksumError); !ok {
+		t.Fatalf("checksumFileCached() of a modified file error = %v, want a *ChecksumError", err)
+	}
+}
@@ -0,0 +1,129 @@
+package plugingetter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// DefaultMaxFailures is the number of consecutive failures after which a
+// CircuitBreaker stops calling its getter.
+const DefaultMaxFailures = 3
+
+// ErrCircuitOpen is returned by a CircuitBreaker that stopped calling its
+// getter.
+var ErrCircuitOpen = errors.New("getter skipped after repeated failures")
+
+// CircuitBreaker wraps a Getter and skips it for the remainder of the run
+// once it failed MaxFailures times in a row, so that an unreachable remote
+// does not add a timeout to every plugin to install.
+//
+// Only network errors and timeouts are counted as failures: a missing file
+// is a valid answer from a healthy remote.
+type CircuitBreaker struct {
+	Getter
+
+	// MaxFailures defaults to DefaultMaxFailures.
+	MaxFailures int
+
+	mu       sync.Mutex
+	failures int
+}
+
+var (
+	_ Getter                  = &CircuitBreaker{}
+	_ Locator                 = &CircuitBreaker{}
+	_ ReleasesSignatureGetter = &CircuitBreaker{}
+	_ RangeGetter             = &CircuitBreaker{}
+)
+
+func (cb *CircuitBreaker) maxFailures() int {
+	if cb.MaxFailures > 0 {
+		return cb.MaxFailures
+	}
+	return DefaultMaxFailures
+}
+
+// Open tells whether the getter is being skipped.
+func (cb *CircuitBreaker) Open() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.failures >= cb.maxFailures()
+}
+
+func (cb *CircuitBreaker) Get(ctx context.Context, what string, opts GetOptions) (io.ReadCloser, error) {
+	if cb.Open() {
+		return nil, fmt.Errorf("%T: %w", cb.Getter, ErrCircuitOpen)
+	}
+
+	rc, err := cb.Getter.Get(ctx, what, opts)
+	cb.record(ctx, err)
+	return rc, err
+}
+
+// GetRange calls the GetRange method of the wrapped getter, when it has one.
+func (cb *CircuitBreaker) GetRange(ctx context.Context, what string, opts GetOptions, offset int64) (io.ReadCloser, error) {
+	rg, ok := cb.Getter.(RangeGetter)
+	if !ok {
+		return nil, fmt.Errorf("%T can not get ranges of %q", cb.Getter, what)
+	}
+	if cb.Open() {
+		return nil, fmt.Errorf("%T: %w", cb.Getter, ErrCircuitOpen)
+	}
+
+	rc, err := rg.GetRange(ctx, what, opts, offset)
+	cb.record(ctx, err)
+	return rc, err
+}
+
+// record counts the failure of a call to the getter, if err is one.
+func (cb *CircuitBreaker) record(ctx context.Context, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch {
+	case err == nil:
+		cb.failures = 0
+	case isUnavailableErr(err):
+		cb.failures++
+		if cb.failures == cb.maxFailures() {
+			Logf(ctx, "[WARN] %T failed %d times in a row, skipping it from now on", cb.Getter, cb.failures)
+		}
+	}
+}
+
+// isUnavailableErr tells whether err is the sign of an unreachable,
+// unresponsive or failing remote. Cancelled requests say nothing about the
+// remote.
+func isUnavailableErr(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode >= 500 {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Locate calls the Locate method of the wrapped getter, when it has one.
+func (cb *CircuitBreaker) Locate(ctx context.Context, what string, opts GetOptions) (string, int64, error) {
+	locator, ok := cb.Getter.(Locator)
+	if !ok || cb.Open() {
+		return "", -1, fmt.Errorf("%T can not locate %q", cb.Getter, what)
+	}
+	return locator.Locate(ctx, what, opts)
+}
+
+// ServesReleasesSignature tells whether the wrapped getter can serve the
+// signature of releases.
+func (cb *CircuitBreaker) ServesReleasesSignature() bool {
+	sg, ok := cb.Getter.(ReleasesSignatureGetter)
+	return ok && sg.ServesReleasesSignature()
+}
@@ -0,0 +1,76 @@
+package plugingetter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+type failingGetter struct {
+	err   error
+	calls int
+}
+
+func (g *failingGetter) Get(ctx context.Context, what string, opts GetOptions) (io.ReadCloser, error) {
+	g.calls++
+	return nil, g.err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	getter := &failingGetter{err: unreachable}
+	cb := &CircuitBreaker{Getter: getter, MaxFailures: 2}
+	for i := 0; i < 4; i++ {
+		if _, err := cb.Get(context.Background(), "releases", GetOptions{}); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if getter.calls != 2 {
+		t.Fatalf("expected the getter to be called twice, got %d calls", getter.calls)
+	}
+	if !cb.Open() {
+		t.Fatal("expected the circuit to be open")
+	}
+	if _, err := cb.Get(context.Background(), "releases", GetOptions{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	getter = &failingGetter{err: &StatusError{URL: "https://example.com/index.json", StatusCode: 502}}
+	cb = &CircuitBreaker{Getter: getter, MaxFailures: 2}
+	for i := 0; i < 4; i++ {
+		_, _ = cb.Get(context.Background(), "releases", GetOptions{})
+	}
+	if getter.calls != 2 || !cb.Open() {
+		t.Fatalf("server errors should open the circuit, got %d calls", getter.calls)
+	}
+
+	getter = &failingGetter{err: &StatusError{URL: "https://example.com/index.json", StatusCode: 404}}
+	cb = &CircuitBreaker{Getter: getter, MaxFailures: 2}
+	for i := 0; i < 4; i++ {
+		_, _ = cb.Get(context.Background(), "releases", GetOptions{})
+	}
+	if getter.calls != 4 || cb.Open() {
+		t.Fatalf("client errors should not open the circuit, got %d calls", getter.calls)
+	}
+
+	getter = &failingGetter{err: errors.New("404 Not Found")}
+	cb = &CircuitBreaker{Getter: getter, MaxFailures: 2}
+	for i := 0; i < 4; i++ {
+		_, _ = cb.Get(context.Background(), "sha256", GetOptions{})
+	}
+	if getter.calls != 4 || cb.Open() {
+		t.Fatalf("non network errors should not open the circuit, got %d calls", getter.calls)
+	}
+
+	getter = &failingGetter{err: context.Canceled}
+	cb = &CircuitBreaker{Getter: getter, MaxFailures: 2}
+	for i := 0; i < 4; i++ {
+		_, _ = cb.Get(context.Background(), "releases", GetOptions{})
+	}
+	if getter.calls != 4 || cb.Open() {
+		t.Fatalf("cancelled calls should not open the circuit, got %d calls", getter.calls)
+	}
+}
@@ -0,0 +1,175 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-version"
+	pluginsdk "github.com/hashicorp/packer-plugin-sdk/plugin"
+)
+
+// ClientOptions are the options of a Client.
+type ClientOptions struct {
+	// InstallOptions every plugin is listed and installed with. Locked must
+	// not be set, as it is specific to a plugin. The platform and protocol
+	// version of the binaries default to the ones of this Packer, and
+	// Checksummers to DefaultChecksummers; only the types of Checksummers
+	// are used, the client creates new hashes for every call.
+	InstallOptions
+
+	// Parallelism is the number of plugins InstallAll installs at the same
+	// time, it defaults to DefaultInstallParallelism.
+	Parallelism int
+
+	// Logger receives the debug messages of the client and of its getters,
+	// it defaults to the standard logger.
+	Logger Logger
+}
+
+// Client lists and installs plugins the way `packer init` does, so that
+// other tools, like IDE integrations, can embed Packer's plugin resolution.
+// A Client is safe for concurrent use.
+type Client struct {
+	opts ClientOptions
+}
+
+// NewClient returns a Client with opts, once they are validated.
+func NewClient(opts ClientOptions) (*Client, error) {
+	if opts.OS == "" {
+		opts.OS = runtime.GOOS
+	}
+	if opts.ARCH == "" {
+		opts.ARCH = runtime.GOARCH
+	}
+	if opts.Ext == "" && opts.OS == "windows" {
+		opts.Ext = ".exe"
+	}
+	if opts.APIVersionMajor == "" && opts.APIVersionMinor == "" {
+		opts.APIVersionMajor, opts.APIVersionMinor = pluginsdk.APIVersionMajor, pluginsdk.APIVersionMinor
+	}
+	if len(opts.Checksummers) == 0 {
+		opts.Checksummers = DefaultChecksummers()
+	}
+	if opts.Parallelism == 0 {
+		opts.Parallelism = DefaultInstallParallelism
+	}
+
+	var errs *multierror.Error
+	if err := opts.InstallOptions.Validate(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if opts.Locked != nil {
+		errs = multierror.Append(errs, fmt.Errorf("Locked can't be set on a client"))
+	}
+	if opts.Parallelism < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("Parallelism must be positive, got %d", opts.Parallelism))
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, fmt.Errorf("invalid client options: %w", err)
+	}
+	return &Client{opts: opts}, nil
+}
+
+// Validate checks that opts can be used to install a plugin.
+func (opts InstallOptions) Validate() error {
+	var errs *multierror.Error
+	if len(opts.Getters) == 0 && !opts.Offline {
+		errs = multierror.Append(errs, fmt.Errorf("at least one getter must be set"))
+	}
+	for i, getter := range opts.Getters {
+		if getter == nil {
+			errs = multierror.Append(errs, fmt.Errorf("getter %d is nil", i))
+		}
+	}
+	if len(opts.InFolders) == 0 {
+		errs = multierror.Append(errs, fmt.Errorf("at least one folder must be set in InFolders"))
+	}
+	if opts.KeepVersions < 0 {
+		errs = multierror.Append(errs, fmt.Errorf("KeepVersions must be positive, got %d", opts.KeepVersions))
+	}
+	for _, pattern := range opts.ExtractFiles {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid ExtractFiles pattern %q: %v", pattern, err))
+		}
+	}
+	if err := opts.BinaryInstallationOptions.Validate(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	return errs.ErrorOrNil()
+}
+
+// Validate checks that binaries can be looked up with opts.
+func (opts BinaryInstallationOptions) Validate() error {
+	var errs *multierror.Error
+	if opts.OS == "" || opts.ARCH == "" {
+		errs = multierror.Append(errs, fmt.Errorf("OS and ARCH must be set"))
+	}
+	if opts.APIVersionMajor == "" || opts.APIVersionMinor == "" {
+		errs = multierror.Append(errs, fmt.Errorf("APIVersionMajor and APIVersionMinor must be set"))
+	}
+	if len(opts.Checksummers) == 0 {
+		errs = multierror.Append(errs, fmt.Errorf("at least one checksummer must be set"))
+	}
+	for _, checksummer := range opts.Checksummers {
+		if _, err := NewChecksummer(checksummer.Type); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// installOptions returns the options of a call, with new hashes.
+func (c *Client) installOptions() InstallOptions {
+	opts := c.opts.InstallOptions
+	opts.Checksummers = make([]Checksummer, 0, len(c.opts.Checksummers))
+	for _, checksummer := range c.opts.Checksummers {
+		// the types were validated by NewClient.
+		checksummer, _ = NewChecksummer(checksummer.Type)
+		opts.Checksummers = append(opts.Checksummers, checksummer)
+	}
+	return opts
+}
+
+func (c *Client) listInstallationsOptions() ListInstallationsOptions {
+	opts := c.installOptions()
+	return ListInstallationsOptions{
+		FromFolders:               opts.InFolders,
+		BinaryInstallationOptions: opts.BinaryInstallationOptions,
+	}
+}
+
+func (c *Client) context(ctx context.Context) context.Context {
+	if c.opts.Logger == nil {
+		return ctx
+	}
+	return WithLogger(ctx, c.opts.Logger)
+}
+
+// ListInstallations lists the installed binaries of pr, see
+// Requirement.ListInstallations.
+func (c *Client) ListInstallations(ctx context.Context, pr *Requirement) (InstallList, error) {
+	return pr.ListInstallations(c.context(ctx), c.listInstallationsOptions())
+}
+
+// InstallLatest installs the highest version of pr, see
+// Requirement.InstallLatest. No Installation is returned when a matching
+// binary is already installed.
+func (c *Client) InstallLatest(ctx context.Context, pr *Requirement) (*Installation, error) {
+	return pr.InstallLatest(c.context(ctx), c.installOptions())
+}
+
+// InstallVersion installs version v of pr, see Requirement.InstallVersion.
+func (c *Client) InstallVersion(ctx context.Context, pr *Requirement, v *version.Version) (*Installation, error) {
+	return pr.InstallVersion(c.context(ctx), v, c.installOptions())
+}
+
+// InstallAll installs the highest version of every requirement of reqs, see
+// Requirements.InstallAll.
+func (c *Client) InstallAll(ctx context.Context, reqs Requirements, onDone func(InstallResult)) ([]InstallResult, error) {
+	return reqs.InstallAll(c.context(ctx), c.opts.Parallelism, func(*Requirement) InstallOptions {
+		return c.installOptions()
+	}, onDone)
+}
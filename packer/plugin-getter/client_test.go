@@ -0,0 +1,89 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+// recordingLogger records the messages logged to it.
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestNewClient_validation(t *testing.T) {
+	_, err := NewClient(ClientOptions{
+		InstallOptions: InstallOptions{
+			Locked: &LockedPlugin{},
+			BinaryInstallationOptions: BinaryInstallationOptions{
+				Checksummers: []Checksummer{{Type: "md5"}},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected invalid options to be refused")
+	}
+	for _, want := range []string{"getter", "InFolders", "Locked", `"md5"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to be about %s, got %v", want, err)
+		}
+	}
+
+	c, err := NewClient(ClientOptions{
+		InstallOptions: InstallOptions{
+			Getters:   []Getter{&mockPluginGetter{}},
+			InFolders: []string{pluginFolderOne},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.opts.OS == "" || c.opts.APIVersionMajor == "" || len(c.opts.Checksummers) != len(ChecksumTypes) {
+		t.Fatalf("expected the options to be defaulted, got %#v", c.opts)
+	}
+}
+
+func TestClient_ListInstallations(t *testing.T) {
+	logger := &recordingLogger{}
+	c, err := NewClient(ClientOptions{
+		InstallOptions: InstallOptions{
+			Getters:   []Getter{&mockPluginGetter{}},
+			InFolders: []string{pluginFolderOne},
+			BinaryInstallationOptions: BinaryInstallationOptions{
+				APIVersionMajor: "5", APIVersionMinor: "0",
+				OS: "darwin", ARCH: "amd64",
+				Checksummers: []Checksummer{{Type: "sha256"}},
+			},
+		},
+		Logger: logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+
+	installs, err := c.ListInstallations(context.Background(), &Requirement{Identifier: identifier})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installs) == 0 {
+		t.Fatal("expected installations of the amazon plugin")
+	}
+	if len(logger.messages) == 0 {
+		t.Fatal("expected the client to log to its logger")
+	}
+}
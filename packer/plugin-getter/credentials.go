@@ -0,0 +1,248 @@
+package plugingetter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Credentials authenticate the requests made to a host, with a bearer token
+// or with basic auth.
+type Credentials struct {
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// apply sets the Authorization header of req.
+func (c *Credentials) apply(req *http.Request) {
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.Username != "" || c.Password != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+// A CredentialsSource gives the credentials of plugin hosts.
+type CredentialsSource interface {
+	// Credentials returns the credentials of host, or nil when it has none.
+	Credentials(host string) (*Credentials, error)
+}
+
+// CredentialsChain consults its sources in order, the first one that has
+// credentials for a host wins.
+type CredentialsChain []CredentialsSource
+
+func (chain CredentialsChain) Credentials(host string) (*Credentials, error) {
+	for _, source := range chain {
+		creds, err := source.Credentials(host)
+		if err != nil {
+			return nil, err
+		}
+		if creds != nil {
+			return creds, nil
+		}
+	}
+	return nil, nil
+}
+
+// EnvCredentials reads the token of a host from the
+// PACKER_PLUGIN_TOKEN_<host> env var, in which the dots of the host are
+// replaced by underscores and its dashes by double underscores, like
+// PACKER_PLUGIN_TOKEN_plugins_example_com for plugins.example.com.
+type EnvCredentials struct{}
+
+// EnvCredentialsVar returns the name of the env var holding the token of host.
+func EnvCredentialsVar(host string) string {
+	host = strings.ReplaceAll(host, "-", "__")
+	host = strings.ReplaceAll(host, ".", "_")
+	return "PACKER_PLUGIN_TOKEN_" + host
+}
+
+func (EnvCredentials) Credentials(host string) (*Credentials, error) {
+	if token := os.Getenv(EnvCredentialsVar(host)); token != "" {
+		return &Credentials{Token: token}, nil
+	}
+	return nil, nil
+}
+
+// NetrcCredentials reads the login and password of a host from a netrc file.
+type NetrcCredentials struct {
+	// Path of the netrc file, the file is optional.
+	Path string
+
+	once     sync.Once
+	machines map[string]*Credentials
+	err      error
+}
+
+// DefaultNetrcPath returns the path of the netrc file of the user: the one
+// set in the NETRC env var, or the .netrc file of their home directory.
+func DefaultNetrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+func (n *NetrcCredentials) Credentials(host string) (*Credentials, error) {
+	n.once.Do(func() {
+		n.machines, n.err = parseNetrc(n.Path)
+	})
+	if n.err != nil {
+		return nil, n.err
+	}
+	if creds, found := n.machines[host]; found {
+		return creds, nil
+	}
+	return n.machines[""], nil
+}
+
+// parseNetrc returns the credentials of the machines of a netrc file, the
+// default entry is keyed by the empty string. macdef entries are skipped.
+func parseNetrc(path string) (map[string]*Credentials, error) {
+	machines := map[string]*Credentials{}
+	if path == "" {
+		return machines, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return machines, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read netrc file: %w", err)
+	}
+
+	var current *Credentials
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// a macro ends with an empty line
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			next := func() string {
+				if i+1 < len(fields) {
+					i++
+					return fields[i]
+				}
+				return ""
+			}
+			switch fields[i] {
+			case "machine":
+				current = &Credentials{}
+				machines[next()] = current
+			case "default":
+				current = &Credentials{}
+				machines[""] = current
+			case "login":
+				if current != nil {
+					current.Username = next()
+				}
+			case "password":
+				if current != nil {
+					current.Password = next()
+				}
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+	return machines, scanner.Err()
+}
+
+// HelperCredentials gets credentials from a helper executable. The helper is
+// run with the `get` argument and the host on its standard input; it prints
+// the credentials as json, like `{"token": "..."}` or
+// `{"username": "...", "password": "..."}`, or nothing when it has none.
+// The helper is run once per host.
+type HelperCredentials struct {
+	Command string
+
+	mu    sync.Mutex
+	hosts map[string]*Credentials
+}
+
+func (h *HelperCredentials) Credentials(host string) (*Credentials, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if creds, found := h.hosts[host]; found {
+		return creds, nil
+	}
+	creds, err := h.run(host)
+	if err != nil {
+		return nil, err
+	}
+	if h.hosts == nil {
+		h.hosts = map[string]*Credentials{}
+	}
+	h.hosts[host] = creds
+	return creds, nil
+}
+
+func (h *HelperCredentials) run(host string) (*Credentials, error) {
+	cmd := exec.Command(h.Command, "get")
+	cmd.Stdin = strings.NewReader(host + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credentials helper %s failed for %s: %v: %s", h.Command, host, err, strings.TrimSpace(stderr.String()))
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	creds := &Credentials{}
+	if err := json.Unmarshal(out, creds); err != nil {
+		return nil, fmt.Errorf("credentials helper %s returned invalid credentials for %s: %v", h.Command, host, err)
+	}
+	return creds, nil
+}
+
+// CredentialsTransport authenticates the https requests it sends with the
+// credentials of their host. Requests that already have an Authorization
+// header are sent as is.
+type CredentialsTransport struct {
+	Source CredentialsSource
+	Base   http.RoundTripper
+}
+
+func (t *CredentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	// credentials are never sent in clear text
+	if t.Source == nil || req.URL.Scheme != "https" || req.Header.Get("Authorization") != "" {
+		return base.RoundTrip(req)
+	}
+	creds, err := t.Source.Credentials(req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		return base.RoundTrip(req)
+	}
+	Logf(req.Context(), "[DEBUG] authenticating request to %s", req.URL.Hostname())
+	req = req.Clone(req.Context())
+	creds.apply(req)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,139 @@
+package plugingetter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	if got := EnvCredentialsVar("my-plugins.example.com"); got != "PACKER_PLUGIN_TOKEN_my__plugins_example_com" {
+		t.Fatalf("EnvCredentialsVar() = %q", got)
+	}
+	os.Setenv("PACKER_PLUGIN_TOKEN_plugins_example_com", "s3cr3t")
+	defer os.Unsetenv("PACKER_PLUGIN_TOKEN_plugins_example_com")
+
+	creds, err := EnvCredentials{}.Credentials("plugins.example.com")
+	if err != nil || creds == nil || creds.Token != "s3cr3t" {
+		t.Fatalf("Credentials() = %v, %v", creds, err)
+	}
+	if creds, _ := (EnvCredentials{}).Credentials("example.com"); creds != nil {
+		t.Fatalf("expected no credentials, got %v", creds)
+	}
+}
+
+func TestNetrcCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-netrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".netrc")
+	content := `machine plugins.example.com login packer password s3cr3t
+macdef init
+cd /pub
+
+machine mirror.example.com
+  login mirror
+  password m1rr0r
+default login anonymous password guest
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	netrc := &NetrcCredentials{Path: path}
+	for host, want := range map[string]Credentials{
+		"plugins.example.com": {Username: "packer", Password: "s3cr3t"},
+		"mirror.example.com":  {Username: "mirror", Password: "m1rr0r"},
+		"other.example.com":   {Username: "anonymous", Password: "guest"},
+	} {
+		creds, err := netrc.Credentials(host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds == nil || *creds != want {
+			t.Fatalf("Credentials(%s) = %v, want %v", host, creds, want)
+		}
+	}
+
+	missing := &NetrcCredentials{Path: filepath.Join(dir, "missing")}
+	if creds, err := missing.Credentials("plugins.example.com"); creds != nil || err != nil {
+		t.Fatalf("Credentials() = %v, %v; expected nothing", creds, err)
+	}
+}
+
+func TestHelperCredentials(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test helper is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "packer-credentials-helper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	helper := filepath.Join(dir, "helper")
+	script := `#!/bin/sh
+read host
+if [ "$1" = get ] && [ "$host" = plugins.example.com ]; then
+  echo '{"token": "s3cr3t"}'
+fi
+`
+	if err := ioutil.WriteFile(helper, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &HelperCredentials{Command: helper}
+	creds, err := h.Credentials("plugins.example.com")
+	if err != nil || creds == nil || creds.Token != "s3cr3t" {
+		t.Fatalf("Credentials() = %v, %v", creds, err)
+	}
+	if creds, err := h.Credentials("example.com"); creds != nil || err != nil {
+		t.Fatalf("Credentials() = %v, %v; expected nothing", creds, err)
+	}
+}
+
+type staticCredentials map[string]*Credentials
+
+func (s staticCredentials) Credentials(host string) (*Credentials, error) {
+	return s[host], nil
+}
+
+func TestCredentialsTransport(t *testing.T) {
+	var auth string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	})
+	tlsSrv := httptest.NewTLSServer(handler)
+	defer tlsSrv.Close()
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	source := staticCredentials{"127.0.0.1": {Username: "packer", Password: "s3cr3t"}}
+	client := &http.Client{Transport: &CredentialsTransport{
+		Source: source,
+		Base:   tlsSrv.Client().Transport,
+	}}
+
+	resp, err := client.Get(tlsSrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if auth != "Basic cGFja2VyOnMzY3IzdA==" {
+		t.Fatalf("unexpected Authorization header %q", auth)
+	}
+
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if auth != "" {
+		t.Fatalf("credentials should not be sent over http, got %q", auth)
+	}
+}
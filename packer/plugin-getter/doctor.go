@@ -0,0 +1,208 @@
+package plugingetter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/longpath"
+)
+
+// Problem is an issue found in a plugin folder by Diagnose.
+type Problem struct {
+	// Path of the file with an issue.
+	Path string
+	// Description of the issue.
+	Description string
+	// Fix describes how to fix the issue.
+	Fix string
+
+	apply func() error
+}
+
+// Fixable tells whether Apply can fix the problem.
+func (p *Problem) Fixable() bool { return p.apply != nil }
+
+// Apply fixes the problem.
+func (p *Problem) Apply() error {
+	if p.apply == nil {
+		return fmt.Errorf("%s can not be fixed automatically: %s", p.Path, p.Fix)
+	}
+	return p.apply()
+}
+
+func removeFiles(paths ...string) func() error {
+	return func() error {
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Diagnose inspects the plugins installed in the hierarchical layout of the
+// opts.FromFolders, like the ones installed by `packer init`, and reports:
+//  * checksum files without a binary and leftovers of interrupted installs,
+//  * binaries with a name Packer can't parse,
+//  * binaries without checksum or with a checksum that doesn't match,
+//  * binaries using an incompatible protocol version,
+//  * versions installed in more than one folder,
+//  * binaries that are not executable.
+//
+// Binaries built for another system are not reported.
+func Diagnose(opts ListInstallationsOptions) ([]*Problem, error) {
+	problems := []*Problem{}
+	checksumExts := map[string]bool{}
+	for _, checksummer := range opts.Checksummers {
+		checksumExts[checksummer.FileExt()] = true
+	}
+
+	// the last folder is the one packer init installs to, so when a version
+	// is installed more than once the copy kept is the one from the last
+	// folder.
+	seen := map[string]string{}
+	for i := len(opts.FromFolders) - 1; i >= 0; i-- {
+		folder := opts.FromFolders[i]
+		// plugins are in folder/hostname/namespace/type/
+		dirs, err := longpath.Glob(folder, "*/*/*")
+		if err != nil {
+			return nil, fmt.Errorf("Diagnose: failed to list plugins of %q: %v", folder, err)
+		}
+		for _, dir := range dirs {
+			if fi, err := os.Stat(longpath.Fix(dir)); err != nil || !fi.IsDir() {
+				continue
+			}
+			files, err := ioutil.ReadDir(longpath.Fix(dir))
+			if err != nil {
+				problems = append(problems, &Problem{
+					Path:        dir,
+					Description: fmt.Sprintf("could not be read: %s", err),
+					Fix:         "fix the permissions of the folder",
+				})
+				continue
+			}
+			pluginType := filepath.Base(dir)
+			for _, file := range files {
+				if file.IsDir() {
+					continue
+				}
+				path := filepath.Join(dir, file.Name())
+				rel, _ := filepath.Rel(folder, path)
+				problems = append(problems, diagnoseFile(opts, pluginType, path, rel, file, checksumExts, seen)...)
+			}
+		}
+	}
+	return problems, nil
+}
+
+func diagnoseFile(opts ListInstallationsOptions, pluginType, path, rel string, file os.FileInfo, checksumExts map[string]bool, seen map[string]string) []*Problem {
+	name := file.Name()
+
+	if strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp") {
+		return []*Problem{{
+			Path:        path,
+			Description: "is a leftover of an interrupted installation",
+			Fix:         "remove it",
+			apply:       removeFiles(path),
+		}}
+	}
+
+	for ext := range checksumExts {
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+		if _, err := os.Stat(strings.TrimSuffix(path, ext)); os.IsNotExist(err) {
+			return []*Problem{{
+				Path:        path,
+				Description: "is a checksum file without binary",
+				Fix:         "remove it",
+				apply:       removeFiles(path),
+			}}
+		}
+		return nil
+	}
+
+	checksumFiles := []string{}
+	for ext := range checksumExts {
+		checksumFiles = append(checksumFiles, path+ext)
+	}
+	removeInstall := removeFiles(append([]string{path}, checksumFiles...)...)
+
+	// name should look like packer-plugin-amazon_v1.2.3_x5.1_darwin_amd64.exe
+	prefix := "packer-plugin-" + pluginType + "_"
+	parsed, err := parsePluginFilename(strings.TrimSuffix(strings.TrimPrefix(name, prefix), opts.Ext))
+	if !strings.HasPrefix(name, prefix) || err != nil {
+		return []*Problem{{
+			Path:        path,
+			Description: fmt.Sprintf("is not named like %sv<version>_x<protocol>_<os>_<arch>, Packer ignores it", prefix),
+			Fix:         "remove it",
+			apply:       removeInstall,
+		}}
+	}
+
+	if parsed.os != opts.OS || parsed.arch != opts.ARCH || !strings.HasSuffix(name, opts.Ext) {
+		return nil
+	}
+
+	if previous, found := seen[rel]; found {
+		return []*Problem{{
+			Path:        path,
+			Description: fmt.Sprintf("is also installed as %s", previous),
+			Fix:         "remove this copy",
+			apply:       removeInstall,
+		}}
+	}
+	seen[rel] = path
+
+	if err := opts.CheckProtocolVersion(parsed.protocol); err != nil {
+		return []*Problem{{
+			Path:        path,
+			Description: fmt.Sprintf("is incompatible with this version of Packer: %s", err),
+			Fix:         "remove it",
+			apply:       removeInstall,
+		}}
+	}
+
+	checksumFound := false
+	for _, checksummer := range opts.Checksummers {
+		cs, err := checksummer.GetCacheChecksumOfFile(path)
+		if err != nil {
+			continue
+		}
+		checksumFound = true
+		if err := checksummer.ChecksumFile(cs, path); err != nil {
+			log.Printf("[TRACE] ChecksumFile(%q) failed: %v", path, err)
+			return []*Problem{{
+				Path:        path,
+				Description: fmt.Sprintf("does not match its %s checksum, Packer ignores it", checksummer.Type),
+				Fix:         "remove it and run packer init again",
+				apply:       removeInstall,
+			}}
+		}
+	}
+	if !checksumFound {
+		return []*Problem{{
+			Path:        path,
+			Description: "has no checksum file, Packer ignores it",
+			Fix:         "reinstall it with packer init",
+		}}
+	}
+
+	if runtime.GOOS != "windows" && file.Mode()&0100 == 0 {
+		return []*Problem{{
+			Path:        path,
+			Description: "is not executable",
+			Fix:         "make it executable",
+			apply: func() error {
+				return os.Chmod(path, file.Mode()|0755)
+			},
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package plugingetter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiagnose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not checked on windows")
+	}
+	dir, err := ioutil.TempDir("", "packer-plugins-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	one, two := filepath.Join(dir, "one"), filepath.Join(dir, "two")
+	write := func(folder, name, content string, mode os.FileMode, withChecksum bool) string {
+		path := filepath.Join(folder, "github.com", "hashicorp", "amazon", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+		if withChecksum {
+			sum := sha256.Sum256([]byte(content))
+			if err := ioutil.WriteFile(path+"_SHA256SUM", []byte(hex.EncodeToString(sum[:])), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+
+	write(one, "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64", "v1", 0755, true)
+	duplicate := write(one, "packer-plugin-amazon_v1.1.0_x5.0_linux_amd64", "v1.1", 0755, true)
+	write(two, "packer-plugin-amazon_v1.1.0_x5.0_linux_amd64", "v1.1", 0755, true)
+	incompatible := write(two, "packer-plugin-amazon_v2.0.0_x6.0_linux_amd64", "v2", 0755, true)
+	noChecksum := write(two, "packer-plugin-amazon_v1.2.0_x5.0_linux_amd64", "v1.2", 0755, false)
+	notExecutable := write(two, "packer-plugin-amazon_v1.3.0_x5.0_linux_amd64", "v1.3", 0644, true)
+	badName := write(two, "packer-plugin-amazon", "legacy", 0755, false)
+	orphan := write(two, "packer-plugin-amazon_v0.1.0_x5.0_linux_amd64_SHA256SUM", "0000", 0644, false)
+	leftover := write(two, ".packer-plugin-amazon_v1.4.0_x5.0_linux_amd64.123.tmp", "v1.4", 0644, false)
+	write(two, "packer-plugin-amazon_v1.0.0_x5.0_darwin_amd64", "other system", 0644, false)
+
+	opts := ListInstallationsOptions{
+		FromFolders: []string{one, two},
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			APIVersionMajor: "5", APIVersionMinor: "0",
+			OS: "linux", ARCH: "amd64",
+			Checksummers: []Checksummer{
+				{Type: "sha256", Hash: sha256.New()},
+			},
+		},
+	}
+
+	problems, err := Diagnose(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, p := range problems {
+		got = append(got, p.Path)
+	}
+	sort.Strings(got)
+	want := []string{duplicate, incompatible, noChecksum, notExecutable, badName, orphan, leftover}
+	sort.Strings(want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected problems: %s", diff)
+	}
+
+	for _, p := range problems {
+		if p.Fixable() {
+			if err := p.Apply(); err != nil {
+				t.Fatalf("Apply(%s): %s", p.Path, err)
+			}
+		}
+	}
+
+	problems, err = Diagnose(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Path != noChecksum {
+		t.Fatalf("expected only the binary without checksum to remain, got %#v", problems)
+	}
+}
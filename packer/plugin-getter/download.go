@@ -0,0 +1,155 @@
+package plugingetter
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/longpath"
+)
+
+// RangeGetter is implemented by getters that can fetch the end of a file,
+// to resume an interrupted download.
+type RangeGetter interface {
+	// GetRange returns what, starting at offset bytes. An error is returned
+	// when the remote can't serve a range.
+	GetRange(ctx context.Context, what string, opts GetOptions, offset int64) (io.ReadCloser, error)
+}
+
+// partialDownloadsDir is where interrupted downloads are kept, it honors
+// PACKER_TMP_DIR like the rest of Packer's temporary files.
+func partialDownloadsDir() string {
+	dir := os.Getenv("PACKER_TMP_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return longpath.Fix(filepath.Join(dir, "packer-plugin-downloads"))
+}
+
+// partialDownload is a zip file being downloaded. When a download is
+// interrupted, the partial file is kept under a name derived from the
+// expected checksum of the zip file, so that the next attempt, from this run
+// or from a later one, can resume it.
+type partialDownload struct {
+	*os.File
+
+	// shared is the path the partial file is kept at.
+	shared string
+	// complete is set once the file was verified, it is then removed.
+	complete bool
+	// logger is the logger of the install the download is for.
+	logger Logger
+}
+
+// openPartialDownload claims the partial download of the zip file described
+// by checksum, or starts a new one. Claiming renames the shared file, so that
+// concurrent installs of the same file never write to the same file.
+func openPartialDownload(ctx context.Context, checksum *FileChecksum) (*partialDownload, error) {
+	dir := partialDownloadsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	name := checksum.Type + "-" + hex.EncodeToString(checksum.Expected) + ArchiveExt(checksum.Filename) + ".part"
+	f, err := ioutil.TempFile(dir, name+".*")
+	if err != nil {
+		return nil, err
+	}
+	own := f.Name()
+	_ = f.Close()
+
+	shared := filepath.Join(dir, name)
+	if err := os.Rename(shared, own); err == nil {
+		Logf(ctx, "[TRACE] found a partial download of %s", checksum.Filename)
+	}
+	f, err = os.OpenFile(own, os.O_RDWR, 0)
+	if err != nil {
+		_ = os.Remove(own)
+		return nil, err
+	}
+	return &partialDownload{File: f, shared: shared, logger: LoggerFrom(ctx)}, nil
+}
+
+// fetch downloads the zip file described by opts with getter, resuming the
+// partial download when the getter supports it. The file is positioned at its
+// start when fetch returns without error. The progress of the download is
+// reported to tracker, when set. The download stops when ctx is done.
+//
+// The file is hashed with sums while it is written, so that it doesn't have to
+// be read back to be verified; only the kept part of a resumed download is.
+func (d *partialDownload) fetch(ctx context.Context, getter Getter, opts GetOptions, tracker ProgressTracker, sums checksums) error {
+	offset, err := d.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	var remote io.ReadCloser
+	if rg, ok := getter.(RangeGetter); ok && offset > 0 {
+		Logf(ctx, "[TRACE] resuming the download of %s from %s at %d bytes", opts.ExpectedZipFilename(), getter, offset)
+		remote, err = rg.GetRange(ctx, "zip", opts, offset)
+		if err != nil {
+			Logf(ctx, "[TRACE] could not resume the download, restarting it: %v", err)
+			remote = nil
+		}
+	}
+	if remote == nil {
+		offset = 0
+		remote, err = getter.Get(ctx, "zip", opts)
+		if err != nil {
+			return err
+		}
+		if err := d.Truncate(0); err != nil {
+			_ = remote.Close()
+			return err
+		}
+		if _, err := d.Seek(0, io.SeekStart); err != nil {
+			_ = remote.Close()
+			return err
+		}
+	}
+
+	sums.reset()
+	if offset > 0 {
+		if _, err := d.Seek(0, io.SeekStart); err != nil {
+			_ = remote.Close()
+			return err
+		}
+		if _, err := io.CopyN(sums, d.File, offset); err != nil {
+			_ = remote.Close()
+			return fmt.Errorf("could not hash the partial download: %w", err)
+		}
+	}
+
+	total := int64(-1)
+	if sized, ok := remote.(SizedReadCloser); ok && sized.Size() >= 0 {
+		total = offset + sized.Size()
+	}
+	tracked, done := trackProgress(tracker, opts.ExpectedZipFilename(), offset, total, remote)
+	_, err = io.Copy(io.MultiWriter(d.File, sums), tracked)
+	done()
+	_ = remote.Close()
+	if err != nil {
+		return fmt.Errorf("Error getting plugin: %w", err)
+	}
+	_, err = d.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close closes the file, which is kept for a later attempt when it holds an
+// incomplete download, and removed otherwise.
+func (d *partialDownload) Close() error {
+	err := d.File.Close()
+	fi, statErr := os.Stat(d.Name())
+	if d.complete || statErr != nil || fi.Size() == 0 {
+		_ = os.Remove(d.Name())
+		return err
+	}
+	if renameErr := os.Rename(d.Name(), d.shared); renameErr != nil {
+		d.logger.Printf("[TRACE] could not keep the partial download %s: %v", d.Name(), renameErr)
+		_ = os.Remove(d.Name())
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package plugingetter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// flakyReader fails once it read failAfter bytes.
+type flakyReader struct {
+	r         io.Reader
+	failAfter int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.failAfter <= 0 {
+		return 0, errors.New("connection reset")
+	}
+	if len(p) > f.failAfter {
+		p = p[:f.failAfter]
+	}
+	n, err := f.r.Read(p)
+	f.failAfter -= n
+	return n, err
+}
+
+// rangeGetter serves zip, failing after failAfter bytes when it is set.
+type rangeGetter struct {
+	zip       []byte
+	failAfter int
+	offsets   []int64
+}
+
+func (g *rangeGetter) Get(ctx context.Context, what string, opts GetOptions) (io.ReadCloser, error) {
+	return g.GetRange(ctx, what, opts, 0)
+}
+
+func (g *rangeGetter) GetRange(ctx context.Context, what string, opts GetOptions, offset int64) (io.ReadCloser, error) {
+	g.offsets = append(g.offsets, offset)
+	var r io.Reader = bytes.NewReader(g.zip[offset:])
+	if g.failAfter > 0 {
+		r = &flakyReader{r: r, failAfter: g.failAfter}
+	}
+	return SizedBody(ioutil.NopCloser(r), int64(len(g.zip))-offset), nil
+}
+
+// progressRecorder records the progress reported to it.
+type progressRecorder struct {
+	src                    string
+	currentSize, totalSize int64
+	read                   int64
+	closed                 bool
+}
+
+func (p *progressRecorder) TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) io.ReadCloser {
+	p.src, p.currentSize, p.totalSize = src, currentSize, totalSize
+	p.read = currentSize
+	return p.readCloser(stream)
+}
+
+func (p *progressRecorder) readCloser(stream io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: readerFunc(func(b []byte) (int, error) {
+			n, err := stream.Read(b)
+			p.read += int64(n)
+			return n, err
+		}),
+		Closer: closerFunc(func() error { p.closed = true; return nil }),
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(b []byte) (int, error) { return f(b) }
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestPartialDownload_resume(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "packer-partial-download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Setenv("PACKER_TMP_DIR", tmpDir)
+	defer os.Unsetenv("PACKER_TMP_DIR")
+
+	zip := []byte("a zip file that takes a while to download")
+	checksum := &FileChecksum{
+		Filename:    "packer-plugin-happycloud_v1.0.0_x5.0_linux_amd64.zip",
+		Expected:    []byte{0xca, 0xfe},
+		Checksummer: Checksummer{Type: "sha256"},
+	}
+	getter := &rangeGetter{zip: zip, failAfter: 10}
+	sums, err := newChecksums(nil, Checksummer{Type: "sha256", Hash: sha256.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	download, err := openPartialDownload(context.Background(), checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := download.fetch(context.Background(), getter, GetOptions{}, nil, sums); err == nil {
+		t.Fatal("expected the download to be interrupted")
+	}
+	if err := download.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	getter.failAfter = 0
+	download, err = openPartialDownload(context.Background(), checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := &progressRecorder{}
+	if err := download.fetch(context.Background(), getter, GetOptions{}, progress, sums); err != nil {
+		t.Fatal(err)
+	}
+	if progress.currentSize != 10 || progress.totalSize != int64(len(zip)) {
+		t.Fatalf("expected progress from byte 10 of %d, got %d of %d", len(zip), progress.currentSize, progress.totalSize)
+	}
+	if progress.read != int64(len(zip)) || !progress.closed {
+		t.Fatalf("expected the tracking to end after %d bytes, read %d bytes, closed: %t", len(zip), progress.read, progress.closed)
+	}
+	got, err := ioutil.ReadAll(download)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, zip) {
+		t.Fatalf("downloaded %q, expected %q", got, zip)
+	}
+	if want := sha256.Sum256(zip); !bytes.Equal(sums["sha256"].Sum(nil), want[:]) {
+		t.Fatalf("the resumed download was hashed as %x, expected %x", sums["sha256"].Sum(nil), want)
+	}
+	if len(getter.offsets) != 2 || getter.offsets[1] != 10 {
+		t.Fatalf("expected the download to resume at byte 10, got offsets %v", getter.offsets)
+	}
+
+	download.complete = true
+	if err := download.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(partialDownloadsDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected the complete download to be removed, found %d files", len(files))
+	}
+}
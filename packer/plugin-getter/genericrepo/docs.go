@@ -0,0 +1,4 @@
+// Package genericrepo defines a getter for plugins hosted in the generic
+// repositories of Artifactory, or in the raw repositories of Nexus.
+
+package genericrepo
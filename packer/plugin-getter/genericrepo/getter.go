@@ -0,0 +1,431 @@
+package genericrepo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+const defaultUserAgent = "packer-plugin-getter"
+
+// Kind is the kind of repository manager hosting a repository.
+type Kind string
+
+const (
+	// Artifactory repositories are listed with AQL queries.
+	Artifactory Kind = "artifactory"
+	// Nexus repositories are listed with the search API.
+	Nexus Kind = "nexus"
+)
+
+// Getter fetches plugins from a generic Artifactory repository, or a raw
+// Nexus repository, in which zip files, or tarballs, are uploaded as they are
+// published on GitHub, without any SHA256SUMS or index file.
+//
+// The plugins.example.com/hashicorp/happycloud plugin is uploaded to
+// <RepositoryURL>/hashicorp/happycloud/v1.2.3/packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip.
+// Its releases are found by listing the repository, and the sha256 checksums
+// of its zip files are the ones the repository manager computed, taken from
+// the listing or from the X-Checksum-Sha256 header of the files.
+type Getter struct {
+	// Hostname is the host plugins are required with.
+	Hostname string
+
+	// Kind of the repository manager, Artifactory or Nexus.
+	Kind Kind
+
+	// RepositoryURL is the URL of the repository, like
+	// https://artifactory.example.com/artifactory/packer-plugins or
+	// https://nexus.example.com/repository/packer-plugins.
+	RepositoryURL string
+
+	Client    *http.Client
+	UserAgent string
+
+	// Timeouts of the requests, used when Client is nil.
+	Timeouts plugingetter.Timeouts
+
+	// Credentials, when set, authenticate the requests to the repository
+	// manager. Used when Client is nil.
+	Credentials plugingetter.CredentialsSource
+
+	// clientOnce guards the creation of Client, as plugins can be installed
+	// concurrently.
+	clientOnce sync.Once
+
+	// files are the zip files of each plugin, listed once per run.
+	filesMu sync.Mutex
+	files   map[string][]file
+}
+
+var (
+	_ plugingetter.Getter      = &Getter{}
+	_ plugingetter.Locator     = &Getter{}
+	_ plugingetter.RangeGetter = &Getter{}
+)
+
+// file is a zip file of a plugin in the repository.
+type file struct {
+	// Path is the slash separated path of the file in the repository.
+	Path string
+	// SHA256 is the checksum of the file computed by the repository manager,
+	// empty when the listing doesn't tell.
+	SHA256 string
+}
+
+func (g *Getter) String() string {
+	return string(g.Kind) + " repository " + g.RepositoryURL
+}
+
+func (g *Getter) initClient() {
+	g.clientOnce.Do(func() {
+		if g.Client != nil {
+			return
+		}
+		var transport http.RoundTripper = plugingetter.NewHTTPTransport(g.Timeouts)
+		if g.Credentials != nil {
+			transport = &plugingetter.CredentialsTransport{Source: g.Credentials, Base: transport}
+		}
+		g.Client = &http.Client{Transport: transport}
+	})
+}
+
+// pluginPath returns the slash separated path of the folder of the plugin
+// described by opts, or an error when the plugin is not required from this
+// host.
+func (g *Getter) pluginPath(opts plugingetter.GetOptions) (string, error) {
+	id := opts.PluginRequirement.Identifier
+	if id.Hostname != g.Hostname {
+		return "", fmt.Errorf("%s is not a %s source address", id, g.Hostname)
+	}
+	return id.Namespace + "/" + id.Type + "/", nil
+}
+
+// fileURL returns the download URL of the file at path in the repository.
+func (g *Getter) fileURL(path string) string {
+	return strings.TrimSuffix(g.RepositoryURL, "/") + "/" + path
+}
+
+func (g *Getter) Get(ctx context.Context, what string, opts plugingetter.GetOptions) (io.ReadCloser, error) {
+	pluginPath, err := g.pluginPath(opts)
+	if err != nil {
+		return nil, err
+	}
+	g.initClient()
+
+	switch what {
+	case "releases":
+		files, err := g.list(ctx, pluginPath, opts.PluginRequirement.FilenamePrefix())
+		if err != nil {
+			return nil, err
+		}
+		return releases(pluginPath, files)
+	case "sha256":
+		files, err := g.list(ctx, pluginPath, opts.PluginRequirement.FilenamePrefix())
+		if err != nil {
+			return nil, err
+		}
+		sums, err := g.checksums(ctx, pluginPath+opts.Version()+"/", files)
+		if err != nil {
+			return nil, err
+		}
+		return plugingetter.TransformChecksumStream()(ioutil.NopCloser(strings.NewReader(sums)))
+	case "zip":
+		return g.download(ctx, pluginPath+opts.Version()+"/"+opts.ExpectedZipFilename(), 0)
+	default:
+		return nil, fmt.Errorf("%q not implemented", what)
+	}
+}
+
+// GetRange returns a zip file of the repository starting at offset, to resume
+// an interrupted download.
+func (g *Getter) GetRange(ctx context.Context, what string, opts plugingetter.GetOptions, offset int64) (io.ReadCloser, error) {
+	if what != "zip" {
+		return nil, fmt.Errorf("%q not implemented", what)
+	}
+	pluginPath, err := g.pluginPath(opts)
+	if err != nil {
+		return nil, err
+	}
+	g.initClient()
+	return g.download(ctx, pluginPath+opts.Version()+"/"+opts.ExpectedZipFilename(), offset)
+}
+
+// Locate returns the URL of a zip file in the repository, and its size as
+// reported by the repository manager, or -1 when it doesn't tell.
+func (g *Getter) Locate(ctx context.Context, what string, opts plugingetter.GetOptions) (string, int64, error) {
+	if what != "zip" {
+		return "", -1, fmt.Errorf("%q not implemented", what)
+	}
+	pluginPath, err := g.pluginPath(opts)
+	if err != nil {
+		return "", -1, err
+	}
+	g.initClient()
+	u := g.fileURL(pluginPath + opts.Version() + "/" + opts.ExpectedZipFilename())
+	resp, err := g.head(ctx, u)
+	if err != nil {
+		plugingetter.Logf(ctx, "[DEBUG] genericrepo-getter: could not get the size of %q: %s", u, err)
+		return u, -1, nil
+	}
+	return u, resp.ContentLength, nil
+}
+
+// releases returns the versions of the plugin that have zip files, as a json
+// list of releases. The version of a zip file is the name of its folder.
+func releases(pluginPath string, files []file) (io.ReadCloser, error) {
+	seen := map[string]bool{}
+	releases := []plugingetter.Release{}
+	for _, f := range files {
+		parts := strings.Split(strings.TrimPrefix(f.Path, pluginPath), "/")
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "v") || seen[parts[0]] {
+			continue
+		}
+		seen[parts[0]] = true
+		releases = append(releases, plugingetter.Release{Version: parts[0]})
+	}
+	out, err := json.Marshal(releases)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(out)), nil
+}
+
+// checksums returns the sha256 checksums of the zip files of the folder
+// versionPath in the format of a SHA256SUMS file. Checksums missing from the
+// listing are read from the X-Checksum-Sha256 header of the files.
+func (g *Getter) checksums(ctx context.Context, versionPath string, files []file) (string, error) {
+	var b strings.Builder
+	for _, f := range files {
+		name := strings.TrimPrefix(f.Path, versionPath)
+		if name == f.Path || strings.Contains(name, "/") {
+			continue
+		}
+		sum := f.SHA256
+		if sum == "" {
+			resp, err := g.head(ctx, g.fileURL(f.Path))
+			if err != nil {
+				return "", err
+			}
+			sum = resp.Header.Get("X-Checksum-Sha256")
+		}
+		if sum == "" {
+			plugingetter.Logf(ctx, "[DEBUG] genericrepo-getter: no sha256 checksum for %q, ignoring it", f.Path)
+			continue
+		}
+		fmt.Fprintf(&b, "%s  %s\n", sum, name)
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("no checksum found for the zip files of %s", g.fileURL(versionPath))
+	}
+	return b.String(), nil
+}
+
+// list returns the zip files of the folder pluginPath whose name starts with
+// prefix, sorted by path. Listings are kept for the lifetime of the getter.
+func (g *Getter) list(ctx context.Context, pluginPath, prefix string) ([]file, error) {
+	g.filesMu.Lock()
+	defer g.filesMu.Unlock()
+	if files, found := g.files[pluginPath]; found {
+		return files, nil
+	}
+
+	var files []file
+	var err error
+	switch g.Kind {
+	case Artifactory:
+		files, err = g.listArtifactory(ctx, pluginPath, prefix)
+	case Nexus:
+		files, err = g.listNexus(ctx, pluginPath)
+	default:
+		err = fmt.Errorf("unknown repository kind %q", g.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := []file{}
+	for _, f := range files {
+		name := f.Path[strings.LastIndex(f.Path, "/")+1:]
+		if strings.HasPrefix(f.Path, pluginPath) && strings.HasPrefix(name, prefix) && plugingetter.ArchiveExt(name) != "" {
+			res = append(res, f)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+
+	if g.files == nil {
+		g.files = map[string][]file{}
+	}
+	g.files[pluginPath] = res
+	return res, nil
+}
+
+// splitRepositoryURL returns the base URL of the repository manager and the
+// name of the repository, the last element of RepositoryURL.
+func (g *Getter) splitRepositoryURL() (string, string, error) {
+	u := strings.TrimSuffix(g.RepositoryURL, "/")
+	i := strings.LastIndex(u, "/")
+	if i < 0 || i == len(u)-1 {
+		return "", "", fmt.Errorf("invalid repository URL %q", g.RepositoryURL)
+	}
+	return u[:i], u[i+1:], nil
+}
+
+// listArtifactory lists the files of the plugin with an AQL query on the
+// Artifactory instance at <base>/api/search/aql.
+func (g *Getter) listArtifactory(ctx context.Context, pluginPath, prefix string) ([]file, error) {
+	base, repo, err := g.splitRepositoryURL()
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`items.find({"repo":%q,"path":{"$match":%q},"name":{"$match":%q}}).include("path","name","sha256")`,
+		repo, pluginPath+"*", prefix+"*")
+	u := base + "/api/search/aql"
+	req, err := g.newRequest(ctx, "POST", u, strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	var res struct {
+		Results []struct {
+			Path   string `json:"path"`
+			Name   string `json:"name"`
+			SHA256 string `json:"sha256"`
+		} `json:"results"`
+	}
+	if err := g.doJSON(req, &res); err != nil {
+		return nil, err
+	}
+	files := make([]file, 0, len(res.Results))
+	for _, r := range res.Results {
+		files = append(files, file{Path: r.Path + "/" + r.Name, SHA256: r.SHA256})
+	}
+	return files, nil
+}
+
+// listNexus lists the files of the plugin with the search API of the Nexus
+// instance at <base>/../service/rest/v1/search/assets, following
+// continuation tokens.
+func (g *Getter) listNexus(ctx context.Context, pluginPath string) ([]file, error) {
+	base, repo, err := g.splitRepositoryURL()
+	if err != nil {
+		return nil, err
+	}
+	// repositories are served under <nexus>/repository/<name>
+	base = strings.TrimSuffix(base, "/repository")
+
+	var files []file
+	token := ""
+	for {
+		q := url.Values{}
+		q.Set("repository", repo)
+		q.Set("name", pluginPath+"*")
+		if token != "" {
+			q.Set("continuationToken", token)
+		}
+		req, err := g.newRequest(ctx, "GET", base+"/service/rest/v1/search/assets?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var res struct {
+			Items []struct {
+				Path     string            `json:"path"`
+				Checksum map[string]string `json:"checksum"`
+			} `json:"items"`
+			ContinuationToken string `json:"continuationToken"`
+		}
+		if err := g.doJSON(req, &res); err != nil {
+			return nil, err
+		}
+		for _, item := range res.Items {
+			files = append(files, file{Path: strings.TrimPrefix(item.Path, "/"), SHA256: item.Checksum["sha256"]})
+		}
+		if res.ContinuationToken == "" {
+			return files, nil
+		}
+		token = res.ContinuationToken
+	}
+}
+
+func (g *Getter) doJSON(req *http.Request, v interface{}) error {
+	ctx := req.Context()
+	plugingetter.Logf(ctx, "[DEBUG] genericrepo-getter: listing %q", req.URL)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &plugingetter.StatusError{URL: req.URL.String(), StatusCode: resp.StatusCode}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid listing %s: %w", req.URL, err)
+	}
+	return nil
+}
+
+func (g *Getter) head(ctx context.Context, u string) (*http.Response, error) {
+	req, err := g.newRequest(ctx, "HEAD", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &plugingetter.StatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// download returns the content of the file at path, starting at offset.
+func (g *Getter) download(ctx context.Context, path string, offset int64) (io.ReadCloser, error) {
+	u := g.fileURL(path)
+	req, err := g.newRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	want := http.StatusOK
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		want = http.StatusPartialContent
+	}
+
+	plugingetter.Logf(ctx, "[DEBUG] genericrepo-getter: getting %q from byte %d", u, offset)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		resp.Body.Close()
+		if offset > 0 && resp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("GET %s: range not served, status %s", u, resp.Status)
+		}
+		return nil, &plugingetter.StatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+	return plugingetter.SizedBody(resp.Body, resp.ContentLength), nil
+}
+
+func (g *Getter) newRequest(ctx context.Context, method, u string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	if g.UserAgent != "" {
+		req.Header.Set("User-Agent", g.UserAgent)
+	}
+	return req, nil
+}
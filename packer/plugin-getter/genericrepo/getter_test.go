@@ -0,0 +1,116 @@
+package genericrepo
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/hcl2template/addrs"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+func happycloudOptions() plugingetter.GetOptions {
+	return plugingetter.GetOptions{
+		PluginRequirement: &plugingetter.Requirement{
+			Identifier: &addrs.Plugin{
+				Hostname:  "plugins.example.com",
+				Namespace: "hashicorp",
+				Type:      "happycloud",
+			},
+		},
+	}
+}
+
+func TestGetter_Get_releases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifactory/api/search/aql":
+			body, _ := ioutil.ReadAll(r.Body)
+			if !strings.Contains(string(body), `"repo":"packer-plugins"`) {
+				t.Errorf("unexpected query %s", body)
+			}
+			_, _ = w.Write([]byte(`{"results":[
+				{"path":"hashicorp/happycloud/v1.2.3","name":"packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip","sha256":"abc"},
+				{"path":"hashicorp/happycloud/v1.2.3","name":"packer-plugin-happycloud_v1.2.3_x5.0_darwin_amd64.zip","sha256":"def"},
+				{"path":"hashicorp/happycloud/v1.3.0","name":"packer-plugin-happycloud_v1.3.0_x5.0_linux_amd64.zip","sha256":"123"},
+				{"path":"hashicorp/happycloud/latest","name":"packer-plugin-happycloud_latest_linux_amd64.zip"}
+			]}`))
+		case "/service/rest/v1/search/assets":
+			if r.URL.Query().Get("continuationToken") == "" {
+				_, _ = w.Write([]byte(`{"items":[
+					{"path":"/hashicorp/happycloud/v1.2.3/packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip","checksum":{"sha256":"abc"}}
+				],"continuationToken":"next"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":[
+				{"path":"/hashicorp/happycloud/v1.3.0/packer-plugin-happycloud_v1.3.0_x5.0_linux_amd64.zip","checksum":{"sha256":"123"}}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, g := range []*Getter{
+		{Hostname: "plugins.example.com", Kind: Artifactory, RepositoryURL: srv.URL + "/artifactory/packer-plugins"},
+		{Hostname: "plugins.example.com", Kind: Nexus, RepositoryURL: srv.URL + "/repository/packer-plugins/"},
+	} {
+		t.Run(string(g.Kind), func(t *testing.T) {
+			rc, err := g.Get(context.Background(), "releases", happycloudOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			releases, err := plugingetter.ParseReleases(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(releases) != 2 || releases[0].Version != "v1.2.3" || releases[1].Version != "v1.3.0" {
+				t.Fatalf("unexpected releases %v", releases)
+			}
+
+			files, err := g.list(context.Background(), "hashicorp/happycloud/", "packer-plugin-happycloud_")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sums, err := g.checksums(context.Background(), "hashicorp/happycloud/v1.3.0/", files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "123  packer-plugin-happycloud_v1.3.0_x5.0_linux_amd64.zip\n"; sums != want {
+				t.Fatalf("unexpected checksums %q", sums)
+			}
+		})
+	}
+}
+
+func TestGetter_checksums_fromHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("unexpected %s request", r.Method)
+		}
+		w.Header().Set("X-Checksum-Sha256", "abc")
+	}))
+	defer srv.Close()
+
+	g := &Getter{Hostname: "plugins.example.com", Kind: Artifactory, RepositoryURL: srv.URL + "/artifactory/packer-plugins"}
+	g.initClient()
+	sums, err := g.checksums(context.Background(), "hashicorp/happycloud/v1.2.3/", []file{
+		{Path: "hashicorp/happycloud/v1.2.3/packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "abc  packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip\n"; sums != want {
+		t.Fatalf("unexpected checksums %q", sums)
+	}
+}
+
+func TestGetter_Get_otherHost(t *testing.T) {
+	g := &Getter{Hostname: "other.example.com", Kind: Nexus, RepositoryURL: "https://nexus.example.com/repository/packer-plugins"}
+	if _, err := g.Get(context.Background(), "releases", happycloudOptions()); err == nil {
+		t.Fatal("expected plugins of other hosts to be refused")
+	}
+}
@@ -0,0 +1,62 @@
+package plugingetter
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// GetterConfig holds the getter settings of a single plugin, set in the
+// getter attribute of its required_plugins entry, so that the plugins of a
+// template can be installed from different sources.
+type GetterConfig struct {
+	// MirrorURL, when set, is the URL of the network mirror the plugin is
+	// installed from, instead of the sources set by the PACKER_PLUGIN_* env
+	// vars. The mirror is laid out like the ones of
+	// PACKER_PLUGIN_NETWORK_MIRROR.
+	MirrorURL string
+
+	// TokenEnv, when set, is the name of the env var holding the token
+	// authenticating the requests to MirrorURL, so that the token itself is
+	// never written in a template.
+	TokenEnv string
+
+	// ReleasesPublicKeys, when set, are the keys the releases of the plugin
+	// must be signed with, instead of the ones of
+	// PACKER_PLUGIN_RELEASES_PUBLIC_KEYS.
+	ReleasesPublicKeys []ed25519.PublicKey
+}
+
+// Validate checks that the mirror URL is an http or https URL, and that a
+// token is only read for a mirror.
+func (c *GetterConfig) Validate() error {
+	if c.MirrorURL != "" {
+		u, err := url.Parse(c.MirrorURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("mirror %q is not an http or https URL", c.MirrorURL)
+		}
+	}
+	if c.TokenEnv != "" && c.MirrorURL == "" {
+		return fmt.Errorf("token_env can only be set along with mirror")
+	}
+	return nil
+}
+
+// TokenEnvCredentials gives the token held by the Env env var to Host, and no
+// credentials to other hosts.
+type TokenEnvCredentials struct {
+	Host string
+	Env  string
+}
+
+func (c *TokenEnvCredentials) Credentials(host string) (*Credentials, error) {
+	if host != c.Host {
+		return nil, nil
+	}
+	token := os.Getenv(c.Env)
+	if token == "" {
+		return nil, fmt.Errorf("the %s env var holding the token of %s is not set", c.Env, host)
+	}
+	return &Credentials{Token: token}, nil
+}
@@ -0,0 +1,40 @@
+package plugingetter
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetterConfig_Validate(t *testing.T) {
+	tests := []struct {
+		config  GetterConfig
+		wantErr bool
+	}{
+		{GetterConfig{}, false},
+		{GetterConfig{MirrorURL: "https://plugins.example.com/packer/", TokenEnv: "MIRROR_TOKEN"}, false},
+		{GetterConfig{MirrorURL: "plugins.example.com/packer/"}, true},
+		{GetterConfig{TokenEnv: "MIRROR_TOKEN"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%#v) = %v, want error: %t", tt.config, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTokenEnvCredentials(t *testing.T) {
+	source := &TokenEnvCredentials{Host: "plugins.example.com", Env: "PACKER_TEST_MIRROR_TOKEN"}
+	if _, err := source.Credentials("plugins.example.com"); err == nil {
+		t.Fatal("expected an error when the env var is not set")
+	}
+	os.Setenv("PACKER_TEST_MIRROR_TOKEN", "s3cr3t")
+	defer os.Unsetenv("PACKER_TEST_MIRROR_TOKEN")
+
+	creds, err := source.Credentials("plugins.example.com")
+	if err != nil || creds == nil || creds.Token != "s3cr3t" {
+		t.Fatalf("Credentials() = %v, %v", creds, err)
+	}
+	if creds, err := source.Credentials("example.com"); creds != nil || err != nil {
+		t.Fatalf("expected no credentials for another host, got %v, %v", creds, err)
+	}
+}
@@ -1,7 +1,6 @@
 package github
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,11 +8,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v33/github"
 	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
@@ -21,55 +21,60 @@ import (
 )
 
 const (
-	ghTokenAccessor  = "PACKER_GITHUB_API_TOKEN"
 	defaultUserAgent = "packer-plugin-getter"
 	defaultHostname  = "github.com"
 )
 
+// ghTokenAccessors are the env vars the token authenticating the requests to
+// github.com is read from, the first one set wins. GITHUB_TOKEN and GH_TOKEN
+// are the ones of GitHub Actions and of the gh CLI.
+var ghTokenAccessors = []string{"PACKER_GITHUB_API_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"}
+
+// defaultRateLimitRetryAfter is how long to wait before retrying a request
+// refused by the secondary rate limits of GitHub, when GitHub doesn't tell.
+const defaultRateLimitRetryAfter = time.Minute
+
 type Getter struct {
 	Client    *github.Client
 	UserAgent string
+
+	// Cache, when set, stores release lists and checksum files so that
+	// they can be fetched with conditional requests.
+	Cache *plugingetter.HTTPCache
+
+	// Timeouts of the requests, used when Client is nil.
+	Timeouts plugingetter.Timeouts
+
+	// Credentials, when set, authenticate the requests to GitHub hosts that
+	// the GITHUB_TOKEN doesn't. Used when Client is nil.
+	Credentials plugingetter.CredentialsSource
+
+	// Enterprise maps the hostnames of the GitHub Enterprise instances
+	// plugins are also installed from to the base URL of their API. An empty
+	// base URL defaults to https://<hostname>/api/v3/.
+	Enterprise map[string]string
+
+	// clientOnce guards the creation of Client, as plugins can be installed
+	// concurrently.
+	clientOnce sync.Once
+	// authenticated tells whether the requests to github.com are made with
+	// a token.
+	authenticated bool
+
+	// enterpriseClients are the clients of the Enterprise instances, by
+	// hostname.
+	enterpriseMu      sync.Mutex
+	enterpriseClients map[string]*github.Client
 }
 
-var _ plugingetter.Getter = &Getter{}
-
-func tranformChecksumStream() func(in io.ReadCloser) (io.ReadCloser, error) {
-	return func(in io.ReadCloser) (io.ReadCloser, error) {
-		defer in.Close()
-		rd := bufio.NewReader(in)
-		buffer := bytes.NewBufferString("[")
-		json := json.NewEncoder(buffer)
-		for i := 0; ; i++ {
-			line, err := rd.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					return nil, fmt.Errorf(
-						"Error reading checksum file: %s", err)
-				}
-				break
-			}
-			parts := strings.Fields(line)
-			switch len(parts) {
-			case 2: // nominal case
-				checksumString, checksumFilename := parts[0], parts[1]
+var (
+	_ plugingetter.Getter      = &Getter{}
+	_ plugingetter.Locator     = &Getter{}
+	_ plugingetter.RangeGetter = &Getter{}
+)
 
-				if i > 0 {
-					_, _ = buffer.WriteString(",")
-				}
-				if err := json.Encode(struct {
-					Checksum string `json:"checksum"`
-					Filename string `json:"filename"`
-				}{
-					Checksum: checksumString,
-					Filename: checksumFilename,
-				}); err != nil {
-					return nil, err
-				}
-			}
-		}
-		_, _ = buffer.WriteString("]")
-		return ioutil.NopCloser(buffer), nil
-	}
+func (g *Getter) String() string {
+	return "GitHub"
 }
 
 // transformVersionStream get a stream from github tags and transforms it into
@@ -154,37 +159,13 @@ func (t *HostSpecificTokenAuthTransport) base() http.RoundTripper {
 	return http.DefaultTransport
 }
 
-func (g *Getter) Get(what string, opts plugingetter.GetOptions) (io.ReadCloser, error) {
-	if opts.PluginRequirement.Identifier.Hostname != defaultHostname {
-		s := opts.PluginRequirement.Identifier.String() + " doesn't appear to be a valid " + defaultHostname + " source address; check source and try again."
-		return nil, errors.New(s)
-	}
-
-	ctx := context.TODO()
-	if g.Client == nil {
-		var tc *http.Client
-		if tk := os.Getenv(ghTokenAccessor); tk != "" {
-			log.Printf("[DEBUG] github-getter: using %s", ghTokenAccessor)
-			ts := oauth2.StaticTokenSource(
-				&oauth2.Token{AccessToken: tk},
-			)
-			tc = &http.Client{
-				Transport: &HostSpecificTokenAuthTransport{
-					TokenSources: map[string]oauth2.TokenSource{
-						"api.github.com": ts,
-					},
-				},
-			}
-		}
-		g.Client = github.NewClient(tc)
-		g.Client.UserAgent = defaultUserAgent
-		if g.UserAgent != "" {
-			g.Client.UserAgent = g.UserAgent
-		}
+func (g *Getter) Get(ctx context.Context, what string, opts plugingetter.GetOptions) (io.ReadCloser, error) {
+	client, err := g.client(ctx, opts.PluginRequirement.Identifier.Hostname)
+	if err != nil {
+		return nil, err
 	}
 
 	var req *http.Request
-	var err error
 	transform := func(in io.ReadCloser) (io.ReadCloser, error) {
 		return in, nil
 	}
@@ -192,41 +173,300 @@ func (g *Getter) Get(what string, opts plugingetter.GetOptions) (io.ReadCloser,
 	switch what {
 	case "releases":
 		u := filepath.ToSlash("/repos/" + opts.PluginRequirement.Identifier.RealRelativePath() + "/git/matching-refs/tags")
-		req, err = g.Client.NewRequest("GET", u, nil)
+		req, err = client.NewRequest("GET", u, nil)
 		transform = transformVersionStream
-	case "sha256":
-		// something like https://github.com/sylviamoss/packer-plugin-comment/releases/download/v0.2.11/packer-plugin-comment_v0.2.11_x5_SHA256SUMS
-		u := filepath.ToSlash("https://github.com/" + opts.PluginRequirement.Identifier.RealRelativePath() + "/releases/download/" + opts.Version() + "/" + opts.PluginRequirement.FilenamePrefix() + opts.Version() + "_SHA256SUMS")
-		req, err = g.Client.NewRequest(
-			"GET",
-			u,
-			nil,
-		)
-		transform = tranformChecksumStream()
 	case "zip":
-		u := filepath.ToSlash("https://github.com/" + opts.PluginRequirement.Identifier.RealRelativePath() + "/releases/download/" + opts.Version() + "/" + opts.ExpectedZipFilename())
-		req, err = g.Client.NewRequest(
-			"GET",
-			u,
-			nil,
-		)
+		req, err = g.newAssetRequest(ctx, client, opts, opts.ExpectedZipFilename())
 
 	default:
-		return nil, fmt.Errorf("%q not implemented", what)
+		if !plugingetter.IsChecksumType(what) {
+			return nil, fmt.Errorf("%q not implemented", what)
+		}
+		// something like https://github.com/sylviamoss/packer-plugin-comment/releases/download/v0.2.11/packer-plugin-comment_v0.2.11_x5_SHA256SUMS
+		req, err = g.newAssetRequest(ctx, client, opts, opts.PluginRequirement.FilenamePrefix()+opts.Version()+plugingetter.ChecksumFileSuffix(what))
+		transform = plugingetter.TransformChecksumStream()
 	}
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("[DEBUG] github-getter: getting %q", req.URL)
-	resp, err := g.Client.BareDo(ctx, req)
+	// zip files are checksummed and can be big, only small documents are
+	// cached.
+	cacheable := what == "releases" || plugingetter.IsChecksumType(what)
+	var cached *plugingetter.CachedResponse
+	if cacheable {
+		cached = g.Cache.Get(ctx, req.URL.String())
+		cached.SetConditionalHeaders(req)
+	}
+
+	plugingetter.Logf(ctx, "[DEBUG] github-getter: getting %q", req.URL)
+	resp, err := client.BareDo(ctx, req)
 	if err != nil {
 		// here BareDo will return an err if the request failed or if the
 		// status is not considered a valid http status.
 		if resp != nil {
 			resp.Body.Close()
+			if resp.StatusCode == http.StatusNotModified && cached != nil {
+				plugingetter.Logf(ctx, "[DEBUG] github-getter: %q not modified, using cached response", req.URL)
+				return transform(ioutil.NopCloser(bytes.NewReader(cached.Body)))
+			}
+			return nil, g.statusError(ctx, req.URL.String(), resp, err)
 		}
 		return nil, err
 	}
+	logRate(ctx, resp)
 
-	return transform(resp.Body)
+	if what == "zip" {
+		return plugingetter.SizedBody(resp.Body, resp.ContentLength), nil
+	}
+	if !cacheable || g.Cache == nil {
+		return transform(resp.Body)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Cache.Put(req.URL.String(), resp.Header, body); err != nil {
+		plugingetter.Logf(ctx, "[DEBUG] github-getter: could not cache response of %q: %s", req.URL, err)
+	}
+	return transform(ioutil.NopCloser(bytes.NewReader(body)))
+}
+
+func (g *Getter) initClient(ctx context.Context) {
+	g.clientOnce.Do(func() { g.newClient(ctx) })
+}
+
+// client returns the client of the GitHub instance at hostname.
+func (g *Getter) client(ctx context.Context, hostname string) (*github.Client, error) {
+	if hostname == defaultHostname {
+		g.initClient(ctx)
+		return g.Client, nil
+	}
+	baseURL, found := g.Enterprise[hostname]
+	if !found {
+		return nil, fmt.Errorf("%s doesn't appear to be a valid %s or GitHub Enterprise hostname; check source and try again.", hostname, defaultHostname)
+	}
+
+	g.enterpriseMu.Lock()
+	defer g.enterpriseMu.Unlock()
+	if client, found := g.enterpriseClients[hostname]; found {
+		return client, nil
+	}
+	if baseURL == "" {
+		baseURL = "https://" + hostname + "/api/v3/"
+	}
+	// uploads are never made, the upload URL is only required to create the
+	// client.
+	client, err := github.NewEnterpriseClient(baseURL, baseURL, g.httpClient())
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q for GitHub Enterprise host %s: %w", baseURL, hostname, err)
+	}
+	client.UserAgent = g.userAgent()
+	if g.enterpriseClients == nil {
+		g.enterpriseClients = map[string]*github.Client{}
+	}
+	g.enterpriseClients[hostname] = client
+	return client, nil
+}
+
+// httpClient returns the http client of a GitHub Enterprise instance. Tokens
+// for these are set like for any other plugin host, see
+// plugingetter.EnvCredentials.
+func (g *Getter) httpClient() *http.Client {
+	tc := &http.Client{
+		Transport: plugingetter.NewHTTPTransport(g.Timeouts),
+	}
+	if g.Credentials != nil {
+		tc.Transport = &plugingetter.CredentialsTransport{Source: g.Credentials, Base: tc.Transport}
+	}
+	return tc
+}
+
+// githubToken returns the token authenticating the requests to github.com,
+// and the env var it was read from.
+func githubToken() (string, string) {
+	for _, accessor := range ghTokenAccessors {
+		if tk := os.Getenv(accessor); tk != "" {
+			return accessor, tk
+		}
+	}
+	return "", ""
+}
+
+// statusError returns the error of a request to u that failed with resp.
+// When a rate limit was exceeded, the error tells when it resets, and the
+// request is retried then.
+func (g *Getter) statusError(ctx context.Context, u string, resp *github.Response, err error) error {
+	statusErr := &plugingetter.StatusError{URL: u, StatusCode: resp.StatusCode, Err: err}
+
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		statusErr.RetryAfter = time.Until(rateErr.Rate.Reset.Time)
+		if statusErr.RetryAfter < time.Second {
+			statusErr.RetryAfter = time.Second
+		}
+		hint := ""
+		if resp.Request != nil && resp.Request.URL.Host == "api.github.com" && !g.authenticated {
+			hint = fmt.Sprintf("; set one of %s to a GitHub token to raise it", strings.Join(ghTokenAccessors, ", "))
+		}
+		statusErr.Err = fmt.Errorf("GitHub API rate limit of %d requests per hour exceeded, it resets at %s%s: %w",
+			rateErr.Rate.Limit, rateErr.Rate.Reset.Format(time.RFC3339), hint, err)
+	case errors.As(err, &abuseErr):
+		statusErr.RetryAfter = defaultRateLimitRetryAfter
+		if abuseErr.RetryAfter != nil {
+			statusErr.RetryAfter = *abuseErr.RetryAfter
+		}
+		statusErr.Err = fmt.Errorf("GitHub API secondary rate limit exceeded, retry in %s: %w", statusErr.RetryAfter, err)
+	}
+	if statusErr.RetryAfter > 0 {
+		plugingetter.Logf(ctx, "[DEBUG] github-getter: %s", statusErr.Err)
+	}
+	return statusErr
+}
+
+// logRate logs the rate limit of the GitHub API reported by resp, when it
+// reports one.
+func logRate(ctx context.Context, resp *github.Response) {
+	if resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+	plugingetter.Logf(ctx, "[DEBUG] github-getter: %d/%d API requests left, resetting at %s",
+		resp.Rate.Remaining, resp.Rate.Limit, resp.Rate.Reset.Format(time.RFC3339))
+}
+
+func (g *Getter) userAgent() string {
+	if g.UserAgent != "" {
+		return g.UserAgent
+	}
+	return defaultUserAgent
+}
+
+func (g *Getter) newClient(ctx context.Context) {
+	if g.Client != nil {
+		return
+	}
+	tc := g.httpClient()
+	if accessor, tk := githubToken(); tk != "" {
+		plugingetter.Logf(ctx, "[DEBUG] github-getter: using %s", accessor)
+		ts := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: tk},
+		)
+		tc.Transport = &HostSpecificTokenAuthTransport{
+			TokenSources: map[string]oauth2.TokenSource{
+				"api.github.com": ts,
+			},
+			Base: tc.Transport,
+		}
+		g.authenticated = true
+	}
+	g.Client = github.NewClient(tc)
+	g.Client.UserAgent = g.userAgent()
+}
+
+// newAssetRequest returns a request getting the release asset filename.
+// Assets of github.com releases are downloaded from their public URL. On
+// GitHub Enterprise, where repositories are often private and public URLs
+// require a browser session, they are downloaded through the API instead.
+func (g *Getter) newAssetRequest(ctx context.Context, client *github.Client, opts plugingetter.GetOptions, filename string) (*http.Request, error) {
+	if opts.PluginRequirement.Identifier.Hostname == defaultHostname {
+		return client.NewRequest("GET", releaseDownloadURL(opts, filename), nil)
+	}
+	asset, err := g.releaseAsset(ctx, client, opts, filename)
+	if err != nil {
+		return nil, err
+	}
+	owner, repo := ownerAndRepo(opts)
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/releases/assets/%d", owner, repo, asset.GetID()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	return req, nil
+}
+
+// releaseAsset returns the asset filename of the release of opts.
+func (g *Getter) releaseAsset(ctx context.Context, client *github.Client, opts plugingetter.GetOptions, filename string) (*github.ReleaseAsset, error) {
+	owner, repo := ownerAndRepo(opts)
+	release, resp, err := client.Repositories.GetReleaseByTag(ctx, owner, repo, opts.Version())
+	if err != nil {
+		if resp != nil {
+			return nil, g.statusError(ctx, resp.Request.URL.String(), resp, err)
+		}
+		return nil, err
+	}
+	logRate(ctx, resp)
+	for _, asset := range release.Assets {
+		if asset.GetName() == filename {
+			return asset, nil
+		}
+	}
+	return nil, fmt.Errorf("release %s of %s has no %s asset", opts.Version(), opts.PluginRequirement.Identifier, filename)
+}
+
+// ownerAndRepo returns the GitHub owner and repository of the plugin of opts.
+func ownerAndRepo(opts plugingetter.GetOptions) (string, string) {
+	parts := strings.SplitN(opts.PluginRequirement.Identifier.RealRelativePath(), "/", 2)
+	return parts[0], parts[1]
+}
+
+// GetRange returns a zip file of a release starting at offset, to resume an
+// interrupted download.
+func (g *Getter) GetRange(ctx context.Context, what string, opts plugingetter.GetOptions, offset int64) (io.ReadCloser, error) {
+	if what != "zip" {
+		return nil, fmt.Errorf("%q not implemented", what)
+	}
+	client, err := g.client(ctx, opts.PluginRequirement.Identifier.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	req, err := g.newAssetRequest(ctx, client, opts, opts.ExpectedZipFilename())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+	plugingetter.Logf(ctx, "[DEBUG] github-getter: getting %q from byte %d", req.URL, offset)
+	resp, err := client.BareDo(ctx, req)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+			return nil, g.statusError(ctx, req.URL.String(), resp, err)
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: range not served, status %s", req.URL, resp.Status)
+	}
+	return plugingetter.SizedBody(resp.Body, resp.ContentLength), nil
+}
+
+// releaseDownloadURL returns the public download URL of a release asset,
+// something like
+// https://github.com/sylviamoss/packer-plugin-comment/releases/download/v0.2.11/packer-plugin-comment_v0.2.11_x5.0_darwin_amd64.zip
+func releaseDownloadURL(opts plugingetter.GetOptions, filename string) string {
+	return filepath.ToSlash("https://" + opts.PluginRequirement.Identifier.Hostname + "/" + opts.PluginRequirement.Identifier.RealRelativePath() + "/releases/download/" + opts.Version() + "/" + filename)
+}
+
+// Locate returns the download URL of a zip file, and its size as reported by
+// the release assets of the GitHub API.
+func (g *Getter) Locate(ctx context.Context, what string, opts plugingetter.GetOptions) (string, int64, error) {
+	if what != "zip" {
+		return "", -1, fmt.Errorf("%q not implemented", what)
+	}
+	client, err := g.client(ctx, opts.PluginRequirement.Identifier.Hostname)
+	if err != nil {
+		return "", -1, err
+	}
+	u := releaseDownloadURL(opts, opts.ExpectedZipFilename())
+
+	asset, err := g.releaseAsset(ctx, client, opts, opts.ExpectedZipFilename())
+	if err != nil {
+		plugingetter.Logf(ctx, "[DEBUG] github-getter: could not get release asset: %s", err)
+		return u, -1, nil
+	}
+	return u, int64(asset.GetSize()), nil
 }
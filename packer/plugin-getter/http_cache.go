@@ -0,0 +1,110 @@
+package plugingetter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// HTTPCache persists the responses of small, frequently fetched documents,
+// like release lists and checksum files, along with their ETag and
+// Last-Modified headers. This allows getters to send conditional requests and
+// to reuse the cached body when the server answers 304 Not Modified.
+type HTTPCache struct {
+	// Dir is the directory in which responses are stored. It is created when
+	// needed.
+	Dir string
+}
+
+// CachedResponse is a response stored in an HTTPCache.
+type CachedResponse struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+func (c *HTTPCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the cached response for url, or nil when there is none.
+func (c *HTTPCache) Get(ctx context.Context, url string) *CachedResponse {
+	if c == nil || c.Dir == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(c.path(url))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Logf(ctx, "[TRACE] http cache: could not read entry for %q: %s", url, err)
+		}
+		return nil
+	}
+	res := &CachedResponse{}
+	if err := json.Unmarshal(b, res); err != nil || res.URL != url {
+		Logf(ctx, "[TRACE] http cache: ignoring invalid entry for %q", url)
+		return nil
+	}
+	return res
+}
+
+// Put stores the response to a request on url, when it carries an ETag or a
+// Last-Modified header.
+func (c *HTTPCache) Put(url string, header http.Header, body []byte) error {
+	if c == nil || c.Dir == "" {
+		return nil
+	}
+	res := &CachedResponse{
+		URL:          url,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Body:         body,
+	}
+	if res.ETag == "" && res.LastModified == "" {
+		return nil
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("Failed to create http cache directory: %s", err)
+	}
+
+	// write to a temporary file first so that concurrent readers never
+	// see a partial entry.
+	tmp, err := ioutil.TempFile(c.Dir, ".entry.*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(url))
+}
+
+// SetConditionalHeaders sets the If-None-Match and If-Modified-Since headers
+// of req from the cached response.
+func (r *CachedResponse) SetConditionalHeaders(req *http.Request) {
+	if r == nil {
+		return
+	}
+	if r.ETag != "" {
+		req.Header.Set("If-None-Match", r.ETag)
+	}
+	if r.LastModified != "" {
+		req.Header.Set("If-Modified-Since", r.LastModified)
+	}
+}
@@ -0,0 +1,64 @@
+package plugingetter
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHTTPCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-http-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := &HTTPCache{Dir: dir}
+	url := "https://api.github.com/repos/hashicorp/packer-plugin-amazon/git/matching-refs/tags"
+
+	if got := cache.Get(context.Background(), url); got != nil {
+		t.Fatalf("expected no cached response, got %#v", got)
+	}
+
+	if err := cache.Put(url, http.Header{}, []byte("no validators")); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.Get(context.Background(), url); got != nil {
+		t.Fatalf("responses without validators should not be cached, got %#v", got)
+	}
+
+	header := http.Header{}
+	header.Set("ETag", `W/"abc"`)
+	header.Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+	if err := cache.Put(url, header, []byte(`[{"version":"v1.0.0"}]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := cache.Get(context.Background(), url)
+	want := &CachedResponse{
+		URL:          url,
+		ETag:         `W/"abc"`,
+		LastModified: "Wed, 21 Oct 2015 07:28:00 GMT",
+		Body:         []byte(`[{"version":"v1.0.0"}]`),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected cached response: %s", diff)
+	}
+
+	req, _ := http.NewRequest("GET", url, nil)
+	got.SetConditionalHeaders(req)
+	if req.Header.Get("If-None-Match") != `W/"abc"` {
+		t.Errorf("unexpected If-None-Match header: %q", req.Header.Get("If-None-Match"))
+	}
+	if req.Header.Get("If-Modified-Since") != "Wed, 21 Oct 2015 07:28:00 GMT" {
+		t.Errorf("unexpected If-Modified-Since header: %q", req.Header.Get("If-Modified-Since"))
+	}
+
+	if got := cache.Get(context.Background(), url+"?other"); got != nil {
+		t.Fatalf("expected no cached response for another url, got %#v", got)
+	}
+}
@@ -0,0 +1,72 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// DefaultInstallParallelism is the number of plugins installed at the same
+// time by InstallAll when no parallelism is given.
+const DefaultInstallParallelism = 4
+
+// InstallResult is the outcome of the installation of a requirement by
+// InstallAll.
+type InstallResult struct {
+	Requirement  *Requirement
+	Installation *Installation
+	Err          error
+}
+
+// InstallAll installs the latest version of every requirement with
+// InstallLatest, parallelism of them at a time. Once ctx is done, the
+// installations in progress are cancelled and the others are not started.
+//
+// opts returns the options to install a requirement with. It is called from
+// the goroutine installing the requirement, and must return new
+// Checksummers every time, as their hashes can't be shared between
+// installations. onDone, when set, is called once a requirement is
+// installed, or failed to be, to report progress; calls to onDone are never
+// concurrent.
+//
+// Results are returned in the order of reqs, along with an error aggregating
+// the errors of the installations that failed.
+func (reqs Requirements) InstallAll(ctx context.Context, parallelism int, opts func(*Requirement) InstallOptions, onDone func(InstallResult)) ([]InstallResult, error) {
+	if parallelism < 1 {
+		parallelism = DefaultInstallParallelism
+	}
+
+	results := make([]InstallResult, len(reqs))
+	sem := make(chan struct{}, parallelism)
+	var (
+		wg     sync.WaitGroup
+		doneMu sync.Mutex
+	)
+	for i, pr := range reqs {
+		wg.Add(1)
+		go func(i int, pr *Requirement) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			install, err := pr.InstallLatest(ctx, opts(pr))
+			results[i] = InstallResult{Requirement: pr, Installation: install, Err: err}
+			if onDone != nil {
+				doneMu.Lock()
+				onDone(results[i])
+				doneMu.Unlock()
+			}
+		}(i, pr)
+	}
+	wg.Wait()
+
+	var errs *multierror.Error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", res.Requirement.Identifier, res.Err))
+		}
+	}
+	return results, errs.ErrorOrNil()
+}
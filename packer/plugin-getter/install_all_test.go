@@ -0,0 +1,97 @@
+package plugingetter
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestRequirements_InstallAll(t *testing.T) {
+	var reqs Requirements
+	for _, req := range [][2]string{
+		{"amazon", "v1.2.3"},
+		{"google", ">= v9"},
+		{"amazon", "v1.2.3"},
+	} {
+		identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/" + req[0])
+		if len(diags) != 0 {
+			t.Fatalf("ParsePluginSourceString(%q): %v", req[0], diags)
+		}
+		reqs = append(reqs, &Requirement{
+			Identifier:         identifier,
+			VersionConstraints: version.MustConstraints(version.NewConstraint(req[1])),
+		})
+	}
+
+	opts := func(pr *Requirement) InstallOptions {
+		return InstallOptions{
+			Getters: []Getter{
+				&mockPluginGetter{
+					Releases: []Release{
+						{Version: "v1.2.3"},
+					},
+					ChecksumFileEntries: map[string][]ChecksumFileEntry{
+						"1.2.3": {{
+							Filename: "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64.zip",
+							Checksum: "1337c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+						}},
+					},
+				},
+			},
+			InFolders: []string{
+				pluginFolderWrongChecksums,
+				pluginFolderOne,
+				pluginFolderTwo,
+			},
+			BinaryInstallationOptions: BinaryInstallationOptions{
+				APIVersionMajor: "5", APIVersionMinor: "0",
+				OS: "darwin", ARCH: "amd64",
+				Checksummers: []Checksummer{
+					{Type: "sha256", Hash: sha256.New()},
+				},
+			},
+		}
+	}
+
+	var done []string
+	results, err := reqs.InstallAll(context.Background(), 2, opts, func(res InstallResult) {
+		done = append(done, res.Requirement.Identifier.Type)
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "github.com/hashicorp/google") {
+		t.Fatalf("expected an error about the google plugin, got %v", err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("expected %d results, got %d", len(reqs), len(results))
+	}
+	for i, res := range results {
+		if res.Requirement != reqs[i] {
+			t.Errorf("result %d is for %s, expected %s", i, res.Requirement.Identifier, reqs[i].Identifier)
+		}
+		if wantErr := res.Requirement.Identifier.Type == "google"; (res.Err != nil) != wantErr {
+			t.Errorf("result %d: error = %v, wantErr %v", i, res.Err, wantErr)
+		}
+		if res.Installation != nil {
+			t.Errorf("result %d: the plugin is already installed, got %#v", i, res.Installation)
+		}
+	}
+	sort.Strings(done)
+	if got := strings.Join(done, ","); got != "amazon,amazon,google" {
+		t.Errorf("onDone was called for %s", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, _ = reqs.InstallAll(ctx, 2, opts, nil)
+	for i, res := range results {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("result %d: expected the installation to be cancelled, got %v", i, res.Err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package plugingetter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InstallSummaryDirName is the name of the directory, in the Packer config
+// directory, in which `packer init` writes install summaries.
+const InstallSummaryDirName = "plugin_summaries"
+
+// InstallSummary records the binaries `packer init` resolved for the plugin
+// requirements of a configuration, so that other commands can use them
+// without listing and checksumming every installed binary again.
+type InstallSummary struct {
+	// Fingerprint identifies the requirements, plugin folders, platform and
+	// lock file the binaries were resolved for, see Fingerprint.
+	Fingerprint string `json:"fingerprint"`
+	// Plugins are the resolved binaries, by requirement accessor.
+	Plugins map[string]SummarizedInstallation `json:"plugins"`
+}
+
+// SummarizedInstallation is a binary resolved for a requirement, along with
+// the size and modification time it had when it was resolved.
+type SummarizedInstallation struct {
+	Source     string    `json:"source"`
+	Version    string    `json:"version"`
+	BinaryPath string    `json:"binary_path"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+}
+
+// Summarize returns the summarized installation of install, for pr.
+func Summarize(pr *Requirement, install *Installation) (SummarizedInstallation, error) {
+	fi, err := os.Stat(install.BinaryPath)
+	if err != nil {
+		return SummarizedInstallation{}, err
+	}
+	return SummarizedInstallation{
+		Source:     pr.Identifier.String(),
+		Version:    install.Version,
+		BinaryPath: install.BinaryPath,
+		Size:       fi.Size(),
+		ModTime:    fi.ModTime().UTC(),
+	}, nil
+}
+
+// Fingerprint identifies everything binaries are resolved from: the
+// requirements, the folders binaries are listed from, the platform and
+// protocol version of Packer, and the lock file, when there is one.
+func Fingerprint(reqs Requirements, opts ListInstallationsOptions, lockFile *LockFile) string {
+	h := sha256.New()
+	for _, pr := range reqs {
+		fmt.Fprintf(h, "requirement %q %q %q %t\n", pr.Accessor, pr.Identifier, pr.VersionConstraints.String(), pr.Latest)
+	}
+	for _, folder := range opts.FromFolders {
+		fmt.Fprintf(h, "folder %q\n", folder)
+	}
+	fmt.Fprintf(h, "platform %q %q %q %q %q\n", opts.OS, opts.ARCH, opts.APIVersionMajor, opts.APIVersionMinor, opts.Ext)
+	if lockFile != nil {
+		_, _ = h.Write(lockFile.Bytes())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Resolved returns the binaries recorded for reqs, by accessor, when every
+// requirement has one and none of them changed since it was recorded. Only
+// the size and modification time of binaries are checked, their checksums
+// were verified when the summary was written.
+func (s *InstallSummary) Resolved(reqs Requirements) (map[string]string, bool) {
+	if s == nil {
+		return nil, false
+	}
+	paths := map[string]string{}
+	for _, pr := range reqs {
+		install, found := s.Plugins[pr.Accessor]
+		if !found || install.Source != pr.Identifier.String() {
+			return nil, false
+		}
+		fi, err := os.Stat(install.BinaryPath)
+		if err != nil || fi.Size() != install.Size || !fi.ModTime().Equal(install.ModTime) {
+			log.Printf("[TRACE] install summary: %q changed since it was recorded", install.BinaryPath)
+			return nil, false
+		}
+		paths[pr.Accessor] = install.BinaryPath
+	}
+	return paths, true
+}
+
+// InstallSummaryCache stores install summaries by fingerprint.
+type InstallSummaryCache struct {
+	// Dir is the directory in which summaries are stored. It is created
+	// when needed.
+	Dir string
+}
+
+func (c *InstallSummaryCache) path(fingerprint string) string {
+	return filepath.Join(c.Dir, fingerprint+".json")
+}
+
+// Get returns the summary stored for fingerprint, or nil when there is none.
+func (c *InstallSummaryCache) Get(fingerprint string) *InstallSummary {
+	if c == nil || c.Dir == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(c.path(fingerprint))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[TRACE] install summary: could not read %q: %s", fingerprint, err)
+		}
+		return nil
+	}
+	s := &InstallSummary{}
+	if err := json.Unmarshal(b, s); err != nil || s.Fingerprint != fingerprint {
+		log.Printf("[TRACE] install summary: ignoring invalid summary %q", fingerprint)
+		return nil
+	}
+	return s
+}
+
+// Put stores s.
+func (c *InstallSummaryCache) Put(s *InstallSummary) error {
+	if c == nil || c.Dir == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("Failed to create install summary directory: %s", err)
+	}
+	return ioutil.WriteFile(c.path(s.Fingerprint), b, 0644)
+}
@@ -0,0 +1,77 @@
+package plugingetter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestInstallSummaryCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-install-summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64")
+	if err := ioutil.WriteFile(binary, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	reqs := Requirements{{
+		Accessor:           "amazon",
+		Identifier:         identifier,
+		VersionConstraints: version.MustConstraints(version.NewConstraint(">= 1.0.0")),
+	}}
+	opts := ListInstallationsOptions{
+		FromFolders: []string{dir},
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			APIVersionMajor: "5", APIVersionMinor: "0",
+			OS: "linux", ARCH: "amd64",
+		},
+	}
+	lockFile := &LockFile{}
+
+	fingerprint := Fingerprint(reqs, opts, lockFile)
+	lockFile.Lock(&LockedPlugin{Identifier: identifier, Version: version.Must(version.NewVersion("1.0.0"))})
+	if Fingerprint(reqs, opts, lockFile) == fingerprint {
+		t.Fatal("the fingerprint should change with the lock file")
+	}
+	fingerprint = Fingerprint(reqs, opts, lockFile)
+
+	summarized, err := Summarize(reqs[0], &Installation{BinaryPath: binary, Version: "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &InstallSummaryCache{Dir: filepath.Join(dir, InstallSummaryDirName)}
+	if cache.Get(fingerprint) != nil {
+		t.Fatal("expected no summary")
+	}
+	err = cache.Put(&InstallSummary{
+		Fingerprint: fingerprint,
+		Plugins:     map[string]SummarizedInstallation{"amazon": summarized},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, ok := cache.Get(fingerprint).Resolved(reqs)
+	if !ok || paths["amazon"] != binary {
+		t.Fatalf("Resolved() = %v, %t", paths, ok)
+	}
+
+	// a binary that changed since init is not used
+	if err := ioutil.WriteFile(binary, []byte("v1 patched"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if paths, ok := cache.Get(fingerprint).Resolved(reqs); ok {
+		t.Fatalf("Resolved() = %v, expected the summary to be outdated", paths)
+	}
+}
@@ -0,0 +1,323 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// LockFileName is the name of the lock file written by `packer init` next to
+// the configuration it installed plugins for.
+const LockFileName = ".packer.lock.hcl"
+
+const lockFileHeader = `# This file is maintained automatically by "packer init".
+# Manual edits may be lost in future updates.
+`
+
+// LockFile records the versions of plugins resolved by `packer init`, along
+// with the checksums of their release files, so that the same versions are
+// installed and used on every machine. The file looks like:
+//
+//	plugin "github.com/hashicorp/amazon" {
+//	  version     = "1.0.0"
+//	  constraints = ">= 1.0.0"
+//	  hashes = [
+//	    "sha256:2d7a0e...",
+//	  ]
+//	}
+//
+// A plugin installed from the source address it was redirected to also
+// records the one required, as redirected_from.
+type LockFile struct {
+	Plugins []*LockedPlugin
+}
+
+// LockedPlugin is the version of a plugin recorded in a LockFile.
+type LockedPlugin struct {
+	Identifier *addrs.Plugin
+	Version    *version.Version
+	// Constraints are the version constraints the version was resolved
+	// with, informative only.
+	Constraints string
+	// Hashes are the checksums of the zip files of the release, for every
+	// platform, like "sha256:<hex>". Any of them must match the checksum of a
+	// zip file being installed.
+	Hashes []string
+	// RedirectedFrom is the source address the plugin was required with,
+	// when it was redirected to Identifier.
+	RedirectedFrom *addrs.Plugin
+}
+
+// LockFilePath returns the path of the lock file of the configuration at
+// path, a file or a directory.
+func LockFilePath(path string) string {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return filepath.Join(path, LockFileName)
+	}
+	return filepath.Join(filepath.Dir(path), LockFileName)
+}
+
+// ReadLockFile reads the lock file at path. An empty LockFile is returned
+// when there is none.
+func ReadLockFile(path string) (*LockFile, error) {
+	lf := &LockFile{}
+	src, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, diags := hclparse.NewParser().ParseHCL(src, path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var content struct {
+		Plugins []struct {
+			Source      string    `hcl:"source,label"`
+			Version     string    `hcl:"version"`
+			Constraints string    `hcl:"constraints,optional"`
+			Hashes      []string  `hcl:"hashes,optional"`
+			Redirected  string    `hcl:"redirected_from,optional"`
+			Range       hcl.Range `hcl:",def_range"`
+		} `hcl:"plugin,block"`
+	}
+	if diags := gohcl.DecodeBody(f.Body, nil, &content); diags.HasErrors() {
+		return nil, diags
+	}
+
+	for _, p := range content.Plugins {
+		id, diags := addrs.ParsePluginSourceString(p.Source)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%s: %s", p.Range, diags)
+		}
+		if lf.Plugin(id) != nil {
+			return nil, fmt.Errorf("%s: plugin %s is locked more than once", p.Range, id)
+		}
+		v, err := version.NewVersion(p.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version of plugin %s: %s", p.Range, id, err)
+		}
+		for _, h := range p.Hashes {
+			if !strings.Contains(h, ":") {
+				return nil, fmt.Errorf("%s: invalid hash %q of plugin %s, expected something like sha256:<hex>", p.Range, h, id)
+			}
+		}
+		locked := &LockedPlugin{
+			Identifier:  id,
+			Version:     v,
+			Constraints: p.Constraints,
+			Hashes:      p.Hashes,
+		}
+		if p.Redirected != "" {
+			if locked.RedirectedFrom, diags = addrs.ParsePluginSourceString(p.Redirected); diags.HasErrors() {
+				return nil, fmt.Errorf("%s: invalid redirected_from of plugin %s: %s", p.Range, id, diags)
+			}
+		}
+		lf.Plugins = append(lf.Plugins, locked)
+	}
+	return lf, nil
+}
+
+// Bytes returns the content of the lock file, plugins sorted by source.
+func (lf *LockFile) Bytes() []byte {
+	plugins := append([]*LockedPlugin{}, lf.Plugins...)
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Identifier.String() < plugins[j].Identifier.String()
+	})
+
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for _, p := range plugins {
+		body.AppendNewline()
+		block := body.AppendNewBlock("plugin", []string{p.Identifier.String()}).Body()
+		block.SetAttributeValue("version", cty.StringVal(p.Version.String()))
+		if p.Constraints != "" {
+			block.SetAttributeValue("constraints", cty.StringVal(p.Constraints))
+		}
+		if p.RedirectedFrom != nil {
+			block.SetAttributeValue("redirected_from", cty.StringVal(p.RedirectedFrom.String()))
+		}
+		if len(p.Hashes) > 0 {
+			// one hash per line, for readable diffs
+			toks := hclwrite.Tokens{
+				{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")},
+				{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+			}
+			for _, h := range p.Hashes {
+				toks = append(toks, hclwrite.TokensForValue(cty.StringVal(h))...)
+				toks = append(toks,
+					&hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")},
+					&hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+			}
+			toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]")})
+			block.SetAttributeRaw("hashes", toks)
+		}
+	}
+	return append([]byte(lockFileHeader), hclwrite.Format(f.Bytes())...)
+}
+
+// WriteFile writes the lock file to path.
+func (lf *LockFile) WriteFile(path string) error {
+	return ioutil.WriteFile(path, lf.Bytes(), 0644)
+}
+
+// Plugin returns the locked version of the plugin id, nil when it isn't
+// locked.
+func (lf *LockFile) Plugin(id *addrs.Plugin) *LockedPlugin {
+	for _, p := range lf.Plugins {
+		if p.Identifier.String() == id.String() {
+			return p
+		}
+	}
+	return nil
+}
+
+// RedirectedFrom returns the locked plugin the plugin id was redirected to,
+// nil when it wasn't or when lf is nil.
+func (lf *LockFile) RedirectedFrom(id *addrs.Plugin) *LockedPlugin {
+	if lf == nil {
+		return nil
+	}
+	for _, p := range lf.Plugins {
+		if p.RedirectedFrom != nil && p.RedirectedFrom.String() == id.String() {
+			return p
+		}
+	}
+	return nil
+}
+
+// Lock records p, replacing any previously locked version of the plugin.
+func (lf *LockFile) Lock(p *LockedPlugin) {
+	for i, existing := range lf.Plugins {
+		if existing.Identifier.String() == p.Identifier.String() {
+			lf.Plugins[i] = p
+			return
+		}
+	}
+	lf.Plugins = append(lf.Plugins, p)
+}
+
+// Unlock removes the locked version of the plugin id, and tells whether
+// there was one.
+func (lf *LockFile) Unlock(id *addrs.Plugin) bool {
+	for i, existing := range lf.Plugins {
+		if existing.Identifier.String() == id.String() {
+			lf.Plugins = append(lf.Plugins[:i], lf.Plugins[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the locked version still satisfies the version
+// constraints of pr.
+func (p *LockedPlugin) Validate(pr *Requirement) error {
+	if c := pr.excludedBy(p.Version); c != nil {
+		return fmt.Errorf("the %s plugin is locked at version %s, which is excluded by the %q constraint; "+
+			"run packer init -upgrade to update the lock file", pr.Identifier, p.Version, c)
+	}
+	if !pr.Allows(p.Version) && p.Version.Prerelease() != "" && !pr.IncludePrereleases {
+		return fmt.Errorf("the %s plugin is locked at the pre-release %s, which is not allowed; "+
+			"set include_prereleases or run packer init -upgrade to update the lock file", pr.Identifier, p.Version)
+	}
+	if !pr.Allows(p.Version) {
+		return fmt.Errorf("the %s plugin is locked at version %s, which doesn't match the constraint(s) %q; "+
+			"run packer init -upgrade to update the lock file", pr.Identifier, p.Version, pr.VersionConstraints.String())
+	}
+	return nil
+}
+
+// Select returns the installation of the locked version, nil when it isn't
+// installed.
+func (p *LockedPlugin) Select(installs InstallList) *Installation {
+	for _, install := range installs {
+		if install.Version == "v"+p.Version.String() {
+			return install
+		}
+	}
+	return nil
+}
+
+// allows tells whether v can be installed, any version can be installed when
+// p is nil.
+func (p *LockedPlugin) allows(v *version.Version) bool {
+	return p == nil || sameVersion(p.Version, v)
+}
+
+// sameVersion tells whether a and b are the same version of a plugin. Build
+// metadata is ignored when comparing semantic versions, but v1.2.3 and
+// v1.2.3+ent are different builds.
+func sameVersion(a, b *version.Version) bool {
+	return a.Equal(b) && a.Metadata() == b.Metadata()
+}
+
+// verifyHash checks that the checksum of a release file is one of the locked
+// hashes. Any checksum is valid when p is nil or has no hashes.
+func (p *LockedPlugin) verifyHash(checksumType, checksum string) error {
+	if p == nil || len(p.Hashes) == 0 {
+		return nil
+	}
+	h := checksumType + ":" + strings.ToLower(checksum)
+	for _, locked := range p.Hashes {
+		if locked == h {
+			return nil
+		}
+	}
+	return fmt.Errorf("the %s checksum %s of the %s plugin %s doesn't match any of the hashes of the lock file",
+		checksumType, checksum, p.Identifier, p.Version)
+}
+
+// ReleaseHashes returns the checksums of the zip files of version v of the
+// plugin, for every platform, as recorded in a LockFile.
+func (pr *Requirement) ReleaseHashes(ctx context.Context, v *version.Version, opts InstallOptions) ([]string, error) {
+	if opts.Offline {
+		return nil, fmt.Errorf("%w: can't get the checksums of the %s plugin %s", ErrOffline, pr.Identifier, v)
+	}
+	var errs []string
+	for _, getter := range opts.Getters {
+		for _, checksummer := range opts.Checksummers {
+			checksumFile, err := getter.Get(ctx, checksummer.Type, GetOptions{
+				PluginRequirement:         pr,
+				BinaryInstallationOptions: opts.BinaryInstallationOptions,
+				version:                   v,
+			})
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			entries, err := ParseChecksumFileEntries(checksumFile)
+			_ = checksumFile.Close()
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			var hashes []string
+			for _, entry := range entries {
+				if err := entry.init(pr); err != nil || entry.binVersion != "v"+v.String() {
+					continue
+				}
+				hashes = append(hashes, checksummer.Type+":"+strings.ToLower(entry.Checksum))
+			}
+			if len(hashes) > 0 {
+				sort.Strings(hashes)
+				return hashes, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("could not get the checksums of the %s plugin %s: %s", pr.Identifier, v, strings.Join(errs, "; "))
+}
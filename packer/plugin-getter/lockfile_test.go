@@ -0,0 +1,200 @@
+package plugingetter
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestLockFile_readWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-lock-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, LockFileName)
+
+	lf, err := ReadLockFile(path)
+	if err != nil || len(lf.Plugins) != 0 {
+		t.Fatalf("a missing lock file should read as empty, got %v, %v", lf, err)
+	}
+
+	lf.Lock(&LockedPlugin{
+		Identifier:  &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "docker"},
+		Version:     version.Must(version.NewVersion("1.0.1")),
+		Constraints: ">= 1.0.0",
+		Hashes:      []string{"sha256:aaaa", "sha256:bbbb"},
+	})
+	amazon := &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "amazon"}
+	lf.Lock(&LockedPlugin{Identifier: amazon, Version: version.Must(version.NewVersion("0.9.0"))})
+	lf.Lock(&LockedPlugin{Identifier: amazon, Version: version.Must(version.NewVersion("1.0.0"))})
+	if err := lf.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	written, _ := ioutil.ReadFile(path)
+	expected := `# This file is maintained automatically by "packer init".
+# Manual edits may be lost in future updates.
+
+plugin "github.com/hashicorp/amazon" {
+  version = "1.0.0"
+}
+
+plugin "github.com/hashicorp/docker" {
+  version     = "1.0.1"
+  constraints = ">= 1.0.0"
+  hashes = [
+    "sha256:aaaa",
+    "sha256:bbbb",
+  ]
+}
+`
+	if string(written) != expected {
+		t.Fatalf("unexpected lock file:\n%s", written)
+	}
+
+	read, err := ReadLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Plugins) != 2 {
+		t.Fatalf("expected 2 locked plugins, got %d", len(read.Plugins))
+	}
+	docker := read.Plugin(&addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "docker"})
+	if docker == nil || docker.Version.String() != "1.0.1" || !reflect.DeepEqual(docker.Hashes, []string{"sha256:aaaa", "sha256:bbbb"}) {
+		t.Fatalf("unexpected locked docker plugin %#v", docker)
+	}
+}
+
+func TestLockFile_redirectedFrom(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-lock-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, LockFileName)
+
+	old := &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "foo"}
+	moved := &addrs.Plugin{Hostname: "github.com", Namespace: "neworg", Type: "foo"}
+	lf := &LockFile{}
+	lf.Lock(&LockedPlugin{Identifier: old, Version: version.Must(version.NewVersion("1.0.0"))})
+	if !lf.Unlock(old) || lf.Unlock(old) {
+		t.Fatal("the plugin should be unlocked once")
+	}
+	lf.Lock(&LockedPlugin{Identifier: moved, Version: version.Must(version.NewVersion("1.0.0")), RedirectedFrom: old})
+	if err := lf.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked := read.RedirectedFrom(old); locked == nil || locked.Identifier.String() != moved.String() {
+		t.Fatalf("expected the redirect from %s to %s to be recorded, got %#v", old, moved, locked)
+	}
+	if read.RedirectedFrom(moved) != nil {
+		t.Fatalf("%s was not redirected", moved)
+	}
+}
+
+func TestReadLockFile_invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"bad version": `plugin "github.com/hashicorp/amazon" { version = "one" }`,
+		"bad source":  `plugin "amazon" { version = "1.0.0" }`,
+		"bad hash": `
+plugin "github.com/hashicorp/amazon" {
+  version = "1.0.0"
+  hashes  = ["aaaa"]
+}`,
+		"duplicated": `
+plugin "github.com/hashicorp/amazon" { version = "1.0.0" }
+plugin "github.com/hashicorp/amazon" { version = "1.0.1" }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "packer-lock-file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			_, _ = f.WriteString(content)
+			f.Close()
+			if _, err := ReadLockFile(f.Name()); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestLockedPlugin(t *testing.T) {
+	req := &Requirement{
+		Identifier:         &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "amazon"},
+		VersionConstraints: version.MustConstraints(version.NewConstraint(">= 1.0.0")),
+	}
+	locked := &LockedPlugin{
+		Identifier: req.Identifier,
+		Version:    version.Must(version.NewVersion("1.2.3")),
+		Hashes:     []string{"sha256:aaaa"},
+	}
+	if err := locked.Validate(req); err != nil {
+		t.Fatalf("Validate: %s", err)
+	}
+	req.VersionConstraints = version.MustConstraints(version.NewConstraint(">= 2.0.0"))
+	if err := locked.Validate(req); err == nil {
+		t.Fatal("a locked version that doesn't match the constraints should fail to validate")
+	}
+	req.VersionConstraints = version.MustConstraints(version.NewConstraint(">= 1.0.0, != 1.2.3"))
+	if err := locked.Validate(req); err == nil || !strings.Contains(err.Error(), "excluded") {
+		t.Fatalf("an excluded locked version should fail to validate, got %v", err)
+	}
+
+	if !locked.allows(version.Must(version.NewVersion("v1.2.3"))) || locked.allows(version.Must(version.NewVersion("1.2.4"))) {
+		t.Fatal("only the locked version should be allowed")
+	}
+	if err := locked.verifyHash("sha256", "AAAA"); err != nil {
+		t.Fatalf("verifyHash: %s", err)
+	}
+	if err := locked.verifyHash("sha256", "bbbb"); err == nil {
+		t.Fatal("a checksum missing from the lock file should fail to verify")
+	}
+
+	installs := InstallList{{Version: "v1.2.2"}, {Version: "v1.2.3"}}
+	if got := locked.Select(installs); got != installs[1] {
+		t.Fatalf("Select returned %v", got)
+	}
+}
+
+func TestRequirement_ReleaseHashes(t *testing.T) {
+	req := &Requirement{
+		Identifier: &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "amazon"},
+	}
+	getter := &mockPluginGetter{
+		ChecksumFileEntries: map[string][]ChecksumFileEntry{
+			"1.2.3": {
+				{Filename: "packer-plugin-amazon_v1.2.3_x5.0_linux_amd64.zip", Checksum: "BBBB"},
+				{Filename: "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64.zip", Checksum: "aaaa"},
+				{Filename: "packer-plugin-amazon_v1.2.2_x5.0_darwin_amd64.zip", Checksum: "cccc"},
+			},
+		},
+	}
+	hashes, err := req.ReleaseHashes(context.Background(), version.Must(version.NewVersion("1.2.3")), InstallOptions{
+		Getters: []Getter{getter},
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			Checksummers: []Checksummer{{Type: "sha256", Hash: sha256.New()}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"sha256:aaaa", "sha256:bbbb"}; !reflect.DeepEqual(hashes, expected) {
+		t.Fatalf("ReleaseHashes returned %v, expected %v", hashes, expected)
+	}
+}
@@ -0,0 +1,40 @@
+package plugingetter
+
+import (
+	"context"
+	"log"
+)
+
+// Logger receives the debug messages of the installation of plugins.
+// *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// stdLogger logs with the standard logger, like the rest of Packer.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) { log.Printf(format, v...) }
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx making the functions and the getters of
+// this package log to logger instead of the standard logger.
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFrom returns the logger set with WithLogger on ctx, or one logging
+// with the standard logger.
+func LoggerFrom(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(Logger); ok && logger != nil {
+		return logger
+	}
+	return stdLogger{}
+}
+
+// Logf logs with the logger of ctx. Getters use it so that their messages go
+// where the ones of the installation go.
+func Logf(ctx context.Context, format string, v ...interface{}) {
+	LoggerFrom(ctx).Printf(format, v...)
+}
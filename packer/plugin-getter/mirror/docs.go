@@ -0,0 +1,3 @@
+// Package mirror defines getters for plugin network and filesystem mirrors.
+
+package mirror
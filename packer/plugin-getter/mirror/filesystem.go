@@ -0,0 +1,54 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+// FilesystemGetter gets plugins from a directory laid out like a network
+// mirror, see Getter. It allows installing plugins without any network
+// access, from a directory seeded beforehand:
+//
+//	<Dir>/github.com/hashicorp/happycloud/index.json
+//	<Dir>/github.com/hashicorp/happycloud/v1.2.3/packer-plugin-happycloud_v1.2.3_SHA256SUMS
+//	<Dir>/github.com/hashicorp/happycloud/v1.2.3/packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip
+type FilesystemGetter struct {
+	// Dir is the root directory of the mirror.
+	Dir string
+}
+
+var (
+	_ plugingetter.Getter                  = &FilesystemGetter{}
+	_ plugingetter.ReleasesSignatureGetter = &FilesystemGetter{}
+)
+
+// ServesReleasesSignature returns true, filesystem mirrors serve the
+// signature of the releases of a plugin as index.json.sig, like network
+// mirrors.
+func (g *FilesystemGetter) ServesReleasesSignature() bool { return true }
+
+func (g *FilesystemGetter) String() string {
+	return "filesystem mirror " + g.Dir
+}
+
+func (g *FilesystemGetter) Get(ctx context.Context, what string, opts plugingetter.GetOptions) (io.ReadCloser, error) {
+	p, err := documentPath(what, opts)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(g.Dir, filepath.FromSlash(p))
+	plugingetter.Logf(ctx, "[DEBUG] filesystem-mirror-getter: opening %q", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found in filesystem mirror: %w", p, err)
+	}
+	if plugingetter.IsChecksumType(what) {
+		return plugingetter.TransformChecksumStream()(f)
+	}
+	return f, nil
+}
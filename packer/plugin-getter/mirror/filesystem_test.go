@@ -0,0 +1,50 @@
+package mirror
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+func TestFilesystemGetter_Get(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-filesystem-mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pluginDir := filepath.Join(dir, "github.com", "hashicorp", "happycloud")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	index := filepath.Join(pluginDir, "index.json")
+	if err := ioutil.WriteFile(index, []byte(`[{"version":"v1.2.3"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &FilesystemGetter{Dir: dir}
+	rc, err := g.Get(context.Background(), "releases", happycloudOptions())
+	if err != nil {
+		t.Fatalf("Get(releases): %s", err)
+	}
+	releases, err := plugingetter.ParseReleases(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 1 || releases[0].Version != "v1.2.3" {
+		t.Fatalf("unexpected releases %v", releases)
+	}
+
+	if _, err := g.Get(context.Background(), "releases.sig", happycloudOptions()); err == nil {
+		t.Fatal("expected an error for a missing signature")
+	}
+	opts := happycloudOptions()
+	opts.PluginRequirement.Identifier.Type = "sadcloud"
+	if _, err := g.Get(context.Background(), "releases", opts); err == nil {
+		t.Fatal("expected an error for a plugin the mirror doesn't have")
+	}
+}
@@ -0,0 +1,265 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+const defaultUserAgent = "packer-plugin-getter"
+
+// Getter fetches plugins from a network mirror: an HTTP server on which the
+// releases of each plugin are laid out by source address, similarly to
+// Terraform's provider network mirrors.
+//
+// For the github.com/hashicorp/happycloud plugin, the mirror at
+// https://mirror.example.com/packer/ serves:
+//
+//   - https://mirror.example.com/packer/github.com/hashicorp/happycloud/index.json,
+//     the json list of releases, like `[{"version": "v1.2.3"}]`, and
+//     optionally its signature in index.json.sig.
+//   - https://mirror.example.com/packer/github.com/hashicorp/happycloud/v1.2.3/,
+//     the files of a release as they are published on GitHub: the
+//     packer-plugin-happycloud_v1.2.3_SHA256SUMS file and the zip files.
+//   - optionally https://mirror.example.com/packer/github.com/hashicorp/happycloud/redirect.json,
+//     when the plugin moved to another source address, like
+//     `{"source": "github.com/neworg/happycloud"}`.
+//
+// When Hostname is set, the getter only serves the plugins of that host, which
+// are laid out without it: https://plugins.example.com/packer/hashicorp/happycloud/index.json
+// for plugins.example.com/hashicorp/happycloud. This is how plugins are
+// hosted on a plain HTTPS server, like an Artifactory or Nexus repository.
+type Getter struct {
+	// BaseURL is the URL of the mirror, like https://mirror.example.com/packer/.
+	BaseURL string
+
+	// Hostname, when set, is the only plugin host served by the getter.
+	Hostname string
+
+	Client    *http.Client
+	UserAgent string
+
+	// Cache, when set, stores release lists and checksum files so that
+	// they can be fetched with conditional requests.
+	Cache *plugingetter.HTTPCache
+
+	// Timeouts of the requests, used when Client is nil.
+	Timeouts plugingetter.Timeouts
+
+	// Credentials, when set, authenticate the requests to the mirror. Used
+	// when Client is nil.
+	Credentials plugingetter.CredentialsSource
+
+	// clientOnce guards the creation of Client, as plugins can be installed
+	// concurrently.
+	clientOnce sync.Once
+}
+
+var (
+	_ plugingetter.Getter                  = &Getter{}
+	_ plugingetter.Locator                 = &Getter{}
+	_ plugingetter.RangeGetter             = &Getter{}
+	_ plugingetter.ReleasesSignatureGetter = &Getter{}
+)
+
+// ServesReleasesSignature returns true, mirrors serve the signature of the
+// releases of a plugin next to them, as index.json.sig.
+func (g *Getter) ServesReleasesSignature() bool { return true }
+
+func (g *Getter) String() string {
+	if g.Hostname != "" {
+		return g.Hostname + " at " + g.BaseURL
+	}
+	return "mirror " + g.BaseURL
+}
+
+// documentPath returns the slash separated path of the document what of the
+// plugin described by opts, relative to the root of a mirror.
+func documentPath(what string, opts plugingetter.GetOptions) (string, error) {
+	return pluginDocumentPath(opts.PluginRequirement.Identifier.String()+"/", what, opts)
+}
+
+// pluginDocumentPath returns the slash separated path of the document what of
+// the plugin described by opts, the files of the plugin being in pluginPath.
+func pluginDocumentPath(pluginPath, what string, opts plugingetter.GetOptions) (string, error) {
+	switch what {
+	case "releases":
+		return pluginPath + "index.json", nil
+	case "releases.sig":
+		return pluginPath + "index.json.sig", nil
+	case "redirect":
+		return pluginPath + "redirect.json", nil
+	case "zip":
+		return pluginPath + opts.Version() + "/" + opts.ExpectedZipFilename(), nil
+	default:
+		if !plugingetter.IsChecksumType(what) {
+			return "", fmt.Errorf("%q not implemented", what)
+		}
+		return pluginPath + opts.Version() + "/" + opts.PluginRequirement.FilenamePrefix() + opts.Version() + plugingetter.ChecksumFileSuffix(what), nil
+	}
+}
+
+// url returns the URL of the document what of the plugin described by opts.
+func (g *Getter) url(what string, opts plugingetter.GetOptions) (string, error) {
+	var p string
+	var err error
+	if g.Hostname == "" {
+		p, err = documentPath(what, opts)
+	} else if id := opts.PluginRequirement.Identifier; id.Hostname != g.Hostname {
+		err = fmt.Errorf("%s is not a %s source address", id, g.Hostname)
+	} else {
+		p, err = pluginDocumentPath(id.Namespace+"/"+id.Type+"/", what, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(g.BaseURL, "/") + "/" + p, nil
+}
+
+func (g *Getter) initClient() {
+	g.clientOnce.Do(func() {
+		if g.Client != nil {
+			return
+		}
+		var transport http.RoundTripper = plugingetter.NewHTTPTransport(g.Timeouts)
+		if g.Credentials != nil {
+			transport = &plugingetter.CredentialsTransport{Source: g.Credentials, Base: transport}
+		}
+		g.Client = &http.Client{Transport: transport}
+	})
+}
+
+func (g *Getter) newRequest(ctx context.Context, method, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	if g.UserAgent != "" {
+		req.Header.Set("User-Agent", g.UserAgent)
+	}
+	return req, nil
+}
+
+func (g *Getter) Get(ctx context.Context, what string, opts plugingetter.GetOptions) (io.ReadCloser, error) {
+	u, err := g.url(what, opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := g.newRequest(ctx, "GET", u)
+	if err != nil {
+		return nil, err
+	}
+	g.initClient()
+
+	transform := func(in io.ReadCloser) (io.ReadCloser, error) {
+		return in, nil
+	}
+	if plugingetter.IsChecksumType(what) {
+		transform = plugingetter.TransformChecksumStream()
+	}
+
+	// zip files are checksummed and can be big, only small documents are
+	// cached.
+	cacheable := what == "releases" || plugingetter.IsChecksumType(what)
+	var cached *plugingetter.CachedResponse
+	if cacheable {
+		cached = g.Cache.Get(ctx, u)
+		cached.SetConditionalHeaders(req)
+	}
+
+	plugingetter.Logf(ctx, "[DEBUG] mirror-getter: getting %q", u)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		plugingetter.Logf(ctx, "[DEBUG] mirror-getter: %q not modified, using cached response", u)
+		return transform(ioutil.NopCloser(bytes.NewReader(cached.Body)))
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, &plugingetter.StatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+
+	if what == "zip" {
+		return plugingetter.SizedBody(resp.Body, resp.ContentLength), nil
+	}
+	if !cacheable || g.Cache == nil {
+		return transform(resp.Body)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Cache.Put(u, resp.Header, body); err != nil {
+		plugingetter.Logf(ctx, "[DEBUG] mirror-getter: could not cache response of %q: %s", u, err)
+	}
+	return transform(ioutil.NopCloser(bytes.NewReader(body)))
+}
+
+// GetRange returns a zip file of the mirror starting at offset, to resume an
+// interrupted download.
+func (g *Getter) GetRange(ctx context.Context, what string, opts plugingetter.GetOptions, offset int64) (io.ReadCloser, error) {
+	if what != "zip" {
+		return nil, fmt.Errorf("%q not implemented", what)
+	}
+	u, err := g.url(what, opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := g.newRequest(ctx, "GET", u)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	g.initClient()
+
+	plugingetter.Logf(ctx, "[DEBUG] mirror-getter: getting %q from byte %d", u, offset)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: range not served, status %s", u, resp.Status)
+	}
+	return plugingetter.SizedBody(resp.Body, resp.ContentLength), nil
+}
+
+// Locate returns the URL of a zip file on the mirror, and its size as
+// reported by the mirror, or -1 when it doesn't tell.
+func (g *Getter) Locate(ctx context.Context, what string, opts plugingetter.GetOptions) (string, int64, error) {
+	if what != "zip" {
+		return "", -1, fmt.Errorf("%q not implemented", what)
+	}
+	u, err := g.url(what, opts)
+	if err != nil {
+		return "", -1, err
+	}
+	req, err := g.newRequest(ctx, "HEAD", u)
+	if err != nil {
+		return "", -1, err
+	}
+	g.initClient()
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		plugingetter.Logf(ctx, "[DEBUG] mirror-getter: could not get the size of %q: %s", u, err)
+		return u, -1, nil
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return u, -1, nil
+	}
+	return u, resp.ContentLength, nil
+}
@@ -0,0 +1,128 @@
+package mirror
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/packer/hcl2template/addrs"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+func happycloudOptions() plugingetter.GetOptions {
+	return plugingetter.GetOptions{
+		PluginRequirement: &plugingetter.Requirement{
+			Identifier: &addrs.Plugin{
+				Hostname:  "github.com",
+				Namespace: "hashicorp",
+				Type:      "happycloud",
+			},
+		},
+	}
+}
+
+func TestGetter_Get(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.Header.Get("User-Agent"); got != "packer-test" {
+			t.Errorf("unexpected user agent %q", got)
+		}
+		switch r.URL.Path {
+		case "/packer/github.com/hashicorp/happycloud/index.json":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`[{"version":"v1.2.3"}]`))
+		case "/packer/github.com/hashicorp/happycloud/index.json.sig":
+			_, _ = w.Write([]byte("c2lnbmF0dXJl"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cacheDir, err := ioutil.TempDir("", "packer-mirror-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	g := &Getter{
+		BaseURL:   srv.URL + "/packer/",
+		UserAgent: "packer-test",
+		Cache:     &plugingetter.HTTPCache{Dir: cacheDir},
+	}
+	for i := 0; i < 2; i++ {
+		rc, err := g.Get(context.Background(), "releases", happycloudOptions())
+		if err != nil {
+			t.Fatalf("Get(releases): %s", err)
+		}
+		releases, err := plugingetter.ParseReleases(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(releases) != 1 || releases[0].Version != "v1.2.3" {
+			t.Fatalf("unexpected releases %v", releases)
+		}
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+
+	rc, err := g.Get(context.Background(), "releases.sig", happycloudOptions())
+	if err != nil {
+		t.Fatalf("Get(releases.sig): %s", err)
+	}
+	if sig, _ := ioutil.ReadAll(rc); string(sig) != "c2lnbmF0dXJl" {
+		t.Fatalf("unexpected signature %q", sig)
+	}
+
+	opts := happycloudOptions()
+	opts.PluginRequirement.Identifier.Type = "sadcloud"
+	if _, err := g.Get(context.Background(), "releases", opts); err == nil {
+		t.Fatal("expected an error for a plugin the mirror doesn't have")
+	}
+	if _, err := g.Get(context.Background(), "docs", opts); err == nil {
+		t.Fatal("expected an error for an unknown document")
+	}
+}
+
+func TestGetter_Get_hostname(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifactory/packer/hashicorp/happycloud/index.json":
+			_, _ = w.Write([]byte(`[{"version":"v1.2.3"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := &Getter{
+		BaseURL:  srv.URL + "/artifactory/packer",
+		Hostname: "plugins.example.com",
+	}
+	opts := happycloudOptions()
+	opts.PluginRequirement.Identifier.Hostname = "plugins.example.com"
+	rc, err := g.Get(context.Background(), "releases", opts)
+	if err != nil {
+		t.Fatalf("Get(releases): %s", err)
+	}
+	releases, err := plugingetter.ParseReleases(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 1 || releases[0].Version != "v1.2.3" {
+		t.Fatalf("unexpected releases %v", releases)
+	}
+
+	if _, err := g.Get(context.Background(), "releases", happycloudOptions()); err == nil {
+		t.Fatal("expected an error for a plugin of another host")
+	}
+}
@@ -0,0 +1,151 @@
+package mirror
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// failureBackoff is how long a mirror that failed is tried after the others.
+const failureBackoff = 15 * time.Minute
+
+// Health is what is known of the availability of a mirror.
+type Health struct {
+	// Latency is a moving average of the time the mirror took to answer.
+	Latency     time.Duration `json:"latency"`
+	LastSuccess time.Time     `json:"last_success,omitempty"`
+	LastFailure time.Time     `json:"last_failure,omitempty"`
+	LastError   string        `json:"last_error,omitempty"`
+}
+
+// failing tells whether the mirror failed recently and didn't answer since.
+func (h Health) failing(now time.Time) bool {
+	return h.LastFailure.After(h.LastSuccess) && now.Sub(h.LastFailure) < failureBackoff
+}
+
+// HealthFile keeps the health of mirrors, by base URL, in a json file, so
+// that the next runs first try the mirrors that answered best. What is
+// recorded is only written to the file by Save.
+type HealthFile struct {
+	// Path of the file. Health is only kept in memory when empty.
+	Path string
+
+	mu      sync.Mutex
+	mirrors map[string]Health
+	// changed is set when health was recorded since the file was saved.
+	changed bool
+}
+
+// load reads the file the first time it is needed, f.mu must be held.
+func (f *HealthFile) load() {
+	if f.mirrors != nil {
+		return
+	}
+	f.mirrors = map[string]Health{}
+	if f.Path == "" {
+		return
+	}
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[TRACE] mirror health: could not read %q: %s", f.Path, err)
+		}
+		return
+	}
+	if err := json.Unmarshal(b, &f.mirrors); err != nil {
+		log.Printf("[TRACE] mirror health: ignoring invalid file %q: %s", f.Path, err)
+		f.mirrors = map[string]Health{}
+	}
+}
+
+// Save writes the recorded health to the file, when it changed. It is meant
+// to be called once per run.
+func (f *HealthFile) Save() error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Path == "" || !f.changed {
+		return nil
+	}
+	b, err := json.MarshalIndent(f.mirrors, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(f.Path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// write to a file of our own then rename it, so that concurrent runs
+	// never read a partial file nor write into each other's.
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(f.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return err
+	}
+	f.changed = false
+	return nil
+}
+
+// Get returns the health of the mirror at baseURL.
+func (f *HealthFile) Get(baseURL string) Health {
+	if f == nil {
+		return Health{}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.load()
+	return f.mirrors[baseURL]
+}
+
+// RecordSuccess records that the mirror at baseURL answered in latency.
+func (f *HealthFile) RecordSuccess(baseURL string, latency time.Duration) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.load()
+	h := f.mirrors[baseURL]
+	if h.Latency == 0 {
+		h.Latency = latency
+	} else {
+		h.Latency = (3*h.Latency + latency) / 4
+	}
+	h.LastSuccess = time.Now().UTC()
+	f.mirrors[baseURL] = h
+	f.changed = true
+}
+
+// RecordFailure records that the mirror at baseURL failed with err.
+func (f *HealthFile) RecordFailure(baseURL string, err error) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.load()
+	h := f.mirrors[baseURL]
+	h.LastFailure = time.Now().UTC()
+	h.LastError = err.Error()
+	f.mirrors[baseURL] = h
+	f.changed = true
+}
@@ -0,0 +1,131 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+// Mirror is a network mirror of a Pool.
+type Mirror struct {
+	*Getter
+	// Priority orders the mirrors of a pool, higher first.
+	Priority int
+}
+
+// Pool fails over between network mirrors. Mirrors are tried by decreasing
+// priority; mirrors of the same priority are tried from the fastest to the
+// slowest, those that failed recently last.
+type Pool struct {
+	Mirrors []*Mirror
+
+	// Health, when set, records how mirrors answered to order future
+	// attempts. Only network errors and server errors count as failures.
+	Health *HealthFile
+}
+
+var (
+	_ plugingetter.Getter                  = &Pool{}
+	_ plugingetter.Locator                 = &Pool{}
+	_ plugingetter.RangeGetter             = &Pool{}
+	_ plugingetter.ReleasesSignatureGetter = &Pool{}
+)
+
+// ServesReleasesSignature tells whether the pool has mirrors, which serve
+// the signature of releases.
+func (p *Pool) ServesReleasesSignature() bool { return len(p.Mirrors) > 0 }
+
+func (p *Pool) String() string {
+	urls := make([]string, 0, len(p.Mirrors))
+	for _, m := range p.Mirrors {
+		urls = append(urls, m.BaseURL)
+	}
+	return "mirrors " + strings.Join(urls, ", ")
+}
+
+// ordered returns the mirrors in the order they should be tried.
+func (p *Pool) ordered() []*Mirror {
+	now := time.Now()
+	type candidate struct {
+		*Mirror
+		health Health
+	}
+	candidates := make([]candidate, 0, len(p.Mirrors))
+	for _, m := range p.Mirrors {
+		candidates = append(candidates, candidate{m, p.Health.Get(m.BaseURL)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if af, bf := a.health.failing(now), b.health.failing(now); af != bf {
+			return bf
+		}
+		// mirrors never measured come after the measured ones
+		if (a.health.Latency == 0) != (b.health.Latency == 0) {
+			return b.health.Latency == 0
+		}
+		return a.health.Latency < b.health.Latency
+	})
+	mirrors := make([]*Mirror, 0, len(candidates))
+	for _, c := range candidates {
+		mirrors = append(mirrors, c.Mirror)
+	}
+	return mirrors
+}
+
+func (p *Pool) Get(ctx context.Context, what string, opts plugingetter.GetOptions) (io.ReadCloser, error) {
+	var errs []string
+	for _, m := range p.ordered() {
+		start := time.Now()
+		rc, err := m.Get(ctx, what, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			plugingetter.Logf(ctx, "[DEBUG] mirror-getter: %s failed, trying the next mirror: %s", m, err)
+			// a mirror answering that it doesn't have a file, like a
+			// checksum file a release doesn't publish, is still healthy.
+			if plugingetter.IsTransientErr(err) {
+				p.Health.RecordFailure(m.BaseURL, err)
+			}
+			errs = append(errs, err.Error())
+			continue
+		}
+		p.Health.RecordSuccess(m.BaseURL, time.Since(start))
+		return rc, nil
+	}
+	return nil, fmt.Errorf("no mirror could get %s: %s", what, strings.Join(errs, "; "))
+}
+
+// GetRange gets the end of a zip file from the first mirror able to serve it.
+func (p *Pool) GetRange(ctx context.Context, what string, opts plugingetter.GetOptions, offset int64) (io.ReadCloser, error) {
+	var errs []string
+	for _, m := range p.ordered() {
+		rc, err := m.GetRange(ctx, what, opts, offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, err.Error())
+			continue
+		}
+		return rc, nil
+	}
+	return nil, fmt.Errorf("no mirror could get a range of %s: %s", what, strings.Join(errs, "; "))
+}
+
+// Locate locates a zip file on the mirror that would be tried first.
+func (p *Pool) Locate(ctx context.Context, what string, opts plugingetter.GetOptions) (string, int64, error) {
+	mirrors := p.ordered()
+	if len(mirrors) == 0 {
+		return "", -1, fmt.Errorf("no mirror configured")
+	}
+	return mirrors[0].Locate(ctx, what, opts)
+}
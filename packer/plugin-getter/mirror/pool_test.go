@@ -0,0 +1,153 @@
+package mirror
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPool_Get(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	requests := 0
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`[{"version":"v1.2.3"}]`))
+	}))
+	defer up.Close()
+
+	dir, err := ioutil.TempDir("", "packer-mirror-health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	healthPath := filepath.Join(dir, "mirrors.json")
+
+	newPool := func() *Pool {
+		return &Pool{
+			Mirrors: []*Mirror{
+				{Getter: &Getter{BaseURL: down.URL}},
+				{Getter: &Getter{BaseURL: up.URL}},
+			},
+			Health: &HealthFile{Path: healthPath},
+		}
+	}
+
+	first := newPool()
+	rc, err := first.Get(context.Background(), "releases", happycloudOptions())
+	if err != nil {
+		t.Fatalf("Get(releases): %s", err)
+	}
+	rc.Close()
+	if requests != 1 {
+		t.Fatalf("expected the request to fail over to the second mirror, got %d requests", requests)
+	}
+	if err := first.Health.Save(); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	// a new run reads the health file, and tries the failing mirror last
+	p := newPool()
+	if got := p.ordered()[0].BaseURL; got != up.URL {
+		t.Fatalf("expected %s to be tried first, got %s", up.URL, got)
+	}
+	h := p.Health.Get(down.URL)
+	if h.LastFailure.IsZero() || h.LastError == "" {
+		t.Fatalf("expected the failure of %s to be recorded, got %#v", down.URL, h)
+	}
+	if h := p.Health.Get(up.URL); h.LastSuccess.IsZero() || h.Latency == 0 {
+		t.Fatalf("expected the success of %s to be recorded, got %#v", up.URL, h)
+	}
+}
+
+func TestPool_Get_notFound(t *testing.T) {
+	requests := 0
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer missing.Close()
+
+	p := &Pool{
+		Mirrors: []*Mirror{{Getter: &Getter{BaseURL: missing.URL}}},
+		Health:  &HealthFile{},
+	}
+	if _, err := p.Get(context.Background(), "releases", happycloudOptions()); err == nil {
+		t.Fatal("expected Get to fail")
+	}
+	if requests != 1 {
+		t.Fatalf("expected one request, got %d", requests)
+	}
+	// a mirror that doesn't publish a file is not failing
+	if h := p.Health.Get(missing.URL); !h.LastFailure.IsZero() {
+		t.Fatalf("expected no failure to be recorded, got %#v", h)
+	}
+}
+
+func TestHealthFile_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-mirror-health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mirrors.json")
+
+	f := &HealthFile{Path: path}
+	f.RecordSuccess("https://mirror.example.com/", time.Second)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written before Save, got %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+
+	if h := (&HealthFile{Path: path}).Get("https://mirror.example.com/"); h.Latency != time.Second {
+		t.Fatalf("expected the saved health to be read back, got %#v", h)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the health file to be left, got %d files", len(files))
+	}
+}
+
+func TestPool_ordered(t *testing.T) {
+	now := time.Now()
+	health := &HealthFile{mirrors: map[string]Health{
+		"slow":    {Latency: time.Second, LastSuccess: now},
+		"fast":    {Latency: time.Millisecond, LastSuccess: now},
+		"failing": {Latency: time.Microsecond, LastFailure: now},
+		"old":     {Latency: time.Microsecond, LastFailure: now.Add(-time.Hour)},
+	}}
+	p := &Pool{
+		Mirrors: []*Mirror{
+			{Getter: &Getter{BaseURL: "unknown"}},
+			{Getter: &Getter{BaseURL: "failing"}},
+			{Getter: &Getter{BaseURL: "slow"}},
+			{Getter: &Getter{BaseURL: "fast"}},
+			{Getter: &Getter{BaseURL: "old"}},
+			{Getter: &Getter{BaseURL: "low"}, Priority: -1},
+			{Getter: &Getter{BaseURL: "high"}, Priority: 1},
+		},
+		Health: health,
+	}
+	var got []string
+	for _, m := range p.ordered() {
+		got = append(got, m.BaseURL)
+	}
+	want := []string{"high", "old", "fast", "slow", "unknown", "failing", "low"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ordered() = %v, want %v", got, want)
+		}
+	}
+}
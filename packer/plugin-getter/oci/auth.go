@@ -0,0 +1,163 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+// do sends req, authenticating it with the token service of the registry when
+// the registry asks for it, as described by the distribution spec: the
+// registry answers 401 with a `WWW-Authenticate: Bearer realm=...` challenge,
+// and the token handed by the realm is sent as a bearer token.
+func (g *Getter) do(req *http.Request) (*http.Response, error) {
+	key := repositoryOf(req.URL.Path)
+	if token := g.token(key); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := g.Client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := parseBearerChallenge(resp.Header.Get("WWW-Authenticate"))
+	if challenge["realm"] == "" {
+		return resp, nil
+	}
+	resp.Body.Close()
+	token, err := g.fetchToken(req.Context(), challenge)
+	if err != nil {
+		return nil, fmt.Errorf("could not authenticate to %s: %w", g.Hostname, err)
+	}
+	g.tokensMu.Lock()
+	if g.tokens == nil {
+		g.tokens = map[string]string{}
+	}
+	g.tokens[key] = token
+	g.tokensMu.Unlock()
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return g.Client.Do(req)
+}
+
+func (g *Getter) token(key string) string {
+	g.tokensMu.Lock()
+	defer g.tokensMu.Unlock()
+	return g.tokens[key]
+}
+
+// fetchToken gets a token from the realm of challenge, with the credentials
+// of the registry when it has some.
+func (g *Getter) fetchToken(ctx context.Context, challenge map[string]string) (string, error) {
+	u, err := url.Parse(challenge["realm"])
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %q: %w", challenge["realm"], err)
+	}
+	q := u.Query()
+	for _, param := range []string{"service", "scope"} {
+		if v := challenge[param]; v != "" {
+			q.Set(param, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := g.newRequest(ctx, u.String())
+	if err != nil {
+		return "", err
+	}
+	if g.Credentials != nil {
+		creds, err := g.Credentials.Credentials(g.Hostname)
+		if err != nil {
+			return "", err
+		}
+		if creds != nil {
+			username, password := creds.Username, creds.Password
+			if creds.Token != "" {
+				// registries accept access tokens as the password of any
+				// user.
+				username, password = "packer", creds.Token
+			}
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	plugingetter.Logf(ctx, "[DEBUG] oci-getter: getting a token from %s", u.Host)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service %s answered %s", u.Host, resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid answer of token service %s: %w", u.Host, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("token service %s returned no token", u.Host)
+}
+
+// repositoryOf returns the repository of a registry API path, like
+// hashicorp/packer-plugin-happycloud for
+// /v2/hashicorp/packer-plugin-happycloud/tags/list.
+func repositoryOf(path string) string {
+	if i := strings.Index(path, "/v2/"); i >= 0 {
+		path = path[i+len("/v2/"):]
+	}
+	for _, endpoint := range []string{"/tags/", "/manifests/", "/blobs/"} {
+		if i := strings.LastIndex(path, endpoint); i >= 0 {
+			return path[:i]
+		}
+	}
+	return path
+}
+
+// parseBearerChallenge returns the parameters of a Bearer
+// WWW-Authenticate header, or nil for another scheme.
+func parseBearerChallenge(header string) map[string]string {
+	const scheme = "bearer "
+	if len(header) < len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) {
+		return nil
+	}
+	params := map[string]string{}
+	rest := header[len(scheme):]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.IndexByte(rest, ','); comma >= 0 {
+			value, rest = rest[:comma], rest[comma+1:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+	}
+	return params
+}
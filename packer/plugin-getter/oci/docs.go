@@ -0,0 +1,4 @@
+// Package oci defines a getter for plugins published as OCI artifacts on a
+// container registry.
+
+package oci
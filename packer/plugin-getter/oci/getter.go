@@ -0,0 +1,330 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+const defaultUserAgent = "packer-plugin-getter"
+
+// Media types of the manifests plugins can be published with.
+const (
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// AnnotationTitle is the annotation holding the filename of a layer.
+	AnnotationTitle = "org.opencontainers.image.title"
+)
+
+// Getter fetches plugins published as OCI artifacts on a container registry,
+// like `oras push` does.
+//
+// The github.com/hashicorp/happycloud plugin published on registry.example.com
+// is required as registry.example.com/hashicorp/happycloud, and its releases
+// are in the hashicorp/packer-plugin-happycloud repository of the registry:
+//
+//   - each tag that starts with a `v`, like v1.2.3, is a release;
+//   - the manifest of a release has one layer per zip file, named by its
+//     org.opencontainers.image.title annotation, like
+//     packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip.
+//
+// The digests of the layers are the sha256 checksums of the zip files, no
+// SHA256SUMS file is needed.
+type Getter struct {
+	// Hostname is the registry host plugins are required with.
+	Hostname string
+
+	// BaseURL is the URL of the registry, it defaults to https://<Hostname>.
+	BaseURL string
+
+	Client    *http.Client
+	UserAgent string
+
+	// Cache, when set, stores tag lists and manifests so that they can be
+	// fetched with conditional requests.
+	Cache *plugingetter.HTTPCache
+
+	// Timeouts of the requests, used when Client is nil.
+	Timeouts plugingetter.Timeouts
+
+	// Credentials, when set, authenticate the requests to the registry, and
+	// to its token service. Used when Client is nil.
+	Credentials plugingetter.CredentialsSource
+
+	// clientOnce guards the creation of Client, as plugins can be installed
+	// concurrently.
+	clientOnce sync.Once
+
+	// tokens are the bearer tokens handed by the token service, by scope.
+	tokensMu sync.Mutex
+	tokens   map[string]string
+}
+
+var (
+	_ plugingetter.Getter      = &Getter{}
+	_ plugingetter.Locator     = &Getter{}
+	_ plugingetter.RangeGetter = &Getter{}
+)
+
+func (g *Getter) String() string {
+	return "registry " + g.Hostname
+}
+
+func (g *Getter) initClient() {
+	g.clientOnce.Do(func() {
+		if g.Client != nil {
+			return
+		}
+		var transport http.RoundTripper = plugingetter.NewHTTPTransport(g.Timeouts)
+		if g.Credentials != nil {
+			transport = &plugingetter.CredentialsTransport{Source: g.Credentials, Base: transport}
+		}
+		g.Client = &http.Client{Transport: transport}
+	})
+}
+
+// repository returns the repository of the plugin described by opts, or an
+// error when the plugin is not required from this registry.
+func (g *Getter) repository(opts plugingetter.GetOptions) (string, error) {
+	id := opts.PluginRequirement.Identifier
+	if id.Hostname != g.Hostname {
+		return "", fmt.Errorf("%s is not a %s source address", id, g.Hostname)
+	}
+	return strings.ToLower(id.RealRelativePath()), nil
+}
+
+func (g *Getter) url(repository, path string) string {
+	base := g.BaseURL
+	if base == "" {
+		base = "https://" + g.Hostname
+	}
+	return strings.TrimSuffix(base, "/") + "/v2/" + repository + "/" + path
+}
+
+func (g *Getter) Get(ctx context.Context, what string, opts plugingetter.GetOptions) (io.ReadCloser, error) {
+	repository, err := g.repository(opts)
+	if err != nil {
+		return nil, err
+	}
+	g.initClient()
+
+	switch what {
+	case "releases":
+		return g.releases(ctx, repository)
+	case "sha256":
+		m, err := g.manifest(ctx, repository, opts.Version())
+		if err != nil {
+			return nil, err
+		}
+		return plugingetter.TransformChecksumStream()(ioutil.NopCloser(strings.NewReader(m.checksums())))
+	case "zip":
+		layer, err := g.layer(ctx, repository, opts)
+		if err != nil {
+			return nil, err
+		}
+		return g.blob(ctx, repository, layer.Digest, 0)
+	default:
+		return nil, fmt.Errorf("%q not implemented", what)
+	}
+}
+
+// GetRange returns a zip file of the registry starting at offset, to resume
+// an interrupted download.
+func (g *Getter) GetRange(ctx context.Context, what string, opts plugingetter.GetOptions, offset int64) (io.ReadCloser, error) {
+	if what != "zip" {
+		return nil, fmt.Errorf("%q not implemented", what)
+	}
+	repository, err := g.repository(opts)
+	if err != nil {
+		return nil, err
+	}
+	g.initClient()
+	layer, err := g.layer(ctx, repository, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.blob(ctx, repository, layer.Digest, offset)
+}
+
+// Locate returns the URL of the blob of a zip file, and its size as listed in
+// the manifest of its release.
+func (g *Getter) Locate(ctx context.Context, what string, opts plugingetter.GetOptions) (string, int64, error) {
+	if what != "zip" {
+		return "", -1, fmt.Errorf("%q not implemented", what)
+	}
+	repository, err := g.repository(opts)
+	if err != nil {
+		return "", -1, err
+	}
+	g.initClient()
+	layer, err := g.layer(ctx, repository, opts)
+	if err != nil {
+		return "", -1, err
+	}
+	return g.url(repository, "blobs/"+layer.Digest), layer.Size, nil
+}
+
+// releases returns the tags of repository that look like versions, as a json
+// list of releases.
+func (g *Getter) releases(ctx context.Context, repository string) (io.ReadCloser, error) {
+	body, err := g.document(ctx, g.url(repository, "tags/list"), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, fmt.Errorf("invalid tag list of %s: %w", repository, err)
+	}
+	releases := []plugingetter.Release{}
+	for _, tag := range tags.Tags {
+		if strings.HasPrefix(tag, "v") {
+			releases = append(releases, plugingetter.Release{Version: tag})
+		}
+	}
+	out, err := json.Marshal(releases)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(out)), nil
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// checksums returns the sha256 digests of the layers of m in the format of a
+// SHA256SUMS file.
+func (m *manifest) checksums() string {
+	var b strings.Builder
+	for _, layer := range m.Layers {
+		title := layer.Annotations[AnnotationTitle]
+		if title == "" || !strings.HasPrefix(layer.Digest, "sha256:") {
+			continue
+		}
+		fmt.Fprintf(&b, "%s  %s\n", strings.TrimPrefix(layer.Digest, "sha256:"), title)
+	}
+	return b.String()
+}
+
+func (g *Getter) manifest(ctx context.Context, repository, tag string) (*manifest, error) {
+	body, err := g.document(ctx, g.url(repository, "manifests/"+url.PathEscape(tag)), MediaTypeOCIManifest+", "+MediaTypeDockerManifest)
+	if err != nil {
+		return nil, err
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s:%s: %w", repository, tag, err)
+	}
+	if m.MediaType != "" && m.MediaType != MediaTypeOCIManifest && m.MediaType != MediaTypeDockerManifest {
+		return nil, fmt.Errorf("manifest %s:%s has unsupported media type %q", repository, tag, m.MediaType)
+	}
+	return m, nil
+}
+
+// layer returns the layer of the zip file described by opts.
+func (g *Getter) layer(ctx context.Context, repository string, opts plugingetter.GetOptions) (*descriptor, error) {
+	m, err := g.manifest(ctx, repository, opts.Version())
+	if err != nil {
+		return nil, err
+	}
+	for i, layer := range m.Layers {
+		if layer.Annotations[AnnotationTitle] == opts.ExpectedZipFilename() {
+			return &m.Layers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("manifest %s:%s has no %s layer", repository, opts.Version(), opts.ExpectedZipFilename())
+}
+
+// document returns the body of the small document at u, from the cache when
+// it was not modified.
+func (g *Getter) document(ctx context.Context, u, accept string) ([]byte, error) {
+	req, err := g.newRequest(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	cached := g.Cache.Get(ctx, u)
+	cached.SetConditionalHeaders(req)
+
+	plugingetter.Logf(ctx, "[DEBUG] oci-getter: getting %q", u)
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		plugingetter.Logf(ctx, "[DEBUG] oci-getter: %q not modified, using cached response", u)
+		return cached.Body, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, &plugingetter.StatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Cache.Put(u, resp.Header, body); err != nil {
+		plugingetter.Logf(ctx, "[DEBUG] oci-getter: could not cache response of %q: %s", u, err)
+	}
+	return body, nil
+}
+
+// blob returns the content of the blob digest, starting at offset.
+func (g *Getter) blob(ctx context.Context, repository, digest string, offset int64) (io.ReadCloser, error) {
+	u := g.url(repository, "blobs/"+digest)
+	req, err := g.newRequest(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	want := http.StatusOK
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		want = http.StatusPartialContent
+	}
+
+	plugingetter.Logf(ctx, "[DEBUG] oci-getter: getting %q from byte %d", u, offset)
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		resp.Body.Close()
+		if offset > 0 && resp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("GET %s: range not served, status %s", u, resp.Status)
+		}
+		return nil, &plugingetter.StatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+	return plugingetter.SizedBody(resp.Body, resp.ContentLength), nil
+}
+
+func (g *Getter) newRequest(ctx context.Context, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	if g.UserAgent != "" {
+		req.Header.Set("User-Agent", g.UserAgent)
+	}
+	return req, nil
+}
@@ -0,0 +1,113 @@
+package oci
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/hcl2template/addrs"
+	plugingetter "github.com/hashicorp/packer/packer/plugin-getter"
+)
+
+func happycloudOptions() plugingetter.GetOptions {
+	return plugingetter.GetOptions{
+		PluginRequirement: &plugingetter.Requirement{
+			Identifier: &addrs.Plugin{
+				Hostname:  "registry.example.com",
+				Namespace: "hashicorp",
+				Type:      "happycloud",
+			},
+		},
+	}
+}
+
+func TestGetter_Get_releases(t *testing.T) {
+	tokens := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokens++
+			if got := r.URL.Query().Get("scope"); got != "repository:hashicorp/packer-plugin-happycloud:pull" {
+				t.Errorf("unexpected scope %q", got)
+			}
+			_, _ = w.Write([]byte(`{"token": "s3cr3t"}`))
+		case "/v2/hashicorp/packer-plugin-happycloud/tags/list":
+			if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="registry.example.com",scope="repository:hashicorp/packer-plugin-happycloud:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"name": "hashicorp/packer-plugin-happycloud", "tags": ["latest", "v1.2.3", "v1.3.0"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := &Getter{Hostname: "registry.example.com", BaseURL: srv.URL}
+	for i := 0; i < 2; i++ {
+		rc, err := g.Get(context.Background(), "releases", happycloudOptions())
+		if err != nil {
+			t.Fatalf("Get(releases): %s", err)
+		}
+		releases, err := plugingetter.ParseReleases(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []plugingetter.Release{{Version: "v1.2.3"}, {Version: "v1.3.0"}}
+		if !reflect.DeepEqual(releases, want) {
+			t.Fatalf("unexpected releases %v", releases)
+		}
+	}
+	if tokens != 1 {
+		t.Fatalf("expected the token to be fetched once, got %d", tokens)
+	}
+
+	opts := happycloudOptions()
+	opts.PluginRequirement.Identifier.Hostname = "github.com"
+	if _, err := g.Get(context.Background(), "releases", opts); err == nil {
+		t.Fatal("expected an error for a plugin of another host")
+	}
+}
+
+func TestManifest_checksums(t *testing.T) {
+	m := &manifest{Layers: []descriptor{
+		{Digest: "sha256:0123", Annotations: map[string]string{AnnotationTitle: "packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip"}},
+		{Digest: "sha256:4567"},
+		{Digest: "sha512:89ab", Annotations: map[string]string{AnnotationTitle: "packer-plugin-happycloud_v1.2.3_x5.0_darwin_amd64.zip"}},
+	}}
+	want := "0123  packer-plugin-happycloud_v1.2.3_x5.0_linux_amd64.zip\n"
+	if got := m.checksums(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	got := parseBearerChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull"`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := parseBearerChallenge(`Basic realm="registry"`); got != nil {
+		t.Fatalf("expected no parameters for basic auth, got %v", got)
+	}
+}
+
+func TestRepositoryOf(t *testing.T) {
+	for path, want := range map[string]string{
+		"/v2/hashicorp/packer-plugin-happycloud/tags/list":            "hashicorp/packer-plugin-happycloud",
+		"/registry/v2/a/b/c/manifests/v1.2.3":                         "a/b/c",
+		"/v2/hashicorp/packer-plugin-happycloud/blobs/sha256:0123abc": "hashicorp/packer-plugin-happycloud",
+	} {
+		if got := repositoryOf(path); got != want {
+			t.Errorf("repositoryOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
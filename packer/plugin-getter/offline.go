@@ -0,0 +1,42 @@
+package plugingetter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrOffline is wrapped by the errors returned in offline mode, when a plugin
+// would have to be downloaded.
+var ErrOffline = errors.New("offline mode")
+
+// offlineError returns the error telling that no binary of pr is installed in
+// folders.
+func (pr *Requirement) offlineError(folders []string) error {
+	return fmt.Errorf("%w: no installation of the %s plugin matching %q was found in %s, and none can be downloaded",
+		ErrOffline, pr.Identifier, pr.VersionConstraints.String(), strings.Join(folders, ", "))
+}
+
+// installOffline is InstallLatest in offline mode: the getters are never
+// called, a binary matching pr, and opts.Locked when set, must already be
+// installed in opts.InFolders.
+func (pr *Requirement) installOffline(ctx context.Context, opts InstallOptions) (*Installation, error) {
+	installs, err := pr.ListInstallations(ctx, ListInstallationsOptions{
+		FromFolders:               opts.InFolders,
+		BinaryInstallationOptions: opts.BinaryInstallationOptions,
+		Offline:                   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	install := installs[len(installs)-1]
+	if opts.Locked != nil {
+		if install = opts.Locked.Select(installs); install == nil {
+			return nil, fmt.Errorf("%w: the %s plugin is locked at version %s, which is not installed, and can't be downloaded",
+				ErrOffline, pr.Identifier, opts.Locked.Version)
+		}
+	}
+	Logf(ctx, "[INFO] offline mode: using the installed %s %s plugin %q", pr.Identifier, install.Version, install.BinaryPath)
+	return nil, nil
+}
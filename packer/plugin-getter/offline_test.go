@@ -0,0 +1,62 @@
+package plugingetter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestRequirement_InstallLatest_offline(t *testing.T) {
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	getter := &failingGetter{err: errors.New("no network")}
+	opts := func(constraint string, locked string) (*Requirement, InstallOptions) {
+		pr := &Requirement{
+			Identifier:         identifier,
+			VersionConstraints: version.MustConstraints(version.NewConstraint(constraint)),
+		}
+		opts := InstallOptions{
+			Getters:   []Getter{getter},
+			InFolders: []string{pluginFolderOne},
+			Offline:   true,
+			BinaryInstallationOptions: BinaryInstallationOptions{
+				APIVersionMajor: "5", APIVersionMinor: "0",
+				OS: "darwin", ARCH: "amd64",
+				Checksummers: DefaultChecksummers(),
+			},
+		}
+		if locked != "" {
+			opts.Locked = &LockedPlugin{Identifier: identifier, Version: version.Must(version.NewVersion(locked))}
+		}
+		return pr, opts
+	}
+
+	pr, installOpts := opts(">= v1.2.3", "")
+	if install, err := pr.InstallLatest(context.Background(), installOpts); err != nil || install != nil {
+		t.Fatalf("expected the installed plugin to be used, got %v, %v", install, err)
+	}
+
+	pr, installOpts = opts(">= v1.2.3", "1.2.4")
+	if install, err := pr.InstallLatest(context.Background(), installOpts); err != nil || install != nil {
+		t.Fatalf("expected the installed locked version to be used, got %v, %v", install, err)
+	}
+
+	pr, installOpts = opts(">= v1.2.3", "1.2.6")
+	if _, err := pr.InstallLatest(context.Background(), installOpts); !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected a locked version that isn't installed to fail in offline mode, got %v", err)
+	}
+
+	pr, installOpts = opts(">= v3", "")
+	if _, err := pr.InstallLatest(context.Background(), installOpts); !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected a plugin that isn't installed to fail in offline mode, got %v", err)
+	}
+
+	if getter.calls != 0 {
+		t.Fatalf("expected the getters not to be called in offline mode, got %d calls", getter.calls)
+	}
+}
@@ -0,0 +1,190 @@
+package plugingetter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// PinnedChecksum is a checksum a release file of a plugin must have, whatever
+// the checksum files of the release say. Pins typically come from a lock file
+// or from the template, so that a compromised release can't be installed.
+type PinnedChecksum struct {
+	// OS and ARCH of the release the checksum is for, like "linux" and
+	// "amd64". Empty values match any platform.
+	OS, ARCH string
+
+	// Type of the checksum, one of ChecksumTypes.
+	Type string
+
+	// Checksum, hex encoded.
+	Checksum string
+
+	// Binary pins the checksum of the extracted plugin binary instead of the
+	// zip file of the release.
+	Binary bool
+}
+
+// ParsePinnedChecksum parses the checksum s, like "sha256:3a7b...", of the
+// zip file released for platform, like "linux_amd64". An empty platform
+// matches any platform.
+func ParsePinnedChecksum(platform, s string) (PinnedChecksum, error) {
+	var pin PinnedChecksum
+	if platform != "" {
+		parts := strings.SplitN(platform, "_", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return pin, fmt.Errorf("malformed platform %q, expected {os}_{arch}", platform)
+		}
+		pin.OS, pin.ARCH = parts[0], parts[1]
+	}
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return pin, fmt.Errorf("malformed checksum %q, expected {type}:{checksum}", s)
+	}
+	pin.Type, pin.Checksum = parts[0], strings.ToLower(parts[1])
+	return pin, pin.validate()
+}
+
+func (pin PinnedChecksum) validate() error {
+	checksummer, err := NewChecksummer(pin.Type)
+	if err != nil {
+		return err
+	}
+	cs, err := hex.DecodeString(pin.Checksum)
+	if err != nil {
+		return fmt.Errorf("malformed %s checksum %q: %v", pin.Type, pin.Checksum, err)
+	}
+	if len(cs) != checksummer.Hash.Size() {
+		return fmt.Errorf("malformed %s checksum %q: expected %d bytes, got %d", pin.Type, pin.Checksum, checksummer.Hash.Size(), len(cs))
+	}
+	return nil
+}
+
+func (pin PinnedChecksum) String() string {
+	return pin.Type + ":" + pin.Checksum
+}
+
+func (pin PinnedChecksum) matches(goos, goarch string, binary bool) bool {
+	return pin.Binary == binary &&
+		(pin.OS == "" || pin.OS == goos) &&
+		(pin.ARCH == "" || pin.ARCH == goarch)
+}
+
+// pinnedChecksums returns the checksums pinned by pr for the zip file, or for
+// the binary, released for goos and goarch.
+func (pr *Requirement) pinnedChecksums(goos, goarch string, binary bool) []PinnedChecksum {
+	var pins []PinnedChecksum
+	for _, pin := range pr.Checksums {
+		if pin.matches(goos, goarch, binary) {
+			pins = append(pins, pin)
+		}
+	}
+	return pins
+}
+
+// verifyChecksumFileEntry checks that the checksum of type checksumType
+// published for a release file agrees with the pins of that type.
+func verifyChecksumFileEntry(pins []PinnedChecksum, checksumType, checksum, filename string) error {
+	for _, pin := range pins {
+		if pin.Type == checksumType && pin.Checksum != strings.ToLower(checksum) {
+			return fmt.Errorf("the %s checksum %s of %s published by the release doesn't match the pinned checksum %s",
+				checksumType, checksum, filename, pin)
+		}
+	}
+	return nil
+}
+
+// checksums computes the checksums of several types of a same stream at
+// once, so that it is read only once.
+type checksums map[string]hash.Hash
+
+// newChecksums returns checksums computing the checksums of checksummers,
+// along with the ones of the types of pins.
+func newChecksums(pins []PinnedChecksum, checksummers ...Checksummer) (checksums, error) {
+	cs := checksums{}
+	for _, checksummer := range checksummers {
+		cs[checksummer.Type] = checksummer.Hash
+	}
+	for _, pin := range pins {
+		if _, found := cs[pin.Type]; found {
+			continue
+		}
+		checksummer, err := NewChecksummer(pin.Type)
+		if err != nil {
+			return nil, err
+		}
+		cs[pin.Type] = checksummer.Hash
+	}
+	cs.reset()
+	return cs, nil
+}
+
+func (cs checksums) Write(b []byte) (int, error) {
+	for _, h := range cs {
+		_, _ = h.Write(b)
+	}
+	return len(b), nil
+}
+
+func (cs checksums) reset() {
+	for _, h := range cs {
+		h.Reset()
+	}
+}
+
+// verifyPinnedSums checks that cs, computed for the file named filename,
+// has every checksum of pins.
+func verifyPinnedSums(pins []PinnedChecksum, cs checksums, filename string) error {
+	for _, pin := range pins {
+		expected, err := hex.DecodeString(pin.Checksum)
+		if err != nil {
+			return fmt.Errorf("malformed pinned %s checksum %q: %v", pin.Type, pin.Checksum, err)
+		}
+		h, found := cs[pin.Type]
+		if !found {
+			return fmt.Errorf("the %s checksum of %s was not computed", pin.Type, filename)
+		}
+		if actual := h.Sum(nil); !bytes.Equal(actual, expected) {
+			return fmt.Errorf("the %s checksum %x of %s doesn't match the pinned checksum %s",
+				pin.Type, actual, filename, pin)
+		}
+	}
+	return nil
+}
+
+// verifyPinnedChecksums checks that the content of r, the file named filename,
+// has every checksum of pins.
+func verifyPinnedChecksums(pins []PinnedChecksum, r io.ReadSeeker, filename string) error {
+	cs, err := newChecksums(pins)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(cs, r); err != nil {
+		return fmt.Errorf("Failed to hash: %s", err)
+	}
+	if err := verifyPinnedSums(pins, cs, filename); err != nil {
+		return err
+	}
+	_, err = r.Seek(0, io.SeekStart)
+	return err
+}
+
+// verifyPinnedChecksumsOfFile is verifyPinnedChecksums for the file at path.
+func verifyPinnedChecksumsOfFile(pins []PinnedChecksum, path string) error {
+	if len(pins) == 0 {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return verifyPinnedChecksums(pins, f, path)
+}
@@ -0,0 +1,143 @@
+package plugingetter
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestParsePinnedChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", sha256.Size)
+	tests := []struct {
+		platform, checksum string
+		want               PinnedChecksum
+		wantErr            bool
+	}{
+		{"", "sha256:" + sum, PinnedChecksum{Type: "sha256", Checksum: sum}, false},
+		{"linux_amd64", "sha256:" + strings.ToUpper(sum), PinnedChecksum{OS: "linux", ARCH: "amd64", Type: "sha256", Checksum: sum}, false},
+		{"linux", "sha256:" + sum, PinnedChecksum{}, true},
+		{"", sum, PinnedChecksum{}, true},
+		{"", "md5:" + sum, PinnedChecksum{}, true},
+		{"", "sha512:" + sum, PinnedChecksum{}, true},
+		{"", "sha256:zz", PinnedChecksum{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePinnedChecksum(tt.platform, tt.checksum)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePinnedChecksum(%q, %q) error = %v, wantErr %v", tt.platform, tt.checksum, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParsePinnedChecksum(%q, %q) = %v, want %v", tt.platform, tt.checksum, got, tt.want)
+		}
+	}
+}
+
+func TestRequirement_InstallLatest_pinnedChecksums(t *testing.T) {
+	const (
+		zipName    = "packer-plugin-amazon_v2.10.0_x6.0_darwin_amd64.zip"
+		binaryName = "packer-plugin-amazon_v2.10.0_x6.0_darwin_amd64"
+		binary     = "v2.10.0_x6.0_darwin_amd64"
+	)
+	zip, err := ioutil.ReadAll(zipFile(map[string]string{binaryName: binary}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipSHA256 := sha256.Sum256(zip)
+	zipSHA512 := sha512.Sum512(zip)
+	binarySHA256 := sha256.Sum256([]byte(binary))
+	wrong := strings.Repeat("13", sha256.Size)
+
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+
+	tests := []struct {
+		name    string
+		pins    []PinnedChecksum
+		wantErr bool
+	}{
+		{"zip pinned", []PinnedChecksum{
+			{Type: "sha256", Checksum: hex.EncodeToString(zipSHA256[:])},
+			{Type: "sha512", Checksum: hex.EncodeToString(zipSHA512[:])},
+		}, false},
+		{"binary pinned", []PinnedChecksum{
+			{OS: "darwin", ARCH: "amd64", Type: "sha256", Checksum: hex.EncodeToString(binarySHA256[:]), Binary: true},
+		}, false},
+		{"pin of another platform", []PinnedChecksum{
+			{OS: "linux", ARCH: "amd64", Type: "sha256", Checksum: wrong},
+		}, false},
+		{"checksum file differs from pin", []PinnedChecksum{
+			{Type: "sha256", Checksum: wrong},
+		}, true},
+		{"zip differs from pin", []PinnedChecksum{
+			{Type: "sha512", Checksum: strings.Repeat("13", sha512.Size)},
+		}, true},
+		{"binary differs from pin", []PinnedChecksum{
+			{Type: "sha256", Checksum: wrong, Binary: true},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "packer-plugins-pinned")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			cts, err := version.NewConstraint(">= v2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			pr := &Requirement{
+				Identifier:         identifier,
+				VersionConstraints: cts,
+				Checksums:          tt.pins,
+			}
+			got, err := pr.InstallLatest(context.Background(), InstallOptions{
+				Getters: []Getter{
+					&mockPluginGetter{
+						Releases: []Release{{Version: "v2.10.0"}},
+						ChecksumFileEntries: map[string][]ChecksumFileEntry{
+							"2.10.0": {{Filename: zipName, Checksum: hex.EncodeToString(zipSHA256[:])}},
+						},
+						Zips: map[string]io.ReadCloser{
+							"github.com/hashicorp/packer-plugin-amazon/" + zipName: ioutil.NopCloser(strings.NewReader(string(zip))),
+						},
+					},
+				},
+				InFolders: []string{dir},
+				BinaryInstallationOptions: BinaryInstallationOptions{
+					APIVersionMajor: "6", APIVersionMinor: "0",
+					OS: "darwin", ARCH: "amd64",
+					Checksummers: []Checksummer{{Type: "sha256", Hash: sha256.New()}},
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InstallLatest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			binaryPath := filepath.Join(dir, "github.com", "hashicorp", "amazon", binaryName)
+			_, statErr := os.Stat(binaryPath)
+			if tt.wantErr {
+				if !os.IsNotExist(statErr) {
+					t.Errorf("a binary not matching its pins was installed: %v", statErr)
+				}
+				return
+			}
+			if got == nil || statErr != nil {
+				t.Errorf("expected %s to be installed, got %v: %v", binaryPath, got, statErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+)
+
+// PlanAction is what installing a plugin would do.
+type PlanAction string
+
+const (
+	// PlanInstall is the download and installation of a binary.
+	PlanInstall PlanAction = "install"
+	// PlanKeep leaves the plugin as it is, a binary allowed by the
+	// requirement being already installed.
+	PlanKeep PlanAction = "keep"
+)
+
+// PlannedAction describes what installing the plugin of a requirement would
+// do.
+type PlannedAction struct {
+	Requirement *Requirement
+	Action      PlanAction
+	// Installation is the planned installation, with its Planned download,
+	// or the installation kept when it is known.
+	Installation *Installation
+	// Reason tells why the plugin is kept, like "already installed".
+	Reason string
+}
+
+func (a *PlannedAction) String() string {
+	switch a.Action {
+	case PlanInstall:
+		return fmt.Sprintf("install %s %s in %q", a.Requirement.Identifier, a.Installation.Version, a.Installation.BinaryPath)
+	case PlanKeep:
+		if a.Installation != nil {
+			return fmt.Sprintf("keep %s %s in %q: %s", a.Requirement.Identifier, a.Installation.Version, a.Installation.BinaryPath, a.Reason)
+		}
+		return fmt.Sprintf("keep %s: %s", a.Requirement.Identifier, a.Reason)
+	}
+	return string(a.Action)
+}
+
+// Plan resolves the version of the plugin InstallLatest would install for
+// pr with opts, along with the checksum of its zip file and the files it
+// would write, without downloading or writing anything.
+func (pr *Requirement) Plan(ctx context.Context, opts InstallOptions) (*PlannedAction, error) {
+	opts.DryRun = true
+	install, err := pr.InstallLatest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if install == nil {
+		// InstallLatest returns no installation when the binary it would
+		// install is already there.
+		return &PlannedAction{Requirement: pr, Action: PlanKeep, Reason: "already installed"}, nil
+	}
+	Logf(ctx, "[TRACE] planned the installation of %s %s", pr.Identifier, install.Version)
+	return &PlannedAction{Requirement: pr, Action: PlanInstall, Installation: install}, nil
+}
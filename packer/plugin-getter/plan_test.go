@@ -0,0 +1,79 @@
+package plugingetter
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestRequirement_Plan(t *testing.T) {
+	getter := &mockPluginGetter{
+		Releases: []Release{
+			{Version: "v1.2.3"},
+			{Version: "v2.10.1"},
+		},
+		ChecksumFileEntries: map[string][]ChecksumFileEntry{
+			"1.2.3": {{
+				Filename: "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64.zip",
+				Checksum: "1337c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			}},
+			"2.10.1": {{
+				Filename: "packer-plugin-amazon_v2.10.1_x5.0_darwin_amd64.zip",
+				Checksum: "90ca5b0f13a90238b62581bbf30bacd7e2c9af6592c7f4849627bddbcb039dec",
+			}},
+		},
+	}
+	opts := func() InstallOptions {
+		return InstallOptions{
+			Getters:   []Getter{getter},
+			InFolders: []string{pluginFolderOne, pluginFolderTwo},
+			BinaryInstallationOptions: BinaryInstallationOptions{
+				APIVersionMajor: "5", APIVersionMinor: "0",
+				OS: "darwin", ARCH: "amd64",
+				Checksummers: []Checksummer{{Type: "sha256", Hash: sha256.New()}},
+			},
+		}
+	}
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+
+	tests := []struct {
+		constraint string
+		want       PlanAction
+	}{
+		{"v1.2.3", PlanKeep},
+		{">= v2", PlanInstall},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraints, err := version.NewConstraint(tt.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pr := &Requirement{Identifier: identifier, VersionConstraints: constraints}
+			action, err := pr.Plan(context.Background(), opts())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if action.Action != tt.want {
+				t.Fatalf("expected to %s the plugin, got %s", tt.want, action)
+			}
+			if tt.want != PlanInstall {
+				return
+			}
+			planned := action.Installation.Planned
+			if planned == nil || len(planned.Writes) != 3 || planned.Checksum == "" {
+				t.Fatalf("unexpected planned download %#v", planned)
+			}
+			if _, err := os.Stat(action.Installation.BinaryPath); !os.IsNotExist(err) {
+				t.Fatalf("planning should not install %s: %v", action.Installation.BinaryPath, err)
+			}
+		})
+	}
+}
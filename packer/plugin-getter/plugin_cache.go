@@ -0,0 +1,129 @@
+package plugingetter
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/longpath"
+)
+
+// cachedBinaryPath returns the path of a binary of the plugin in cacheDir,
+// which is laid out like a plugin folder.
+func (pr *Requirement) cachedBinaryPath(cacheDir, binaryFilename string) string {
+	return longpath.Fix(filepath.Join(cacheDir, filepath.Join(pr.Identifier.Parts()...), binaryFilename))
+}
+
+// installFromCache installs the binary at outputFileName, of version v, from
+// cacheDir, when it is there along with a checksum file it matches. Binaries
+// were verified against the checksums of their release when they were added
+// to the cache. The type of the checksum the binary was verified with is
+// returned, it is empty when the binary was not installed.
+func (pr *Requirement) installFromCache(ctx context.Context, cacheDir, outputFolder, outputFileName, v string, checksummers []Checksummer) (string, error) {
+	cached := pr.cachedBinaryPath(cacheDir, filepath.Base(outputFileName))
+	for _, checksummer := range checksummers {
+		cs, err := checksummer.GetCacheChecksumOfFile(cached)
+		if err != nil || len(cs) == 0 {
+			continue
+		}
+		if err := checksummer.ChecksumFile(cs, cached); err != nil {
+			Logf(ctx, "[TRACE] ignoring cached binary %q: %v", cached, err)
+			continue
+		}
+		if err := checkWithinFolder(outputFolder, outputFileName); err != nil {
+			return "", err
+		}
+		Logf(ctx, "[INFO] installing %q from the plugin cache", cached)
+		if err := linkOrCopy(ctx, cached, outputFileName); err != nil {
+			return "", fmt.Errorf("Failed to install %s from the plugin cache: %v", outputFileName, err)
+		}
+		_ = os.Remove(longpath.Fix(outputFileName + checksummer.FileExt()))
+		if err := ioutil.WriteFile(longpath.Fix(outputFileName+checksummer.FileExt()), []byte(hex.EncodeToString(cs)), 0555); err != nil {
+			Logf(ctx, "[WARNING] failed to write local binary checksum file: %s, ignoring", err)
+		}
+
+		// files of a previous install of this binary are replaced.
+		if err := removeInstallFiles(outputFileName); err != nil {
+			Logf(ctx, "[WARNING] failed to remove the previous install receipt of %s: %v, ignoring", outputFileName, err)
+		}
+		receipt := newInstallReceipt(pr, v)
+		receipt.Getter = ReceiptGetterPluginCache
+		receipt.BinaryChecksums = map[string]string{checksummer.Type: hex.EncodeToString(cs)}
+		if err := writeInstallReceipt(outputFileName, receipt); err != nil {
+			Logf(ctx, "[WARNING] failed to write the install receipt of %s: %v, ignoring", outputFileName, err)
+		}
+		return checksummer.Type, nil
+	}
+	return "", nil
+}
+
+// addToCache adds the installed binary at path, whose checksum is cs, to
+// cacheDir. Failing to do so only costs a download later, so errors are
+// logged.
+func (pr *Requirement) addToCache(ctx context.Context, cacheDir, path string, checksummer Checksummer, cs []byte) {
+	cached := pr.cachedBinaryPath(cacheDir, filepath.Base(path))
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		Logf(ctx, "[WARNING] could not create plugin cache folder: %v", err)
+		return
+	}
+	if err := linkOrCopy(ctx, path, cached); err != nil {
+		Logf(ctx, "[WARNING] could not add %q to the plugin cache: %v", path, err)
+		return
+	}
+	// checksum files are read only
+	_ = os.Remove(cached + checksummer.FileExt())
+	if err := ioutil.WriteFile(cached+checksummer.FileExt(), []byte(hex.EncodeToString(cs)), 0555); err != nil {
+		Logf(ctx, "[WARNING] could not write the checksum file of %q in the plugin cache: %v", cached, err)
+	}
+}
+
+// linkOrCopy hard links src to dst, or copies it when it can't be linked,
+// for example across file systems. dst is replaced atomically.
+func linkOrCopy(ctx context.Context, src, dst string) error {
+	src, dst = longpath.Fix(src), longpath.Fix(dst)
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	_ = tmp.Close()
+	defer os.Remove(tmpName)
+
+	// os.Link doesn't replace existing files
+	_ = os.Remove(tmpName)
+	if err := os.Link(src, tmpName); err != nil {
+		Logf(ctx, "[TRACE] could not link %q, copying it: %v", src, err)
+		if err := copyFile(src, tmpName); err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(tmpName, 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, dst)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,94 @@
+package plugingetter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestRequirement_InstallLatest_fromCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-plugin-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cacheDir, installDir := filepath.Join(dir, "cache"), filepath.Join(dir, "workspace")
+
+	binaryName := "packer-plugin-amazon_v1.2.3_x5.0_linux_amd64"
+	cached := filepath.Join(cacheDir, "github.com", "hashicorp", "amazon", binaryName)
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("cached binary")
+	if err := ioutil.WriteFile(cached, content, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	if err := ioutil.WriteFile(cached+"_SHA256SUM", []byte(hex.EncodeToString(sum[:])), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	pr := &Requirement{
+		Identifier:         identifier,
+		VersionConstraints: version.MustConstraints(version.NewConstraint(">= v1")),
+	}
+	// the mock getter has no zip file, it panics when the plugin is
+	// downloaded.
+	got, err := pr.InstallLatest(context.Background(), InstallOptions{
+		Getters: []Getter{
+			&mockPluginGetter{
+				Releases: []Release{{Version: "v1.2.3"}},
+				ChecksumFileEntries: map[string][]ChecksumFileEntry{
+					"1.2.3": {{
+						Filename: binaryName + ".zip",
+						Checksum: "1337c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+					}},
+				},
+			},
+		},
+		InFolders: []string{installDir},
+		CacheDir:  cacheDir,
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			APIVersionMajor: "5", APIVersionMinor: "0",
+			OS: "linux", ARCH: "amd64",
+			Checksummers: []Checksummer{
+				{Type: "sha256", Hash: sha256.New()},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installed := filepath.Join(installDir, "github.com", "hashicorp", "amazon", binaryName)
+	want := &Installation{
+		BinaryPath:   filepath.ToSlash(installed),
+		Version:      "v1.2.3",
+		APIVersion:   "x5.0",
+		OS:           "linux",
+		ARCH:         "amd64",
+		ChecksumType: "sha256",
+	}
+	if diff := cmp.Diff(want, got, ignoreModTime, ignoreReceipt); diff != "" {
+		t.Fatalf("InstallLatest(): %s", diff)
+	}
+	b, err := ioutil.ReadFile(installed)
+	if err != nil || string(b) != string(content) {
+		t.Fatalf("expected the cached binary to be installed, got %q, %v", b, err)
+	}
+	if _, err := os.Stat(installed + "_SHA256SUM"); err != nil {
+		t.Fatalf("expected a checksum file: %v", err)
+	}
+}
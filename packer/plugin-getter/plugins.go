@@ -1,22 +1,27 @@
 package plugingetter
 
 import (
-	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-version"
-	"github.com/hashicorp/packer-plugin-sdk/tmp"
 	"github.com/hashicorp/packer/hcl2template/addrs"
+	"github.com/hashicorp/packer/helper/longpath"
 )
 
 type Requirements []*Requirement
@@ -35,12 +40,84 @@ type Requirement struct {
 	// previous example.
 	Identifier *addrs.Plugin
 
+	// RedirectedFrom is the source address the plugin was required with,
+	// when ResolveRedirects found that it moved to Identifier.
+	RedirectedFrom *addrs.Plugin
+
 	// VersionConstraints as defined by user. Empty ( to be avoided ) means
-	// highest found version.
+	// highest found version. The versions excluded with "!=", like a
+	// known-broken release, are never installed nor used, even when
+	// pre-releases or the latest version are allowed.
 	VersionConstraints version.Constraints
 
+	// Latest is set when the user explicitly asked for the most recent
+	// release, using `version = "latest"`. It isn't read by this package:
+	// contrary to an empty constraint, it makes `packer init` look for a newer
+	// release even when a version is already installed, as if -upgrade was
+	// set for this plugin, and then update the lock file with the version
+	// installed.
+	Latest bool
+
 	// was this require implicitly guessed ?
 	Implicit bool
+
+	// IncludePrereleases allows pre-release versions, like v1.0.0-beta.1, to
+	// be installed and used when they, or the version they are a pre-release
+	// of, match the version constraints. Pre-releases are otherwise skipped,
+	// unless a version constraint names one, like ">= v1.0.0-beta.1".
+	IncludePrereleases bool
+
+	// Checksums pins the checksums of the release files of the plugin.
+	// InstallLatest refuses to install a release whose zip file, or binary,
+	// doesn't have the pinned checksums of its platform, whatever its
+	// checksum files say.
+	Checksums []PinnedChecksum
+
+	// Getter, when set, holds the getter settings of the plugin, like the
+	// mirror it is installed from. It isn't read by this package: `packer
+	// init` sets the Getters and ReleasesPublicKeys of the InstallOptions of
+	// the plugin from it.
+	Getter *GetterConfig
+}
+
+// Allows tells whether version v of the plugin satisfies pr. Pre-releases
+// are only allowed when pr opts in to them; see IncludePrereleases.
+func (pr *Requirement) Allows(v *version.Version) bool {
+	if pr.excludedBy(v) != nil {
+		return false
+	}
+	if v.Prerelease() == "" {
+		return pr.VersionConstraints.Check(v)
+	}
+	if pr.IncludePrereleases {
+		// constraints without a pre-release never match a pre-release, the
+		// version it is a pre-release of is checked instead.
+		return pr.VersionConstraints.Check(v) || pr.VersionConstraints.Check(v.Core())
+	}
+	return namesPrerelease(pr.VersionConstraints) && pr.VersionConstraints.Check(v)
+}
+
+// excludedBy returns the constraint of pr explicitly excluding version v,
+// like "!= 1.4.2", or nil when v isn't excluded.
+func (pr *Requirement) excludedBy(v *version.Version) *version.Constraint {
+	for _, c := range pr.VersionConstraints {
+		if strings.HasPrefix(c.String(), "!=") && !c.Check(v) {
+			return c
+		}
+	}
+	return nil
+}
+
+// namesPrerelease tells whether one of constraints is on a pre-release, like
+// ">= v1.0.0-beta.1".
+func namesPrerelease(constraints version.Constraints) bool {
+	for _, c := range constraints {
+		v, err := version.NewVersion(strings.TrimLeft(c.String(), "=!<>~ "))
+		if err == nil && v.Prerelease() != "" {
+			return true
+		}
+	}
+	return false
 }
 
 type BinaryInstallationOptions struct {
@@ -61,6 +138,10 @@ type ListInstallationsOptions struct {
 	// safety but can also be relative.
 	FromFolders []string
 
+	// Offline, when set, makes ListInstallations fail with an error wrapping
+	// ErrOffline when no binary matches, as none could be downloaded.
+	Offline bool
+
 	BinaryInstallationOptions
 }
 
@@ -80,79 +161,217 @@ func (opts BinaryInstallationOptions) filenameSuffix() string {
 //
 // At least one opts.Checksumers must be given for a binary to be even
 // considered.
-func (pr Requirement) ListInstallations(opts ListInstallationsOptions) (InstallList, error) {
+func (pr Requirement) ListInstallations(ctx context.Context, opts ListInstallationsOptions) (InstallList, error) {
+	installs, err := pr.installations(ctx, opts, false)
+	if err != nil {
+		return nil, err
+	}
 	res := InstallList{}
+	for _, install := range installs {
+		res.InsertSortedUniq(install)
+	}
+	if opts.Offline && len(res) == 0 {
+		return nil, pr.offlineError(opts.FromFolders)
+	}
+	return res, nil
+}
+
+// installations returns the binaries of the plugin matching the version
+// constraints of pr, in the order of opts.FromFolders. Binaries that Packer
+// can't use, because of their protocol version or of their checksum, are
+// only returned when all is set. Listing stops when ctx is done.
+//
+// Folders are listed concurrently, and so are the binaries verified, as
+// verifying the checksum of a binary reads all of it.
+func (pr Requirement) installations(ctx context.Context, opts ListInstallationsOptions, all bool) ([]*Installation, error) {
 	FilenamePrefix := pr.FilenamePrefix()
 	filenameSuffix := opts.filenameSuffix()
-	log.Printf("[TRACE] listing potential installations for %q that match %q. %#v", pr.Identifier, pr.VersionConstraints, opts)
-	for _, knownFolder := range opts.FromFolders {
-		glob := filepath.Join(knownFolder, pr.Identifier.Hostname, pr.Identifier.Namespace, pr.Identifier.Type, FilenamePrefix+"*"+filenameSuffix)
+	Logf(ctx, "[TRACE] listing potential installations for %q that match %q. %#v", pr.Identifier, pr.VersionConstraints, opts)
+	pattern := path.Join(pr.Identifier.Hostname, pr.Identifier.Namespace, pr.Identifier.Type, FilenamePrefix+"*"+filenameSuffix)
+
+	folderMatches := make([][]string, len(opts.FromFolders))
+	folderErrs := make([]error, len(opts.FromFolders))
+	var wg sync.WaitGroup
+	for i, knownFolder := range opts.FromFolders {
+		wg.Add(1)
+		go func(i int, knownFolder string) {
+			defer wg.Done()
+			folderMatches[i], folderErrs[i] = longpath.Glob(knownFolder, pattern)
+		}(i, knownFolder)
+	}
+	wg.Wait()
+	var paths []string
+	for i, matches := range folderMatches {
+		if err := folderErrs[i]; err != nil {
+			return nil, fmt.Errorf("ListInstallations: %q failed to list binaries in folder: %v", pr.Identifier.String(), err)
+		}
+		paths = append(paths, matches...)
+	}
 
-		matches, err := filepath.Glob(glob)
+	// hashes are not safe for concurrent use, every worker verifies binaries
+	// with checksummers of its own. Checksummers of unknown types can't be
+	// copied, binaries are then verified one at a time.
+	workers := runtime.NumCPU()
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	workerChecksummers := make([][]Checksummer, workers)
+	for w := range workerChecksummers {
+		checksummers, err := copyChecksummers(opts.Checksummers)
 		if err != nil {
-			return nil, fmt.Errorf("ListInstallations: %q failed to list binaries in folder: %v", pr.Identifier.String(), err)
+			Logf(ctx, "[TRACE] verifying binaries one at a time: %v", err)
+			workerChecksummers = [][]Checksummer{opts.Checksummers}
+			break
 		}
-		for _, path := range matches {
-			fname := filepath.Base(path)
-			if fname == "." {
-				continue
+		workerChecksummers[w] = checksummers
+	}
+
+	installs := make([]*Installation, len(paths))
+	work := make(chan int)
+	for _, checksummers := range workerChecksummers {
+		wg.Add(1)
+		go func(checksummers []Checksummer) {
+			defer wg.Done()
+			for i := range work {
+				installs[i] = pr.installation(ctx, opts, paths[i], checksummers, all)
 			}
+		}(checksummers)
+	}
+feed:
+	for i := range paths {
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-			// base name could look like packer-plugin-amazon_v1.2.3_x5.1_darwin_amd64.exe
-			versionsStr := strings.TrimPrefix(fname, FilenamePrefix)
-			versionsStr = strings.TrimSuffix(versionsStr, filenameSuffix)
+	var res []*Installation
+	for _, install := range installs {
+		if install != nil {
+			res = append(res, install)
+		}
+	}
+	return res, nil
+}
 
-			// versionsStr now looks like v1.2.3_x5.1
-			parts := strings.SplitN(versionsStr, "_", 2)
-			pluginVersionStr, protocolVerionStr := parts[0], parts[1]
-			pv, err := version.NewVersion(pluginVersionStr)
-			if err != nil {
-				// could not be parsed, ignoring the file
-				log.Printf("found %q with an incorrect %q version, ignoring it. %v", path, pluginVersionStr, err)
-				continue
-			}
+// installation returns the installation of the binary at path, when it
+// matches the version constraints of pr and, unless all is set, when Packer
+// can use it. Its checksum is verified with checksummers.
+func (pr Requirement) installation(ctx context.Context, opts ListInstallationsOptions, path string, checksummers []Checksummer, all bool) *Installation {
+	fname := filepath.Base(path)
+	if fname == "." {
+		return nil
+	}
 
-			// no constraint means always pass, this will happen for implicit
-			// plugin requirements
-			if !pr.VersionConstraints.Check(pv) {
-				log.Printf("[TRACE] version %q of file %q does not match constraint %q", pluginVersionStr, path, pr.VersionConstraints.String())
-				continue
-			}
+	// base name could look like packer-plugin-amazon_v1.2.3_x5.1_darwin_amd64.exe
+	parsed, err := parsePluginFilename(strings.TrimSuffix(strings.TrimPrefix(fname, pr.FilenamePrefix()), opts.Ext))
+	if err != nil {
+		// could not be parsed, ignoring the file
+		Logf(ctx, "found %q with an incorrect name, ignoring it. %v", path, err)
+		return nil
+	}
+	pluginVersionStr, protocolVerionStr := parsed.version, parsed.protocol
+	pv, err := version.NewVersion(pluginVersionStr)
+	if err != nil {
+		// could not be parsed, ignoring the file
+		Logf(ctx, "found %q with an incorrect %q version, ignoring it. %v", path, pluginVersionStr, err)
+		return nil
+	}
 
-			if err := opts.CheckProtocolVersion(protocolVerionStr); err != nil {
-				log.Printf("[NOTICE] binary %s requires protocol version %s that is incompatible "+
-					"with this version of Packer. %s", path, protocolVerionStr, err)
-				continue
-			}
+	// no constraint means always pass, this will happen for implicit
+	// plugin requirements. Every matching binary is removed, including
+	// pre-releases.
+	matches := pr.Allows(pv)
+	if all {
+		matches = pr.VersionConstraints.Check(pv)
+	}
+	if !matches {
+		if c := pr.excludedBy(pv); c != nil {
+			Logf(ctx, "[INFO] ignoring %q, version %s of the %s plugin is excluded by the %q constraint", path, pv, pr.Identifier, c)
+			return nil
+		}
+		Logf(ctx, "[TRACE] version %q of file %q does not match constraint %q", pluginVersionStr, path, pr.VersionConstraints.String())
+		return nil
+	}
 
-			checksumOk := false
-			for _, checksummer := range opts.Checksummers {
+	if all {
+		return newInstallation(path, parsed, "")
+	}
 
-				cs, err := checksummer.GetCacheChecksumOfFile(path)
-				if err != nil {
-					log.Printf("[TRACE] GetChecksumOfFile(%q) failed: %v", path, err)
-					continue
-				}
+	if err := opts.CheckProtocolVersion(protocolVerionStr); err != nil {
+		Logf(ctx, "[NOTICE] binary %s requires protocol version %s that is incompatible "+
+			"with this version of Packer. %s", path, protocolVerionStr, err)
+		return nil
+	}
 
-				if err := checksummer.ChecksumFile(cs, path); err != nil {
-					log.Printf("[TRACE] ChecksumFile(%q) failed: %v", path, err)
-					continue
-				}
-				checksumOk = true
-				break
-			}
-			if !checksumOk {
-				log.Printf("[TRACE] No checksum found for %q ignoring possibly unsafe binary", path)
-				continue
-			}
+	checksumType := ""
+	for _, checksummer := range checksummers {
+
+		cs, err := checksummer.GetCacheChecksumOfFile(path)
+		if err != nil {
+			Logf(ctx, "[TRACE] GetChecksumOfFile(%q) failed: %v", path, err)
+			continue
+		}
 
-			res.InsertSortedUniq(&Installation{
-				BinaryPath: path,
-				Version:    pluginVersionStr,
-			})
+		if err := checksummer.checksumFileCached(cs, path); err != nil {
+			Logf(ctx, "[TRACE] ChecksumFile(%q) failed: %v", path, err)
+			continue
 		}
+		checksumType = checksummer.Type
+		break
 	}
-	return res, nil
+	if checksumType == "" {
+		Logf(ctx, "[TRACE] No checksum found for %q ignoring possibly unsafe binary", path)
+		return nil
+	}
+
+	return newInstallation(path, parsed, checksumType)
+}
+
+// Uninstall removes the binaries of the plugin matching the version
+// constraints of pr, along with their checksum files, and returns the
+// removed installations. Binaries are looked up in opts.FromFolders like
+// ListInstallations does; but every matching binary is removed, including
+// the ones Packer ignores and the ones shadowed by a binary of the same
+// version in another folder.
+func (pr Requirement) Uninstall(opts ListInstallationsOptions) (InstallList, error) {
+	ctx := context.Background()
+	installs, err := pr.installations(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	removed := InstallList{}
+	for _, install := range installs {
+		if err := removeInstallation(ctx, install, opts.Checksummers); err != nil {
+			return removed, fmt.Errorf("Uninstall: %q %v", pr.Identifier.String(), err)
+		}
+		removed = append(removed, install)
+	}
+	return removed, nil
+}
+
+// removeInstallation removes the binary of install and its checksum files.
+func removeInstallation(ctx context.Context, install *Installation, checksummers []Checksummer) error {
+	Logf(ctx, "[TRACE] removing %q", install.BinaryPath)
+	if err := os.Remove(install.BinaryPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove binary: %v", err)
+	}
+	for _, checksummer := range checksummers {
+		checksumPath := install.BinaryPath + checksummer.FileExt()
+		if err := os.Remove(checksumPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove checksum file: %v", err)
+		}
+	}
+	if err := removeInstallFiles(install.BinaryPath); err != nil {
+		return fmt.Errorf("failed to remove install receipt: %v", err)
+	}
+	return nil
 }
 
 // InstallList is a list of installed plugins (binaries) with their versions,
@@ -162,6 +381,20 @@ func (pr Requirement) ListInstallations(opts ListInstallationsOptions) (InstallL
 // returned.
 type InstallList []*Installation
 
+// versionLess tells whether the installed version a sorts before b. Versions
+// that can't be parsed are compared lexicographically.
+func versionLess(a, b string) bool {
+	va, errA := version.NewVersion(a)
+	vb, errB := version.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	if c := va.Compare(vb); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+
 func (l InstallList) String() string {
 	v := &strings.Builder{}
 	v.Write([]byte("["))
@@ -175,11 +408,58 @@ func (l InstallList) String() string {
 	return v.String()
 }
 
+// MarshalJSON marshals l as a JSON array, which is empty when l is.
+func (l InstallList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]*Installation(l))
+}
+
+// Filter returns the installations of l for which keep returns true, in the
+// same order.
+func (l InstallList) Filter(keep func(*Installation) bool) InstallList {
+	res := InstallList{}
+	for _, install := range l {
+		if keep(install) {
+			res = append(res, install)
+		}
+	}
+	return res
+}
+
+// ForPlatform returns the installations of l built for os and arch.
+func (l InstallList) ForPlatform(os, arch string) InstallList {
+	return l.Filter(func(install *Installation) bool {
+		return install.OS == os && install.ARCH == arch
+	})
+}
+
+// Matching returns the installations of l whose version matches constraints.
+func (l InstallList) Matching(constraints version.Constraints) InstallList {
+	return l.Filter(func(install *Installation) bool {
+		v, err := version.NewVersion(install.Version)
+		return err == nil && constraints.Check(v)
+	})
+}
+
+// Latest returns the installation of the highest version of l, or nil when l
+// is empty. ListInstallations sorts installations by version, so it is the
+// last one.
+func (l InstallList) Latest() *Installation {
+	if len(l) == 0 {
+		return nil
+	}
+	return l[len(l)-1]
+}
+
 // InsertSortedUniq inserts the installation in the right spot in the list by
-// comparing the version lexicographically.
+// comparing the semantic versions, so that pre-releases sort before their
+// release. Versions that only differ by their build metadata, like v1.2.3 and
+// v1.2.3+ent, are different builds sorted lexicographically.
 // A Duplicate version will replace any already present version.
 func (l *InstallList) InsertSortedUniq(install *Installation) {
-	pos := sort.Search(len(*l), func(i int) bool { return (*l)[i].Version >= install.Version })
+	pos := sort.Search(len(*l), func(i int) bool { return !versionLess((*l)[i].Version, install.Version) })
 	if len(*l) > pos && (*l)[pos].Version == install.Version {
 		// already detected, let's ignore any new foundings, this way any plugin
 		// close to cwd or the packer exec takes precedence; this will be better
@@ -195,12 +475,76 @@ func (l *InstallList) InsertSortedUniq(install *Installation) {
 type Installation struct {
 	// path to where binary is installed, if installed.
 	// Ex: /usr/azr/.packer.d/plugins/github.com/hashicorp/packer-plugin-amazon/packer-plugin-amazon_v1.2.3_darwin_amd64
-	BinaryPath string
+	BinaryPath string `json:"binary_path"`
 
 	// Version of this plugin, if installed and versionned. Ex:
 	//  * v1.2.3 for packer-plugin-amazon_v1.2.3_darwin_x5
 	//  * empty  for packer-plugin-amazon
-	Version string
+	Version string `json:"version"`
+
+	// APIVersion is the plugin protocol version of the binary, like x5.0.
+	APIVersion string `json:"api_version,omitempty"`
+
+	// OS and ARCH the binary was built for, like linux and amd64.
+	OS   string `json:"os,omitempty"`
+	ARCH string `json:"arch,omitempty"`
+
+	// ChecksumType is the type of the checksum file the binary was verified
+	// with, like sha256. It is empty when the binary was not verified.
+	ChecksumType string `json:"checksum_type,omitempty"`
+
+	// ModTime is the modification time of the binary, zero when planned.
+	ModTime time.Time `json:"mod_time"`
+
+	// Planned is only set by InstallLatest in dry-run mode, BinaryPath is
+	// then where the plugin would be installed.
+	Planned *PlannedDownload `json:"planned,omitempty"`
+
+	// Receipt tells where the binary comes from, when it was installed by
+	// InstallLatest. It is nil for the binaries installed manually.
+	Receipt *InstallReceipt `json:"receipt,omitempty"`
+}
+
+// newInstallation returns the installation of the binary at path, named after
+// parsed, which was verified with a checksum of checksumType.
+func newInstallation(path string, parsed pluginFilename, checksumType string) *Installation {
+	install := &Installation{
+		BinaryPath:   path,
+		Version:      parsed.version,
+		APIVersion:   parsed.protocol,
+		OS:           parsed.os,
+		ARCH:         parsed.arch,
+		ChecksumType: checksumType,
+	}
+	if fi, err := os.Stat(longpath.Fix(path)); err == nil {
+		install.ModTime = fi.ModTime()
+	}
+	// receipts are informational, invalid ones are ignored.
+	if receipt, err := ReadInstallReceipt(path); err == nil {
+		install.Receipt = receipt
+	}
+	return install
+}
+
+// PlannedDownload describes a download InstallLatest would do.
+type PlannedDownload struct {
+	// SourceURL of the zip file, empty when unknown.
+	SourceURL string `json:"source_url,omitempty"`
+	// Size of the zip file in bytes, -1 when unknown.
+	Size int64 `json:"size"`
+	// Checksum the zip file would be verified with, like sha256:<hex>.
+	Checksum string `json:"checksum,omitempty"`
+	// Writes lists the files the installation would write: the binary, its
+	// checksum file and its install receipt.
+	Writes []string `json:"writes,omitempty"`
+}
+
+// Locator is implemented by getters that can tell where a file would be
+// fetched from, without fetching it.
+type Locator interface {
+	// Locate returns the URL of what, and its size in bytes or -1 when
+	// unknown.
+	Locate(ctx context.Context, what string, opts GetOptions) (url string, size int64, err error)
 }
 
 // InstallOptions describes the possible options for installing the plugin that
@@ -213,6 +557,50 @@ type InstallOptions struct {
 	// folder of this list.
 	InFolders []string
 
+	// DryRun makes InstallLatest resolve the version to install and return
+	// a planned Installation, without downloading or writing anything.
+	DryRun bool
+
+	// ReleasesPublicKeys, when set, requires the releases document of a
+	// getter to be signed by one of these keys. Getters that can't provide
+	// a valid "releases.sig" signature are skipped.
+	ReleasesPublicKeys []ed25519.PublicKey
+
+	// Locked, when set, is the version of the plugin recorded in the lock
+	// file. Only that version can be installed, and the checksum of its zip
+	// file must be one of the locked hashes.
+	Locked *LockedPlugin
+
+	// CacheDir, when set, is a plugin folder shared between workspaces.
+	// Installed binaries are added to it, and binaries found there are hard
+	// linked, or copied, into the install folder instead of being downloaded.
+	CacheDir string
+
+	// RetryPolicy, when set, retries the calls to the getters failing with
+	// transient errors, before moving on to the next getter.
+	RetryPolicy *RetryPolicy
+
+	// ProgressTracker, when set, is told about the progress of the download
+	// of the zip file and of the extraction of the binary.
+	ProgressTracker ProgressTracker
+
+	// KeepVersions, when set, makes InstallLatest remove the older versions
+	// of the plugin from the install folder once a version is installed, so
+	// that only the KeepVersions highest versions up to the installed one
+	// remain. Higher versions are left untouched.
+	KeepVersions int
+
+	// ExtractFiles are path.Match patterns of files, like "LICENSE*", to
+	// extract from the archive along with the binary. They are installed in
+	// the FilesDir of the binary, and listed in its install receipt.
+	ExtractFiles []string
+
+	// Offline, when set, makes InstallLatest never call the Getters, so that
+	// environments without network access fail fast. It then fails with an
+	// error wrapping ErrOffline, unless a binary matching the requirement,
+	// and Locked when set, is installed in InFolders.
+	Offline bool
+
 	BinaryInstallationOptions
 }
 
@@ -226,8 +614,9 @@ type GetOptions struct {
 	expectedZipFilename string
 }
 
-// ExpectedZipFilename is the filename of the zip we expect to find, the
-// value is known only after parsing the checksum file file.
+// ExpectedZipFilename is the filename of the archive we expect to find, a
+// zip file or a tarball, see ArchiveExtensions. The value is known only after
+// parsing the checksum file file.
 func (gp *GetOptions) ExpectedZipFilename() string {
 	return gp.expectedZipFilename
 }
@@ -277,7 +666,10 @@ type Getter interface {
 	//  * 'releases'
 	//  * 'sha256'
 	//  * 'binary'
-	Get(what string, opts GetOptions) (io.ReadCloser, error)
+	//  * 'redirect', optionally, see Redirect
+	//
+	// Requests are cancelled when ctx is done.
+	Get(ctx context.Context, what string, opts GetOptions) (io.ReadCloser, error)
 }
 
 type Release struct {
@@ -303,28 +695,59 @@ func (e ChecksumFileEntry) ProtVersion() string { return e.protVersion }
 func (e ChecksumFileEntry) Os() string          { return e.os }
 func (e ChecksumFileEntry) Arch() string        { return e.arch }
 
+// filename returns the parts of the name of the binary of e, of version.
+func (e ChecksumFileEntry) filename(version string) pluginFilename {
+	return pluginFilename{version: version, protocol: e.protVersion, os: e.os, arch: e.arch}
+}
+
+// pluginFilename holds the parts of the name of a plugin binary or zip file.
+type pluginFilename struct {
+	version, protocol, os, arch string
+}
+
+// parsePluginFilename parses the part of the name of a plugin file that is
+// between its prefix and its extension, like v0.2.12_x5.0_freebsd_amd64.
+// Semver forbids underscores, so the version can carry a pre-release and
+// build metadata, like v1.2.3-beta.1 or v1.2.3+ent, without being mistaken
+// for the protocol version.
+func parsePluginFilename(s string) (pluginFilename, error) {
+	parts := strings.Split(s, "_")
+	// ["v0.2.12", "x5.0", "freebsd", "amd64"]
+	if len(parts) != 4 || !strings.HasPrefix(parts[0], "v") || !strings.HasPrefix(parts[1], "x") {
+		return pluginFilename{}, fmt.Errorf("%q is not like v{version}_x{protocol-version}_{os}_{arch}", s)
+	}
+	if _, err := version.NewVersion(parts[0]); err != nil {
+		return pluginFilename{}, fmt.Errorf("invalid version %q: %s", parts[0], err)
+	}
+	return pluginFilename{version: parts[0], protocol: parts[1], os: parts[2], arch: parts[3]}, nil
+}
+
 // a file inside will look like so:
 //  packer-plugin-comment_v0.2.12_x5.0_freebsd_amd64.zip
+// or, for a tarball, with one of the other ArchiveExtensions:
+//  packer-plugin-comment_v0.2.12_x5.0_linux_amd64.tar.gz
 //
 func (e *ChecksumFileEntry) init(req *Requirement) (err error) {
 	filename := e.Filename
 	res := strings.TrimPrefix(filename, req.FilenamePrefix())
 	// res now looks like v0.2.12_x5.0_freebsd_amd64.zip
 
-	e.ext = filepath.Ext(res)
+	e.ext = ArchiveExt(res)
+	if e.ext == "" {
+		return fmt.Errorf("unsupported archive %q, expected one of the %s extensions", filename, strings.Join(ArchiveExtensions, ", "))
+	}
 
 	res = strings.TrimSuffix(res, e.ext)
 	// res now looks like v0.2.12_x5.0_freebsd_amd64
 
-	parts := strings.Split(res, "_")
-	// ["v0.2.12", "x5.0", "freebsd", "amd64"]
-	if len(parts) < 4 {
-		return fmt.Errorf("malformed filename expected %s{version}_x{protocol-version}_{os}_{arch}", req.FilenamePrefix())
+	parsed, err := parsePluginFilename(res)
+	if err != nil {
+		return fmt.Errorf("malformed filename expected %s{version}_x{protocol-version}_{os}_{arch}: %s", req.FilenamePrefix(), err)
 	}
 
-	e.binVersion, e.protVersion, e.os, e.arch = parts[0], parts[1], parts[2], parts[3]
+	e.binVersion, e.protVersion, e.os, e.arch = parsed.version, parsed.protocol, parsed.os, parsed.arch
 
-	return err
+	return nil
 }
 
 func (e *ChecksumFileEntry) validate(expectedVersion string, installOpts BinaryInstallationOptions) error {
@@ -343,50 +766,119 @@ func ParseChecksumFileEntries(f io.Reader) ([]ChecksumFileEntry, error) {
 	return entries, json.NewDecoder(f).Decode(&entries)
 }
 
-func (pr *Requirement) InstallLatest(opts InstallOptions) (*Installation, error) {
+// InstallLatest installs the highest version of the plugin allowed by pr,
+// and opts.Locked when set. Downloads are cancelled when ctx is done; the
+// partial zip file is then kept so that a later install can resume it.
+func (pr *Requirement) InstallLatest(ctx context.Context, opts InstallOptions) (*Installation, error) {
+	install, err := pr.installLatest(ctx, opts)
+	if err != nil || install == nil || install.Planned != nil || opts.KeepVersions <= 0 {
+		return install, err
+	}
+	// only the install folder is pruned, other folders may be shared or
+	// read-only.
+	listOpts := ListInstallationsOptions{
+		FromFolders:               opts.InFolders[len(opts.InFolders)-1:],
+		BinaryInstallationOptions: opts.BinaryInstallationOptions,
+	}
+	removed, pruneErr := Requirement{Identifier: pr.Identifier}.prune(ctx, listOpts, opts.KeepVersions, install.Version)
+	for _, r := range removed {
+		Logf(ctx, "[INFO] removed superseded version %s of the %s plugin: %q", r.Version, pr.Identifier, r.BinaryPath)
+	}
+	if pruneErr != nil {
+		Logf(ctx, "[WARN] could not remove superseded versions of the %s plugin: %s", pr.Identifier, pruneErr)
+	}
+	return install, nil
+}
+
+// InstallVersion installs version v of the plugin, whatever the version
+// constraints of pr and opts.Locked, so that a version older than the
+// installed ones can be installed. It goes through the same checksum
+// verifications as InstallLatest. No Installation is returned when version v
+// is already installed.
+func (pr *Requirement) InstallVersion(ctx context.Context, v *version.Version, opts InstallOptions) (*Installation, error) {
+	constraints, err := version.NewConstraint("= " + v.String())
+	if err != nil {
+		return nil, err
+	}
+	exact := *pr
+	exact.VersionConstraints = constraints
+	exact.Latest = false
+	opts.Locked = nil
+	Logf(ctx, "[TRACE] installing version %s of the %s plugin", v, pr.Identifier)
+	return exact.InstallLatest(ctx, opts)
+}
+
+func (pr *Requirement) installLatest(ctx context.Context, opts InstallOptions) (*Installation, error) {
+	if opts.Offline {
+		return pr.installOffline(ctx, opts)
+	}
 
-	getters := opts.Getters
+	getters := make([]Getter, 0, len(opts.Getters))
+	for _, getter := range opts.Getters {
+		getters = append(getters, opts.RetryPolicy.Wrap(getter))
+	}
 	fail := fmt.Errorf("could not find a local nor a remote checksum for plugin %q %q", pr.Identifier, pr.VersionConstraints)
 
-	log.Printf("[TRACE] getting available versions for the %s plugin", pr.Identifier)
+	Logf(ctx, "[TRACE] getting available versions for the %s plugin", pr.Identifier)
 	versions := version.Collection{}
 	for _, getter := range getters {
 
-		releasesFile, err := getter.Get("releases", GetOptions{
+		getOpts := GetOptions{
 			PluginRequirement:         pr,
 			BinaryInstallationOptions: opts.BinaryInstallationOptions,
-		})
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		releasesFile, err := getter.Get(ctx, "releases", getOpts)
 		if err != nil {
 			err := fmt.Errorf("%q getter could not get release: %w", getter, err)
-			log.Printf("[TRACE] %s", err.Error())
+			Logf(ctx, "[TRACE] %s", err.Error())
 			continue
 		}
 
+		if len(opts.ReleasesPublicKeys) > 0 {
+			releasesFile, err = verifyReleases(ctx, opts.ReleasesPublicKeys, getter, getOpts, releasesFile)
+			if err != nil {
+				err := fmt.Errorf("could not verify releases of %s: %w", pr.Identifier, err)
+				Logf(ctx, "[WARN] %s, ignoring getter", err.Error())
+				continue
+			}
+		}
+
 		releases, err := ParseReleases(releasesFile)
 		if err != nil {
 			err := fmt.Errorf("could not parse release: %w", err)
-			log.Printf("[TRACE] %s", err.Error())
+			Logf(ctx, "[TRACE] %s", err.Error())
 			continue
 		}
 		if len(releases) == 0 {
 			err := fmt.Errorf("no release found")
-			log.Printf("[TRACE] %s", err.Error())
+			Logf(ctx, "[TRACE] %s", err.Error())
 			continue
 		}
 		for _, release := range releases {
 			v, err := version.NewVersion(release.Version)
 			if err != nil {
 				err := fmt.Errorf("Could not parse release version %s. %w", release.Version, err)
-				log.Printf("[TRACE] %s, ignoring it", err.Error())
+				Logf(ctx, "[TRACE] %s, ignoring it", err.Error())
 				continue
 			}
-			if pr.VersionConstraints.Check(v) {
+			if !pr.Allows(v) {
+				if c := pr.excludedBy(v); c != nil {
+					Logf(ctx, "[INFO] skipping version %s of the %s plugin, excluded by the %q constraint", v, pr.Identifier, c)
+				} else if v.Prerelease() != "" {
+					Logf(ctx, "[TRACE] skipping pre-release %s of the %s plugin", v, pr.Identifier)
+				}
+				continue
+			}
+			if opts.Locked.allows(v) {
 				versions = append(versions, v)
 			}
 		}
 		if len(versions) == 0 {
 			err := fmt.Errorf("no matching version found in releases. In %v", releases)
-			log.Printf("[TRACE] %s", err.Error())
+			Logf(ctx, "[TRACE] %s", err.Error())
 			continue
 		}
 
@@ -397,8 +889,11 @@ func (pr *Requirement) InstallLatest(opts InstallOptions) (*Installation, error)
 	// that matches the requirements.
 	// The system and protocol version need to match too.
 	sort.Sort(sort.Reverse(versions))
-	log.Printf("[DEBUG] will try to install: %s", versions)
+	Logf(ctx, "[DEBUG] will try to install: %s", versions)
 
+	if len(versions) == 0 && opts.Locked != nil {
+		return nil, fmt.Errorf("the locked version %s of the %s plugin was not found in its releases", opts.Locked.Version, pr.Identifier)
+	}
 	if len(versions) == 0 {
 		err := fmt.Errorf("no release version found for the %s plugin matching the constraint(s): %q", pr.Identifier, pr.VersionConstraints.String())
 		return nil, err
@@ -414,9 +909,14 @@ func (pr *Requirement) InstallLatest(opts InstallOptions) (*Installation, error)
 			filepath.Join(pr.Identifier.Parts()...),
 		)
 
-		log.Printf("[TRACE] fetching checksums file for the %q version of the %s plugin in %q...", version, pr.Identifier, outputFolder)
+		Logf(ctx, "[TRACE] fetching checksums file for the %q version of the %s plugin in %q...", version, pr.Identifier, outputFolder)
 
 		var checksum *FileChecksum
+		// releases publish the checksum files of some of the checksum types
+		// only, so a missing checksum file is only an error when no checksum
+		// file of any type was found.
+		checksumFileFound := false
+		var checksumFileErrs []string
 		for _, getter := range getters {
 			if checksum != nil {
 				break
@@ -425,48 +925,61 @@ func (pr *Requirement) InstallLatest(opts InstallOptions) (*Installation, error)
 				if checksum != nil {
 					break
 				}
-				checksumFile, err := getter.Get(checksummer.Type, GetOptions{
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				checksumFile, err := getter.Get(ctx, checksummer.Type, GetOptions{
 					PluginRequirement:         pr,
 					BinaryInstallationOptions: opts.BinaryInstallationOptions,
 					version:                   version,
 				})
 				if err != nil {
-					err := fmt.Errorf("could not get %s checksum file for %s version %s. Is the file present on the release and correctly named ? %s", checksummer.Type, pr.Identifier, version, err)
-					log.Printf("[TRACE] %s", err.Error())
-					return nil, err
+					Logf(ctx, "[TRACE] could not get %s checksum file for %s version %s: %s", checksummer.Type, pr.Identifier, version, err)
+					checksumFileErrs = append(checksumFileErrs, fmt.Sprintf("%s: %s", checksummer.Type, err))
+					continue
 				}
+				checksumFileFound = true
 				entries, err := ParseChecksumFileEntries(checksumFile)
 				_ = checksumFile.Close()
 				if err != nil {
-					log.Printf("[TRACE] could not parse %s checksumfile: %v. Make sure the checksum file contains a checksum and a binary filename per line.", checksummer.Type, err)
+					Logf(ctx, "[TRACE] could not parse %s checksumfile: %v. Make sure the checksum file contains a checksum and a binary filename per line.", checksummer.Type, err)
 					continue
 				}
 
 				for _, entry := range entries {
 					if err := entry.init(pr); err != nil {
-						log.Printf("[TRACE] could not parse checksum filename %s. Is it correctly formatted ? %s", entry.Filename, err)
+						Logf(ctx, "[TRACE] could not parse checksum filename %s. Is it correctly formatted ? %s", entry.Filename, err)
 						continue
 					}
 					if err := entry.validate("v"+version.String(), opts.BinaryInstallationOptions); err != nil {
-						log.Printf("[TRACE] Ignoring remote binary %s, %s", entry.Filename, err)
+						Logf(ctx, "[TRACE] Ignoring remote binary %s, %s", entry.Filename, err)
 						continue
 					}
 
-					log.Printf("[TRACE] About to get: %s", entry.Filename)
+					Logf(ctx, "[TRACE] About to get: %s", entry.Filename)
 
 					cs, err := checksummer.ParseChecksum(strings.NewReader(entry.Checksum))
 					if err != nil {
-						log.Printf("[TRACE] could not parse %s checksum: %v. Make sure the checksum file contains the checksum and only the checksum.", checksummer.Type, err)
+						Logf(ctx, "[TRACE] could not parse %s checksum: %v. Make sure the checksum file contains the checksum and only the checksum.", checksummer.Type, err)
 						continue
 					}
 
+					if err := opts.Locked.verifyHash(checksummer.Type, entry.Checksum); err != nil {
+						return nil, err
+					}
+					zipPins := pr.pinnedChecksums(entry.Os(), entry.Arch(), false)
+					binaryPins := pr.pinnedChecksums(entry.Os(), entry.Arch(), true)
+					if err := verifyChecksumFileEntry(zipPins, checksummer.Type, entry.Checksum, entry.Filename); err != nil {
+						return nil, err
+					}
+
 					checksum = &FileChecksum{
 						Filename:    entry.Filename,
 						Expected:    cs,
 						Checksummer: checksummer,
 					}
 					expectedZipFilename := checksum.Filename
-					expectedBinaryFilename := strings.TrimSuffix(expectedZipFilename, filepath.Ext(expectedZipFilename)) + opts.BinaryInstallationOptions.Ext
+					expectedBinaryFilename := strings.TrimSuffix(expectedZipFilename, entry.Ext()) + opts.BinaryInstallationOptions.Ext
 
 					for _, outputFolder := range opts.InFolders {
 						potentialOutputFilename := filepath.Join(
@@ -486,10 +999,14 @@ func (pr *Requirement) InstallLatest(opts InstallOptions) (*Installation, error)
 									Checksummer: potentialChecksumer,
 								}
 
-								log.Printf("[TRACE] found a pre-exising %q checksum file", potentialChecksumer.Type)
+								Logf(ctx, "[TRACE] found a pre-exising %q checksum file", potentialChecksumer.Type)
 								// if outputFile is there and matches the checksum: do nothing more.
 								if err := localChecksum.ChecksumFile(localChecksum.Expected, potentialOutputFilename); err == nil {
-									log.Printf("[INFO] %s v%s plugin is already correctly installed in %q", pr.Identifier, version, potentialOutputFilename)
+									if err := verifyPinnedChecksumsOfFile(binaryPins, potentialOutputFilename); err != nil {
+										Logf(ctx, "[WARN] reinstalling %q: %v", potentialOutputFilename, err)
+										continue
+									}
+									Logf(ctx, "[INFO] %s v%s plugin is already correctly installed in %q", pr.Identifier, version, potentialOutputFilename)
 									return nil, nil
 								}
 							}
@@ -499,127 +1016,269 @@ func (pr *Requirement) InstallLatest(opts InstallOptions) (*Installation, error)
 					// The last folder from the installation list is where we will install.
 					outputFileName := filepath.Join(outputFolder, expectedBinaryFilename)
 
+					if opts.DryRun {
+						return planInstall(ctx, getters, GetOptions{
+							PluginRequirement:         pr,
+							BinaryInstallationOptions: opts.BinaryInstallationOptions,
+							version:                   version,
+							expectedZipFilename:       expectedZipFilename,
+						}, checksum, newInstallation(outputFileName, entry.filename("v"+version.String()), checksummer.Type)), nil
+					}
+
 					// create directories if need be
-					if err := os.MkdirAll(outputFolder, 0755); err != nil {
+					if err := os.MkdirAll(longpath.Fix(outputFolder), 0755); err != nil {
 						err := fmt.Errorf("could not create plugin folder %q: %w", outputFolder, err)
-						log.Printf("[TRACE] %s", err.Error())
+						Logf(ctx, "[TRACE] %s", err.Error())
 						return nil, err
 					}
 
-					for _, getter := range getters {
-						// create temporary file that will receive a temporary binary.zip
-						tmpFile, err := tmp.File("packer-plugin-*.zip")
+					if opts.CacheDir != "" {
+						checksumType, err := pr.installFromCache(ctx, opts.CacheDir, outputFolder, outputFileName, "v"+version.String(), opts.Checksummers)
 						if err != nil {
-							return nil, fmt.Errorf("could not create temporary file to dowload plugin: %w", err)
+							return nil, err
 						}
-						defer tmpFile.Close()
+						if checksumType != "" {
+							if err := verifyPinnedChecksumsOfFile(binaryPins, outputFileName); err != nil {
+								Logf(ctx, "[WARN] ignoring the cached binary: %v", err)
+								_ = os.Remove(longpath.Fix(outputFileName))
+								_ = removeInstallFiles(outputFileName)
+								checksumType = ""
+							}
+						}
+						if checksumType != "" {
+							return newInstallation(strings.ReplaceAll(outputFileName, "\\", "/"), entry.filename("v"+version.String()), checksumType), nil
+						}
+					}
 
+					// the zip is downloaded to a file kept between attempts, so
+					// that an interrupted download can be resumed.
+					download, err := openPartialDownload(ctx, checksum)
+					if err != nil {
+						return nil, fmt.Errorf("could not create temporary file to dowload plugin: %w", err)
+					}
+					defer download.Close()
+					tmpFile := download.File
+					zipSums, err := newChecksums(zipPins, checksum.Checksummer)
+					if err != nil {
+						return nil, err
+					}
+
+					for _, getter := range getters {
 						// start fetching binary
-						remoteZipFile, err := getter.Get("zip", GetOptions{
+						err := download.fetch(ctx, getter, GetOptions{
 							PluginRequirement:         pr,
 							BinaryInstallationOptions: opts.BinaryInstallationOptions,
 							version:                   version,
 							expectedZipFilename:       expectedZipFilename,
-						})
+						}, opts.ProgressTracker, zipSums)
+						if ctxErr := ctx.Err(); ctxErr != nil {
+							return nil, ctxErr
+						}
 						if err != nil {
 							err := fmt.Errorf("could not get binary for %s version %s. Is the file present on the release and correctly named ? %s", pr.Identifier, version, err)
-							log.Printf("[TRACE] %v", err)
+							Logf(ctx, "[TRACE] %v, trying another getter", err)
 							continue
 						}
 
-						// write binary to tmp file
-						_, err = io.Copy(tmpFile, remoteZipFile)
-						_ = remoteZipFile.Close()
-						if err != nil {
-							err := fmt.Errorf("Error getting plugin: %w", err)
-							log.Printf("[TRACE] %v, trying another getter", err)
+						// verify that the checksum for the zip is what we expect,
+						// the zip was hashed while it was downloaded.
+						if actual := zipSums[checksum.Type].Sum(nil); !bytes.Equal(actual, checksum.Expected) {
+							err := fmt.Errorf("%w. Is the checksum file correct ? Is the binary file correct ?", &ChecksumError{
+								Hash:     checksum.Hash,
+								Actual:   actual,
+								Expected: checksum.Expected,
+								File:     expectedZipFilename,
+							})
+							Logf(ctx, "%s, truncating the zipfile", err)
+							if err := tmpFile.Truncate(0); err != nil {
+								Logf(ctx, "[TRACE] %v", err)
+							}
 							continue
 						}
+						// the pinned checksums are verified no matter what the
+						// checksum file says.
+						if err := verifyPinnedSums(zipPins, zipSums, expectedZipFilename); err != nil {
+							_ = tmpFile.Truncate(0)
+							return nil, err
+						}
+						download.complete = true
 
-						if _, err := tmpFile.Seek(0, 0); err != nil {
-							err := fmt.Errorf("Error seeking begining of temporary file for checksumming: %w", err)
-							log.Printf("[TRACE] %v, continuing", err)
-							continue
+						copyFrom, binarySize, err := openArchivedFile(tmpFile, entry.Ext(), expectedBinaryFilename)
+						if err != nil {
+							return nil, fmt.Errorf("%s: %v", checksum.Filename, err)
 						}
 
-						// verify that the checksum for the zip is what we expect.
-						if err := checksum.Checksummer.Checksum(checksum.Expected, tmpFile); err != nil {
-							err := fmt.Errorf("%w. Is the checksum file correct ? Is the binary file correct ?", err)
-							log.Printf("%s, truncating the zipfile", err)
-							if err := tmpFile.Truncate(0); err != nil {
-								log.Printf("[TRACE] %v", err)
-							}
-							continue
+						if err := checkWithinFolder(outputFolder, outputFileName); err != nil {
+							return nil, err
 						}
 
-						tmpFileStat, err := tmpFile.Stat()
+						// Extract the binary to a temporary file in the output folder,
+						// it is only renamed into place once its checksum is verified
+						// so that an interrupted install never leaves a half written
+						// binary behind.
+						tmpOutputFile, err := ioutil.TempFile(longpath.Fix(outputFolder), "."+expectedBinaryFilename+".*.tmp")
 						if err != nil {
-							err := fmt.Errorf("failed to stat: %v", err)
+							err := fmt.Errorf("Failed to create temporary file in %s: %v", outputFolder, err)
 							return nil, err
 						}
+						tmpOutputFileName := tmpOutputFile.Name()
+						defer os.Remove(tmpOutputFileName)
 
-						zr, err := zip.NewReader(tmpFile, tmpFileStat.Size())
+						binarySums, err := newChecksums(binaryPins, checksum.Checksummer)
+						if err != nil {
+							_ = tmpOutputFile.Close()
+							_ = copyFrom.Close()
+							return nil, err
+						}
+						extracted, done := trackProgress(opts.ProgressTracker, expectedBinaryFilename, 0, binarySize, copyFrom)
+						_, err = io.Copy(io.MultiWriter(tmpOutputFile, binarySums), extracted)
+						done()
+						_ = copyFrom.Close()
 						if err != nil {
-							err := fmt.Errorf("zip : %v", err)
+							_ = tmpOutputFile.Close()
+							err := fmt.Errorf("Extract file: %v", err)
 							return nil, err
 						}
+						cs := binarySums[checksum.Type].Sum(nil)
 
-						var copyFrom io.ReadCloser
-						for _, f := range zr.File {
-							if f.Name != expectedBinaryFilename {
-								continue
-							}
-							copyFrom, err = f.Open()
-							if err != nil {
-								return nil, err
-							}
-							break
+						if err := tmpOutputFile.Sync(); err != nil {
+							_ = tmpOutputFile.Close()
+							return nil, fmt.Errorf("Failed to sync %s: %v", tmpOutputFileName, err)
 						}
-						if copyFrom == nil {
-							err := fmt.Errorf("could not find a %s file in zipfile", checksum.Filename)
-							return nil, err
+						if err := tmpOutputFile.Close(); err != nil {
+							return nil, fmt.Errorf("Failed to close %s: %v", tmpOutputFileName, err)
 						}
 
-						outputFile, err := os.OpenFile(outputFileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-						if err != nil {
-							err := fmt.Errorf("Failed to create %s: %v", outputFileName, err)
+						// verify that what was written on disk is what was extracted.
+						if err := checksum.Checksummer.ChecksumFile(cs, tmpOutputFileName); err != nil {
+							err := fmt.Errorf("extracted binary is corrupted: %w", err)
 							return nil, err
 						}
-						defer outputFile.Close()
 
-						if _, err := io.Copy(outputFile, copyFrom); err != nil {
-							err := fmt.Errorf("Extract file: %v", err)
+						if err := verifyPinnedSums(binaryPins, binarySums, tmpOutputFileName); err != nil {
 							return nil, err
 						}
 
-						if _, err := outputFile.Seek(0, 0); err != nil {
-							err := fmt.Errorf("Error seeking begining of binary file for checksumming: %w", err)
-							log.Printf("[WARNING] %v, ignoring", err)
+						if err := os.Chmod(tmpOutputFileName, 0755); err != nil {
+							return nil, fmt.Errorf("Failed to set permissions of %s: %v", tmpOutputFileName, err)
 						}
 
-						cs, err := checksum.Checksummer.Sum(outputFile)
-						if err != nil {
-							err := fmt.Errorf("failed to checksum binary file: %s", err)
-							log.Printf("[WARNING] %v, ignoring", err)
+						// the other files are extracted before the binary is
+						// installed, so that a failure leaves nothing behind.
+						var tmpFilesDir string
+						receipt := newInstallReceipt(pr, "v"+version.String())
+						receipt.Getter = GetterName(getter)
+						receipt.Archive = expectedZipFilename
+						receipt.ArchiveChecksums = zipSums.hexSums()
+						receipt.BinaryChecksums = binarySums.hexSums()
+						if len(opts.ExtractFiles) > 0 {
+							tmpFilesDir, err = ioutil.TempDir(longpath.Fix(outputFolder), "."+expectedBinaryFilename+".*.files.tmp")
+							if err != nil {
+								return nil, fmt.Errorf("Failed to create temporary folder in %s: %v", outputFolder, err)
+							}
+							defer os.RemoveAll(tmpFilesDir)
+							receipt.Files, err = extractArchivedFiles(tmpFile, entry.Ext(), opts.ExtractFiles, expectedBinaryFilename, tmpFilesDir)
+							if err != nil {
+								return nil, fmt.Errorf("%s: %v", checksum.Filename, err)
+							}
+						}
+
+						if err := os.Rename(tmpOutputFileName, longpath.Fix(outputFileName)); err != nil {
+							err := fmt.Errorf("Failed to install %s: %v", outputFileName, err)
+							return nil, err
+						}
+
+						// files of a previous install of this binary are replaced.
+						if err := removeInstallFiles(outputFileName); err != nil {
+							Logf(ctx, "[WARNING] failed to remove the previous install receipt of %s: %v, ignoring", outputFileName, err)
+						}
+						if len(receipt.Files) > 0 {
+							if err := os.Rename(tmpFilesDir, longpath.Fix(FilesDir(outputFileName))); err != nil {
+								Logf(ctx, "[WARNING] failed to install the files of %s: %v, ignoring", outputFileName, err)
+								receipt.Files = nil
+							}
+						}
+						if err := writeInstallReceipt(outputFileName, receipt); err != nil {
+							Logf(ctx, "[WARNING] failed to write the install receipt of %s: %v, ignoring", outputFileName, err)
 						}
 
-						if err := ioutil.WriteFile(outputFileName+checksum.Checksummer.FileExt(), []byte(hex.EncodeToString(cs)), 0555); err != nil {
+						if err := ioutil.WriteFile(longpath.Fix(outputFileName+checksum.Checksummer.FileExt()), []byte(hex.EncodeToString(cs)), 0555); err != nil {
 							err := fmt.Errorf("failed to write local binary checksum file: %s", err)
-							log.Printf("[WARNING] %v, ignoring", err)
+							Logf(ctx, "[WARNING] %v, ignoring", err)
+						}
+
+						if opts.CacheDir != "" {
+							pr.addToCache(ctx, opts.CacheDir, outputFileName, checksum.Checksummer, cs)
 						}
 
 						// Success !!
-						return &Installation{
-							BinaryPath: strings.ReplaceAll(outputFileName, "\\", "/"),
-							Version:    "v" + version.String(),
-						}, nil
+						return newInstallation(strings.ReplaceAll(outputFileName, "\\", "/"), entry.filename("v"+version.String()), checksum.Checksummer.Type), nil
 					}
 
 				}
 			}
 
 		}
+		if !checksumFileFound {
+			err := fmt.Errorf("could not get a checksum file for %s version %s. Is one of the %s files present on the release and correctly named ? %s",
+				pr.Identifier, version, checksumFileSuffixes(opts.Checksummers), strings.Join(checksumFileErrs, "; "))
+			Logf(ctx, "[TRACE] %s", err.Error())
+			return nil, err
+		}
 	}
 
 	return nil, fail
 }
+
+// checksumFileSuffixes lists the suffixes of the checksum files of
+// checksummers, for error messages.
+func checksumFileSuffixes(checksummers []Checksummer) string {
+	suffixes := make([]string, 0, len(checksummers))
+	for _, checksummer := range checksummers {
+		suffixes = append(suffixes, ChecksumFileSuffix(checksummer.Type))
+	}
+	return strings.Join(suffixes, ", ")
+}
+
+// checkWithinFolder makes sure path is a direct child of folder, so that a
+// crafted filename can never make us write outside of the plugin folder.
+func checkWithinFolder(folder, path string) error {
+	rel, err := filepath.Rel(folder, path)
+	if err != nil {
+		return fmt.Errorf("could not verify that %q is within %q: %v", path, folder, err)
+	}
+	if rel == "." || rel == ".." || rel != filepath.Base(rel) {
+		return fmt.Errorf("refusing to install %q outside of the plugin folder %q", path, folder)
+	}
+	return nil
+}
+
+// planInstall returns install, the Installation a download of the zip file
+// described by opts and verified with checksum would produce, with its
+// planned download. The source is the first getter able to locate it.
+func planInstall(ctx context.Context, getters []Getter, opts GetOptions, checksum *FileChecksum, install *Installation) *Installation {
+	planned := &PlannedDownload{
+		Size:     -1,
+		Checksum: checksum.Type + ":" + hex.EncodeToString(checksum.Expected),
+		Writes: []string{
+			install.BinaryPath,
+			install.BinaryPath + checksum.Checksummer.FileExt(),
+			ReceiptPath(install.BinaryPath),
+		},
+	}
+	for _, getter := range getters {
+		locator, ok := getter.(Locator)
+		if !ok {
+			continue
+		}
+		url, size, err := locator.Locate(ctx, "zip", opts)
+		if err != nil {
+			Logf(ctx, "[TRACE] could not locate %s: %s", opts.ExpectedZipFilename(), err)
+			continue
+		}
+		planned.SourceURL, planned.Size = url, size
+		break
+	}
+	install.Planned = planned
+	return install
+}
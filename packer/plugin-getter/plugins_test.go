@@ -3,7 +3,10 @@ package plugingetter
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/packer/hcl2template/addrs"
 )
@@ -25,6 +29,14 @@ var (
 	pluginFolderTwo = filepath.Join("testdata", "plugins_2")
 
 	pluginFolderWrongChecksums = filepath.Join("testdata", "wrong_checksums")
+
+	// the modification times of binaries depend on when they were checked
+	// out or installed.
+	ignoreModTime = cmpopts.IgnoreFields(Installation{}, "ModTime")
+
+	// receipts record when binaries were installed, they are tested on
+	// their own.
+	ignoreReceipt = cmpopts.IgnoreFields(Installation{}, "Receipt")
 )
 
 func TestChecksumFileEntry_init(t *testing.T) {
@@ -53,6 +65,142 @@ func TestChecksumFileEntry_init(t *testing.T) {
 	}
 }
 
+func TestParsePluginFilename(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    pluginFilename
+		wantErr bool
+	}{
+		{"v1.2.3_x5.0_darwin_amd64", pluginFilename{"v1.2.3", "x5.0", "darwin", "amd64"}, false},
+		{"v1.2.3+ent_x5.0_linux_arm64", pluginFilename{"v1.2.3+ent", "x5.0", "linux", "arm64"}, false},
+		{"v1.2.3-beta.1_x5.1_windows_amd64", pluginFilename{"v1.2.3-beta.1", "x5.1", "windows", "amd64"}, false},
+		{"v1.2.3-beta.1+ent.2_x5.0_linux_amd64", pluginFilename{"v1.2.3-beta.1+ent.2", "x5.0", "linux", "amd64"}, false},
+		{"v1.2.3_darwin_amd64", pluginFilename{}, true},
+		{"v1.2.3_5.0_darwin_amd64", pluginFilename{}, true},
+		{"1.2.3_x5.0_darwin_amd64", pluginFilename{}, true},
+		{"vnext_x5.0_darwin_amd64", pluginFilename{}, true},
+		{"v1.2.3", pluginFilename{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePluginFilename(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePluginFilename(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePluginFilename(%q) = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInstallList_InsertSortedUniq(t *testing.T) {
+	l := InstallList{}
+	for _, v := range []string{"v1.10.0", "v1.2.3", "v1.2.3-beta.1", "v1.9.0", "v1.2.3+ent", "v1.2.3"} {
+		l.InsertSortedUniq(&Installation{Version: v})
+	}
+	var got []string
+	for _, install := range l {
+		got = append(got, install.Version)
+	}
+	expected := []string{"v1.2.3-beta.1", "v1.2.3", "v1.2.3+ent", "v1.9.0", "v1.10.0"}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("unexpected order: %s", diff)
+	}
+}
+
+func TestCheckWithinFolder(t *testing.T) {
+	folder := filepath.Join("plugins", "github.com", "hashicorp", "amazon")
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{filepath.Join(folder, "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64"), false},
+		{folder, true},
+		{filepath.Join(folder, ".."), true},
+		{filepath.Join(folder, "..", "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64"), true},
+		{filepath.Join(folder, "sub", "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64"), true},
+	}
+	for _, tt := range tests {
+		if err := checkWithinFolder(folder, tt.path); (err != nil) != tt.wantErr {
+			t.Errorf("checkWithinFolder(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestInstallList_filters(t *testing.T) {
+	l := InstallList{
+		{Version: "v1.2.3", OS: "linux", ARCH: "amd64"},
+		{Version: "v1.2.4", OS: "darwin", ARCH: "arm64"},
+		{Version: "v2.0.0", OS: "linux", ARCH: "amd64"},
+	}
+	versions := func(l InstallList) (res []string) {
+		for _, install := range l {
+			res = append(res, install.Version)
+		}
+		return res
+	}
+	if diff := cmp.Diff([]string{"v1.2.3", "v2.0.0"}, versions(l.ForPlatform("linux", "amd64"))); diff != "" {
+		t.Errorf("ForPlatform(): %s", diff)
+	}
+	if diff := cmp.Diff([]string{"v1.2.3", "v1.2.4"}, versions(l.Matching(version.MustConstraints(version.NewConstraint("< v2"))))); diff != "" {
+		t.Errorf("Matching(): %s", diff)
+	}
+	if got := l.ForPlatform("windows", "amd64").Latest(); got != nil {
+		t.Errorf("Latest() of an empty list = %v", got)
+	}
+	if got := l.Latest(); got.Version != "v2.0.0" {
+		t.Errorf("Latest() = %s", got.Version)
+	}
+
+	b, err := json.Marshal(InstallList(nil))
+	if err != nil || string(b) != "[]" {
+		t.Errorf("Marshal(nil) = %s, %v", b, err)
+	}
+	b, err = json.Marshal(l[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"binary_path":"","version":"v1.2.3","os":"linux","arch":"amd64","mod_time":"0001-01-01T00:00:00Z"}]`
+	if string(b) != want {
+		t.Errorf("Marshal() = %s, want %s", b, want)
+	}
+}
+
+func TestRequirement_Allows(t *testing.T) {
+	tests := []struct {
+		constraint         string
+		includePrereleases bool
+		version            string
+		want               bool
+	}{
+		{"", false, "v1.0.0", true},
+		{"", false, "v1.0.0-beta.1", false},
+		{"", true, "v1.0.0-beta.1", true},
+		{">= v1.0.0", false, "v1.1.0-beta.1", false},
+		{">= v1.0.0", true, "v1.1.0-beta.1", true},
+		{">= v1.0.0, < v1.1.0", true, "v1.1.0-beta.1", false},
+		{">= v1.1.0-beta.1", false, "v1.1.0-beta.2", true},
+		{">= v1.1.0-beta.1", false, "v1.1.0", true},
+		{">= v1.0.0, != v1.4.2", false, "v1.4.2", false},
+		{">= v1.0.0, != v1.4.2", false, "v1.4.3", true},
+		{">= v1.0.0, != v1.4.2", true, "v1.4.2-rc.1", true},
+		{">= v1.0.0, != v1.4.2-rc.1", true, "v1.4.2-rc.1", false},
+	}
+	for _, tt := range tests {
+		constraints, err := version.NewConstraint(tt.constraint)
+		if tt.constraint == "" {
+			constraints, err = nil, nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pr := &Requirement{VersionConstraints: constraints, IncludePrereleases: tt.includePrereleases}
+		if got := pr.Allows(version.Must(version.NewVersion(tt.version))); got != tt.want {
+			t.Errorf("Allows(%s) with %q, include pre-releases: %t = %t, want %t", tt.version, tt.constraint, tt.includePrereleases, got, tt.want)
+		}
+	}
+}
+
 func TestPlugin_ListInstallations(t *testing.T) {
 
 	type fields struct {
@@ -90,16 +238,28 @@ func TestPlugin_ListInstallations(t *testing.T) {
 			false,
 			[]*Installation{
 				{
-					Version:    "v1.2.3",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64"),
+					Version:      "v1.2.3",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64"),
+					APIVersion:   "x5.0",
+					OS:           "darwin",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v1.2.4",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.4_x5.0_darwin_amd64"),
+					Version:      "v1.2.4",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.4_x5.0_darwin_amd64"),
+					APIVersion:   "x5.0",
+					OS:           "darwin",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v1.2.5",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.5_x5.0_darwin_amd64"),
+					Version:      "v1.2.5",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.5_x5.0_darwin_amd64"),
+					APIVersion:   "x5.0",
+					OS:           "darwin",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 			},
 		},
@@ -127,20 +287,36 @@ func TestPlugin_ListInstallations(t *testing.T) {
 			false,
 			[]*Installation{
 				{
-					Version:    "v1.2.3",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64"),
+					Version:      "v1.2.3",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.3_x5.0_darwin_amd64"),
+					APIVersion:   "x5.0",
+					OS:           "darwin",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v1.2.4",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.4_x5.0_darwin_amd64"),
+					Version:      "v1.2.4",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.4_x5.0_darwin_amd64"),
+					APIVersion:   "x5.0",
+					OS:           "darwin",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v1.2.5",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.5_x5.0_darwin_amd64"),
+					Version:      "v1.2.5",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.5_x5.0_darwin_amd64"),
+					APIVersion:   "x5.0",
+					OS:           "darwin",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v1.2.6",
-					BinaryPath: filepath.Join(pluginFolderTwo, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.6_x5.1_darwin_amd64"),
+					Version:      "v1.2.6",
+					BinaryPath:   filepath.Join(pluginFolderTwo, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.6_x5.1_darwin_amd64"),
+					APIVersion:   "x5.1",
+					OS:           "darwin",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 			},
 		},
@@ -169,16 +345,28 @@ func TestPlugin_ListInstallations(t *testing.T) {
 			false,
 			[]*Installation{
 				{
-					Version:    "v1.2.3",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.3_x5.0_windows_amd64.exe"),
+					Version:      "v1.2.3",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.3_x5.0_windows_amd64.exe"),
+					APIVersion:   "x5.0",
+					OS:           "windows",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v1.2.4",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.4_x5.0_windows_amd64.exe"),
+					Version:      "v1.2.4",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.4_x5.0_windows_amd64.exe"),
+					APIVersion:   "x5.0",
+					OS:           "windows",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v1.2.5",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.5_x5.0_windows_amd64.exe"),
+					Version:      "v1.2.5",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.5_x5.0_windows_amd64.exe"),
+					APIVersion:   "x5.0",
+					OS:           "windows",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 			},
 		},
@@ -207,20 +395,36 @@ func TestPlugin_ListInstallations(t *testing.T) {
 			false,
 			[]*Installation{
 				{
-					Version:    "v4.5.6",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.6_x5.0_windows_amd64.exe"),
+					Version:      "v4.5.6",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.6_x5.0_windows_amd64.exe"),
+					APIVersion:   "x5.0",
+					OS:           "windows",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v4.5.7",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.7_x5.0_windows_amd64.exe"),
+					Version:      "v4.5.7",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.7_x5.0_windows_amd64.exe"),
+					APIVersion:   "x5.0",
+					OS:           "windows",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v4.5.8",
-					BinaryPath: filepath.Join(pluginFolderOne, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.8_x5.0_windows_amd64.exe"),
+					Version:      "v4.5.8",
+					BinaryPath:   filepath.Join(pluginFolderOne, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.8_x5.0_windows_amd64.exe"),
+					APIVersion:   "x5.0",
+					OS:           "windows",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 				{
-					Version:    "v4.5.9",
-					BinaryPath: filepath.Join(pluginFolderTwo, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.9_x5.0_windows_amd64.exe"),
+					Version:      "v4.5.9",
+					BinaryPath:   filepath.Join(pluginFolderTwo, "github.com", "hashicorp", "google", "packer-plugin-google_v4.5.9_x5.0_windows_amd64.exe"),
+					APIVersion:   "x5.0",
+					OS:           "windows",
+					ARCH:         "amd64",
+					ChecksumType: "sha256",
 				},
 			},
 		},
@@ -235,18 +439,87 @@ func TestPlugin_ListInstallations(t *testing.T) {
 				Identifier:         identifier,
 				VersionConstraints: tt.fields.VersionConstraints,
 			}
-			got, err := p.ListInstallations(tt.opts)
+			got, err := p.ListInstallations(context.Background(), tt.opts)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Plugin.ListInstallations() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if diff := cmp.Diff(tt.want, got); diff != "" {
+			if diff := cmp.Diff(tt.want, got, ignoreModTime); diff != "" {
 				t.Errorf("Plugin.ListInstallations() unexpected output: %s", diff)
 			}
 		})
 	}
 }
 
+func TestRequirement_Uninstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-plugins-uninstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	one, two := filepath.Join(dir, "one"), filepath.Join(dir, "two")
+	write := func(folder, name string) string {
+		path := filepath.Join(folder, "github.com", "hashicorp", "amazon", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []string{path, path + "_SHA256SUM"} {
+			if err := ioutil.WriteFile(p, []byte(name), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+	v1 := write(one, "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64")
+	shadowed := write(two, "packer-plugin-amazon_v1.0.0_x5.0_linux_amd64")
+	incompatible := write(two, "packer-plugin-amazon_v1.1.0_x6.0_linux_amd64")
+	kept := write(two, "packer-plugin-amazon_v2.0.0_x5.0_linux_amd64")
+
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	pr := Requirement{
+		Identifier:         identifier,
+		VersionConstraints: version.MustConstraints(version.NewConstraint("< v2")),
+	}
+	removed, err := pr.Uninstall(ListInstallationsOptions{
+		FromFolders: []string{one, two},
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			APIVersionMajor: "5", APIVersionMinor: "0",
+			OS: "linux", ARCH: "amd64",
+			Checksummers: []Checksummer{
+				{Type: "sha256", Hash: sha256.New()},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := InstallList{
+		{BinaryPath: v1, Version: "v1.0.0", APIVersion: "x5.0", OS: "linux", ARCH: "amd64"},
+		{BinaryPath: shadowed, Version: "v1.0.0", APIVersion: "x5.0", OS: "linux", ARCH: "amd64"},
+		{BinaryPath: incompatible, Version: "v1.1.0", APIVersion: "x6.0", OS: "linux", ARCH: "amd64"},
+	}
+	if diff := cmp.Diff(want, removed, ignoreModTime); diff != "" {
+		t.Fatalf("Uninstall(): %s", diff)
+	}
+	for _, install := range removed {
+		for _, p := range []string{install.BinaryPath, install.BinaryPath + "_SHA256SUM"} {
+			if _, err := os.Stat(p); !os.IsNotExist(err) {
+				t.Errorf("%s should have been removed: %v", p, err)
+			}
+		}
+	}
+	for _, p := range []string{kept, kept + "_SHA256SUM"} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should have been kept: %v", p, err)
+		}
+	}
+}
+
 func TestRequirement_InstallLatest(t *testing.T) {
 	type fields struct {
 		Identifier         string
@@ -265,7 +538,7 @@ func TestRequirement_InstallLatest(t *testing.T) {
 		{"already-installed-same-api-version",
 			fields{"amazon", "v1.2.3"},
 			args{InstallOptions{
-				[]Getter{
+				Getters: []Getter{
 					&mockPluginGetter{
 						Releases: []Release{
 							{Version: "v1.2.3"},
@@ -282,12 +555,12 @@ func TestRequirement_InstallLatest(t *testing.T) {
 						},
 					},
 				},
-				[]string{
+				InFolders: []string{
 					pluginFolderWrongChecksums,
 					pluginFolderOne,
 					pluginFolderTwo,
 				},
-				BinaryInstallationOptions{
+				BinaryInstallationOptions: BinaryInstallationOptions{
 					APIVersionMajor: "5", APIVersionMinor: "0",
 					OS: "darwin", ARCH: "amd64",
 					Checksummers: []Checksummer{
@@ -305,7 +578,7 @@ func TestRequirement_InstallLatest(t *testing.T) {
 			// with the 5.0 one of an already installed plugin.
 			fields{"amazon", "v1.2.3"},
 			args{InstallOptions{
-				[]Getter{
+				Getters: []Getter{
 					&mockPluginGetter{
 						Releases: []Release{
 							{Version: "v1.2.3"},
@@ -318,12 +591,12 @@ func TestRequirement_InstallLatest(t *testing.T) {
 						},
 					},
 				},
-				[]string{
+				InFolders: []string{
 					pluginFolderWrongChecksums,
 					pluginFolderOne,
 					pluginFolderTwo,
 				},
-				BinaryInstallationOptions{
+				BinaryInstallationOptions: BinaryInstallationOptions{
 					APIVersionMajor: "5", APIVersionMinor: "1",
 					OS: "darwin", ARCH: "amd64",
 					Checksummers: []Checksummer{
@@ -343,7 +616,7 @@ func TestRequirement_InstallLatest(t *testing.T) {
 			// ignored.
 			fields{"amazon", ">= v1"},
 			args{InstallOptions{
-				[]Getter{
+				Getters: []Getter{
 					&mockPluginGetter{
 						Releases: []Release{
 							{Version: "v1.2.3"},
@@ -363,12 +636,12 @@ func TestRequirement_InstallLatest(t *testing.T) {
 						},
 					},
 				},
-				[]string{
+				InFolders: []string{
 					pluginFolderWrongChecksums,
 					pluginFolderOne,
 					pluginFolderTwo,
 				},
-				BinaryInstallationOptions{
+				BinaryInstallationOptions: BinaryInstallationOptions{
 					APIVersionMajor: "5", APIVersionMinor: "0",
 					OS: "darwin", ARCH: "amd64",
 					Checksummers: []Checksummer{
@@ -387,7 +660,7 @@ func TestRequirement_InstallLatest(t *testing.T) {
 			// version than the one we support.
 			fields{"amazon", ">= v2"},
 			args{InstallOptions{
-				[]Getter{
+				Getters: []Getter{
 					&mockPluginGetter{
 						Releases: []Release{
 							{Version: "v1.2.3"},
@@ -410,12 +683,12 @@ func TestRequirement_InstallLatest(t *testing.T) {
 						},
 					},
 				},
-				[]string{
+				InFolders: []string{
 					pluginFolderWrongChecksums,
 					pluginFolderOne,
 					pluginFolderTwo,
 				},
-				BinaryInstallationOptions{
+				BinaryInstallationOptions: BinaryInstallationOptions{
 					APIVersionMajor: "6", APIVersionMinor: "1",
 					OS: "darwin", ARCH: "amd64",
 					Checksummers: []Checksummer{
@@ -427,8 +700,12 @@ func TestRequirement_InstallLatest(t *testing.T) {
 				},
 			}},
 			&Installation{
-				BinaryPath: "testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.0_x6.0_darwin_amd64",
-				Version:    "v2.10.0",
+				BinaryPath:   "testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.0_x6.0_darwin_amd64",
+				Version:      "v2.10.0",
+				APIVersion:   "x6.0",
+				OS:           "darwin",
+				ARCH:         "amd64",
+				ChecksumType: "sha256",
 			}, false},
 
 		{"upgrade-with-same-protocol-version",
@@ -436,7 +713,7 @@ func TestRequirement_InstallLatest(t *testing.T) {
 			// be installed.
 			fields{"amazon", ">= v2"},
 			args{InstallOptions{
-				[]Getter{
+				Getters: []Getter{
 					&mockPluginGetter{
 						Releases: []Release{
 							{Version: "v1.2.3"},
@@ -460,12 +737,12 @@ func TestRequirement_InstallLatest(t *testing.T) {
 						},
 					},
 				},
-				[]string{
+				InFolders: []string{
 					pluginFolderWrongChecksums,
 					pluginFolderOne,
 					pluginFolderTwo,
 				},
-				BinaryInstallationOptions{
+				BinaryInstallationOptions: BinaryInstallationOptions{
 					APIVersionMajor: "6", APIVersionMinor: "1",
 					OS: "darwin", ARCH: "amd64",
 					Checksummers: []Checksummer{
@@ -477,16 +754,211 @@ func TestRequirement_InstallLatest(t *testing.T) {
 				},
 			}},
 			&Installation{
-				BinaryPath: "testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
-				Version:    "v2.10.1",
+				BinaryPath:   "testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
+				Version:      "v2.10.1",
+				APIVersion:   "x6.1",
+				OS:           "darwin",
+				ARCH:         "amd64",
+				ChecksumType: "sha256",
 			}, false},
 
+		{"dry-run",
+			// here a newer version is available, but only the planned
+			// installation is returned: the mock getter has no zip file.
+			fields{"amazon", ">= v2"},
+			args{InstallOptions{
+				Getters: []Getter{
+					&mockPluginGetter{
+						Releases: []Release{
+							{Version: "v2.10.0"},
+							{Version: "v2.10.1"},
+						},
+						ChecksumFileEntries: map[string][]ChecksumFileEntry{
+							"2.10.1": {{
+								Filename: "packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64.zip",
+								Checksum: "90ca5b0f13a90238b62581bbf30bacd7e2c9af6592c7f4849627bddbcb039dec",
+							}},
+						},
+					},
+				},
+				InFolders: []string{
+					pluginFolderWrongChecksums,
+					pluginFolderOne,
+					pluginFolderTwo,
+				},
+				DryRun: true,
+				BinaryInstallationOptions: BinaryInstallationOptions{
+					APIVersionMajor: "6", APIVersionMinor: "1",
+					OS: "darwin", ARCH: "amd64",
+					Checksummers: []Checksummer{
+						{
+							Type: "sha256",
+							Hash: sha256.New(),
+						},
+					},
+				},
+			}},
+			&Installation{
+				BinaryPath:   "testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
+				Version:      "v2.10.1",
+				APIVersion:   "x6.1",
+				OS:           "darwin",
+				ARCH:         "amd64",
+				ChecksumType: "sha256",
+				Planned: &PlannedDownload{
+					Size:     -1,
+					Checksum: "sha256:90ca5b0f13a90238b62581bbf30bacd7e2c9af6592c7f4849627bddbcb039dec",
+					Writes: []string{
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64_SHA256SUM",
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64_receipt.json",
+					},
+				},
+			}, false},
+
+		{"dry-run-skips-prereleases",
+			// here a pre-release is the highest version, it is skipped as
+			// the requirement doesn't include pre-releases.
+			fields{"amazon", ">= v2"},
+			args{InstallOptions{
+				Getters: []Getter{
+					&mockPluginGetter{
+						Releases: []Release{
+							{Version: "v2.10.1"},
+							{Version: "v2.11.0-beta.1"},
+						},
+						ChecksumFileEntries: map[string][]ChecksumFileEntry{
+							"2.10.1": {{
+								Filename: "packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64.zip",
+								Checksum: "90ca5b0f13a90238b62581bbf30bacd7e2c9af6592c7f4849627bddbcb039dec",
+							}},
+						},
+					},
+				},
+				InFolders: []string{
+					pluginFolderWrongChecksums,
+					pluginFolderOne,
+					pluginFolderTwo,
+				},
+				DryRun: true,
+				BinaryInstallationOptions: BinaryInstallationOptions{
+					APIVersionMajor: "6", APIVersionMinor: "1",
+					OS: "darwin", ARCH: "amd64",
+					Checksummers: []Checksummer{
+						{
+							Type: "sha256",
+							Hash: sha256.New(),
+						},
+					},
+				},
+			}},
+			&Installation{
+				BinaryPath:   "testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
+				Version:      "v2.10.1",
+				APIVersion:   "x6.1",
+				OS:           "darwin",
+				ARCH:         "amd64",
+				ChecksumType: "sha256",
+				Planned: &PlannedDownload{
+					Size:     -1,
+					Checksum: "sha256:90ca5b0f13a90238b62581bbf30bacd7e2c9af6592c7f4849627bddbcb039dec",
+					Writes: []string{
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64_SHA256SUM",
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64_receipt.json",
+					},
+				},
+			}, false},
+
+		{"dry-run-sha512-only",
+			// here the release only publishes a SHA512SUMS file, it is found
+			// after the SHA256SUMS one is not.
+			fields{"amazon", ">= v2"},
+			args{InstallOptions{
+				Getters: []Getter{
+					&mockPluginGetter{
+						Releases: []Release{
+							{Version: "v2.10.1"},
+						},
+						ChecksumType: "sha512",
+						ChecksumFileEntries: map[string][]ChecksumFileEntry{
+							"2.10.1": {{
+								Filename: "packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64.zip",
+								Checksum: "5d1711a2491c2b2a45de5d0ef41ca4b297df1af731fbef7f6b96d2b56ec1c4cf79fdf771492175e031b41601f47437edd3c1a8bd824ece5d651e9bf1c483e262",
+							}},
+						},
+					},
+				},
+				InFolders: []string{
+					pluginFolderWrongChecksums,
+					pluginFolderOne,
+					pluginFolderTwo,
+				},
+				DryRun: true,
+				BinaryInstallationOptions: BinaryInstallationOptions{
+					APIVersionMajor: "6", APIVersionMinor: "1",
+					OS: "darwin", ARCH: "amd64",
+					Checksummers: DefaultChecksummers(),
+				},
+			}},
+			&Installation{
+				BinaryPath:   "testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
+				Version:      "v2.10.1",
+				APIVersion:   "x6.1",
+				OS:           "darwin",
+				ARCH:         "amd64",
+				ChecksumType: "sha512",
+				Planned: &PlannedDownload{
+					Size:     -1,
+					Checksum: "sha512:5d1711a2491c2b2a45de5d0ef41ca4b297df1af731fbef7f6b96d2b56ec1c4cf79fdf771492175e031b41601f47437edd3c1a8bd824ece5d651e9bf1c483e262",
+					Writes: []string{
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64",
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64_SHA512SUM",
+						"testdata/plugins_2/github.com/hashicorp/amazon/packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64_receipt.json",
+					},
+				},
+			}, false},
+
+		{"no-known-checksum-file",
+			// here the release only publishes a checksum file of a type that
+			// is not used.
+			fields{"amazon", ">= v2"},
+			args{InstallOptions{
+				Getters: []Getter{
+					&mockPluginGetter{
+						Releases: []Release{
+							{Version: "v2.10.1"},
+						},
+						ChecksumType: "blake2b",
+						ChecksumFileEntries: map[string][]ChecksumFileEntry{
+							"2.10.1": {{
+								Filename: "packer-plugin-amazon_v2.10.1_x6.1_darwin_amd64.zip",
+								Checksum: "90ca5b0f13a90238b62581bbf30bacd7e2c9af6592c7f4849627bddbcb039dec",
+							}},
+						},
+					},
+				},
+				InFolders: []string{
+					pluginFolderTwo,
+				},
+				DryRun: true,
+				BinaryInstallationOptions: BinaryInstallationOptions{
+					APIVersionMajor: "6", APIVersionMinor: "1",
+					OS: "darwin", ARCH: "amd64",
+					Checksummers: []Checksummer{
+						{Type: "sha256", Hash: sha256.New()},
+						{Type: "sha512", Hash: sha512.New()},
+					},
+				},
+			}},
+			nil, true},
+
 		{"wrong-zip-checksum",
 			// here we have something locally and test that a newer version with
 			// a wrong checksum will not be installed and error.
 			fields{"amazon", ">= v2"},
 			args{InstallOptions{
-				[]Getter{
+				Getters: []Getter{
 					&mockPluginGetter{
 						Releases: []Release{
 							{Version: "v2.10.0"},
@@ -504,12 +976,12 @@ func TestRequirement_InstallLatest(t *testing.T) {
 						},
 					},
 				},
-				[]string{
+				InFolders: []string{
 					pluginFolderWrongChecksums,
 					pluginFolderOne,
 					pluginFolderTwo,
 				},
-				BinaryInstallationOptions{
+				BinaryInstallationOptions: BinaryInstallationOptions{
 					APIVersionMajor: "6", APIVersionMinor: "1",
 					OS: "darwin", ARCH: "amd64",
 					Checksummers: []Checksummer{
@@ -529,7 +1001,7 @@ func TestRequirement_InstallLatest(t *testing.T) {
 			// this should totally error.
 			fields{"amazon", ">= v1"},
 			args{InstallOptions{
-				[]Getter{
+				Getters: []Getter{
 					&mockPluginGetter{
 						Releases: []Release{
 							{Version: "v2.10.0"},
@@ -547,10 +1019,10 @@ func TestRequirement_InstallLatest(t *testing.T) {
 						},
 					},
 				},
-				[]string{
+				InFolders: []string{
 					pluginFolderWrongChecksums,
 				},
-				BinaryInstallationOptions{
+				BinaryInstallationOptions: BinaryInstallationOptions{
 					APIVersionMajor: "6", APIVersionMinor: "1",
 					OS: "darwin", ARCH: "amd64",
 					Checksummers: []Checksummer{
@@ -580,17 +1052,23 @@ func TestRequirement_InstallLatest(t *testing.T) {
 				Identifier:         identifier,
 				VersionConstraints: cts,
 			}
-			got, err := pr.InstallLatest(tt.args.opts)
+			got, err := pr.InstallLatest(context.Background(), tt.args.opts)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Requirement.InstallLatest() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if diff := cmp.Diff(got, tt.want); diff != "" {
+			if diff := cmp.Diff(got, tt.want, ignoreModTime, ignoreReceipt); diff != "" {
 				t.Errorf("Requirement.InstallLatest() %s", diff)
 			}
+			if tt.want != nil && tt.want.Planned != nil {
+				if _, err := os.Stat(tt.want.BinaryPath); !os.IsNotExist(err) {
+					t.Fatalf("dry-run should not install %s: %v", tt.want.BinaryPath, err)
+				}
+				return
+			}
 			if tt.want != nil && tt.want.BinaryPath != "" {
 				// Cleanup.
-				// These two files should be here by now and os.Remove will fail if
+				// These files should be here by now and os.Remove will fail if
 				// they aren't.
 				if err := os.Remove(filepath.Clean(tt.want.BinaryPath)); err != nil {
 					t.Fatal(err)
@@ -598,24 +1076,100 @@ func TestRequirement_InstallLatest(t *testing.T) {
 				if err := os.Remove(filepath.Clean(tt.want.BinaryPath + "_SHA256SUM")); err != nil {
 					t.Fatal(err)
 				}
+				if err := os.Remove(filepath.Clean(ReceiptPath(tt.want.BinaryPath))); err != nil {
+					t.Fatal(err)
+				}
 			}
 		})
 	}
 }
 
+func TestRequirement_InstallVersion(t *testing.T) {
+	const filename = "packer-plugin-amazon_v1.2.1_x5.0_darwin_amd64.zip"
+	content, err := ioutil.ReadAll(zipFile(map[string]string{
+		"packer-plugin-amazon_v1.2.1_x5.0_darwin_amd64": "v1.2.1_x5.0_darwin_amd64",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+
+	tmpDir, err := ioutil.TempDir("", "packer-install-version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	// newer versions are installed, and allowed by the constraints: v1.2.1
+	// is still installed when asked for.
+	constraints, err := version.NewConstraint(">= v1.2.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr := &Requirement{Identifier: identifier, VersionConstraints: constraints}
+	got, err := pr.InstallVersion(context.Background(), version.Must(version.NewVersion("v1.2.1")), InstallOptions{
+		Getters: []Getter{&mockPluginGetter{
+			Releases: []Release{{Version: "v1.2.1"}, {Version: "v1.2.5"}},
+			ChecksumFileEntries: map[string][]ChecksumFileEntry{
+				"1.2.1": {{Filename: filename, Checksum: hex.EncodeToString(sum[:])}},
+			},
+			Zips: map[string]io.ReadCloser{
+				"github.com/hashicorp/packer-plugin-amazon/" + filename: ioutil.NopCloser(bytes.NewReader(content)),
+			},
+		}},
+		InFolders: []string{pluginFolderOne, tmpDir},
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			APIVersionMajor: "5", APIVersionMinor: "0",
+			OS: "darwin", ARCH: "amd64",
+			Checksummers: []Checksummer{{Type: "sha256", Hash: sha256.New()}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Installation{
+		BinaryPath:   filepath.ToSlash(filepath.Join(tmpDir, "github.com", "hashicorp", "amazon", "packer-plugin-amazon_v1.2.1_x5.0_darwin_amd64")),
+		Version:      "v1.2.1",
+		APIVersion:   "x5.0",
+		OS:           "darwin",
+		ARCH:         "amd64",
+		ChecksumType: "sha256",
+	}
+	if diff := cmp.Diff(want, got, ignoreModTime, ignoreReceipt); diff != "" {
+		t.Fatalf("Requirement.InstallVersion() %s", diff)
+	}
+	if pr.VersionConstraints.String() != ">= v1.2.5" {
+		t.Fatalf("expected the constraints of the requirement to be kept, got %q", pr.VersionConstraints)
+	}
+}
+
 type mockPluginGetter struct {
 	Releases            []Release
 	ChecksumFileEntries map[string][]ChecksumFileEntry
 	Zips                map[string]io.ReadCloser
+	// ChecksumType is the type of the only checksum file of the releases,
+	// sha256 when empty.
+	ChecksumType string
 }
 
-func (g *mockPluginGetter) Get(what string, options GetOptions) (io.ReadCloser, error) {
+func (g *mockPluginGetter) Get(ctx context.Context, what string, options GetOptions) (io.ReadCloser, error) {
 
 	var toEncode interface{}
 	switch what {
 	case "releases":
 		toEncode = g.Releases
-	case "sha256":
+	case "sha256", "sha512", "blake2b":
+		checksumType := g.ChecksumType
+		if checksumType == "" {
+			checksumType = "sha256"
+		}
+		if what != checksumType {
+			return nil, &StatusError{URL: what, StatusCode: 404}
+		}
 		toEncode = g.ChecksumFileEntries[options.version.String()]
 	case "zip":
 		acc := options.PluginRequirement.Identifier.Hostname + "/" +
@@ -0,0 +1,53 @@
+package plugingetter
+
+import (
+	"io"
+)
+
+// ProgressTracker is told about the progress of the download of the zip file
+// of a plugin, and of the extraction of its binary, to render progress bars.
+// It has the signature of the ProgressTracker of go-getter, so a packer Ui
+// can be used as one.
+type ProgressTracker interface {
+	// TrackProgress returns a reader of stream reporting its progress; src
+	// is the name of the file, currentSize the bytes already read, when a
+	// download is resumed, and totalSize the size of the file or -1 when
+	// unknown. Closing the returned reader ends the tracking, stream is
+	// closed by the caller.
+	TrackProgress(src string, currentSize, totalSize int64, stream io.ReadCloser) (body io.ReadCloser)
+}
+
+// SizedReadCloser is a file returned by a getter that knows its size, so that
+// the progress of its download can be reported.
+type SizedReadCloser interface {
+	io.ReadCloser
+	// Size returns the number of bytes left to read, or -1 when unknown.
+	Size() int64
+}
+
+type sizedReadCloser struct {
+	io.ReadCloser
+	size int64
+}
+
+func (s *sizedReadCloser) Size() int64 { return s.size }
+
+// SizedBody returns body, with its size of size bytes, typically the content
+// length of the response of a zip file. Getters use it to report the progress
+// of downloads.
+func SizedBody(body io.ReadCloser, size int64) SizedReadCloser {
+	if size < 0 {
+		size = -1
+	}
+	return &sizedReadCloser{ReadCloser: body, size: size}
+}
+
+// trackProgress returns stream tracked by tracker, when set. The returned
+// closer must be called once stream was read.
+func trackProgress(tracker ProgressTracker, src string, currentSize, totalSize int64, stream io.ReadCloser) (io.Reader, func()) {
+	if tracker == nil {
+		return stream, func() {}
+	}
+	tracked := tracker.TrackProgress(src, currentSize, totalSize, stream)
+	return tracked, func() { _ = tracked.Close() }
+}
@@ -0,0 +1,65 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Prune removes the binaries of the plugin matching the version constraints
+// of pr, along with their checksum files, but the ones of its keep highest
+// versions, and returns the removed installations. Binaries are looked up in
+// opts.FromFolders like Uninstall does, so binaries of a same version, built
+// for different protocol versions or found in different folders, count as
+// one version. Long-lived machines can use it to get rid of superseded
+// versions.
+func (pr Requirement) Prune(opts ListInstallationsOptions, keep int) (InstallList, error) {
+	return pr.prune(context.Background(), opts, keep, "")
+}
+
+// prune is Prune, ignoring the versions higher than upTo when it is set.
+func (pr Requirement) prune(ctx context.Context, opts ListInstallationsOptions, keep int, upTo string) (InstallList, error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("Prune: %q at least one version must be kept, got %d", pr.Identifier.String(), keep)
+	}
+	installs, err := pr.installations(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	if upTo != "" {
+		installs = InstallList(installs).Filter(func(install *Installation) bool {
+			return !versionLess(upTo, install.Version)
+		})
+	}
+
+	versions := []string{}
+	for _, install := range installs {
+		found := false
+		for _, v := range versions {
+			found = found || v == install.Version
+		}
+		if !found {
+			versions = append(versions, install.Version)
+		}
+	}
+	if len(versions) <= keep {
+		return InstallList{}, nil
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionLess(versions[j], versions[i]) })
+	kept := map[string]bool{}
+	for _, v := range versions[:keep] {
+		kept[v] = true
+	}
+
+	removed := InstallList{}
+	for _, install := range installs {
+		if kept[install.Version] {
+			continue
+		}
+		if err := removeInstallation(ctx, install, opts.Checksummers); err != nil {
+			return removed, fmt.Errorf("Prune: %q %v", pr.Identifier.String(), err)
+		}
+		removed = append(removed, install)
+	}
+	return removed, nil
+}
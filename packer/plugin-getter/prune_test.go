@@ -0,0 +1,88 @@
+package plugingetter
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+func TestRequirement_Prune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-plugins-prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string) string {
+		path := filepath.Join(dir, "github.com", "hashicorp", "amazon", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []string{path, path + "_SHA256SUM"} {
+			if err := ioutil.WriteFile(p, []byte(name), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+	v1 := write("packer-plugin-amazon_v1.0.0_x5.0_linux_amd64")
+	v1x6 := write("packer-plugin-amazon_v1.0.0_x6.0_linux_amd64")
+	v11 := write("packer-plugin-amazon_v1.1.0_x5.0_linux_amd64")
+	write("packer-plugin-amazon_v1.2.0_x5.0_linux_amd64")
+	write("packer-plugin-amazon_v2.0.0_x5.0_linux_amd64")
+
+	identifier, diags := addrs.ParsePluginSourceString("github.com/hashicorp/amazon")
+	if len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	opts := ListInstallationsOptions{
+		FromFolders: []string{dir},
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			APIVersionMajor: "5", APIVersionMinor: "0",
+			OS: "linux", ARCH: "amd64",
+			Checksummers: []Checksummer{
+				{Type: "sha256", Hash: sha256.New()},
+			},
+		},
+	}
+	pr := Requirement{Identifier: identifier}
+
+	if _, err := pr.Prune(opts, 0); err == nil {
+		t.Fatal("expected keeping no version to be refused")
+	}
+
+	// v2.0.0 is higher than v1.2.0, it is neither kept nor removed.
+	removed, err := pr.prune(context.Background(), opts, 1, "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InstallList{
+		{BinaryPath: v1, Version: "v1.0.0", APIVersion: "x5.0", OS: "linux", ARCH: "amd64"},
+		{BinaryPath: v1x6, Version: "v1.0.0", APIVersion: "x6.0", OS: "linux", ARCH: "amd64"},
+		{BinaryPath: v11, Version: "v1.1.0", APIVersion: "x5.0", OS: "linux", ARCH: "amd64"},
+	}
+	if diff := cmp.Diff(want, removed, ignoreModTime); diff != "" {
+		t.Fatalf("prune(): %s", diff)
+	}
+	for _, install := range removed {
+		for _, p := range []string{install.BinaryPath, install.BinaryPath + "_SHA256SUM"} {
+			if _, err := os.Stat(p); !os.IsNotExist(err) {
+				t.Errorf("%s should have been removed: %v", p, err)
+			}
+		}
+	}
+
+	removed, err = pr.Prune(opts, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("expected nothing to be removed, got %s", removed)
+	}
+}
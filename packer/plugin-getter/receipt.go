@@ -0,0 +1,141 @@
+package plugingetter
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/helper/longpath"
+	packerversion "github.com/hashicorp/packer/version"
+)
+
+// DefaultExtractFiles are the files `packer init` extracts from plugin
+// archives next to the binaries: licenses and JSON schemas of components.
+var DefaultExtractFiles = []string{"LICENSE*", "NOTICE*", "*.schema.json", "schemas/*.json"}
+
+// InstallReceipt describes where a binary comes from and how it was
+// installed. It is written next to the binaries installed by InstallLatest,
+// in the file of ReceiptPath.
+type InstallReceipt struct {
+	// Source is the source address of the plugin, like
+	// github.com/hashicorp/amazon.
+	Source string `json:"source,omitempty"`
+
+	// Version of the installed plugin, like v1.2.3.
+	Version string `json:"version"`
+
+	// Getter describes where the archive was downloaded from, like GitHub
+	// or a mirror, see GetterName. It is ReceiptGetterPluginCache for the
+	// binaries installed from the plugin cache.
+	Getter string `json:"getter,omitempty"`
+
+	// Archive is the name of the zip file, or tarball, the binary was
+	// extracted from. It is empty for the binaries installed from the
+	// plugin cache.
+	Archive string `json:"archive,omitempty"`
+
+	// ArchiveChecksums are the hex encoded checksums of Archive, by
+	// checksum type, like sha256.
+	ArchiveChecksums map[string]string `json:"archive_checksums,omitempty"`
+
+	// BinaryChecksums are the hex encoded checksums of the binary, by
+	// checksum type.
+	BinaryChecksums map[string]string `json:"binary_checksums,omitempty"`
+
+	// Files are the slash separated names of the files extracted from the
+	// archive along with the binary, see InstallOptions.ExtractFiles. They
+	// are relative to the folder of FilesDir.
+	Files []string `json:"files,omitempty"`
+
+	// InstalledAt is when the binary was installed.
+	InstalledAt time.Time `json:"installed_at"`
+
+	// PackerVersion is the version of Packer that installed the binary.
+	PackerVersion string `json:"packer_version,omitempty"`
+}
+
+// ReceiptGetterPluginCache is the Getter of the receipts of the binaries
+// installed from the plugin cache.
+const ReceiptGetterPluginCache = "plugin cache"
+
+// newInstallReceipt returns the receipt of a binary of pr, of version v,
+// installed now by this version of Packer.
+func newInstallReceipt(pr *Requirement, v string) *InstallReceipt {
+	return &InstallReceipt{
+		Source:        pr.Identifier.String(),
+		Version:       v,
+		InstalledAt:   time.Now().UTC(),
+		PackerVersion: packerversion.FormattedVersion(),
+	}
+}
+
+// GetterName describes getter in install receipts: the getters wrapped by
+// a RetryPolicy or a CircuitBreaker are unwrapped, getters that don't
+// describe themselves with a String method are named after their type.
+func GetterName(getter Getter) string {
+	switch g := getter.(type) {
+	case *retryingGetter:
+		return GetterName(g.Getter)
+	case *CircuitBreaker:
+		return GetterName(g.Getter)
+	case fmt.Stringer:
+		return g.String()
+	default:
+		return fmt.Sprintf("%T", getter)
+	}
+}
+
+// hexSums returns the hex encoded sums of cs, by checksum type.
+func (cs checksums) hexSums() map[string]string {
+	res := make(map[string]string, len(cs))
+	for typ, h := range cs {
+		res[typ] = hex.EncodeToString(h.Sum(nil))
+	}
+	return res
+}
+
+// ReceiptPath returns the path of the install receipt of the binary at
+// binaryPath.
+func ReceiptPath(binaryPath string) string {
+	return binaryPath + "_receipt.json"
+}
+
+// FilesDir returns the path of the folder the files extracted along with the
+// binary at binaryPath are installed in.
+func FilesDir(binaryPath string) string {
+	return binaryPath + "_files"
+}
+
+// ReadInstallReceipt reads the install receipt of the binary at binaryPath.
+// The error satisfies os.IsNotExist when the binary has no receipt, like the
+// ones installed manually or from the plugin cache.
+func ReadInstallReceipt(binaryPath string) (*InstallReceipt, error) {
+	b, err := ioutil.ReadFile(longpath.Fix(ReceiptPath(binaryPath)))
+	if err != nil {
+		return nil, err
+	}
+	receipt := &InstallReceipt{}
+	return receipt, json.Unmarshal(b, receipt)
+}
+
+// writeInstallReceipt writes receipt as the install receipt of the binary at
+// binaryPath.
+func writeInstallReceipt(binaryPath string, receipt *InstallReceipt) error {
+	b, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(longpath.Fix(ReceiptPath(binaryPath)), append(b, '\n'), 0644)
+}
+
+// removeInstallFiles removes the install receipt and the extracted files of
+// the binary at binaryPath.
+func removeInstallFiles(binaryPath string) error {
+	if err := os.Remove(longpath.Fix(ReceiptPath(binaryPath))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(longpath.Fix(FilesDir(binaryPath)))
+}
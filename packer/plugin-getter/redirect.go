@@ -0,0 +1,148 @@
+package plugingetter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+// SourceAliasesEnvVar is the env var setting the local source aliases of
+// plugins, a comma separated list of source=target pairs, like
+// github.com/hashicorp/foo=github.com/neworg/foo.
+const SourceAliasesEnvVar = "PACKER_PLUGIN_SOURCE_ALIASES"
+
+// maxRedirects is the number of redirects followed for a plugin, so that a
+// chain of redirects going nowhere fails.
+const maxRedirects = 5
+
+// maxRedirectSize is the maximum size of a redirect document.
+const maxRedirectSize = 64 * 1024
+
+// Redirect is the "redirect" document the host of a plugin, or a mirror,
+// serves when the plugin moved to another source address:
+//
+//	{"source": "github.com/neworg/foo"}
+type Redirect struct {
+	Source string `json:"source"`
+}
+
+// RedirectOptions tells ResolveRedirects where redirects are found.
+type RedirectOptions struct {
+	// Aliases maps the source addresses of plugins to the ones they moved
+	// to. They are set locally and take precedence over the other redirects.
+	Aliases map[string]string
+
+	// LockFile, when set, provides the redirects recorded by packer init.
+	LockFile *LockFile
+
+	// Getters are asked for the "redirect" document of the plugin. No
+	// getter is asked when empty, like when offline.
+	Getters []Getter
+}
+
+// ParseSourceAliases parses a comma separated list of source=target aliases,
+// like github.com/hashicorp/foo=github.com/neworg/foo.
+func ParseSourceAliases(value string) (map[string]string, error) {
+	aliases := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.Index(entry, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid plugin source alias %q: expected source=target", entry)
+		}
+		source, target := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		for _, s := range []string{source, target} {
+			if _, diags := addrs.ParsePluginSourceString(s); diags.HasErrors() {
+				return nil, fmt.Errorf("invalid plugin source alias %q: %s", entry, diags)
+			}
+		}
+		aliases[source] = target
+	}
+	return aliases, nil
+}
+
+// SourceAliasesFromEnv returns the source aliases set with
+// PACKER_PLUGIN_SOURCE_ALIASES.
+func SourceAliasesFromEnv() (map[string]string, error) {
+	aliases, err := ParseSourceAliases(os.Getenv(SourceAliasesEnvVar))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s: %s", SourceAliasesEnvVar, err)
+	}
+	return aliases, nil
+}
+
+// ResolveRedirects follows the redirects of the source address of pr, so
+// that a plugin that moved, like from github.com/hashicorp/foo to
+// github.com/neworg/foo, is installed and used from its new source without
+// changing every template requiring it. pr is updated with the final source
+// address, and its original one is recorded in RedirectedFrom.
+func (pr *Requirement) ResolveRedirects(ctx context.Context, opts RedirectOptions) error {
+	for i := 0; ; i++ {
+		from := pr.Identifier
+		to, why, err := pr.redirect(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if to == nil {
+			return nil
+		}
+		if i == maxRedirects {
+			return fmt.Errorf("the %s plugin was redirected more than %d times", pr.RedirectedFrom, maxRedirects)
+		}
+		if to.String() == from.String() || (pr.RedirectedFrom != nil && to.String() == pr.RedirectedFrom.String()) {
+			return fmt.Errorf("the %s plugin is redirected back to %s by %s", from, to, why)
+		}
+		Logf(ctx, "[INFO] the %s plugin moved to %s, as told by %s", from, to, why)
+		if pr.RedirectedFrom == nil {
+			pr.RedirectedFrom = from
+		}
+		pr.Identifier = to
+	}
+}
+
+// redirect returns the source address the plugin of pr moved to, and what
+// told so, or nil when it didn't move.
+func (pr *Requirement) redirect(ctx context.Context, opts RedirectOptions) (*addrs.Plugin, string, error) {
+	if target, found := opts.Aliases[pr.Identifier.String()]; found {
+		to, err := parseRedirectSource(target)
+		return to, "a local alias", err
+	}
+	if locked := opts.LockFile.RedirectedFrom(pr.Identifier); locked != nil {
+		return locked.Identifier, "the lock file", nil
+	}
+	for _, getter := range opts.Getters {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		doc, err := getter.Get(ctx, "redirect", GetOptions{PluginRequirement: pr})
+		if err != nil {
+			Logf(ctx, "[TRACE] %q getter has no redirect for the %s plugin: %s", getter, pr.Identifier, err)
+			continue
+		}
+		var redirect Redirect
+		err = json.NewDecoder(io.LimitReader(doc, maxRedirectSize)).Decode(&redirect)
+		_ = doc.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("could not parse the redirect of the %s plugin served by %s: %w", pr.Identifier, getter, err)
+		}
+		to, err := parseRedirectSource(redirect.Source)
+		return to, fmt.Sprint(getter), err
+	}
+	return nil, "", nil
+}
+
+func parseRedirectSource(source string) (*addrs.Plugin, error) {
+	to, diags := addrs.ParsePluginSourceString(source)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid redirect source %q: %s", source, diags)
+	}
+	return to, nil
+}
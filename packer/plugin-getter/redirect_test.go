@@ -0,0 +1,101 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/hcl2template/addrs"
+)
+
+// redirectGetter serves the redirects of the plugins it maps to their new
+// source address.
+type redirectGetter map[string]string
+
+func (g redirectGetter) Get(_ context.Context, what string, opts GetOptions) (io.ReadCloser, error) {
+	if what != "redirect" {
+		return nil, fmt.Errorf("%q not implemented", what)
+	}
+	target, found := g[opts.PluginRequirement.Identifier.String()]
+	if !found {
+		return nil, &StatusError{URL: what, StatusCode: 404}
+	}
+	return ioutil.NopCloser(strings.NewReader(`{"source": "` + target + `"}`)), nil
+}
+
+func TestRequirement_ResolveRedirects(t *testing.T) {
+	lockFile := &LockFile{Plugins: []*LockedPlugin{{
+		Identifier:     &addrs.Plugin{Hostname: "github.com", Namespace: "neworg", Type: "locked"},
+		Version:        version.Must(version.NewVersion("1.0.0")),
+		RedirectedFrom: &addrs.Plugin{Hostname: "github.com", Namespace: "hashicorp", Type: "locked"},
+	}}}
+	opts := RedirectOptions{
+		Aliases: map[string]string{
+			"github.com/hashicorp/aliased": "github.com/neworg/aliased",
+		},
+		LockFile: lockFile,
+		Getters: []Getter{redirectGetter{
+			"github.com/hashicorp/moved":  "github.com/neworg/moved",
+			"github.com/neworg/moved":     "github.com/otherorg/moved",
+			"github.com/hashicorp/loop":   "github.com/neworg/loop",
+			"github.com/neworg/loop":      "github.com/hashicorp/loop",
+			"github.com/hashicorp/broken": "not a source",
+		}},
+	}
+
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{"github.com/hashicorp/amazon", "github.com/hashicorp/amazon", false},
+		{"github.com/hashicorp/aliased", "github.com/neworg/aliased", false},
+		{"github.com/hashicorp/locked", "github.com/neworg/locked", false},
+		{"github.com/hashicorp/moved", "github.com/otherorg/moved", false},
+		{"github.com/hashicorp/loop", "", true},
+		{"github.com/hashicorp/broken", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			id, diags := addrs.ParsePluginSourceString(tt.source)
+			if len(diags) != 0 {
+				t.Fatal(diags)
+			}
+			pr := &Requirement{Identifier: id}
+			err := pr.ResolveRedirects(context.Background(), opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRedirects() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if pr.Identifier.String() != tt.want {
+				t.Errorf("expected %s to be redirected to %s, got %s", tt.source, tt.want, pr.Identifier)
+			}
+			if moved := tt.want != tt.source; moved != (pr.RedirectedFrom != nil) {
+				t.Errorf("unexpected RedirectedFrom %v", pr.RedirectedFrom)
+			} else if moved && pr.RedirectedFrom.String() != tt.source {
+				t.Errorf("expected the redirect from %s to be recorded, got %s", tt.source, pr.RedirectedFrom)
+			}
+		})
+	}
+}
+
+func TestParseSourceAliases(t *testing.T) {
+	aliases, err := ParseSourceAliases(" github.com/hashicorp/foo=github.com/neworg/foo ,, example.com/a/b = example.com/c/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 2 || aliases["github.com/hashicorp/foo"] != "github.com/neworg/foo" || aliases["example.com/a/b"] != "example.com/c/b" {
+		t.Fatalf("unexpected aliases %v", aliases)
+	}
+	for _, invalid := range []string{"github.com/hashicorp/foo", "github.com/hashicorp/foo=not a source"} {
+		if _, err := ParseSourceAliases(invalid); err == nil {
+			t.Errorf("%q should not parse", invalid)
+		}
+	}
+}
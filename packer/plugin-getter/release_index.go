@@ -0,0 +1,95 @@
+package plugingetter
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// verifyReleases reads the releases document and checks that it was signed
+// by one of the keys. The signature is fetched from the getter as
+// "releases.sig" and must be a base64 encoded ed25519 signature of the exact
+// bytes of the releases document.
+func verifyReleases(ctx context.Context, keys []ed25519.PublicKey, getter Getter, opts GetOptions, releasesFile io.ReadCloser) (io.ReadCloser, error) {
+	defer releasesFile.Close()
+	releases, err := ioutil.ReadAll(releasesFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read releases: %w", err)
+	}
+
+	sigFile, err := getter.Get(ctx, "releases.sig", opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not get releases signature: %w", err)
+	}
+	defer sigFile.Close()
+	encodedSig, err := ioutil.ReadAll(sigFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read releases signature: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSig)))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode releases signature: %w", err)
+	}
+
+	if err := VerifyReleases(keys, releases, sig); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(releases)), nil
+}
+
+// ReleasesSignatureGetter is implemented by getters that can serve the
+// "releases.sig" signature of the releases document.
+type ReleasesSignatureGetter interface {
+	// ServesReleasesSignature tells whether "releases.sig" can be fetched
+	// from the getter.
+	ServesReleasesSignature() bool
+}
+
+// ServeReleasesSignature tells whether one of getters can serve the signature
+// of the releases document, without which no release can be installed when
+// InstallOptions.ReleasesPublicKeys are set.
+func ServeReleasesSignature(getters []Getter) bool {
+	for _, getter := range getters {
+		if sg, ok := getter.(ReleasesSignatureGetter); ok && sg.ServesReleasesSignature() {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyReleases checks that sig is a valid ed25519 signature of releases
+// by one of keys.
+func VerifyReleases(keys []ed25519.PublicKey, releases, sig []byte) error {
+	for _, key := range keys {
+		if ed25519.Verify(key, releases, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("releases signature does not match any of the %d trusted key(s)", len(keys))
+}
+
+// ParsePublicKeys parses a comma separated list of base64 encoded ed25519
+// public keys.
+func ParsePublicKeys(s string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, encoded := range strings.Split(s, ",") {
+		encoded = strings.TrimSpace(encoded)
+		if encoded == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode public key %q: %w", encoded, err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("public key %q is %d bytes long, expected %d", encoded, len(key), ed25519.PublicKeySize)
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	return keys, nil
+}
@@ -0,0 +1,79 @@
+package plugingetter
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+type signedReleasesGetter struct {
+	releases, sig []byte
+}
+
+func (g *signedReleasesGetter) Get(ctx context.Context, what string, opts GetOptions) (io.ReadCloser, error) {
+	switch what {
+	case "releases":
+		return ioutil.NopCloser(bytes.NewReader(g.releases)), nil
+	case "releases.sig":
+		return ioutil.NopCloser(bytes.NewBufferString(base64.StdEncoding.EncodeToString(g.sig))), nil
+	}
+	return nil, fmt.Errorf("%q not implemented", what)
+}
+
+func TestVerifyReleases(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := ParsePublicKeys(base64.StdEncoding.EncodeToString(otherPub) + ", " + base64.StdEncoding.EncodeToString(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+
+	releases := []byte(`[{"version":"v1.0.0"}]`)
+	getter := &signedReleasesGetter{releases: releases, sig: ed25519.Sign(priv, releases)}
+	rc, err := verifyReleases(context.Background(), keys, getter, GetOptions{}, ioutil.NopCloser(bytes.NewReader(releases)))
+	if err != nil {
+		t.Fatalf("verifyReleases: %s", err)
+	}
+	got, _ := ioutil.ReadAll(rc)
+	if !bytes.Equal(got, releases) {
+		t.Fatalf("unexpected releases %q", got)
+	}
+
+	tampered := []byte(`[{"version":"v6.6.6"}]`)
+	if _, err := verifyReleases(context.Background(), keys, getter, GetOptions{}, ioutil.NopCloser(bytes.NewReader(tampered))); err == nil {
+		t.Fatal("expected tampered releases to be rejected")
+	}
+
+	if _, err := ParsePublicKeys("bm90IGEga2V5"); err == nil {
+		t.Fatal("expected a key of the wrong size to be rejected")
+	}
+}
+
+func (g *signedReleasesGetter) ServesReleasesSignature() bool { return true }
+
+func TestServeReleasesSignature(t *testing.T) {
+	signed := &CircuitBreaker{Getter: &signedReleasesGetter{}}
+	unsigned := &CircuitBreaker{Getter: &failingGetter{}}
+
+	if ServeReleasesSignature([]Getter{unsigned}) {
+		t.Fatal("expected a getter without signatures to not serve them")
+	}
+	if !ServeReleasesSignature([]Getter{unsigned, (&RetryPolicy{}).Wrap(signed)}) {
+		t.Fatal("expected a wrapped signing getter to serve signatures")
+	}
+}
@@ -0,0 +1,212 @@
+package plugingetter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultRetryBackoff is the time waited before the first retry of a getter
+// call when the Backoff of a RetryPolicy is not set. It doubles after each
+// attempt.
+const DefaultRetryBackoff = time.Second
+
+// maxRetryBackoff caps the time waited between two attempts of a call.
+const maxRetryBackoff = 30 * time.Second
+
+// DefaultMaxRetryAfter is the longest time waited for a remote asking to
+// retry later, like a rate limited API, when the MaxRetryAfter of a
+// RetryPolicy is not set.
+const DefaultMaxRetryAfter = 15 * time.Minute
+
+// StatusError is returned by getters when a remote answers with an unexpected
+// HTTP status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// Err is the underlying error, if any, it describes the request.
+	Err error
+	// RetryAfter, when set, is how long the remote asked to wait before
+	// retrying, like until the reset of an exceeded rate limit.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("GET %s: unexpected status %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// IsTransientErr tells whether err is worth retrying: a network error, a
+// timeout, a server error or a rate limit.
+func IsTransientErr(err error) bool {
+	if isUnavailableErr(err) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests || statusErr.RetryAfter > 0
+	}
+	return false
+}
+
+// retryAfter returns how long the remote that failed with err asked to wait
+// before retrying, or 0 when it didn't.
+func retryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// RetryPolicy tells how calls to getters are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a call is made, including the
+	// first one. Calls are not retried when it is 1 or less.
+	MaxAttempts int
+
+	// Backoff is the time waited before the first retry, it doubles after
+	// each attempt. It defaults to DefaultRetryBackoff.
+	Backoff time.Duration
+
+	// Retryable tells whether a call failing with err should be retried, it
+	// defaults to IsTransientErr.
+	Retryable func(err error) bool
+
+	// MaxRetryAfter is the longest time waited for a remote asking to retry
+	// later, like until the reset of a rate limit. Calls asked to wait
+	// longer are not retried. It defaults to DefaultMaxRetryAfter.
+	MaxRetryAfter time.Duration
+
+	// sleep is replaced in tests.
+	sleep func(time.Duration)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransientErr(err)
+}
+
+// backoff returns how long to wait before the retry following attempt,
+// attempts being numbered from 1.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+func (p *RetryPolicy) maxRetryAfter() time.Duration {
+	if p.MaxRetryAfter > 0 {
+		return p.MaxRetryAfter
+	}
+	return DefaultMaxRetryAfter
+}
+
+// do calls f until it succeeds, fails with an error that is not retryable,
+// was called MaxAttempts times, or ctx is done.
+func (p *RetryPolicy) do(ctx context.Context, desc string, f func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	for attempt := 1; ; attempt++ {
+		rc, err := f()
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !p.retryable(err) {
+			return rc, err
+		}
+		backoff := p.backoff(attempt)
+		if after := retryAfter(err); after > 0 {
+			if after > p.maxRetryAfter() {
+				Logf(ctx, "[DEBUG] %s failed, not retrying in %s, longer than %s: %v", desc, after, p.maxRetryAfter(), err)
+				return rc, err
+			}
+			if after > backoff {
+				backoff = after
+			}
+		}
+		Logf(ctx, "[DEBUG] %s failed, retrying in %s (attempt %d/%d): %v", desc, backoff, attempt+1, p.MaxAttempts, err)
+		if p.sleep != nil {
+			p.sleep(backoff)
+			continue
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Wrap returns getter with its calls retried according to the policy, or
+// getter itself when p is nil.
+func (p *RetryPolicy) Wrap(getter Getter) Getter {
+	if p == nil || p.MaxAttempts <= 1 {
+		return getter
+	}
+	return &retryingGetter{Getter: getter, policy: p}
+}
+
+// retryingGetter retries the calls to its getter according to a RetryPolicy.
+type retryingGetter struct {
+	Getter
+	policy *RetryPolicy
+}
+
+var (
+	_ Getter                  = &retryingGetter{}
+	_ Locator                 = &retryingGetter{}
+	_ RangeGetter             = &retryingGetter{}
+	_ ReleasesSignatureGetter = &retryingGetter{}
+)
+
+func (g *retryingGetter) String() string {
+	return fmt.Sprint(g.Getter)
+}
+
+func (g *retryingGetter) Get(ctx context.Context, what string, opts GetOptions) (io.ReadCloser, error) {
+	return g.policy.do(ctx, fmt.Sprintf("%s: getting %s", g, what), func() (io.ReadCloser, error) {
+		return g.Getter.Get(ctx, what, opts)
+	})
+}
+
+// GetRange calls the GetRange method of the wrapped getter, when it has one.
+func (g *retryingGetter) GetRange(ctx context.Context, what string, opts GetOptions, offset int64) (io.ReadCloser, error) {
+	rg, ok := g.Getter.(RangeGetter)
+	if !ok {
+		return nil, fmt.Errorf("%s can not get ranges of %q", g, what)
+	}
+	return g.policy.do(ctx, fmt.Sprintf("%s: getting a range of %s", g, what), func() (io.ReadCloser, error) {
+		return rg.GetRange(ctx, what, opts, offset)
+	})
+}
+
+// Locate calls the Locate method of the wrapped getter, when it has one.
+func (g *retryingGetter) Locate(ctx context.Context, what string, opts GetOptions) (string, int64, error) {
+	locator, ok := g.Getter.(Locator)
+	if !ok {
+		return "", -1, fmt.Errorf("%s can not locate %q", g, what)
+	}
+	return locator.Locate(ctx, what, opts)
+}
+
+// ServesReleasesSignature tells whether the wrapped getter can serve the
+// signature of releases.
+func (g *retryingGetter) ServesReleasesSignature() bool {
+	sg, ok := g.Getter.(ReleasesSignatureGetter)
+	return ok && sg.ServesReleasesSignature()
+}
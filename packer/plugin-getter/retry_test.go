@@ -0,0 +1,121 @@
+package plugingetter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyGetter fails with errs before succeeding.
+type flakyGetter struct {
+	errs  []error
+	calls int
+}
+
+func (g *flakyGetter) Get(ctx context.Context, what string, opts GetOptions) (io.ReadCloser, error) {
+	g.calls++
+	if len(g.errs) > 0 {
+		err := g.errs[0]
+		g.errs = g.errs[1:]
+		return nil, err
+	}
+	return ioutil.NopCloser(strings.NewReader(what)), nil
+}
+
+func TestRetryPolicy(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	unavailable := &StatusError{URL: "https://example.com/index.json", StatusCode: 503}
+	notFound := &StatusError{URL: "https://example.com/index.json", StatusCode: 404}
+
+	var slept []time.Duration
+	policy := &RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+		sleep:       func(d time.Duration) { slept = append(slept, d) },
+	}
+
+	getter := &flakyGetter{errs: []error{unreachable, unavailable}}
+	if _, err := policy.Wrap(getter).Get(context.Background(), "releases", GetOptions{}); err != nil {
+		t.Fatalf("expected the call to be retried until it succeeds, got %v", err)
+	}
+	if getter.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", getter.calls)
+	}
+	if len(slept) != 2 || slept[0] != time.Second || slept[1] != 2*time.Second {
+		t.Fatalf("unexpected backoffs %v", slept)
+	}
+
+	getter = &flakyGetter{errs: []error{unavailable, unavailable, unavailable, unavailable}}
+	if _, err := policy.Wrap(getter).Get(context.Background(), "releases", GetOptions{}); !errors.Is(err, unavailable) {
+		t.Fatalf("expected the last error after MaxAttempts, got %v", err)
+	}
+	if getter.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", getter.calls)
+	}
+
+	getter = &flakyGetter{errs: []error{notFound}}
+	if _, err := policy.Wrap(getter).Get(context.Background(), "releases", GetOptions{}); !errors.Is(err, notFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if getter.calls != 1 {
+		t.Fatalf("a missing file should not be retried, got %d calls", getter.calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	getter = &flakyGetter{errs: []error{unavailable}}
+	if _, err := policy.Wrap(getter).Get(ctx, "releases", GetOptions{}); !errors.Is(err, unavailable) {
+		t.Fatalf("expected the first error once cancelled, got %v", err)
+	}
+	if getter.calls != 1 {
+		t.Fatalf("a cancelled call should not be retried, got %d calls", getter.calls)
+	}
+
+	var nilPolicy *RetryPolicy
+	if g := nilPolicy.Wrap(getter); g != Getter(getter) {
+		t.Fatal("a nil policy should not wrap getters")
+	}
+}
+
+func TestRetryPolicy_retryAfter(t *testing.T) {
+	rateLimited := &StatusError{URL: "https://api.github.com/repos/hashicorp/packer-plugin-amazon/git/matching-refs/tags", StatusCode: 403, RetryAfter: 2 * time.Minute}
+	forbidden := &StatusError{URL: "https://api.github.com/repos/hashicorp/packer-plugin-amazon/git/matching-refs/tags", StatusCode: 403}
+
+	var slept []time.Duration
+	policy := &RetryPolicy{
+		MaxAttempts:   3,
+		Backoff:       time.Second,
+		MaxRetryAfter: 5 * time.Minute,
+		sleep:         func(d time.Duration) { slept = append(slept, d) },
+	}
+
+	getter := &flakyGetter{errs: []error{rateLimited}}
+	if _, err := policy.Wrap(getter).Get(context.Background(), "releases", GetOptions{}); err != nil {
+		t.Fatalf("expected the call to be retried after the rate limit reset, got %v", err)
+	}
+	if len(slept) != 1 || slept[0] != 2*time.Minute {
+		t.Fatalf("expected to wait for the rate limit reset, waited %v", slept)
+	}
+
+	getter = &flakyGetter{errs: []error{forbidden}}
+	if _, err := policy.Wrap(getter).Get(context.Background(), "releases", GetOptions{}); !errors.Is(err, forbidden) {
+		t.Fatalf("expected a forbidden error, got %v", err)
+	}
+	if getter.calls != 1 {
+		t.Fatalf("a forbidden call should not be retried, got %d calls", getter.calls)
+	}
+
+	policy.MaxRetryAfter = time.Minute
+	getter = &flakyGetter{errs: []error{rateLimited}}
+	if _, err := policy.Wrap(getter).Get(context.Background(), "releases", GetOptions{}); !errors.Is(err, rateLimited) {
+		t.Fatalf("expected the rate limit error, got %v", err)
+	}
+	if getter.calls != 1 {
+		t.Fatalf("a rate limit resetting after MaxRetryAfter should not be waited for, got %d calls", getter.calls)
+	}
+}
@@ -0,0 +1,126 @@
+package plugingetter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/packer/helper/tlspolicy"
+)
+
+const (
+	// DefaultConnectTimeout is used when a getter has no connect timeout.
+	DefaultConnectTimeout = 30 * time.Second
+	// DefaultReadTimeout is used when a getter has no read timeout.
+	DefaultReadTimeout = 60 * time.Second
+)
+
+// Timeouts configures how long a getter waits for a remote.
+type Timeouts struct {
+	// Connect bounds the time spent dialing and doing the TLS handshake.
+	Connect time.Duration
+	// Read bounds the time spent waiting for the response headers once the
+	// request is sent, and then the time spent waiting for each new piece of
+	// the response body.
+	Read time.Duration
+}
+
+// NewHTTPTransport returns an http transport, based on the default one,
+// honoring t and the TLS policy of the process, see tlspolicy. Zero
+// durations are replaced by their default value. Requests whose response body
+// doesn't receive any data for t.Read are cancelled; uploads are not bounded.
+func NewHTTPTransport(t Timeouts) http.RoundTripper {
+	if t.Connect == 0 {
+		t.Connect = DefaultConnectTimeout
+	}
+	if t.Read == 0 {
+		t.Read = DefaultReadTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   t.Connect,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = t.Connect
+	transport.ResponseHeaderTimeout = t.Read
+	// an invalid policy makes Packer exit when it starts.
+	if err := tlspolicy.ConfigureTransport(transport); err != nil {
+		log.Printf("[ERR] %s", err)
+	}
+	return &idleReadTransport{Base: transport, Timeout: t.Read}
+}
+
+// idleReadTransport cancels the requests whose response body stalls for
+// Timeout, as http.Transport only bounds the wait for the response headers.
+type idleReadTransport struct {
+	Base    http.RoundTripper
+	Timeout time.Duration
+}
+
+func (t *idleReadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	body := &idleReadBody{ReadCloser: resp.Body, timeout: t.Timeout, cancel: cancel}
+	body.timer = time.AfterFunc(t.Timeout, func() {
+		atomic.StoreInt32(&body.timedOut, 1)
+		cancel()
+	})
+	resp.Body = body
+	return resp, nil
+}
+
+// idleReadBody is a response body whose request is cancelled when no data is
+// read from it for timeout.
+type idleReadBody struct {
+	io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	cancel   context.CancelFunc
+	timedOut int32
+}
+
+func (b *idleReadBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if atomic.LoadInt32(&b.timedOut) == 1 {
+		return n, &readTimeoutError{timeout: b.timeout}
+	}
+	switch {
+	case err != nil:
+		// the body is read, or failed on its own.
+		b.timer.Stop()
+	case n > 0:
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleReadBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// readTimeoutError is returned when a response body received no data for
+// timeout. It is a net.Error, so that the request is retried and counted as
+// a failure of the remote.
+type readTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e *readTimeoutError) Error() string {
+	return fmt.Sprintf("no data received for %s while reading the response", e.timeout)
+}
+
+func (e *readTimeoutError) Timeout() bool   { return true }
+func (e *readTimeoutError) Temporary() bool { return true }
+
+var _ net.Error = &readTimeoutError{}
@@ -0,0 +1,169 @@
+package plugingetter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/longpath"
+)
+
+// IntegrityStatus is the outcome of the verification of an installed plugin
+// binary.
+type IntegrityStatus string
+
+const (
+	// IntegrityOK is the status of a binary matching all of its checksum
+	// files.
+	IntegrityOK IntegrityStatus = "ok"
+	// IntegrityMismatch is the status of a binary not matching one of its
+	// checksum files.
+	IntegrityMismatch IntegrityStatus = "mismatch"
+	// IntegrityMissingChecksum is the status of a binary without checksum
+	// file.
+	IntegrityMissingChecksum IntegrityStatus = "missing_checksum"
+	// IntegrityUnreadable is the status of a binary, or of a checksum file,
+	// that could not be read.
+	IntegrityUnreadable IntegrityStatus = "unreadable"
+)
+
+// ChecksumVerification is the comparison of the checksum of a binary with
+// one of its checksum files.
+type ChecksumVerification struct {
+	Type     string `json:"type"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Match tells whether the binary has the expected checksum.
+func (v ChecksumVerification) Match() bool { return v.Expected == v.Actual }
+
+// VerifiedInstallation is the verification of an installed plugin binary.
+type VerifiedInstallation struct {
+	BinaryPath string                 `json:"binary_path"`
+	Status     IntegrityStatus        `json:"status"`
+	Checksums  []ChecksumVerification `json:"checksums,omitempty"`
+	// Error is why the binary, or one of its checksum files, could not be
+	// read.
+	Error string `json:"error,omitempty"`
+}
+
+// VerificationReport is the result of VerifyInstallations.
+type VerificationReport struct {
+	Installations []VerifiedInstallation `json:"installations"`
+}
+
+// OK tells whether every binary matches its checksum files.
+func (r *VerificationReport) OK() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the verifications of the binaries that are not ok.
+func (r *VerificationReport) Failures() []VerifiedInstallation {
+	var failures []VerifiedInstallation
+	for _, install := range r.Installations {
+		if install.Status != IntegrityOK {
+			failures = append(failures, install)
+		}
+	}
+	return failures
+}
+
+// VerifyInstallations computes the checksums of the plugin binaries installed
+// in the hierarchical layout of the opts.FromFolders, of any system, and
+// compares them to their checksum files of the types of opts.Checksummers,
+// like the _SHA256SUM files written by `packer init`. The report lists every
+// binary, sorted by path.
+//
+// Contrary to Diagnose, the binaries of other systems are verified too, and
+// nothing is fixed. Binaries whose name can't be parsed, and leftovers of
+// interrupted installs, are not verified.
+func VerifyInstallations(opts ListInstallationsOptions) (*VerificationReport, error) {
+	report := &VerificationReport{Installations: []VerifiedInstallation{}}
+	for _, folder := range opts.FromFolders {
+		// plugins are in folder/hostname/namespace/type/
+		dirs, err := longpath.Glob(folder, "*/*/*")
+		if err != nil {
+			return nil, fmt.Errorf("VerifyInstallations: failed to list plugins of %q: %v", folder, err)
+		}
+		for _, dir := range dirs {
+			files, err := ioutil.ReadDir(longpath.Fix(dir))
+			if err != nil {
+				continue
+			}
+			prefix := "packer-plugin-" + filepath.Base(dir) + "_"
+			for _, file := range files {
+				name := file.Name()
+				if file.IsDir() || !strings.HasPrefix(name, prefix) || isChecksumFile(name, opts.Checksummers) {
+					continue
+				}
+				if _, err := parsePluginFilename(strings.TrimSuffix(strings.TrimPrefix(name, prefix), opts.Ext)); err != nil {
+					continue
+				}
+				report.Installations = append(report.Installations, verifyInstallation(filepath.Join(dir, name), opts.Checksummers))
+			}
+		}
+	}
+	sort.Slice(report.Installations, func(i, j int) bool {
+		return report.Installations[i].BinaryPath < report.Installations[j].BinaryPath
+	})
+	return report, nil
+}
+
+func isChecksumFile(name string, checksummers []Checksummer) bool {
+	for _, checksummer := range checksummers {
+		if strings.HasSuffix(name, checksummer.FileExt()) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyInstallation compares the checksums of the binary at path with its
+// checksum files.
+func verifyInstallation(path string, checksummers []Checksummer) VerifiedInstallation {
+	verified := VerifiedInstallation{
+		BinaryPath: strings.ReplaceAll(path, "\\", "/"),
+		Status:     IntegrityMissingChecksum,
+	}
+	for _, checksummer := range checksummers {
+		expected, err := checksummer.GetCacheChecksumOfFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			verified.Status = IntegrityUnreadable
+			verified.Error = fmt.Sprintf("could not read the %s checksum file: %v", checksummer.Type, err)
+			return verified
+		}
+		f, err := os.Open(longpath.Fix(path))
+		if err != nil {
+			verified.Status = IntegrityUnreadable
+			verified.Error = err.Error()
+			return verified
+		}
+		actual, err := checksummer.Sum(f)
+		_ = f.Close()
+		if err != nil {
+			verified.Status = IntegrityUnreadable
+			verified.Error = err.Error()
+			return verified
+		}
+		v := ChecksumVerification{
+			Type:     checksummer.Type,
+			Expected: hex.EncodeToString(expected),
+			Actual:   hex.EncodeToString(actual),
+		}
+		verified.Checksums = append(verified.Checksums, v)
+		if !v.Match() {
+			verified.Status = IntegrityMismatch
+		} else if verified.Status != IntegrityMismatch {
+			verified.Status = IntegrityOK
+		}
+	}
+	return verified
+}
@@ -0,0 +1,81 @@
+package plugingetter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVerifyInstallations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-plugins-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sum := func(content string) string {
+		s := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(s[:])
+	}
+	write := func(name, content, checksum string) string {
+		path := filepath.Join(dir, "github.com", "hashicorp", "amazon", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if checksum != "" {
+			if err := ioutil.WriteFile(path+"_SHA256SUM", []byte(checksum), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return strings.ReplaceAll(path, "\\", "/")
+	}
+
+	ok := write("packer-plugin-amazon_v1.0.0_x5.0_linux_amd64", "v1", sum("v1"))
+	otherSystem := write("packer-plugin-amazon_v1.0.0_x5.0_darwin_amd64", "v1 darwin", sum("v1 darwin"))
+	tampered := write("packer-plugin-amazon_v1.1.0_x5.0_linux_amd64", "v1.1 tampered", sum("v1.1"))
+	noChecksum := write("packer-plugin-amazon_v1.2.0_x5.0_linux_amd64", "v1.2", "")
+	write("packer-plugin-amazon", "legacy", "")
+	write(".packer-plugin-amazon_v1.3.0_x5.0_linux_amd64.123.tmp", "v1.3", "")
+
+	report, err := VerifyInstallations(ListInstallationsOptions{
+		FromFolders: []string{dir},
+		BinaryInstallationOptions: BinaryInstallationOptions{
+			OS: "linux", ARCH: "amd64",
+			Checksummers: []Checksummer{{Type: "sha256", Hash: sha256.New()}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []VerifiedInstallation{
+		{BinaryPath: otherSystem, Status: IntegrityOK, Checksums: []ChecksumVerification{
+			{Type: "sha256", Expected: sum("v1 darwin"), Actual: sum("v1 darwin")},
+		}},
+		{BinaryPath: ok, Status: IntegrityOK, Checksums: []ChecksumVerification{
+			{Type: "sha256", Expected: sum("v1"), Actual: sum("v1")},
+		}},
+		{BinaryPath: tampered, Status: IntegrityMismatch, Checksums: []ChecksumVerification{
+			{Type: "sha256", Expected: sum("v1.1"), Actual: sum("v1.1 tampered")},
+		}},
+		{BinaryPath: noChecksum, Status: IntegrityMissingChecksum},
+	}
+	if diff := cmp.Diff(want, report.Installations); diff != "" {
+		t.Errorf("VerifyInstallations() unexpected report: %s", diff)
+	}
+	if report.OK() {
+		t.Error("the report should not be ok")
+	}
+	if failures := report.Failures(); len(failures) != 2 {
+		t.Errorf("expected 2 failures, got %v", failures)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/pathing"
 	pluginsdk "github.com/hashicorp/packer-plugin-sdk/plugin"
+	"github.com/hashicorp/packer/helper/longpath"
 )
 
 // PluginConfig helps load and use packer plugins
@@ -215,7 +216,8 @@ func (c *PluginConfig) discoverExternalComponents(path string) error {
 }
 
 func (c *PluginConfig) discoverSingle(glob string) (map[string]string, error) {
-	matches, err := filepath.Glob(glob)
+	// the folder is not a pattern, it can be an extended Windows path
+	matches, err := longpath.Glob(filepath.Dir(glob), filepath.Base(glob))
 	if err != nil {
 		return nil, err
 	}
@@ -38,6 +38,7 @@ type PluginClient struct {
 	doneLogging chan struct{}
 	l           sync.Mutex
 	address     net.Addr
+	tracer      *pluginTracer
 }
 
 // PluginClientConfig is the configuration used to initialize a new
@@ -114,7 +115,7 @@ func NewClient(config *PluginClientConfig) (c *PluginClient) {
 		config.Stderr = ioutil.Discard
 	}
 
-	c = &PluginClient{config: config}
+	c = &PluginClient{config: config, tracer: getPluginTracer()}
 	if config.Managed {
 		managedClients = append(managedClients, c)
 	}
@@ -407,6 +408,8 @@ func (c *PluginClient) Client() (*packerrpc.Client, error) {
 		tcpConn.SetKeepAlive(true)
 	}
 
+	conn = c.traceConn(conn)
+
 	client, err := packerrpc.NewClient(conn)
 	if err != nil {
 		conn.Close()
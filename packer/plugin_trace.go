@@ -0,0 +1,191 @@
+package packer
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// These are the environment variables that enable the plugin trace, and set
+// the file it is written to.
+const (
+	EnvPluginTrace     = "PACKER_PLUGIN_TRACE"
+	EnvPluginTraceFile = "PACKER_PLUGIN_TRACE_PATH"
+)
+
+// DefaultPluginTraceFile is the file the plugin trace is appended to when
+// PACKER_PLUGIN_TRACE_PATH is not set.
+const DefaultPluginTraceFile = "packer-plugin-trace.jsonl"
+
+// PluginTraceEvent is a line of the plugin trace. Every call Packer makes to
+// a method of a plugin component, like the Prepare and Run methods of a
+// builder, produces a "start" event when the call is made and an "end" event
+// when it returns, so that a call that never returns is visible. The calls
+// plugins make back to Packer while serving such a call, to the Ui, hooks or
+// communicator, are not traced: they happen within the traced call. When a
+// connection to a plugin is closed, a "connection" event tells how much data
+// went through it.
+type PluginTraceEvent struct {
+	Time      time.Time `json:"time"`
+	CallID    uint64    `json:"call_id,omitempty"`
+	Phase     string    `json:"phase"`
+	Plugin    string    `json:"plugin"`
+	Component string    `json:"component,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	// Duration in milliseconds of the call, set on end events, or of the
+	// connection, set on connection events.
+	DurationMs float64 `json:"duration_ms,omitempty"`
+	// Bytes sent to and received from the plugin through a connection, set
+	// on connection events. They include the traffic of every call made on
+	// that connection, and of the calls the plugin made back to Packer.
+	BytesSent     int64 `json:"bytes_sent,omitempty"`
+	BytesReceived int64 `json:"bytes_received,omitempty"`
+	// Error returned by the call, with secrets hidden like in the logs.
+	Error string `json:"error,omitempty"`
+}
+
+type pluginTracer struct {
+	l      sync.Mutex
+	enc    *json.Encoder
+	callID uint64
+}
+
+var (
+	pluginTracerOnce sync.Once
+	globalTracer     *pluginTracer
+)
+
+// getPluginTracer returns the plugin tracer, or nil when the trace is not
+// enabled.
+func getPluginTracer() *pluginTracer {
+	pluginTracerOnce.Do(func() {
+		if v := os.Getenv(EnvPluginTrace); v == "" || v == "0" {
+			return
+		}
+		path := os.Getenv(EnvPluginTraceFile)
+		if path == "" {
+			path = DefaultPluginTraceFile
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Printf("[WARN] Failed to open plugin trace file, plugin calls won't be traced: %s", err)
+			return
+		}
+		log.Printf("[INFO] Tracing plugin calls to %s", path)
+		globalTracer = newPluginTracer(f)
+	})
+	return globalTracer
+}
+
+func newPluginTracer(w io.Writer) *pluginTracer {
+	return &pluginTracer{enc: json.NewEncoder(w)}
+}
+
+func (t *pluginTracer) write(e PluginTraceEvent) {
+	t.l.Lock()
+	defer t.l.Unlock()
+	if err := t.enc.Encode(e); err != nil {
+		log.Printf("[WARN] Failed to write plugin trace: %s", err)
+	}
+}
+
+// countingConn counts the bytes going through a connection to a plugin, and
+// writes them to the trace when it is closed.
+type countingConn struct {
+	// sent and received are first to be 64-bit aligned for atomic
+	// operations.
+	sent, received int64
+
+	net.Conn
+	tracer    *pluginTracer
+	plugin    string
+	opened    time.Time
+	closeOnce sync.Once
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.received, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.sent, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		now := time.Now()
+		c.tracer.write(PluginTraceEvent{
+			Time:          now,
+			Phase:         "connection",
+			Plugin:        c.plugin,
+			DurationMs:    float64(now.Sub(c.opened)) / float64(time.Millisecond),
+			BytesSent:     atomic.LoadInt64(&c.sent),
+			BytesReceived: atomic.LoadInt64(&c.received),
+		})
+	})
+	return err
+}
+
+// tracePluginName returns how the plugin is named in the trace: its binary
+// and arguments.
+func (c *PluginClient) tracePluginName() string {
+	plugin := filepath.Base(c.config.Cmd.Path)
+	if len(c.config.Cmd.Args) > 1 {
+		plugin += " " + strings.Join(c.config.Cmd.Args[1:], " ")
+	}
+	return plugin
+}
+
+// traceConn returns conn, counting the bytes going through it when the
+// plugin trace is enabled.
+func (c *PluginClient) traceConn(conn net.Conn) net.Conn {
+	if c.tracer == nil {
+		return conn
+	}
+	return &countingConn{Conn: conn, tracer: c.tracer, plugin: c.tracePluginName(), opened: time.Now()}
+}
+
+// traceCall traces a call to method of a component of the plugin, when the
+// plugin trace is enabled. The returned function must be called with the
+// error returned by the call.
+func (c *PluginClient) traceCall(component, method string) func(error) {
+	tracer := c.tracer
+	if tracer == nil {
+		return func(error) {}
+	}
+
+	start := PluginTraceEvent{
+		Time:      time.Now(),
+		CallID:    atomic.AddUint64(&tracer.callID, 1),
+		Phase:     "start",
+		Plugin:    c.tracePluginName(),
+		Component: component,
+		Method:    method,
+	}
+	tracer.write(start)
+
+	return func(err error) {
+		end := start
+		end.Time = time.Now()
+		end.Phase = "end"
+		end.DurationMs = float64(end.Time.Sub(start.Time)) / float64(time.Millisecond)
+		if err != nil {
+			end.Error = packersdk.LogSecretFilter.FilterString(err.Error())
+		}
+		tracer.write(end)
+	}
+}
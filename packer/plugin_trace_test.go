@@ -0,0 +1,93 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPluginClient_traceCall(t *testing.T) {
+	buf := new(bytes.Buffer)
+	c := &PluginClient{
+		config: &PluginClientConfig{Cmd: exec.Command("/opt/packer-plugin-test", "start", "builder")},
+		tracer: newPluginTracer(buf),
+	}
+	packersdk.LogSecretFilter.Set("s3cr3t")
+
+	c.traceCall("builder", "Prepare")(errors.New("invalid password s3cr3t"))
+
+	dec := json.NewDecoder(buf)
+	var start, end PluginTraceEvent
+	if err := dec.Decode(&start); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&end); err != nil {
+		t.Fatal(err)
+	}
+
+	if start.Phase != "start" || end.Phase != "end" || start.CallID != end.CallID {
+		t.Fatalf("unexpected events: %#v %#v", start, end)
+	}
+	if end.Plugin != "packer-plugin-test start builder" || end.Component != "builder" || end.Method != "Prepare" {
+		t.Errorf("unexpected call description: %#v", end)
+	}
+	if end.Error != "invalid password <sensitive>" {
+		t.Errorf("unexpected error: %q", end.Error)
+	}
+}
+
+func TestPluginClient_traceCall_disabled(t *testing.T) {
+	c := &PluginClient{config: &PluginClientConfig{Cmd: exec.Command("packer-plugin-test")}}
+	// must not panic
+	c.traceCall("builder", "Run")(nil)
+	if conn := c.traceConn(nil); conn != nil {
+		t.Fatalf("expected the connection to be left as is, got %#v", conn)
+	}
+}
+
+func TestCountingConn(t *testing.T) {
+	buf := new(bytes.Buffer)
+	c := &PluginClient{
+		config: &PluginClientConfig{Cmd: exec.Command("packer-plugin-test")},
+		tracer: newPluginTracer(buf),
+	}
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := c.traceConn(client)
+
+	go func() {
+		b := make([]byte, 5)
+		io.ReadFull(server, b)
+		server.Write(append(b, '!'))
+	}()
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 6)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	conn.Close()
+
+	var event PluginTraceEvent
+	dec := json.NewDecoder(buf)
+	if err := dec.Decode(&event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Phase != "connection" || event.Plugin != "packer-plugin-test" {
+		t.Errorf("unexpected event: %#v", event)
+	}
+	if event.BytesSent != 5 || event.BytesReceived != 6 {
+		t.Errorf("unexpected counts: sent %d, received %d", event.BytesSent, event.BytesReceived)
+	}
+	if dec.More() {
+		t.Error("expected a single event when the connection is closed twice")
+	}
+}
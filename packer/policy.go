@@ -0,0 +1,184 @@
+package packer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// PolicyQuery is the rego document evaluated by the PolicyChecker. Policies
+// are expected to be in the `packer` package and to define `deny` and
+// optionally `warn` rules producing messages.
+const PolicyQuery = "data.packer"
+
+// PolicyInput is the document policies are evaluated against. It contains
+// the resolved builds, after variables, locals and data sources were
+// evaluated.
+type PolicyInput struct {
+	Builds []PolicyBuild `json:"builds"`
+}
+
+// PolicyBuild describes a resolved build.
+type PolicyBuild struct {
+	Name           string              `json:"name"`
+	BuilderType    string              `json:"builder_type"`
+	Config         interface{}         `json:"config"`
+	Provisioners   []PolicyComponent   `json:"provisioners"`
+	PostProcessors [][]PolicyComponent `json:"post_processors"`
+}
+
+// PolicyComponent describes a provisioner or a post-processor of a build.
+type PolicyComponent struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// NewPolicyBuild returns the PolicyBuild of build b. config is the resolved
+// configuration of its builder.
+func NewPolicyBuild(b *CoreBuild, builderType string, config interface{}) PolicyBuild {
+	pb := PolicyBuild{
+		Name:           b.Name(),
+		BuilderType:    builderType,
+		Config:         config,
+		Provisioners:   []PolicyComponent{},
+		PostProcessors: [][]PolicyComponent{},
+	}
+	for _, p := range b.Provisioners {
+		pb.Provisioners = append(pb.Provisioners, PolicyComponent{Type: p.PType, Name: p.PName})
+	}
+	for _, pps := range b.PostProcessors {
+		list := []PolicyComponent{}
+		for _, pp := range pps {
+			list = append(list, PolicyComponent{Type: pp.PType, Name: pp.PName})
+		}
+		pb.PostProcessors = append(pb.PostProcessors, list)
+	}
+	return pb
+}
+
+// PolicyResult contains the messages produced by the `deny` and `warn` rules.
+type PolicyResult struct {
+	Deny []string `json:"deny"`
+	Warn []string `json:"warn"`
+}
+
+// PolicyChecker evaluates the rego policies found in a directory against a
+// PolicyInput, using the opa executable.
+type PolicyChecker struct {
+	// Dir is the directory containing the .rego policy files.
+	Dir string
+
+	// OPAPath is the path to the opa executable. Defaults to "opa".
+	OPAPath string
+}
+
+func (pc *PolicyChecker) Check(ctx context.Context, input *PolicyInput) (*PolicyResult, error) {
+	in, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal policy input: %s", err)
+	}
+
+	opa := pc.OPAPath
+	if opa == "" {
+		opa = "opa"
+	}
+	args := []string{"eval", "--format", "json", "--data", pc.Dir, "--stdin-input", PolicyQuery}
+	log.Printf("[INFO] evaluating policies: %s %v", opa, args)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, opa, args...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Failed to evaluate policies in %q: %s\n%s", pc.Dir, err, stderr.String())
+	}
+
+	return parsePolicyOutput(stdout.Bytes())
+}
+
+// parsePolicyOutput reads the result of `opa eval --format json data.packer`.
+func parsePolicyOutput(out []byte) (*PolicyResult, error) {
+	var evaluation struct {
+		Result []struct {
+			Expressions []struct {
+				Value struct {
+					Deny []string `json:"deny"`
+					Warn []string `json:"warn"`
+				} `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out, &evaluation); err != nil {
+		return nil, fmt.Errorf("Failed to parse policy evaluation output: %s", err)
+	}
+
+	res := &PolicyResult{}
+	for _, r := range evaluation.Result {
+		for _, expr := range r.Expressions {
+			res.Deny = append(res.Deny, expr.Value.Deny...)
+			res.Warn = append(res.Warn, expr.Value.Warn...)
+		}
+	}
+	return res, nil
+}
+
+// PolicyInput returns the PolicyInput of JSON template builds. The builder
+// configuration is the one found in the template, with its user variables
+// and template functions resolved. Values only known when the build runs,
+// like {{ .HTTPIP }}, are kept as they are.
+func (c *Core) PolicyInput(builds []packersdk.Build) (*PolicyInput, error) {
+	input := &PolicyInput{Builds: []PolicyBuild{}}
+	for _, b := range builds {
+		cb, ok := b.(*CoreBuild)
+		if !ok {
+			return nil, fmt.Errorf("unexpected build type %T", b)
+		}
+		ctx := c.Context()
+		ctx.BuildName = cb.Type
+		ctx.BuildType = cb.BuilderType
+		config, err := renderPolicyConfig(cb.BuilderConfig, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to resolve the configuration of build %q for policies: %s", cb.Name(), err)
+		}
+		input.Builds = append(input.Builds, NewPolicyBuild(cb, cb.BuilderType, config))
+	}
+	return input, nil
+}
+
+// renderPolicyConfig returns a copy of the JSON template configuration v,
+// with its strings interpolated.
+func renderPolicyConfig(v interface{}, ctx *interpolate.Context) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return interpolate.RenderRegex(v, ctx, buildTimeRenderFilter)
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			rendered, err := renderPolicyConfig(value, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", k, err)
+			}
+			res[k] = rendered
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, 0, len(v))
+		for i, value := range v {
+			rendered, err := renderPolicyConfig(value, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			res = append(res, rendered)
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
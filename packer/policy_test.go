@@ -0,0 +1,107 @@
+package packer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestParsePolicyOutput(t *testing.T) {
+	out := []byte(`{
+  "result": [
+    {
+      "expressions": [
+        {
+          "value": {
+            "deny": ["amazon-ebs.ubuntu: encrypt_boot must be set"],
+            "warn": ["amazon-ebs.ubuntu: missing team tag"],
+            "other": true
+          },
+          "text": "data.packer",
+          "location": {"row": 1, "col": 1}
+        }
+      ]
+    }
+  ]
+}`)
+	got, err := parsePolicyOutput(out)
+	if err != nil {
+		t.Fatalf("parsePolicyOutput: %s", err)
+	}
+	want := &PolicyResult{
+		Deny: []string{"amazon-ebs.ubuntu: encrypt_boot must be set"},
+		Warn: []string{"amazon-ebs.ubuntu: missing team tag"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected result: %s", diff)
+	}
+
+	got, err = parsePolicyOutput([]byte(`{}`))
+	if err != nil {
+		t.Fatalf("parsePolicyOutput: %s", err)
+	}
+	if len(got.Deny) != 0 || len(got.Warn) != 0 {
+		t.Fatalf("expected no result, got %#v", got)
+	}
+
+	if _, err := parsePolicyOutput([]byte(`not json`)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNewPolicyBuild(t *testing.T) {
+	b := &CoreBuild{
+		BuildName: "base",
+		Type:      "null.example",
+		Provisioners: []CoreBuildProvisioner{
+			{PType: "shell"},
+		},
+		PostProcessors: [][]CoreBuildPostProcessor{
+			{{PType: "manifest", PName: "main"}},
+		},
+	}
+	got := NewPolicyBuild(b, "null", map[string]interface{}{"communicator": "none"})
+	want := PolicyBuild{
+		Name:        "base.null.example",
+		BuilderType: "null",
+		Config:      map[string]interface{}{"communicator": "none"},
+		Provisioners: []PolicyComponent{
+			{Type: "shell"},
+		},
+		PostProcessors: [][]PolicyComponent{
+			{{Type: "manifest", Name: "main"}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected policy build: %s", diff)
+	}
+}
+
+func TestCore_PolicyInput(t *testing.T) {
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("policy-input.json"))
+	TestBuilder(t, config, "test")
+	core := TestCore(t, config)
+
+	build, err := core.Build("test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	input, err := core.PolicyInput([]packersdk.Build{build})
+	if err != nil {
+		t.Fatalf("PolicyInput: %s", err)
+	}
+	if len(input.Builds) != 1 {
+		t.Fatalf("expected one build, got %#v", input.Builds)
+	}
+
+	want := map[string]interface{}{
+		"region":       "eu-west-1",
+		"image":        "test-eu-west-1",
+		"boot_command": []interface{}{"http://{{ .HTTPIP }}/ks.cfg"},
+	}
+	if diff := cmp.Diff(want, input.Builds[0].Config); diff != "" {
+		t.Fatalf("unexpected config: %s", diff)
+	}
+}
@@ -24,6 +24,17 @@ type ProvisionHook struct {
 	// The provisioners to run as part of the hook. These should already
 	// be prepared (by calling Prepare) at some earlier stage.
 	Provisioners []*HookedProvisioner
+
+	// WorkDir is the scratch directory of the build. When set, it is passed
+	// to the provisioners as the PackerWorkDir build variable.
+	WorkDir string
+
+	// ArtifactsDir is the artifacts directory of the build. When set, it is
+	// passed to the provisioners as the PackerArtifactsDir build variable.
+	ArtifactsDir string
+
+	// Report records the provisioners run, when set.
+	Report *BuildReport
 }
 
 // BuilderDataCommonKeys is the list of common keys that all builder will
@@ -46,6 +57,8 @@ var BuilderDataCommonKeys = []string{
 	"PackerHTTPPort",
 	"PackerHTTPIP",
 	"PackerHTTPAddr",
+	"PackerWorkDir",
+	"PackerArtifactsDir",
 	"SSHPublicKey",
 	"SSHPrivateKey",
 	"WinRMPassword",
@@ -117,10 +130,18 @@ func (h *ProvisionHook) Run(ctx context.Context, name string, ui packersdk.Ui, c
 	}
 	for _, p := range h.Provisioners {
 		ts := CheckpointReporter.AddSpan(p.TypeName, "provisioner", p.Config)
+		endStep := h.Report.startStep("provisioner", p.TypeName)
 
 		cast := CastDataToMap(data)
+		if h.WorkDir != "" {
+			cast["PackerWorkDir"] = h.WorkDir
+		}
+		if h.ArtifactsDir != "" {
+			cast[ArtifactsDirBuildVariable] = h.ArtifactsDir
+		}
 		err := p.Provisioner.Provision(ctx, ui, comm, cast)
 
+		endStep(err)
 		ts.End(err)
 		if err != nil {
 			return err
@@ -88,6 +88,37 @@ func TestProvisionHook_cancel(t *testing.T) {
 	}
 }
 
+type dataRecordingProvisioner struct {
+	packersdk.MockProvisioner
+	data map[string]interface{}
+}
+
+func (p *dataRecordingProvisioner) Provision(_ context.Context, _ packersdk.Ui, _ packersdk.Communicator, data map[string]interface{}) error {
+	p.data = data
+	return nil
+}
+
+func TestProvisionHook_workDir(t *testing.T) {
+	p := &dataRecordingProvisioner{}
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{p, nil, ""},
+		},
+		WorkDir: "/tmp/packer-build-123",
+	}
+
+	data := map[string]interface{}{"ID": "i-123"}
+	if err := hook.Run(context.Background(), "foo", testUi(), new(packersdk.MockCommunicator), data); err != nil {
+		t.Fatal(err)
+	}
+	if p.data["PackerWorkDir"] != "/tmp/packer-build-123" {
+		t.Errorf("unexpected PackerWorkDir: %#v", p.data["PackerWorkDir"])
+	}
+	if p.data["ID"] != "i-123" {
+		t.Errorf("builder data should be kept, got %#v", p.data)
+	}
+}
+
 // TODO(mitchellh): Test that they're run in the proper order
 
 func TestPausedProvisioner_impl(t *testing.T) {
@@ -10,8 +10,32 @@ type GetBuildsOptions struct {
 	// Get builds except the ones that match with except and with only the ones
 	// that match with Only. When those are empty everything matches.
 	Except, Only []string
-	Debug, Force bool
-	OnError      string
+	// SkipProvisioners and ExcludePostProcessors are the names, or types,
+	// of the provisioners and post-processors not to run.
+	SkipProvisioners, ExcludePostProcessors []string
+	Debug, Force                            bool
+	OnError                                 string
+}
+
+// SkipsProvisioner tells whether the provisioner of type pType named pName
+// should not be run.
+func (opts GetBuildsOptions) SkipsProvisioner(pType, pName string) bool {
+	return matchesComponent(opts.SkipProvisioners, pType, pName)
+}
+
+// ExcludesPostProcessor tells whether the post-processor of type pType named
+// pName should not be run.
+func (opts GetBuildsOptions) ExcludesPostProcessor(pType, pName string) bool {
+	return matchesComponent(opts.ExcludePostProcessors, pType, pName)
+}
+
+func matchesComponent(names []string, pType, pName string) bool {
+	for _, n := range names {
+		if n != "" && (n == pType || n == pName) {
+			return true
+		}
+	}
+	return false
 }
 
 type BuildGetter interface {
@@ -28,6 +52,14 @@ type Evaluator interface {
 	EvaluateExpression(expr string) (output string, exit bool, diags hcl.Diagnostics)
 }
 
+// ExpressionCompleter can be implemented by an Evaluator to help completing
+// expressions in the `packer console` command.
+type ExpressionCompleter interface {
+	// ExpressionCompletions returns the sorted list of commands, references
+	// and functions that can be used in an expression.
+	ExpressionCompletions() []string
+}
+
 type InitializeOptions struct {
 	// When set, the execution of datasources will be skipped and the datasource will provide
 	// a output spec that will be used for validation only.
@@ -47,6 +79,7 @@ type Handler interface {
 	BuildGetter
 	ConfigFixer
 	ConfigInspector
+	PolicyInputGetter
 }
 
 //go:generate enumer -type FixConfigMode
@@ -79,3 +112,9 @@ type ConfigInspector interface {
 	// Inspect will output self inspection for a configuration
 	InspectConfig(InspectConfigOptions) (ret int)
 }
+
+type PolicyInputGetter interface {
+	// PolicyInput returns the resolved configuration of builds, as returned by
+	// GetBuilds, so that it can be evaluated by policies.
+	PolicyInput([]packersdk.Build) (*PolicyInput, hcl.Diagnostics)
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/diagnostics"
 	"github.com/mitchellh/panicwrap"
 )
 
@@ -60,9 +61,42 @@ func panicHandler(logF *os.File) panicwrap.HandlerFunc {
 			return
 		}
 
+		bundle := &diagnostics.Bundle{
+			Reason:        strings.SplitN(strings.TrimSpace(m), "\n", 2)[0],
+			Goroutines:    m,
+			Args:          os.Args[1:],
+			Env:           os.Environ(),
+			TemplatePaths: existingPaths(os.Args[1:]),
+			PluginFolders: packer.PluginFolders("."),
+			LogPath:       logF.Name(),
+		}
+		bundlePath, err := bundle.Write(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write diagnostics bundle: %s", err)
+		}
+
 		// Tell the user a crash occurred in some helpful way that
 		// they'll hopefully notice.
 		fmt.Printf("\n\n")
 		fmt.Println(strings.TrimSpace(panicOutput))
+		if bundlePath != "" {
+			fmt.Printf("\nA diagnostics bundle has been placed at %q, please attach it\n"+
+				"to your report.\n", bundlePath)
+		}
+	}
+}
+
+// existingPaths returns the arguments that are paths to existing files or
+// directories, like the template passed to a command.
+func existingPaths(args []string) []string {
+	var res []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if _, err := os.Stat(arg); err == nil {
+			res = append(res, arg)
+		}
 	}
+	return res
 }
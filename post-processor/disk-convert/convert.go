@@ -0,0 +1,126 @@
+package diskconvert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// qemuImgFormats maps the formats this post-processor knows of to their name
+// for qemu-img.
+var qemuImgFormats = map[string]string{
+	"qcow2": "qcow2",
+	"raw":   "raw",
+	"vhd":   "vpc",
+	"vhdx":  "vhdx",
+	"vmdk":  "vmdk",
+}
+
+// formatExtensions are the file extensions recognized as disk images.
+var formatExtensions = map[string]string{
+	".qcow2": "qcow2",
+	".raw":   "raw",
+	".img":   "raw",
+	".vhd":   "vhd",
+	".vhdx":  "vhdx",
+	".vmdk":  "vmdk",
+}
+
+// detectFormat returns the format of the disk image at path, from its magic
+// number. Files that are not recognized are considered raw images.
+func detectFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	n, _ := f.ReadAt(header, 0)
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, []byte("QFI\xfb")):
+		return "qcow2", nil
+	case bytes.HasPrefix(header, []byte("KDMV")):
+		return "vmdk", nil
+	case bytes.HasPrefix(header, []byte("vhdxfile")):
+		return "vhdx", nil
+	case bytes.HasPrefix(header, vhdCookie):
+		// dynamic VHDs start with a copy of their footer
+		return "vhd", nil
+	}
+	footer, err := readVHDFooter(f)
+	if err != nil {
+		return "", err
+	}
+	if footer != nil {
+		return "vhd", nil
+	}
+	return "raw", nil
+}
+
+// qemuImgConvertArgs returns the arguments of the qemu-img call converting
+// src to dst.
+func qemuImgConvertArgs(src, srcFormat, dst, dstFormat string, compress bool) []string {
+	args := []string{"convert", "-f", qemuImgFormats[srcFormat], "-O", qemuImgFormats[dstFormat]}
+	switch dstFormat {
+	case "qcow2":
+		if compress {
+			args = append(args, "-c")
+		}
+	case "vmdk":
+		if compress {
+			args = append(args, "-o", "subformat=streamOptimized")
+		}
+	case "vhd":
+		// fixed VHDs, whose size isn't rounded to a CHS geometry, are what
+		// clouds expect
+		args = append(args, "-o", "subformat=fixed,force_size")
+	}
+	return append(args, src, dst)
+}
+
+// runQemuImg runs qemu-img, found at path.
+func runQemuImg(ctx context.Context, path string, args []string) error {
+	log.Printf("[INFO] disk-convert: running %s %s", path, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, path, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %s\n%s", filepath.Base(path), err, out)
+	}
+	return nil
+}
+
+// parseSize parses a disk size in bytes, or with a K, M, G or T binary
+// suffix, like 20G.
+func parseSize(size string) (int64, error) {
+	s := strings.TrimSpace(strings.ToUpper(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n * multiplier, nil
+}
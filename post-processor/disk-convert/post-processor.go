@@ -0,0 +1,249 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package diskconvert
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const defaultOutputPath = "{{.Dir}}/{{.Name}}.{{.Format}}"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The formats to convert the disk images of the artifact to, any of
+	// `qcow2`, `raw`, `vhd`, `vhdx` and `vmdk`. VHDs are written as fixed
+	// disks whose size is a multiple of 1MiB, as expected by Azure.
+	Formats []string `mapstructure:"formats" required:"true"`
+	// The path of each converted image. This is a template engine, `Dir` and
+	// `Name` are the directory and the name without extension of the
+	// converted image, `Format` is the format being written, `BuildName`
+	// and `BuilderType` are available too. Defaults to
+	// `{{.Dir}}/{{.Name}}.{{.Format}}`.
+	OutputPath string `mapstructure:"output"`
+	// Compress the qcow2 images, and write vmdk images as streamOptimized,
+	// which is compressed. This has no effect on other formats.
+	Compress bool `mapstructure:"compress"`
+	// Grow the disks to this size before converting them, like `20G`.
+	// Disks can't be shrunk.
+	Resize string `mapstructure:"resize"`
+	// Path to the qemu-img executable. Defaults to `qemu-img`, looked up in
+	// the PATH. When qemu-img can't be found, raw images and fixed VHDs can
+	// still be converted to each other.
+	QemuImgPath string `mapstructure:"qemu_img_path"`
+
+	resizeBytes int64
+	ctx         interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "disk-convert",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"output"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+	errs := new(packersdk.MultiError)
+
+	if len(p.config.Formats) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("formats must be set"))
+	}
+	seen := map[string]bool{}
+	for _, format := range p.config.Formats {
+		if _, ok := qemuImgFormats[format]; !ok {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("Unrecognized format: %s, expected one of %s", format, knownFormats()))
+		}
+		if seen[format] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("format %s is set more than once", format))
+		}
+		seen[format] = true
+	}
+
+	if p.config.OutputPath == "" {
+		p.config.OutputPath = defaultOutputPath
+	}
+	if err = interpolate.Validate(p.config.OutputPath, &p.config.ctx); err != nil {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing output template: %s", err))
+	}
+
+	if p.config.Resize != "" {
+		p.config.resizeBytes, err = parseSize(p.config.Resize)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("resize: %s", err))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func knownFormats() string {
+	formats := make([]string, 0, len(qemuImgFormats))
+	for format := range qemuImgFormats {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return strings.Join(formats, ", ")
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	sources := diskImages(artifact.Files())
+	if len(sources) == 0 {
+		return nil, false, false, fmt.Errorf(
+			"No disk image found in the artifact of %s", artifact.BuilderId())
+	}
+
+	qemuImg := p.qemuImg()
+	if qemuImg == "" {
+		ui.Say("qemu-img not found, only raw and fixed VHD images can be converted")
+	}
+
+	var temporaryFiles []string
+	defer func() {
+		for _, f := range temporaryFiles {
+			os.Remove(f)
+		}
+	}()
+
+	newArtifact := &Artifact{}
+	for _, src := range sources {
+		srcFormat, err := detectFormat(src)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("Failed to read %s: %s", src, err)
+		}
+
+		input, inputFormat := src, srcFormat
+		if p.config.resizeBytes > 0 {
+			ui.Say(fmt.Sprintf("Resizing %s to %s", src, p.config.Resize))
+			input, inputFormat, err = p.resize(ctx, qemuImg, src, srcFormat)
+			if input != "" {
+				temporaryFiles = append(temporaryFiles, input)
+			}
+			if err != nil {
+				return nil, false, false, fmt.Errorf("Failed to resize %s: %s", src, err)
+			}
+		}
+
+		for _, format := range p.config.Formats {
+			dst, err := p.output(src, format)
+			if err != nil {
+				return nil, false, false, err
+			}
+			if filepath.Clean(dst) == filepath.Clean(src) {
+				return nil, false, false, fmt.Errorf(
+					"Converting %s to %s would overwrite it, set output to write it elsewhere", src, format)
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return nil, false, false, fmt.Errorf("Unable to create dir for %s: %s", dst, err)
+			}
+
+			ui.Say(fmt.Sprintf("Converting %s to %s", src, dst))
+			if qemuImg != "" {
+				args := qemuImgConvertArgs(input, inputFormat, dst, format, p.config.Compress)
+				err = runQemuImg(ctx, qemuImg, args)
+			} else {
+				err = convertNative(input, inputFormat, dst, format, 0)
+			}
+			if err != nil {
+				return nil, false, false, fmt.Errorf("Failed to convert %s to %s: %s", src, format, err)
+			}
+			newArtifact.files = append(newArtifact.files, dst)
+		}
+	}
+
+	return newArtifact, false, false, nil
+}
+
+// qemuImg returns the path to qemu-img, or an empty string when it can't be
+// found.
+func (p *PostProcessor) qemuImg() string {
+	name := p.config.QemuImgPath
+	if name == "" {
+		name = "qemu-img"
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// resize writes a copy of src grown to the configured size, and returns its
+// path and format.
+func (p *PostProcessor) resize(ctx context.Context, qemuImg, src, srcFormat string) (string, string, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(src), "packer-resize-*")
+	if err != nil {
+		return "", "", err
+	}
+	tmp.Close()
+
+	if qemuImg == "" {
+		return tmp.Name(), "raw", convertNative(src, srcFormat, tmp.Name(), "raw", p.config.resizeBytes)
+	}
+	if err := runQemuImg(ctx, qemuImg, qemuImgConvertArgs(src, srcFormat, tmp.Name(), "qcow2", false)); err != nil {
+		return tmp.Name(), "", err
+	}
+	args := []string{"resize", "-f", "qcow2", tmp.Name(), fmt.Sprintf("%d", p.config.resizeBytes)}
+	return tmp.Name(), "qcow2", runQemuImg(ctx, qemuImg, args)
+}
+
+func (p *PostProcessor) output(src, format string) (string, error) {
+	name := filepath.Base(src)
+	p.config.ctx.Data = map[string]interface{}{
+		"BuildName":   p.config.PackerBuildName,
+		"BuilderType": p.config.PackerBuilderType,
+		"Dir":         filepath.Dir(src),
+		"Name":        strings.TrimSuffix(name, filepath.Ext(name)),
+		"Format":      format,
+	}
+	dst, err := interpolate.Render(p.config.OutputPath, &p.config.ctx)
+	if err != nil {
+		return "", fmt.Errorf("Error interpolating output value: %s", err)
+	}
+	return filepath.FromSlash(dst), nil
+}
+
+// diskImages returns the files that look like disk images. Builders like
+// QEMU name their image without extension, so the only file of an artifact
+// is always considered a disk image.
+func diskImages(files []string) []string {
+	var images []string
+	for _, f := range files {
+		if _, ok := formatExtensions[strings.ToLower(filepath.Ext(f))]; ok {
+			images = append(images, f)
+		}
+	}
+	if len(images) == 0 && len(files) == 1 {
+		return files
+	}
+	return images
+}
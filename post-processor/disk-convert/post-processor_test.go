@@ -0,0 +1,194 @@
+package diskconvert
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_Configure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"formats", map[string]interface{}{"formats": []string{"qcow2", "vhd", "vmdk"}}, false},
+		{"resize", map[string]interface{}{"formats": []string{"raw"}, "resize": "20G"}, false},
+		{"no formats", map[string]interface{}{}, true},
+		{"unknown format", map[string]interface{}{"formats": []string{"vdi"}}, true},
+		{"duplicated format", map[string]interface{}{"formats": []string{"raw", "raw"}}, true},
+		{"bad resize", map[string]interface{}{"formats": []string{"raw"}, "resize": "big"}, true},
+		{"bad output template", map[string]interface{}{"formats": []string{"raw"}, "output": "{{.Nope"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PostProcessor{}
+			err := p.Configure(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":  1024,
+		"512M":  512 << 20,
+		"20G":   20 << 30,
+		"20GiB": 20 << 30,
+		"1t":    1 << 40,
+		" 64K ": 64 << 10,
+	}
+	for in, want := range tests {
+		got, err := parseSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "G", "-1G", "20X"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestQemuImgConvertArgs(t *testing.T) {
+	tests := []struct {
+		dstFormat string
+		compress  bool
+		want      []string
+	}{
+		{"qcow2", true, []string{"convert", "-f", "raw", "-O", "qcow2", "-c", "in", "out"}},
+		{"qcow2", false, []string{"convert", "-f", "raw", "-O", "qcow2", "in", "out"}},
+		{"vmdk", true, []string{"convert", "-f", "raw", "-O", "vmdk", "-o", "subformat=streamOptimized", "in", "out"}},
+		{"vhd", true, []string{"convert", "-f", "raw", "-O", "vpc", "-o", "subformat=fixed,force_size", "in", "out"}},
+	}
+	for _, tt := range tests {
+		got := qemuImgConvertArgs("in", "raw", "out", tt.dstFormat, tt.compress)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("qemuImgConvertArgs(%s, %t) = %v, want %v", tt.dstFormat, tt.compress, got, tt.want)
+		}
+	}
+}
+
+func TestVHDGeometry(t *testing.T) {
+	tests := []struct {
+		size                   int64
+		cylinders              uint16
+		heads, sectorsPerTrack uint8
+	}{
+		{1 << 20, 30, 4, 17},
+		{1 << 30, 2080, 16, 63},
+		{30 << 30, 62415, 16, 63},
+		{128 << 30, 65535, 16, 255},
+	}
+	for _, tt := range tests {
+		c, h, s := vhdGeometry(tt.size)
+		if c != tt.cylinders || h != tt.heads || s != tt.sectorsPerTrack {
+			t.Errorf("vhdGeometry(%d) = %d/%d/%d, want %d/%d/%d",
+				tt.size, c, h, s, tt.cylinders, tt.heads, tt.sectorsPerTrack)
+		}
+	}
+}
+
+func TestVHDFooter_checksum(t *testing.T) {
+	footer := vhdFooter(1<<20, time.Now())
+	if got, want := binary.BigEndian.Uint32(footer[64:68]), vhdChecksum(footer); got != want {
+		t.Fatalf("checksum is %x, want %x", got, want)
+	}
+}
+
+func TestPostProcessor_PostProcess_native(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-disk-convert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// no extension, like the images of the QEMU builder
+	src := filepath.Join(dir, "packer-ubuntu")
+	content := bytes.Repeat([]byte("packer"), 1000)
+	if err := ioutil.WriteFile(src, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{
+		"formats":       []string{"vhd", "raw"},
+		"resize":        "2M",
+		"qemu_img_path": filepath.Join(dir, "no-qemu-img"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	artifact := &packersdk.MockArtifact{FilesValue: []string{src}}
+	result, keep, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keep {
+		t.Errorf("the input artifact should not be kept by default")
+	}
+	vhdPath, rawPath := src+".vhd", src+".raw"
+	if !reflect.DeepEqual(result.Files(), []string{vhdPath, rawPath}) {
+		t.Fatalf("unexpected artifact files: %v", result.Files())
+	}
+
+	raw, _ := ioutil.ReadFile(rawPath)
+	if len(raw) != 2<<20 || !bytes.Equal(raw[:len(content)], content) {
+		t.Errorf("unexpected raw image of %d bytes", len(raw))
+	}
+	vhd, _ := ioutil.ReadFile(vhdPath)
+	if len(vhd) != 2<<20+vhdFooterSize || !bytes.Equal(vhd[:2<<20], raw) {
+		t.Errorf("unexpected vhd image of %d bytes", len(vhd))
+	}
+	if format, err := detectFormat(vhdPath); err != nil || format != "vhd" {
+		t.Errorf("detectFormat(vhd) = %q, %v", format, err)
+	}
+
+	// and back, from the fixed VHD
+	back := filepath.Join(dir, "back.raw")
+	if err := convertNative(vhdPath, "vhd", back, "raw", 0); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(back); !bytes.Equal(b, raw) {
+		t.Errorf("raw image converted back from vhd differs")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "packer-resize-*"))
+	if len(files) != 0 {
+		t.Errorf("temporary files left behind: %v", files)
+	}
+}
+
+func TestPostProcessor_PostProcess_requiresQemuImg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-disk-convert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "disk.raw")
+	if err := ioutil.WriteFile(src, []byte("disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{
+		"formats":       []string{"qcow2"},
+		"qemu_img_path": filepath.Join(dir, "no-qemu-img"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	artifact := &packersdk.MockArtifact{FilesValue: []string{src}}
+	if _, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), artifact); err == nil {
+		t.Fatal("converting to qcow2 without qemu-img should fail")
+	}
+}
@@ -0,0 +1,184 @@
+package diskconvert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// A fixed VHD is a raw disk image followed by a 512 bytes footer, which is
+// simple enough to be written and stripped without qemu-img.
+
+const (
+	vhdFooterSize = 512
+	vhdFixedType  = 2
+	// vhdAlignment is the size VHDs are rounded up to, as required by Azure.
+	vhdAlignment = 1024 * 1024
+)
+
+var (
+	vhdCookie = []byte("conectix")
+	vhdEpoch  = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// vhdFooter returns the footer of a fixed VHD of size bytes.
+func vhdFooter(size int64, now time.Time) []byte {
+	f := make([]byte, vhdFooterSize)
+	copy(f[0:8], vhdCookie)
+	binary.BigEndian.PutUint32(f[8:12], 2)                   // features: reserved bit
+	binary.BigEndian.PutUint32(f[12:16], 0x00010000)         // file format version
+	binary.BigEndian.PutUint64(f[16:24], 0xFFFFFFFFFFFFFFFF) // data offset, none for fixed disks
+	binary.BigEndian.PutUint32(f[24:28], uint32(now.Sub(vhdEpoch)/time.Second))
+	copy(f[28:32], "pckr")                             // creator application
+	binary.BigEndian.PutUint32(f[32:36], 0x00010000)   // creator version
+	copy(f[36:40], "Wi2k")                             // creator host OS
+	binary.BigEndian.PutUint64(f[40:48], uint64(size)) // original size
+	binary.BigEndian.PutUint64(f[48:56], uint64(size)) // current size
+	cylinders, heads, sectors := vhdGeometry(size)
+	binary.BigEndian.PutUint16(f[56:58], cylinders)
+	f[58] = heads
+	f[59] = sectors
+	binary.BigEndian.PutUint32(f[60:64], vhdFixedType)
+	_, _ = rand.Read(f[68:84]) // unique ID
+	binary.BigEndian.PutUint32(f[64:68], vhdChecksum(f))
+	return f
+}
+
+// vhdGeometry computes the CHS geometry of a disk, as described in the VHD
+// specification.
+func vhdGeometry(size int64) (cylinders uint16, heads, sectorsPerTrack uint8) {
+	totalSectors := size / 512
+	if totalSectors > 65535*16*255 {
+		totalSectors = 65535 * 16 * 255
+	}
+	var spt, h, cylinderTimesHeads int64
+	if totalSectors >= 65535*16*63 {
+		spt = 255
+		h = 16
+		cylinderTimesHeads = totalSectors / spt
+	} else {
+		spt = 17
+		cylinderTimesHeads = totalSectors / spt
+		h = (cylinderTimesHeads + 1023) / 1024
+		if h < 4 {
+			h = 4
+		}
+		if cylinderTimesHeads >= h*1024 || h > 16 {
+			spt = 31
+			h = 16
+			cylinderTimesHeads = totalSectors / spt
+		}
+		if cylinderTimesHeads >= h*1024 {
+			spt = 63
+			h = 16
+			cylinderTimesHeads = totalSectors / spt
+		}
+	}
+	return uint16(cylinderTimesHeads / h), uint8(h), uint8(spt)
+}
+
+// vhdChecksum is the one's complement of the sum of the bytes of the footer,
+// without its checksum field.
+func vhdChecksum(footer []byte) uint32 {
+	var sum uint32
+	for i, b := range footer {
+		if i >= 64 && i < 68 {
+			continue
+		}
+		sum += uint32(b)
+	}
+	return ^sum
+}
+
+// readVHDFooter returns the footer of the VHD f, or nil when f is not a
+// VHD.
+func readVHDFooter(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < vhdFooterSize {
+		return nil, nil
+	}
+	footer := make([]byte, vhdFooterSize)
+	if _, err := f.ReadAt(footer, fi.Size()-vhdFooterSize); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(footer[0:8], vhdCookie) {
+		return nil, nil
+	}
+	return footer, nil
+}
+
+// convertNative converts between raw images and fixed VHDs without
+// qemu-img. When size is larger than the disk, the disk is grown to size.
+func convertNative(src, srcFormat, dst, dstFormat string, size int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	dataSize := fi.Size()
+	switch srcFormat {
+	case "raw":
+	case "vhd":
+		footer, err := readVHDFooter(in)
+		if err != nil {
+			return err
+		}
+		if footer == nil {
+			return fmt.Errorf("%s is not a VHD", src)
+		}
+		if binary.BigEndian.Uint32(footer[60:64]) != vhdFixedType {
+			return fmt.Errorf("%s is a dynamic or differencing VHD, converting it requires qemu-img", src)
+		}
+		dataSize -= vhdFooterSize
+	default:
+		return fmt.Errorf("converting %s images requires qemu-img", srcFormat)
+	}
+
+	diskSize := dataSize
+	if size > 0 {
+		if size < diskSize {
+			return fmt.Errorf("cannot shrink %s from %d to %d bytes", src, diskSize, size)
+		}
+		diskSize = size
+	}
+	switch dstFormat {
+	case "raw":
+	case "vhd":
+		if rem := diskSize % vhdAlignment; rem != 0 {
+			diskSize += vhdAlignment - rem
+		}
+	default:
+		return fmt.Errorf("converting to %s images requires qemu-img", dstFormat)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, io.LimitReader(in, dataSize)); err != nil {
+		return err
+	}
+	// grow the disk, sparsely when the filesystem allows it
+	if err := out.Truncate(diskSize); err != nil {
+		return err
+	}
+	if dstFormat == "vhd" {
+		if _, err := out.WriteAt(vhdFooter(diskSize, time.Now()), diskSize); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
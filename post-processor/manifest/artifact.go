@@ -17,6 +17,14 @@ type Artifact struct {
 	ArtifactId    string            `json:"artifact_id"`
 	PackerRunUUID string            `json:"packer_run_uuid"`
 	CustomData    map[string]string `json:"custom_data"`
+	Signatures    []string          `json:"signatures,omitempty"`
+	// Skipped lists the provisioners and post-processors that were not run,
+	// as requested on the command line.
+	Skipped []string `json:"skipped,omitempty"`
+	// ArtifactsDir is the artifacts directory of the build, and
+	// ArtifactsDirFiles the files kept there, relative to it.
+	ArtifactsDir      string   `json:"artifacts_dir,omitempty"`
+	ArtifactsDirFiles []string `json:"artifacts_dir_files,omitempty"`
 }
 
 func (a *Artifact) BuilderId() string {
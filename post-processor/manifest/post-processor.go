@@ -107,6 +107,26 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, source
 		}
 		artifact.ArtifactFiles = append(artifact.ArtifactFiles, af)
 	}
+	// Signature files are set by the signature post-processor.
+	if signatures, ok := source.State("signatures").([]string); ok {
+		for _, name := range signatures {
+			if p.config.StripPath {
+				name = filepath.Base(name)
+			}
+			artifact.Signatures = append(artifact.Signatures, name)
+		}
+	}
+	// Components skipped with `packer build -skip-provisioner` or
+	// `-exclude-post-processors` are set by the build.
+	if skipped, ok := source.State("skipped_components").([]string); ok {
+		artifact.Skipped = skipped
+	}
+	// The artifacts directory is set by the build when its build block has
+	// an artifacts_dir.
+	if dir, ok := source.State("artifacts_dir").(string); ok {
+		artifact.ArtifactsDir = dir
+		artifact.ArtifactsDirFiles, _ = source.State("artifacts_dir_files").([]string)
+	}
 	artifact.ArtifactId = source.Id()
 	artifact.CustomData = p.config.CustomData
 	artifact.BuilderType = p.config.PackerBuilderType
@@ -0,0 +1,166 @@
+package ovf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// descriptorEdits are the changes made to an OVF descriptor.
+type descriptorEdits struct {
+	// Properties maps the keys of ProductSection properties to their new
+	// value.
+	Properties map[string]string
+	// Networks maps network names to their new name.
+	Networks map[string]string
+	// HardwareVersion replaces the VirtualSystemType of the virtual
+	// hardware, like vmx-13.
+	HardwareVersion string
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// editDescriptor copies the OVF descriptor read from r to w with edits
+// applied and returns the files listed in its References section.
+//
+// The descriptor is rewritten token by token, without resolving namespaces,
+// so that prefixes, comments and formatting are kept as they are; only empty
+// elements are written with an end tag.
+func editDescriptor(r io.Reader, w io.Writer, edits descriptorEdits) ([]string, error) {
+	d := xml.NewDecoder(r)
+	var (
+		stack []string
+		refs  []string
+		found = map[string]bool{}
+		err   error
+	)
+	write := func(s string) {
+		if err == nil {
+			_, err = io.WriteString(w, s)
+		}
+	}
+
+	for {
+		tok, tokErr := d.RawToken()
+		if tokErr == io.EOF {
+			break
+		}
+		if tokErr != nil {
+			return nil, fmt.Errorf("Failed to parse OVF descriptor: %s", tokErr)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			switch {
+			case t.Name.Local == "Property" && parent == "ProductSection":
+				key := attr(t, "key")
+				if value, ok := edits.Properties[key]; ok {
+					t = setAttr(t, "value", value, "key")
+					found[key] = true
+				}
+			case t.Name.Local == "Network" && parent == "NetworkSection":
+				if name, ok := edits.Networks[attr(t, "name")]; ok {
+					t = setAttr(t, "name", name, "name")
+				}
+			case t.Name.Local == "File" && parent == "References":
+				refs = append(refs, attr(t, "href"))
+			}
+			stack = append(stack, t.Name.Local)
+
+			write("<" + qname(t.Name))
+			for _, a := range t.Attr {
+				write(" " + qname(a.Name) + `="` + attrEscaper.Replace(a.Value) + `"`)
+			}
+			write(">")
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			write("</" + qname(t.Name) + ">")
+		case xml.CharData:
+			text := string(t)
+			current := ""
+			if len(stack) > 0 {
+				current = stack[len(stack)-1]
+			}
+			switch {
+			case current == "VirtualSystemType" && edits.HardwareVersion != "":
+				text = edits.HardwareVersion
+			case current == "Connection":
+				if name, ok := edits.Networks[strings.TrimSpace(text)]; ok {
+					text = name
+				}
+			}
+			write(textEscaper.Replace(text))
+		case xml.Comment:
+			write("<!--" + string(t) + "-->")
+		case xml.ProcInst:
+			write("<?" + t.Target)
+			if len(t.Inst) > 0 {
+				write(" " + string(t.Inst))
+			}
+			write("?>")
+		case xml.Directive:
+			write("<!" + string(t) + ">")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for key := range edits.Properties {
+		if !found[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("properties not found in the OVF descriptor: %s", strings.Join(missing, ", "))
+	}
+	return refs, nil
+}
+
+func qname(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// attr returns the value of the attribute named local, whatever its prefix.
+func attr(t xml.StartElement, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// setAttr sets the attribute named local. When the element doesn't have it
+// yet, it is added with the prefix of the sibling attribute.
+func setAttr(t xml.StartElement, local, value, sibling string) xml.StartElement {
+	t = t.Copy()
+	prefix := ""
+	for i, a := range t.Attr {
+		if a.Name.Local == local {
+			t.Attr[i].Value = value
+			return t
+		}
+		if a.Name.Local == sibling {
+			prefix = a.Name.Space
+		}
+	}
+	t.Attr = append(t.Attr, xml.Attr{Name: xml.Name{Space: prefix, Local: local}, Value: value})
+	return t
+}
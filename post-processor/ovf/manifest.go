@@ -0,0 +1,117 @@
+package ovf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io/ioutil"
+
+	// register the hash functions used in manifests
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// manifestAlgorithms are the digest algorithms a manifest can be written
+// with, by name in the manifest.
+var manifestAlgorithms = map[string]crypto.Hash{
+	"SHA1":   crypto.SHA1,
+	"SHA256": crypto.SHA256,
+	"SHA512": crypto.SHA512,
+}
+
+// fileDigest is an entry of a manifest.
+type fileDigest struct {
+	name   string
+	digest []byte
+}
+
+// manifest returns the content of an OVF manifest listing files.
+func manifest(algorithm string, files []fileDigest) []byte {
+	buf := new(bytes.Buffer)
+	for _, f := range files {
+		fmt.Fprintf(buf, "%s(%s)= %s\n", algorithm, f.name, hex.EncodeToString(f.digest))
+	}
+	return buf.Bytes()
+}
+
+func newHash(algorithm string) hash.Hash {
+	return manifestAlgorithms[algorithm].New()
+}
+
+// signer writes OVF certificate files, that hold the signature of the
+// manifest followed by the certificate of the signing key.
+type signer struct {
+	key         *rsa.PrivateKey
+	certificate []byte
+}
+
+func loadSigner(keyPath, certificatePath string) (*signer, error) {
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read signing key: %s", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data found in signing key %s", keyPath)
+	}
+	var key *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var k interface{}
+		k, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if key, ok = k.(*rsa.PrivateKey); !ok {
+				err = fmt.Errorf("only RSA keys are supported")
+			}
+		}
+	default:
+		err = fmt.Errorf("unexpected PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse signing key %s: %s", keyPath, err)
+	}
+
+	certificate, err := ioutil.ReadFile(certificatePath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read signing certificate: %s", err)
+	}
+	block, _ = pem.Decode(certificate)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("No PEM certificate found in %s", certificatePath)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse signing certificate %s: %s", certificatePath, err)
+	}
+	if pub, ok := cert.PublicKey.(*rsa.PublicKey); !ok || pub.N.Cmp(key.N) != 0 {
+		return nil, fmt.Errorf("The signing certificate doesn't match the signing key")
+	}
+
+	return &signer{key: key, certificate: certificate}, nil
+}
+
+// sign returns the content of the certificate file for the manifest named
+// manifestName.
+func (s *signer) sign(algorithm, manifestName string, manifest []byte) ([]byte, error) {
+	h := manifestAlgorithms[algorithm]
+	digest := h.New()
+	digest.Write(manifest)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, h, digest.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "%s(%s)= %s\n", algorithm, manifestName, hex.EncodeToString(signature))
+	buf.Write(s.certificate)
+	return buf.Bytes(), nil
+}
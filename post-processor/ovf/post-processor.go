@@ -0,0 +1,335 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package ovf
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// Values of the OVF properties to set, by property key. Properties are
+	// the `Property` elements of the `ProductSection` of the descriptor; it
+	// is an error to set a property the descriptor doesn't declare.
+	Properties map[string]string `mapstructure:"properties"`
+	// Networks to rename, mapping their current name to their new name. Both
+	// the `NetworkSection` and the connections of the network adapters are
+	// updated.
+	Networks map[string]string `mapstructure:"networks"`
+	// The virtual hardware family to set, like `vmx-13` for VMware or
+	// `virtualbox-2.2` for VirtualBox.
+	HardwareVersion string `mapstructure:"hardware_version"`
+	// The digest algorithm used in the manifest, one of `sha1`, `sha256` or
+	// `sha512`. Defaults to `sha256`.
+	ManifestAlgorithm string `mapstructure:"manifest_algorithm"`
+	// Path to a PEM encoded RSA private key used to sign the manifest. When
+	// set, `signing_certificate` must be set too and a certificate file is
+	// written next to the manifest. Otherwise any existing certificate file,
+	// which no longer matches the manifest, is removed.
+	SigningKey string `mapstructure:"signing_key"`
+	// Path to the PEM encoded certificate of `signing_key`, included in the
+	// certificate file.
+	SigningCertificate string `mapstructure:"signing_certificate"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+	signer *signer
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "ovf",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+	errs := new(packersdk.MultiError)
+
+	if p.config.ManifestAlgorithm == "" {
+		p.config.ManifestAlgorithm = "sha256"
+	}
+	p.config.ManifestAlgorithm = strings.ToUpper(p.config.ManifestAlgorithm)
+	if _, ok := manifestAlgorithms[p.config.ManifestAlgorithm]; !ok {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("Unrecognized manifest_algorithm: %s, expected one of sha1, sha256, sha512",
+				strings.ToLower(p.config.ManifestAlgorithm)))
+	}
+
+	switch {
+	case p.config.SigningKey == "" && p.config.SigningCertificate == "":
+	case p.config.SigningKey == "" || p.config.SigningCertificate == "":
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("signing_key and signing_certificate must be set together"))
+	default:
+		p.signer, err = loadSigner(p.config.SigningKey, p.config.SigningCertificate)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) edits() descriptorEdits {
+	return descriptorEdits{
+		Properties:      p.config.Properties,
+		Networks:        p.config.Networks,
+		HardwareVersion: p.config.HardwareVersion,
+	}
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	files := append([]string{}, artifact.Files()...)
+	processed := false
+
+	for _, path := range artifact.Files() {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ova":
+			ui.Say(fmt.Sprintf("Updating OVA %s", path))
+			if err := p.processOVA(path); err != nil {
+				return nil, false, true, fmt.Errorf("Failed to update %s: %s", path, err)
+			}
+		case ".ovf":
+			ui.Say(fmt.Sprintf("Updating OVF %s", path))
+			written, err := p.processOVF(path)
+			if err != nil {
+				return nil, false, true, fmt.Errorf("Failed to update %s: %s", path, err)
+			}
+			for _, w := range written {
+				if !contains(files, w) {
+					files = append(files, w)
+				}
+			}
+		default:
+			continue
+		}
+		processed = true
+	}
+
+	if !processed {
+		return nil, false, true, fmt.Errorf(
+			"No OVF or OVA file found in the artifact of %s", artifact.BuilderId())
+	}
+
+	// sets keep and forceOverride to true because the files of the input
+	// artifact are edited in place and are part of the new artifact.
+	return NewArtifact(files), true, true, nil
+}
+
+// processOVF edits an OVF descriptor in place and regenerates the manifest
+// and certificate files next to it, whose paths are returned.
+func (p *PostProcessor) processOVF(path string) ([]string, error) {
+	in, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out := new(bytes.Buffer)
+	refs, err := editDescriptor(bytes.NewReader(in), out, p.edits())
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(path, out.Bytes()); err != nil {
+		return nil, err
+	}
+
+	algorithm := p.config.ManifestAlgorithm
+	h := newHash(algorithm)
+	h.Write(out.Bytes())
+	digests := []fileDigest{{name: filepath.Base(path), digest: h.Sum(nil)}}
+	dir := filepath.Dir(path)
+	for _, ref := range refs {
+		f, err := os.Open(filepath.Join(dir, ref))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read file referenced by the descriptor: %s", err)
+		}
+		h := newHash(algorithm)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, fileDigest{name: ref, digest: h.Sum(nil)})
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	mfPath, certPath := base+".mf", base+".cert"
+	mf := manifest(algorithm, digests)
+	if err := writeFile(mfPath, mf); err != nil {
+		return nil, err
+	}
+	if p.signer == nil {
+		if err := os.Remove(certPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return []string{mfPath}, nil
+	}
+	cert, err := p.signer.sign(algorithm, filepath.Base(mfPath), mf)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to sign manifest: %s", err)
+	}
+	if err := writeFile(certPath, cert); err != nil {
+		return nil, err
+	}
+	return []string{mfPath, certPath}, nil
+}
+
+// processOVA rewrites an OVA with its descriptor edited. The descriptor is
+// kept first in the archive, and the regenerated manifest and certificate
+// files are placed at the end, which the OVF specification allows.
+func (p *PostProcessor) processOVA(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	algorithm := p.config.ManifestAlgorithm
+	tr := tar.NewReader(in)
+	tw := tar.NewWriter(out)
+	descriptor := ""
+	var digests []fileDigest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var content io.Reader = tr
+		switch strings.ToLower(filepath.Ext(hdr.Name)) {
+		case ".mf", ".cert":
+			// regenerated below
+			continue
+		case ".ovf":
+			if descriptor != "" {
+				return fmt.Errorf("found more than one OVF descriptor: %s and %s", descriptor, hdr.Name)
+			}
+			buf := new(bytes.Buffer)
+			if _, err := editDescriptor(tr, buf, p.edits()); err != nil {
+				return err
+			}
+			hdr.Size = int64(buf.Len())
+			content = buf
+			descriptor = hdr.Name
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		h := newHash(algorithm)
+		if _, err := io.Copy(io.MultiWriter(tw, h), content); err != nil {
+			return err
+		}
+		digests = append(digests, fileDigest{name: hdr.Name, digest: h.Sum(nil)})
+	}
+	if descriptor == "" {
+		return fmt.Errorf("no OVF descriptor found")
+	}
+
+	base := strings.TrimSuffix(descriptor, filepath.Ext(descriptor))
+	mf := manifest(algorithm, digests)
+	if err := writeTarFile(tw, base+".mf", mf); err != nil {
+		return err
+	}
+	if p.signer != nil {
+		cert, err := p.signer.sign(algorithm, base+".mf", mf)
+		if err != nil {
+			return fmt.Errorf("Failed to sign manifest: %s", err)
+		}
+		if err := writeTarFile(tw, base+".cert", cert); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Rename(out.Name(), path)
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+		Format:  tar.FormatUSTAR,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+// writeFile replaces the content of path, going through a temporary file so
+// that path is never left half written.
+func writeFile(path string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package ovf
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const testDescriptor = `<?xml version="1.0" encoding="UTF-8"?>
+<!--Generated by VMware ovftool-->
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">
+  <References>
+    <File ovf:href="disk-1.vmdk" ovf:id="file1"/>
+  </References>
+  <NetworkSection>
+    <Network ovf:name="NAT">
+      <Description>The NAT network</Description>
+    </Network>
+  </NetworkSection>
+  <VirtualSystem ovf:id="appliance">
+    <ProductSection>
+      <Property ovf:key="hostname" ovf:type="string" ovf:value="localhost"/>
+      <Property ovf:key="motd" ovf:type="string"/>
+    </ProductSection>
+    <VirtualHardwareSection>
+      <System>
+        <vssd:VirtualSystemType>vmx-10</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:Connection>NAT</rasd:Connection>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+func TestPostProcessor_Configure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"empty", map[string]interface{}{}, false},
+		{"edits", map[string]interface{}{
+			"properties":       map[string]string{"hostname": "appliance"},
+			"networks":         map[string]string{"NAT": "VM Network"},
+			"hardware_version": "vmx-13",
+		}, false},
+		{"sha512", map[string]interface{}{"manifest_algorithm": "sha512"}, false},
+		{"unknown algorithm", map[string]interface{}{"manifest_algorithm": "md5"}, true},
+		{"key without certificate", map[string]interface{}{"signing_key": "signing.key"}, true},
+		{"missing key", map[string]interface{}{
+			"signing_key":         "does-not-exist.key",
+			"signing_certificate": "does-not-exist.crt",
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PostProcessor{}
+			err := p.Configure(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEditDescriptor(t *testing.T) {
+	out := new(bytes.Buffer)
+	refs, err := editDescriptor(strings.NewReader(testDescriptor), out, descriptorEdits{
+		Properties:      map[string]string{"hostname": "appliance", "motd": "a < b & c"},
+		Networks:        map[string]string{"NAT": "VM Network"},
+		HardwareVersion: "vmx-13",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+
+	if len(refs) != 1 || refs[0] != "disk-1.vmdk" {
+		t.Errorf("unexpected references: %v", refs)
+	}
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<!--Generated by VMware ovftool-->`,
+		`<Property ovf:key="hostname" ovf:type="string" ovf:value="appliance"></Property>`,
+		`<Property ovf:key="motd" ovf:type="string" ovf:value="a &lt; b &amp; c"></Property>`,
+		`<Network ovf:name="VM Network">`,
+		`<vssd:VirtualSystemType>vmx-13</vssd:VirtualSystemType>`,
+		`<rasd:Connection>VM Network</rasd:Connection>`,
+		`xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData"`,
+		"\n      <Description>The NAT network</Description>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q not found in descriptor:\n%s", want, got)
+		}
+	}
+}
+
+func TestEditDescriptor_unknownProperty(t *testing.T) {
+	_, err := editDescriptor(strings.NewReader(testDescriptor), ioutil.Discard, descriptorEdits{
+		Properties: map[string]string{"hostname": "appliance", "domain": "example.com"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "domain") {
+		t.Fatalf("expected an error about the domain property, got %v", err)
+	}
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPostProcessor_PostProcess_ovf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-ovf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ovfPath := filepath.Join(dir, "appliance.ovf")
+	files := map[string]string{
+		ovfPath:                              testDescriptor,
+		filepath.Join(dir, "disk-1.vmdk"):    "disk",
+		filepath.Join(dir, "appliance.mf"):   "SHA1(appliance.ovf)= stale\n",
+		filepath.Join(dir, "appliance.cert"): "stale",
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"hardware_version": "vmx-13"}); err != nil {
+		t.Fatal(err)
+	}
+	artifact := &packersdk.MockArtifact{FilesValue: []string{ovfPath, filepath.Join(dir, "disk-1.vmdk")}}
+	result, keep, forceOverride, err := p.PostProcess(context.Background(), packersdk.TestUi(t), artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !keep || !forceOverride {
+		t.Errorf("the input artifact must be kept")
+	}
+	if len(result.Files()) != 3 || result.Files()[2] != filepath.Join(dir, "appliance.mf") {
+		t.Errorf("unexpected artifact files: %v", result.Files())
+	}
+
+	descriptor, _ := ioutil.ReadFile(ovfPath)
+	if !strings.Contains(string(descriptor), "vmx-13") {
+		t.Errorf("descriptor not updated:\n%s", descriptor)
+	}
+	mf, _ := ioutil.ReadFile(filepath.Join(dir, "appliance.mf"))
+	want := fmt.Sprintf("SHA256(appliance.ovf)= %s\nSHA256(disk-1.vmdk)= %s\n",
+		sha256Hex(descriptor), sha256Hex([]byte("disk")))
+	if string(mf) != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", mf, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "appliance.cert")); !os.IsNotExist(err) {
+		t.Errorf("stale certificate should have been removed: %v", err)
+	}
+}
+
+// writeSigningKey writes an RSA key and a self signed certificate for it.
+func writeSigningKey(t *testing.T, dir string) (string, string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "packer"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath, certPath := filepath.Join(dir, "signing.key"), filepath.Join(dir, "signing.crt")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certPath, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return keyPath, certPath, key
+}
+
+func TestPostProcessor_PostProcess_ova(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-ovf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyPath, certPath, key := writeSigningKey(t, dir)
+
+	ovaPath := filepath.Join(dir, "appliance.ova")
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, f := range []struct{ name, content string }{
+		{"appliance.ovf", testDescriptor},
+		{"appliance.mf", "SHA1(appliance.ovf)= stale\n"},
+		{"disk-1.vmdk", "disk"},
+	} {
+		if err := writeTarFile(tw, f.name, []byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	if err := ioutil.WriteFile(ovaPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{
+		"networks":            map[string]string{"NAT": "VM Network"},
+		"signing_key":         keyPath,
+		"signing_certificate": certPath,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	artifact := &packersdk.MockArtifact{FilesValue: []string{ovaPath}}
+	if _, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), artifact); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(ovaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	var names []string
+	contents := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+		contents[hdr.Name], _ = ioutil.ReadAll(tr)
+	}
+
+	if got := strings.Join(names, ","); got != "appliance.ovf,disk-1.vmdk,appliance.mf,appliance.cert" {
+		t.Fatalf("unexpected OVA entries: %s", got)
+	}
+	if !strings.Contains(string(contents["appliance.ovf"]), `<Network ovf:name="VM Network">`) {
+		t.Errorf("network not renamed:\n%s", contents["appliance.ovf"])
+	}
+	mf := contents["appliance.mf"]
+	want := fmt.Sprintf("SHA256(appliance.ovf)= %s\nSHA256(disk-1.vmdk)= %s\n",
+		sha256Hex(contents["appliance.ovf"]), sha256Hex([]byte("disk")))
+	if string(mf) != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", mf, want)
+	}
+
+	cert := string(contents["appliance.cert"])
+	prefix := "SHA256(appliance.mf)= "
+	if !strings.HasPrefix(cert, prefix) || !strings.Contains(cert, "BEGIN CERTIFICATE") {
+		t.Fatalf("unexpected certificate file:\n%s", cert)
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(strings.SplitN(cert, "\n", 2)[0], prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(mf)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("invalid manifest signature: %s", err)
+	}
+}
+
+func TestPostProcessor_PostProcess_noOVF(t *testing.T) {
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	artifact := &packersdk.MockArtifact{FilesValue: []string{"disk.qcow2"}}
+	if _, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), artifact); err == nil {
+		t.Fatal("expected an error for an artifact without OVF files")
+	}
+}
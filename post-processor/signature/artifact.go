@@ -0,0 +1,71 @@
+package signature
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const BuilderId = "packer.post-processor.signature"
+
+type Artifact struct {
+	// source is the signed artifact, its ID and state are the ones of this
+	// artifact, so that the post-processors that follow still see them.
+	source     packersdk.Artifact
+	files      []string
+	signatures []string
+	// created are the files written by the post-processor, the signatures
+	// and the metadata file. The other files belong to the signed artifact.
+	created []string
+}
+
+// NewArtifact returns the artifact signing source, whose files are the files
+// of source until signatures are added.
+func NewArtifact(source packersdk.Artifact) *Artifact {
+	return &Artifact{
+		source: source,
+		files:  append([]string{}, source.Files()...),
+	}
+}
+
+func (a *Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.files
+}
+
+func (a *Artifact) Id() string {
+	return a.source.Id()
+}
+
+func (a *Artifact) String() string {
+	files := strings.Join(a.files, ", ")
+	return fmt.Sprintf("Created artifact from files: %s", files)
+}
+
+// State returns the list of signature files for "signatures", this is used by
+// the manifest post-processor to record them. Other states are the ones of
+// the signed artifact.
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "signatures":
+		return a.signatures
+	}
+	return a.source.State(name)
+}
+
+// Destroy removes the files created by the post-processor only, the files of
+// the signed artifact are destroyed with that artifact.
+func (a *Artifact) Destroy() error {
+	for _, f := range a.created {
+		err := os.RemoveAll(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
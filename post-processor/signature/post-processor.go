@@ -0,0 +1,197 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package signature
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The tool used to sign files, one of `gpg` or `cosign`.
+	Signer string `mapstructure:"signer" required:"true"`
+	// The key used to sign. For `gpg` this is the key ID or user ID passed to
+	// `--local-user`; when empty the default gpg key is used. For `cosign`
+	// this is a path to a private key or a KMS reference such as
+	// `awskms:///alias/packer`, `gcpkms://...`, `azurekms://...` or
+	// `hashivault://...`.
+	Key string `mapstructure:"key"`
+	// Path to the signer executable. Defaults to `gpg` or `cosign`, looked
+	// up in the PATH.
+	Executable string `mapstructure:"executable"`
+	// Extra arguments passed to the signer before the file to sign.
+	ExtraArguments []string `mapstructure:"extra_arguments"`
+	// Extension appended to the name of each signed file to create the
+	// signature file. Defaults to `.sig`.
+	SignatureExtension string `mapstructure:"signature_extension"`
+	// When set, a JSON document describing the artifact (build name, builder
+	// type, artifact ID and the sha256 of each file) is written to this path
+	// and signed too. This is useful to sign artifacts that have no files,
+	// like cloud images. This is a template engine, `BuildName` and
+	// `BuilderType` are available.
+	MetadataOutput string `mapstructure:"metadata_output"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+	signer signer
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "signature",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"metadata_output"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+	errs := new(packersdk.MultiError)
+
+	if p.config.SignatureExtension == "" {
+		p.config.SignatureExtension = ".sig"
+	}
+
+	switch p.config.Signer {
+	case "gpg":
+		p.signer = &gpgSigner{config: &p.config}
+	case "cosign":
+		if p.config.Key == "" {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("key must be set when using the cosign signer"))
+		}
+		p.signer = &cosignSigner{config: &p.config}
+	case "":
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("signer must be set"))
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("Unrecognized signer: %s, expected one of gpg, cosign", p.config.Signer))
+	}
+
+	if p.config.MetadataOutput != "" {
+		if err = interpolate.Validate(p.config.MetadataOutput, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing metadata_output template: %s", err))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// metadata is the document signed when metadata_output is set.
+type metadata struct {
+	BuildName   string         `json:"name"`
+	BuilderType string         `json:"builder_type"`
+	ArtifactId  string         `json:"artifact_id"`
+	Files       []metadataFile `json:"files"`
+}
+
+type metadataFile struct {
+	Name   string `json:"name"`
+	Sha256 string `json:"sha256"`
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	toSign := append([]string{}, artifact.Files()...)
+	newartifact := NewArtifact(artifact)
+
+	if p.config.MetadataOutput != "" {
+		p.config.ctx.Data = map[string]interface{}{
+			"BuildName":   p.config.PackerBuildName,
+			"BuilderType": p.config.PackerBuilderType,
+		}
+		metadataFile, err := interpolate.Render(p.config.MetadataOutput, &p.config.ctx)
+		if err != nil {
+			return nil, false, true, err
+		}
+		if err := writeMetadata(metadataFile, &p.config, artifact); err != nil {
+			return nil, false, true, err
+		}
+		newartifact.files = append(newartifact.files, metadataFile)
+		newartifact.created = append(newartifact.created, metadataFile)
+		toSign = append(toSign, metadataFile)
+	}
+
+	if len(toSign) == 0 {
+		ui.Say("Artifact has no files to sign, set metadata_output to sign its metadata.")
+		return artifact, true, false, nil
+	}
+
+	for _, file := range toSign {
+		signature := file + p.config.SignatureExtension
+		ui.Say(fmt.Sprintf("Signing %s with %s", file, p.config.Signer))
+		if err := p.signer.sign(ctx, file, signature); err != nil {
+			return nil, false, true, fmt.Errorf("unable to sign %s: %s", file, err)
+		}
+		newartifact.files = append(newartifact.files, signature)
+		newartifact.signatures = append(newartifact.signatures, signature)
+		newartifact.created = append(newartifact.created, signature)
+	}
+
+	// sets keep and forceOverride to true because we don't want to accidentally
+	// delete the very artifact we're signing.
+	return newartifact, true, true, nil
+}
+
+func writeMetadata(path string, config *Config, artifact packersdk.Artifact) error {
+	md := metadata{
+		BuildName:   config.PackerBuildName,
+		BuilderType: config.PackerBuilderType,
+		ArtifactId:  artifact.Id(),
+		Files:       []metadataFile{},
+	}
+	for _, name := range artifact.Files() {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("unable to open file %s: %s", name, err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to compute sha256 hash for %s: %s", name, err)
+		}
+		md.Files = append(md.Files, metadataFile{
+			Name:   filepath.Base(name),
+			Sha256: hex.EncodeToString(h.Sum(nil)),
+		})
+	}
+
+	out, err := json.MarshalIndent(md, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to marshal JSON %s", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0755)); err != nil {
+		return fmt.Errorf("unable to create dir: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("Unable to write %s: %s", path, err)
+	}
+	return nil
+}
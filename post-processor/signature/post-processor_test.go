@@ -0,0 +1,130 @@
+package signature
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestPostProcessor_Configure(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"gpg", map[string]interface{}{"signer": "gpg"}, false},
+		{"gpg with key", map[string]interface{}{"signer": "gpg", "key": "ops@example.com"}, false},
+		{"cosign with kms key", map[string]interface{}{"signer": "cosign", "key": "awskms:///alias/packer"}, false},
+		{"cosign without key", map[string]interface{}{"signer": "cosign"}, true},
+		{"no signer", map[string]interface{}{}, true},
+		{"unknown signer", map[string]interface{}{"signer": "notary"}, true},
+		{"bad metadata template", map[string]interface{}{"signer": "gpg", "metadata_output": "{{.Nope"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PostProcessor{}
+			err := p.Configure(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type mockSigner struct {
+	signed []string
+}
+
+func (s *mockSigner) sign(_ context.Context, file, signature string) error {
+	s.signed = append(s.signed, file)
+	return ioutil.WriteFile(signature, []byte("signed "+file), 0644)
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-signature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "package.txt")
+	if err := ioutil.WriteFile(file, []byte("Hello world!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PostProcessor{}
+	if err := p.Configure(map[string]interface{}{
+		"signer":          "gpg",
+		"metadata_output": filepath.Join(dir, "{{.BuildName}}.json"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	p.config.PackerBuildName = "vanilla"
+	signer := &mockSigner{}
+	p.signer = signer
+
+	// the files of the source have room to grow, so that appending to them
+	// in place would show.
+	sourceFiles := make([]string, 1, 8)
+	sourceFiles[0] = file
+	source := &packersdk.MockArtifact{
+		FilesValue:  sourceFiles,
+		IdValue:     "ami-1234",
+		StateValues: map[string]interface{}{"region": "us-east-1"},
+	}
+	artifact, keep, forceOverride, err := p.PostProcess(context.Background(), packersdk.TestUi(t), source)
+	if err != nil {
+		t.Fatalf("PostProcess: %s", err)
+	}
+	if !keep || !forceOverride {
+		t.Errorf("expected input artifact to be kept")
+	}
+
+	metadataFile := filepath.Join(dir, "vanilla.json")
+	if len(signer.signed) != 2 || signer.signed[0] != file || signer.signed[1] != metadataFile {
+		t.Fatalf("unexpected signed files: %v", signer.signed)
+	}
+
+	expected := []string{file, metadataFile, file + ".sig", metadataFile + ".sig"}
+	files := artifact.Files()
+	if len(files) != len(expected) {
+		t.Fatalf("expected files %v, got %v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Fatalf("expected files %v, got %v", expected, files)
+		}
+	}
+
+	if full := sourceFiles[:cap(sourceFiles)]; full[1] != "" {
+		t.Errorf("the files of the signed artifact were modified: %v", full)
+	}
+	if id := artifact.Id(); id != "ami-1234" {
+		t.Errorf("expected the ID of the signed artifact, got %q", id)
+	}
+	if region := artifact.State("region"); region != "us-east-1" {
+		t.Errorf("expected the state of the signed artifact, got %#v", region)
+	}
+
+	signatures, ok := artifact.State("signatures").([]string)
+	if !ok || len(signatures) != 2 {
+		t.Fatalf("unexpected signatures state: %#v", artifact.State("signatures"))
+	}
+
+	// destroying the signatures leaves the signed files alone
+	if err := artifact.Destroy(); err != nil {
+		t.Fatalf("Destroy: %s", err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("expected %s to be kept: %s", file, err)
+	}
+	for _, created := range []string{metadataFile, file + ".sig", metadataFile + ".sig"} {
+		if _, err := os.Stat(created); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", created, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package signature
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// A signer creates a detached signature of a file.
+type signer interface {
+	sign(ctx context.Context, file, signature string) error
+}
+
+// gpgSigner creates armored detached signatures using gpg.
+type gpgSigner struct {
+	config *Config
+}
+
+func (s *gpgSigner) sign(ctx context.Context, file, signature string) error {
+	args := []string{"--batch", "--yes", "--armor", "--detach-sign"}
+	if s.config.Key != "" {
+		args = append(args, "--local-user", s.config.Key)
+	}
+	args = append(args, s.config.ExtraArguments...)
+	args = append(args, "--output", signature, file)
+	return run(ctx, executable(s.config, "gpg"), args)
+}
+
+// cosignSigner creates signatures using cosign sign-blob, this also covers
+// keys stored in a KMS as cosign understands KMS key references.
+type cosignSigner struct {
+	config *Config
+}
+
+func (s *cosignSigner) sign(ctx context.Context, file, signature string) error {
+	args := []string{"sign-blob", "--key", s.config.Key, "--output-signature", signature}
+	args = append(args, s.config.ExtraArguments...)
+	args = append(args, file)
+	return run(ctx, executable(s.config, "cosign"), args)
+}
+
+func executable(config *Config, defaultExecutable string) string {
+	if config.Executable != "" {
+		return config.Executable
+	}
+	return defaultExecutable
+}
+
+func run(ctx context.Context, name string, args []string) error {
+	log.Printf("[INFO] signature: running %s %s", name, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, name, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %s\n%s", name, err, out)
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer/helper/longpath"
 )
 
 type Config struct {
@@ -95,7 +96,7 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 
 	if p.config.Direction == "upload" {
 		for _, src := range p.config.Sources {
-			if _, err := os.Stat(src); p.config.Generated == false && err != nil {
+			if _, err := os.Stat(longpath.Fix(src)); p.config.Generated == false && err != nil {
 				errs = packersdk.MultiErrorAppend(errs,
 					fmt.Errorf("Bad source '%s': %s", src, err))
 			}
@@ -155,7 +156,7 @@ func (p *Provisioner) ProvisionDownload(ui packersdk.Ui, comm packersdk.Communic
 		ui.Say(fmt.Sprintf("Downloading %s => %s", src, dst))
 
 		if dir != "" {
-			err := os.MkdirAll(dir, os.FileMode(0755))
+			err := os.MkdirAll(longpath.Fix(dir), os.FileMode(0755))
 			if err != nil {
 				return err
 			}
@@ -165,7 +166,7 @@ func (p *Provisioner) ProvisionDownload(ui packersdk.Ui, comm packersdk.Communic
 			return comm.DownloadDir(src, dst, nil)
 		}
 
-		f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		f, err := os.OpenFile(longpath.Fix(dst), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 		if err != nil {
 			return err
 		}
@@ -196,7 +197,7 @@ func (p *Provisioner) ProvisionUpload(ui packersdk.Ui, comm packersdk.Communicat
 
 		ui.Say(fmt.Sprintf("Uploading %s => %s", src, dst))
 
-		info, err := os.Stat(src)
+		info, err := os.Stat(longpath.Fix(src))
 		if err != nil {
 			return err
 		}
@@ -211,7 +212,7 @@ func (p *Provisioner) ProvisionUpload(ui packersdk.Ui, comm packersdk.Communicat
 		}
 
 		// We're uploading a file...
-		f, err := os.Open(src)
+		f, err := os.Open(longpath.Fix(src))
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,314 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+// Package waitfor implements a provisioner that blocks a build until an
+// external condition is met: an HTTP endpoint returns an expected status, a
+// file appears on the machine being provisioned, the setup of the guest
+// completes, or an operator approves.
+package waitfor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultWaitTimeout  = 30 * time.Minute
+
+	// failureLogLines is the number of lines of the setup log shown when the
+	// setup of the guest fails or times out.
+	failureLogLines = 50
+)
+
+// The guest setups guest_setup can wait for.
+const (
+	GuestSetupCloudInit = "cloud-init"
+	GuestSetupWindows   = "windows"
+)
+
+// guestSetup describes how to tell that the setup of a guest completed.
+type guestSetup struct {
+	// check prints `done`, `running` or `error` on the first line of its
+	// output.
+	check string
+	// log prints the last lines of the setup log, shown on failure.
+	log string
+}
+
+var guestSetups = map[string]guestSetup{
+	// cloud-init status answers with `status: done`, `status: running` or
+	// `status: error`; images without the cloud-init command only have the
+	// file written once the final stage completed.
+	GuestSetupCloudInit: {
+		check: `if command -v cloud-init >/dev/null 2>&1; then cloud-init status | sed -n 's/^status: //p'; ` +
+			`elif [ -f /var/lib/cloud/instance/boot-finished ]; then echo done; else echo running; fi`,
+		log: fmt.Sprintf("tail -n %d /var/log/cloud-init-output.log", failureLogLines),
+	},
+	// Windows Setup sets the image state to IMAGE_STATE_COMPLETE once the
+	// specialize and OOBE passes are done.
+	GuestSetupWindows: {
+		check: `powershell -NoProfile -Command "$s = (Get-ItemProperty 'HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\Setup\State').ImageState; ` +
+			`if ($s -eq 'IMAGE_STATE_COMPLETE') { 'done' } elseif ($s) { 'running' } else { 'error' }"`,
+		log: fmt.Sprintf(`powershell -NoProfile -Command "Get-Content -Tail %d $env:WINDIR\Panther\setupact.log"`, failureLogLines),
+	},
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// The URL to poll until it answers with one of HTTPStatusCodes.
+	HTTPURL string `mapstructure:"http_url"`
+	// The status codes that end the wait. Defaults to `[200]`.
+	HTTPStatusCodes []int `mapstructure:"http_status_codes"`
+	// A path on the remote machine to wait for. It is fetched through the
+	// communicator, so it should point to a small marker file.
+	RemotePath string `mapstructure:"remote_path"`
+	// The setup of the guest to wait for: `cloud-init`, to wait for the final
+	// stage of cloud-init, or `windows`, to wait for Windows Setup to
+	// complete. The end of the setup log is shown when it fails or times
+	// out.
+	GuestSetup string `mapstructure:"guest_setup"`
+	// A message asking an operator to approve the rest of the build.
+	Approval string `mapstructure:"approval"`
+	// How long to wait between two checks. Defaults to `5s`.
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// How long to wait for the condition before failing the build. Defaults
+	// to `30m`.
+	WaitTimeout time.Duration `mapstructure:"wait_timeout"`
+
+	ctx interpolate.Context
+}
+
+type Provisioner struct {
+	config Config
+}
+
+var _ packersdk.Provisioner = new(Provisioner)
+
+func (p *Provisioner) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "wait-for",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if len(p.config.HTTPStatusCodes) == 0 {
+		p.config.HTTPStatusCodes = []int{http.StatusOK}
+	}
+	if p.config.PollInterval == 0 {
+		p.config.PollInterval = DefaultPollInterval
+	}
+	if p.config.WaitTimeout == 0 {
+		p.config.WaitTimeout = DefaultWaitTimeout
+	}
+
+	var errs *packersdk.MultiError
+	conditions := 0
+	for _, set := range []bool{p.config.HTTPURL != "", p.config.RemotePath != "", p.config.GuestSetup != "", p.config.Approval != ""} {
+		if set {
+			conditions++
+		}
+	}
+	if conditions != 1 {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("exactly one of http_url, remote_path, guest_setup or approval must be set"))
+	}
+	if _, known := guestSetups[p.config.GuestSetup]; p.config.GuestSetup != "" && !known {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("guest_setup must be %q or %q", GuestSetupCloudInit, GuestSetupWindows))
+	}
+	if p.config.PollInterval < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("poll_interval must be positive"))
+	}
+	if p.config.WaitTimeout < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("wait_timeout must be positive"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, _ map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, p.config.WaitTimeout)
+	defer cancel()
+
+	switch {
+	case p.config.Approval != "":
+		return p.waitForApproval(ctx, ui)
+	case p.config.HTTPURL != "":
+		return p.poll(ctx, ui, fmt.Sprintf("%s to answer", p.config.HTTPURL), p.checkHTTP)
+	case p.config.GuestSetup != "":
+		return p.waitForGuestSetup(ctx, ui, comm)
+	default:
+		return p.poll(ctx, ui, fmt.Sprintf("%s to exist on the remote machine", p.config.RemotePath),
+			func(ctx context.Context) (bool, error) {
+				return p.checkRemotePath(comm)
+			})
+	}
+}
+
+// errConditionFailed is returned by checks when the condition waited for can
+// no longer be met.
+var errConditionFailed = errors.New("condition failed")
+
+// poll calls check every PollInterval until it succeeds, fails with
+// errConditionFailed, or ctx is done.
+func (p *Provisioner) poll(ctx context.Context, ui packersdk.Ui, what string, check func(context.Context) (bool, error)) error {
+	ui.Say(fmt.Sprintf("Waiting for %s...", what))
+	for {
+		done, err := check(ctx)
+		if err == errConditionFailed {
+			return err
+		}
+		if err != nil {
+			log.Printf("[DEBUG] wait-for: %s", err)
+		}
+		if done {
+			ui.Say(fmt.Sprintf("Done waiting for %s.", what))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Timeout waiting for %s: %s", what, ctx.Err())
+		case <-time.After(p.config.PollInterval):
+		}
+	}
+}
+
+func (p *Provisioner) checkHTTP(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.HTTPURL, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	for _, code := range p.config.HTTPStatusCodes {
+		if resp.StatusCode == code {
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("%s answered with unexpected status %q", p.config.HTTPURL, resp.Status)
+}
+
+func (p *Provisioner) checkRemotePath(comm packersdk.Communicator) (bool, error) {
+	if err := comm.Download(p.config.RemotePath, ioutil.Discard); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *Provisioner) waitForGuestSetup(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator) error {
+	setup := guestSetups[p.config.GuestSetup]
+	err := p.poll(ctx, ui, fmt.Sprintf("the %s setup of the guest to complete", p.config.GuestSetup),
+		func(ctx context.Context) (bool, error) {
+			status, err := runRemote(ctx, comm, setup.check)
+			if err != nil {
+				return false, err
+			}
+			switch status = firstLine(status); status {
+			case "done", "disabled":
+				return true, nil
+			case "error":
+				return false, errConditionFailed
+			default:
+				return false, fmt.Errorf("%s setup status: %q", p.config.GuestSetup, status)
+			}
+		})
+	if err == nil {
+		return nil
+	}
+
+	// the build context may be done, the log is fetched regardless.
+	logCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if out, logErr := runRemote(logCtx, comm, setup.log); logErr != nil {
+		log.Printf("[DEBUG] wait-for: could not get the %s setup log: %s", p.config.GuestSetup, logErr)
+	} else {
+		ui.Error(fmt.Sprintf("Last lines of the %s setup log:\n%s", p.config.GuestSetup, out))
+	}
+	if err == errConditionFailed {
+		return fmt.Errorf("The %s setup of the guest failed", p.config.GuestSetup)
+	}
+	return err
+}
+
+// runRemote runs command on the remote machine and returns its standard
+// output.
+func runRemote(ctx context.Context, comm packersdk.Communicator, command string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: command,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return "", err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return "", fmt.Errorf("%q exited with status %d: %s", command, status, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func (p *Provisioner) waitForApproval(ctx context.Context, ui packersdk.Ui) error {
+	result := make(chan string, 1)
+	errs := make(chan error, 1)
+	go func() {
+		line, err := ui.Ask(fmt.Sprintf("%s [y/N]", p.config.Approval))
+		if err != nil {
+			errs <- fmt.Errorf("Error asking for input: %s", err)
+			return
+		}
+		result <- line
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("Timeout waiting for approval: %s", ctx.Err())
+	case err := <-errs:
+		return err
+	case line := <-result:
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			ui.Say("Approved, continuing...")
+			return nil
+		default:
+			return fmt.Errorf("Build was not approved")
+		}
+	}
+}
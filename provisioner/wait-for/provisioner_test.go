@@ -0,0 +1,183 @@
+package waitfor
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testUi(input string) *packersdk.BasicUi {
+	return &packersdk.BasicUi{
+		Reader:      strings.NewReader(input),
+		Writer:      new(bytes.Buffer),
+		ErrorWriter: new(bytes.Buffer),
+	}
+}
+
+func TestProvisionerPrepare(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]interface{}
+		wantErr bool
+	}{
+		{"http", map[string]interface{}{"http_url": "http://localhost/ready"}, false},
+		{"remote path", map[string]interface{}{"remote_path": "/var/run/joined"}, false},
+		{"approval", map[string]interface{}{"approval": "Has the agent been registered?"}, false},
+		{"cloud-init", map[string]interface{}{"guest_setup": "cloud-init"}, false},
+		{"windows", map[string]interface{}{"guest_setup": "windows"}, false},
+		{"unknown guest setup", map[string]interface{}{"guest_setup": "ignition"}, true},
+		{"nothing to wait for", map[string]interface{}{}, true},
+		{"two conditions", map[string]interface{}{
+			"http_url":    "http://localhost/ready",
+			"remote_path": "/var/run/joined",
+		}, true},
+		{"negative timeout", map[string]interface{}{
+			"http_url":     "http://localhost/ready",
+			"wait_timeout": "-1s",
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Provisioner
+			if err := p.Prepare(tt.raw); (err != nil) != tt.wantErr {
+				t.Fatalf("Prepare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvisionerPrepare_defaults(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(map[string]interface{}{"http_url": "http://localhost/ready"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.config.HTTPStatusCodes) != 1 || p.config.HTTPStatusCodes[0] != http.StatusOK {
+		t.Errorf("unexpected default status codes: %v", p.config.HTTPStatusCodes)
+	}
+	if p.config.PollInterval != DefaultPollInterval {
+		t.Errorf("unexpected default poll interval: %s", p.config.PollInterval)
+	}
+	if p.config.WaitTimeout != DefaultWaitTimeout {
+		t.Errorf("unexpected default wait timeout: %s", p.config.WaitTimeout)
+	}
+}
+
+func TestProvisionerProvision_http(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	p := &Provisioner{config: Config{
+		HTTPURL:         ts.URL,
+		HTTPStatusCodes: []int{http.StatusNoContent},
+		PollInterval:    time.Millisecond,
+		WaitTimeout:     time.Minute,
+	}}
+	if err := p.Provision(context.Background(), testUi(""), nil, nil); err != nil {
+		t.Fatalf("Provision() failed: %s", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestProvisionerProvision_httpTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	p := &Provisioner{config: Config{
+		HTTPURL:         ts.URL,
+		HTTPStatusCodes: []int{http.StatusOK},
+		PollInterval:    time.Millisecond,
+		WaitTimeout:     20 * time.Millisecond,
+	}}
+	if err := p.Provision(context.Background(), testUi(""), nil, nil); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+}
+
+func TestProvisionerProvision_remotePath(t *testing.T) {
+	comm := &packersdk.MockCommunicator{}
+	p := &Provisioner{config: Config{
+		RemotePath:   "/var/run/joined",
+		PollInterval: time.Millisecond,
+		WaitTimeout:  time.Minute,
+	}}
+	if err := p.Provision(context.Background(), testUi(""), comm, nil); err != nil {
+		t.Fatalf("Provision() failed: %s", err)
+	}
+	if comm.DownloadPath != "/var/run/joined" {
+		t.Errorf("unexpected download path: %q", comm.DownloadPath)
+	}
+}
+
+func TestProvisionerProvision_guestSetup(t *testing.T) {
+	comm := &packersdk.MockCommunicator{StartStdout: "done\n"}
+	p := &Provisioner{config: Config{
+		GuestSetup:   GuestSetupCloudInit,
+		PollInterval: time.Millisecond,
+		WaitTimeout:  time.Minute,
+	}}
+	if err := p.Provision(context.Background(), testUi(""), comm, nil); err != nil {
+		t.Fatalf("Provision() failed: %s", err)
+	}
+	if !strings.Contains(comm.StartCmd.Command, "cloud-init status") {
+		t.Errorf("unexpected command: %q", comm.StartCmd.Command)
+	}
+}
+
+func TestProvisionerProvision_guestSetupFailed(t *testing.T) {
+	comm := &packersdk.MockCommunicator{StartStdout: "error\n"}
+	p := &Provisioner{config: Config{
+		GuestSetup:   GuestSetupCloudInit,
+		PollInterval: time.Millisecond,
+		WaitTimeout:  time.Minute,
+	}}
+	ui := testUi("")
+	if err := p.Provision(context.Background(), ui, comm, nil); err == nil {
+		t.Fatal("expected an error for a failed setup")
+	}
+	if !strings.Contains(comm.StartCmd.Command, "cloud-init-output.log") {
+		t.Errorf("expected the setup log to be fetched, last command: %q", comm.StartCmd.Command)
+	}
+	if !strings.Contains(ui.ErrorWriter.(*bytes.Buffer).String(), "setup log") {
+		t.Errorf("expected the setup log to be shown")
+	}
+}
+
+func TestProvisionerProvision_approval(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"yes\n", false},
+		{"Y\n", false},
+		{"no\n", true},
+		{"\n", true},
+	}
+	for _, tt := range tests {
+		p := &Provisioner{config: Config{
+			Approval:    "Continue?",
+			WaitTimeout: time.Minute,
+		}}
+		err := p.Provision(context.Background(), testUi(tt.input), nil, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Provision() with input %q: error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+	}
+}